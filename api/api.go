@@ -14,24 +14,37 @@
 package api
 
 import (
+	"compress/gzip"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"net/http"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
 	"github.com/prometheus/prometheus/pkg/labels"
 
+	"github.com/prometheus/alertmanager/api/apipb"
+	"github.com/prometheus/alertmanager/audit"
+	"github.com/prometheus/alertmanager/cluster"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/pkg/parse"
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/silence"
@@ -73,20 +86,170 @@ func setCORS(w http.ResponseWriter) {
 	}
 }
 
+// withGzip compresses the responses of f for clients that accept gzip
+// encoded content. Responses without a body are not compressed.
+func withGzip(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r) || r.Method == http.MethodHead {
+			f(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+
+		f(gw, r)
+	}
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(nil) },
+}
+
+// acceptsGzip returns true iff the Accept-Encoding header of the request
+// allows gzip encoded responses.
+func acceptsGzip(r *http.Request) bool {
+	return accepts(r.Header.Get("Accept-Encoding"), "gzip")
+}
+
+// protobufContentType is the media type of protobuf encoded responses.
+const protobufContentType = "application/x-protobuf"
+
+// acceptsProtobuf returns true iff the Accept header of the request allows
+// protobuf encoded responses.
+func acceptsProtobuf(r *http.Request) bool {
+	return accepts(r.Header.Get("Accept"), protobufContentType)
+}
+
+// accepts returns true iff the value of a content negotiation header lists
+// v without a quality value of zero.
+func accepts(header, v string) bool {
+	for _, enc := range strings.Split(header, ",") {
+		parts := strings.Split(enc, ";")
+		if strings.TrimSpace(parts[0]) != v {
+			continue
+		}
+		for _, p := range parts[1:] {
+			kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+			if len(kv) != 2 || kv[0] != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the body written to it. The header is only
+// written along with the first part of the body, so that it is only marked
+// as compressed if there is a body.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	// gz is set once the compressed body was started.
+	gz *gzip.Writer
+	// code is the status code the handler set, if any.
+	code        int
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader && w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		w.writeHeader(true)
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.gz.Write(b)
+}
+
+// Flush implements the http.Flusher interface. It sends the data written
+// so far, which requires writing the header.
+func (w *gzipResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.writeHeader(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeHeader writes the header and starts compressing the body if
+// compress is true and the status code allows a body.
+func (w *gzipResponseWriter) writeHeader(compress bool) {
+	w.wroteHeader = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if compress && bodyAllowed(w.code) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
+
+// close writes the header of responses without a body and completes the
+// compressed body otherwise.
+func (w *gzipResponseWriter) close() {
+	if !w.wroteHeader {
+		w.writeHeader(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+	}
+}
+
+// bodyAllowed returns whether a response with the status code may have a
+// body.
+func bodyAllowed(code int) bool {
+	return code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
 // API provides registration of handlers for API routes.
 type API struct {
 	alerts         provider.Alerts
 	silences       *silence.Silences
+	nflog          nflog.Log
 	config         *config.Config
 	route          *dispatch.Route
 	resolveTimeout time.Duration
 	uptime         time.Time
 	mrouter        *mesh.Router
+	blocklist      *cluster.Blocklist
+	peerVersions   *cluster.PeerVersions
+	audit          *audit.Log
+	labels         *provider.LabelCatalog
 	logger         log.Logger
 
 	groups         groupsFn
 	getAlertStatus getAlertStatusFn
 
+	// Alerts are rejected while draining and clients are asked to retry
+	// after the given duration.
+	draining   bool
+	retryAfter time.Duration
+
+	// Whether the state export and import endpoints are enabled.
+	stateTransfer bool
+
 	mtx sync.RWMutex
 }
 
@@ -94,14 +257,19 @@ type groupsFn func([]*labels.Matcher) dispatch.AlertOverview
 type getAlertStatusFn func(model.Fingerprint) types.AlertStatus
 
 // New returns a new API.
-func New(alerts provider.Alerts, silences *silence.Silences, gf groupsFn, sf getAlertStatusFn, router *mesh.Router, l log.Logger) *API {
+func New(alerts provider.Alerts, silences *silence.Silences, nl nflog.Log, gf groupsFn, sf getAlertStatusFn, router *mesh.Router, bl *cluster.Blocklist, pv *cluster.PeerVersions, al *audit.Log, lc *provider.LabelCatalog, l log.Logger) *API {
 	return &API{
 		alerts:         alerts,
 		silences:       silences,
+		nflog:          nl,
 		groups:         gf,
 		getAlertStatus: sf,
 		uptime:         time.Now(),
 		mrouter:        router,
+		blocklist:      bl,
+		peerVersions:   pv,
+		audit:          al,
+		labels:         lc,
 		logger:         l,
 	}
 }
@@ -112,29 +280,109 @@ func (api *API) Register(r *route.Router) {
 	ihf := func(name string, f http.HandlerFunc) http.HandlerFunc {
 		return prometheus.InstrumentHandlerFunc(name, func(w http.ResponseWriter, r *http.Request) {
 			setCORS(w)
-			f(w, r)
+			withGzip(f)(w, r)
 		})
 	}
 
 	r.Options("/*path", ihf("options", func(w http.ResponseWriter, r *http.Request) {}))
 
 	// Register legacy forwarder for alert pushing.
-	r.Post("/alerts", ihf("legacy_add_alerts", api.legacyAddAlerts))
+	r.Post("/alerts", ihf("legacy_add_alerts", api.acceptAlerts(api.audit.Wrap("alerts_add", api.legacyAddAlerts))))
 
 	// Register actual API.
 	r = r.WithPrefix("/v1")
 
 	r.Get("/status", ihf("status", api.status))
 	r.Get("/receivers", ihf("receivers", api.receivers))
+	r.Get("/routes", ihf("routes", api.routes))
+	r.Get("/routes/match", ihf("match_routes", api.matchRoutes))
+	r.Get("/routes/effective", ihf("effective_routes", api.effectiveRoutes))
 	r.Get("/alerts/groups", ihf("alert_groups", api.alertGroups))
+	r.Get("/alerts/cardinality", ihf("alert_cardinality", api.alertCardinality))
+	r.Get("/alerts/inhibited", ihf("inhibited_alerts", api.inhibitedAlerts))
 
 	r.Get("/alerts", ihf("list_alerts", api.listAlerts))
-	r.Post("/alerts", ihf("add_alerts", api.addAlerts))
+	r.Post("/alerts", ihf("add_alerts", api.acceptAlerts(api.audit.Wrap("alerts_add", api.addAlerts))))
+	r.Post("/alerts/resolve", ihf("resolve_alerts", api.acceptAlerts(api.audit.Wrap("alerts_resolve", api.resolveAlerts))))
+	r.Post("/alert/:fingerprint/annotations", ihf("annotate_alert", api.audit.Wrap("alert_annotate", api.annotateAlert)))
 
 	r.Get("/silences", ihf("list_silences", api.listSilences))
-	r.Post("/silences", ihf("add_silence", api.setSilence))
+	r.Get("/silences/audit", ihf("silence_audit", api.silenceEvents))
+	r.Post("/silences", ihf("add_silence", api.audit.Wrap("silence_set", api.setSilence)))
 	r.Get("/silence/:sid", ihf("get_silence", api.getSilence))
-	r.Del("/silence/:sid", ihf("del_silence", api.delSilence))
+	r.Del("/silence/:sid", ihf("del_silence", api.audit.Wrap("silence_expire", api.delSilence)))
+	r.Post("/silence/:sid/extend", ihf("extend_silence", api.audit.Wrap("silence_extend", api.extendSilence)))
+	r.Post("/silence/:sid/transfer", ihf("transfer_silence", api.audit.Wrap("silence_transfer", api.transferSilence)))
+
+	r.Get("/audit", ihf("audit", api.auditEntries))
+
+	r.Get("/nflog", ihf("list_nflog", api.listNflog))
+	r.Get("/nflog/stats", ihf("nflog_stats", api.nflogStats))
+	r.Post("/acknowledgements/:integration", ihf("acknowledge", api.audit.Wrap("notification_acknowledge", api.acknowledge)))
+
+	r.Get("/admin/export", ihf("admin_export", api.requireStateTransfer(api.exportState)))
+	r.Post("/admin/import", ihf("admin_import", api.requireStateTransfer(api.audit.Wrap("state_import", api.importState))))
+	r.Post("/admin/gc", ihf("admin_gc", api.audit.Wrap("state_gc", api.gc)))
+	r.Post("/admin/nflog/delete", ihf("admin_nflog_delete", api.audit.Wrap("nflog_delete", api.deleteNflog)))
+}
+
+// Drain makes the API reject alerts with a 503 status, asking clients to
+// retry after the given duration.
+func (api *API) Drain(retryAfter time.Duration) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.draining = true
+	api.retryAfter = retryAfter
+}
+
+// acceptAlerts wraps a handler receiving alerts to reject them while
+// draining.
+func (api *API) acceptAlerts(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.mtx.RLock()
+		draining, retryAfter := api.draining, api.retryAfter
+		api.mtx.RUnlock()
+
+		if draining {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+			api.respondError(w, apiError{
+				typ: errorUnavailable,
+				err: fmt.Errorf("alertmanager is draining"),
+			}, nil)
+			return
+		}
+		f(w, r)
+	}
+}
+
+// EnableStateTransfer enables the endpoints exporting and importing the
+// silences and notification log. They are not authenticated, so access to
+// them must be restricted otherwise, e.g. by a proxy.
+func (api *API) EnableStateTransfer() {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.stateTransfer = true
+}
+
+// requireStateTransfer wraps a handler of the state export and import
+// endpoints to reject requests unless they are enabled.
+func (api *API) requireStateTransfer(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		api.mtx.RLock()
+		enabled := api.stateTransfer
+		api.mtx.RUnlock()
+
+		if !enabled {
+			api.respondError(w, apiError{
+				typ: errorNotFound,
+				err: fmt.Errorf("state export and import are disabled"),
+			}, nil)
+			return
+		}
+		f(w, r)
+	}
 }
 
 // Update sets the configuration string to a new value.
@@ -151,9 +399,15 @@ func (api *API) Update(cfg *config.Config, resolveTimeout time.Duration) error {
 type errorType string
 
 const (
-	errorNone     errorType = ""
-	errorInternal           = "server_error"
-	errorBadData            = "bad_data"
+	errorNone          errorType = ""
+	errorInternal                = "server_error"
+	errorBadData                 = "bad_data"
+	errorUnauthorized            = "unauthorized"
+	errorNotFound                = "not_found"
+	errorConflict                = "conflict"
+	errorUnavailable             = "unavailable"
+	errorTooLarge                = "too_large"
+	errorLimitExceeded           = "limit_exceeded"
 )
 
 type apiError struct {
@@ -165,10 +419,26 @@ func (e *apiError) Error() string {
 	return fmt.Sprintf("%s: %s", e.typ, e.err)
 }
 
+type apiReceiver struct {
+	Name     string           `json:"name"`
+	Metadata *config.Metadata `json:"metadata,omitempty"`
+}
+
 func (api *API) receivers(w http.ResponseWriter, req *http.Request) {
 	api.mtx.RLock()
 	defer api.mtx.RUnlock()
 
+	// Only list receiver names unless metadata is requested explicitly
+	// to remain compatible with existing clients.
+	if req.FormValue("metadata") == "true" {
+		receivers := make([]*apiReceiver, 0, len(api.config.Receivers))
+		for _, r := range api.config.Receivers {
+			receivers = append(receivers, &apiReceiver{Name: r.Name, Metadata: r.Metadata})
+		}
+		api.respond(w, receivers)
+		return
+	}
+
 	receivers := make([]string, 0, len(api.config.Receivers))
 	for _, r := range api.config.Receivers {
 		receivers = append(receivers, r.Name)
@@ -177,18 +447,110 @@ func (api *API) receivers(w http.ResponseWriter, req *http.Request) {
 	api.respond(w, receivers)
 }
 
+type apiRoute struct {
+	Receiver    string           `json:"receiver"`
+	Matchers    types.Matchers   `json:"matchers"`
+	MinSeverity string           `json:"minSeverity,omitempty"`
+	Continue    bool             `json:"continue"`
+	Metadata    *config.Metadata `json:"metadata,omitempty"`
+	Routes      []*apiRoute      `json:"routes,omitempty"`
+}
+
+func newAPIRoute(r *dispatch.Route) *apiRoute {
+	ar := &apiRoute{
+		Receiver: r.RouteOpts.Receiver,
+		Matchers: r.Matchers,
+		Continue: r.Continue,
+		Metadata: r.Metadata,
+	}
+	if ar.Matchers == nil {
+		ar.Matchers = types.Matchers{}
+	}
+	if r.MinSeverity > types.SeverityUnknown {
+		ar.MinSeverity = r.MinSeverity.String()
+	}
+	for _, cr := range r.Routes {
+		ar.Routes = append(ar.Routes, newAPIRoute(cr))
+	}
+	return ar
+}
+
+func (api *API) routes(w http.ResponseWriter, req *http.Request) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	api.respond(w, newAPIRoute(api.route))
+}
+
+// effectiveRoutes returns all leaf routes along with the routing options
+// they inherited from their parents.
+func (api *API) effectiveRoutes(w http.ResponseWriter, req *http.Request) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	res := []*dispatch.EffectiveRoute{}
+	for _, r := range api.route.Leaves() {
+		res = append(res, r.Effective())
+	}
+	api.respond(w, res)
+}
+
+// matchRoutes returns the routes an alert with the label set given by the
+// labels parameter would be routed to.
+func (api *API) matchRoutes(w http.ResponseWriter, req *http.Request) {
+	s := req.FormValue("labels")
+	if s == "" {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("missing labels parameter"),
+		}, nil)
+		return
+	}
+	matchers, err := parse.Matchers(s)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	lset := model.LabelSet{}
+	for _, m := range matchers {
+		if m.Type != labels.MatchEqual {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("label %q must be given as name=value", m.Name),
+			}, nil)
+			return
+		}
+		lset[model.LabelName(m.Name)] = model.LabelValue(m.Value)
+	}
+
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	res := []*dispatch.MatchedRoute{}
+	for _, r := range api.route.Match(lset) {
+		res = append(res, r.Matched())
+	}
+	api.respond(w, res)
+}
+
 func (api *API) status(w http.ResponseWriter, req *http.Request) {
 	api.mtx.RLock()
 
 	var status = struct {
-		ConfigYAML  string            `json:"configYAML"`
-		ConfigJSON  *config.Config    `json:"configJSON"`
-		VersionInfo map[string]string `json:"versionInfo"`
-		Uptime      time.Time         `json:"uptime"`
-		MeshStatus  *meshStatus       `json:"meshStatus"`
+		ConfigYAML   string                `json:"configYAML"`
+		ConfigJSON   *config.Config        `json:"configJSON"`
+		VersionInfo  map[string]string     `json:"versionInfo"`
+		Uptime       time.Time             `json:"uptime"`
+		MeshStatus   *meshStatus           `json:"meshStatus"`
+		Deprecations []config.Deprecation  `json:"deprecations"`
+		AlertMemory  *provider.MemoryUsage `json:"alertMemory,omitempty"`
 	}{
-		ConfigYAML: api.config.String(),
-		ConfigJSON: api.config,
+		ConfigYAML:   api.config.String(),
+		ConfigJSON:   api.config,
+		Deprecations: api.config.Deprecations(),
 		VersionInfo: map[string]string{
 			"version":   version.Version,
 			"revision":  version.Revision,
@@ -200,6 +562,10 @@ func (api *API) status(w http.ResponseWriter, req *http.Request) {
 		Uptime:     api.uptime,
 		MeshStatus: getMeshStatus(api),
 	}
+	if ma, ok := api.alerts.(provider.MemoryAccounter); ok {
+		mu := ma.MemoryUsage()
+		status.AlertMemory = &mu
+	}
 
 	api.mtx.RUnlock()
 
@@ -207,15 +573,26 @@ func (api *API) status(w http.ResponseWriter, req *http.Request) {
 }
 
 type meshStatus struct {
-	Name     string       `json:"name"`
-	NickName string       `json:"nickName"`
-	Peers    []peerStatus `json:"peers"`
+	Name         string              `json:"name"`
+	NickName     string              `json:"nickName"`
+	Peers        []peerStatus        `json:"peers"`
+	BlockedPeers []blockedPeerStatus `json:"blockedPeers"`
 }
 
 type peerStatus struct {
 	Name     string `json:"name"`     // e.g. "00:00:00:00:00:01"
 	NickName string `json:"nickName"` // e.g. "a"
 	UID      uint64 `json:"uid"`      // e.g. "14015114173033265000"
+
+	// The version information shared by the peer, if any.
+	Version    string   `json:"version,omitempty"`
+	Features   []string `json:"features,omitempty"`
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+type blockedPeerStatus struct {
+	Name  string    `json:"name"`
+	Until time.Time `json:"until"`
 }
 
 func getMeshStatus(api *API) *meshStatus {
@@ -231,11 +608,27 @@ func getMeshStatus(api *API) *meshStatus {
 	}
 
 	for i := 0; i < len(status.Peers); i++ {
-		strippedStatus.Peers[i] = peerStatus{
+		ps := peerStatus{
 			Name:     status.Peers[i].Name,
 			NickName: status.Peers[i].NickName,
 			UID:      uint64(status.Peers[i].UID),
 		}
+		if name, err := mesh.PeerNameFromString(ps.Name); err == nil {
+			if info, ok := api.peerVersions.Info(name); ok {
+				ps.Version = info.Version
+				ps.Features = info.Features
+				ps.Mismatches = api.peerVersions.Mismatches(name)
+			}
+		}
+		strippedStatus.Peers[i] = ps
+	}
+
+	strippedStatus.BlockedPeers = []blockedPeerStatus{}
+	for _, p := range api.blocklist.Blocked() {
+		strippedStatus.BlockedPeers = append(strippedStatus.BlockedPeers, blockedPeerStatus{
+			Name:  p.Name.String(),
+			Until: p.Until,
+		})
 	}
 
 	return strippedStatus
@@ -245,6 +638,8 @@ func (api *API) alertGroups(w http.ResponseWriter, r *http.Request) {
 	var err error
 	matchers := []*labels.Matcher{}
 
+	w.Header().Add("Vary", "Accept")
+
 	if filter := r.FormValue("filter"); filter != "" {
 		matchers, err = parse.Matchers(filter)
 		if err != nil {
@@ -256,11 +651,96 @@ func (api *API) alertGroups(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	groups := api.groups(matchers)
+	var pollTimeout time.Duration
+	if s := r.FormValue("pollTimeout"); s != "" {
+		if pollTimeout, err = time.ParseDuration(s); err != nil || pollTimeout < 0 {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid 'pollTimeout' parameter: %q", s),
+			}, nil)
+			return
+		}
+		if pollTimeout > maxGroupsPollTimeout {
+			pollTimeout = maxGroupsPollTimeout
+		}
+	}
+
+	groups, etag, err := api.groupsVersion(matchers)
+	if err == nil && pollTimeout > 0 {
+		// Clients pass the version of the groups they know from a previous
+		// response. Otherwise they are waiting for the current groups to
+		// change.
+		known := r.Header.Get("If-None-Match")
+		if known == "" {
+			known = etag
+		}
+		if etag == known {
+			groups, etag, err = api.waitGroups(r.Context().Done(), matchers, known, pollTimeout)
+		}
+	}
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
 
+	w.Header().Set("ETag", etag)
+	if acceptsProtobuf(r) {
+		api.respondProto(w, groupsToProto(groups))
+		return
+	}
 	api.respond(w, groups)
 }
 
+// groupsPollInterval is how often the alert groups are checked for changes
+// while a long-polling request is held open.
+var groupsPollInterval = time.Second
+
+// maxGroupsPollTimeout is the longest a long-polling request for alert
+// groups is held open.
+const maxGroupsPollTimeout = 5 * time.Minute
+
+// groupsVersion returns the alert groups matching the matchers along with
+// an entity tag identifying their state.
+func (api *API) groupsVersion(matchers []*labels.Matcher) (dispatch.AlertOverview, string, error) {
+	groups := api.groups(matchers)
+
+	b, err := json.Marshal(groups)
+	if err != nil {
+		return nil, "", err
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return groups, fmt.Sprintf("%q", fmt.Sprintf("%016x", h.Sum64())), nil
+}
+
+// waitGroups returns the alert groups once their entity tag differs from
+// the given one, the timeout elapsed or done is closed.
+func (api *API) waitGroups(done <-chan struct{}, matchers []*labels.Matcher, etag string, timeout time.Duration) (dispatch.AlertOverview, string, error) {
+	var (
+		timer  = time.NewTimer(timeout)
+		ticker = time.NewTicker(groupsPollInterval)
+	)
+	defer timer.Stop()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return api.groupsVersion(matchers)
+		case <-timer.C:
+			return api.groupsVersion(matchers)
+		case <-ticker.C:
+			groups, v, err := api.groupsVersion(matchers)
+			if err != nil || v != etag {
+				return groups, v, err
+			}
+		}
+	}
+}
+
 func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 	var (
 		err error
@@ -273,6 +753,8 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 		showInhibited = true
 	)
 
+	w.Header().Add("Vary", "Accept")
+
 	if filter := r.FormValue("filter"); filter != "" {
 		matchers, err = parse.Matchers(filter)
 		if err != nil {
@@ -339,8 +821,10 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 
 		routes := api.route.Match(a.Labels)
 		receivers := make([]string, 0, len(routes))
+		matched := make([]*dispatch.MatchedRoute, 0, len(routes))
 		for _, r := range routes {
 			receivers = append(receivers, r.RouteOpts.Receiver)
+			matched = append(matched, r.Matched())
 		}
 
 		if re != nil && !regexpAny(re, receivers) {
@@ -370,7 +854,9 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 			Alert:       &a.Alert,
 			Status:      status,
 			Receivers:   receivers,
+			Routes:      matched,
 			Fingerprint: a.Fingerprint().String(),
+			Enrichment:  a.Enrichment,
 		}
 
 		res = append(res, apiAlert)
@@ -386,89 +872,678 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 	sort.Slice(res, func(i, j int) bool {
 		return res[i].Fingerprint < res[j].Fingerprint
 	})
-	api.respond(w, res)
-}
-
-func regexpAny(re *regexp.Regexp, ss []string) bool {
-	for _, s := range ss {
-		if re.MatchString(s) {
-			return true
+	if acceptsProtobuf(r) {
+		pa := &apipb.Alerts{Alerts: make([]*apipb.Alert, 0, len(res))}
+		for _, a := range res {
+			pa.Alerts = append(pa.Alerts, alertToProto(a))
 		}
+		api.respondProto(w, pa)
+		return
 	}
-
-	return false
+	api.respond(w, res)
 }
 
-func alertMatchesFilterLabels(a *model.Alert, matchers []*labels.Matcher) bool {
-	for _, m := range matchers {
-		v, prs := a.Labels[model.LabelName(m.Name)]
-		switch m.Type {
-		case labels.MatchNotEqual, labels.MatchNotRegexp:
-			if !m.Matches(string(v)) {
-				return false
-			}
-		default:
-			if !prs || !m.Matches(string(v)) {
-				return false
-			}
-		}
-	}
-
-	return true
+// inhibitedAlert is an alert inhibited by the queried source alert.
+type inhibitedAlert struct {
+	*dispatch.APIAlert
+	// Sources are the fingerprints of all alerts inhibiting the alert,
+	// including the queried one. Alerts without other sources are
+	// notified about once the queried alert resolves.
+	Sources []string `json:"sources"`
 }
 
-func (api *API) legacyAddAlerts(w http.ResponseWriter, r *http.Request) {
-	var legacyAlerts = []struct {
-		Summary     model.LabelValue `json:"summary"`
-		Description model.LabelValue `json:"description"`
-		Runbook     model.LabelValue `json:"runbook"`
-		Labels      model.LabelSet   `json:"labels"`
-		Payload     model.LabelSet   `json:"payload"`
-	}{}
-	if err := api.receive(r, &legacyAlerts); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// inhibitedAlerts lists the alerts inhibited by the alert with the
+// fingerprint given by the source parameter.
+func (api *API) inhibitedAlerts(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.FingerprintFromString(r.FormValue("source"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid source fingerprint %q: %s", r.FormValue("source"), err),
+		}, nil)
 		return
 	}
-
-	var alerts []*types.Alert
-	for _, la := range legacyAlerts {
-		a := &types.Alert{
-			Alert: model.Alert{
-				Labels:      la.Labels,
-				Annotations: la.Payload,
-			},
-		}
-		if a.Annotations == nil {
-			a.Annotations = model.LabelSet{}
-		}
-		a.Annotations["summary"] = la.Summary
-		a.Annotations["description"] = la.Description
-		a.Annotations["runbook"] = la.Runbook
-
-		alerts = append(alerts, a)
+	res, err := api.inhibitedBy(fp)
+	if err == provider.ErrNotFound {
+		api.respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert %s not found", fp),
+		}, nil)
+		return
 	}
-
-	api.insertAlerts(w, r, alerts...)
-}
-
-func (api *API) addAlerts(w http.ResponseWriter, r *http.Request) {
-	var alerts []*types.Alert
-	if err := api.receive(r, &alerts); err != nil {
+	if err != nil {
 		api.respondError(w, apiError{
-			typ: errorBadData,
+			typ: errorInternal,
 			err: err,
 		}, nil)
 		return
 	}
-
-	api.insertAlerts(w, r, alerts...)
+	api.respond(w, res)
 }
 
-func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*types.Alert) {
-	now := time.Now()
-
-	for _, alert := range alerts {
+// inhibitedBy returns the active alerts that the active alert with the
+// given fingerprint inhibits according to the configured inhibit rules.
+func (api *API) inhibitedBy(fp model.Fingerprint) ([]*inhibitedAlert, error) {
+	source, err := api.alerts.Get(fp)
+	if err != nil {
+		return nil, err
+	}
+	res := []*inhibitedAlert{}
+	if source.Resolved() {
+		return res, nil
+	}
+
+	api.mtx.RLock()
+	var (
+		rules []*inhibit.InhibitRule
+		route = api.route
+	)
+	if api.config != nil {
+		for _, cr := range api.config.InhibitRules {
+			rules = append(rules, inhibit.NewInhibitRule(cr))
+		}
+	}
+	api.mtx.RUnlock()
+
+	inhibits := func(source, target model.LabelSet) bool {
+		for _, r := range rules {
+			if r.Inhibits(source, target) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var active []*types.Alert
+	alerts := api.alerts.GetPending()
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			alerts.Close()
+			return nil, err
+		}
+		if !a.Resolved() {
+			active = append(active, a)
+		}
+	}
+	alerts.Close()
+	if err := alerts.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range active {
+		if a.Fingerprint() == fp || !inhibits(source.Labels, a.Labels) {
+			continue
+		}
+		var sources []string
+		for _, s := range active {
+			if s.Fingerprint() != a.Fingerprint() && inhibits(s.Labels, a.Labels) {
+				sources = append(sources, s.Fingerprint().String())
+			}
+		}
+		sort.Strings(sources)
+
+		routes := route.Match(a.Labels)
+		receivers := make([]string, 0, len(routes))
+		matched := make([]*dispatch.MatchedRoute, 0, len(routes))
+		for _, r := range routes {
+			receivers = append(receivers, r.RouteOpts.Receiver)
+			matched = append(matched, r.Matched())
+		}
+		res = append(res, &inhibitedAlert{
+			APIAlert: &dispatch.APIAlert{
+				Alert:       &a.Alert,
+				Status:      api.getAlertStatus(a.Fingerprint()),
+				Receivers:   receivers,
+				Routes:      matched,
+				Fingerprint: a.Fingerprint().String(),
+			},
+			Sources: sources,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Fingerprint < res[j].Fingerprint
+	})
+	return res, nil
+}
+
+// resolveAlerts forcefully resolves all active alerts matching the given
+// filter. Stateful integrations are notified about them even if they do
+// not send resolved notifications otherwise.
+func (api *API) resolveAlerts(w http.ResponseWriter, r *http.Request) {
+	filter := r.FormValue("filter")
+	if filter == "" {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("parameter 'filter' is required"),
+		}, nil)
+		return
+	}
+	matchers, err := parse.Matchers(filter)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var (
+		now      = time.Now()
+		resolved []*types.Alert
+		// Initialize result slice to prevent api returning `null` when no
+		// alerts were resolved.
+		res = []string{}
+	)
+
+	alerts := api.alerts.GetPending()
+	defer alerts.Close()
+
+	for a := range alerts.Next() {
+		if err = alerts.Err(); err != nil {
+			break
+		}
+		if a.Resolved() || !alertMatchesFilterLabels(&a.Alert, matchers) {
+			continue
+		}
+		ra := *a
+		ra.EndsAt = now
+		ra.UpdatedAt = now
+		ra.Timeout = false
+		ra.ForceResolved = true
+
+		resolved = append(resolved, &ra)
+		res = append(res, a.Fingerprint().String())
+	}
+	if err == nil {
+		err = api.alerts.Put(resolved...)
+	}
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	sort.Strings(res)
+	audit.Summarize(r, "filter=%q resolved=%d", filter, len(res))
+
+	api.respond(w, res)
+}
+
+// alertCardinality returns the labels with the most distinct values among
+// the active alerts of an alert name.
+func (api *API) alertCardinality(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid 'limit' parameter: %q", s),
+			}, nil)
+			return
+		}
+	}
+	res, err := provider.TopLabelCardinality(api.alerts, limit)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, res)
+}
+
+func (api *API) auditEntries(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.FormValue("since"); s != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid 'since' parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+	api.respond(w, api.audit.Entries(since))
+}
+
+func (api *API) silenceEvents(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.FormValue("since"); s != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid 'since' parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+	api.respond(w, api.audit.SilenceEvents(r.FormValue("id"), since))
+}
+
+// recordSilence adds an event for the change of the silence made by the
+// request to the audit log.
+func (api *API) recordSilence(r *http.Request, action, prevID string, psil *silencepb.Silence) {
+	e := &audit.SilenceEvent{
+		Action:     action,
+		SilenceID:  psil.Id,
+		PreviousID: prevID,
+		StartsAt:   psil.StartsAt,
+		EndsAt:     psil.EndsAt,
+		CreatedBy:  psil.CreatedBy,
+		Comment:    psil.Comment,
+	}
+	if sil, err := silenceFromProto(psil); err == nil {
+		e.Matchers = sil.Matchers.String()
+	}
+	if err := api.audit.RecordSilence(r, e); err != nil {
+		level.Error(api.logger).Log("msg", "Recording silence event failed", "silence", psil.Id, "err", err)
+	}
+}
+
+type nflogReceiver struct {
+	GroupName   string `json:"groupName"`
+	Integration string `json:"integration"`
+	Idx         uint32 `json:"idx"`
+	ConfigHash  string `json:"configHash,omitempty"`
+}
+
+type nflogEntry struct {
+	Receiver       nflogReceiver `json:"receiver"`
+	GroupKey       string        `json:"groupKey"`
+	Resolved       bool          `json:"resolved"`
+	FiringAlerts   []uint64      `json:"firingAlerts"`
+	ResolvedAlerts []uint64      `json:"resolvedAlerts"`
+	Timestamp      time.Time     `json:"timestamp"`
+	ProviderID     string        `json:"providerId,omitempty"`
+	AcknowledgedAt *time.Time    `json:"acknowledgedAt,omitempty"`
+}
+
+func newNflogEntry(e *nflogpb.Entry) *nflogEntry {
+	return &nflogEntry{
+		Receiver: nflogReceiver{
+			GroupName:   e.Receiver.GroupName,
+			Integration: e.Receiver.Integration,
+			Idx:         e.Receiver.Idx,
+			ConfigHash:  e.Receiver.ConfigHash,
+		},
+		GroupKey:       string(e.GroupKey),
+		Resolved:       len(e.FiringAlerts) == 0,
+		FiringAlerts:   e.FiringAlerts,
+		ResolvedAlerts: e.ResolvedAlerts,
+		Timestamp:      e.Timestamp,
+		ProviderID:     e.ProviderId,
+		AcknowledgedAt: e.AcknowledgedAt,
+	}
+}
+
+// listNflog returns the current notification log entries ordered by group
+// key and receiver. They can be filtered by group key and paginated.
+func (api *API) listNflog(w http.ResponseWriter, r *http.Request) {
+	var params []nflog.QueryParam
+	if gk := r.FormValue("groupKey"); gk != "" {
+		params = append(params, nflog.QGroupKey(gk))
+	}
+	if recv := r.FormValue("receiver"); recv != "" {
+		params = append(params, nflog.QReceiverName(recv))
+	}
+	for _, p := range []struct {
+		name string
+		qp   func(int) nflog.QueryParam
+	}{
+		{"offset", nflog.QOffset},
+		{"limit", nflog.QLimit},
+	} {
+		s := r.FormValue(p.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid '%s' parameter: %q", p.name, s),
+			}, nil)
+			return
+		}
+		params = append(params, p.qp(n))
+	}
+
+	it, err := api.nflog.QueryIter(params...)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	res := []*nflogEntry{}
+	for it.Next() {
+		res = append(res, newNflogEntry(it.At()))
+	}
+	api.respond(w, res)
+}
+
+type nflogStats struct {
+	Receiver    string `json:"receiver"`
+	Integration string `json:"integration"`
+	LastHour    uint64 `json:"lastHour"`
+	LastDay     uint64 `json:"lastDay"`
+}
+
+// nflogStats returns the number of notifications sent by this Alertmanager
+// per receiver integration within the last hour and day.
+func (api *API) nflogStats(w http.ResponseWriter, r *http.Request) {
+	res := []*nflogStats{}
+	for _, s := range api.nflog.Stats() {
+		if rcv := r.FormValue("receiver"); rcv != "" && s.Receiver != rcv {
+			continue
+		}
+		res = append(res, &nflogStats{
+			Receiver:    s.Receiver,
+			Integration: s.Integration,
+			LastHour:    s.LastHour,
+			LastDay:     s.LastDay,
+		})
+	}
+	api.respond(w, res)
+}
+
+// apiState is the state of an Alertmanager as exported for backups.
+type apiState struct {
+	Silences        []*silencepb.MeshSilence `json:"silences"`
+	NotificationLog []*nflogpb.MeshEntry     `json:"notificationLog"`
+	Alerts          []*model.Alert           `json:"alerts,omitempty"`
+}
+
+// exportState returns the silences, notification log entries and active
+// alerts of the instance.
+func (api *API) exportState(w http.ResponseWriter, r *http.Request) {
+	state := apiState{
+		Silences:        api.silences.MeshSilences(),
+		NotificationLog: api.nflog.View().MeshEntries(),
+		Alerts:          []*model.Alert{},
+	}
+
+	alerts := api.alerts.GetPending()
+	defer alerts.Close()
+
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			api.respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		if !a.Resolved() {
+			state.Alerts = append(state.Alerts, &a.Alert)
+		}
+	}
+	api.respond(w, state)
+}
+
+// importState merges exported silences and notification log entries into
+// the state. Alerts are restored through the alerts endpoint so that they
+// are validated like alerts sent by clients.
+func (api *API) importState(w http.ResponseWriter, r *http.Request) {
+	var state apiState
+	if err := api.receive(r, &state); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	audit.Summarize(r, "silences=%d notificationLog=%d", len(state.Silences), len(state.NotificationLog))
+
+	nsil, err := api.silences.Merge(state.Silences...)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	nlog, err := api.nflog.Merge(state.NotificationLog...)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.respond(w, struct {
+		Silences        int `json:"silences"`
+		NotificationLog int `json:"notificationLog"`
+	}{
+		Silences:        nsil,
+		NotificationLog: nlog,
+	})
+}
+
+// gc immediately garbage collects expired silences and notification log
+// entries instead of waiting for the next maintenance run.
+func (api *API) gc(w http.ResponseWriter, r *http.Request) {
+	nsil, err := api.silences.GC()
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	nlog, err := api.nflog.GC()
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	audit.Summarize(r, "silences=%d notificationLog=%d", nsil, nlog)
+
+	api.respond(w, struct {
+		Silences        int `json:"silences"`
+		NotificationLog int `json:"notificationLog"`
+	}{
+		Silences:        nsil,
+		NotificationLog: nlog,
+	})
+}
+
+// deleteNflog deletes the notification log entry for the group key and
+// receiver integration given by the groupKey, receiver, integration, idx
+// and configHash parameters, so that the group is notified about again on
+// its next flush.
+func (api *API) deleteNflog(w http.ResponseWriter, r *http.Request) {
+	gk := r.FormValue("groupKey")
+	recv := &nflogpb.Receiver{
+		GroupName:   r.FormValue("receiver"),
+		Integration: r.FormValue("integration"),
+		ConfigHash:  r.FormValue("configHash"),
+	}
+	if gk == "" || recv.GroupName == "" || recv.Integration == "" {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("groupKey, receiver and integration parameters must be given"),
+		}, nil)
+		return
+	}
+	if s := r.FormValue("idx"); s != "" {
+		idx, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid 'idx' parameter: %q", s),
+			}, nil)
+			return
+		}
+		recv.Idx = uint32(idx)
+	}
+	audit.Summarize(r, "groupKey=%q receiver=%q integration=%q idx=%d", gk, recv.GroupName, recv.Integration, recv.Idx)
+
+	if err := api.nflog.Delete(recv, []byte(gk)); err != nil {
+		apiErr := apiError{typ: errorBadData, err: err}
+		if err == nflog.ErrNotFound {
+			apiErr.typ = errorNotFound
+			apiErr.err = fmt.Errorf("no notification log entry for group %q and receiver %s/%s/%d", gk, recv.GroupName, recv.Integration, recv.Idx)
+		}
+		api.respondError(w, apiErr, nil)
+		return
+	}
+	api.respond(w, nil)
+}
+
+// acknowledge records the acknowledgements of notifications posted by the
+// webhooks of the provider of the integration. It returns the entries of
+// the notifications acknowledged for the first time.
+func (api *API) acknowledge(w http.ResponseWriter, r *http.Request) {
+	integration := route.Param(r.Context(), "integration")
+
+	acks, err := notify.ParseAcknowledgements(integration, r.Body, time.Now())
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	entries, err := notify.Acknowledge(api.nflog, integration, acks)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	audit.Summarize(r, "integration=%q acknowledgements=%d notifications=%d", integration, len(acks), len(entries))
+
+	res := []*nflogEntry{}
+	for _, e := range entries {
+		res = append(res, newNflogEntry(e))
+	}
+	api.respond(w, res)
+}
+
+func regexpAny(re *regexp.Regexp, ss []string) bool {
+	for _, s := range ss {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func alertMatchesFilterLabels(a *model.Alert, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		v, prs := a.Labels[model.LabelName(m.Name)]
+		switch m.Type {
+		case labels.MatchNotEqual, labels.MatchNotRegexp:
+			if !m.Matches(string(v)) {
+				return false
+			}
+		default:
+			if !prs || !m.Matches(string(v)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (api *API) legacyAddAlerts(w http.ResponseWriter, r *http.Request) {
+	var legacyAlerts = []struct {
+		Summary     model.LabelValue `json:"summary"`
+		Description model.LabelValue `json:"description"`
+		Runbook     model.LabelValue `json:"runbook"`
+		Labels      model.LabelSet   `json:"labels"`
+		Payload     model.LabelSet   `json:"payload"`
+	}{}
+	if err := api.receive(r, &legacyAlerts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var alerts []*types.Alert
+	for _, la := range legacyAlerts {
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:      la.Labels,
+				Annotations: la.Payload,
+			},
+		}
+		if a.Annotations == nil {
+			a.Annotations = model.LabelSet{}
+		}
+		a.Annotations["summary"] = la.Summary
+		a.Annotations["description"] = la.Description
+		a.Annotations["runbook"] = la.Runbook
+
+		alerts = append(alerts, a)
+	}
+
+	api.insertAlerts(w, r, alerts, nil)
+}
+
+// alertError is the reason an alert at an index of a posted batch was
+// rejected.
+type alertError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+func (api *API) addAlerts(w http.ResponseWriter, r *http.Request) {
+	// Alerts are decoded one by one so that a malformed alert only
+	// rejects itself and not the whole batch.
+	var raw []json.RawMessage
+	if err := api.receive(r, &raw); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var (
+		alerts = make([]*types.Alert, len(raw))
+		errs   []*alertError
+	)
+	for i, b := range raw {
+		var a types.Alert
+		if err := json.Unmarshal(b, &a); err != nil {
+			errs = append(errs, &alertError{Index: i, Error: err.Error()})
+			continue
+		}
+		alerts[i] = &a
+	}
+
+	api.insertAlerts(w, r, alerts, errs)
+}
+
+// insertAlerts inserts all valid alerts. Nil alerts were already rejected
+// with one of the given errors. If any alert is invalid, the errors are
+// reported by index in the response data.
+func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts []*types.Alert, errs []*alertError) {
+	now := time.Now()
+
+	audit.Summarize(r, "alerts=%d", len(alerts))
+
+	tp := traceParent(r)
+	for _, alert := range alerts {
+		if alert == nil {
+			continue
+		}
 		alert.UpdatedAt = now
+		alert.TraceParent = tp
 
 		// Ensure StartsAt is set.
 		if alert.StartsAt.IsZero() {
@@ -480,53 +1555,255 @@ func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*
 			alert.Timeout = true
 			alert.EndsAt = now.Add(api.resolveTimeout)
 
-			numReceivedAlerts.WithLabelValues("firing").Inc()
-		} else {
-			numReceivedAlerts.WithLabelValues("resolved").Inc()
-		}
+			numReceivedAlerts.WithLabelValues("firing").Inc()
+		} else {
+			numReceivedAlerts.WithLabelValues("resolved").Inc()
+		}
+	}
+
+	// Make a best effort to insert all alerts that are valid.
+	validAlerts := make([]*types.Alert, 0, len(alerts))
+	for i, a := range alerts {
+		if a == nil {
+			continue
+		}
+		if err := a.Validate(); err != nil {
+			errs = append(errs, &alertError{Index: i, Error: err.Error()})
+			continue
+		}
+		validAlerts = append(validAlerts, a)
+	}
+	if err := api.alerts.Put(validAlerts...); err != nil {
+		var typ errorType = errorInternal
+		if merr, ok := err.(*provider.MemoryLimitError); ok {
+			// Alerts not fitting into the budget at all are never going
+			// to be accepted, others may be once memory is released.
+			typ = errorLimitExceeded
+			if merr.Required > merr.Usage.Limit {
+				typ = errorTooLarge
+			}
+		}
+		api.respondError(w, apiError{
+			typ: typ,
+			err: err,
+		}, nil)
+		return
+	}
+	if api.labels != nil {
+		api.labels.Observe(validAlerts...)
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+
+		validationErrs := &types.MultiError{}
+		for _, e := range errs {
+			validationErrs.Add(fmt.Errorf("alert %d: %s", e.Index, e.Error))
+		}
+		numInvalidAlerts.Add(float64(len(errs)))
+
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: validationErrs,
+		}, errs)
+		return
+	}
+
+	api.respond(w, nil)
+}
+
+// enrichmentSource returns the configured alert enrichment and the name of
+// the source authenticated by the bearer token of the request. The name is
+// empty if no source matches.
+func (api *API) enrichmentSource(r *http.Request) (*config.AlertEnrichment, string) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if api.config == nil || api.config.AlertEnrichment == nil {
+		return nil, ""
+	}
+	conf := api.config.AlertEnrichment
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return conf, ""
+	}
+	token := []byte(strings.TrimPrefix(auth, "Bearer "))
+	for _, s := range conf.Sources {
+		if subtle.ConstantTimeCompare(token, []byte(s.Token)) == 1 {
+			return conf, s.Name
+		}
+	}
+	return conf, ""
+}
+
+// annotateAlert merges the annotations of the request into the ones
+// attached to an existing alert. Annotations with empty values are removed.
+// The vendored router does not support PATCH, so the endpoint uses POST.
+func (api *API) annotateAlert(w http.ResponseWriter, r *http.Request) {
+	conf, source := api.enrichmentSource(r)
+	if conf == nil {
+		api.respondError(w, apiError{
+			typ: errorNotFound,
+			err: fmt.Errorf("alert enrichment is not configured"),
+		}, nil)
+		return
+	}
+	if source == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		api.respondError(w, apiError{
+			typ: errorUnauthorized,
+			err: fmt.Errorf("missing or invalid bearer token"),
+		}, nil)
+		return
+	}
+
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
 	}
 
-	// Make a best effort to insert all alerts that are valid.
-	var (
-		validAlerts    = make([]*types.Alert, 0, len(alerts))
-		validationErrs = &types.MultiError{}
-	)
-	for _, a := range alerts {
-		if err := a.Validate(); err != nil {
-			validationErrs.Add(err)
-			numInvalidAlerts.Inc()
-			continue
-		}
-		validAlerts = append(validAlerts, a)
+	var req struct {
+		Annotations model.LabelSet `json:"annotations"`
 	}
-	if err := api.alerts.Put(validAlerts...); err != nil {
+	if err := api.receive(r, &req); err != nil {
 		api.respondError(w, apiError{
-			typ: errorInternal,
+			typ: errorBadData,
 			err: err,
 		}, nil)
 		return
 	}
-
-	if validationErrs.Len() > 0 {
+	if len(req.Annotations) == 0 {
 		api.respondError(w, apiError{
 			typ: errorBadData,
-			err: validationErrs,
+			err: fmt.Errorf("no annotations to attach"),
+		}, nil)
+		return
+	}
+	names := make([]string, 0, len(req.Annotations))
+	for ln, lv := range req.Annotations {
+		if !ln.IsValid() {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid annotation name %q", ln),
+			}, nil)
+			return
+		}
+		if !lv.IsValid() {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid annotation value %q", lv),
+			}, nil)
+			return
+		}
+		names = append(names, string(ln))
+	}
+	sort.Strings(names)
+	audit.Summarize(r, "fingerprint=%s source=%q annotations=%q", fp, source, names)
+
+	alert, err := api.alerts.Get(fp)
+	if err != nil {
+		apiErr := apiError{typ: errorInternal, err: err}
+		if err == provider.ErrNotFound {
+			apiErr = apiError{
+				typ: errorNotFound,
+				err: fmt.Errorf("alert %s not found", fp),
+			}
+		}
+		api.respondError(w, apiErr, nil)
+		return
+	}
+
+	alert = alert.Enrich(source, req.Annotations)
+	if size := alert.Enrichment.Size(); size > conf.MaxSize {
+		api.respondError(w, apiError{
+			typ: errorTooLarge,
+			err: fmt.Errorf("attached annotations of %d bytes exceed the limit of %d bytes", size, conf.MaxSize),
 		}, nil)
 		return
 	}
+	alert.UpdatedAt = time.Now()
 
-	api.respond(w, nil)
+	if err := api.alerts.Put(alert); err != nil {
+		apiErr := apiError{typ: errorInternal, err: err}
+		if _, ok := err.(*provider.MemoryLimitError); ok {
+			apiErr.typ = errorLimitExceeded
+		}
+		api.respondError(w, apiErr, nil)
+		return
+	}
+
+	api.respond(w, struct {
+		Annotations model.LabelSet    `json:"annotations"`
+		Enrichment  *types.Enrichment `json:"enrichment"`
+	}{
+		Annotations: alert.Annotations,
+		Enrichment:  alert.Enrichment,
+	})
+}
+
+// traceParentRE matches W3C traceparent headers of version 00.
+var traceParentRE = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// traceParent returns the trace context the request was made in. Invalid
+// trace contexts are ignored.
+func traceParent(r *http.Request) string {
+	tp := strings.TrimSpace(r.Header.Get("traceparent"))
+	m := traceParentRE.FindStringSubmatch(tp)
+	if m == nil || strings.Trim(m[1], "0") == "" || strings.Trim(m[2], "0") == "" {
+		return ""
+	}
+	return tp
 }
 
 func (api *API) setSilence(w http.ResponseWriter, r *http.Request) {
-	var sil types.Silence
-	if err := api.receive(r, &sil); err != nil {
+	var req struct {
+		types.Silence
+		// MatcherExpression holds the matchers of the silence as an
+		// expression such as {job="api"}. It takes precedence over the
+		// matchers, which clients may send along for older servers.
+		MatcherExpression string `json:"matcherExpression,omitempty"`
+	}
+	if err := api.receive(r, &req); err != nil {
 		api.respondError(w, apiError{
 			typ: errorBadData,
 			err: err,
 		}, nil)
 		return
 	}
+	sil := req.Silence
+	if req.MatcherExpression != "" {
+		ms, err := parse.SilenceMatchers(req.MatcherExpression)
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid matcher expression %q: %s", req.MatcherExpression, err),
+			}, nil)
+			return
+		}
+		sil.Matchers = ms
+	}
+	var warnings []string
+	if policy := api.silencePolicy(); policy != nil {
+		if latest, ok := latestSilenceEnd(policy, sil.StartsAt, time.Now()); ok && sil.EndsAt.After(latest) {
+			if policy.Action != config.SilencePolicyClamp {
+				api.respondError(w, apiError{
+					typ: errorBadData,
+					err: fmt.Errorf("silence must not last longer than %s", policy.MaxDuration),
+				}, nil)
+				return
+			}
+			warnings = append(warnings, fmt.Sprintf(
+				"silence ends at %s instead of %s as it must not last longer than %s",
+				latest.Format(time.RFC3339), sil.EndsAt.Format(time.RFC3339), policy.MaxDuration,
+			))
+			sil.EndsAt = latest
+		}
+	}
 	psil, err := silenceToProto(&sil)
 	if err != nil {
 		api.respondError(w, apiError{
@@ -535,6 +1812,10 @@ func (api *API) setSilence(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	psil.Principal = api.audit.Principal(r)
+	psil.Source = r.UserAgent()
+
+	audit.Summarize(r, "id=%q matchers=%s startsAt=%s endsAt=%s createdBy=%q", sil.ID, sil.Matchers, sil.StartsAt.Format(time.RFC3339), sil.EndsAt.Format(time.RFC3339), sil.CreatedBy)
 
 	sid, err := api.silences.Set(psil)
 	if err != nil {
@@ -544,14 +1825,68 @@ func (api *API) setSilence(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	switch sil.ID {
+	case "":
+		api.recordSilence(r, audit.SilenceCreate, "", psil)
+	case sid:
+		api.recordSilence(r, audit.SilenceUpdate, "", psil)
+	default:
+		api.recordSilence(r, audit.SilenceUpdate, sil.ID, psil)
+	}
 
 	api.respond(w, struct {
-		SilenceID string `json:"silenceId"`
+		SilenceID string   `json:"silenceId"`
+		Warnings  []string `json:"warnings,omitempty"`
 	}{
 		SilenceID: sid,
+		Warnings:  append(warnings, api.matcherWarnings(sil.Matchers)...),
 	})
 }
 
+// silencePolicy returns the configured silence policy, which may be nil.
+func (api *API) silencePolicy() *config.SilencePolicy {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if api.config == nil {
+		return nil
+	}
+	return api.config.SilencePolicy
+}
+
+// latestSilenceEnd returns the latest time a silence starting at the given
+// time may end at under the policy. Silences that started already may last
+// the maximum duration from now on. It returns false if silences may last
+// indefinitely.
+func latestSilenceEnd(p *config.SilencePolicy, startsAt, now time.Time) (time.Time, bool) {
+	if p == nil || p.MaxDuration == 0 {
+		return time.Time{}, false
+	}
+	if startsAt.Before(now) {
+		startsAt = now
+	}
+	return startsAt.Add(time.Duration(p.MaxDuration)), true
+}
+
+// matcherWarnings returns a warning for each matcher referencing a label
+// name that did not occur on any recently received alert, which is likely
+// a typo. No warnings are returned if no label catalog is configured.
+func (api *API) matcherWarnings(ms types.Matchers) []string {
+	if api.labels == nil {
+		return nil
+	}
+	var warnings []string
+	for _, m := range ms {
+		if !api.labels.Contains(model.LabelName(m.Name)) {
+			warnings = append(warnings, fmt.Sprintf(
+				"matcher %s references label %q, which no alert received in the last %s had",
+				m, m.Name, model.Duration(api.labels.Retention()),
+			))
+		}
+	}
+	return warnings
+}
+
 func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
 	sid := route.Param(r.Context(), "sid")
 
@@ -575,6 +1910,31 @@ func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
 func (api *API) delSilence(w http.ResponseWriter, r *http.Request) {
 	sid := route.Param(r.Context(), "sid")
 
+	if dryRun := r.FormValue("dryRun"); dryRun != "" {
+		if dryRun != "true" && dryRun != "false" {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("parameter 'dryRun' can either be 'true' or 'false', not '%v'", dryRun),
+			}, nil)
+			return
+		}
+		if dryRun == "true" {
+			audit.Summarize(r, "id=%q dryRun=true", sid)
+
+			res, err := api.unsilencedBy(sid)
+			if err != nil {
+				api.respondError(w, apiError{
+					typ: errorBadData,
+					err: err,
+				}, nil)
+				return
+			}
+			api.respond(w, res)
+			return
+		}
+	}
+	audit.Summarize(r, "id=%q", sid)
+
 	if err := api.silences.Expire(sid); err != nil {
 		api.respondError(w, apiError{
 			typ: errorBadData,
@@ -582,11 +1942,177 @@ func (api *API) delSilence(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
+	if sils, err := api.silences.Query(silence.QIDs(sid)); err == nil && len(sils) == 1 {
+		api.recordSilence(r, audit.SilenceExpire, "", sils[0])
+	}
 	api.respond(w, nil)
 }
 
+// unsilencedBy returns the active alerts that would be notified about if the
+// silence with the given ID was expired, i.e. the alerts that it silences
+// and that are neither silenced by another silence nor inhibited.
+func (api *API) unsilencedBy(sid string) ([]*dispatch.APIAlert, error) {
+	res := []*dispatch.APIAlert{}
+
+	if sils, err := api.silences.Query(silence.QIDs(sid)); err != nil {
+		return nil, err
+	} else if len(sils) == 0 {
+		return nil, silence.ErrNotFound
+	}
+	if active, err := api.silences.Query(silence.QIDs(sid), silence.QState(silence.StateActive)); err != nil || len(active) == 0 {
+		return res, err
+	}
+
+	alerts := api.alerts.GetPending()
+	defer alerts.Close()
+
+	now := time.Now()
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			return nil, err
+		}
+		if !a.EndsAt.IsZero() && a.EndsAt.Before(now) {
+			continue
+		}
+		muting, err := api.silences.Query(silence.QState(silence.StateActive), silence.QMatches(a.Labels))
+		if err != nil {
+			return nil, err
+		}
+		if len(muting) != 1 || muting[0].Id != sid {
+			continue
+		}
+		status := api.getAlertStatus(a.Fingerprint())
+		if len(status.InhibitedBy) > 0 {
+			continue
+		}
+
+		routes := api.route.Match(a.Labels)
+		receivers := make([]string, 0, len(routes))
+		matched := make([]*dispatch.MatchedRoute, 0, len(routes))
+		for _, r := range routes {
+			receivers = append(receivers, r.RouteOpts.Receiver)
+			matched = append(matched, r.Matched())
+		}
+		res = append(res, &dispatch.APIAlert{
+			Alert:       &a.Alert,
+			Status:      status,
+			Receivers:   receivers,
+			Routes:      matched,
+			Fingerprint: a.Fingerprint().String(),
+		})
+	}
+	if err := alerts.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Fingerprint < res[j].Fingerprint
+	})
+	return res, nil
+}
+
+func (api *API) extendSilence(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(r.Context(), "sid")
+
+	var req struct {
+		Duration string `json:"duration"`
+		// UpdatedAt optionally holds the modification time of the silence
+		// the extension is based on.
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+	if err := api.receive(r, &req); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	audit.Summarize(r, "id=%q duration=%s updatedAt=%s", sid, d, req.UpdatedAt.Format(time.RFC3339Nano))
+
+	var limit silence.DurationLimit
+	if policy := api.silencePolicy(); policy != nil {
+		limit.Max = time.Duration(policy.MaxDuration)
+		limit.Clamp = policy.Action == config.SilencePolicyClamp
+	}
+
+	psil, err := api.silences.Extend(sid, d, req.UpdatedAt, limit)
+	if err != nil {
+		apiErr := apiError{typ: errorBadData, err: err}
+		switch err {
+		case silence.ErrNotFound:
+			apiErr.typ = errorNotFound
+		case silence.ErrConflict:
+			apiErr.typ = errorConflict
+		}
+		api.respondError(w, apiErr, nil)
+		return
+	}
+	api.recordSilence(r, audit.SilenceUpdate, "", psil)
+	sil, err := silenceFromProto(psil)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.respond(w, sil)
+}
+
+func (api *API) transferSilence(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(r.Context(), "sid")
+
+	var req struct {
+		Team string `json:"team"`
+		// UpdatedAt optionally holds the modification time of the silence
+		// the transfer is based on.
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+	if err := api.receive(r, &req); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	audit.Summarize(r, "id=%q team=%q updatedAt=%s", sid, req.Team, req.UpdatedAt.Format(time.RFC3339Nano))
+
+	psil, err := api.silences.Transfer(sid, req.Team, req.UpdatedAt)
+	if err != nil {
+		apiErr := apiError{typ: errorBadData, err: err}
+		if err == silence.ErrConflict {
+			apiErr.typ = errorConflict
+		}
+		api.respondError(w, apiErr, nil)
+		return
+	}
+	api.recordSilence(r, audit.SilenceUpdate, "", psil)
+	sil, err := silenceFromProto(psil)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.respond(w, sil)
+}
+
 func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
-	psils, err := api.silences.Query()
+	var params []silence.QueryParam
+	if team := r.FormValue("team"); team != "" {
+		params = append(params, silence.QTeam(team))
+	}
+	psils, err := api.silences.Query(params...)
 	if err != nil {
 		api.respondError(w, apiError{
 			typ: errorInternal,
@@ -610,6 +2136,12 @@ func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
 	sils := []*types.Silence{}
 	for _, ps := range psils {
 		s, err := silenceFromProto(ps)
+		if err == silence.ErrUnsupportedMatcher {
+			// Silences created by newer peers are retained but cannot
+			// be represented.
+			level.Debug(api.logger).Log("msg", "Skipping silence with unsupported matcher", "id", ps.Id)
+			continue
+		}
 		if err != nil {
 			api.respondError(w, apiError{
 				typ: errorInternal,
@@ -676,6 +2208,7 @@ func silenceToProto(s *types.Silence) (*silencepb.Silence, error) {
 		UpdatedAt: s.UpdatedAt,
 		Comment:   s.Comment,
 		CreatedBy: s.CreatedBy,
+		Team:      s.Team,
 	}
 	for _, m := range s.Matchers {
 		matcher := &silencepb.Matcher{
@@ -683,14 +2216,77 @@ func silenceToProto(s *types.Silence) (*silencepb.Silence, error) {
 			Pattern: m.Value,
 			Type:    silencepb.Matcher_EQUAL,
 		}
-		if m.IsRegex {
+		switch {
+		case m.IsRegex && m.IsNegative:
+			matcher.Type = silencepb.Matcher_NOT_REGEXP
+		case m.IsRegex:
 			matcher.Type = silencepb.Matcher_REGEXP
+		case m.IsNegative:
+			matcher.Type = silencepb.Matcher_NOT_EQUAL
 		}
 		sil.Matchers = append(sil.Matchers, matcher)
 	}
+	if s.Recurrence != nil {
+		r, err := recurrenceToProto(s.Recurrence)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence: %s", err)
+		}
+		sil.Recurrence = r
+	}
 	return sil, nil
 }
 
+func recurrenceToProto(r *types.Recurrence) (*silencepb.Recurrence, error) {
+	var res silencepb.Recurrence
+	for _, s := range r.Weekdays {
+		wd, ok := weekdays[strings.ToLower(s)]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", s)
+		}
+		res.Weekdays |= 1 << uint(wd)
+	}
+	start, err := time.Parse("15:04", r.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time %q", r.StartTime)
+	}
+	res.StartMinute = uint32(start.Hour()*60 + start.Minute())
+
+	d, err := model.ParseDuration(r.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q: %s", r.Duration, err)
+	}
+	if time.Duration(d)%time.Minute != 0 {
+		return nil, fmt.Errorf("duration %q is not a multiple of a minute", r.Duration)
+	}
+	res.DurationMinutes = uint32(time.Duration(d) / time.Minute)
+	res.Location = r.Location
+
+	return &res, nil
+}
+
+// weekdays maps the lowercase names of weekdays to them.
+var weekdays = func() map[string]time.Weekday {
+	m := map[string]time.Weekday{}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		m[strings.ToLower(wd.String())] = wd
+	}
+	return m
+}()
+
+func recurrenceFromProto(r *silencepb.Recurrence) *types.Recurrence {
+	res := &types.Recurrence{
+		StartTime: fmt.Sprintf("%02d:%02d", r.StartMinute/60, r.StartMinute%60),
+		Duration:  model.Duration(time.Duration(r.DurationMinutes) * time.Minute).String(),
+		Location:  r.Location,
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if r.Weekdays&(1<<uint(wd)) != 0 {
+			res.Weekdays = append(res.Weekdays, strings.ToLower(wd.String()))
+		}
+	}
+	return res
+}
+
 func silenceFromProto(s *silencepb.Silence) (*types.Silence, error) {
 	sil := &types.Silence{
 		ID:        s.Id,
@@ -702,6 +2298,9 @@ func silenceFromProto(s *silencepb.Silence) (*types.Silence, error) {
 		},
 		Comment:   s.Comment,
 		CreatedBy: s.CreatedBy,
+		Principal: s.Principal,
+		Source:    s.Source,
+		Team:      s.Team,
 	}
 	for _, m := range s.Matchers {
 		matcher := &types.Matcher{
@@ -712,11 +2311,21 @@ func silenceFromProto(s *silencepb.Silence) (*types.Silence, error) {
 		case silencepb.Matcher_EQUAL:
 		case silencepb.Matcher_REGEXP:
 			matcher.IsRegex = true
+		case silencepb.Matcher_NOT_EQUAL:
+			matcher.IsNegative = true
+		case silencepb.Matcher_NOT_REGEXP:
+			matcher.IsRegex = true
+			matcher.IsNegative = true
 		default:
-			return nil, fmt.Errorf("unknown matcher type")
+			return nil, silence.ErrUnsupportedMatcher
 		}
 		sil.Matchers = append(sil.Matchers, matcher)
 	}
+	if s.Recurrence != nil {
+		sil.Recurrence = recurrenceFromProto(s.Recurrence)
+		// Recurring silences are pending between their windows.
+		sil.Status.State = types.SilenceState(silence.State(s, time.Now()))
+	}
 
 	return sil, nil
 }
@@ -750,14 +2359,132 @@ func (api *API) respond(w http.ResponseWriter, data interface{}) {
 	w.Write(b)
 }
 
+// respondProto writes the protobuf encoding of m. Unlike JSON responses it
+// is not wrapped into a response object. Errors are still returned as JSON.
+func (api *API) respondProto(w http.ResponseWriter, m proto.Message) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		level.Error(api.logger).Log("msg", "Error marshalling protobuf", "err", err)
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	w.Header().Set("Content-Type", protobufContentType)
+	w.WriteHeader(200)
+	w.Write(b)
+}
+
+func labelSetToProto(ls model.LabelSet) map[string]string {
+	res := make(map[string]string, len(ls))
+	for ln, lv := range ls {
+		res[string(ln)] = string(lv)
+	}
+	return res
+}
+
+func alertToProto(a *dispatch.APIAlert) *apipb.Alert {
+	pa := &apipb.Alert{
+		Labels:       labelSetToProto(a.Labels),
+		Annotations:  labelSetToProto(a.Annotations),
+		StartsAt:     a.StartsAt,
+		EndsAt:       a.EndsAt,
+		GeneratorUrl: a.GeneratorURL,
+		Status: apipb.AlertStatus{
+			State:       string(a.Status.State),
+			SilencedBy:  a.Status.SilencedBy,
+			InhibitedBy: a.Status.InhibitedBy,
+			FlapScore:   int64(a.Status.FlapScore),
+		},
+		Receivers:   a.Receivers,
+		Fingerprint: a.Fingerprint,
+	}
+	for _, r := range a.Routes {
+		mr := &apipb.MatchedRoute{Receiver: r.Receiver}
+		for _, i := range r.Path {
+			mr.Path = append(mr.Path, int64(i))
+		}
+		pa.Routes = append(pa.Routes, mr)
+	}
+	if e := a.Enrichment; e != nil {
+		pa.Enrichment = &apipb.Enrichment{
+			Annotations: labelSetToProto(e.Annotations),
+			Sources:     make(map[string]string, len(e.Sources)),
+		}
+		for ln, src := range e.Sources {
+			pa.Enrichment.Sources[string(ln)] = src
+		}
+	}
+	return pa
+}
+
+func routeOptsToProto(ro *dispatch.RouteOpts) *apipb.RouteOpts {
+	if ro == nil {
+		return nil
+	}
+	pro := &apipb.RouteOpts{
+		Receiver:           ro.Receiver,
+		GroupWait:          ro.GroupWait,
+		GroupInterval:      ro.GroupInterval,
+		RepeatInterval:     ro.RepeatInterval,
+		GroupIntervalMax:   ro.GroupIntervalMax,
+		ResolveInterval:    ro.ResolveInterval,
+		ResolvedMaxAge:     ro.ResolvedMaxAge,
+		EscalateAfter:      int64(ro.EscalateAfter),
+		EscalationReceiver: ro.EscalationReceiver,
+		ChangeDetection:    ro.ChangeDetection.Mode,
+		ChangeAnnotations:  ro.ChangeDetection.Annotations,
+	}
+	for ln := range ro.GroupBy {
+		pro.GroupBy = append(pro.GroupBy, string(ln))
+	}
+	sort.Strings(pro.GroupBy)
+	for _, t := range ro.ChangeDetection.Thresholds {
+		pro.ChangeThresholds = append(pro.ChangeThresholds, int64(t))
+	}
+	return pro
+}
+
+func groupsToProto(groups dispatch.AlertOverview) *apipb.AlertGroups {
+	res := &apipb.AlertGroups{Groups: make([]*apipb.AlertGroup, 0, len(groups))}
+	for _, g := range groups {
+		pg := &apipb.AlertGroup{
+			Labels:   labelSetToProto(g.Labels),
+			GroupKey: g.GroupKey,
+		}
+		for _, b := range g.Blocks {
+			pb := &apipb.AlertBlock{RouteOpts: routeOptsToProto(b.RouteOpts)}
+			for _, a := range b.Alerts {
+				pb.Alerts = append(pb.Alerts, alertToProto(a))
+			}
+			pg.Blocks = append(pg.Blocks, pb)
+		}
+		res.Groups = append(res.Groups, pg)
+	}
+	return res
+}
+
 func (api *API) respondError(w http.ResponseWriter, apiErr apiError, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 
 	switch apiErr.typ {
 	case errorBadData:
 		w.WriteHeader(http.StatusBadRequest)
+	case errorUnauthorized:
+		w.WriteHeader(http.StatusUnauthorized)
+	case errorNotFound:
+		w.WriteHeader(http.StatusNotFound)
 	case errorInternal:
 		w.WriteHeader(http.StatusInternalServerError)
+	case errorConflict:
+		w.WriteHeader(http.StatusConflict)
+	case errorUnavailable:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case errorTooLarge:
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case errorLimitExceeded:
+		w.WriteHeader(http.StatusTooManyRequests)
 	default:
 		panic(fmt.Sprintf("unknown error type %q", apiErr))
 	}
@@ -1,12 +1,39 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-kit/kit/log"
+	"github.com/gogo/protobuf/proto"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/route"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/api/apipb"
+	"github.com/prometheus/alertmanager/audit"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
 )
 
 func TestAlertFiltering(t *testing.T) {
@@ -88,3 +115,1630 @@ func TestAlertFiltering(t *testing.T) {
 		require.Equal(t, test.expected, actual, msg)
 	}
 }
+
+func TestWithGzip(t *testing.T) {
+	h := withGzip(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	for _, c := range []struct {
+		accept string
+		gzip   bool
+	}{
+		{accept: "", gzip: false},
+		{accept: "deflate", gzip: false},
+		{accept: "gzip", gzip: true},
+		{accept: "deflate, gzip;q=0.8", gzip: true},
+		{accept: "gzip;q=0", gzip: false},
+		{accept: "gzip; q=0.0", gzip: false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", c.accept)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+
+		require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+		body := rec.Body.Bytes()
+		if c.gzip {
+			require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), c.accept)
+
+			gz, err := gzip.NewReader(rec.Body)
+			require.NoError(t, err)
+			body, err = ioutil.ReadAll(gz)
+			require.NoError(t, err)
+		} else {
+			require.Empty(t, rec.Header().Get("Content-Encoding"), c.accept)
+		}
+		require.Equal(t, "hello", string(body), c.accept)
+	}
+}
+
+func TestWithGzipResponses(t *testing.T) {
+	serve := func(method string, f http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		withGzip(f)(rec, req)
+		return rec
+	}
+	gunzip := func(rec *httptest.ResponseRecorder) string {
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		gz, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(gz)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	// Responses without a body are sent as they are.
+	for _, c := range []struct {
+		method string
+		f      http.HandlerFunc
+		code   int
+	}{
+		{method: "OPTIONS", f: func(w http.ResponseWriter, r *http.Request) {}, code: http.StatusOK},
+		{method: "GET", f: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }, code: http.StatusNoContent},
+		{method: "GET", f: func(w http.ResponseWriter, r *http.Request) { w.Write(nil) }, code: http.StatusOK},
+		{method: "HEAD", f: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) }, code: http.StatusOK},
+	} {
+		rec := serve(c.method, c.f)
+		require.Equal(t, c.code, rec.Code, c.method)
+		require.Empty(t, rec.Header().Get("Content-Encoding"), c.method)
+		if c.method != "HEAD" {
+			require.Empty(t, rec.Body.Bytes(), c.method)
+		}
+	}
+
+	// The status code is kept.
+	rec := serve("GET", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("hello"))
+	})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Empty(t, rec.Header().Get("Content-Length"))
+	require.Equal(t, "hello", gunzip(rec))
+
+	// Flushing sends the compressed body written so far.
+	var flushed string
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	withGzip(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hel"))
+		w.(http.Flusher).Flush()
+
+		gz, err := gzip.NewReader(bytes.NewReader(rec.Body.Bytes()))
+		require.NoError(t, err)
+		b := make([]byte, 3)
+		_, err = io.ReadFull(gz, b)
+		require.NoError(t, err)
+		flushed = string(b)
+
+		w.Write([]byte("lo"))
+	})(rec, req)
+	require.True(t, rec.Flushed)
+	require.Equal(t, "hel", flushed)
+	require.Equal(t, "hello", gunzip(rec))
+}
+
+func TestMatchRoutes(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: default
+  routes:
+  - match:
+      team: a
+    receiver: team-a
+  - match:
+      team: b
+    receiver: team-b
+    continue: true
+  - match_re:
+      team: b|c
+    receiver: team-bc
+receivers:
+- name: default
+- name: team-a
+- name: team-b
+- name: team-bc
+`)
+	require.NoError(t, err)
+
+	api := &API{
+		route:  dispatch.NewRoute(conf.Route, nil),
+		logger: log.NewNopLogger(),
+	}
+
+	for _, c := range []struct {
+		labels   string
+		expected string
+	}{
+		{`{team="a"}`, `[{"path":[0],"receiver":"team-a"}]`},
+		{`{team="b",severity="page"}`, `[{"path":[1],"receiver":"team-b"},{"path":[2],"receiver":"team-bc"}]`},
+		{`{team="d"}`, `[{"path":[],"receiver":"default"}]`},
+	} {
+		rec := httptest.NewRecorder()
+		api.matchRoutes(rec, httptest.NewRequest("GET", "/?labels="+url.QueryEscape(c.labels), nil))
+		require.Equal(t, http.StatusOK, rec.Code, c.labels)
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.JSONEq(t, c.expected, string(res.Data), c.labels)
+	}
+
+	rec := httptest.NewRecorder()
+	api.matchRoutes(rec, httptest.NewRequest("GET", "/?labels="+url.QueryEscape(`{team=~"a"}`), nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestEffectiveRoutes(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: default
+  group_by: [alertname]
+  group_wait: 10s
+  routes:
+  - match:
+      team: a
+    receiver: team-a
+    group_interval: 1m
+    routes:
+    - match_re:
+        service: db|cache
+      group_by: [service, cluster]
+      repeat_interval: 1h
+  - match:
+      team: b
+    receiver: team-b
+    continue: true
+    metadata:
+      owner: team-b@example.com
+receivers:
+- name: default
+- name: team-a
+- name: team-b
+`)
+	require.NoError(t, err)
+
+	api := &API{
+		route:  dispatch.NewRoute(conf.Route, nil),
+		logger: log.NewNopLogger(),
+	}
+
+	rec := httptest.NewRecorder()
+	api.effectiveRoutes(rec, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var res struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.JSONEq(t, `[
+		{
+			"path": [0, 0],
+			"matcherChain": [
+				{"matchers": []},
+				{"matchers": [{"name": "team", "value": "a", "isRegex": false, "isNegative": false}]},
+				{"matchers": [{"name": "service", "value": "^(?:db|cache)$", "isRegex": true, "isNegative": false}]}
+			],
+			"continue": false,
+			"routeOpts": {
+				"receiver": "team-a",
+				"groupBy": ["cluster", "service"],
+				"groupWait": 10000000000,
+				"groupInterval": 60000000000,
+				"repeatInterval": 3600000000000
+			}
+		},
+		{
+			"path": [1],
+			"matcherChain": [
+				{"matchers": []},
+				{"matchers": [{"name": "team", "value": "b", "isRegex": false, "isNegative": false}]}
+			],
+			"continue": true,
+			"routeOpts": {
+				"receiver": "team-b",
+				"groupBy": ["alertname"],
+				"groupWait": 10000000000,
+				"groupInterval": 300000000000,
+				"repeatInterval": 14400000000000
+			},
+			"metadata": {"owner": "team-b@example.com"}
+		}
+	]`, string(res.Data))
+}
+
+func TestConfigMetadata(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: default
+  metadata:
+    owner: ops
+  routes:
+  - match:
+      team: a
+    receiver: team-a
+    metadata:
+      owner: team-a@example.com
+      link: https://tickets.example.com/A-1
+      description: Team A alerts
+receivers:
+- name: default
+- name: team-a
+  metadata:
+    owner: team-a@example.com
+`)
+	require.NoError(t, err)
+
+	api := &API{
+		config: conf,
+		route:  dispatch.NewRoute(conf.Route, nil),
+		logger: log.NewNopLogger(),
+	}
+
+	get := func(h http.HandlerFunc, url string) string {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest("GET", url, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return string(res.Data)
+	}
+
+	require.JSONEq(t, `{
+		"receiver": "default",
+		"matchers": [],
+		"continue": false,
+		"metadata": {"owner": "ops"},
+		"routes": [
+			{
+				"receiver": "team-a",
+				"matchers": [{"name": "team", "value": "a", "isRegex": false, "isNegative": false}],
+				"continue": false,
+				"metadata": {
+					"owner": "team-a@example.com",
+					"link": "https://tickets.example.com/A-1",
+					"description": "Team A alerts"
+				}
+			}
+		]
+	}`, get(api.routes, "/"))
+
+	// Receivers are listed by name unless metadata is requested.
+	require.JSONEq(t, `["default", "team-a"]`, get(api.receivers, "/"))
+	require.JSONEq(t, `[
+		{"name": "default"},
+		{"name": "team-a", "metadata": {"owner": "team-a@example.com"}}
+	]`, get(api.receivers, "/?metadata=true"))
+}
+
+func TestSilenceSource(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := &API{
+		silences: silences,
+		logger:   log.NewNopLogger(),
+	}
+
+	set := func(req *http.Request) *types.Silence {
+		rec := httptest.NewRecorder()
+		api.setSilence(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Data struct {
+				SilenceID string `json:"silenceId"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+
+		sils, err := silences.Query(silence.QIDs(res.Data.SilenceID))
+		require.NoError(t, err)
+		require.Len(t, sils, 1)
+		sil, err := silenceFromProto(sils[0])
+		require.NoError(t, err)
+		return sil
+	}
+
+	// Principal and source are taken from the request, not the body.
+	endsAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	req := httptest.NewRequest("POST", "/silences", strings.NewReader(
+		`{"matchers":[{"name":"a","value":"b"}],"endsAt":"`+endsAt+`","createdBy":"mallory","principal":"mallory","source":"forged"}`,
+	))
+	req.Header.Set("X-Forwarded-User", "alice")
+	req.Header.Set("User-Agent", "amtool/0.9.1")
+
+	sil := set(req)
+	require.Equal(t, "alice", sil.Principal)
+	require.Equal(t, "amtool/0.9.1", sil.Source)
+
+	// Updates keep the principal and source of the creating request.
+	req = httptest.NewRequest("POST", "/silences", strings.NewReader(fmt.Sprintf(
+		`{"id":%q,"matchers":[{"name":"a","value":"b"}],"startsAt":%q,"endsAt":%q,"comment":"extended"}`,
+		sil.ID, sil.StartsAt.Format(time.RFC3339Nano), time.Now().Add(2*time.Hour).Format(time.RFC3339),
+	)))
+	req.Header.Set("X-Forwarded-User", "bob")
+
+	upd := set(req)
+	require.Equal(t, sil.ID, upd.ID)
+	require.Equal(t, "extended", upd.Comment)
+	require.Equal(t, "alice", upd.Principal)
+	require.Equal(t, "amtool/0.9.1", upd.Source)
+}
+
+func TestExtendSilence(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := &API{
+		silences: silences,
+		logger:   log.NewNopLogger(),
+	}
+
+	now := time.Now()
+	sid, err := silences.Set(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	sils, err := silences.Query(silence.QIDs(sid))
+	require.NoError(t, err)
+	sil := sils[0]
+
+	extend := func(sid, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/silence/"+sid+"/extend", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		api.extendSilence(rec, req.WithContext(route.WithParam(req.Context(), "sid", sid)))
+		return rec
+	}
+
+	rec := extend("unknown", `{"duration":"1h"}`)
+	require.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	rec = extend(sid, `{"duration":"1h","updatedAt":"2000-01-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+
+	rec = extend(sid, fmt.Sprintf(`{"duration":"1h","updatedAt":%q}`, sil.UpdatedAt.Format(time.RFC3339Nano)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	sils, err = silences.Query(silence.QIDs(sid))
+	require.NoError(t, err)
+	require.True(t, sil.EndsAt.Add(time.Hour).Equal(sils[0].EndsAt))
+
+	rec = extend(sid, `{"duration":"-1h"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+}
+
+func TestSilencePolicy(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	policy := &config.SilencePolicy{MaxDuration: model.Duration(24 * time.Hour), Action: config.SilencePolicyReject}
+	api := &API{
+		silences: silences,
+		config:   &config.Config{SilencePolicy: policy},
+		logger:   log.NewNopLogger(),
+	}
+
+	type setResponse struct {
+		ErrorType string `json:"errorType"`
+		Data      struct {
+			SilenceID string   `json:"silenceId"`
+			Warnings  []string `json:"warnings"`
+		} `json:"data"`
+	}
+	set := func(startsAt, endsAt time.Time) (int, setResponse) {
+		rec := httptest.NewRecorder()
+		api.setSilence(rec, httptest.NewRequest("POST", "/silences", strings.NewReader(fmt.Sprintf(
+			`{"matchers":[{"name":"a","value":"b"}],"startsAt":%q,"endsAt":%q,"createdBy":"alice"}`,
+			startsAt.Format(time.RFC3339), endsAt.Format(time.RFC3339),
+		))))
+		var res setResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return rec.Code, res
+	}
+	extend := func(sid, d string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/silence/"+sid+"/extend", strings.NewReader(`{"duration":"`+d+`"}`))
+		rec := httptest.NewRecorder()
+		api.extendSilence(rec, req.WithContext(route.WithParam(req.Context(), "sid", sid)))
+		return rec
+	}
+	endsAt := func(sid string) time.Time {
+		sils, err := silences.Query(silence.QIDs(sid))
+		require.NoError(t, err)
+		require.Len(t, sils, 1)
+		return sils[0].EndsAt
+	}
+
+	now := time.Now().Truncate(time.Second)
+	code, res := set(now.Add(time.Hour), now.Add(25*time.Hour))
+	require.Equal(t, http.StatusOK, code)
+	sid := res.Data.SilenceID
+
+	code, res = set(now.Add(time.Hour), now.Add(26*time.Hour))
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, errorBadData, string(res.ErrorType))
+
+	rec := extend(sid, "1h")
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "must not last longer than 1d")
+
+	policy.Action = config.SilencePolicyClamp
+
+	// Silences that started already may last the maximum duration from now on.
+	code, res = set(now.Add(-48*time.Hour), now.Add(48*time.Hour))
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, res.Data.Warnings, 1)
+	require.Contains(t, res.Data.Warnings[0], "must not last longer than 1d")
+	require.False(t, endsAt(res.Data.SilenceID).After(time.Now().Add(24*time.Hour)))
+	require.True(t, endsAt(res.Data.SilenceID).After(now.Add(23*time.Hour)))
+
+	// Extensions are shortened to the maximum duration.
+	code, res = set(now.Add(time.Hour), now.Add(2*time.Hour))
+	require.Equal(t, http.StatusOK, code)
+	require.Empty(t, res.Data.Warnings)
+	rec = extend(res.Data.SilenceID, "48h")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.True(t, now.Add(25*time.Hour).Equal(endsAt(res.Data.SilenceID)))
+
+	rec = extend(sid, "1h")
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), "already lasts the maximum duration")
+}
+
+func TestSilenceEvents(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	al := audit.New(10, nil)
+	al.SetPrincipalHeader("X-Auth-User")
+	api := &API{
+		silences: silences,
+		audit:    al,
+		logger:   log.NewNopLogger(),
+	}
+
+	set := func(user, body string) string {
+		req := httptest.NewRequest("POST", "/silences", strings.NewReader(body))
+		req.Header.Set("X-Auth-User", user)
+		rec := httptest.NewRecorder()
+		api.setSilence(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Data struct {
+				SilenceID string `json:"silenceId"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Data.SilenceID
+	}
+	events := func(query string) []*audit.SilenceEvent {
+		rec := httptest.NewRecorder()
+		api.silenceEvents(rec, httptest.NewRequest("GET", "/silences/audit?"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Data []*audit.SilenceEvent `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Data
+	}
+
+	startsAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	endsAt := time.Now().Add(2 * time.Hour).Format(time.RFC3339)
+	id := set("alice", `{"matchers":[{"name":"a","value":"b"}],"startsAt":"`+startsAt+`","endsAt":"`+endsAt+`","createdBy":"alice","comment":"maintenance"}`)
+	other := set("alice", `{"matchers":[{"name":"c","value":"d"}],"startsAt":"`+startsAt+`","endsAt":"`+endsAt+`","createdBy":"alice"}`)
+
+	// Changing the matchers replaces the silence.
+	newID := set("bob", `{"id":"`+id+`","matchers":[{"name":"a","value":"c"}],"startsAt":"`+startsAt+`","endsAt":"`+endsAt+`","createdBy":"alice"}`)
+	require.NotEqual(t, id, newID)
+
+	req := httptest.NewRequest("DELETE", "/silence/"+newID, nil)
+	req.Header.Set("X-Auth-User", "carol")
+	// Users are not read from the default header if another one is configured.
+	req.Header.Set("X-Forwarded-User", "mallory")
+	rec := httptest.NewRecorder()
+	api.delSilence(rec, req.WithContext(route.WithParam(req.Context(), "sid", newID)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.Len(t, events(""), 4)
+
+	evs := events("id=" + id)
+	require.Len(t, evs, 2)
+	require.Equal(t, audit.SilenceCreate, evs[0].Action)
+	require.Equal(t, id, evs[0].SilenceID)
+	require.Equal(t, "alice", evs[0].Principal)
+	require.Equal(t, `{a="b"}`, evs[0].Matchers)
+	require.Equal(t, "maintenance", evs[0].Comment)
+	require.Equal(t, audit.SilenceUpdate, evs[1].Action)
+	require.Equal(t, newID, evs[1].SilenceID)
+	require.Equal(t, id, evs[1].PreviousID)
+	require.Equal(t, "bob", evs[1].Principal)
+
+	evs = events("id=" + newID)
+	require.Len(t, evs, 2)
+	require.Equal(t, audit.SilenceExpire, evs[1].Action)
+	require.Equal(t, "carol", evs[1].Principal)
+	require.False(t, evs[1].EndsAt.After(time.Now()))
+
+	require.Len(t, events("id="+other), 1)
+	require.Empty(t, events("since="+time.Now().Add(time.Minute).Format(time.RFC3339)))
+}
+
+func TestSilenceRecurrence(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := &API{
+		silences: silences,
+		logger:   log.NewNopLogger(),
+	}
+
+	endsAt := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	set := func(recurrence string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/silences", strings.NewReader(
+			`{"matchers":[{"name":"a","value":"b"}],"endsAt":"`+endsAt+`","createdBy":"alice","comment":"maintenance","recurrence":`+recurrence+`}`,
+		))
+		rec := httptest.NewRecorder()
+		api.setSilence(rec, req)
+		return rec
+	}
+
+	rec := set(`{"weekdays":["Saturday","sunday"],"startTime":"02:30","duration":"90m","location":"Europe/Berlin"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	sils, err := silences.Query()
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, &silencepb.Recurrence{
+		Weekdays:        1<<uint(time.Sunday) | 1<<uint(time.Saturday),
+		StartMinute:     150,
+		DurationMinutes: 90,
+		Location:        "Europe/Berlin",
+	}, sils[0].Recurrence)
+
+	sil, err := silenceFromProto(sils[0])
+	require.NoError(t, err)
+	require.Equal(t, &types.Recurrence{
+		Weekdays:  []string{"sunday", "saturday"},
+		StartTime: "02:30",
+		Duration:  "90m",
+		Location:  "Europe/Berlin",
+	}, sil.Recurrence)
+
+	for _, r := range []string{
+		`{"weekdays":["someday"],"startTime":"02:30","duration":"1h"}`,
+		`{"startTime":"25:00","duration":"1h"}`,
+		`{"startTime":"02:30","duration":"90s"}`,
+		`{"startTime":"02:30","duration":"8d"}`,
+		`{"startTime":"02:30","duration":"1h","location":"Nowhere/Atlantis"}`,
+	} {
+		rec := set(r)
+		require.Equal(t, http.StatusBadRequest, rec.Code, r)
+	}
+}
+
+func TestSilenceMatcherExpression(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := &API{
+		silences: silences,
+		logger:   log.NewNopLogger(),
+	}
+
+	endsAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	set := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.setSilence(rec, httptest.NewRequest("POST", "/silences", strings.NewReader(body)))
+		return rec
+	}
+
+	// The expression takes precedence over the matchers sent along.
+	rec := set(`{"matchers":[{"name":"a","value":"b"}],"matcherExpression":"{job=\"api\",instance=~\"web-.*\"}","endsAt":"` + endsAt + `","createdBy":"alice","comment":"deploy"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	sils, err := silences.Query()
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, []*silencepb.Matcher{
+		{Name: "job", Pattern: "api", Type: silencepb.Matcher_EQUAL},
+		{Name: "instance", Pattern: "web-.*", Type: silencepb.Matcher_REGEXP},
+	}, sils[0].Matchers)
+
+	rec = set(`{"matcherExpression":"{job}","endsAt":"` + endsAt + `","createdBy":"alice","comment":"deploy"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+}
+
+func TestSilenceMatcherWarnings(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	catalog := provider.NewLabelCatalog(time.Hour)
+	catalog.Observe(&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a", "severity": "critical"}}})
+
+	api := &API{
+		silences: silences,
+		labels:   catalog,
+		logger:   log.NewNopLogger(),
+	}
+
+	set := func(matchers string) []string {
+		endsAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+		req := httptest.NewRequest("POST", "/silences", strings.NewReader(
+			`{"matchers":`+matchers+`,"endsAt":"`+endsAt+`","createdBy":"alice","comment":"test"}`,
+		))
+		rec := httptest.NewRecorder()
+		api.setSilence(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Data struct {
+				SilenceID string   `json:"silenceId"`
+				Warnings  []string `json:"warnings"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.NotEmpty(t, res.Data.SilenceID)
+		return res.Data.Warnings
+	}
+
+	require.Empty(t, set(`[{"name":"alertname","value":"a"},{"name":"severity","value":"critical"}]`))
+
+	// Silences referencing unknown label names are created with a warning.
+	warnings := set(`[{"name":"alertname","value":"a"},{"name":"sevrity","value":"critical"}]`)
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], `"sevrity"`)
+
+	// Matchers are not validated without a catalog.
+	api.labels = nil
+	require.Empty(t, set(`[{"name":"sevrity","value":"critical"}]`))
+}
+
+func TestDrainRejectsAlerts(t *testing.T) {
+	api := &API{logger: log.NewNopLogger()}
+
+	accepted := 0
+	h := api.acceptAlerts(func(w http.ResponseWriter, r *http.Request) {
+		accepted++
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("POST", "/alerts", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 1, accepted)
+
+	api.Drain(90 * time.Second)
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest("POST", "/alerts", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "90", rec.Header().Get("Retry-After"))
+	require.Equal(t, 1, accepted)
+}
+
+func TestListSilencesTeam(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := &API{
+		silences: silences,
+		logger:   log.NewNopLogger(),
+	}
+	for _, team := range []string{"a", "b", ""} {
+		_, err := silences.Set(&silencepb.Silence{
+			Matchers:  []*silencepb.Matcher{{Name: "a", Pattern: "b"}},
+			StartsAt:  time.Now(),
+			EndsAt:    time.Now().Add(time.Hour),
+			CreatedBy: "alice",
+			Comment:   "maintenance",
+			Team:      team,
+		})
+		require.NoError(t, err)
+	}
+
+	for team, n := range map[string]int{"": 3, "a": 1, "c": 0} {
+		rec := httptest.NewRecorder()
+		api.listSilences(rec, httptest.NewRequest("GET", "/silences?team="+team, nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var res struct {
+			Data []*types.Silence `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Len(t, res.Data, n, "team %q", team)
+		for _, s := range res.Data {
+			if team != "" {
+				require.Equal(t, team, s.Team)
+			}
+		}
+	}
+}
+
+func TestExportImportState(t *testing.T) {
+	newAPI := func() *API {
+		silences, err := silence.New(silence.Options{Retention: time.Hour})
+		require.NoError(t, err)
+		nl, err := nflog.New(nflog.WithRetention(time.Hour))
+		require.NoError(t, err)
+		alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+		require.NoError(t, err)
+
+		return &API{
+			alerts:   alerts,
+			silences: silences,
+			nflog:    nl,
+			logger:   log.NewNopLogger(),
+		}
+	}
+
+	src := newAPI()
+	sid, err := src.silences.Set(&silencepb.Silence{
+		Matchers:  []*silencepb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt:  time.Now(),
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	recv := &nflogpb.Receiver{GroupName: "team-a", Integration: "email"}
+	require.NoError(t, src.nflog.Log(recv, "{}:{a=\"b\"}", []uint64{1}, nil))
+	require.NoError(t, src.alerts.Put(
+		&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"a": "b"}, StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour)}},
+		&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"a": "c"}, StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(-time.Minute)}},
+	))
+
+	rec := httptest.NewRecorder()
+	src.exportState(rec, httptest.NewRequest("GET", "/admin/export", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+
+	exported := string(res.Data)
+
+	var state apiState
+	require.NoError(t, json.Unmarshal(res.Data, &state))
+	require.Len(t, state.Silences, 1)
+	require.Len(t, state.NotificationLog, 1)
+	// Resolved alerts are not exported.
+	require.Len(t, state.Alerts, 1)
+	require.Equal(t, model.LabelSet{"a": "b"}, state.Alerts[0].Labels)
+
+	dst := newAPI()
+	rec = httptest.NewRecorder()
+	dst.importState(rec, httptest.NewRequest("POST", "/admin/import", strings.NewReader(exported)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.JSONEq(t, `{"silences":1,"notificationLog":1}`, string(res.Data))
+
+	sils, err := dst.silences.Query(silence.QIDs(sid))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, "alice", sils[0].CreatedBy)
+
+	e, err := dst.nflog.QueryOne(nflog.QReceiver(recv), nflog.QGroupKey("{}:{a=\"b\"}"))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, e.FiringAlerts)
+
+	// Importing the same state again merges nothing.
+	rec = httptest.NewRecorder()
+	dst.importState(rec, httptest.NewRequest("POST", "/admin/import", strings.NewReader(exported)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.JSONEq(t, `{"silences":0,"notificationLog":0}`, string(res.Data))
+}
+
+func TestStateTransferDisabled(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+	nl, err := nflog.New(nflog.WithRetention(time.Hour))
+	require.NoError(t, err)
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+	api := &API{alerts: alerts, silences: silences, nflog: nl, logger: log.NewNopLogger()}
+
+	export := func() int {
+		rec := httptest.NewRecorder()
+		api.requireStateTransfer(api.exportState)(rec, httptest.NewRequest("GET", "/admin/export", nil))
+		return rec.Code
+	}
+	rec := httptest.NewRecorder()
+	api.requireStateTransfer(api.importState)(rec, httptest.NewRequest("POST", "/admin/import", strings.NewReader(`{}`)))
+	require.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+	require.Equal(t, http.StatusNotFound, export())
+
+	api.EnableStateTransfer()
+	require.Equal(t, http.StatusOK, export())
+}
+
+func TestGC(t *testing.T) {
+	now := time.Now()
+	silences, err := silence.New(silence.Options{Retention: time.Millisecond})
+	require.NoError(t, err)
+	nl, err := nflog.New(nflog.WithRetention(time.Hour), nflog.WithNow(func() time.Time { return now }))
+	require.NoError(t, err)
+	api := &API{silences: silences, nflog: nl, logger: log.NewNopLogger()}
+
+	sid, err := silences.Set(&silencepb.Silence{
+		Matchers:  []*silencepb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt:  time.Now(),
+		EndsAt:    time.Now().Add(time.Hour),
+		CreatedBy: "alice",
+	})
+	require.NoError(t, err)
+	require.NoError(t, silences.Expire(sid))
+
+	recv := &nflogpb.Receiver{GroupName: "team-a", Integration: "email"}
+	require.NoError(t, nl.Log(recv, "a", []uint64{1}, nil))
+	require.NoError(t, nl.Log(recv, "b", []uint64{1}, nil, nflog.LRetention(time.Minute)))
+
+	time.Sleep(10 * time.Millisecond)
+	now = now.Add(30 * time.Minute)
+
+	var res struct {
+		Data json.RawMessage `json:"data"`
+	}
+	rec := httptest.NewRecorder()
+	api.gc(rec, httptest.NewRequest("POST", "/admin/gc", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.JSONEq(t, `{"silences":1,"notificationLog":1}`, string(res.Data))
+
+	_, err = nl.QueryOne(nflog.QReceiver(recv), nflog.QGroupKey("a"))
+	require.NoError(t, err)
+}
+
+func TestExpireSilenceDryRun(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: default
+receivers:
+- name: default
+`)
+	require.NoError(t, err)
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	inhibited := model.LabelSet{"alertname": "inhibited", "team": "a"}.Fingerprint()
+	api := &API{
+		alerts:   alerts,
+		silences: silences,
+		route:    dispatch.NewRoute(conf.Route, nil),
+		getAlertStatus: func(fp model.Fingerprint) types.AlertStatus {
+			s := types.AlertStatus{State: types.AlertStateSuppressed}
+			if fp == inhibited {
+				s.InhibitedBy = []string{"source"}
+			}
+			return s
+		},
+		logger: log.NewNopLogger(),
+	}
+
+	now := time.Now()
+	setSilence := func(pattern string) string {
+		sid, err := silences.Set(&silencepb.Silence{
+			Matchers:  []*silencepb.Matcher{{Name: "alertname", Pattern: pattern, Type: silencepb.Matcher_REGEXP}},
+			StartsAt:  now,
+			EndsAt:    now.Add(time.Hour),
+			CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+		return sid
+	}
+	sid := setSilence("unsilenced|silenced|inhibited")
+	setSilence("silenced")
+
+	for _, name := range []model.LabelValue{"unsilenced", "silenced", "inhibited", "other"} {
+		require.NoError(t, alerts.Put(&types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": name, "team": "a"},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: now,
+		}))
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/silence/"+sid+"?dryRun=true", nil)
+	api.delSilence(rec, req.WithContext(route.WithParam(req.Context(), "sid", sid)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res struct {
+		Data []*dispatch.APIAlert `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Data, 1)
+	require.Equal(t, model.LabelValue("unsilenced"), res.Data[0].Labels["alertname"])
+	require.Equal(t, []string{"default"}, res.Data[0].Receivers)
+
+	// The silence is left untouched.
+	sils, err := silences.Query(silence.QIDs(sid), silence.QState(silence.StateActive))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+
+	_, err = api.unsilencedBy("unknown")
+	require.Equal(t, silence.ErrNotFound, err)
+}
+
+func TestInhibitedAlerts(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: default
+receivers:
+- name: default
+inhibit_rules:
+- source_match:
+    severity: critical
+  target_match:
+    severity: warning
+  equal: [cluster]
+`)
+	require.NoError(t, err)
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+
+	api := &API{
+		alerts: alerts,
+		getAlertStatus: func(model.Fingerprint) types.AlertStatus {
+			return types.AlertStatus{State: types.AlertStateSuppressed}
+		},
+		logger: log.NewNopLogger(),
+	}
+	require.NoError(t, api.Update(conf, time.Hour))
+
+	now := time.Now()
+	put := func(name, severity, cluster string) model.Fingerprint {
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": model.LabelValue(name),
+					"severity":  model.LabelValue(severity),
+					"cluster":   model.LabelValue(cluster),
+				},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: now,
+		}
+		require.NoError(t, alerts.Put(a))
+		return a.Fingerprint()
+	}
+	var (
+		source = put("Down", "critical", "a")
+		other  = put("Unreachable", "critical", "a")
+		w1     = put("Latency", "warning", "a")
+		w2     = put("Errors", "warning", "a")
+	)
+	put("Down", "critical", "b")
+	put("Latency", "warning", "b")
+	put("Info", "info", "a")
+
+	query := func(fp string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.inhibitedAlerts(rec, httptest.NewRequest("GET", "/alerts/inhibited?source="+fp, nil))
+		return rec
+	}
+
+	rec := query(source.String())
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res struct {
+		Data []*inhibitedAlert `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Data, 2)
+
+	sources := []string{source.String(), other.String()}
+	sort.Strings(sources)
+	got := map[string][]string{}
+	for _, a := range res.Data {
+		got[a.Fingerprint] = a.Sources
+		require.Equal(t, []string{"default"}, a.Receivers)
+	}
+	require.Equal(t, map[string][]string{
+		w1.String(): sources,
+		w2.String(): sources,
+	}, got)
+
+	// Alerts that are no inhibition source inhibit nothing.
+	rec = query(w1.String())
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Data, 0)
+
+	require.Equal(t, http.StatusNotFound, query("0000000000000001").Code)
+	require.Equal(t, http.StatusBadRequest, query("invalid").Code)
+}
+
+func TestTraceParent(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		exp    string
+	}{
+		{
+			header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			exp:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{header: ""},
+		// Unknown version.
+		{header: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		// Upper case hex digits.
+		{header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		// All zero trace and parent IDs.
+		{header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+	} {
+		r := httptest.NewRequest("POST", "/api/v1/alerts", nil)
+		r.Header.Set("traceparent", tc.header)
+		require.Equal(t, tc.exp, traceParent(r), "header %q", tc.header)
+	}
+}
+
+func TestAlertGroupsLongPoll(t *testing.T) {
+	defer func(d time.Duration) { groupsPollInterval = d }(groupsPollInterval)
+	groupsPollInterval = time.Millisecond
+
+	var (
+		mtx    sync.Mutex
+		groups = dispatch.AlertOverview{{GroupKey: "a"}}
+	)
+	api := &API{
+		groups: func([]*labels.Matcher) dispatch.AlertOverview {
+			mtx.Lock()
+			defer mtx.Unlock()
+			return groups
+		},
+		logger: log.NewNopLogger(),
+	}
+	get := func(query, etag string) (*httptest.ResponseRecorder, time.Duration) {
+		req := httptest.NewRequest("GET", "/alerts/groups?"+query, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		api.alertGroups(rec, req)
+		return rec, time.Since(start)
+	}
+
+	rec, _ := get("", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Unchanged groups are returned once the timeout elapsed.
+	rec, d := get("pollTimeout=50ms", etag)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+	require.True(t, d >= 50*time.Millisecond, "request returned after %s", d)
+
+	// Clients with an outdated version receive the groups immediately.
+	rec, d = get("pollTimeout=1m", `"outdated"`)
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+	require.True(t, d < time.Second, "request returned after %s", d)
+
+	// Changes are returned before the timeout.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		mtx.Lock()
+		groups = dispatch.AlertOverview{{GroupKey: "a"}, {GroupKey: "b"}}
+		mtx.Unlock()
+	}()
+	rec, d = get("pollTimeout=1m", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEqual(t, etag, rec.Header().Get("ETag"))
+	require.True(t, d < 30*time.Second, "request returned after %s", d)
+
+	var res struct {
+		Data dispatch.AlertOverview `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res.Data, 2)
+
+	for _, q := range []string{"pollTimeout=x", "pollTimeout=-1s"} {
+		rec, _ = get(q, "")
+		require.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestProtobufResponses(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: default
+  group_by: [alertname]
+receivers:
+- name: default
+`)
+	require.NoError(t, err)
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "test"},
+			Annotations: model.LabelSet{"summary": "broken"},
+			StartsAt:    now.Add(-time.Hour),
+			EndsAt:      now.Add(time.Hour),
+		},
+		UpdatedAt: now,
+	}
+	require.NoError(t, alerts.Put(alert))
+	fp := alert.Fingerprint().String()
+
+	route := dispatch.NewRoute(conf.Route, nil)
+	api := &API{
+		alerts: alerts,
+		route:  route,
+		groups: func([]*labels.Matcher) dispatch.AlertOverview {
+			return dispatch.AlertOverview{{
+				Labels:   model.LabelSet{"alertname": "test"},
+				GroupKey: "{}:{alertname=\"test\"}",
+				Blocks: []*dispatch.AlertBlock{{
+					RouteOpts: &route.RouteOpts,
+					Alerts:    []*dispatch.APIAlert{{Alert: &alert.Alert, Fingerprint: fp}},
+				}},
+			}}
+		},
+		getAlertStatus: func(model.Fingerprint) types.AlertStatus {
+			return types.AlertStatus{State: types.AlertStateSuppressed, SilencedBy: []string{"s1"}}
+		},
+		logger: log.NewNopLogger(),
+	}
+	get := func(h http.HandlerFunc, path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "Accept", rec.Header().Get("Vary"))
+		return rec
+	}
+
+	rec := get(api.listAlerts, "/alerts", "application/x-protobuf")
+	require.Equal(t, protobufContentType, rec.Header().Get("Content-Type"))
+	var pa apipb.Alerts
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &pa))
+	require.Len(t, pa.Alerts, 1)
+	require.Equal(t, map[string]string{"alertname": "test"}, pa.Alerts[0].Labels)
+	require.Equal(t, map[string]string{"summary": "broken"}, pa.Alerts[0].Annotations)
+	require.True(t, alert.StartsAt.Equal(pa.Alerts[0].StartsAt))
+	require.True(t, alert.EndsAt.Equal(pa.Alerts[0].EndsAt))
+	require.Equal(t, fp, pa.Alerts[0].Fingerprint)
+	require.Equal(t, []string{"default"}, pa.Alerts[0].Receivers)
+	require.Equal(t, apipb.AlertStatus{State: "suppressed", SilencedBy: []string{"s1"}}, pa.Alerts[0].Status)
+
+	rec = get(api.alertGroups, "/alerts/groups", "application/json;q=0.5, application/x-protobuf")
+	require.Equal(t, protobufContentType, rec.Header().Get("Content-Type"))
+	var pg apipb.AlertGroups
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &pg))
+	require.Len(t, pg.Groups, 1)
+	require.Equal(t, "{}:{alertname=\"test\"}", pg.Groups[0].GroupKey)
+	require.Len(t, pg.Groups[0].Blocks, 1)
+	ro := pg.Groups[0].Blocks[0].RouteOpts
+	require.Equal(t, "default", ro.Receiver)
+	require.Equal(t, []string{"alertname"}, ro.GroupBy)
+	require.Equal(t, route.RouteOpts.GroupInterval, ro.GroupInterval)
+	require.Len(t, pg.Groups[0].Blocks[0].Alerts, 1)
+	require.Equal(t, fp, pg.Groups[0].Blocks[0].Alerts[0].Fingerprint)
+
+	// Responses are JSON encoded unless protobuf is accepted.
+	for _, accept := range []string{"", "application/json", "application/x-protobuf;q=0"} {
+		for _, h := range []http.HandlerFunc{api.listAlerts, api.alertGroups} {
+			rec = get(h, "/", accept)
+			require.Equal(t, "application/json", rec.Header().Get("Content-Type"), accept)
+			require.True(t, json.Valid(rec.Body.Bytes()), accept)
+		}
+	}
+}
+
+func TestListNflog(t *testing.T) {
+	nl, err := nflog.New(nflog.WithRetention(time.Hour))
+	require.NoError(t, err)
+	api := &API{nflog: nl, logger: log.NewNopLogger()}
+
+	recv := &nflogpb.Receiver{GroupName: "team-a", Integration: "email"}
+	require.NoError(t, nl.Log(recv, "a", []uint64{1}, nil))
+	require.NoError(t, nl.Log(recv, "b", nil, []uint64{2}))
+
+	for _, tc := range []struct {
+		query  string
+		code   int
+		groups []string
+	}{
+		{query: "", code: http.StatusOK, groups: []string{"a", "b"}},
+		{query: "groupKey=b", code: http.StatusOK, groups: []string{"b"}},
+		{query: "receiver=team-a", code: http.StatusOK, groups: []string{"a", "b"}},
+		{query: "receiver=team-a&groupKey=a", code: http.StatusOK, groups: []string{"a"}},
+		{query: "receiver=team-b", code: http.StatusOK, groups: []string{}},
+		{query: "offset=1", code: http.StatusOK, groups: []string{"b"}},
+		{query: "limit=1", code: http.StatusOK, groups: []string{"a"}},
+		{query: "offset=2", code: http.StatusOK, groups: []string{}},
+		{query: "limit=0", code: http.StatusBadRequest},
+		{query: "offset=x", code: http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		api.listNflog(rec, httptest.NewRequest("GET", "/nflog?"+tc.query, nil))
+		require.Equal(t, tc.code, rec.Code, "query %q: %s", tc.query, rec.Body.String())
+		if tc.code != http.StatusOK {
+			continue
+		}
+
+		var res struct {
+			Data []*nflogEntry `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		groups := []string{}
+		for _, e := range res.Data {
+			require.Equal(t, "team-a", e.Receiver.GroupName)
+			require.Equal(t, e.GroupKey == "b", e.Resolved)
+			require.False(t, e.Timestamp.IsZero())
+			groups = append(groups, e.GroupKey)
+		}
+		require.Equal(t, tc.groups, groups, "query %q", tc.query)
+	}
+}
+
+func TestNflogStats(t *testing.T) {
+	nl, err := nflog.New(nflog.WithRetention(time.Hour))
+	require.NoError(t, err)
+	api := &API{nflog: nl, logger: log.NewNopLogger()}
+
+	require.NoError(t, nl.Log(&nflogpb.Receiver{GroupName: "team-a", Integration: "email"}, "a", []uint64{1}, nil))
+	require.NoError(t, nl.Log(&nflogpb.Receiver{GroupName: "team-a", Integration: "email"}, "b", []uint64{1}, nil))
+	require.NoError(t, nl.Log(&nflogpb.Receiver{GroupName: "team-b", Integration: "slack"}, "a", []uint64{1}, nil))
+
+	for _, tc := range []struct {
+		query string
+		exp   []*nflogStats
+	}{
+		{
+			query: "",
+			exp: []*nflogStats{
+				{Receiver: "team-a", Integration: "email", LastHour: 2, LastDay: 2},
+				{Receiver: "team-b", Integration: "slack", LastHour: 1, LastDay: 1},
+			},
+		},
+		{
+			query: "receiver=team-b",
+			exp:   []*nflogStats{{Receiver: "team-b", Integration: "slack", LastHour: 1, LastDay: 1}},
+		},
+		{
+			query: "receiver=team-c",
+			exp:   []*nflogStats{},
+		},
+	} {
+		rec := httptest.NewRecorder()
+		api.nflogStats(rec, httptest.NewRequest("GET", "/nflog/stats?"+tc.query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var res struct {
+			Data []*nflogStats `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Equal(t, tc.exp, res.Data, "query %q", tc.query)
+	}
+}
+
+func TestDeleteNflog(t *testing.T) {
+	nl, err := nflog.New(nflog.WithRetention(time.Hour))
+	require.NoError(t, err)
+	api := &API{nflog: nl, logger: log.NewNopLogger()}
+
+	recv := &nflogpb.Receiver{GroupName: "team-a", Integration: "email", Idx: 1}
+	require.NoError(t, nl.Log(recv, "a", []uint64{1}, nil))
+
+	for _, tc := range []struct {
+		query string
+		code  int
+	}{
+		{query: "receiver=team-a&integration=email&idx=1", code: http.StatusBadRequest},
+		{query: "groupKey=a&receiver=team-a&integration=email&idx=x", code: http.StatusBadRequest},
+		{query: "groupKey=a&receiver=team-a&integration=email", code: http.StatusNotFound},
+		{query: "groupKey=a&receiver=team-a&integration=email&idx=1", code: http.StatusOK},
+		{query: "groupKey=a&receiver=team-a&integration=email&idx=1", code: http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		api.deleteNflog(rec, httptest.NewRequest("POST", "/admin/nflog/delete?"+tc.query, nil))
+		require.Equal(t, tc.code, rec.Code, "query %q: %s", tc.query, rec.Body.String())
+	}
+
+	_, err = nl.QueryOne(nflog.QGroupKey("a"), nflog.QReceiver(recv))
+	require.Equal(t, nflog.ErrNotFound, err)
+}
+
+func TestAcknowledge(t *testing.T) {
+	nl, err := nflog.New(nflog.WithRetention(time.Hour))
+	require.NoError(t, err)
+	api := &API{nflog: nl, logger: log.NewNopLogger()}
+
+	recv := &nflogpb.Receiver{GroupName: "team-a", Integration: "pagerduty"}
+	require.NoError(t, nl.Log(recv, "a", []uint64{1}, nil, nflog.LProviderID("key")))
+
+	for _, tc := range []struct {
+		integration string
+		body        string
+		code        int
+		acked       int
+	}{
+		{integration: "email", body: `{}`, code: http.StatusBadRequest},
+		{integration: "pagerduty", body: `{`, code: http.StatusBadRequest},
+		{integration: "pagerduty", body: `{"messages": [{"event": "incident.acknowledge", "incident": {"incident_key": "key"}}]}`, code: http.StatusOK, acked: 1},
+		{integration: "pagerduty", body: `{"messages": [{"event": "incident.acknowledge", "incident": {"incident_key": "key"}}]}`, code: http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/acknowledgements/"+tc.integration, strings.NewReader(tc.body))
+		api.acknowledge(rec, req.WithContext(route.WithParam(req.Context(), "integration", tc.integration)))
+		require.Equal(t, tc.code, rec.Code, "%s: %s", tc.body, rec.Body.String())
+		if tc.code != http.StatusOK {
+			continue
+		}
+		var res struct {
+			Data []*nflogEntry `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.Len(t, res.Data, tc.acked)
+	}
+
+	e, err := nl.QueryOne(nflog.QGroupKey("a"), nflog.QReceiver(recv))
+	require.NoError(t, err)
+	require.NotNil(t, e.AcknowledgedAt)
+}
+
+func TestAddAlertsPartiallyInvalid(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+	api := &API{alerts: alerts, resolveTimeout: time.Minute, logger: log.NewNopLogger()}
+
+	body := `[
+		{"labels": {"alertname": "a"}},
+		{"labels": {}},
+		{"labels": "foo"},
+		{"labels": {"alertname": "b"}}
+	]`
+	rec := httptest.NewRecorder()
+	api.addAlerts(rec, httptest.NewRequest("POST", "/alerts", strings.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	var res struct {
+		Status string        `json:"status"`
+		Data   []*alertError `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, "error", res.Status)
+	require.Len(t, res.Data, 2)
+	require.Equal(t, 1, res.Data[0].Index)
+	require.Equal(t, 2, res.Data[1].Index)
+
+	// The valid alerts were inserted regardless.
+	for _, name := range []model.LabelValue{"a", "b"} {
+		fp := model.LabelSet{"alertname": name}.Fingerprint()
+		_, err := alerts.Get(fp)
+		require.NoError(t, err, "alert %q", name)
+	}
+
+	// Malformed batches are still rejected as a whole.
+	rec = httptest.NewRecorder()
+	api.addAlerts(rec, httptest.NewRequest("POST", "/alerts", strings.NewReader(`{}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAddAlertsIgnoresEnrichment(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+	api := &API{alerts: alerts, resolveTimeout: time.Minute, logger: log.NewNopLogger()}
+
+	body := `[{
+		"labels": {"alertname": "a"},
+		"annotations": {"summary": "disk full"},
+		"enrichment": {"annotations": {"runbook": "x"}, "sources": {"runbook": "trusted-bot"}}
+	}]`
+	rec := httptest.NewRecorder()
+	api.addAlerts(rec, httptest.NewRequest("POST", "/alerts", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	a, err := alerts.Get(model.LabelSet{"alertname": "a"}.Fingerprint())
+	require.NoError(t, err)
+	require.Nil(t, a.Enrichment)
+	require.Equal(t, model.LabelSet{"summary": "disk full"}, a.Annotations)
+}
+
+func TestAddAlertsMemoryLimit(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+	api := &API{alerts: alerts, config: &config.Config{}, resolveTimeout: time.Minute, logger: log.NewNopLogger()}
+
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		api.addAlerts(rec, httptest.NewRequest("POST", "/alerts", strings.NewReader(body)))
+		return rec.Code
+	}
+	require.Equal(t, http.StatusOK, post(`[{"labels": {"alertname": "a"}}]`))
+
+	used := alerts.MemoryUsage().Used
+	alerts.SetMemoryLimit(2 * used)
+
+	// Refused alerts exceeding the whole budget can never be accepted. The
+	// alerts of the batch still fitting into it are stored.
+	require.Equal(t, http.StatusRequestEntityTooLarge, post(`[
+		{"labels": {"alertname": "b"}},
+		{"labels": {"alertname": "c"}},
+		{"labels": {"alertname": "d"}},
+		{"labels": {"alertname": "e"}}
+	]`))
+	_, err = alerts.Get(model.LabelSet{"alertname": "b"}.Fingerprint())
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusTooManyRequests, post(`[{"labels": {"alertname": "c"}}]`))
+	// Stored alerts can still be updated.
+	require.Equal(t, http.StatusOK, post(`[{"labels": {"alertname": "a"}, "startsAt": "2017-01-01T00:00:00Z", "endsAt": "2017-01-02T00:00:00Z"}]`))
+
+	rec := httptest.NewRecorder()
+	api.status(rec, httptest.NewRequest("GET", "/status", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var res struct {
+		Data struct {
+			AlertMemory provider.MemoryUsage `json:"alertMemory"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, provider.MemoryUsage{Used: 2 * used, Limit: 2 * used}, res.Data.AlertMemory)
+}
+
+func TestResolveAlerts(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+	api := &API{alerts: alerts, logger: log.NewNopLogger()}
+
+	now := time.Now()
+	newAlert := func(name model.LabelValue, endsAt time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": name, "team": "a"},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   endsAt,
+			},
+			UpdatedAt: now.Add(-time.Minute),
+		}
+	}
+	var (
+		firing   = newAlert("firing", now.Add(time.Hour))
+		timeout  = newAlert("timeout", now.Add(time.Hour))
+		resolved = newAlert("resolved", now.Add(-time.Minute))
+	)
+	timeout.Timeout = true
+	require.NoError(t, alerts.Put(firing, timeout, resolved))
+
+	resolve := func(filter string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.resolveAlerts(rec, httptest.NewRequest("POST", "/alerts/resolve?filter="+url.QueryEscape(filter), nil))
+		return rec
+	}
+	fingerprints := func(rec *httptest.ResponseRecorder) []string {
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var res struct {
+			Data []string `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Data
+	}
+
+	require.Equal(t, http.StatusBadRequest, resolve("").Code)
+	require.Equal(t, http.StatusBadRequest, resolve(`{team=}`).Code)
+
+	// Filters matching no active alerts resolve nothing.
+	require.Equal(t, []string{}, fingerprints(resolve(`{alertname="unknown"}`)))
+	require.Equal(t, []string{}, fingerprints(resolve(`{alertname="resolved"}`)))
+
+	exp := []string{firing.Fingerprint().String(), timeout.Fingerprint().String()}
+	sort.Strings(exp)
+	require.Equal(t, exp, fingerprints(resolve(`{team="a"}`)))
+
+	for _, a := range []*types.Alert{firing, timeout} {
+		got, err := alerts.Get(a.Fingerprint())
+		require.NoError(t, err)
+		require.True(t, got.Resolved(), "%s", a.Name())
+		require.True(t, got.ForceResolved, "%s", a.Name())
+		require.False(t, got.Timeout, "%s", a.Name())
+	}
+	got, err := alerts.Get(resolved.Fingerprint())
+	require.NoError(t, err)
+	require.False(t, got.ForceResolved)
+	require.Equal(t, resolved.EndsAt.Unix(), got.EndsAt.Unix())
+
+	// Alerts already resolved are not resolved again.
+	require.Equal(t, []string{}, fingerprints(resolve(`{team="a"}`)))
+}
+
+func TestAnnotateAlert(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	require.NoError(t, err)
+
+	conf := &config.AlertEnrichment{
+		MaxSize: 30,
+		Sources: []*config.EnrichmentSource{{Name: "triage", Token: "s3cr3t"}},
+	}
+	api := &API{
+		alerts: alerts,
+		config: &config.Config{AlertEnrichment: conf},
+		logger: log.NewNopLogger(),
+	}
+
+	now := time.Now()
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "HighLatency"},
+			Annotations: model.LabelSet{"summary": "latency is high"},
+			StartsAt:    now.Add(-time.Minute),
+			EndsAt:      now.Add(time.Hour),
+		},
+		UpdatedAt: now.Add(-time.Minute),
+	}
+	require.NoError(t, alerts.Put(alert))
+	fp := alert.Fingerprint().String()
+
+	annotate := func(fp, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/alert/"+fp+"/annotations", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		api.annotateAlert(rec, req.WithContext(route.WithParam(req.Context(), "fingerprint", fp)))
+		return rec
+	}
+
+	rec := annotate(fp, "", `{"annotations":{"owner":"db"}}`)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = annotate(fp, "wrong", `{"annotations":{"owner":"db"}}`)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = annotate("0000000000000001", "s3cr3t", `{"annotations":{"owner":"db"}}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = annotate(fp, "s3cr3t", `{"annotations":{"owner":"db","summary":"disk full"}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = annotate(fp, "s3cr3t", `{"annotations":{"runbook":"http://example.com"}}`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+
+	got, err := alerts.Get(alert.Fingerprint())
+	require.NoError(t, err)
+	require.Equal(t, model.LabelSet{"summary": "disk full", "owner": "db"}, got.Annotations)
+	require.Equal(t, map[model.LabelName]string{"summary": "triage", "owner": "triage"}, got.Enrichment.Sources)
+
+	// Attached annotations are kept as the source of the alert updates it.
+	update := *alert
+	update.Annotations = model.LabelSet{"summary": "latency is very high", "severity": "page"}
+	update.UpdatedAt = now
+	require.NoError(t, alerts.Put(&update))
+
+	got, err = alerts.Get(alert.Fingerprint())
+	require.NoError(t, err)
+	require.Equal(t, model.LabelSet{"summary": "disk full", "owner": "db", "severity": "page"}, got.Annotations)
+
+	// Empty values remove annotations.
+	rec = annotate(fp, "s3cr3t", `{"annotations":{"summary":""}}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	got, err = alerts.Get(alert.Fingerprint())
+	require.NoError(t, err)
+	require.Equal(t, model.LabelSet{"owner": "db", "severity": "page"}, got.Annotations)
+	require.Equal(t, model.LabelSet{"owner": "db"}, got.Enrichment.Annotations)
+}
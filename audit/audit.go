@@ -0,0 +1,218 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records mutating requests made against the web endpoints.
+//
+// Alertmanager does not authenticate requests itself. The users recorded
+// are taken from a request header, which can only be trusted if an
+// authenticating proxy in front of Alertmanager sets it and removes it
+// from client requests. The same proxy should restrict access to the
+// audit endpoints.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxErrorLength bounds the size of response bodies kept for failed requests.
+const maxErrorLength = 512
+
+// Entry describes a single mutating request and its outcome.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	Principal  string    `json:"principal,omitempty"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Summary    string    `json:"summary,omitempty"`
+	Status     int       `json:"status"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DefaultPrincipalHeader is the request header naming the user on whose
+// behalf requests are made unless configured otherwise. Clients can set
+// it freely unless a trusted proxy overrides it.
+const DefaultPrincipalHeader = "X-Forwarded-User"
+
+// Log keeps the most recent audit entries in memory and optionally
+// streams all entries as JSON lines to a writer. A nil Log records nothing.
+// All methods are goroutine-safe.
+type Log struct {
+	mtx     sync.RWMutex
+	entries []*Entry
+	size    int
+	next    int
+	full    bool
+
+	silences    []*SilenceEvent
+	silenceNext int
+	silenceFull bool
+
+	header string
+	enc    *json.Encoder
+	now    func() time.Time
+}
+
+// New returns a Log retaining up to size entries and size silence events.
+// If w is not nil, every entry and event is additionally written to it.
+func New(size int, w io.Writer) *Log {
+	l := &Log{
+		entries:  make([]*Entry, size),
+		silences: make([]*SilenceEvent, size),
+		size:     size,
+		header:   DefaultPrincipalHeader,
+		now:      time.Now,
+	}
+	if w != nil {
+		l.enc = json.NewEncoder(w)
+	}
+	return l
+}
+
+// Record adds the entry to the log.
+func (l *Log) Record(e *Entry) error {
+	if l == nil {
+		return nil
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.size > 0 {
+		l.entries[l.next] = e
+		l.next = (l.next + 1) % l.size
+		l.full = l.full || l.next == 0
+	}
+	if l.enc != nil {
+		return l.enc.Encode(e)
+	}
+	return nil
+}
+
+// Entries returns all retained entries recorded at or after the given
+// time, oldest first.
+func (l *Log) Entries(since time.Time) []*Entry {
+	res := []*Entry{}
+	if l == nil {
+		return res
+	}
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	var all []*Entry
+	if l.full {
+		all = append(all, l.entries[l.next:]...)
+	}
+	all = append(all, l.entries[:l.next]...)
+
+	for _, e := range all {
+		if !e.Time.Before(since) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+type contextKey int
+
+const keyEntry contextKey = iota
+
+// Summarize sets the payload summary of the entry recorded for the request.
+// It has no effect if the request is not audited.
+func Summarize(r *http.Request, format string, args ...interface{}) {
+	if e, ok := r.Context().Value(keyEntry).(*Entry); ok {
+		e.Summary = fmt.Sprintf(format, args...)
+	}
+}
+
+// Wrap returns a handler that records an entry with the given action for
+// every request served by h.
+func (l *Log) Wrap(action string, h http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		e := &Entry{
+			Time:       l.now(),
+			Action:     action,
+			Principal:  l.Principal(r),
+			RemoteAddr: r.RemoteAddr,
+		}
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		h(rw, r.WithContext(context.WithValue(r.Context(), keyEntry, e)))
+
+		e.Status = rw.status
+		e.Success = rw.status < 400
+		if !e.Success {
+			e.Error = rw.body
+		}
+		l.Record(e)
+	}
+}
+
+// SetPrincipalHeader sets the request header naming the user on whose
+// behalf requests are made, which is typically set by an authenticating
+// proxy.
+func (l *Log) SetPrincipalHeader(name string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.header = name
+}
+
+// Principal returns the user on whose behalf the request was made, as
+// far as it is known. A nil Log reads the default principal header. Users
+// given by basic authentication are ignored as Alertmanager does not
+// verify their passwords.
+func (l *Log) Principal(r *http.Request) string {
+	header := DefaultPrincipalHeader
+	if l != nil {
+		l.mtx.RLock()
+		header = l.header
+		l.mtx.RUnlock()
+	}
+	if header == "" {
+		return ""
+	}
+	return r.Header.Get(header)
+}
+
+// responseWriter captures the status code and the beginning of the body
+// of error responses.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	body   string
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status >= 400 && len(w.body) < maxErrorLength {
+		n := maxErrorLength - len(w.body)
+		if n > len(b) {
+			n = len(b)
+		}
+		w.body += string(b[:n])
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogWrap(t *testing.T) {
+	var (
+		now = time.Now()
+		buf bytes.Buffer
+		l   = New(10, &buf)
+	)
+	l.now = func() time.Time { return now }
+
+	ok := l.Wrap("ok", func(w http.ResponseWriter, r *http.Request) {
+		Summarize(r, "id=%q", "abc")
+		w.Write([]byte("done"))
+	})
+	fail := l.Wrap("fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("bad input"))
+	})
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Forwarded-User", "alice")
+	ok(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Forwarded-User", "bob")
+	rec := httptest.NewRecorder()
+	fail(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	expected := []*Entry{
+		{
+			Time:       now,
+			Action:     "ok",
+			Principal:  "alice",
+			RemoteAddr: req.RemoteAddr,
+			Summary:    `id="abc"`,
+			Status:     http.StatusOK,
+			Success:    true,
+		},
+		{
+			Time:       now,
+			Action:     "fail",
+			Principal:  "bob",
+			RemoteAddr: req.RemoteAddr,
+			Status:     http.StatusBadRequest,
+			Error:      "bad input",
+		},
+	}
+	require.Equal(t, expected, l.Entries(time.Time{}))
+
+	dec := json.NewDecoder(&buf)
+	for _, exp := range expected {
+		var e Entry
+		require.NoError(t, dec.Decode(&e))
+		require.Equal(t, exp.Action, e.Action)
+		require.Equal(t, exp.Status, e.Status)
+	}
+}
+
+func TestLogEntries(t *testing.T) {
+	var (
+		now = time.Now()
+		l   = New(3, nil)
+	)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Record(&Entry{Time: now.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	res := l.Entries(time.Time{})
+	require.Len(t, res, 3)
+	for i, e := range res {
+		require.Equal(t, now.Add(time.Duration(i+2)*time.Minute), e.Time)
+	}
+
+	require.Len(t, l.Entries(now.Add(3*time.Minute)), 2)
+
+	var nl *Log
+	require.NoError(t, nl.Record(&Entry{}))
+	require.Empty(t, nl.Entries(time.Time{}))
+}
+
+func TestLogPrincipal(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Forwarded-User", "bob")
+	req.Header.Set("X-Auth-User", "carol")
+
+	var nl *Log
+	require.Equal(t, "bob", nl.Principal(req))
+
+	l := New(1, nil)
+	require.Equal(t, "bob", l.Principal(req))
+	l.SetPrincipalHeader("X-Auth-User")
+	require.Equal(t, "carol", l.Principal(req))
+	l.SetPrincipalHeader("")
+	require.Equal(t, "", l.Principal(req))
+
+	// Unverified basic authentication cannot spoof the principal.
+	l.SetPrincipalHeader(DefaultPrincipalHeader)
+	req.SetBasicAuth("alice", "secret")
+	require.Equal(t, "bob", l.Principal(req))
+}
+
+func TestLogSilenceEvents(t *testing.T) {
+	var (
+		now = time.Now()
+		buf bytes.Buffer
+		l   = New(3, &buf)
+	)
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Forwarded-User", "bob")
+
+	for i, id := range []string{"a", "b", "c", "d"} {
+		l.now = func() time.Time { return now.Add(time.Duration(i) * time.Minute) }
+		require.NoError(t, l.RecordSilence(req, &SilenceEvent{Action: SilenceCreate, SilenceID: id}))
+	}
+	l.now = func() time.Time { return now.Add(4 * time.Minute) }
+	require.NoError(t, l.RecordSilence(req, &SilenceEvent{Action: SilenceUpdate, SilenceID: "e", PreviousID: "c"}))
+
+	res := l.SilenceEvents("", time.Time{})
+	require.Len(t, res, 3)
+	for i, e := range res {
+		require.Equal(t, now.Add(time.Duration(i+2)*time.Minute), e.Time)
+		require.Equal(t, "bob", e.Principal)
+		require.Equal(t, req.RemoteAddr, e.RemoteAddr)
+	}
+
+	res = l.SilenceEvents("c", time.Time{})
+	require.Len(t, res, 2)
+	require.Equal(t, "c", res[0].SilenceID)
+	require.Equal(t, "e", res[1].SilenceID)
+
+	require.Len(t, l.SilenceEvents("", now.Add(3*time.Minute)), 2)
+	require.Empty(t, l.SilenceEvents("a", time.Time{}))
+
+	dec := json.NewDecoder(&buf)
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		var e SilenceEvent
+		require.NoError(t, dec.Decode(&e))
+		require.Equal(t, id, e.SilenceID)
+	}
+
+	var nl *Log
+	require.NoError(t, nl.RecordSilence(req, &SilenceEvent{}))
+	require.Empty(t, nl.SilenceEvents("", time.Time{}))
+}
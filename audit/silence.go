@@ -0,0 +1,98 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"net/http"
+	"time"
+)
+
+// Actions of silence events.
+const (
+	SilenceCreate = "create"
+	SilenceUpdate = "update"
+	SilenceExpire = "expire"
+)
+
+// SilenceEvent describes a change made to a silence through the web
+// endpoints of this instance. The silence fields hold its state after the
+// change.
+type SilenceEvent struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	SilenceID string    `json:"silenceId"`
+	// PreviousID is the ID of the silence that was expired and replaced by
+	// the silence as it could not be updated in place.
+	PreviousID string    `json:"previousId,omitempty"`
+	Principal  string    `json:"principal,omitempty"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Matchers   string    `json:"matchers,omitempty"`
+	StartsAt   time.Time `json:"startsAt"`
+	EndsAt     time.Time `json:"endsAt"`
+	CreatedBy  string    `json:"createdBy,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+}
+
+// RecordSilence adds the event for the change made by the request to the
+// log. Its time, principal and remote address are taken from the request.
+func (l *Log) RecordSilence(r *http.Request, e *SilenceEvent) error {
+	if l == nil {
+		return nil
+	}
+	e.Time = l.now()
+	e.Principal = l.Principal(r)
+	e.RemoteAddr = r.RemoteAddr
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.size > 0 {
+		l.silences[l.silenceNext] = e
+		l.silenceNext = (l.silenceNext + 1) % l.size
+		l.silenceFull = l.silenceFull || l.silenceNext == 0
+	}
+	if l.enc != nil {
+		return l.enc.Encode(e)
+	}
+	return nil
+}
+
+// SilenceEvents returns all retained events recorded at or after the
+// given time, oldest first. If id is not empty, only events of the silence
+// with the ID and of updates replacing it are returned.
+func (l *Log) SilenceEvents(id string, since time.Time) []*SilenceEvent {
+	res := []*SilenceEvent{}
+	if l == nil {
+		return res
+	}
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	var all []*SilenceEvent
+	if l.silenceFull {
+		all = append(all, l.silences[l.silenceNext:]...)
+	}
+	all = append(all, l.silences[:l.silenceNext]...)
+
+	for _, e := range all {
+		if e.Time.Before(since) {
+			continue
+		}
+		if id != "" && e.SilenceID != id && e.PreviousID != id {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+type alertmanagerStateResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	ErrorType string          `json:"errorType,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// backupState is the state exported by an Alertmanager. Only the parts
+// needed to restore it are decoded.
+type backupState struct {
+	Silences        json.RawMessage `json:"silences"`
+	NotificationLog json.RawMessage `json:"notificationLog"`
+	Alerts          json.RawMessage `json:"alerts,omitempty"`
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [file]",
+	Short: "Export the state of an alertmanager",
+	Long: `Export the silences, notification log and active alerts of an alertmanager
+
+  amtool backup --alertmanager.url=http://old:9093 state.json
+
+	Writes the state as JSON to state.json, or to stdout if no file is given.
+
+  The alertmanager must run with -web.enable-state-transfer.
+	`,
+	Run: CommandWrapper(backup),
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore the state of an alertmanager from a backup",
+	Long: `Restore silences, notification log and active alerts from a backup
+
+  amtool restore --alertmanager.url=http://new:9093 state.json
+
+	Silences and notification log entries are merged into the state of the
+	alertmanager, keeping newer ones it already has. Alerts are sent to it
+	like alerts from any other client.
+
+  The alertmanager must run with -web.enable-state-transfer.
+	`,
+	Run: CommandWrapper(restore),
+}
+
+func init() {
+	RootCmd.AddCommand(backupCmd)
+	RootCmd.AddCommand(restoreCmd)
+}
+
+func backup(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return errors.New("Only one backup file can be given")
+	}
+	u, err := GetAlertmanagerURL()
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join(u.Path, "/api/v1/admin/export")
+
+	res, err := http.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var response alertmanagerStateResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("Unable to parse state json response from %s", u.String())
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("[%s] %s", response.ErrorType, response.Error)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, response.Data, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+
+	if len(args) == 0 {
+		_, err = buf.WriteTo(os.Stdout)
+		return err
+	}
+	return ioutil.WriteFile(args[0], buf.Bytes(), 0644)
+}
+
+func restore(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Exactly one backup file must be given")
+	}
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var state backupState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("Invalid backup file %s: %s", args[0], err)
+	}
+
+	var imported struct {
+		Silences        int `json:"silences"`
+		NotificationLog int `json:"notificationLog"`
+	}
+	err = postState("/api/v1/admin/import", backupState{
+		Silences:        state.Silences,
+		NotificationLog: state.NotificationLog,
+	}, &imported)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d silences and %d notification log entries\n", imported.Silences, imported.NotificationLog)
+
+	var alerts []json.RawMessage
+	if len(state.Alerts) > 0 {
+		if err := json.Unmarshal(state.Alerts, &alerts); err != nil {
+			return fmt.Errorf("Invalid alerts in backup file %s: %s", args[0], err)
+		}
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	if err := postState("/api/v1/alerts", alerts, nil); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d alerts\n", len(alerts))
+	return nil
+}
+
+// postState posts v as JSON to the API path and decodes the response data
+// into out unless it is nil.
+func postState(p string, v interface{}, out interface{}) error {
+	u, err := GetAlertmanagerURL()
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join(u.Path, p)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	res, err := http.Post(u.String(), "application/json", &buf)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var response alertmanagerStateResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return fmt.Errorf("Unable to parse json response from %s", u.String())
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("[%s] %s", response.ErrorType, response.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Data, out)
+}
@@ -1,11 +1,23 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
 )
 
 // alertCmd represents the alert command
@@ -17,7 +29,16 @@ var checkConfigCmd = &cobra.Command{
 
 Will validate the syntax and schema for alertmanager config file
 and associated templates. Non existing templates will not trigger
-errors`,
+errors.
+
+The templated fields of all receivers are rendered against sample data
+to catch errors that only occur when notifications are sent. Queries to
+Prometheus return no samples while rendering. Environment variables
+referenced by enabled_if conditions that are not set are reported as
+warnings.
+
+With the json output format a report of all files is written instead,
+e.g. for use in CI pipelines. The command fails if any file has errors.`,
 	RunE: checkConfig,
 }
 
@@ -31,35 +52,271 @@ func checkConfig(cmd *cobra.Command, args []string) error {
 	return CheckConfig(args)
 }
 
+// configReport is the result of checking a single config file.
+type configReport struct {
+	File      string   `json:"file"`
+	Success   bool     `json:"success"`
+	Templates int      `json:"templates"`
+	Errors    []string `json:"errors,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
 func CheckConfig(args []string) error {
 	failed := 0
 
+	reports := make([]*configReport, 0, len(args))
 	for _, arg := range args {
-		fmt.Printf("Checking '%s'", arg)
-		config, _, err := config.LoadFile(arg)
-		if err != nil {
-			fmt.Printf("  FAILED: %s\n", err)
+		r := checkConfigFile(arg)
+		if !r.Success {
 			failed++
-		} else {
-			fmt.Printf("  SUCCESS\n")
 		}
+		reports = append(reports, r)
+	}
 
-		if config != nil {
-			fmt.Printf("Found %d templates: ", len(config.Templates))
-			if len(config.Templates) > 0 {
-				_, err = template.FromGlobs(config.Templates...)
-				if err != nil {
-					fmt.Printf("  FAILED: %s\n", err)
-					failed++
-				} else {
-					fmt.Printf("  SUCCESS\n")
-				}
+	if viper.GetString("output") == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range reports {
+			fmt.Printf("Checking '%s'", r.File)
+			if r.Success {
+				fmt.Printf("  SUCCESS\n")
+			} else {
+				fmt.Printf("  FAILED\n")
+			}
+			fmt.Printf("Found %d template files\n", r.Templates)
+			for _, e := range r.Errors {
+				fmt.Printf("  ERROR: %s\n", e)
 			}
+			for _, w := range r.Warnings {
+				fmt.Printf("  WARNING: %s\n", w)
+			}
+			fmt.Printf("\n")
 		}
-		fmt.Printf("\n")
 	}
 	if failed > 0 {
 		return fmt.Errorf("Failed to validate %d file(s).", failed)
 	}
 	return nil
 }
+
+// checkConfigFile loads the config file and its templates and renders
+// the templated fields of all receivers.
+func checkConfigFile(filename string) *configReport {
+	r := &configReport{File: filename}
+	defer func() { r.Success = len(r.Errors) == 0 }()
+
+	conf, content, err := config.LoadFile(filename)
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+		return r
+	}
+	r.Warnings = append(r.Warnings, checkEnvReferences(content)...)
+
+	for _, glob := range conf.Templates {
+		files, err := filepath.Glob(glob)
+		if err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("template %q: %s", glob, err))
+			continue
+		}
+		if len(files) == 0 {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("template %q matches no files", glob))
+		}
+		r.Templates += len(files)
+	}
+	if len(r.Errors) > 0 {
+		return r
+	}
+
+	tmpl, err := template.FromGlobs(conf.Templates...)
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+		return r
+	}
+	for _, ref := range conf.TemplateRefs() {
+		if !tmpl.Defined(ref.Name) {
+			r.Errors = append(r.Errors, fmt.Sprintf("%s: template %q is not defined", ref.Path, ref.Name))
+		}
+	}
+	if len(r.Errors) > 0 {
+		return r
+	}
+	tmpl.ExternalURL, _ = url.Parse("http://localhost:9093")
+	tmpl.SummaryThreshold = conf.Global.SummaryThreshold
+	tmpl.Severities = conf.Global.Severities()
+	tmpl.Query = &template.QueryOptions{DryRun: true}
+
+	for _, rcv := range conf.Receivers {
+		r.Errors = append(r.Errors, checkReceiverTemplates(tmpl, rcv)...)
+	}
+	return r
+}
+
+// sampleAlerts are the alerts that templates are rendered for.
+func sampleAlerts() []*types.Alert {
+	now := time.Now()
+	return []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": "SampleAlert",
+					"instance":  "localhost:9090",
+					"severity":  "critical",
+				},
+				Annotations: model.LabelSet{
+					"summary":     "Sample alert",
+					"description": "Sample alert rendered by amtool check-config.",
+				},
+				StartsAt:     now.Add(-time.Hour),
+				GeneratorURL: "http://localhost:9090/graph",
+			},
+			UpdatedAt: now,
+		},
+		{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": "SampleAlert",
+					"instance":  "localhost:9100",
+					"severity":  "warning",
+				},
+				StartsAt: now.Add(-2 * time.Hour),
+				EndsAt:   now.Add(-time.Minute),
+			},
+			UpdatedAt: now,
+		},
+	}
+}
+
+// checkReceiverTemplates renders all templated fields of the receiver's
+// notifier configs and returns the errors encountered.
+func checkReceiverTemplates(tmpl *template.Template, rcv *config.Receiver) []string {
+	var (
+		errs []string
+		data = tmpl.Data(rcv.Name, model.LabelSet{"alertname": "SampleAlert"}, sampleAlerts()...)
+	)
+	v := reflect.ValueOf(rcv).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Slice {
+			continue
+		}
+		name := yamlName(v.Type().Field(i))
+		for j := 0; j < f.Len(); j++ {
+			renderFields(f.Index(j), fmt.Sprintf("%s[%d]", name, j), func(path, text string, html bool) {
+				var err error
+				if html {
+					_, err = tmpl.ExecuteHTMLString(text, data)
+				} else {
+					_, err = tmpl.ExecuteTextString(text, data)
+				}
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("receiver %q: %s: %s", rcv.Name, path, err))
+				}
+			})
+		}
+	}
+	return errs
+}
+
+var (
+	configPkgPath = reflect.TypeOf(config.Config{}).PkgPath()
+	secretType    = reflect.TypeOf(config.Secret(""))
+)
+
+// renderFields calls render for all string fields of the config value and
+// the config values nested in it. Secrets are not templated and skipped.
+func renderFields(v reflect.Value, path string, render func(path, text string, html bool)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			renderFields(v.Elem(), path, render)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			renderFields(v.Index(i), fmt.Sprintf("%s[%d]", path, i), render)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String || v.Type().Elem() == secretType {
+			return
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			render(fmt.Sprintf("%s.%v", path, k), v.MapIndex(k).String(), false)
+		}
+	case reflect.Struct:
+		if v.Type().PkgPath() != configPkgPath {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			p := path
+			if !sf.Anonymous {
+				p = path + "." + yamlName(sf)
+			}
+			f := v.Field(i)
+			if f.Kind() == reflect.String {
+				if f.Type() != secretType {
+					render(p, f.String(), sf.Name == "HTML")
+				}
+				continue
+			}
+			renderFields(f, p, render)
+		}
+	}
+}
+
+// yamlName returns the name of the struct field in the YAML config.
+func yamlName(sf reflect.StructField) string {
+	if n := strings.Split(sf.Tag.Get("yaml"), ",")[0]; n != "" {
+		return n
+	}
+	return sf.Name
+}
+
+// checkEnvReferences returns warnings for environment variables referenced
+// by enabled_if conditions of the raw config that are not set.
+func checkEnvReferences(content []byte) []string {
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil
+	}
+	var (
+		warnings []string
+		seen     = map[string]struct{}{}
+	)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[interface{}]interface{}:
+			for k, e := range v {
+				if k == "enabled_if" {
+					name, _ := e.(string)
+					name = strings.TrimPrefix(name, "!")
+					if _, ok := seen[name]; ok || name == "" {
+						continue
+					}
+					seen[name] = struct{}{}
+					if _, ok := os.LookupEnv(name); !ok {
+						warnings = append(warnings, fmt.Sprintf("environment variable %q referenced by enabled_if is not set", name))
+					}
+					continue
+				}
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(raw)
+	sort.Strings(warnings)
+	return warnings
+}
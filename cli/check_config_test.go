@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -15,3 +17,28 @@ func TestCheckConfig(t *testing.T) {
 		t.Fatalf("Failed to detect invalid file.")
 	}
 }
+
+func TestCheckConfigTemplates(t *testing.T) {
+	r := checkConfigFile("testdata/conf.templates.yml")
+	if r.Success {
+		t.Fatalf("Failed to detect invalid template.")
+	}
+	if len(r.Errors) != 1 || !strings.HasPrefix(r.Errors[0], `receiver "team-a": slack_configs[0].text: `) {
+		t.Errorf("Unexpected errors %q", r.Errors)
+	}
+	exp := []string{`environment variable "AMTOOL_TEST_UNSET_VARIABLE" referenced by enabled_if is not set`}
+	if !reflect.DeepEqual(r.Warnings, exp) {
+		t.Errorf("Unexpected warnings %q", r.Warnings)
+	}
+}
+
+func TestCheckConfigTemplateRefs(t *testing.T) {
+	r := checkConfigFile("testdata/conf.template-refs.yml")
+	if r.Success {
+		t.Fatalf("Failed to detect undefined template.")
+	}
+	exp := []string{`receivers[team-a].slack_configs[0].text_template: template "team.missing" is not defined`}
+	if !reflect.DeepEqual(r.Errors, exp) {
+		t.Errorf("Unexpected errors %q", r.Errors)
+	}
+}
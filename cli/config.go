@@ -56,8 +56,52 @@ The amount of output is controlled by the output selection flag:
 	RunE: queryConfig,
 }
 
+var configReceiversCmd = &cobra.Command{
+	Use:   "receivers",
+	Short: "List the configured receivers",
+	Long: `List the configured receivers along with their metadata
+
+The amount of output is controlled by the output selection flag:
+	- Simple: Print receiver names and owners
+	- Extended: Print all receiver metadata
+	- Json: Print receivers as json`,
+	RunE: queryReceivers,
+}
+
+var configRoutesCmd = &cobra.Command{
+	Use:   "routes",
+	Short: "List the effective configuration of all leaf routes",
+	Long: `List the effective configuration of all leaf routes
+
+Every route without child routes is listed with the matchers of all
+routes leading to it and the routing options it inherited from them.
+Routes are identified by the indices of the child routes from the root.
+
+The amount of output is controlled by the output selection flag:
+	- Simple: Print the matchers, receiver, grouping, intervals and owner
+	- Extended: Print all routing options and metadata
+	- Json: Print routes as json`,
+	RunE: queryRoutes,
+}
+
+type alertmanagerRoutesResponse struct {
+	Status    string                  `json:"status"`
+	Data      []format.EffectiveRoute `json:"data,omitempty"`
+	ErrorType string                  `json:"errorType,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+}
+
+type alertmanagerReceiversResponse struct {
+	Status    string            `json:"status"`
+	Data      []format.Receiver `json:"data,omitempty"`
+	ErrorType string            `json:"errorType,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
 func init() {
 	RootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configReceiversCmd)
+	configCmd.AddCommand(configRoutesCmd)
 }
 
 func fetchConfig() (Config, error) {
@@ -102,3 +146,86 @@ func queryConfig(cmd *cobra.Command, args []string) error {
 
 	return formatter.FormatConfig(c)
 }
+
+func fetchReceivers() ([]format.Receiver, error) {
+	receiversResponse := alertmanagerReceiversResponse{}
+	u, err := GetAlertmanagerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = path.Join(u.Path, "/api/v1/receivers")
+	u.RawQuery = "metadata=true"
+	res, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	err = json.NewDecoder(res.Body).Decode(&receiversResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if receiversResponse.Status != "success" {
+		return nil, fmt.Errorf("[%s] %s", receiversResponse.ErrorType, receiversResponse.Error)
+	}
+
+	return receiversResponse.Data, nil
+}
+
+func queryReceivers(cmd *cobra.Command, args []string) error {
+	receivers, err := fetchReceivers()
+	if err != nil {
+		return err
+	}
+
+	formatter, found := format.Formatters[viper.GetString("output")]
+	if !found {
+		return errors.New("Unknown output formatter")
+	}
+
+	return formatter.FormatReceivers(receivers)
+}
+
+func fetchRoutes() ([]format.EffectiveRoute, error) {
+	routesResponse := alertmanagerRoutesResponse{}
+	u, err := GetAlertmanagerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = path.Join(u.Path, "/api/v1/routes/effective")
+	res, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	err = json.NewDecoder(res.Body).Decode(&routesResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if routesResponse.Status != "success" {
+		return nil, fmt.Errorf("[%s] %s", routesResponse.ErrorType, routesResponse.Error)
+	}
+
+	return routesResponse.Data, nil
+}
+
+func queryRoutes(cmd *cobra.Command, args []string) error {
+	routes, err := fetchRoutes()
+	if err != nil {
+		return err
+	}
+
+	formatter, found := format.Formatters[viper.GetString("output")]
+	if !found {
+		return errors.New("Unknown output formatter")
+	}
+
+	return formatter.FormatRoutes(routes)
+}
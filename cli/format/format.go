@@ -2,6 +2,8 @@ package format
 
 import (
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/alertmanager/config"
@@ -34,12 +36,77 @@ type PeerStatus struct {
 	UID      uint64 `uid`
 }
 
+// Receiver is a receiver name along with its configured metadata.
+type Receiver struct {
+	Name     string           `json:"name"`
+	Metadata *config.Metadata `json:"metadata,omitempty"`
+}
+
+// RouteMatchers are the conditions of a single route along the path to a
+// leaf route.
+type RouteMatchers struct {
+	Matchers    types.Matchers `json:"matchers"`
+	MinSeverity string         `json:"minSeverity,omitempty"`
+}
+
+// RouteOpts are the resolved routing options of a route.
+type RouteOpts struct {
+	Receiver           string        `json:"receiver"`
+	GroupBy            []string      `json:"groupBy"`
+	GroupWait          time.Duration `json:"groupWait"`
+	GroupInterval      time.Duration `json:"groupInterval"`
+	RepeatInterval     time.Duration `json:"repeatInterval"`
+	GroupIntervalMax   time.Duration `json:"groupIntervalMax,omitempty"`
+	ResolveInterval    time.Duration `json:"resolveInterval,omitempty"`
+	ResolvedMaxAge     time.Duration `json:"resolvedMaxAge,omitempty"`
+	EscalateAfter      int           `json:"escalateAfter,omitempty"`
+	EscalationReceiver string        `json:"escalationReceiver,omitempty"`
+	ChangeDetection    string        `json:"changeDetection,omitempty"`
+	ChangeThresholds   []int         `json:"changeThresholds,omitempty"`
+	ChangeAnnotations  []string      `json:"changeAnnotations,omitempty"`
+}
+
+// EffectiveRoute is a leaf route along with the routing options it
+// inherited from its parents.
+type EffectiveRoute struct {
+	Path         []int            `json:"path"`
+	MatcherChain []RouteMatchers  `json:"matcherChain"`
+	Continue     bool             `json:"continue"`
+	RouteOpts    RouteOpts        `json:"routeOpts"`
+	Metadata     *config.Metadata `json:"metadata,omitempty"`
+}
+
+// NflogReceiver identifies the integration of a receiver a notification was
+// sent to.
+type NflogReceiver struct {
+	GroupName   string `json:"groupName"`
+	Integration string `json:"integration"`
+	Idx         uint32 `json:"idx"`
+	ConfigHash  string `json:"configHash,omitempty"`
+}
+
+// NflogEntry is the notification log entry of the last notification of an
+// aggregation group sent to a receiver.
+type NflogEntry struct {
+	Receiver       NflogReceiver `json:"receiver"`
+	GroupKey       string        `json:"groupKey"`
+	Resolved       bool          `json:"resolved"`
+	FiringAlerts   []uint64      `json:"firingAlerts"`
+	ResolvedAlerts []uint64      `json:"resolvedAlerts"`
+	Timestamp      time.Time     `json:"timestamp"`
+	ProviderID     string        `json:"providerId,omitempty"`
+	AcknowledgedAt *time.Time    `json:"acknowledgedAt,omitempty"`
+}
+
 // Formatter needs to be implemented for each new output formatter
 type Formatter interface {
 	SetOutput(io.Writer)
 	FormatSilences([]types.Silence) error
 	FormatAlerts([]*dispatch.APIAlert) error
 	FormatConfig(Config) error
+	FormatReceivers([]Receiver) error
+	FormatRoutes([]EffectiveRoute) error
+	FormatNflogEntries([]NflogEntry) error
 }
 
 // Formatters is a map of cli argument name to formatter inferface object
@@ -49,3 +116,32 @@ func FormatDate(input time.Time) string {
 	dateformat := viper.GetString("date.format")
 	return input.Format(dateformat)
 }
+
+// formatRoutePath returns the path of a route as the indices of the child
+// routes separated by slashes, or a single slash for the root route.
+func formatRoutePath(path []int) string {
+	s := make([]string, 0, len(path))
+	for _, i := range path {
+		s = append(s, strconv.Itoa(i))
+	}
+	return "/" + strings.Join(s, "/")
+}
+
+// formatMatcherChain returns the conditions of the routes along a path
+// separated by slashes. Routes without conditions are skipped.
+func formatMatcherChain(chain []RouteMatchers, formatMatchers func(types.Matchers) string) string {
+	var s []string
+	for _, rm := range chain {
+		m := formatMatchers(rm.Matchers)
+		if rm.MinSeverity != "" {
+			if m != "" {
+				m += " "
+			}
+			m += "severity>=" + rm.MinSeverity
+		}
+		if m != "" {
+			s = append(s, m)
+		}
+	}
+	return strings.Join(s, " / ")
+}
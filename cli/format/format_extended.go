@@ -8,6 +8,7 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/dispatch"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
@@ -28,15 +29,16 @@ func (formatter *ExtendedFormatter) SetOutput(writer io.Writer) {
 func (formatter *ExtendedFormatter) FormatSilences(silences []types.Silence) error {
 	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
 	sort.Sort(ByEndAt(silences))
-	fmt.Fprintln(w, "ID\tMatchers\tStarts At\tEnds At\tUpdated At\tCreated By\tComment\t")
+	fmt.Fprintln(w, "ID\tMatchers\tStarts At\tEnds At\tRecurrence\tUpdated At\tCreated By\tComment\t")
 	for _, silence := range silences {
 		fmt.Fprintf(
 			w,
-			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
 			silence.ID,
 			extendedFormatMatchers(silence.Matchers),
 			FormatDate(silence.StartsAt),
 			FormatDate(silence.EndsAt),
+			extendedFormatRecurrence(silence.Recurrence),
 			FormatDate(silence.UpdatedAt),
 			silence.CreatedBy,
 			silence.Comment,
@@ -77,6 +79,107 @@ func (formatter *ExtendedFormatter) FormatConfig(config Config) error {
 	return nil
 }
 
+func (formatter *ExtendedFormatter) FormatReceivers(receivers []Receiver) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tOwner\tLink\tDescription\t")
+	for _, r := range receivers {
+		md := r.Metadata
+		if md == nil {
+			md = &config.Metadata{}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", r.Name, md.Owner, md.Link, md.Description)
+	}
+	w.Flush()
+	return nil
+}
+
+func (formatter *ExtendedFormatter) FormatRoutes(routes []EffectiveRoute) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Path\tMatchers\tContinue\tReceiver\tGroup By\tGroup Wait\tGroup Interval\tGroup Interval Max\tRepeat Interval\tResolve Interval\tResolved Max Age\tEscalation\tChange Detection\tOwner\tLink\tDescription\t")
+	for _, r := range routes {
+		ro := r.RouteOpts
+		md := r.Metadata
+		if md == nil {
+			md = &config.Metadata{}
+		}
+		var escalation, changeDetection string
+		if ro.EscalateAfter > 0 {
+			escalation = fmt.Sprintf("%s after %d", ro.EscalationReceiver, ro.EscalateAfter)
+		}
+		if ro.ChangeDetection != "" {
+			changeDetection = ro.ChangeDetection
+			if len(ro.ChangeThresholds) > 0 {
+				changeDetection += fmt.Sprintf(" %v", ro.ChangeThresholds)
+			}
+		}
+		if len(ro.ChangeAnnotations) > 0 {
+			if changeDetection != "" {
+				changeDetection += " "
+			}
+			changeDetection += "annotations " + strings.Join(ro.ChangeAnnotations, ",")
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%t\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			formatRoutePath(r.Path),
+			formatMatcherChain(r.MatcherChain, extendedFormatMatchers),
+			r.Continue,
+			ro.Receiver,
+			strings.Join(ro.GroupBy, ","),
+			ro.GroupWait,
+			ro.GroupInterval,
+			ro.GroupIntervalMax,
+			ro.RepeatInterval,
+			ro.ResolveInterval,
+			ro.ResolvedMaxAge,
+			escalation,
+			changeDetection,
+			md.Owner,
+			md.Link,
+			md.Description,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+func (formatter *ExtendedFormatter) FormatNflogEntries(entries []NflogEntry) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Receiver\tIntegration\tIndex\tConfig Hash\tGroup Key\tNotified At\tFiring Alerts\tResolved Alerts\tProvider ID\tAcknowledged At\t")
+	for _, e := range entries {
+		var ackedAt string
+		if e.AcknowledgedAt != nil {
+			ackedAt = FormatDate(*e.AcknowledgedAt)
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			e.Receiver.GroupName,
+			e.Receiver.Integration,
+			e.Receiver.Idx,
+			e.Receiver.ConfigHash,
+			e.GroupKey,
+			FormatDate(e.Timestamp),
+			extendedFormatAlertHashes(e.FiringAlerts),
+			extendedFormatAlertHashes(e.ResolvedAlerts),
+			e.ProviderID,
+			ackedAt,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+// extendedFormatAlertHashes returns the hashes of the alerts of a
+// notification log entry as hexadecimal numbers.
+func extendedFormatAlertHashes(hashes []uint64) string {
+	output := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		output = append(output, fmt.Sprintf("%016x", h))
+	}
+	return strings.Join(output, ",")
+}
+
 func extendedFormatLabels(labels model.LabelSet) string {
 	output := []string{}
 	for name, value := range labels {
@@ -103,9 +206,29 @@ func extendedFormatMatchers(matchers types.Matchers) string {
 	return strings.Join(output, " ")
 }
 
+// extendedFormatRecurrence formats a recurrence like "22:00+4h saturday,sunday".
+func extendedFormatRecurrence(r *types.Recurrence) string {
+	if r == nil {
+		return ""
+	}
+	output := []string{r.StartTime + "+" + r.Duration}
+	if len(r.Weekdays) > 0 {
+		output = append(output, strings.Join(r.Weekdays, ","))
+	}
+	if r.Location != "" {
+		output = append(output, r.Location)
+	}
+	return strings.Join(output, " ")
+}
+
 func extendedFormatMatcher(matcher types.Matcher) string {
-	if matcher.IsRegex {
+	switch {
+	case matcher.IsRegex && matcher.IsNegative:
+		return fmt.Sprintf("%s!~%s", matcher.Name, matcher.Value)
+	case matcher.IsRegex:
 		return fmt.Sprintf("%s~=%s", matcher.Name, matcher.Value)
+	case matcher.IsNegative:
+		return fmt.Sprintf("%s!=%s", matcher.Name, matcher.Value)
 	}
 	return fmt.Sprintf("%s=%s", matcher.Name, matcher.Value)
 }
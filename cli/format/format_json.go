@@ -35,3 +35,18 @@ func (formatter *JSONFormatter) FormatConfig(config Config) error {
 	enc := json.NewEncoder(formatter.writer)
 	return enc.Encode(config)
 }
+
+func (formatter *JSONFormatter) FormatReceivers(receivers []Receiver) error {
+	enc := json.NewEncoder(formatter.writer)
+	return enc.Encode(receivers)
+}
+
+func (formatter *JSONFormatter) FormatRoutes(routes []EffectiveRoute) error {
+	enc := json.NewEncoder(formatter.writer)
+	return enc.Encode(routes)
+}
+
+func (formatter *JSONFormatter) FormatNflogEntries(entries []NflogEntry) error {
+	enc := json.NewEncoder(formatter.writer)
+	return enc.Encode(entries)
+}
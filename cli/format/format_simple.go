@@ -65,6 +65,64 @@ func (formatter *SimpleFormatter) FormatConfig(config Config) error {
 	return nil
 }
 
+func (formatter *SimpleFormatter) FormatReceivers(receivers []Receiver) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Name\tOwner\t")
+	for _, r := range receivers {
+		var owner string
+		if r.Metadata != nil {
+			owner = r.Metadata.Owner
+		}
+		fmt.Fprintf(w, "%s\t%s\t\n", r.Name, owner)
+	}
+	w.Flush()
+	return nil
+}
+
+func (formatter *SimpleFormatter) FormatRoutes(routes []EffectiveRoute) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Path\tMatchers\tReceiver\tGroup By\tGroup Wait\tGroup Interval\tRepeat Interval\tOwner\t")
+	for _, r := range routes {
+		var owner string
+		if r.Metadata != nil {
+			owner = r.Metadata.Owner
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			formatRoutePath(r.Path),
+			formatMatcherChain(r.MatcherChain, simpleFormatMatchers),
+			r.RouteOpts.Receiver,
+			strings.Join(r.RouteOpts.GroupBy, ","),
+			r.RouteOpts.GroupWait,
+			r.RouteOpts.GroupInterval,
+			r.RouteOpts.RepeatInterval,
+			owner,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+func (formatter *SimpleFormatter) FormatNflogEntries(entries []NflogEntry) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Receiver\tIntegration\tGroup Key\tNotified At\tFiring\tResolved\t")
+	for _, e := range entries {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%d\t%d\t\n",
+			e.Receiver.GroupName,
+			e.Receiver.Integration,
+			e.GroupKey,
+			FormatDate(e.Timestamp),
+			len(e.FiringAlerts),
+			len(e.ResolvedAlerts),
+		)
+	}
+	w.Flush()
+	return nil
+}
+
 func simpleFormatMatchers(matchers types.Matchers) string {
 	output := []string{}
 	for _, matcher := range matchers {
@@ -74,8 +132,13 @@ func simpleFormatMatchers(matchers types.Matchers) string {
 }
 
 func simpleFormatMatcher(matcher types.Matcher) string {
-	if matcher.IsRegex {
+	switch {
+	case matcher.IsRegex && matcher.IsNegative:
+		return fmt.Sprintf("%s!~%s", matcher.Name, matcher.Value)
+	case matcher.IsRegex:
 		return fmt.Sprintf("%s=~%s", matcher.Name, matcher.Value)
+	case matcher.IsNegative:
+		return fmt.Sprintf("%s!=%s", matcher.Name, matcher.Value)
 	}
 	return fmt.Sprintf("%s=%s", matcher.Name, matcher.Value)
 }
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/prometheus/alertmanager/cli/format"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+type alertmanagerNflogResponse struct {
+	Status    string              `json:"status"`
+	Data      []format.NflogEntry `json:"data,omitempty"`
+	ErrorType string              `json:"errorType,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+var nflogCmd = &cobra.Command{
+	Use:   "nflog",
+	Short: "Inspect the notification log",
+	Long:  `Inspect the notification log of a running Alertmanager, which records the last notification of each aggregation group to each receiver`,
+}
+
+var nflogQueryFlags *flag.FlagSet
+var nflogQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Query notification log entries",
+	Long: `Query the notification log entries of a running Alertmanager
+
+  amtool nflog query --receiver=team-a --group-key='{}:{alertname="foo"}'
+
+	Lists the last notification of the aggregation group sent to each
+	integration of the team-a receiver. Without flags all entries are
+	listed.
+
+The amount of output is controlled by the output selection flag:
+	- Simple: Print receiver, group key, time and number of alerts
+	- Extended: Print all entry fields including the alert hashes
+	- Json: Print entries as json`,
+	RunE: queryNflog,
+}
+
+func init() {
+	nflogQueryCmd.Flags().String("receiver", "", "Only show entries of notifications to the receiver")
+	nflogQueryCmd.Flags().String("group-key", "", "Only show entries of notifications of the aggregation group")
+	nflogQueryCmd.Flags().Int("limit", 0, "Maximum number of entries to show, zero shows all")
+	nflogQueryFlags = nflogQueryCmd.Flags()
+
+	RootCmd.AddCommand(nflogCmd)
+	nflogCmd.AddCommand(nflogQueryCmd)
+}
+
+func fetchNflog(receiver, groupKey string, limit int) ([]format.NflogEntry, error) {
+	nflogResponse := alertmanagerNflogResponse{}
+	u, err := GetAlertmanagerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	if receiver != "" {
+		q.Set("receiver", receiver)
+	}
+	if groupKey != "" {
+		q.Set("groupKey", groupKey)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	u.Path = path.Join(u.Path, "/api/v1/nflog")
+	u.RawQuery = q.Encode()
+
+	res, err := http.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	err = json.NewDecoder(res.Body).Decode(&nflogResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if nflogResponse.Status != "success" {
+		return nil, fmt.Errorf("[%s] %s", nflogResponse.ErrorType, nflogResponse.Error)
+	}
+
+	return nflogResponse.Data, nil
+}
+
+func queryNflog(cmd *cobra.Command, args []string) error {
+	receiver, err := nflogQueryFlags.GetString("receiver")
+	if err != nil {
+		return err
+	}
+	groupKey, err := nflogQueryFlags.GetString("group-key")
+	if err != nil {
+		return err
+	}
+	limit, err := nflogQueryFlags.GetInt("limit")
+	if err != nil {
+		return err
+	}
+
+	entries, err := fetchNflog(receiver, groupKey, limit)
+	if err != nil {
+		return err
+	}
+
+	formatter, found := format.Formatters[viper.GetString("output")]
+	if !found {
+		return errors.New("Unknown output formatter")
+	}
+
+	return formatter.FormatNflogEntries(entries)
+}
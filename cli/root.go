@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/prometheus/alertmanager/cli/format"
+	"github.com/prometheus/alertmanager/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -34,8 +35,14 @@ The accepted config options are as follows:
 	comment_required
 		Require a comment on silence creation
 
+	max_duration
+		Refuse to add or extend silences lasting longer than the duration (168h)
+
 	output
 		Set a default output type. Options are (simple, extended, json)
+
+	labels.utf8-names
+		Accept label names with any UTF-8 characters, like an alertmanager run with the same flag
 	`,
 }
 
@@ -58,6 +65,8 @@ func init() {
 	viper.BindPFlag("output", RootCmd.PersistentFlags().Lookup("output"))
 	RootCmd.PersistentFlags().BoolP("verbose", "v", false, "Verbose running information")
 	viper.BindPFlag("verbose", RootCmd.PersistentFlags().Lookup("verbose"))
+	RootCmd.PersistentFlags().Bool("labels.utf8-names", false, "Accept label names with any UTF-8 characters")
+	viper.BindPFlag("labels.utf8-names", RootCmd.PersistentFlags().Lookup("labels.utf8-names"))
 	viper.SetDefault("date.format", format.DefaultDateFormat)
 }
 
@@ -80,4 +89,5 @@ func initConfig() {
 			fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 		}
 	}
+	types.UTF8LabelNames = viper.GetBool("labels.utf8-names")
 }
@@ -20,7 +20,7 @@ type alertmanagerSilenceResponse struct {
 var silenceCmd = &cobra.Command{
 	Use:   "silence",
 	Short: "Manage silences",
-	Long:  `Add, expire or view silences. For more information and additional flags see query help`,
+	Long:  `Add, expire, extend or view silences. For more information and additional flags see query help`,
 	Run:   CommandWrapper(query),
 }
 
@@ -30,5 +30,7 @@ func init() {
 	viper.BindPFlag("quiet", silenceCmd.PersistentFlags().Lookup("quiet"))
 	silenceCmd.AddCommand(addCmd)
 	silenceCmd.AddCommand(expireCmd)
+	silenceCmd.AddCommand(extendCmd)
+	silenceCmd.AddCommand(expiringCmd)
 	silenceCmd.AddCommand(queryCmd)
 }
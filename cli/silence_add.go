@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"os/user"
 	"path"
+	"strings"
 	"time"
 
+	"github.com/prometheus/alertmanager/pkg/parse"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
@@ -19,7 +22,8 @@ import (
 type addResponse struct {
 	Status string `json:"status"`
 	Data   struct {
-		SilenceID string `json:"silenceId"`
+		SilenceID string   `json:"silenceId"`
+		Warnings  []string `json:"warnings,omitempty"`
 	} `json:"data,omitempty"`
 	ErrorType string `json:"errorType,omitempty"`
 	Error     string `json:"error,omitempty"`
@@ -51,6 +55,22 @@ var addCmd = &cobra.Command{
 	As well as direct equality, regex matching is also supported. The '=~' syntax
 	(similar to prometheus) is used to represent a regex match. Regex matching
 	can be used in combination with a direct match.
+
+  amtool silence add alertname=foo 'instance!~web-[0-9]+' env!=dev
+
+	Negative matchers silence alerts whose label value does not match. The
+	'!=' and '!~' syntax negates direct and regex matches.
+
+  amtool silence add '{job="api",instance=~"web-.*"}'
+
+	A single argument in braces is parsed as a matcher expression like the
+	ones of the alert group filters. Alertmanager parses it the same way.
+
+  amtool silence add --expires=720h --recur-start=22:00 --recur-duration=4h --recur-weekdays=saturday,sunday job=backup
+
+	Recurring silences are only active during the windows of their schedule
+	within the time range of the silence, here from 22:00 to 02:00 UTC in
+	the nights of weekends over the next 30 days.
 	`,
 	Run: CommandWrapper(add),
 }
@@ -69,6 +89,10 @@ func init() {
 	addCmd.Flags().StringP("expires", "e", "1h", "Duration of silence (100h)")
 	addCmd.Flags().String("expire-on", "", "Expire at a certain time (Overwrites expires) RFC3339 format 2006-01-02T15:04:05Z07:00")
 	addCmd.Flags().StringP("comment", "c", "", "A comment to help describe the silence")
+	addCmd.Flags().String("recur-start", "", "Make the silence recur daily, starting at the time of day (22:00)")
+	addCmd.Flags().String("recur-duration", "1h", "Duration of the windows of recurring silences")
+	addCmd.Flags().StringSlice("recur-weekdays", nil, "Only recur on the days of the week (saturday,sunday)")
+	addCmd.Flags().String("recur-location", "", "Time zone of the recurrence schedule (Europe/Berlin), UTC by default")
 	viper.BindPFlag("author", addCmd.Flags().Lookup("author"))
 	viper.BindPFlag("expires", addCmd.Flags().Lookup("expires"))
 	viper.BindPFlag("comment", addCmd.Flags().Lookup("comment"))
@@ -79,13 +103,32 @@ func init() {
 func add(cmd *cobra.Command, args []string) error {
 	var err error
 
-	matchers, err := parseMatchers(args)
-	if err != nil {
-		return err
-	}
+	var (
+		typeMatchers types.Matchers
+		expr         string
+	)
+	if len(args) == 1 && strings.HasPrefix(args[0], "{") {
+		// The expression is sent along with the matchers parsed from it,
+		// which the API parses the same way.
+		expr = args[0]
+		typeMatchers, err = parse.SilenceMatchers(expr)
+		if err != nil {
+			return fmt.Errorf("Invalid matcher expression: %s", err)
+		}
+	} else {
+		matchers, err := parseMatchers(args)
+		if err != nil {
+			return err
+		}
+
+		if len(matchers) < 1 {
+			return fmt.Errorf("No matchers specified")
+		}
 
-	if len(matchers) < 1 {
-		return fmt.Errorf("No matchers specified")
+		typeMatchers, err = TypeMatchers(matchers)
+		if err != nil {
+			return err
+		}
 	}
 
 	expireOn, err := addFlags.GetString("expire-on")
@@ -116,9 +159,7 @@ func add(cmd *cobra.Command, args []string) error {
 	if commentRequired && comment == "" {
 		return errors.New("Comment required by config")
 	}
-
-	typeMatchers, err := TypeMatchers(matchers)
-	if err != nil {
+	if err := checkMaxDuration(time.Now().UTC(), endsAt); err != nil {
 		return err
 	}
 
@@ -130,6 +171,23 @@ func add(cmd *cobra.Command, args []string) error {
 		Comment:   comment,
 	}
 
+	recurStart, err := addFlags.GetString("recur-start")
+	if err != nil {
+		return err
+	}
+	if recurStart != "" {
+		silence.Recurrence = &types.Recurrence{StartTime: recurStart}
+		if silence.Recurrence.Duration, err = addFlags.GetString("recur-duration"); err != nil {
+			return err
+		}
+		if silence.Recurrence.Weekdays, err = addFlags.GetStringSlice("recur-weekdays"); err != nil {
+			return err
+		}
+		if silence.Recurrence.Location, err = addFlags.GetString("recur-location"); err != nil {
+			return err
+		}
+	}
+
 	u, err := GetAlertmanagerURL()
 	if err != nil {
 		return err
@@ -137,7 +195,10 @@ func add(cmd *cobra.Command, args []string) error {
 	u.Path = path.Join(u.Path, "/api/v1/silences")
 
 	buf := bytes.NewBuffer([]byte{})
-	err = json.NewEncoder(buf).Encode(silence)
+	err = json.NewEncoder(buf).Encode(struct {
+		types.Silence
+		MatcherExpression string `json:"matcherExpression,omitempty"`
+	}{silence, expr})
 	if err != nil {
 		return err
 	}
@@ -157,6 +218,9 @@ func add(cmd *cobra.Command, args []string) error {
 	if response.Status == "error" {
 		fmt.Printf("[%s] %s\n", response.ErrorType, response.Error)
 	} else {
+		for _, w := range response.Data.Warnings {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", w)
+		}
 		fmt.Println(response.Data.SilenceID)
 	}
 	return nil
@@ -3,20 +3,51 @@ package cli
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"path"
 
 	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/prometheus/alertmanager/cli/format"
+	"github.com/prometheus/alertmanager/dispatch"
 )
 
+type expireDryRunResponse struct {
+	Status    string               `json:"status"`
+	Data      []*dispatch.APIAlert `json:"data,omitempty"`
+	ErrorType string               `json:"errorType,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+var expireFlags *flag.FlagSet
 var expireCmd = &cobra.Command{
 	Use:   "expire",
 	Short: "expire silence",
-	Long:  `expire an alertmanager silence`,
-	Run:   CommandWrapper(expire),
+	Long: `expire an alertmanager silence
+
+  amtool silence expire --dry-run 8d2b6b5c-6a8f-4e8b-9e3b-5b1a8f1c6a52
+
+	With --dry-run the silences are not expired. Instead the alerts that
+	would be notified about are listed, i.e. the active alerts only muted
+	by the given silences and not inhibited.
+	`,
+	Run: CommandWrapper(expire),
+}
+
+func init() {
+	expireCmd.Flags().Bool("dry-run", false, "List the alerts that would be notified about instead of expiring the silences")
+	expireFlags = expireCmd.Flags()
 }
 
 func expire(cmd *cobra.Command, args []string) error {
+	dryRun, err := expireFlags.GetBool("dry-run")
+	if err != nil {
+		return err
+	}
 	u, err := GetAlertmanagerURL()
 	if err != nil {
 		return err
@@ -26,6 +57,9 @@ func expire(cmd *cobra.Command, args []string) error {
 	if len(args) < 1 {
 		return errors.New("No silence IDs specified")
 	}
+	if dryRun {
+		return expireDryRun(u, basePath, args)
+	}
 
 	for _, arg := range args {
 		u.Path = path.Join(basePath, arg)
@@ -50,3 +84,47 @@ func expire(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+// expireDryRun lists the alerts that would be notified about if the
+// silences were expired. Each silence is considered on its own, so alerts
+// muted by more than one of the given silences are not listed.
+func expireDryRun(u *url.URL, basePath string, ids []string) error {
+	var (
+		alerts = []*dispatch.APIAlert{}
+		seen   = map[string]struct{}{}
+	)
+	for _, id := range ids {
+		u.Path = path.Join(basePath, id)
+		u.RawQuery = "dryRun=true"
+		req, err := http.NewRequest("DELETE", u.String(), nil)
+		if err != nil {
+			return err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		response := expireDryRunResponse{}
+		err = json.NewDecoder(res.Body).Decode(&response)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("Unable to decode json response: %s", err)
+		}
+		if response.Status == "error" {
+			return errors.New(response.Error)
+		}
+		for _, a := range response.Data {
+			if _, ok := seen[a.Fingerprint]; ok {
+				continue
+			}
+			seen[a.Fingerprint] = struct{}{}
+			alerts = append(alerts, a)
+		}
+	}
+
+	formatter, found := format.Formatters[viper.GetString("output")]
+	if !found {
+		return errors.New("Unknown output formatter")
+	}
+	return formatter.FormatAlerts(alerts)
+}
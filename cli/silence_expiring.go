@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/prometheus/alertmanager/cli/format"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var expiringFlags *flag.FlagSet
+var expiringCmd = &cobra.Command{
+	Use:   "expiring",
+	Short: "List silences about to expire",
+	Long: `List alertmanager silences expiring within a time window and optionally
+  post reminders about them
+
+  amtool silence expiring --within 12h
+
+	Lists all active and pending silences ending within the next 12 hours.
+
+  amtool silence expiring --slack-url https://hooks.slack.com/... --interval 1h
+
+	Checks for expiring silences every hour and posts a reminder to Slack
+	about each silence once. Extended silences are reminded about again
+	once they are about to expire.
+
+  Reminders posted to --webhook-url are JSON objects holding the list of
+  expiring silences and the window they expire within.
+	`,
+	Run: CommandWrapper(expiring),
+}
+
+func init() {
+	expiringCmd.Flags().Duration("within", 24*time.Hour, "Window within which silences must expire to be listed")
+	expiringCmd.Flags().String("webhook-url", "", "URL to post reminders to as JSON")
+	expiringCmd.Flags().String("slack-url", "", "Slack incoming webhook URL to post reminders to")
+	expiringCmd.Flags().Duration("interval", 0, "Keep running and check for expiring silences in this interval")
+	expiringFlags = expiringCmd.Flags()
+}
+
+type expiringReminder struct {
+	Within   string          `json:"within"`
+	Silences []types.Silence `json:"silences"`
+}
+
+func expiring(cmd *cobra.Command, args []string) error {
+	within, err := expiringFlags.GetDuration("within")
+	if err != nil {
+		return err
+	}
+	webhookURL, err := expiringFlags.GetString("webhook-url")
+	if err != nil {
+		return err
+	}
+	slackURL, err := expiringFlags.GetString("slack-url")
+	if err != nil {
+		return err
+	}
+	interval, err := expiringFlags.GetDuration("interval")
+	if err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		_, err := checkExpiring(within, webhookURL, slackURL, reminders{})
+		return err
+	}
+
+	reminded := reminders{}
+	for {
+		if reminded, err = checkExpiring(within, webhookURL, slackURL, reminded); err != nil {
+			fmt.Printf("Error: %s\n", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// reminders holds the end times of the silences reminders were posted for
+// by target. A silence is reminded about again if its end time changed.
+type reminders map[string]map[string]time.Time
+
+// reminderTarget posts reminders about silences.
+type reminderTarget struct {
+	name string
+	post func([]types.Silence) error
+}
+
+// checkExpiring prints the silences expiring within the window and posts
+// reminders about those not reminded about yet to each target. It returns
+// the silences reminded about that have not expired yet. Targets that
+// failed are reminded about the same silences again on the next check.
+func checkExpiring(within time.Duration, webhookURL, slackURL string, reminded reminders) (reminders, error) {
+	silences, err := fetchSilences("")
+	if err != nil {
+		return reminded, err
+	}
+
+	var (
+		now      = time.Now()
+		expiring = []types.Silence{}
+		current  = map[string]time.Time{}
+	)
+	for _, s := range silences {
+		if !s.EndsAt.After(now) || s.EndsAt.After(now.Add(within)) {
+			continue
+		}
+		expiring = append(expiring, s)
+		current[s.ID] = s.EndsAt
+	}
+
+	if viper.GetBool("quiet") {
+		for _, s := range expiring {
+			fmt.Println(s.ID)
+		}
+	} else {
+		formatter, found := format.Formatters[viper.GetString("output")]
+		if !found {
+			return reminded, errors.New("Unknown output formatter")
+		}
+		formatter.FormatSilences(expiring)
+	}
+
+	var targets []reminderTarget
+	if webhookURL != "" {
+		targets = append(targets, reminderTarget{name: "webhook", post: func(remind []types.Silence) error {
+			return postReminder(webhookURL, expiringReminder{
+				Within:   within.String(),
+				Silences: remind,
+			})
+		}})
+	}
+	if slackURL != "" {
+		targets = append(targets, reminderTarget{name: "slack", post: func(remind []types.Silence) error {
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "%d silence(s) expiring within %s:\n", len(remind), within)
+			for _, s := range remind {
+				fmt.Fprintf(&buf, "• %s %s by %s expires %s: %s\n", s.ID, s.Matchers, s.CreatedBy, format.FormatDate(s.EndsAt), s.Comment)
+			}
+			return postReminder(slackURL, struct {
+				Text string `json:"text"`
+			}{
+				Text: buf.String(),
+			})
+		}})
+	}
+
+	next := make(reminders, len(targets))
+	for _, t := range targets {
+		var remind []types.Silence
+		for _, s := range expiring {
+			if endsAt, ok := reminded[t.name][s.ID]; !ok || !endsAt.Equal(s.EndsAt) {
+				remind = append(remind, s)
+			}
+		}
+		next[t.name] = current
+		if len(remind) == 0 {
+			continue
+		}
+		sort.Slice(remind, func(i, j int) bool { return remind[i].EndsAt.Before(remind[j].EndsAt) })
+
+		if perr := t.post(remind); perr != nil {
+			next[t.name] = reminded[t.name]
+			if err == nil {
+				err = perr
+			}
+		}
+	}
+	return next, err
+}
+
+func postReminder(url string, v interface{}) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	res, err := http.Post(url, "application/json", &buf)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d posting reminder to %s", res.StatusCode, url)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCheckExpiringFailedTarget(t *testing.T) {
+	now := time.Now()
+	sil := types.Silence{
+		ID:        "sil1",
+		Matchers:  types.Matchers{{Name: "job", Value: "test"}},
+		StartsAt:  now.Add(-time.Hour),
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "alice",
+	}
+
+	var (
+		mtx       sync.Mutex
+		posts     = map[string]int{}
+		slackFail = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		switch r.URL.Path {
+		case "/api/v1/silences":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status": "success",
+				"data":   []types.Silence{sil},
+			})
+		case "/slack":
+			posts[r.URL.Path]++
+			if slackFail {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			posts[r.URL.Path]++
+		}
+	}))
+	defer srv.Close()
+
+	defer viper.Set("alertmanager.url", "")
+	defer viper.Set("quiet", false)
+	viper.Set("alertmanager.url", srv.URL)
+	viper.Set("quiet", true)
+
+	check := func(reminded reminders) reminders {
+		next, err := checkExpiring(24*time.Hour, srv.URL+"/webhook", srv.URL+"/slack", reminded)
+		mtx.Lock()
+		defer mtx.Unlock()
+		if slackFail && err == nil {
+			t.Error("Expected error for failed Slack reminder")
+		}
+		if !slackFail && err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		return next
+	}
+	expectPosts := func(webhook, slack int) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if posts["/webhook"] != webhook || posts["/slack"] != slack {
+			t.Errorf("Expected %d webhook and %d Slack reminders, got %d and %d", webhook, slack, posts["/webhook"], posts["/slack"])
+		}
+	}
+
+	reminded := check(reminders{})
+	expectPosts(1, 1)
+
+	// Only the failed target is reminded again.
+	mtx.Lock()
+	slackFail = false
+	mtx.Unlock()
+	reminded = check(reminded)
+	expectPosts(1, 2)
+
+	reminded = check(reminded)
+	expectPosts(1, 2)
+
+	// Extended silences are reminded about again by all targets.
+	mtx.Lock()
+	sil.EndsAt = sil.EndsAt.Add(time.Minute)
+	mtx.Unlock()
+	check(reminded)
+	expectPosts(2, 3)
+}
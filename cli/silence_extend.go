@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+type extendResponse struct {
+	Status    string        `json:"status"`
+	Data      types.Silence `json:"data,omitempty"`
+	ErrorType string        `json:"errorType,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+var extendFlags *flag.FlagSet
+var extendCmd = &cobra.Command{
+	Use:   "extend [silence ids]",
+	Short: "Extend silence",
+	Long: `Extend the expiry of active or pending alertmanager silences
+
+  amtool silence extend --by 2h 8d2b6b5c-6a8f-4e8b-9e3b-5b1a8f1c6a52
+
+	The silence is only extended if it was not modified by someone else
+	since amtool read it.
+	`,
+	Run: CommandWrapper(extend),
+}
+
+func init() {
+	extendCmd.Flags().String("by", "1h", "Duration to extend the silence by")
+	extendFlags = extendCmd.Flags()
+}
+
+func extend(cmd *cobra.Command, args []string) error {
+	by, err := extendFlags.GetString("by")
+	if err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(by)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return errors.New("No silence IDs specified")
+	}
+
+	u, err := GetAlertmanagerURL()
+	if err != nil {
+		return err
+	}
+	basePath := path.Join(u.Path, "/api/v1/silence")
+
+	for _, arg := range args {
+		u.Path = path.Join(basePath, arg)
+
+		// Read the silence first so the extension fails if it is
+		// modified concurrently.
+		res, err := http.Get(u.String())
+		if err != nil {
+			return err
+		}
+		current := extendResponse{}
+		err = json.NewDecoder(res.Body).Decode(&current)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("Unable to parse silence json response from %s", u.String())
+		}
+		if current.Status == "error" {
+			return errors.New(current.Error)
+		}
+		if err := checkMaxDuration(current.Data.StartsAt, current.Data.EndsAt.Add(d)); err != nil {
+			return err
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+		err = json.NewEncoder(buf).Encode(struct {
+			Duration  string    `json:"duration"`
+			UpdatedAt time.Time `json:"updatedAt"`
+		}{
+			Duration:  d.String(),
+			UpdatedAt: current.Data.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		u.Path = path.Join(basePath, arg, "extend")
+		res, err = http.Post(u.String(), "application/json", buf)
+		if err != nil {
+			return err
+		}
+		response := extendResponse{}
+		err = json.NewDecoder(res.Body).Decode(&response)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("Unable to parse silence json response from %s", u.String())
+		}
+		if response.Status == "error" {
+			return fmt.Errorf("[%s] %s", response.ErrorType, response.Error)
+		}
+		fmt.Printf("%s expires at %s\n", response.Data.ID, response.Data.EndsAt.Format(time.RFC3339))
+	}
+	return nil
+}
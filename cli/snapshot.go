@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/snapshot"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+// The types of snapshots written by an Alertmanager.
+const (
+	snapshotTypeNflog    = "nflog"
+	snapshotTypeSilences = "silences"
+)
+
+// snapshotState is the JSON representation of a snapshot. It has the same
+// schema as the state written by backup, so that it can be restored as
+// well.
+type snapshotState struct {
+	Silences        []*silencepb.MeshSilence `json:"silences,omitempty"`
+	NotificationLog []*nflogpb.MeshEntry     `json:"notificationLog,omitempty"`
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Convert notification log and silences snapshots from and to JSON",
+	Long: `Convert notification log and silences snapshot files from and to JSON
+
+  This allows to migrate state between clusters and to repair corrupted
+  state by hand. The files must not be used by a running alertmanager.
+	`,
+}
+
+var snapshotDumpFlags *flag.FlagSet
+var snapshotDumpCmd = &cobra.Command{
+	Use:   "dump <snapshot> [file]",
+	Short: "Dump a snapshot file as JSON",
+	Long: `Dump a notification log or silences snapshot file as JSON
+
+  amtool snapshot dump data/nflog nflog.json
+
+	Writes the entries of the snapshot as JSON to nflog.json, or to stdout
+	if no file is given. The type of the snapshot is detected from its
+	header. Snapshots written before headers were added require --type.
+	`,
+	Run: CommandWrapper(snapshotDump),
+}
+
+var snapshotBuildFlags *flag.FlagSet
+var snapshotBuildCmd = &cobra.Command{
+	Use:   "build <file> <snapshot>",
+	Short: "Build a snapshot file from JSON",
+	Long: `Build a notification log or silences snapshot file from JSON
+
+  amtool snapshot build --type=silences state.json data/silences
+
+	Writes the silences of the JSON in state.json, as written by backup or
+	snapshot dump, to the snapshot file data/silences. The type can be
+	omitted if the JSON holds only one kind of state. Expired silences are
+	not written to snapshots. With --codec=json the entries are written as
+	JSON lines, which can be read and edited without amtool.
+	`,
+	Run: CommandWrapper(snapshotBuild),
+}
+
+func init() {
+	snapshotDumpCmd.Flags().String("type", "", "Type of the snapshot, nflog or silences")
+	snapshotDumpFlags = snapshotDumpCmd.Flags()
+
+	snapshotBuildCmd.Flags().String("type", "", "Type of the snapshot, nflog or silences")
+	snapshotBuildCmd.Flags().String("compression", "none", "Compression of the snapshot, none, gzip or snappy")
+	snapshotBuildCmd.Flags().String("codec", "proto", "Encoding of the snapshot entries, proto or json")
+	snapshotBuildFlags = snapshotBuildCmd.Flags()
+
+	snapshotCmd.AddCommand(snapshotDumpCmd)
+	snapshotCmd.AddCommand(snapshotBuildCmd)
+	RootCmd.AddCommand(snapshotCmd)
+}
+
+func snapshotDump(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("A snapshot file and at most one output file must be given")
+	}
+	typ, err := snapshotDumpFlags.GetString("type")
+	if err != nil {
+		return err
+	}
+	state, err := readSnapshot(args[0], typ)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if len(args) == 1 {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(args[1], b, 0644)
+}
+
+func snapshotBuild(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return errors.New("A JSON file and a snapshot file must be given")
+	}
+	typ, err := snapshotBuildFlags.GetString("type")
+	if err != nil {
+		return err
+	}
+	c, err := snapshotBuildFlags.GetString("compression")
+	if err != nil {
+		return err
+	}
+	compression, err := snapshot.ParseCompression(c)
+	if err != nil {
+		return err
+	}
+	cn, err := snapshotBuildFlags.GetString("codec")
+	if err != nil {
+		return err
+	}
+	codec, err := snapshot.ParseCodec(cn)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var state snapshotState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("Invalid JSON file %s: %s", args[0], err)
+	}
+	typ, n, err := writeSnapshot(args[1], typ, &state, compression, codec)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s snapshot with %d entries\n", typ, n)
+	return nil
+}
+
+// detectSnapshotType returns the type of the snapshot read from r based on
+// its header. It returns the empty string for snapshots without header.
+func detectSnapshotType(r io.Reader) (string, error) {
+	dr, err := snapshot.NewReader(r)
+	if err != nil {
+		return "", err
+	}
+	b, err := bufio.NewReader(dr).Peek(4)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	switch string(b) {
+	case "ANFL":
+		return snapshotTypeNflog, nil
+	case "ASIL":
+		return snapshotTypeSilences, nil
+	}
+	return "", nil
+}
+
+// readSnapshot loads the snapshot file of the given type. The type is
+// detected if it is empty.
+func readSnapshot(filename, typ string) (*snapshotState, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	detected, err := detectSnapshotType(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid snapshot file %s: %s", filename, err)
+	}
+	switch {
+	case typ == "" && detected == "":
+		return nil, fmt.Errorf("Unable to detect the type of snapshot file %s, --type must be given", filename)
+	case typ == "":
+		typ = detected
+	case detected != "" && typ != detected:
+		return nil, fmt.Errorf("Snapshot file %s is a %s snapshot", filename, detected)
+	}
+
+	var state snapshotState
+	switch typ {
+	case snapshotTypeNflog:
+		l, err := nflog.New(nflog.WithSnapshot(filename))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid snapshot file %s: %s", filename, err)
+		}
+		state.NotificationLog = l.View().MeshEntries()
+	case snapshotTypeSilences:
+		s, err := silence.New(silence.Options{SnapshotReader: bytes.NewReader(b)})
+		if err != nil {
+			return nil, fmt.Errorf("Invalid snapshot file %s: %s", filename, err)
+		}
+		state.Silences = s.MeshSilences()
+	default:
+		return nil, fmt.Errorf("Unknown snapshot type %q", typ)
+	}
+	return &state, nil
+}
+
+// writeSnapshot writes the part of the state of the given type to the
+// snapshot file. The type is inferred from the state if it is empty. It
+// returns the type and the number of entries written.
+func writeSnapshot(filename, typ string, state *snapshotState, c snapshot.Compression, codec snapshot.Codec) (string, int, error) {
+	if typ == "" {
+		switch {
+		case len(state.Silences) > 0 && len(state.NotificationLog) > 0:
+			return "", 0, errors.New("The JSON holds silences and notification log entries, --type must be given")
+		case len(state.Silences) > 0:
+			typ = snapshotTypeSilences
+		case len(state.NotificationLog) > 0:
+			typ = snapshotTypeNflog
+		default:
+			return "", 0, errors.New("The JSON holds no state, --type must be given")
+		}
+	}
+
+	var (
+		s interface {
+			Snapshot(io.Writer) (int, error)
+		}
+		n int
+	)
+	switch typ {
+	case snapshotTypeNflog:
+		l, err := nflog.New(nflog.WithSnapshotCompression(c), nflog.WithSnapshotCodec(codec))
+		if err != nil {
+			return "", 0, err
+		}
+		if n, err = l.Merge(state.NotificationLog...); err != nil {
+			return "", 0, fmt.Errorf("Invalid notification log entries: %s", err)
+		}
+		s = l
+	case snapshotTypeSilences:
+		sil, err := silence.New(silence.Options{SnapshotCompression: c, SnapshotCodec: codec})
+		if err != nil {
+			return "", 0, err
+		}
+		if n, err = sil.Merge(state.Silences...); err != nil {
+			return "", 0, fmt.Errorf("Invalid silences: %s", err)
+		}
+		s = sil
+	default:
+		return "", 0, fmt.Errorf("Unknown snapshot type %q", typ)
+	}
+
+	f, err := snapshot.OpenReplace(filename, snapshot.SyncOnClose)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := s.Snapshot(f); err != nil {
+		f.File.Close()
+		os.Remove(f.File.Name())
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return "", 0, err
+	}
+	return typ, n, nil
+}
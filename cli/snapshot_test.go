@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/snapshot"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+func TestSnapshotRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "amtool-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	state := &snapshotState{
+		Silences: []*silencepb.MeshSilence{{
+			Silence: &silencepb.Silence{
+				Id:        "id1",
+				Matchers:  []*silencepb.Matcher{{Name: "job", Pattern: "test"}},
+				StartsAt:  now,
+				EndsAt:    now.Add(time.Hour),
+				UpdatedAt: now,
+				CreatedBy: "test",
+				Comment:   "test",
+			},
+			ExpiresAt: now.Add(2 * time.Hour),
+		}},
+		NotificationLog: []*nflogpb.MeshEntry{{
+			Entry: &nflogpb.Entry{
+				GroupKey:  []byte("group"),
+				Receiver:  &nflogpb.Receiver{GroupName: "team", Integration: "email"},
+				Timestamp: now,
+			},
+			ExpiresAt: now.Add(time.Hour),
+		}},
+	}
+
+	if _, _, err := writeSnapshot(filepath.Join(dir, "any"), "", state, snapshot.CompressionNone, snapshot.ProtoCodec{}); err == nil {
+		t.Fatalf("expected error for JSON holding both kinds of state without type")
+	}
+
+	nflogf := filepath.Join(dir, "nflog")
+	typ, n, err := writeSnapshot(nflogf, snapshotTypeNflog, state, snapshot.CompressionGzip, snapshot.ProtoCodec{})
+	if err != nil {
+		t.Fatalf("writing nflog snapshot failed: %s", err)
+	}
+	if typ != snapshotTypeNflog || n != 1 {
+		t.Errorf("unexpected type %q and %d entries written", typ, n)
+	}
+	got, err := readSnapshot(nflogf, "")
+	if err != nil {
+		t.Fatalf("reading nflog snapshot failed: %s", err)
+	}
+	if !reflect.DeepEqual(got, &snapshotState{NotificationLog: state.NotificationLog}) {
+		t.Errorf("unexpected nflog state %v", got)
+	}
+	if _, err := readSnapshot(nflogf, snapshotTypeSilences); err == nil {
+		t.Errorf("expected error for reading nflog snapshot as silences")
+	}
+
+	silencesf := filepath.Join(dir, "silences")
+	typ, n, err = writeSnapshot(silencesf, "", &snapshotState{Silences: state.Silences}, snapshot.CompressionNone, snapshot.JSONCodec{})
+	if err != nil {
+		t.Fatalf("writing silences snapshot failed: %s", err)
+	}
+	if typ != snapshotTypeSilences || n != 1 {
+		t.Errorf("unexpected type %q and %d entries written", typ, n)
+	}
+	got, err = readSnapshot(silencesf, "")
+	if err != nil {
+		t.Fatalf("reading silences snapshot failed: %s", err)
+	}
+	if !reflect.DeepEqual(got, &snapshotState{Silences: state.Silences}) {
+		t.Errorf("unexpected silences state %v", got)
+	}
+}
@@ -5,13 +5,13 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/prometheus/alertmanager/pkg/parse"
 	"github.com/prometheus/alertmanager/types"
-	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
 )
 
@@ -58,6 +58,27 @@ func parseMatchers(inputLabels []string) ([]labels.Matcher, error) {
 	return matchers, nil
 }
 
+// checkMaxDuration returns an error if a silence from start to end lasts
+// longer than the max_duration config option allows. Like in Alertmanager,
+// silences that started already are measured from now on.
+func checkMaxDuration(start, end time.Time) error {
+	s := viper.GetString("max_duration")
+	if s == "" {
+		return nil
+	}
+	max, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("Invalid max_duration in config: %s", err)
+	}
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	if end.Sub(start) > max {
+		return fmt.Errorf("Silence lasts longer than the max_duration of %s set by config", max)
+	}
+	return nil
+}
+
 // Only valid for when you are going to add a silence
 func TypeMatchers(matchers []labels.Matcher) (types.Matchers, error) {
 	typeMatchers := types.Matchers{}
@@ -72,17 +93,10 @@ func TypeMatchers(matchers []labels.Matcher) (types.Matchers, error) {
 }
 
 // Only valid for when you are going to add a silence
-// Doesn't allow negative operators
 func TypeMatcher(matcher labels.Matcher) (types.Matcher, error) {
-	typeMatcher := types.NewMatcher(model.LabelName(matcher.Name), matcher.Value)
-
-	switch matcher.Type {
-	case labels.MatchEqual:
-		typeMatcher.IsRegex = false
-	case labels.MatchRegexp:
-		typeMatcher.IsRegex = true
-	default:
-		return types.Matcher{}, fmt.Errorf("invalid match type for creation operation: %s", matcher.Type)
+	typeMatcher, err := parse.SilenceMatcher(&matcher)
+	if err != nil {
+		return types.Matcher{}, err
 	}
 	return *typeMatcher, nil
 }
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCheckMaxDuration(t *testing.T) {
+	defer viper.Set("max_duration", "")
+	now := time.Now()
+
+	viper.Set("max_duration", "")
+	if err := checkMaxDuration(now, now.Add(1000*time.Hour)); err != nil {
+		t.Errorf("Unexpected error without maximum: %v", err)
+	}
+
+	viper.Set("max_duration", "168h")
+	if err := checkMaxDuration(now.Add(time.Hour), now.Add(168*time.Hour)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := checkMaxDuration(now.Add(time.Hour), now.Add(170*time.Hour)); err == nil {
+		t.Error("Expected error for silence lasting too long")
+	}
+	// Silences that started already are measured from now on.
+	if err := checkMaxDuration(now.Add(-100*time.Hour), now.Add(160*time.Hour)); err != nil {
+		t.Errorf("Unexpected error for started silence: %v", err)
+	}
+
+	viper.Set("max_duration", "a week")
+	if err := checkMaxDuration(now, now); err == nil {
+		t.Error("Expected error for invalid maximum")
+	}
+}
+
+func TestTypeMatchers(t *testing.T) {
+	matchers, err := parseMatchers([]string{"a=1", "b=~2.*", "c!=3", "d!~4.*"})
+	if err != nil {
+		t.Fatalf("Parsing matchers failed: %v", err)
+	}
+	ms, err := TypeMatchers(matchers)
+	if err != nil {
+		t.Fatalf("Converting matchers failed: %v", err)
+	}
+
+	expected := []types.Matcher{
+		{Name: "a", Value: "1"},
+		{Name: "b", Value: "2.*", IsRegex: true},
+		{Name: "c", Value: "3", IsNegative: true},
+		{Name: "d", Value: "4.*", IsRegex: true, IsNegative: true},
+	}
+	if len(ms) != len(expected) {
+		t.Fatalf("Expected %d matchers, got %d", len(expected), len(ms))
+	}
+	for i, m := range ms {
+		if m.Name != expected[i].Name || m.Value != expected[i].Value || m.IsRegex != expected[i].IsRegex || m.IsNegative != expected[i].IsNegative {
+			t.Errorf("Unexpected matcher %d: %v", i, m)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cluster contains helpers for sharing state between Alertmanager
+// peers over the mesh network.
+package cluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/mesh"
+)
+
+// Blocklist tracks gossip messages from peers that failed to decode or
+// merge. Peers with too many failures within a time window are blocked
+// for a while, during which their gossip is ignored.
+//
+// Only broadcast and unicast messages can be attributed to a peer. Periodic
+// full-state gossip carries no sender and is always processed.
+type Blocklist struct {
+	threshold int
+	window    time.Duration
+	duration  time.Duration
+	logger    log.Logger
+	now       func() time.Time
+
+	mtx   sync.Mutex
+	peers map[mesh.PeerName]*peerErrors
+
+	errorsTotal  *prometheus.CounterVec
+	droppedTotal *prometheus.CounterVec
+}
+
+type peerErrors struct {
+	// Times of the failures within the window, oldest first.
+	times        []time.Time
+	blockedUntil time.Time
+}
+
+// BlockedPeer is a peer whose gossip is currently ignored.
+type BlockedPeer struct {
+	Name  mesh.PeerName
+	Until time.Time
+}
+
+// NewBlocklist returns a Blocklist that blocks peers for the given duration
+// once threshold failures occurred within window. A threshold of zero
+// disables blocking.
+func NewBlocklist(threshold int, window, duration time.Duration, l log.Logger, r prometheus.Registerer) *Blocklist {
+	b := &Blocklist{
+		threshold: threshold,
+		window:    window,
+		duration:  duration,
+		logger:    l,
+		now:       time.Now,
+		peers:     map[mesh.PeerName]*peerErrors{},
+		errorsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_mesh_gossip_errors_total",
+			Help: "Number of gossip messages from peers that could not be decoded or merged.",
+		}, []string{"channel"}),
+		droppedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_mesh_gossip_blocked_total",
+			Help: "Number of gossip messages ignored because their sender was blocked.",
+		}, []string{"channel"}),
+	}
+	if b.logger == nil {
+		b.logger = log.NewNopLogger()
+	}
+	if r != nil {
+		blocked := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "alertmanager_mesh_blocked_peers",
+			Help: "Number of peers whose gossip is currently ignored.",
+		}, func() float64 {
+			return float64(len(b.Blocked()))
+		})
+		r.MustRegister(b.errorsTotal, b.droppedTotal, blocked)
+	}
+	return b
+}
+
+// Wrap returns a mesh.Gossiper for the named channel that records failures
+// of g and ignores messages from blocked peers.
+func (b *Blocklist) Wrap(channel string, g mesh.Gossiper) mesh.Gossiper {
+	if b == nil || b.threshold <= 0 {
+		return g
+	}
+	return &blockingGossiper{Gossiper: g, bl: b, channel: channel}
+}
+
+// Blocked returns the currently blocked peers ordered by name.
+func (b *Blocklist) Blocked() []BlockedPeer {
+	if b == nil {
+		return nil
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := b.now()
+	res := []BlockedPeer{}
+	for name, pe := range b.peers {
+		if now.Before(pe.blockedUntil) {
+			res = append(res, BlockedPeer{Name: name, Until: pe.blockedUntil})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// blocked returns whether messages from the peer are currently ignored.
+func (b *Blocklist) blocked(src mesh.PeerName) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	pe, ok := b.peers[src]
+	return ok && b.now().Before(pe.blockedUntil)
+}
+
+// fail records a failure of a message from the peer and blocks it if
+// the threshold is reached.
+func (b *Blocklist) fail(channel string, src mesh.PeerName, err error) {
+	b.errorsTotal.WithLabelValues(channel).Inc()
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := b.now()
+	pe, ok := b.peers[src]
+	if !ok {
+		pe = &peerErrors{}
+		b.peers[src] = pe
+	}
+	// Drop failures that left the window.
+	i := 0
+	for i < len(pe.times) && !pe.times[i].After(now.Add(-b.window)) {
+		i++
+	}
+	pe.times = append(pe.times[i:], now)
+
+	if len(pe.times) < b.threshold {
+		return
+	}
+	pe.times = nil
+	pe.blockedUntil = now.Add(b.duration)
+
+	level.Warn(b.logger).Log(
+		"msg", "Blocking gossip from peer after repeated failures",
+		"peer", src,
+		"channel", channel,
+		"until", pe.blockedUntil,
+		"err", err,
+	)
+}
+
+type blockingGossiper struct {
+	mesh.Gossiper
+	bl      *Blocklist
+	channel string
+}
+
+// OnGossipUnicast implements the mesh.Gossiper interface.
+func (g *blockingGossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	if g.bl.blocked(src) {
+		g.bl.droppedTotal.WithLabelValues(g.channel).Inc()
+		return nil
+	}
+	err := g.Gossiper.OnGossipUnicast(src, msg)
+	if err != nil {
+		g.bl.fail(g.channel, src, err)
+	}
+	return err
+}
+
+// OnGossipBroadcast implements the mesh.Gossiper interface.
+func (g *blockingGossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	if g.bl.blocked(src) {
+		g.bl.droppedTotal.WithLabelValues(g.channel).Inc()
+		return nil, nil
+	}
+	d, err := g.Gossiper.OnGossipBroadcast(src, msg)
+	if err != nil {
+		g.bl.fail(g.channel, src, err)
+	}
+	return d, err
+}
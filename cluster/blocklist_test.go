@@ -0,0 +1,93 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
+)
+
+// testGossiper fails on messages starting with "x".
+type testGossiper struct {
+	received int
+}
+
+func (g *testGossiper) handle(msg []byte) error {
+	if len(msg) > 0 && msg[0] == 'x' {
+		return errors.New("decode failed")
+	}
+	g.received++
+	return nil
+}
+
+func (g *testGossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	return g.handle(msg)
+}
+
+func (g *testGossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	return nil, g.handle(msg)
+}
+
+func (g *testGossiper) Gossip() mesh.GossipData { return nil }
+
+func (g *testGossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
+	return nil, g.handle(msg)
+}
+
+func TestBlocklist(t *testing.T) {
+	var (
+		now = time.Now()
+		tg  = &testGossiper{}
+		bl  = NewBlocklist(3, time.Minute, 10*time.Minute, nil, nil)
+		g   = bl.Wrap("test", tg)
+	)
+	bl.now = func() time.Time { return now }
+
+	const bad, good mesh.PeerName = 1, 2
+
+	// Failures that left the window do not count.
+	require.Error(t, g.OnGossipUnicast(bad, []byte("x")))
+	now = now.Add(2 * time.Minute)
+	require.Error(t, g.OnGossipUnicast(bad, []byte("x")))
+	_, err := g.OnGossipBroadcast(bad, []byte("x"))
+	require.Error(t, err)
+	require.Empty(t, bl.Blocked())
+
+	require.NoError(t, g.OnGossipUnicast(bad, []byte("ok")))
+	require.Equal(t, 1, tg.received)
+
+	_, err = g.OnGossipBroadcast(bad, []byte("x"))
+	require.Error(t, err)
+	require.Equal(t, []BlockedPeer{{Name: bad, Until: now.Add(10 * time.Minute)}}, bl.Blocked())
+
+	// Messages from the blocked peer are dropped, others still processed.
+	require.NoError(t, g.OnGossipUnicast(bad, []byte("ok")))
+	_, err = g.OnGossipBroadcast(bad, []byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, g.OnGossipUnicast(good, []byte("ok")))
+	require.Equal(t, 2, tg.received)
+
+	// The peer is unblocked after the block duration.
+	now = now.Add(10 * time.Minute)
+	require.Empty(t, bl.Blocked())
+	require.NoError(t, g.OnGossipUnicast(bad, []byte("ok")))
+	require.Equal(t, 3, tg.received)
+
+	// A zero threshold disables blocking.
+	require.Equal(t, mesh.Gossiper(tg), NewBlocklist(0, time.Minute, time.Minute, nil, nil).Wrap("test", tg))
+}
@@ -0,0 +1,251 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/mesh"
+)
+
+// Compressed gossip messages start with the magic bytes of their format,
+// which is the stream identifier for the snappy framing format. Uncompressed
+// messages of the nflog and silences channels start with the length of a
+// protobuf message or a unicast message type followed by a field tag, and
+// never match them. Messages compressed with gzip by peers of previous
+// versions are still decoded.
+var (
+	snappyMagic = []byte("\xff\x06\x00\x00sNaPpY")
+	gzipMagic   = []byte{0x1f, 0x8b}
+)
+
+// maxDecompressedSize bounds the size of decompressed gossip messages.
+const maxDecompressedSize = 64 << 20
+
+// GossipCompression compresses gossip messages larger than a threshold.
+// Peers running older versions cannot decode compressed messages, so
+// messages are only compressed while all peers announced in their PeerInfo
+// that they decode them. Compressed messages are always decoded.
+type GossipCompression struct {
+	threshold int
+	pv        *PeerVersions
+	peers     func() []mesh.PeerName
+
+	compressedTotal *prometheus.CounterVec
+	savedBytes      *prometheus.CounterVec
+}
+
+// NewGossipCompression returns a GossipCompression compressing messages
+// larger than threshold bytes. The peers function returns the names of all
+// peers of the mesh. Messages are never compressed if threshold is zero.
+func NewGossipCompression(threshold int, pv *PeerVersions, peers func() []mesh.PeerName, r prometheus.Registerer) *GossipCompression {
+	c := &GossipCompression{
+		threshold: threshold,
+		pv:        pv,
+		peers:     peers,
+		compressedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_mesh_gossip_compressed_total",
+			Help: "Number of gossip messages sent compressed.",
+		}, []string{"channel"}),
+		savedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_mesh_gossip_compression_saved_bytes_total",
+			Help: "Number of bytes saved by compressing gossip messages.",
+		}, []string{"channel"}),
+	}
+	if r != nil {
+		r.MustRegister(c.compressedTotal, c.savedBytes)
+	}
+	return c
+}
+
+// Wrap returns a mesh.Gossiper for the named channel that decodes
+// compressed messages and compresses the gossip data returned by g.
+func (c *GossipCompression) Wrap(channel string, g mesh.Gossiper) mesh.Gossiper {
+	if c == nil {
+		return g
+	}
+	return &compressedGossiper{Gossiper: g, c: c, channel: channel}
+}
+
+// WrapGossip returns a mesh.Gossip for the named channel that compresses
+// the messages sent through g.
+func (c *GossipCompression) WrapGossip(channel string, g mesh.Gossip) mesh.Gossip {
+	if c == nil {
+		return g
+	}
+	return &compressedGossip{Gossip: g, c: c, channel: channel}
+}
+
+// enabled returns whether messages to the given peers can be compressed.
+func (c *GossipCompression) enabled(peers ...mesh.PeerName) bool {
+	if c.threshold <= 0 || c.pv == nil {
+		return false
+	}
+	for _, p := range peers {
+		if p == c.pv.self {
+			continue
+		}
+		if info, ok := c.pv.Info(p); !ok || !info.GossipSnappy {
+			return false
+		}
+	}
+	return true
+}
+
+// compress returns the compressed message if it exceeds the threshold and
+// compressing it saves space.
+func (c *GossipCompression) compress(channel string, msg []byte) []byte {
+	if len(msg) <= c.threshold {
+		return msg
+	}
+	var buf bytes.Buffer
+	sw := snappy.NewBufferedWriter(&buf)
+	if _, err := sw.Write(msg); err != nil {
+		return msg
+	}
+	if err := sw.Close(); err != nil || buf.Len() >= len(msg) {
+		return msg
+	}
+	c.compressedTotal.WithLabelValues(channel).Inc()
+	c.savedBytes.WithLabelValues(channel).Add(float64(len(msg) - buf.Len()))
+
+	return buf.Bytes()
+}
+
+// decompress returns the uncompressed contents of the message.
+func decompress(msg []byte) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case bytes.HasPrefix(msg, snappyMagic):
+		r = snappy.NewReader(bytes.NewReader(msg))
+	case bytes.HasPrefix(msg, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(msg))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	default:
+		return msg, nil
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed gossip message exceeds %d bytes", maxDecompressedSize)
+	}
+	return b, nil
+}
+
+// wrap returns the gossip data compressing its encoding, or nil if d is
+// nil.
+func (c *GossipCompression) wrap(channel string, d mesh.GossipData) mesh.GossipData {
+	if d == nil {
+		return nil
+	}
+	return &compressedData{GossipData: d, c: c, channel: channel}
+}
+
+type compressedData struct {
+	mesh.GossipData
+	c       *GossipCompression
+	channel string
+}
+
+// Encode implements the mesh.GossipData interface. Whether messages are
+// compressed is decided anew every time as peers join the mesh.
+func (d *compressedData) Encode() [][]byte {
+	msgs := d.GossipData.Encode()
+	if !d.c.enabled(d.c.peers()...) {
+		return msgs
+	}
+	for i, msg := range msgs {
+		msgs[i] = d.c.compress(d.channel, msg)
+	}
+	return msgs
+}
+
+// Merge implements the mesh.GossipData interface.
+func (d *compressedData) Merge(other mesh.GossipData) mesh.GossipData {
+	if o, ok := other.(*compressedData); ok {
+		other = o.GossipData
+	}
+	d.GossipData = d.GossipData.Merge(other)
+	return d
+}
+
+type compressedGossiper struct {
+	mesh.Gossiper
+	c       *GossipCompression
+	channel string
+}
+
+// Gossip implements the mesh.Gossiper interface.
+func (g *compressedGossiper) Gossip() mesh.GossipData {
+	return g.c.wrap(g.channel, g.Gossiper.Gossip())
+}
+
+// OnGossip implements the mesh.Gossiper interface.
+func (g *compressedGossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
+	msg, err := decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	d, err := g.Gossiper.OnGossip(msg)
+	return g.c.wrap(g.channel, d), err
+}
+
+// OnGossipBroadcast implements the mesh.Gossiper interface.
+func (g *compressedGossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	msg, err := decompress(msg)
+	if err != nil {
+		return nil, err
+	}
+	d, err := g.Gossiper.OnGossipBroadcast(src, msg)
+	return g.c.wrap(g.channel, d), err
+}
+
+// OnGossipUnicast implements the mesh.Gossiper interface.
+func (g *compressedGossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	msg, err := decompress(msg)
+	if err != nil {
+		return err
+	}
+	return g.Gossiper.OnGossipUnicast(src, msg)
+}
+
+type compressedGossip struct {
+	mesh.Gossip
+	c       *GossipCompression
+	channel string
+}
+
+// GossipUnicast implements the mesh.Gossip interface.
+func (g *compressedGossip) GossipUnicast(dst mesh.PeerName, msg []byte) error {
+	if g.c.enabled(dst) {
+		msg = g.c.compress(g.channel, msg)
+	}
+	return g.Gossip.GossipUnicast(dst, msg)
+}
+
+// GossipBroadcast implements the mesh.Gossip interface.
+func (g *compressedGossip) GossipBroadcast(update mesh.GossipData) {
+	g.Gossip.GossipBroadcast(g.c.wrap(g.channel, update))
+}
@@ -0,0 +1,162 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
+)
+
+// testData is gossip data consisting of messages.
+type testData [][]byte
+
+func (d testData) Encode() [][]byte {
+	res := make([][]byte, len(d))
+	copy(res, d)
+	return res
+}
+
+func (d testData) Merge(other mesh.GossipData) mesh.GossipData {
+	return append(d, other.(testData)...)
+}
+
+type echoGossiper struct {
+	received [][]byte
+}
+
+func (g *echoGossiper) Gossip() mesh.GossipData { return testData{[]byte("state")} }
+
+func (g *echoGossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
+	g.received = append(g.received, msg)
+	return testData{msg}, nil
+}
+
+func (g *echoGossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	g.received = append(g.received, msg)
+	return nil, nil
+}
+
+func (g *echoGossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	g.received = append(g.received, msg)
+	return nil
+}
+
+type recordingGossip struct {
+	unicasts   [][]byte
+	broadcasts []mesh.GossipData
+}
+
+func (g *recordingGossip) GossipUnicast(dst mesh.PeerName, msg []byte) error {
+	g.unicasts = append(g.unicasts, msg)
+	return nil
+}
+
+func (g *recordingGossip) GossipBroadcast(update mesh.GossipData) {
+	g.broadcasts = append(g.broadcasts, update)
+}
+
+func TestGossipCompression(t *testing.T) {
+	var (
+		now   = time.Now()
+		pv    = NewPeerVersions(1, PeerInfo{Version: "0.10.0", GossipSnappy: true, Started: now}, nil, nil)
+		peers = []mesh.PeerName{1, 2, 3}
+		c     = NewGossipCompression(100, pv, func() []mesh.PeerName { return peers }, nil)
+		eg    = &echoGossiper{}
+		rg    = &recordingGossip{}
+		g     = c.Wrap("nflog", eg)
+		gsp   = c.WrapGossip("nflog", rg)
+		large = bytes.Repeat([]byte("silenced "), 100)
+		small = []byte("small")
+	)
+	compressed := c.compress("nflog", large)
+	require.True(t, bytes.HasPrefix(compressed, snappyMagic))
+	require.True(t, len(compressed) < len(large))
+	require.Equal(t, small, c.compress("nflog", small))
+
+	// Messages are not compressed until all peers decode them, including
+	// peers only decoding gzip compressed messages.
+	_, err := pv.OnGossip(versionData{
+		2: PeerInfo{Version: "0.10.0", GossipSnappy: true, Started: now},
+		3: PeerInfo{Version: "0.10.0", GossipCompression: true, Started: now},
+	}.Encode()[0])
+	require.NoError(t, err)
+
+	gsp.GossipBroadcast(testData{large, small})
+	require.Len(t, rg.broadcasts, 1)
+	require.Equal(t, [][]byte{large, small}, rg.broadcasts[0].Encode())
+
+	require.NoError(t, gsp.GossipUnicast(3, large))
+	require.NoError(t, gsp.GossipUnicast(2, large))
+	require.Equal(t, [][]byte{large, compressed}, rg.unicasts)
+
+	_, err = pv.OnGossip(versionData{
+		3: PeerInfo{Version: "0.10.0", GossipSnappy: true, Started: now.Add(time.Minute)},
+	}.Encode()[0])
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{compressed, small}, rg.broadcasts[0].Encode())
+
+	// Peers that did not share their information are not sent compressed
+	// messages.
+	peers = append(peers, 4)
+	require.Equal(t, [][]byte{large, small}, rg.broadcasts[0].Encode())
+	peers = peers[:3]
+
+	// Merging unwraps the data of other messages.
+	d := g.Gossip().Merge(rg.broadcasts[0])
+	require.Equal(t, [][]byte{[]byte("state"), compressed, small}, d.Encode())
+
+	// Received messages are decompressed, whether the local peer compresses
+	// messages or not.
+	for _, g := range []mesh.Gossiper{g, NewGossipCompression(0, nil, nil, nil).Wrap("nflog", eg)} {
+		eg.received = nil
+
+		delta, err := g.OnGossip(compressed)
+		require.NoError(t, err)
+		_, err = g.OnGossipBroadcast(2, compressed)
+		require.NoError(t, err)
+		require.NoError(t, g.OnGossipUnicast(2, small))
+		require.Equal(t, [][]byte{large, large, small}, eg.received)
+		require.NotNil(t, delta)
+
+		// Nil gossip data is not wrapped.
+		delta, err = g.OnGossipBroadcast(2, small)
+		require.NoError(t, err)
+		require.Nil(t, delta)
+	}
+
+	// Messages compressed with gzip by peers of previous versions are
+	// decompressed.
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err = zw.Write(large)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	eg.received = nil
+	_, err = g.OnGossip(gz.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{large}, eg.received)
+
+	for _, magic := range [][]byte{snappyMagic, gzipMagic} {
+		_, err = g.OnGossip(append(append([]byte{}, magic...), "garbage"...))
+		require.Error(t, err)
+	}
+
+	var nc *GossipCompression
+	require.Equal(t, eg, nc.Wrap("nflog", eg))
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/mesh"
+)
+
+// PeerPriorities maps peer nicknames or IDs to an operator assigned
+// priority, which orders the peers for sending notifications. It
+// implements the flag.Value interface.
+type PeerPriorities map[string]int
+
+// Set implements the flag.Value interface.
+func (pp PeerPriorities) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		i := strings.LastIndex(v, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid peer priority %q, expected <peer>=<priority>", v)
+		}
+		p, err := strconv.Atoi(v[i+1:])
+		if err != nil {
+			return fmt.Errorf("invalid priority for peer %q: %s", v[:i], err)
+		}
+		pp[v[:i]] = p
+	}
+	return nil
+}
+
+// String implements the flag.Value interface.
+func (pp PeerPriorities) String() string {
+	res := make([]string, 0, len(pp))
+	for k, v := range pp {
+		res = append(res, fmt.Sprintf("%s=%d", k, v))
+	}
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
+
+// priority returns the priority configured for the peer. The peer ID takes
+// precedence over the nickname.
+func (pp PeerPriorities) priority(desc mesh.PeerDescription) (int, bool) {
+	if p, ok := pp[desc.Name.String()]; ok {
+		return p, true
+	}
+	p, ok := pp[desc.NickName]
+	return p, ok
+}
+
+// Less orders peers by their configured priority. Peers without a priority
+// come last and ties are ordered by peer UID.
+func (pp PeerPriorities) Less(a, b mesh.PeerDescription) bool {
+	pa, oka := pp.priority(a)
+	pb, okb := pp.priority(b)
+
+	if oka != okb {
+		return oka
+	}
+	if oka && pa != pb {
+		return pa < pb
+	}
+	return a.UID < b.UID
+}
+
+// Position returns the number of peers ordered before the peer describing
+// ourselves.
+func (pp PeerPriorities) Position(peers []mesh.PeerDescription) int {
+	peers = append([]mesh.PeerDescription(nil), peers...)
+	sort.Slice(peers, func(i, j int) bool {
+		return pp.Less(peers[i], peers[j])
+	})
+
+	k := 0
+	for _, desc := range peers {
+		if desc.Self {
+			break
+		}
+		k++
+	}
+	return k
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
+)
+
+func TestPeerPrioritiesSet(t *testing.T) {
+	pp := PeerPriorities{}
+	require.NoError(t, pp.Set("am-primary=0, am-secondary=1"))
+	require.NoError(t, pp.Set("00:00:00:00:00:03=2"))
+	require.Equal(t, PeerPriorities{"am-primary": 0, "am-secondary": 1, "00:00:00:00:00:03": 2}, pp)
+	require.Equal(t, "00:00:00:00:00:03=2,am-primary=0,am-secondary=1", pp.String())
+
+	require.Error(t, pp.Set("am-primary"))
+	require.Error(t, pp.Set("=1"))
+	require.Error(t, pp.Set("am-primary=first"))
+}
+
+func TestPeerPrioritiesPosition(t *testing.T) {
+	peers := []mesh.PeerDescription{
+		{Name: 1, NickName: "a", UID: 4},
+		{Name: 2, NickName: "b", UID: 3},
+		{Name: 3, NickName: "c", UID: 2},
+		{Name: 4, NickName: "d", UID: 1},
+	}
+	position := func(pp PeerPriorities, self int) int {
+		ps := make([]mesh.PeerDescription, len(peers))
+		copy(ps, peers)
+		ps[self].Self = true
+		return pp.Position(ps)
+	}
+
+	cases := []struct {
+		name       string
+		priorities PeerPriorities
+		// Indices of the peers in the expected order.
+		order []int
+	}{
+		{
+			// Peers are ordered by UID without priorities.
+			name:       "none",
+			priorities: PeerPriorities{},
+			order:      []int{3, 2, 1, 0},
+		},
+		{
+			// Peers without a priority come after all others.
+			name:       "nicknames",
+			priorities: PeerPriorities{"a": 1, "b": 0},
+			order:      []int{1, 0, 3, 2},
+		},
+		{
+			// Equal priorities are ordered by UID.
+			name:       "ties",
+			priorities: PeerPriorities{"a": 0, "b": 0, "c": 1},
+			order:      []int{1, 0, 2, 3},
+		},
+		{
+			// The peer ID takes precedence over the nickname.
+			name:       "peer ID",
+			priorities: PeerPriorities{"a": 0, mesh.PeerName(1).String(): 2, "d": 1},
+			order:      []int{3, 0, 2, 1},
+		},
+		{
+			// Negative priorities come before the default.
+			name:       "negative",
+			priorities: PeerPriorities{"c": -1, "d": 0},
+			order:      []int{2, 3, 1, 0},
+		},
+	}
+	for _, c := range cases {
+		for pos, self := range c.order {
+			require.Equal(t, pos, position(c.priorities, self), "%s: peer %s", c.name, peers[self].NickName)
+		}
+	}
+
+	// The given peers are not reordered.
+	ps := []mesh.PeerDescription{peers[0], peers[1]}
+	PeerPriorities{"b": 0}.Position(ps)
+	require.Equal(t, []mesh.PeerDescription{peers[0], peers[1]}, ps)
+}
@@ -0,0 +1,221 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/weaveworks/mesh"
+)
+
+// simQueueSize is the number of messages that can be pending for a replica
+// on a channel before further messages are dropped.
+const simQueueSize = 1024
+
+// Simulation connects the gossipers of replicas running in the same process
+// through in-memory transports, so that their behavior as a cluster can be
+// reproduced without a mesh. Every replica is connected to all others.
+// Messages are encoded like on the wire and delivered asynchronously, in
+// order and after the configured latency. Periodic full-state gossip is not
+// simulated.
+type Simulation struct {
+	latency time.Duration
+	logger  log.Logger
+	names   []mesh.PeerName
+
+	mtx      sync.RWMutex
+	channels map[string]map[mesh.PeerName]*simPeer
+	stopped  bool
+}
+
+// NewSimulation returns a Simulation of n replicas whose messages are
+// delayed by the given latency.
+func NewSimulation(n int, latency time.Duration, l log.Logger) (*Simulation, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid number of replicas %d", n)
+	}
+	if l == nil {
+		l = log.NewNopLogger()
+	}
+	s := &Simulation{
+		latency:  latency,
+		logger:   l,
+		names:    make([]mesh.PeerName, n),
+		channels: map[string]map[mesh.PeerName]*simPeer{},
+	}
+	for i := range s.names {
+		id := uint64(i + 1)
+		name, err := mesh.PeerNameFromUserInput(fmt.Sprintf(
+			"%02x:%02x:%02x:%02x:%02x:%02x",
+			byte(id>>40), byte(id>>32), byte(id>>24), byte(id>>16), byte(id>>8), byte(id),
+		))
+		if err != nil {
+			return nil, err
+		}
+		s.names[i] = name
+	}
+	return s, nil
+}
+
+// Replicas returns the number of simulated replicas.
+func (s *Simulation) Replicas() int {
+	return len(s.names)
+}
+
+// PeerName returns the peer name of the i-th replica.
+func (s *Simulation) PeerName(i int) mesh.PeerName {
+	return s.names[i]
+}
+
+// NewGossip connects the gossiper of the i-th replica to the gossipers of
+// the other replicas on the named channel. It returns the mesh.Gossip the
+// gossiper sends its messages through.
+func (s *Simulation) NewGossip(i int, channel string, g mesh.Gossiper) mesh.Gossip {
+	p := &simPeer{
+		name:     s.names[i],
+		gossiper: g,
+		queue:    make(chan simMessage, simQueueSize),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	peers, ok := s.channels[channel]
+	if !ok {
+		peers = map[mesh.PeerName]*simPeer{}
+		s.channels[channel] = peers
+	}
+	if prev, ok := peers[p.name]; ok {
+		close(prev.queue)
+	}
+	peers[p.name] = p
+
+	return &simGossip{sim: s, channel: channel, src: p.name}
+}
+
+// Stop stops delivering messages. Pending messages are dropped.
+func (s *Simulation) Stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	for _, peers := range s.channels {
+		for _, p := range peers {
+			close(p.done)
+			close(p.queue)
+		}
+	}
+}
+
+// send queues the message for delivery to the replica on the channel. It
+// must be called with the read lock held.
+func (s *Simulation) send(channel string, p *simPeer, m simMessage) {
+	select {
+	case p.queue <- m:
+	default:
+		level.Warn(s.logger).Log("msg", "Dropping simulated gossip message", "channel", channel, "src", m.src, "dst", p.name)
+	}
+}
+
+type simMessage struct {
+	src       mesh.PeerName
+	msg       []byte
+	unicast   bool
+	deliverAt time.Time
+}
+
+// simPeer delivers the messages of a channel to the gossiper of a replica.
+type simPeer struct {
+	name     mesh.PeerName
+	gossiper mesh.Gossiper
+	queue    chan simMessage
+	done     chan struct{}
+}
+
+func (p *simPeer) run() {
+	for m := range p.queue {
+		if d := time.Until(m.deliverAt); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-p.done:
+				return
+			}
+		}
+		// Errors are handled by the gossipers themselves like with the
+		// mesh, which only logs them.
+		if m.unicast {
+			p.gossiper.OnGossipUnicast(m.src, m.msg)
+		} else {
+			p.gossiper.OnGossipBroadcast(m.src, m.msg)
+		}
+	}
+}
+
+// simGossip implements mesh.Gossip for a replica on a channel.
+type simGossip struct {
+	sim     *Simulation
+	channel string
+	src     mesh.PeerName
+}
+
+// GossipUnicast implements the mesh.Gossip interface.
+func (g *simGossip) GossipUnicast(dst mesh.PeerName, msg []byte) error {
+	g.sim.mtx.RLock()
+	defer g.sim.mtx.RUnlock()
+
+	if g.sim.stopped {
+		return nil
+	}
+	p, ok := g.sim.channels[g.channel][dst]
+	if !ok {
+		return fmt.Errorf("unknown peer %s", dst)
+	}
+	g.sim.send(g.channel, p, simMessage{
+		src:       g.src,
+		msg:       msg,
+		unicast:   true,
+		deliverAt: time.Now().Add(g.sim.latency),
+	})
+	return nil
+}
+
+// GossipBroadcast implements the mesh.Gossip interface.
+func (g *simGossip) GossipBroadcast(update mesh.GossipData) {
+	msgs := update.Encode()
+
+	g.sim.mtx.RLock()
+	defer g.sim.mtx.RUnlock()
+
+	if g.sim.stopped {
+		return
+	}
+	deliverAt := time.Now().Add(g.sim.latency)
+	for name, p := range g.sim.channels[g.channel] {
+		if name == g.src {
+			continue
+		}
+		for _, msg := range msgs {
+			g.sim.send(g.channel, p, simMessage{src: g.src, msg: msg, deliverAt: deliverAt})
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/nflog"
+	pb "github.com/prometheus/alertmanager/nflog/nflogpb"
+)
+
+func TestSimulation(t *testing.T) {
+	sim, err := NewSimulation(3, 10*time.Millisecond, nil)
+	require.NoError(t, err)
+	defer sim.Stop()
+
+	require.Equal(t, 3, sim.Replicas())
+	require.NotEqual(t, sim.PeerName(0), sim.PeerName(1))
+
+	var (
+		logs     = make([]nflog.Log, sim.Replicas())
+		recv     = &pb.Receiver{GroupName: "team", Integration: "email"}
+		newNflog = func(i int) {
+			logs[i], err = nflog.New(
+				nflog.WithRetention(time.Hour),
+				nflog.WithMesh(func(g mesh.Gossiper) mesh.Gossip {
+					return sim.NewGossip(i, "nflog", g)
+				}),
+			)
+			require.NoError(t, err)
+		}
+		found = func(l nflog.Log, key string) bool {
+			_, err := l.QueryOne(nflog.QGroupKey(key), nflog.QReceiver(recv))
+			return err == nil
+		}
+		eventually = func(f func() bool) {
+			deadline := time.Now().Add(5 * time.Second)
+			for !f() {
+				if time.Now().After(deadline) {
+					t.Fatal("condition not met in time")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+	)
+	newNflog(0)
+	newNflog(1)
+
+	// Broadcasts reach all other replicas after the latency.
+	require.NoError(t, logs[0].Log(recv, "key", []uint64{1}, nil))
+	require.False(t, found(logs[1], "key"), "broadcast must be delayed")
+	eventually(func() bool { return found(logs[1], "key") })
+
+	// A replica joining later receives missing entries through unicast
+	// repair requests and responses.
+	newNflog(2)
+	require.False(t, found(logs[2], "key"))
+	require.NoError(t, logs[2].Repair(sim.PeerName(0)))
+	eventually(func() bool { return found(logs[2], "key") })
+
+	require.Error(t, sim.NewGossip(0, "other", nil).GossipUnicast(mesh.UnknownPeerName, nil))
+
+	sim.Stop()
+	require.NoError(t, logs[0].Log(recv, "stopped", []uint64{1}, nil))
+	time.Sleep(20 * time.Millisecond)
+	require.False(t, found(logs[1], "stopped"), "messages must not be delivered after stopping")
+}
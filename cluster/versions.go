@@ -0,0 +1,317 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/mesh"
+)
+
+// PeerInfo describes the software a peer runs.
+type PeerInfo struct {
+	Version string `json:"version"`
+	// Features are the enabled features that affect the shared state.
+	Features []string `json:"features,omitempty"`
+	// Schemas maps gossip channels to the version of the state schema
+	// shared over them.
+	Schemas map[string]int `json:"schemas,omitempty"`
+	// GossipCompression is whether the peer decodes gzip compressed gossip
+	// messages.
+	GossipCompression bool `json:"gossipCompression,omitempty"`
+	// GossipSnappy is whether the peer decodes snappy compressed gossip
+	// messages, which are sent once all peers do.
+	GossipSnappy bool `json:"gossipSnappy,omitempty"`
+	// Started orders the information of a peer across restarts.
+	Started time.Time `json:"started"`
+}
+
+// PeerVersions shares the PeerInfo of all peers over its own gossip
+// channel. Gossip from peers with a different state schema version for a
+// channel is ignored on that channel, so that peers stay operational while
+// they are upgraded one by one. Peers that did not share their information
+// are assumed to be compatible.
+//
+// Like with the Blocklist, only broadcast and unicast messages can be
+// attributed to a peer. Periodic full-state gossip is always processed.
+type PeerVersions struct {
+	self   mesh.PeerName
+	local  PeerInfo
+	logger log.Logger
+
+	mtx   sync.RWMutex
+	peers versionData
+
+	droppedTotal *prometheus.CounterVec
+}
+
+// NewPeerVersions returns PeerVersions sharing the information of the local
+// peer with the given name.
+func NewPeerVersions(self mesh.PeerName, local PeerInfo, l log.Logger, r prometheus.Registerer) *PeerVersions {
+	pv := &PeerVersions{
+		self:   self,
+		local:  local,
+		logger: l,
+		peers:  versionData{self: local},
+		droppedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "alertmanager_mesh_gossip_incompatible_total",
+			Help: "Number of gossip messages ignored because their sender uses an incompatible state schema.",
+		}, []string{"channel"}),
+	}
+	if pv.logger == nil {
+		pv.logger = log.NewNopLogger()
+	}
+	if r != nil {
+		incompatible := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "alertmanager_mesh_incompatible_peers",
+			Help: "Number of peers using an incompatible state schema on any channel.",
+		}, func() float64 {
+			pv.mtx.RLock()
+			defer pv.mtx.RUnlock()
+
+			var n int
+			for _, info := range pv.peers {
+				if len(pv.incompatibleSchemas(info)) > 0 {
+					n++
+				}
+			}
+			return float64(n)
+		})
+		r.MustRegister(pv.droppedTotal, incompatible)
+	}
+	return pv
+}
+
+// Wrap returns a mesh.Gossiper for the named channel that ignores messages
+// from peers with an incompatible state schema for it.
+func (pv *PeerVersions) Wrap(channel string, g mesh.Gossiper) mesh.Gossiper {
+	if pv == nil {
+		return g
+	}
+	return &versionedGossiper{Gossiper: g, pv: pv, channel: channel}
+}
+
+// Info returns the information shared by the named peer.
+func (pv *PeerVersions) Info(name mesh.PeerName) (PeerInfo, bool) {
+	if pv == nil {
+		return PeerInfo{}, false
+	}
+	pv.mtx.RLock()
+	defer pv.mtx.RUnlock()
+
+	info, ok := pv.peers[name]
+	return info, ok
+}
+
+// Mismatches returns descriptions of how the named peer differs from the
+// local peer. It returns nil for peers that did not share their
+// information.
+func (pv *PeerVersions) Mismatches(name mesh.PeerName) []string {
+	info, ok := pv.Info(name)
+	if !ok {
+		return nil
+	}
+	var res []string
+	if info.Version != pv.local.Version {
+		res = append(res, fmt.Sprintf("version %s differs from local version %s", info.Version, pv.local.Version))
+	}
+	res = append(res, pv.incompatibleSchemas(info)...)
+
+	local := map[string]bool{}
+	for _, f := range pv.local.Features {
+		local[f] = true
+	}
+	for _, f := range info.Features {
+		if !local[f] {
+			res = append(res, fmt.Sprintf("feature %s is only enabled on the peer", f))
+		}
+		delete(local, f)
+	}
+	var missing []string
+	for f := range local {
+		missing = append(missing, f)
+	}
+	sort.Strings(missing)
+	for _, f := range missing {
+		res = append(res, fmt.Sprintf("feature %s is not enabled on the peer", f))
+	}
+	return res
+}
+
+// incompatibleSchemas returns descriptions of the channels for which the
+// peer uses a different state schema version.
+func (pv *PeerVersions) incompatibleSchemas(info PeerInfo) []string {
+	var channels []string
+	for c := range info.Schemas {
+		channels = append(channels, c)
+	}
+	sort.Strings(channels)
+
+	var res []string
+	for _, c := range channels {
+		if v, ok := pv.local.Schemas[c]; ok && v != info.Schemas[c] {
+			res = append(res, fmt.Sprintf("%s schema version %d is incompatible with local version %d", c, info.Schemas[c], v))
+		}
+	}
+	return res
+}
+
+// compatible returns whether gossip from the peer on the channel can be
+// processed.
+func (pv *PeerVersions) compatible(src mesh.PeerName, channel string) bool {
+	pv.mtx.RLock()
+	defer pv.mtx.RUnlock()
+
+	info, ok := pv.peers[src]
+	if !ok {
+		return true
+	}
+	v, ok := info.Schemas[channel]
+	if !ok {
+		return true
+	}
+	lv, ok := pv.local.Schemas[channel]
+	return !ok || v == lv
+}
+
+// merge merges the received information and returns the updated entries.
+func (pv *PeerVersions) merge(vd versionData) versionData {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	delta := versionData{}
+	for name, info := range vd {
+		// Only the local peer knows its current information.
+		if name == pv.self {
+			continue
+		}
+		if prev, ok := pv.peers[name]; ok && !info.Started.After(prev.Started) {
+			continue
+		}
+		pv.peers[name] = info
+		delta[name] = info
+
+		if msgs := pv.incompatibleSchemas(info); len(msgs) > 0 {
+			level.Warn(pv.logger).Log("msg", "Ignoring gossip from peer with incompatible state schema", "peer", name, "version", info.Version, "err", msgs[0])
+		} else if info.Version != pv.local.Version {
+			level.Info(pv.logger).Log("msg", "Peer runs a different version", "peer", name, "version", info.Version)
+		}
+	}
+	return delta
+}
+
+// Gossip implements the mesh.Gossiper interface.
+func (pv *PeerVersions) Gossip() mesh.GossipData {
+	pv.mtx.RLock()
+	defer pv.mtx.RUnlock()
+
+	vd := make(versionData, len(pv.peers))
+	for name, info := range pv.peers {
+		vd[name] = info
+	}
+	return vd
+}
+
+// OnGossip implements the mesh.Gossiper interface.
+func (pv *PeerVersions) OnGossip(msg []byte) (mesh.GossipData, error) {
+	vd, err := decodeVersionData(msg)
+	if err != nil {
+		return nil, err
+	}
+	if delta := pv.merge(vd); len(delta) > 0 {
+		return delta, nil
+	}
+	return nil, nil
+}
+
+// OnGossipBroadcast implements the mesh.Gossiper interface.
+func (pv *PeerVersions) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	vd, err := decodeVersionData(msg)
+	if err != nil {
+		return nil, err
+	}
+	return pv.merge(vd), nil
+}
+
+// OnGossipUnicast implements the mesh.Gossiper interface.
+func (pv *PeerVersions) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	vd, err := decodeVersionData(msg)
+	if err != nil {
+		return err
+	}
+	pv.merge(vd)
+	return nil
+}
+
+// versionData is the gossiped information of all known peers.
+type versionData map[mesh.PeerName]PeerInfo
+
+func decodeVersionData(msg []byte) (versionData, error) {
+	var vd versionData
+	if err := json.Unmarshal(msg, &vd); err != nil {
+		return nil, err
+	}
+	return vd, nil
+}
+
+// Encode implements the mesh.GossipData interface.
+func (vd versionData) Encode() [][]byte {
+	b, err := json.Marshal(vd)
+	if err != nil {
+		// Only maps, strings, integers and times are encoded.
+		panic(err)
+	}
+	return [][]byte{b}
+}
+
+// Merge implements the mesh.GossipData interface.
+func (vd versionData) Merge(other mesh.GossipData) mesh.GossipData {
+	for name, info := range other.(versionData) {
+		if prev, ok := vd[name]; !ok || info.Started.After(prev.Started) {
+			vd[name] = info
+		}
+	}
+	return vd
+}
+
+type versionedGossiper struct {
+	mesh.Gossiper
+	pv      *PeerVersions
+	channel string
+}
+
+// OnGossipUnicast implements the mesh.Gossiper interface.
+func (g *versionedGossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
+	if !g.pv.compatible(src, g.channel) {
+		g.pv.droppedTotal.WithLabelValues(g.channel).Inc()
+		return nil
+	}
+	return g.Gossiper.OnGossipUnicast(src, msg)
+}
+
+// OnGossipBroadcast implements the mesh.Gossiper interface.
+func (g *versionedGossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
+	if !g.pv.compatible(src, g.channel) {
+		g.pv.droppedTotal.WithLabelValues(g.channel).Inc()
+		return nil, nil
+	}
+	return g.Gossiper.OnGossipBroadcast(src, msg)
+}
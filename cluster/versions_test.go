@@ -0,0 +1,83 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
+)
+
+func TestPeerVersions(t *testing.T) {
+	var (
+		now   = time.Now()
+		local = PeerInfo{
+			Version:  "0.10.0",
+			Features: []string{"utf8-label-names"},
+			Schemas:  map[string]int{"nflog": 1, "silences": 1},
+			Started:  now,
+		}
+		pv = NewPeerVersions(1, local, nil, nil)
+		tg = &testGossiper{}
+		g  = pv.Wrap("nflog", tg)
+	)
+
+	remote := versionData{
+		// Peers must not override the information of the local peer.
+		1: PeerInfo{Version: "0.1.0", Started: now.Add(time.Hour)},
+		2: PeerInfo{Version: "0.10.0", Features: []string{"utf8-label-names"}, Schemas: map[string]int{"nflog": 1, "silences": 1}, Started: now},
+		3: PeerInfo{Version: "0.11.0", Features: []string{"nflog-hash-keys"}, Schemas: map[string]int{"nflog": 2, "silences": 1}, Started: now},
+	}
+	delta, err := pv.OnGossip(remote.Encode()[0])
+	require.NoError(t, err)
+	require.Len(t, delta, 2)
+
+	info, ok := pv.Info(1)
+	require.True(t, ok)
+	require.Equal(t, "0.10.0", info.Version)
+
+	require.Empty(t, pv.Mismatches(2))
+	require.Equal(t, []string{
+		"version 0.11.0 differs from local version 0.10.0",
+		"nflog schema version 2 is incompatible with local version 1",
+		"feature nflog-hash-keys is only enabled on the peer",
+		"feature utf8-label-names is not enabled on the peer",
+	}, pv.Mismatches(3))
+	// Peers that did not share their information are assumed compatible.
+	require.Empty(t, pv.Mismatches(4))
+
+	for _, src := range []mesh.PeerName{2, 3, 4} {
+		_, err := g.OnGossipBroadcast(src, []byte("a"))
+		require.NoError(t, err)
+		require.NoError(t, g.OnGossipUnicast(src, []byte("a")))
+	}
+	require.Equal(t, 4, tg.received, "gossip from incompatible peer must be ignored")
+
+	// The peer is upgraded to a compatible version after a restart.
+	up := versionData{3: PeerInfo{Version: "0.10.0", Features: []string{"utf8-label-names"}, Schemas: map[string]int{"nflog": 1}, Started: now.Add(time.Minute)}}
+	_, err = pv.OnGossipBroadcast(3, up.Encode()[0])
+	require.NoError(t, err)
+	require.Empty(t, pv.Mismatches(3))
+
+	_, err = g.OnGossipBroadcast(3, []byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, 5, tg.received)
+
+	// Outdated information is not merged.
+	delta, err = pv.OnGossip(remote.Encode()[0])
+	require.NoError(t, err)
+	require.Nil(t, delta)
+}
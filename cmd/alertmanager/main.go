@@ -18,6 +18,7 @@ import (
 	"encoding/binary"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -35,17 +36,24 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/alertmanager/api"
+	"github.com/prometheus/alertmanager/audit"
+	"github.com/prometheus/alertmanager/cluster"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/flap"
 	"github.com/prometheus/alertmanager/inhibit"
 	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pkg/snapshot"
+	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/alertmanager/ui"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
@@ -70,6 +78,10 @@ var (
 		Name: "alertmanager_config_last_reload_success_timestamp_seconds",
 		Help: "Timestamp of the last successful configuration reload.",
 	})
+	configDeprecations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "alertmanager_config_deprecated_settings",
+		Help: "Number of uses of deprecated settings in the currently loaded configuration.",
+	}, []string{"setting"})
 	alertsActive     prometheus.GaugeFunc
 	alertsSuppressed prometheus.GaugeFunc
 )
@@ -79,6 +91,7 @@ func init() {
 	prometheus.MustRegister(configSuccess)
 	prometheus.MustRegister(configSuccessTime)
 	prometheus.MustRegister(configHash)
+	prometheus.MustRegister(configDeprecations)
 	prometheus.MustRegister(version.NewCollector("alertmanager"))
 }
 
@@ -116,17 +129,72 @@ func main() {
 		dataDir    = flag.String("storage.path", "data/", "Base path for data storage.")
 		retention  = flag.Duration("data.retention", 5*24*time.Hour, "How long to keep data for.")
 
+		nflogMaxSize      = flag.Int64("nflog.snapshot-max-size", 0, "Size in bytes above which a warning is logged for notification log snapshots. Zero disables the limit.")
+		nflogPrune        = flag.Bool("nflog.snapshot-prune", false, "Drop the notification log entries expiring soonest to keep snapshots within -nflog.snapshot-max-size.")
+		nflogRotations    = flag.Int("nflog.snapshot-rotations", 0, "Number of previous notification log snapshots to keep for recovery.")
+		nflogIncrementals = flag.Int("nflog.snapshot-incrementals", 0, "Number of incremental notification log snapshots holding only the changed entries written between full snapshots. Zero always writes full snapshots.")
+		nflogHistory      = flag.Int("nflog.history", 0, "Number of previous notifications kept in memory per aggregation group and receiver.")
+		nflogMaxEntries   = flag.Int("nflog.max-entries", 0, "Maximum number of notification log entries. The oldest entries are evicted once it is exceeded. Zero means no limit. The limit applies per peer and should be the same on all peers, as evicted entries are not removed from peers.")
+		nflogHashKeys     = flag.Bool("nflog.hash-keys", false, "Index notification log entries by a fixed-size hash of their group key and receiver to bound memory usage for large group keys.")
+		nflogWriteBuf     = flag.Int("nflog.write-buffer", 0, "Number of notification log entries buffered for asynchronous writes, which are applied in batches. Zero makes writes synchronous.")
+		nflogBolt         = flag.Bool("nflog.boltdb", false, "Persist every notification log change to a BoltDB database in the data directory, which the notification log is loaded from instead of snapshots.")
+		nflogSkew         = flag.Duration("nflog.clock-skew-tolerance", 0, "How much newer notification log entries received from peers may be than local notifications replacing them, to tolerate peers with clocks ahead.")
+
+		snapshotCompression = flag.String("storage.snapshot-compression", "none", "Compression of notification log and silence snapshots. One of: [none, gzip, snappy]. Snapshots of any format are loaded regardless.")
+		snapshotCodec       = flag.String("storage.snapshot-codec", "proto", "Encoding of the entries of notification log and silence snapshots. One of: [proto, json]. JSON snapshots are human-readable for debugging. Snapshots of either encoding are loaded regardless.")
+		snapshotSync        = flag.String("storage.snapshot-sync", "on-close", "When notification log and silence snapshots are synced to disk. One of: [always, on-close, never]. Except for never, the data directory is synced after replacing a snapshot.")
+		snapshotStore       = flag.String("storage.snapshot-store", "", "URL of an object store notification log and silence snapshots are uploaded to, e.g. s3://bucket/prefix?region=eu-west-1 or an https:// URL accepting PUT requests. State is restored from it if there are no local snapshots.")
+		snapshotStoreOnly   = flag.Bool("storage.snapshot-store-only", false, "Only write snapshots to -storage.snapshot-store instead of the data directory.")
+
+		payloadBudget   = flag.Int64("notify.payload-memory-limit", 0, "Total size in bytes of rendered notification payloads held in memory. Payloads beyond it are spilled to -notify.payload-spill-dir until they are sent. Zero disables the limit.")
+		payloadSpillDir = flag.String("notify.payload-spill-dir", "", "Directory rendered notification payloads are spilled to. Defaults to the payloads directory below -storage.path.")
+
+		auditFile = flag.String("audit.file", "", "File to which audit entries of mutating web requests and silence events are appended as JSON lines. Entries are only kept in memory if empty.")
+		auditSize = flag.Int("audit.size", 1000, "Number of recent audit entries and silence events kept in memory.")
+		auditUser = flag.String("audit.user-header", audit.DefaultPrincipalHeader, "Request header naming the user on whose behalf requests are made, as recorded in the audit log. It is only trustworthy if an authenticating proxy in front of Alertmanager sets it and strips it from client requests. That proxy should also restrict access to the unauthenticated /api/v1/audit endpoints.")
+
+		flapWindow    = flag.Duration("alerts.flap-window", time.Hour, "Time range over which firing/resolved transitions of an alert are counted.")
+		flapThreshold = flag.Int("alerts.flap-threshold", 0, "Number of transitions within -alerts.flap-window at which an alert is considered flapping. Zero disables flapping detection.")
+		flapHold      = flag.Duration("alerts.flap-hold", 10*time.Minute, "How long a flapping alert has to remain unchanged before notifications for it are sent.")
+
+		backfillTimeout = flag.Duration("alerts.backfill-timeout", 10*time.Second, "Timeout of requests for the alerts of -alerts.backfill-url servers.")
+
+		alertMemoryLimit = flag.Int64("alerts.memory-limit", 0, "Approximate size in bytes of the alerts held in memory above which new alerts are refused with a 429 status, or 413 if they exceed it on their own. Updates of existing alerts not growing their size are always accepted. Zero disables the limit.")
+
+		cardinalityTopK = flag.Int("alerts.cardinality-top-k", 10, "Number of labels with the highest cardinality among active alerts to export as metrics. Zero disables the metrics.")
+
+		labelCatalog = flag.Duration("silences.label-catalog-retention", 0, "How long the label names of received alerts are remembered to warn about silence matchers referencing label names no alert had. Zero disables the warnings.")
+
 		externalURL   = flag.String("web.external-url", "", "The URL under which Alertmanager is externally reachable (for example, if Alertmanager is served via a reverse proxy). Used for generating relative and absolute links back to Alertmanager itself. If the URL has a path portion, it will be used to prefix all HTTP endpoints served by Alertmanager. If omitted, relevant URL components will be derived automatically.")
 		routePrefix   = flag.String("web.route-prefix", "", "Prefix for the internal routes of web endpoints. Defaults to path of -web.external-url.")
 		listenAddress = flag.String("web.listen-address", ":9093", "Address to listen on for the web interface and API.")
+		stateTransfer = flag.Bool("web.enable-state-transfer", false, "Enable the /api/v1/admin/export and /api/v1/admin/import endpoints used by amtool backup and restore. They are not authenticated, so access to them should be restricted by a proxy.")
+		drainTimeout  = flag.Duration("web.drain-timeout", time.Minute, "How long to wait for notifications in progress when draining. Clients sending alerts while draining are asked to retry after this duration.")
 
 		meshListen = flag.String("mesh.listen-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(mesh.Port)), "Mesh listen address. Pass an empty string to disable.")
 		hwaddr     = flag.String("mesh.peer-id", "", "Mesh peer ID (default: MAC address).")
 		nickname   = flag.String("mesh.nickname", mustHostname(), "Mesh peer nickname.")
 		password   = flag.String("mesh.password", "", "Password to join the peer network (empty password disables encryption).")
+
+		blockThreshold = flag.Int("mesh.block-threshold", 10, "Number of gossip messages from a peer failing to decode or merge within -mesh.block-window after which its gossip is ignored. Zero disables blocking.")
+		blockWindow    = flag.Duration("mesh.block-window", time.Minute, "Time range over which gossip failures of a peer are counted.")
+		blockDuration  = flag.Duration("mesh.block-duration", 10*time.Minute, "How long gossip from a blocked peer is ignored.")
+
+		compressionThreshold = flag.Int("mesh.gossip-compression-threshold", 0, "Size in bytes above which notification log and silence gossip messages are compressed once all peers support it. Zero disables compression.")
+
+		simulate        = flag.Int("cluster.simulate", 0, "Run the given number of replicas in-process, gossiping through in-memory transports instead of the mesh, to reproduce high availability behavior on a single machine. Only the first replica serves the API and persists its state. For development only.")
+		simulateLatency = flag.Duration("cluster.simulate-latency", 0, "Delay of gossip messages between the replicas run by -cluster.simulate.")
+
+		utf8LabelNames = flag.Bool("labels.utf8-names", false, "Accept label names with any UTF-8 characters in alerts, silences and the configuration. Names that are not classic Prometheus label names must be quoted in matcher filters. Older peers and clients may reject such names.")
 	)
 	peers := &stringset{}
 	flag.Var(peers, "mesh.peer", "Initial peers (may be repeated)")
+	backfillURLs := &stringset{}
+	flag.Var(backfillURLs, "alerts.backfill-url", "URL of a Prometheus server whose firing alerts are inserted on startup, so that they are available before the server sends them again (may be repeated)")
+	priorities := cluster.PeerPriorities{}
+	flag.Var(priorities, "mesh.peer-priority", "Notification priority of a peer as <nickname or peer ID>=<priority>. Peers with lower priority notify first, peers without one last (may be repeated)")
+	receiverRetention := retentions{}
+	flag.Var(receiverRetention, "nflog.receiver-retention", "Retention of the notification log entries of a receiver or integration as <receiver or integration>=<duration>, e.g. pagerduty=168h. Receiver names take precedence over integration names. Other entries are kept for -data.retention (may be repeated)")
 
 	logLevel := &promlog.AllowedLevel{}
 	if err := logLevel.Set("info"); err != nil {
@@ -138,6 +206,8 @@ func main() {
 
 	logger := promlog.New(*logLevel)
 
+	types.UTF8LabelNames = *utf8LabelNames
+
 	if *hwaddr == "" {
 		*hwaddr = mustHardwareAddr()
 	}
@@ -161,6 +231,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	var sim *cluster.Simulation
+	if *simulate > 0 {
+		sim, err = cluster.NewSimulation(*simulate, *simulateLatency, log.With(logger, "component", "simulation"))
+		if err != nil {
+			level.Error(logger).Log("msg", "Unable to simulate cluster", "err", err)
+			os.Exit(1)
+		}
+		level.Warn(logger).Log("msg", "Simulating cluster replicas in-process, the mesh is disabled", "replicas", *simulate)
+		*meshListen = ""
+	}
+
 	var mrouter *mesh.Router
 	if *meshListen != "" {
 		mrouter, err = initMesh(*meshListen, *hwaddr, *nickname, *password, log.With(logger, "component", "mesh"))
@@ -170,25 +251,138 @@ func main() {
 		}
 	}
 
+	compression, err := snapshot.ParseCompression(*snapshotCompression)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+
+	codec, err := snapshot.ParseCodec(*snapshotCodec)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+
+	syncPolicy, err := snapshot.ParseSyncPolicy(*snapshotSync)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+
+	var (
+		store         snapshot.Store
+		nflogSnapf    = filepath.Join(*dataDir, "nflog")
+		silencesSnapf = filepath.Join(*dataDir, "silences")
+	)
+	if *snapshotStore != "" {
+		if store, err = snapshot.NewStore(*snapshotStore); err != nil {
+			level.Error(logger).Log("err", err)
+			os.Exit(1)
+		}
+	}
+	if *snapshotStoreOnly {
+		if store == nil {
+			level.Error(logger).Log("err", "-storage.snapshot-store-only requires -storage.snapshot-store")
+			os.Exit(1)
+		}
+		nflogSnapf, silencesSnapf = "", ""
+	}
+
+	var budget *notify.PayloadBudget
+	if *payloadBudget > 0 {
+		if *payloadSpillDir == "" {
+			*payloadSpillDir = filepath.Join(*dataDir, "payloads")
+		}
+		if budget, err = notify.NewPayloadBudget(*payloadBudget, *payloadSpillDir); err != nil {
+			level.Error(logger).Log("msg", "Unable to create notification payload spill directory", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	blocklist := cluster.NewBlocklist(*blockThreshold, *blockWindow, *blockDuration, log.With(logger, "component", "mesh"), prometheus.DefaultRegisterer)
+
+	var (
+		peerVersions      *cluster.PeerVersions
+		gossipCompression *cluster.GossipCompression
+	)
+	if mrouter != nil {
+		var features []string
+		if *utf8LabelNames {
+			features = append(features, "labels.utf8-names")
+		}
+		if *nflogHashKeys {
+			features = append(features, "nflog.hash-keys")
+		}
+		peerVersions = cluster.NewPeerVersions(mrouter.Ourself.Name, cluster.PeerInfo{
+			Version:  version.Version,
+			Features: features,
+			Schemas: map[string]int{
+				"nflog":    nflog.GossipVersion,
+				"silences": silence.GossipVersion,
+			},
+			GossipCompression: true,
+			GossipSnappy:      true,
+			Started:           time.Now(),
+		}, log.With(logger, "component", "mesh"), prometheus.DefaultRegisterer)
+
+		gossipCompression = cluster.NewGossipCompression(*compressionThreshold, peerVersions, func() []mesh.PeerName {
+			var names []mesh.PeerName
+			for _, p := range mrouter.Peers.Descriptions() {
+				names = append(names, p.Name)
+			}
+			return names
+		}, prometheus.DefaultRegisterer)
+
+		if _, err := mrouter.NewGossip("peers", peerVersions); err != nil {
+			level.Error(logger).Log("err", err)
+			os.Exit(1)
+		}
+	}
+
 	stopc := make(chan struct{})
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	notificationLogOpts := []nflog.Option{
+	// Options that affect notifications and are shared by simulated replicas.
+	nflogBehaviorOpts := []nflog.Option{
+		nflog.WithHistory(*nflogHistory),
+		nflog.WithHashedKeys(*nflogHashKeys),
+		nflog.WithMaxEntries(*nflogMaxEntries),
+		nflog.WithAsyncWrites(*nflogWriteBuf),
+		nflog.WithClockSkewTolerance(*nflogSkew),
+		nflog.WithReceiverRetention(receiverRetention.retention),
+	}
+	notificationLogOpts := append([]nflog.Option{
 		nflog.WithRetention(*retention),
-		nflog.WithSnapshot(filepath.Join(*dataDir, "nflog")),
+		nflog.WithSnapshot(nflogSnapf),
+		nflog.WithMaxSnapshotSize(*nflogMaxSize, *nflogPrune),
+		nflog.WithSnapshotRotation(*nflogRotations),
+		nflog.WithIncrementalSnapshots(*nflogIncrementals),
+		nflog.WithSnapshotCompression(compression),
+		nflog.WithSnapshotCodec(codec),
+		nflog.WithSnapshotSync(syncPolicy),
 		nflog.WithMaintenance(15*time.Minute, stopc, wg.Done),
 		nflog.WithMetrics(prometheus.DefaultRegisterer),
 		nflog.WithLogger(log.With(logger, "component", "nflog")),
+	}, nflogBehaviorOpts...)
+	if store != nil {
+		notificationLogOpts = append(notificationLogOpts, nflog.WithSnapshotStore(store, "nflog"))
+	}
+	if *nflogBolt {
+		notificationLogOpts = append(notificationLogOpts, nflog.WithBoltDB(filepath.Join(*dataDir, "nflog.db")))
 	}
 	if *meshListen != "" {
 		notificationLogOpts = append(notificationLogOpts, nflog.WithMesh(func(g mesh.Gossiper) mesh.Gossip {
-			res, err := mrouter.NewGossip("nflog", g)
+			res, err := mrouter.NewGossip("nflog", blocklist.Wrap("nflog", peerVersions.Wrap("nflog", gossipCompression.Wrap("nflog", g))))
 			if err != nil {
 				level.Error(logger).Log("err", err)
 				os.Exit(1)
 			}
-			return res
+			return gossipCompression.WrapGossip("nflog", res)
+		}))
+	} else if sim != nil {
+		notificationLogOpts = append(notificationLogOpts, nflog.WithMesh(func(g mesh.Gossiper) mesh.Gossip {
+			return sim.NewGossip(0, "nflog", g)
 		}))
 	}
 	notificationLog, err := nflog.New(notificationLogOpts...)
@@ -201,19 +395,28 @@ func main() {
 	newMarkerMetrics(marker)
 
 	silenceOpts := silence.Options{
-		SnapshotFile: filepath.Join(*dataDir, "silences"),
-		Retention:    *retention,
-		Logger:       log.With(logger, "component", "silences"),
-		Metrics:      prometheus.DefaultRegisterer,
+		SnapshotFile:        silencesSnapf,
+		SnapshotCompression: compression,
+		SnapshotCodec:       codec,
+		SnapshotSync:        syncPolicy,
+		SnapshotStore:       store,
+		SnapshotStoreName:   "silences",
+		Retention:           *retention,
+		Logger:              log.With(logger, "component", "silences"),
+		Metrics:             prometheus.DefaultRegisterer,
 	}
 	if *meshListen != "" {
 		silenceOpts.Gossip = func(g mesh.Gossiper) mesh.Gossip {
-			res, err := mrouter.NewGossip("silences", g)
+			res, err := mrouter.NewGossip("silences", blocklist.Wrap("silences", peerVersions.Wrap("silences", gossipCompression.Wrap("silences", g))))
 			if err != nil {
 				level.Error(logger).Log("err", err)
 				os.Exit(1)
 			}
-			return res
+			return gossipCompression.WrapGossip("silences", res)
+		}
+	} else if sim != nil {
+		silenceOpts.Gossip = func(g mesh.Gossiper) mesh.Gossip {
+			return sim.NewGossip(0, "silences", g)
 		}
 	}
 	silences, err := silence.New(silenceOpts)
@@ -226,10 +429,20 @@ func main() {
 	// Start providers before router potentially sends updates.
 	wg.Add(1)
 	go func() {
-		silences.Maintenance(15*time.Minute, filepath.Join(*dataDir, "silences"), stopc)
+		silences.Maintenance(15*time.Minute, silencesSnapf, stopc)
 		wg.Done()
 	}()
 
+	var replicas []*replica
+	for i := 1; i < *simulate; i++ {
+		r, err := newReplica(sim, i, *retention, nflogBehaviorOpts, stopc, &wg, logger)
+		if err != nil {
+			level.Error(logger).Log("msg", "Unable to create simulated replica", "replica", i, "err", err)
+			os.Exit(1)
+		}
+		replicas = append(replicas, r)
+	}
+
 	// Disable mesh if empty string passed for mesh.listen-address flag.
 	if *meshListen != "" {
 		mrouter.Start()
@@ -242,6 +455,12 @@ func main() {
 			// Stop receiving updates from router before shutting down.
 			mrouter.Stop()
 		}
+		if sim != nil {
+			for _, r := range replicas {
+				r.disp.Stop()
+			}
+			sim.Stop()
+		}
 		wg.Wait()
 	}()
 
@@ -251,25 +470,75 @@ func main() {
 		os.Exit(1)
 	}
 	defer alerts.Close()
+	alerts.SetMemoryLimit(*alertMemoryLimit)
+
+	if *cardinalityTopK > 0 {
+		prometheus.MustRegister(provider.NewCardinalityCollector(alerts, *cardinalityTopK))
+	}
+
+	var catalog *provider.LabelCatalog
+	if *labelCatalog > 0 {
+		catalog = provider.NewLabelCatalog(*labelCatalog)
+		if err := catalog.ObservePending(alerts); err != nil {
+			level.Error(logger).Log("msg", "Reading label names of alerts failed", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	flaps := flap.NewDetector(alerts, flap.Options{
+		Window:    *flapWindow,
+		Threshold: *flapThreshold,
+		Hold:      *flapHold,
+	}, log.With(logger, "component", "flap"))
+	go flaps.Run()
+	defer flaps.Stop()
 
 	var (
-		inhibitor *inhibit.Inhibitor
-		tmpl      *template.Template
-		pipeline  notify.Stage
-		disp      *dispatch.Dispatcher
+		inhibitor    *inhibit.Inhibitor
+		autoResolver *dispatch.AutoResolver
+		expiry       *dispatch.SilenceExpiryNotifier
+		decisions    *notify.DecisionLog
+		tmpl         *template.Template
+		pipeline     notify.Stage
+		disp         *dispatch.Dispatcher
 	)
 	defer disp.Stop()
 
+	var auditWriter io.Writer
+	if *auditFile != "" {
+		f, err := os.OpenFile(*auditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			level.Error(logger).Log("msg", "Opening audit file failed", "err", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		auditWriter = f
+	}
+	auditLog := audit.New(*auditSize, auditWriter)
+	auditLog.SetPrincipalHeader(*auditUser)
+
 	apiv := api.New(
 		alerts,
 		silences,
+		notificationLog,
 		func(matchers []*labels.Matcher) dispatch.AlertOverview {
 			return disp.Groups(matchers)
 		},
-		marker.Status,
+		func(fp model.Fingerprint) types.AlertStatus {
+			s := marker.Status(fp)
+			s.FlapScore = flaps.Score(fp)
+			return s
+		},
 		mrouter,
+		blocklist,
+		peerVersions,
+		auditLog,
+		catalog,
 		logger,
 	)
+	if *stateTransfer {
+		apiv.EnableStateTransfer()
+	}
 
 	amURL, err := extURL(*listenAddress, *externalURL)
 	if err != nil {
@@ -279,16 +548,15 @@ func main() {
 
 	waitFunc := func() time.Duration { return 0 }
 	if *meshListen != "" {
-		waitFunc = meshWait(mrouter, 5*time.Second)
-	}
-	timeoutFunc := func(d time.Duration) time.Duration {
-		if d < notify.MinTimeout {
-			d = notify.MinTimeout
-		}
-		return d + waitFunc()
+		waitFunc = meshWait(mrouter, 5*time.Second, priorities)
+	} else if sim != nil {
+		waitFunc = simulationWait(0, 5*time.Second)
 	}
 
-	var hash float64
+	var (
+		hash           float64
+		resolveTimeout time.Duration
+	)
 	reload := func() (err error) {
 		level.Info(logger).Log("msg", "Loading configuration file", "file", *configFile)
 		defer func() {
@@ -309,7 +577,14 @@ func main() {
 
 		hash = md5HashAsMetricValue(plainCfg)
 
-		err = apiv.Update(conf, time.Duration(conf.Global.ResolveTimeout))
+		configDeprecations.Reset()
+		for _, d := range conf.Deprecations() {
+			level.Warn(logger).Log("msg", "Deprecated configuration setting", "path", d.Path, "reason", d.Message)
+			configDeprecations.WithLabelValues(d.Setting).Inc()
+		}
+
+		resolveTimeout = time.Duration(conf.Global.ResolveTimeout)
+		err = apiv.Update(conf, resolveTimeout)
 		if err != nil {
 			return err
 		}
@@ -318,11 +593,41 @@ func main() {
 		if err != nil {
 			return err
 		}
+		for _, ref := range conf.TemplateRefs() {
+			if !tmpl.Defined(ref.Name) {
+				return fmt.Errorf("%s: template %q is not defined", ref.Path, ref.Name)
+			}
+		}
 		tmpl.ExternalURL = amURL
+		tmpl.SummaryThreshold = conf.Global.SummaryThreshold
+		tmpl.Severities = conf.Global.Severities()
+		tmpl.Themes = map[string]template.Theme{}
+		for sev, t := range conf.Global.SeverityThemes {
+			tmpl.Themes[sev] = template.Theme{
+				Color:    t.Color,
+				Emoji:    t.Emoji,
+				Priority: t.Priority,
+			}
+		}
+		if q := conf.Global.PrometheusQuery; q != nil {
+			u, err := url.Parse(q.URL)
+			if err != nil {
+				return err
+			}
+			tmpl.Query = &template.QueryOptions{
+				URL:        u,
+				Timeout:    time.Duration(q.Timeout),
+				MaxSamples: q.MaxSamples,
+			}
+		}
 
 		inhibitor.Stop()
+		autoResolver.Stop()
+		expiry.Stop()
 		disp.Stop()
+		decisions.Stop()
 
+		decisions = notify.NewDecisionLog(conf.DecisionLog, marker, log.With(logger, "component", "decision_log"))
 		inhibitor = inhibit.NewInhibitor(alerts, conf.InhibitRules, marker, logger)
 		pipeline = notify.BuildPipeline(
 			conf.Receivers,
@@ -332,12 +637,55 @@ func main() {
 			silences,
 			notificationLog,
 			marker,
+			flaps,
+			budget,
+			decisions,
 			logger,
 		)
-		disp = dispatch.NewDispatcher(alerts, dispatch.NewRoute(conf.Route, nil), pipeline, marker, timeoutFunc, logger)
+		disp = dispatch.NewDispatcher(alerts, dispatch.NewRoute(conf.Route, nil), pipeline, marker, timeoutFunc(waitFunc), logger)
+
+		// Simulated replicas share the inhibitor as they receive the
+		// same alerts. Their decisions are not logged.
+		for _, r := range replicas {
+			r.disp.Stop()
+			p := notify.BuildPipeline(
+				conf.Receivers,
+				tmpl,
+				r.wait,
+				inhibitor,
+				r.silences,
+				r.nflog,
+				marker,
+				flaps,
+				budget,
+				nil,
+				r.logger,
+			)
+			r.disp = dispatch.NewDispatcher(alerts, dispatch.NewRoute(conf.Route, nil), p, marker, timeoutFunc(r.wait), r.logger)
+			go r.disp.Run()
+		}
+
+		autoResolver = dispatch.NewAutoResolver(alerts, conf.AutoResolveRules, dispatch.DefaultAutoResolveInterval, log.With(logger, "component", "autoresolve"))
+
+		// Expiring silences are notified about without silencing the
+		// notifications themselves.
+		var expiryStage notify.Stage
+		if se := conf.SilenceExpiry; se != nil {
+			for _, rc := range conf.Receivers {
+				if rc.Name == se.Receiver {
+					expiryStage = notify.BuildReceiverStage(rc, tmpl, waitFunc, notificationLog, budget, logger)
+				}
+			}
+		}
+		expiry = dispatch.NewSilenceExpiryNotifier(conf.SilenceExpiry, silences, alerts, marker, expiryStage, dispatch.DefaultSilenceExpiryInterval, log.With(logger, "component", "silence_expiry"))
 
 		go disp.Run()
 		go inhibitor.Run()
+		go autoResolver.Run()
+		go expiry.Run()
+		if decisions != nil {
+			go decisions.Run()
+		}
 
 		return nil
 	}
@@ -346,6 +694,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	if urls := backfillURLs.slice(); len(urls) > 0 {
+		go provider.Backfill(alerts, provider.BackfillOptions{
+			URLs:           urls,
+			Timeout:        *backfillTimeout,
+			ResolveTimeout: resolveTimeout,
+			Logger:         log.With(logger, "component", "backfill"),
+		})
+	}
+
 	// Make routePrefix default to externalURL path if empty string.
 	if routePrefix == nil || *routePrefix == "" {
 		*routePrefix = amURL.Path
@@ -359,9 +716,43 @@ func main() {
 		router = router.WithPrefix(*routePrefix)
 	}
 
-	webReload := make(chan struct{})
+	var (
+		webReload = make(chan struct{})
+		webDrain  = make(chan struct{})
+		drained   = make(chan struct{})
+	)
+	ready := func() bool {
+		select {
+		case <-drained:
+			return false
+		default:
+			return true
+		}
+	}
+	// drain stops accepting alerts and sending notifications and writes
+	// snapshots, so that the instance can be replaced without losing state.
+	drain := func() {
+		level.Info(logger).Log("msg", "Draining", "timeout", *drainTimeout)
+
+		apiv.Drain(*drainTimeout)
+		if !disp.Drain(*drainTimeout) {
+			level.Warn(logger).Log("msg", "Aborted notifications still in progress after drain timeout")
+		}
+		for _, r := range replicas {
+			r.disp.Drain(*drainTimeout)
+		}
+		if err := notificationLog.SnapshotFile(); err != nil {
+			level.Error(logger).Log("msg", "Creating notification log snapshot failed", "err", err)
+		}
+		if err := silences.SnapshotFile(silencesSnapf); err != nil {
+			level.Error(logger).Log("msg", "Creating silences snapshot failed", "err", err)
+		}
+		close(drained)
 
-	ui.Register(router, webReload, logger)
+		level.Info(logger).Log("msg", "Drained, reporting as not ready")
+	}
+
+	ui.Register(router, webReload, webDrain, ready, auditLog, logger)
 
 	apiv.Register(router.WithPrefix("/api"))
 
@@ -378,10 +769,22 @@ func main() {
 
 	go func() {
 		<-hupReady
+		draining := false
 		for {
 			select {
 			case <-hup:
 			case <-webReload:
+			case <-webDrain:
+				if !draining {
+					draining = true
+					drain()
+				}
+				continue
+			}
+			// Reloading would restart the dispatcher.
+			if draining {
+				level.Warn(logger).Log("msg", "Not reloading configuration while draining")
+				continue
 			}
 			reload()
 		}
@@ -395,29 +798,65 @@ func main() {
 	level.Info(logger).Log("msg", "Received SIGTERM, exiting gracefully...")
 }
 
-type peerDescSlice []mesh.PeerDescription
-
-func (s peerDescSlice) Len() int           { return len(s) }
-func (s peerDescSlice) Less(i, j int) bool { return s[i].UID < s[j].UID }
-func (s peerDescSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+// retentions maps receiver or integration names to the retention of their
+// notification log entries.
+type retentions map[string]time.Duration
 
-// meshWait returns a function that inspects the current peer state and returns
-// a duration of one base timeout for each peer with a higher ID than ourselves.
-func meshWait(r *mesh.Router, timeout time.Duration) func() time.Duration {
-	return func() time.Duration {
-		var peers peerDescSlice
-		for _, desc := range r.Peers.Descriptions() {
-			peers = append(peers, desc)
+func (rs retentions) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		i := strings.LastIndex(v, "=")
+		if i <= 0 {
+			return fmt.Errorf("invalid retention %q, expected <receiver or integration>=<duration>", v)
+		}
+		d, err := time.ParseDuration(v[i+1:])
+		if err != nil {
+			return fmt.Errorf("invalid retention for %q: %s", v[:i], err)
 		}
-		sort.Sort(peers)
+		if d <= 0 {
+			return fmt.Errorf("retention for %q must be positive", v[:i])
+		}
+		rs[v[:i]] = d
+	}
+	return nil
+}
 
-		k := 0
-		for _, desc := range peers {
-			if desc.Self {
-				break
-			}
-			k++
+func (rs retentions) String() string {
+	res := make([]string, 0, len(rs))
+	for k, v := range rs {
+		res = append(res, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
+
+// retention returns the retention configured for the receiver name or
+// else its integration, or zero if there is none.
+func (rs retentions) retention(r *nflogpb.Receiver) time.Duration {
+	if d, ok := rs[r.GroupName]; ok {
+		return d
+	}
+	return rs[r.Integration]
+}
+
+// timeoutFunc returns a function that returns the timeout of notifications
+// for a group interval, which is extended by the wait of the instance.
+func timeoutFunc(wait func() time.Duration) func(time.Duration) time.Duration {
+	return func(d time.Duration) time.Duration {
+		if d < notify.MinTimeout {
+			d = notify.MinTimeout
 		}
+		return d + wait()
+	}
+}
+
+// meshWait returns a function that inspects the current peer state and returns
+// a duration of one base timeout for each peer ordered before ourselves.
+func meshWait(r *mesh.Router, timeout time.Duration, pp cluster.PeerPriorities) func() time.Duration {
+	return func() time.Duration {
+		k := pp.Position(r.Peers.Descriptions())
 		peerPosition.Set(float64(k))
 		return time.Duration(k) * timeout
 	}
@@ -0,0 +1,90 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/weaveworks/mesh"
+
+	"github.com/prometheus/alertmanager/cluster"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/silence"
+)
+
+// replica is an additional Alertmanager replica run in-process by
+// -cluster.simulate. It shares the alerts with the other replicas but has
+// its own notification log, silences and notification pipeline. Its state
+// is kept in memory only.
+type replica struct {
+	nflog    nflog.Log
+	silences *silence.Silences
+	wait     func() time.Duration
+	disp     *dispatch.Dispatcher
+	logger   log.Logger
+}
+
+// newReplica creates the i-th simulated replica. Its maintenance stops
+// once stopc is closed and is tracked by wg.
+func newReplica(sim *cluster.Simulation, i int, retention time.Duration, nflogOpts []nflog.Option, stopc chan struct{}, wg *sync.WaitGroup, logger log.Logger) (*replica, error) {
+	r := &replica{
+		wait:   simulationWait(i, 5*time.Second),
+		logger: log.With(logger, "replica", i),
+	}
+
+	wg.Add(1)
+	opts := append([]nflog.Option{
+		nflog.WithRetention(retention),
+		nflog.WithMaintenance(15*time.Minute, stopc, wg.Done),
+		nflog.WithLogger(log.With(r.logger, "component", "nflog")),
+		nflog.WithMesh(func(g mesh.Gossiper) mesh.Gossip {
+			return sim.NewGossip(i, "nflog", g)
+		}),
+	}, nflogOpts...)
+	nl, err := nflog.New(opts...)
+	if err != nil {
+		wg.Done()
+		return nil, err
+	}
+	r.nflog = nl
+
+	r.silences, err = silence.New(silence.Options{
+		Retention: retention,
+		Logger:    log.With(r.logger, "component", "silences"),
+		Gossip: func(g mesh.Gossiper) mesh.Gossip {
+			return sim.NewGossip(i, "silences", g)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	wg.Add(1)
+	go func() {
+		r.silences.Maintenance(15*time.Minute, "", stopc)
+		wg.Done()
+	}()
+
+	return r, nil
+}
+
+// simulationWait returns a function that returns a duration of one base
+// timeout for each simulated replica ordered before the i-th one.
+func simulationWait(i int, timeout time.Duration) func() time.Duration {
+	return func() time.Duration {
+		return time.Duration(i) * timeout
+	}
+}
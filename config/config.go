@@ -18,13 +18,18 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/url"
+	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/types"
 )
 
 // Secret is a string that must not be revealed on marshaling.
@@ -104,11 +109,16 @@ func resolveFilepaths(baseDir string, cfg *Config) {
 
 // Config is the top-level configuration for Alertmanager's config files.
 type Config struct {
-	Global       *GlobalConfig  `yaml:"global,omitempty" json:"global,omitempty"`
-	Route        *Route         `yaml:"route,omitempty" json:"route,omitempty"`
-	InhibitRules []*InhibitRule `yaml:"inhibit_rules,omitempty" json:"inhibit_rules,omitempty"`
-	Receivers    []*Receiver    `yaml:"receivers,omitempty" json:"receivers,omitempty"`
-	Templates    []string       `yaml:"templates" json:"templates"`
+	Global           *GlobalConfig      `yaml:"global,omitempty" json:"global,omitempty"`
+	Route            *Route             `yaml:"route,omitempty" json:"route,omitempty"`
+	InhibitRules     []*InhibitRule     `yaml:"inhibit_rules,omitempty" json:"inhibit_rules,omitempty"`
+	AutoResolveRules []*AutoResolveRule `yaml:"auto_resolve_rules,omitempty" json:"auto_resolve_rules,omitempty"`
+	SilenceExpiry    *SilenceExpiry     `yaml:"silence_expiry,omitempty" json:"silence_expiry,omitempty"`
+	SilencePolicy    *SilencePolicy     `yaml:"silence_policy,omitempty" json:"silence_policy,omitempty"`
+	AlertEnrichment  *AlertEnrichment   `yaml:"alert_enrichment,omitempty" json:"alert_enrichment,omitempty"`
+	DecisionLog      *DecisionLog       `yaml:"decision_log,omitempty" json:"decision_log,omitempty"`
+	Receivers        []*Receiver        `yaml:"receivers,omitempty" json:"receivers,omitempty"`
+	Templates        []string           `yaml:"templates" json:"templates"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
@@ -153,6 +163,26 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		*c.Global = DefaultGlobalConfig
 	}
 
+	// Drop disabled receivers and routes first, so that enabled ones may
+	// reuse their names.
+	disabled := map[string]struct{}{}
+	receivers := c.Receivers[:0]
+	for _, rcv := range c.Receivers {
+		if !rcv.Enabled() {
+			disabled[rcv.Name] = struct{}{}
+			continue
+		}
+		receivers = append(receivers, rcv)
+	}
+	c.Receivers = receivers
+
+	if c.Route != nil {
+		if c.Route.EnabledIf != "" {
+			return fmt.Errorf("root route must not have enabled_if")
+		}
+		dropDisabledRoutes(c.Route)
+	}
+
 	names := map[string]struct{}{}
 
 	for _, rcv := range c.Receivers {
@@ -269,30 +299,118 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 
 	// Validate that all receivers used in the routing tree are defined.
-	if err := checkReceiver(c.Route, names); err != nil {
+	if err := checkReceiver(c.Route, names, disabled); err != nil {
 		return err
 	}
+	if c.Route.MinSeverity != "" {
+		return fmt.Errorf("root route must not have a min_severity")
+	}
+	setSeverities(c.Route, c.Global.Severities())
+
+	for _, ar := range c.AutoResolveRules {
+		if ar.Timeout >= c.Global.ResolveTimeout {
+			return fmt.Errorf("auto-resolve timeout %s must be shorter than the resolve timeout %s", ar.Timeout, c.Global.ResolveTimeout)
+		}
+	}
+
+	if se := c.SilenceExpiry; se != nil {
+		if _, ok := names[se.Receiver]; !ok {
+			if _, ok := disabled[se.Receiver]; ok {
+				return fmt.Errorf("disabled receiver %q used for silence expiry notifications", se.Receiver)
+			}
+			return fmt.Errorf("undefined receiver %q used for silence expiry notifications", se.Receiver)
+		}
+	}
 
 	return checkOverflow(c.XXX, "config")
 }
 
+// setSeverities sets the severity mapping on all routes of the tree that
+// match on severity.
+func setSeverities(r *Route, m types.SeverityMap) {
+	if r.MinSeverity != "" {
+		r.Severities = m
+	}
+	for _, sr := range r.Routes {
+		setSeverities(sr, m)
+	}
+}
+
+// dropDisabledRoutes removes the disabled child routes from the routing
+// tree.
+func dropDisabledRoutes(r *Route) {
+	routes := r.Routes[:0]
+	for _, sr := range r.Routes {
+		if !sr.Enabled() {
+			continue
+		}
+		dropDisabledRoutes(sr)
+		routes = append(routes, sr)
+	}
+	r.Routes = routes
+}
+
 // checkReceiver returns an error if a node in the routing tree
-// references a receiver not in the given map.
-func checkReceiver(r *Route, receivers map[string]struct{}) error {
+// references a receiver not in the given map. Receivers in the
+// disabled map are reported as such.
+func checkReceiver(r *Route, receivers, disabled map[string]struct{}) error {
+	check := func(name, kind string) error {
+		if _, ok := receivers[name]; ok {
+			return nil
+		}
+		if _, ok := disabled[name]; ok {
+			return fmt.Errorf("disabled %s %q used in route", kind, name)
+		}
+		return fmt.Errorf("undefined %s %q used in route", kind, name)
+	}
+	if r.EscalationReceiver != "" {
+		if err := check(r.EscalationReceiver, "escalation receiver"); err != nil {
+			return err
+		}
+	}
 	if r.Receiver == "" {
 		return nil
 	}
-	if _, ok := receivers[r.Receiver]; !ok {
-		return fmt.Errorf("undefined receiver %q used in route", r.Receiver)
+	if err := check(r.Receiver, "receiver"); err != nil {
+		return err
 	}
 	for _, sr := range r.Routes {
-		if err := checkReceiver(sr, receivers); err != nil {
+		if err := checkReceiver(sr, receivers, disabled); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+var envNameRE = regexp.MustCompile(`^!?[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// checkEnabledIf validates an enabled_if condition.
+func checkEnabledIf(cond string) error {
+	if cond != "" && !envNameRE.MatchString(cond) {
+		return fmt.Errorf("invalid enabled_if %q, must be an environment variable name optionally prefixed with !", cond)
+	}
+	return nil
+}
+
+// enabled evaluates an enabled_if condition. The condition holds if the
+// named environment variable is set to a non-empty value that is not
+// false, e.g. "0" or "false". A leading ! negates the condition.
+func enabled(cond string) bool {
+	if cond == "" {
+		return true
+	}
+	negate := strings.HasPrefix(cond, "!")
+	if negate {
+		cond = cond[1:]
+	}
+	v := os.Getenv(cond)
+	on := v != ""
+	if b, err := strconv.ParseBool(v); err == nil {
+		on = b
+	}
+	return on != negate
+}
+
 // DefaultGlobalConfig provides global default values.
 var DefaultGlobalConfig = GlobalConfig{
 	ResolveTimeout: model.Duration(5 * time.Minute),
@@ -327,6 +445,20 @@ type GlobalConfig struct {
 	VictorOpsAPIURL  string `yaml:"victorops_api_url,omitempty" json:"victorops_api_url,omitempty"`
 	VictorOpsAPIKey  Secret `yaml:"victorops_api_key,omitempty" json:"victorops_api_key,omitempty"`
 
+	// SeverityThemes maps values of the severity label to the way
+	// notifications are presented by the default templates.
+	SeverityThemes map[string]*SeverityTheme `yaml:"severity_themes,omitempty" json:"severity_themes,omitempty"`
+	// SeverityMapping maps values of the severity label to the canonical
+	// severities info, warning, error, and critical.
+	SeverityMapping map[string]string `yaml:"severity_mapping,omitempty" json:"severity_mapping,omitempty"`
+
+	// PrometheusQuery enables the query template function.
+	PrometheusQuery *PrometheusQueryConfig `yaml:"prometheus_query,omitempty" json:"prometheus_query,omitempty"`
+
+	// SummaryThreshold is the number of alerts above which the default
+	// templates summarize a notification instead of listing all alerts.
+	SummaryThreshold int `yaml:"summary_threshold,omitempty" json:"summary_threshold,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
@@ -338,13 +470,102 @@ func (c *GlobalConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
+	if c.SummaryThreshold < 0 {
+		return fmt.Errorf("summary_threshold must not be negative")
+	}
+	for v, sev := range c.SeverityMapping {
+		if _, err := types.ParseSeverity(sev); err != nil {
+			return fmt.Errorf("invalid severity mapping for %q: %s", v, err)
+		}
+	}
 	return checkOverflow(c.XXX, "global")
 }
 
+// Severities returns the severity mapping in normalized form.
+func (c *GlobalConfig) Severities() types.SeverityMap {
+	m := make(types.SeverityMap, len(c.SeverityMapping))
+	for v, sev := range c.SeverityMapping {
+		m[v], _ = types.ParseSeverity(sev)
+	}
+	return m
+}
+
+// SeverityTheme configures the color, emoji, and priority used by the
+// default templates for alerts of a severity.
+type SeverityTheme struct {
+	Color    string `yaml:"color,omitempty" json:"color,omitempty"`
+	Emoji    string `yaml:"emoji,omitempty" json:"emoji,omitempty"`
+	Priority string `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SeverityTheme) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SeverityTheme
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return checkOverflow(c.XXX, "severity theme")
+}
+
+// DefaultPrometheusQueryConfig provides the default limits for queries
+// executed from templates.
+var DefaultPrometheusQueryConfig = PrometheusQueryConfig{
+	Timeout:    model.Duration(5 * time.Second),
+	MaxSamples: 100,
+}
+
+// PrometheusQueryConfig configures the Prometheus server that templates
+// can run instant queries against.
+type PrometheusQueryConfig struct {
+	URL        string         `yaml:"url" json:"url"`
+	Timeout    model.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	MaxSamples int            `yaml:"max_samples,omitempty" json:"max_samples,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *PrometheusQueryConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultPrometheusQueryConfig
+	type plain PrometheusQueryConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing url in prometheus_query config")
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q in prometheus_query config", c.URL)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout in prometheus_query config must be positive")
+	}
+	if c.MaxSamples <= 0 {
+		return fmt.Errorf("max_samples in prometheus_query config must be positive")
+	}
+	return checkOverflow(c.XXX, "prometheus_query")
+}
+
+// The change detection modes of routes.
+const (
+	// ChangeDetectionAny notifies about any newly firing or resolved
+	// alert of a group.
+	ChangeDetectionAny = "any"
+	// ChangeDetectionNewFiring only notifies about newly firing alerts.
+	ChangeDetectionNewFiring = "new_firing"
+	// ChangeDetectionGroupSize only notifies if the number of firing
+	// alerts crosses a threshold.
+	ChangeDetectionGroupSize = "group_size"
+)
+
 // A Route is a node that contains definitions of how to handle alerts.
 type Route struct {
-	Receiver string            `yaml:"receiver,omitempty" json:"receiver,omitempty"`
-	GroupBy  []model.LabelName `yaml:"group_by,omitempty" json:"group_by,omitempty"`
+	Receiver string     `yaml:"receiver,omitempty" json:"receiver,omitempty"`
+	GroupBy  LabelNames `yaml:"group_by,omitempty" json:"group_by,omitempty"`
 
 	Match    map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
 	MatchRE  map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
@@ -354,11 +575,51 @@ type Route struct {
 	GroupWait      *model.Duration `yaml:"group_wait,omitempty" json:"group_wait,omitempty"`
 	GroupInterval  *model.Duration `yaml:"group_interval,omitempty" json:"group_interval,omitempty"`
 	RepeatInterval *model.Duration `yaml:"repeat_interval,omitempty" json:"repeat_interval,omitempty"`
+	// GroupIntervalMax enables an exponential backoff of the group interval
+	// up to the given duration while a group's alerts do not change.
+	GroupIntervalMax *model.Duration `yaml:"group_interval_max,omitempty" json:"group_interval_max,omitempty"`
+	// ResolveInterval limits how often resolved alerts are included in
+	// notifications. ResolvedMaxAge drops resolved alerts from notifications
+	// once they have been resolved for longer than the given duration.
+	ResolveInterval *model.Duration `yaml:"resolve_interval,omitempty" json:"resolve_interval,omitempty"`
+	ResolvedMaxAge  *model.Duration `yaml:"resolved_max_age,omitempty" json:"resolved_max_age,omitempty"`
+	// EscalateAfter is the number of repeat intervals after which a group
+	// that keeps firing is escalated. Escalated notifications are sent to
+	// the EscalationReceiver if set, in addition to the route's receiver.
+	EscalateAfter      *int   `yaml:"escalate_after,omitempty" json:"escalate_after,omitempty"`
+	EscalationReceiver string `yaml:"escalation_receiver,omitempty" json:"escalation_receiver,omitempty"`
+	// ChangeDetection controls which changes of a group's alerts are
+	// notified about before the repeat interval passed. With the group_size
+	// mode, changes are only notified about if the number of firing alerts
+	// crosses one of the ChangeThresholds. Changes of the ChangeAnnotations
+	// of firing alerts are notified about regardless of the mode.
+	ChangeDetection   string   `yaml:"change_detection,omitempty" json:"change_detection,omitempty"`
+	ChangeThresholds  []int    `yaml:"change_thresholds,omitempty" json:"change_thresholds,omitempty"`
+	ChangeAnnotations []string `yaml:"change_annotations,omitempty" json:"change_annotations,omitempty"`
+	// MinSeverity restricts the route to alerts whose normalized severity
+	// is at least the given canonical severity.
+	MinSeverity string `yaml:"min_severity,omitempty" json:"min_severity,omitempty"`
+	// Severities is the global severity mapping. It is set when the
+	// configuration is loaded.
+	Severities types.SeverityMap `yaml:"-" json:"-"`
+
+	// EnabledIf names an environment variable that must be set for the
+	// route and its children to be part of the routing tree. A leading !
+	// requires it to be unset instead. It is evaluated when the
+	// configuration is loaded.
+	EnabledIf string `yaml:"enabled_if,omitempty" json:"enabled_if,omitempty"`
+
+	Metadata *Metadata `yaml:"metadata,omitempty" json:"metadata,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
+// Enabled returns whether the route's enabled_if condition holds.
+func (r *Route) Enabled() bool {
+	return enabled(r.EnabledIf)
+}
+
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
 func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type plain Route
@@ -367,13 +628,13 @@ func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 
 	for k := range r.Match {
-		if !model.LabelNameRE.MatchString(k) {
+		if !types.IsValidLabelName(model.LabelName(k)) {
 			return fmt.Errorf("invalid label name %q", k)
 		}
 	}
 
 	for k := range r.MatchRE {
-		if !model.LabelNameRE.MatchString(k) {
+		if !types.IsValidLabelName(model.LabelName(k)) {
 			return fmt.Errorf("invalid label name %q", k)
 		}
 	}
@@ -387,9 +648,72 @@ func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		groupBy[ln] = struct{}{}
 	}
 
+	if r.GroupInterval != nil && r.GroupIntervalMax != nil && *r.GroupIntervalMax < *r.GroupInterval {
+		return fmt.Errorf("group_interval_max must not be less than group_interval")
+	}
+
+	if r.EscalateAfter != nil && *r.EscalateAfter < 0 {
+		return fmt.Errorf("escalate_after must not be negative")
+	}
+	switch r.ChangeDetection {
+	case "", ChangeDetectionAny, ChangeDetectionNewFiring:
+		if len(r.ChangeThresholds) > 0 {
+			return fmt.Errorf("change_thresholds require the %s change detection", ChangeDetectionGroupSize)
+		}
+	case ChangeDetectionGroupSize:
+		if len(r.ChangeThresholds) == 0 {
+			return fmt.Errorf("missing change_thresholds for the %s change detection", ChangeDetectionGroupSize)
+		}
+		for i, n := range r.ChangeThresholds {
+			if n <= 0 || (i > 0 && n <= r.ChangeThresholds[i-1]) {
+				return fmt.Errorf("change_thresholds must be positive and increasing")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown change_detection %q", r.ChangeDetection)
+	}
+	changeAnnotations := map[string]struct{}{}
+	for _, an := range r.ChangeAnnotations {
+		if !types.IsValidLabelName(model.LabelName(an)) {
+			return fmt.Errorf("invalid annotation name %q in change_annotations", an)
+		}
+		if _, ok := changeAnnotations[an]; ok {
+			return fmt.Errorf("duplicated annotation %q in change_annotations", an)
+		}
+		changeAnnotations[an] = struct{}{}
+	}
+	if r.MinSeverity != "" {
+		if _, err := types.ParseSeverity(r.MinSeverity); err != nil {
+			return fmt.Errorf("invalid min_severity: %s", err)
+		}
+	}
+	if err := checkEnabledIf(r.EnabledIf); err != nil {
+		return err
+	}
+
 	return checkOverflow(r.XXX, "route")
 }
 
+// Metadata holds descriptive information about a route or receiver, such
+// as who maintains it. It has no effect on how alerts are processed.
+type Metadata struct {
+	Owner       string `yaml:"owner,omitempty" json:"owner,omitempty"`
+	Link        string `yaml:"link,omitempty" json:"link,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (m *Metadata) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Metadata
+	if err := unmarshal((*plain)(m)); err != nil {
+		return err
+	}
+	return checkOverflow(m.XXX, "metadata")
+}
+
 // InhibitRule defines an inhibition rule that mutes alerts that match the
 // target labels if an alert matching the source labels exists.
 // Both alerts have to have a set of labels being equal.
@@ -408,7 +732,7 @@ type InhibitRule struct {
 	TargetMatchRE map[string]Regexp `yaml:"target_match_re,omitempty" json:"target_match_re,omitempty"`
 	// A set of labels that must be equal between the source and target alert
 	// for them to be a match.
-	Equal model.LabelNames `yaml:"equal,omitempty" json:"equal,omitempty"`
+	Equal LabelNames `yaml:"equal,omitempty" json:"equal,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
@@ -422,25 +746,25 @@ func (r *InhibitRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 
 	for k := range r.SourceMatch {
-		if !model.LabelNameRE.MatchString(k) {
+		if !types.IsValidLabelName(model.LabelName(k)) {
 			return fmt.Errorf("invalid label name %q", k)
 		}
 	}
 
 	for k := range r.SourceMatchRE {
-		if !model.LabelNameRE.MatchString(k) {
+		if !types.IsValidLabelName(model.LabelName(k)) {
 			return fmt.Errorf("invalid label name %q", k)
 		}
 	}
 
 	for k := range r.TargetMatch {
-		if !model.LabelNameRE.MatchString(k) {
+		if !types.IsValidLabelName(model.LabelName(k)) {
 			return fmt.Errorf("invalid label name %q", k)
 		}
 	}
 
 	for k := range r.TargetMatchRE {
-		if !model.LabelNameRE.MatchString(k) {
+		if !types.IsValidLabelName(model.LabelName(k)) {
 			return fmt.Errorf("invalid label name %q", k)
 		}
 	}
@@ -448,6 +772,255 @@ func (r *InhibitRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return checkOverflow(r.XXX, "inhibit rule")
 }
 
+// AutoResolveRule resolves alerts of senders that only report events and
+// never resolve their alerts themselves.
+type AutoResolveRule struct {
+	// Match defines a set of labels that have to equal the given value for
+	// alerts to be resolved.
+	Match map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	// MatchRE defines pairs like Match but does regular expression
+	// matching.
+	MatchRE map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+	// Timeout after which matching alerts are resolved if they were not
+	// updated. It must be shorter than the global resolve timeout.
+	Timeout model.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// ResolutionMatch defines a set of labels that have to equal the given
+	// value for alerts whose firing resolves the matching alerts.
+	ResolutionMatch map[string]string `yaml:"resolution_match,omitempty" json:"resolution_match,omitempty"`
+	// ResolutionMatchRE defines pairs like ResolutionMatch but does regular
+	// expression matching.
+	ResolutionMatchRE map[string]Regexp `yaml:"resolution_match_re,omitempty" json:"resolution_match_re,omitempty"`
+	// A set of labels that must be equal between the resolution alert and
+	// the alerts it resolves.
+	Equal LabelNames `yaml:"equal,omitempty" json:"equal,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *AutoResolveRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AutoResolveRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+
+	for _, m := range []map[string]string{r.Match, r.ResolutionMatch} {
+		for k := range m {
+			if !types.IsValidLabelName(model.LabelName(k)) {
+				return fmt.Errorf("invalid label name %q", k)
+			}
+		}
+	}
+	for _, m := range []map[string]Regexp{r.MatchRE, r.ResolutionMatchRE} {
+		for k := range m {
+			if !types.IsValidLabelName(model.LabelName(k)) {
+				return fmt.Errorf("invalid label name %q", k)
+			}
+		}
+	}
+
+	if len(r.Match) == 0 && len(r.MatchRE) == 0 {
+		return fmt.Errorf("auto-resolve rule must have matchers")
+	}
+	if r.Timeout < 0 {
+		return fmt.Errorf("auto-resolve timeout must not be negative")
+	}
+	resolution := len(r.ResolutionMatch) > 0 || len(r.ResolutionMatchRE) > 0
+	if r.Timeout == 0 && !resolution {
+		return fmt.Errorf("auto-resolve rule must have a timeout or resolution matchers")
+	}
+	if len(r.Equal) > 0 && !resolution {
+		return fmt.Errorf("auto-resolve rule must have resolution matchers to use equal")
+	}
+
+	return checkOverflow(r.XXX, "auto-resolve rule")
+}
+
+// DefaultSilenceExpiry provides default values for silence expiry
+// notifications.
+var DefaultSilenceExpiry = SilenceExpiry{
+	NotifyBefore: model.Duration(time.Hour),
+}
+
+// SilenceExpiry configures notifications about silences that expire soon
+// while still silencing alerts.
+type SilenceExpiry struct {
+	// Receiver notified about expiring silences.
+	Receiver string `yaml:"receiver" json:"receiver"`
+	// How long before their expiry silences are notified about.
+	NotifyBefore model.Duration `yaml:"notify_before,omitempty" json:"notify_before,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (se *SilenceExpiry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*se = DefaultSilenceExpiry
+	type plain SilenceExpiry
+	if err := unmarshal((*plain)(se)); err != nil {
+		return err
+	}
+	if se.Receiver == "" {
+		return fmt.Errorf("silence expiry notifications must have a receiver")
+	}
+	if se.NotifyBefore <= 0 {
+		return fmt.Errorf("silence expiry notify_before must be positive")
+	}
+	return checkOverflow(se.XXX, "silence expiry")
+}
+
+// The actions of silence policies for silences lasting longer than the
+// maximum duration.
+const (
+	// SilencePolicyReject rejects the silences.
+	SilencePolicyReject = "reject"
+	// SilencePolicyClamp shortens the silences to the maximum duration.
+	SilencePolicyClamp = "clamp"
+)
+
+// DefaultSilencePolicy provides default values for the silence policy.
+var DefaultSilencePolicy = SilencePolicy{
+	Action: SilencePolicyReject,
+}
+
+// SilencePolicy restricts the silences that are created or updated through
+// the API.
+type SilencePolicy struct {
+	// MaxDuration is the maximum time silences last from their start, or
+	// from now for silences that started already. Zero means no limit.
+	MaxDuration model.Duration `yaml:"max_duration,omitempty" json:"max_duration,omitempty"`
+	// What to do with silences lasting longer, either reject or clamp.
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilencePolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSilencePolicy
+	type plain SilencePolicy
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	switch c.Action {
+	case SilencePolicyReject, SilencePolicyClamp:
+	default:
+		return fmt.Errorf("unknown silence policy action %q", c.Action)
+	}
+	return checkOverflow(c.XXX, "silence policy")
+}
+
+// DefaultAlertEnrichment provides default values for alert enrichment.
+var DefaultAlertEnrichment = AlertEnrichment{
+	MaxSize: 4096,
+}
+
+// AlertEnrichment configures the sources that may attach annotations to
+// alerts through the API.
+type AlertEnrichment struct {
+	// MaxSize is the maximum total size in bytes of the names and values
+	// of the annotations attached to an alert.
+	MaxSize int                 `yaml:"max_size,omitempty" json:"max_size,omitempty"`
+	Sources []*EnrichmentSource `yaml:"sources" json:"sources"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *AlertEnrichment) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultAlertEnrichment
+	type plain AlertEnrichment
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.MaxSize <= 0 {
+		return fmt.Errorf("alert enrichment max_size must be positive")
+	}
+	if len(c.Sources) == 0 {
+		return fmt.Errorf("alert enrichment must have at least one source")
+	}
+	names := map[string]struct{}{}
+	tokens := map[Secret]struct{}{}
+	for _, s := range c.Sources {
+		if _, ok := names[s.Name]; ok {
+			return fmt.Errorf("alert enrichment source name %q is not unique", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		if _, ok := tokens[s.Token]; ok {
+			return fmt.Errorf("token of alert enrichment source %q is not unique", s.Name)
+		}
+		tokens[s.Token] = struct{}{}
+	}
+	return checkOverflow(c.XXX, "alert enrichment")
+}
+
+// EnrichmentSource is a source attaching annotations to alerts, such as a
+// bot adding triage information. It authenticates with a bearer token.
+type EnrichmentSource struct {
+	// Name of the source, which is recorded with the annotations.
+	Name  string `yaml:"name" json:"name"`
+	Token Secret `yaml:"token" json:"token"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *EnrichmentSource) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EnrichmentSource
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("missing name in alert enrichment source")
+	}
+	if c.Token == "" {
+		return fmt.Errorf("missing token in alert enrichment source %q", c.Name)
+	}
+	return checkOverflow(c.XXX, "alert enrichment source")
+}
+
+// DefaultDecisionLog provides default values for the decision log.
+var DefaultDecisionLog = DecisionLog{
+	Timeout: model.Duration(10 * time.Second),
+}
+
+// DecisionLog configures a webhook receiving the decisions the notification
+// pipeline makes about the alerts of each group, such as alerts being
+// silenced or notifications being deduplicated.
+type DecisionLog struct {
+	// URL to which events are posted.
+	URL string `yaml:"url" json:"url"`
+	// Timeout of requests posting events.
+	Timeout model.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DecisionLog) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultDecisionLog
+	type plain DecisionLog
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing url in decision_log config")
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q in decision_log config", c.URL)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout in decision_log config must be positive")
+	}
+	return checkOverflow(c.XXX, "decision_log")
+}
+
 // Receiver configuration provides configuration on how to contact a receiver.
 type Receiver struct {
 	// A unique identifier for this receiver.
@@ -461,11 +1034,38 @@ type Receiver struct {
 	OpsGenieConfigs  []*OpsGenieConfig  `yaml:"opsgenie_configs,omitempty" json:"opsgenie_configs,omitempty"`
 	PushoverConfigs  []*PushoverConfig  `yaml:"pushover_configs,omitempty" json:"pushover_configs,omitempty"`
 	VictorOpsConfigs []*VictorOpsConfig `yaml:"victorops_configs,omitempty" json:"victorops_configs,omitempty"`
+	DingTalkConfigs  []*DingTalkConfig  `yaml:"dingtalk_configs,omitempty" json:"dingtalk_configs,omitempty"`
+	LarkConfigs      []*LarkConfig      `yaml:"lark_configs,omitempty" json:"lark_configs,omitempty"`
+	FileConfigs      []*FileConfig      `yaml:"file_configs,omitempty" json:"file_configs,omitempty"`
+
+	// AlignInterval defers notifications to the first flush after each
+	// multiple of the interval since midnight UTC, e.g. to the full and half
+	// hour for 30m. Groups with firing alerts of at least AlignBypassSeverity,
+	// which defaults to critical, are notified about right away.
+	AlignInterval       model.Duration `yaml:"align_interval,omitempty" json:"align_interval,omitempty"`
+	AlignBypassSeverity string         `yaml:"align_bypass_severity,omitempty" json:"align_bypass_severity,omitempty"`
+
+	// DedupByConfig keeps the notification log entries of each integration
+	// apart by a hash over its configuration, so that all groups are
+	// notified about again after it changed.
+	DedupByConfig bool `yaml:"dedup_by_config,omitempty" json:"dedup_by_config,omitempty"`
+
+	// EnabledIf names an environment variable that must be set for the
+	// receiver to be defined. It is evaluated when the configuration is
+	// loaded.
+	EnabledIf string `yaml:"enabled_if,omitempty" json:"enabled_if,omitempty"`
+
+	Metadata *Metadata `yaml:"metadata,omitempty" json:"metadata,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
+// Enabled returns whether the receiver's enabled_if condition holds.
+func (c *Receiver) Enabled() bool {
+	return enabled(c.EnabledIf)
+}
+
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
 func (c *Receiver) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type plain Receiver
@@ -475,9 +1075,57 @@ func (c *Receiver) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if c.Name == "" {
 		return fmt.Errorf("missing name in receiver")
 	}
+	if c.AlignInterval < 0 || (c.AlignInterval > 0 && (24*time.Hour)%time.Duration(c.AlignInterval) != 0) {
+		return fmt.Errorf("align_interval of receiver %q must evenly divide 24h", c.Name)
+	}
+	if c.AlignBypassSeverity != "" {
+		if c.AlignInterval == 0 {
+			return fmt.Errorf("align_bypass_severity of receiver %q requires align_interval", c.Name)
+		}
+		if _, err := types.ParseSeverity(c.AlignBypassSeverity); err != nil {
+			return fmt.Errorf("invalid align_bypass_severity of receiver %q: %s", c.Name, err)
+		}
+	}
+	if err := checkEnabledIf(c.EnabledIf); err != nil {
+		return err
+	}
 	return checkOverflow(c.XXX, "receiver config")
 }
 
+// LabelNames is a list of label names. Unlike model.LabelNames, it accepts
+// all names allowed by types.IsValidLabelName.
+type LabelNames []model.LabelName
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (ln *LabelNames) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var names []string
+	if err := unmarshal(&names); err != nil {
+		return err
+	}
+	return ln.set(names)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (ln *LabelNames) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	return ln.set(names)
+}
+
+func (ln *LabelNames) set(names []string) error {
+	res := make(LabelNames, 0, len(names))
+	for _, n := range names {
+		if !types.IsValidLabelName(model.LabelName(n)) {
+			return fmt.Errorf("%q is not a valid label name", n)
+		}
+		res = append(res, model.LabelName(n))
+	}
+	*ln = res
+	return nil
+}
+
 // Regexp encapsulates a regexp.Regexp and makes it YAML marshalable.
 type Regexp struct {
 	*regexp.Regexp
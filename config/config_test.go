@@ -15,6 +15,7 @@ package config
 
 import (
 	"encoding/json"
+	"os"
 	"reflect"
 	"regexp"
 	"strings"
@@ -24,6 +25,8 @@ import (
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/types"
 )
 
 func TestLoadEmptyString(t *testing.T) {
@@ -380,3 +383,605 @@ func TestVictorOpsNoAPIKey(t *testing.T) {
 		t.Errorf("Expected: %s\nGot: %s", "no global VictorOps API Key set", err.Error())
 	}
 }
+
+func TestMetadata(t *testing.T) {
+	c, _, err := LoadFile("testdata/conf.good.yml")
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", "testdata/conf.good.yml", err)
+	}
+
+	exp := &Metadata{
+		Owner:       "team-X",
+		Link:        "https://tickets.example.org/TEAMX-1",
+		Description: "Mails for team X.",
+	}
+	if !reflect.DeepEqual(c.Receivers[0].Metadata, exp) {
+		t.Errorf("Invalid receiver metadata: %v\nExpected: %v", c.Receivers[0].Metadata, exp)
+	}
+	if c.Route.Metadata != nil {
+		t.Errorf("Unexpected root route metadata: %v", c.Route.Metadata)
+	}
+	if c.Route.Routes[0].Metadata == nil || c.Route.Routes[0].Metadata.Owner != "team-X" {
+		t.Errorf("Invalid route metadata: %v", c.Route.Routes[0].Metadata)
+	}
+}
+
+func TestMetadataUnknownField(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  metadata:
+    team: X
+`
+	_, err := Load(in)
+	if err == nil || err.Error() != "unknown fields in metadata: team" {
+		t.Errorf("Expected unknown field error, got: %v", err)
+	}
+}
+
+func TestSeverityThemes(t *testing.T) {
+	c, _, err := LoadFile("testdata/conf.good.yml")
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", "testdata/conf.good.yml", err)
+	}
+
+	exp := map[string]*SeverityTheme{
+		"critical": {Color: "#E6522C", Emoji: ":fire:", Priority: "2"},
+	}
+	if !reflect.DeepEqual(c.Global.SeverityThemes, exp) {
+		t.Errorf("Invalid severity themes: %v\nExpected: %v", c.Global.SeverityThemes, exp)
+	}
+}
+
+func TestPrometheusQuery(t *testing.T) {
+	c, _, err := LoadFile("testdata/conf.good.yml")
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", "testdata/conf.good.yml", err)
+	}
+
+	exp := &PrometheusQueryConfig{
+		URL:        "http://prometheus.example.org:9090",
+		Timeout:    model.Duration(10 * time.Second),
+		MaxSamples: DefaultPrometheusQueryConfig.MaxSamples,
+	}
+	if !reflect.DeepEqual(c.Global.PrometheusQuery, exp) {
+		t.Errorf("Invalid prometheus_query config: %v\nExpected: %v", c.Global.PrometheusQuery, exp)
+	}
+
+	in := `
+global:
+  prometheus_query:
+    url: 'prometheus:9090'
+route:
+  receiver: team-X
+receivers:
+- name: 'team-X'
+`
+	_, err = Load(in)
+	if err == nil || err.Error() != `invalid url "prometheus:9090" in prometheus_query config` {
+		t.Errorf("Expected invalid url error, got: %v", err)
+	}
+}
+
+func TestSummaryThreshold(t *testing.T) {
+	c, _, err := LoadFile("testdata/conf.good.yml")
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", "testdata/conf.good.yml", err)
+	}
+	if c.Global.SummaryThreshold != 20 {
+		t.Errorf("Expected summary threshold 20, got %d", c.Global.SummaryThreshold)
+	}
+
+	in := `
+global:
+  summary_threshold: -1
+route:
+  receiver: team-X
+receivers:
+- name: 'team-X'
+`
+	_, err = Load(in)
+	if err == nil || err.Error() != "summary_threshold must not be negative" {
+		t.Errorf("Expected negative threshold error, got: %v", err)
+	}
+}
+
+func TestDeprecations(t *testing.T) {
+	c, _, err := LoadFile("testdata/conf.good.yml")
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", "testdata/conf.good.yml", err)
+	}
+
+	exp := []Deprecation{
+		{Setting: "hipchat_url", Path: "global.hipchat_url", Message: hipchatDeprecation},
+		{Setting: "hipchat_auth_token", Path: "global.hipchat_auth_token", Message: hipchatDeprecation},
+		{Setting: "hipchat_configs", Path: "receivers[team-X-hipchat].hipchat_configs", Message: hipchatDeprecation},
+	}
+	if res := c.Deprecations(); !reflect.DeepEqual(res, exp) {
+		t.Errorf("Unexpected deprecations: %v\nExpected: %v", res, exp)
+	}
+
+	c, err = Load(`
+route:
+  receiver: team-X
+receivers:
+- name: 'team-X'
+`)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if res := c.Deprecations(); len(res) != 0 {
+		t.Errorf("Expected no deprecations, got: %v", res)
+	}
+}
+
+func TestEscalationReceiverExists(t *testing.T) {
+	in := `
+route:
+    receiver: team-X
+    escalate_after: 2
+    escalation_receiver: team-Y
+
+receivers:
+- name: 'team-X'
+`
+	_, err := Load(in)
+
+	expected := "undefined escalation receiver \"team-Y\" used in route"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%q", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, err.Error())
+	}
+}
+
+func TestChangeDetection(t *testing.T) {
+	c, err := Load(`
+route:
+  receiver: team-X
+  change_detection: group_size
+  change_thresholds: [10, 50]
+receivers:
+- name: 'team-X'
+`)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if !reflect.DeepEqual(c.Route.ChangeThresholds, []int{10, 50}) {
+		t.Errorf("Unexpected change thresholds %v", c.Route.ChangeThresholds)
+	}
+
+	for _, tc := range []struct {
+		route    string
+		expected string
+	}{
+		{
+			route:    "{change_detection: size}",
+			expected: `unknown change_detection "size"`,
+		}, {
+			route:    "{change_detection: group_size}",
+			expected: "missing change_thresholds for the group_size change detection",
+		}, {
+			route:    "{change_thresholds: [10]}",
+			expected: "change_thresholds require the group_size change detection",
+		}, {
+			route:    "{change_detection: group_size, change_thresholds: [10, 10]}",
+			expected: "change_thresholds must be positive and increasing",
+		}, {
+			route:    "{change_annotations: [run-book]}",
+			expected: `invalid annotation name "run-book" in change_annotations`,
+		}, {
+			route:    "{change_annotations: [summary, summary]}",
+			expected: `duplicated annotation "summary" in change_annotations`,
+		},
+	} {
+		_, err := Load("route: " + tc.route + "\nreceivers:\n- name: team-X\n")
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%q", tc.expected)
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("\nexpected:\n%q\ngot:\n%q", tc.expected, err.Error())
+		}
+	}
+}
+
+func TestSeverityMapping(t *testing.T) {
+	c, err := Load(`
+global:
+  severity_mapping:
+    p1: critical
+    p2: Error
+route:
+  receiver: team-X
+  routes:
+  - min_severity: error
+    receiver: team-X
+receivers:
+- name: 'team-X'
+`)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	exp := types.SeverityMap{"p1": types.SeverityCritical, "p2": types.SeverityError}
+	if !reflect.DeepEqual(c.Global.Severities(), exp) {
+		t.Errorf("Invalid severities: %v\nExpected: %v", c.Global.Severities(), exp)
+	}
+	if !reflect.DeepEqual(c.Route.Routes[0].Severities, exp) {
+		t.Errorf("Severities not set on route, got: %v", c.Route.Routes[0].Severities)
+	}
+
+	_, err = Load(`
+global:
+  severity_mapping:
+    p1: urgent
+route:
+  receiver: team-X
+receivers:
+- name: 'team-X'
+`)
+	expected := `invalid severity mapping for "p1": unknown severity "urgent"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%v", expected, err)
+	}
+}
+
+func TestReceiverAlignInterval(t *testing.T) {
+	for _, c := range []struct {
+		in  string
+		err string
+	}{
+		{in: `{name: a, align_interval: 30m, align_bypass_severity: error}`},
+		{in: `{name: a, align_interval: 7m}`, err: `align_interval of receiver "a" must evenly divide 24h`},
+		{in: `{name: a, align_bypass_severity: error}`, err: `align_bypass_severity of receiver "a" requires align_interval`},
+		{in: `{name: a, align_interval: 1h, align_bypass_severity: urgent}`, err: `invalid align_bypass_severity of receiver "a": unknown severity "urgent"`},
+	} {
+		var rc Receiver
+		err := yaml.Unmarshal([]byte(c.in), &rc)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %s: %s", c.in, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error:\n%v\ngot:\n%v", c.err, err)
+		}
+	}
+}
+
+func TestEnabledIf(t *testing.T) {
+	os.Setenv("AM_TEST_PRODUCTION", "true")
+	os.Setenv("AM_TEST_STAGING", "false")
+	defer os.Unsetenv("AM_TEST_PRODUCTION")
+	defer os.Unsetenv("AM_TEST_STAGING")
+
+	in := `
+route:
+  receiver: team
+  routes:
+  - receiver: pager
+    enabled_if: AM_TEST_PRODUCTION
+  - receiver: staging
+    enabled_if: AM_TEST_STAGING
+    routes:
+    - receiver: pager
+
+receivers:
+- name: team
+  enabled_if: AM_TEST_PRODUCTION
+- name: team
+  enabled_if: "!AM_TEST_PRODUCTION"
+- name: pager
+- name: staging
+  enabled_if: AM_TEST_UNSET
+`
+	cfg, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if len(cfg.Receivers) != 2 || cfg.Receivers[0].Name != "team" || cfg.Receivers[0].EnabledIf != "AM_TEST_PRODUCTION" || cfg.Receivers[1].Name != "pager" {
+		t.Errorf("Unexpected receivers %v", cfg.Receivers)
+	}
+	if len(cfg.Route.Routes) != 1 || cfg.Route.Routes[0].Receiver != "pager" {
+		t.Errorf("Unexpected routes %v", cfg.Route.Routes)
+	}
+
+	for _, c := range []struct {
+		in  string
+		err string
+	}{
+		{
+			in:  "route: {receiver: a, enabled_if: AM_TEST_PRODUCTION}\nreceivers: [{name: a}]",
+			err: "root route must not have enabled_if",
+		},
+		{
+			in:  "route: {receiver: a, routes: [{receiver: b}]}\nreceivers: [{name: a}, {name: b, enabled_if: AM_TEST_STAGING}]",
+			err: `disabled receiver "b" used in route`,
+		},
+		{
+			in:  "route: {receiver: a}\nreceivers: [{name: a, enabled_if: 'AM-TEST'}]",
+			err: `invalid enabled_if "AM-TEST", must be an environment variable name optionally prefixed with !`,
+		},
+	} {
+		_, err := Load(c.in)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error:\n%v\ngot:\n%v", c.err, err)
+		}
+	}
+}
+
+func TestUTF8LabelNames(t *testing.T) {
+	defer func() { types.UTF8LabelNames = false }()
+
+	in := `
+route:
+  receiver: team
+  group_by: [service.name]
+  routes:
+  - receiver: team
+    match:
+      service.name: api
+inhibit_rules:
+- source_match:
+    service.name: api
+  target_match_re:
+    service.name: web.*
+  equal: [service.namespace]
+receivers:
+- name: team
+`
+	if _, err := Load(in); err == nil {
+		t.Fatalf("expected error for UTF-8 label names")
+	}
+
+	types.UTF8LabelNames = true
+
+	cfg, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if !reflect.DeepEqual(cfg.Route.GroupBy, LabelNames{"service.name"}) {
+		t.Errorf("unexpected group_by %v", cfg.Route.GroupBy)
+	}
+	if !reflect.DeepEqual(cfg.InhibitRules[0].Equal, LabelNames{"service.namespace"}) {
+		t.Errorf("unexpected equal %v", cfg.InhibitRules[0].Equal)
+	}
+
+	// The configuration survives a JSON round trip.
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Error marshaling config: %s", err)
+	}
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		t.Fatalf("Error unmarshaling config: %s", err)
+	}
+	if !reflect.DeepEqual(c.Route.GroupBy, cfg.Route.GroupBy) {
+		t.Errorf("unexpected group_by after round trip %v", c.Route.GroupBy)
+	}
+}
+
+func TestAutoResolveRules(t *testing.T) {
+	in := `
+route:
+  receiver: team
+receivers:
+- name: team
+auto_resolve_rules:
+- match: {source: events}
+  timeout: 2m
+- match: {alertname: JobFailed}
+  resolution_match: {alertname: JobSucceeded}
+  equal: [job]
+`
+	cfg, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if len(cfg.AutoResolveRules) != 2 {
+		t.Fatalf("Unexpected auto-resolve rules %v", cfg.AutoResolveRules)
+	}
+	if r := cfg.AutoResolveRules[0]; time.Duration(r.Timeout) != 2*time.Minute {
+		t.Errorf("Unexpected timeout %s", r.Timeout)
+	}
+	if r := cfg.AutoResolveRules[1]; r.ResolutionMatch["alertname"] != "JobSucceeded" || len(r.Equal) != 1 || r.Equal[0] != "job" {
+		t.Errorf("Unexpected resolution rule %v", r)
+	}
+
+	for _, c := range []struct {
+		rule string
+		err  string
+	}{
+		{
+			rule: "{timeout: 1m}",
+			err:  "auto-resolve rule must have matchers",
+		},
+		{
+			rule: "{match: {a: b}}",
+			err:  "auto-resolve rule must have a timeout or resolution matchers",
+		},
+		{
+			rule: "{match: {a: b}, timeout: 1m, equal: [c]}",
+			err:  "auto-resolve rule must have resolution matchers to use equal",
+		},
+		{
+			rule: "{match: {a: b}, timeout: 5m}",
+			err:  "auto-resolve timeout 5m must be shorter than the resolve timeout 5m",
+		},
+	} {
+		_, err := Load("route: {receiver: a}\nreceivers: [{name: a}]\nauto_resolve_rules: [" + c.rule + "]")
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error:\n%v\ngot:\n%v", c.err, err)
+		}
+	}
+}
+
+func TestTemplateRefs(t *testing.T) {
+	in := `
+route:
+  receiver: team
+receivers:
+- name: team
+  slack_configs:
+  - api_url: http://localhost/
+    title_template: team.title
+  pushover_configs:
+  - user_key: key
+    token: token
+    message_template: team.message
+`
+	cfg, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	exp := []TemplateRef{
+		{Path: "receivers[team].slack_configs[0].title_template", Name: "team.title"},
+		{Path: "receivers[team].pushover_configs[0].message_template", Name: "team.message"},
+	}
+	if refs := cfg.TemplateRefs(); !reflect.DeepEqual(refs, exp) {
+		t.Errorf("expected template references:\n%v\ngot:\n%v", exp, refs)
+	}
+}
+
+func TestAlertEnrichment(t *testing.T) {
+	cfg, err := Load("route: {receiver: team}\nreceivers: [{name: team}]\nalert_enrichment: {sources: [{name: triage, token: s3cr3t}]}")
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if ae := cfg.AlertEnrichment; ae.MaxSize != 4096 || len(ae.Sources) != 1 || ae.Sources[0].Token != "s3cr3t" {
+		t.Errorf("Unexpected alert enrichment %v", ae)
+	}
+	if strings.Contains(cfg.String(), "s3cr3t") {
+		t.Errorf("Token revealed in config:\n%s", cfg)
+	}
+
+	for _, c := range []struct {
+		enrichment string
+		err        string
+	}{
+		{
+			enrichment: "{sources: []}",
+			err:        "alert enrichment must have at least one source",
+		},
+		{
+			enrichment: "{max_size: 0, sources: [{name: a, token: b}]}",
+			err:        "alert enrichment max_size must be positive",
+		},
+		{
+			enrichment: "{sources: [{name: a}]}",
+			err:        `missing token in alert enrichment source "a"`,
+		},
+		{
+			enrichment: "{sources: [{name: a, token: b}, {name: a, token: c}]}",
+			err:        `alert enrichment source name "a" is not unique`,
+		},
+		{
+			enrichment: "{sources: [{name: a, token: b}, {name: c, token: b}]}",
+			err:        `token of alert enrichment source "c" is not unique`,
+		},
+	} {
+		_, err := Load("route: {receiver: a}\nreceivers: [{name: a}]\nalert_enrichment: " + c.enrichment)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error:\n%v\ngot:\n%v", c.err, err)
+		}
+	}
+}
+
+func TestSilencePolicy(t *testing.T) {
+	cfg, err := Load("route: {receiver: team}\nreceivers: [{name: team}]\nsilence_policy: {max_duration: 7d}")
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if sp := cfg.SilencePolicy; time.Duration(sp.MaxDuration) != 7*24*time.Hour || sp.Action != SilencePolicyReject {
+		t.Errorf("Unexpected silence policy %v", sp)
+	}
+
+	for _, c := range []struct {
+		policy string
+		err    string
+	}{
+		{
+			policy: "{max_duration: 7d, action: truncate}",
+			err:    `unknown silence policy action "truncate"`,
+		},
+		{
+			policy: "{max_duration: 7d, maximum: 1d}",
+			err:    "unknown fields in silence policy: maximum",
+		},
+	} {
+		_, err := Load("route: {receiver: a}\nreceivers: [{name: a}]\nsilence_policy: " + c.policy)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error:\n%v\ngot:\n%v", c.err, err)
+		}
+	}
+}
+
+func TestDecisionLog(t *testing.T) {
+	cfg, err := Load("route: {receiver: team}\nreceivers: [{name: team}]\ndecision_log: {url: 'http://audit.example.com/decisions'}")
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if dl := cfg.DecisionLog; dl.URL != "http://audit.example.com/decisions" || time.Duration(dl.Timeout) != 10*time.Second {
+		t.Errorf("Unexpected decision log %v", dl)
+	}
+
+	for _, c := range []struct {
+		decisionLog string
+		err         string
+	}{
+		{
+			decisionLog: "{timeout: 1s}",
+			err:         "missing url in decision_log config",
+		},
+		{
+			decisionLog: "{url: 'audit.example.com'}",
+			err:         `invalid url "audit.example.com" in decision_log config`,
+		},
+		{
+			decisionLog: "{url: 'http://audit.example.com', timeout: 0s}",
+			err:         "timeout in decision_log config must be positive",
+		},
+	} {
+		_, err := Load("route: {receiver: a}\nreceivers: [{name: a}]\ndecision_log: " + c.decisionLog)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error:\n%v\ngot:\n%v", c.err, err)
+		}
+	}
+}
+
+func TestSilenceExpiry(t *testing.T) {
+	cfg, err := Load("route: {receiver: team}\nreceivers: [{name: team}]\nsilence_expiry: {receiver: team}")
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	if se := cfg.SilenceExpiry; se.Receiver != "team" || time.Duration(se.NotifyBefore) != time.Hour {
+		t.Errorf("Unexpected silence expiry %v", se)
+	}
+
+	for _, c := range []struct {
+		expiry string
+		err    string
+	}{
+		{
+			expiry: "{notify_before: 1h}",
+			err:    "silence expiry notifications must have a receiver",
+		},
+		{
+			expiry: "{receiver: a, notify_before: 0s}",
+			err:    "silence expiry notify_before must be positive",
+		},
+		{
+			expiry: "{receiver: b}",
+			err:    `undefined receiver "b" used for silence expiry notifications`,
+		},
+	} {
+		_, err := Load("route: {receiver: a}\nreceivers: [{name: a}]\nsilence_expiry: " + c.expiry)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("expected error:\n%v\ngot:\n%v", c.err, err)
+		}
+	}
+}
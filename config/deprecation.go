@@ -0,0 +1,61 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "fmt"
+
+// Deprecation describes a use of a deprecated configuration setting.
+type Deprecation struct {
+	// Setting identifies the deprecated setting independent of where it
+	// is used, e.g. "hipchat_configs".
+	Setting string `json:"setting"`
+	// Path is the location of the setting in the configuration.
+	Path string `json:"path"`
+	// Message describes how to migrate away from the setting.
+	Message string `json:"message"`
+}
+
+const hipchatDeprecation = "HipChat has been discontinued by Atlassian, use another integration instead"
+
+// Deprecations returns all uses of deprecated settings in the configuration.
+func (c *Config) Deprecations() []Deprecation {
+	res := []Deprecation{}
+
+	if c.Global != nil {
+		if c.Global.HipchatURL != DefaultGlobalConfig.HipchatURL {
+			res = append(res, Deprecation{
+				Setting: "hipchat_url",
+				Path:    "global.hipchat_url",
+				Message: hipchatDeprecation,
+			})
+		}
+		if c.Global.HipchatAuthToken != "" {
+			res = append(res, Deprecation{
+				Setting: "hipchat_auth_token",
+				Path:    "global.hipchat_auth_token",
+				Message: hipchatDeprecation,
+			})
+		}
+	}
+	for _, rcv := range c.Receivers {
+		if len(rcv.HipchatConfigs) > 0 {
+			res = append(res, Deprecation{
+				Setting: "hipchat_configs",
+				Path:    fmt.Sprintf("receivers[%s].hipchat_configs", rcv.Name),
+				Message: hipchatDeprecation,
+			})
+		}
+	}
+	return res
+}
@@ -17,6 +17,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/prometheus/common/model"
 )
 
 var (
@@ -25,6 +27,15 @@ var (
 		NotifierConfig: NotifierConfig{
 			VSendResolved: true,
 		},
+		PayloadVersion: WebhookPayloadV1,
+	}
+
+	// DefaultFileConfig defines default values for File configurations.
+	DefaultFileConfig = FileConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Filename: `{{ .Receiver }}.json`,
 	}
 
 	// DefaultEmailConfig defines default values for Email configurations.
@@ -52,6 +63,7 @@ var (
 			"resolved":     `{{ template "pagerduty.default.instances" .Alerts.Resolved }}`,
 			"num_firing":   `{{ .Alerts.Firing | len }}`,
 			"num_resolved": `{{ .Alerts.Resolved | len }}`,
+			"severity":     `{{ .Severity }}`,
 		},
 	}
 
@@ -60,7 +72,7 @@ var (
 		NotifierConfig: NotifierConfig{
 			VSendResolved: false,
 		},
-		Color:     `{{ if eq .Status "firing" }}danger{{ else }}good{{ end }}`,
+		Color:     `{{ if eq .Status "firing" }}{{ with .Theme.Color }}{{ . }}{{ else }}danger{{ end }}{{ else }}good{{ end }}`,
 		Username:  `{{ template "slack.default.username" . }}`,
 		Title:     `{{ template "slack.default.title" . }}`,
 		TitleLink: `{{ template "slack.default.titlelink" . }}`,
@@ -91,6 +103,7 @@ var (
 		Message:     `{{ template "opsgenie.default.message" . }}`,
 		Description: `{{ template "opsgenie.default.description" . }}`,
 		Source:      `{{ template "opsgenie.default.source" . }}`,
+		Priority:    `{{ template "opsgenie.default.priority" . }}`,
 		// TODO: Add a details field with all the alerts.
 	}
 
@@ -105,6 +118,24 @@ var (
 		MonitoringTool:    `{{ template "victorops.default.monitoring_tool" . }}`,
 	}
 
+	// DefaultDingTalkConfig defines default values for DingTalk configurations.
+	DefaultDingTalkConfig = DingTalkConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Title:   `{{ template "dingtalk.default.title" . }}`,
+		Message: `{{ template "dingtalk.default.message" . }}`,
+	}
+
+	// DefaultLarkConfig defines default values for Lark configurations.
+	DefaultLarkConfig = LarkConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Title:   `{{ template "lark.default.title" . }}`,
+		Message: `{{ template "lark.default.message" . }}`,
+	}
+
 	// DefaultPushoverConfig defines default values for Pushover configurations.
 	DefaultPushoverConfig = PushoverConfig{
 		NotifierConfig: NotifierConfig{
@@ -113,9 +144,9 @@ var (
 		Title:    `{{ template "pushover.default.title" . }}`,
 		Message:  `{{ template "pushover.default.message" . }}`,
 		URL:      `{{ template "pushover.default.url" . }}`,
-		Priority: `{{ if eq .Status "firing" }}2{{ else }}0{{ end }}`, // emergency (firing) or normal
-		Retry:    duration(1 * time.Minute),
-		Expire:   duration(1 * time.Hour),
+		Priority: `{{ if eq .Status "firing" }}{{ with .Theme.Priority }}{{ . }}{{ else }}{{ template "pushover.default.priority" . }}{{ end }}{{ else }}0{{ end }}`, // by severity (firing) or normal
+		Retry:    `1m`,
+		Expire:   `1h`,
 	}
 )
 
@@ -146,6 +177,13 @@ type EmailConfig struct {
 	Text         string            `yaml:"text,omitempty" json:"text,omitempty"`
 	RequireTLS   *bool             `yaml:"require_tls,omitempty" json:"require_tls,omitempty"`
 
+	// Names of the templates used for the Subject header, the HTML and the
+	// text body instead of email.default.subject and email.default.html.
+	// They are mutually exclusive with the respective settings.
+	SubjectTemplate string `yaml:"subject_template,omitempty" json:"subject_template,omitempty"`
+	HTMLTemplate    string `yaml:"html_template,omitempty" json:"html_template,omitempty"`
+	TextTemplate    string `yaml:"text_template,omitempty" json:"text_template,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
@@ -171,6 +209,19 @@ func (c *EmailConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 	c.Headers = normalizedHeaders
 
+	if c.SubjectTemplate != "" {
+		ref := templateRef(c.SubjectTemplate)
+		if subject, ok := c.Headers["Subject"]; ok && subject != ref {
+			return fmt.Errorf("Subject header and subject_template are mutually exclusive in email config")
+		}
+		c.Headers["Subject"] = ref
+	}
+	if err := setTemplate(&c.HTML, DefaultEmailConfig.HTML, c.HTMLTemplate, "html", "email config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Text, DefaultEmailConfig.Text, c.TextTemplate, "text", "email config"); err != nil {
+		return err
+	}
 	return checkOverflow(c.XXX, "email config")
 }
 
@@ -185,6 +236,11 @@ type PagerdutyConfig struct {
 	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
 	Details     map[string]string `yaml:"details,omitempty" json:"details,omitempty"`
 
+	// Name of the template used for the description instead of
+	// pagerduty.default.description. It is mutually exclusive with
+	// description.
+	DescriptionTemplate string `yaml:"description_template,omitempty" json:"description_template,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
@@ -199,6 +255,9 @@ func (c *PagerdutyConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.ServiceKey == "" {
 		return fmt.Errorf("missing service key in PagerDuty config")
 	}
+	if err := setTemplate(&c.Description, DefaultPagerdutyConfig.Description, c.DescriptionTemplate, "description", "PagerDuty config"); err != nil {
+		return err
+	}
 	return checkOverflow(c.XXX, "pagerduty config")
 }
 
@@ -222,6 +281,14 @@ type SlackConfig struct {
 	IconURL   string `yaml:"icon_url,omitempty" json:"icon_url,omitempty"`
 	LinkNames bool   `yaml:"link_names,omitempty" json:"link_names,omitempty"`
 
+	// Names of the templates used for the title, text, pretext and fallback
+	// instead of the respective slack.default.* templates. They are mutually
+	// exclusive with the respective settings.
+	TitleTemplate    string `yaml:"title_template,omitempty" json:"title_template,omitempty"`
+	TextTemplate     string `yaml:"text_template,omitempty" json:"text_template,omitempty"`
+	PretextTemplate  string `yaml:"pretext_template,omitempty" json:"pretext_template,omitempty"`
+	FallbackTemplate string `yaml:"fallback_template,omitempty" json:"fallback_template,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
@@ -233,6 +300,18 @@ func (c *SlackConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal((*plain)(c)); err != nil {
 		return err
 	}
+	if err := setTemplate(&c.Title, DefaultSlackConfig.Title, c.TitleTemplate, "title", "slack config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Text, DefaultSlackConfig.Text, c.TextTemplate, "text", "slack config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Pretext, DefaultSlackConfig.Pretext, c.PretextTemplate, "pretext", "slack config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Fallback, DefaultSlackConfig.Fallback, c.FallbackTemplate, "fallback", "slack config"); err != nil {
+		return err
+	}
 	return checkOverflow(c.XXX, "slack config")
 }
 
@@ -249,6 +328,10 @@ type HipchatConfig struct {
 	MessageFormat string `yaml:"message_format,omitempty" json:"message_format,omitempty"`
 	Color         string `yaml:"color,omitempty" json:"color,omitempty"`
 
+	// Name of the template used for the message instead of
+	// hipchat.default.message. It is mutually exclusive with message.
+	MessageTemplate string `yaml:"message_template,omitempty" json:"message_template,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" ,json:"-"`
 }
@@ -263,10 +346,22 @@ func (c *HipchatConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if c.RoomID == "" {
 		return fmt.Errorf("missing room id in Hipchat config")
 	}
+	if err := setTemplate(&c.Message, DefaultHipchatConfig.Message, c.MessageTemplate, "message", "Hipchat config"); err != nil {
+		return err
+	}
 
 	return checkOverflow(c.XXX, "hipchat config")
 }
 
+// Webhook payload schema versions.
+const (
+	// WebhookPayloadV1 is the original payload.
+	WebhookPayloadV1 = "v1"
+	// WebhookPayloadV2 adds alert fingerprints and the number of alerts
+	// left out due to max_alerts.
+	WebhookPayloadV2 = "v2"
+)
+
 // WebhookConfig configures notifications via a generic webhook.
 type WebhookConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
@@ -274,6 +369,17 @@ type WebhookConfig struct {
 	// URL to send POST request to.
 	URL string `yaml:"url" json:"url"`
 
+	// PayloadVersion pins the schema of the JSON payload.
+	PayloadVersion string `yaml:"payload_version,omitempty" json:"payload_version,omitempty"`
+	// MaxAlerts limits the number of alerts sent in a payload. Zero means
+	// no limit. It requires payload v2, which reports the number of alerts
+	// left out.
+	MaxAlerts int `yaml:"max_alerts,omitempty" json:"max_alerts,omitempty"`
+	// MaxPayloadSize is the maximum size of a payload in bytes. Larger
+	// payloads are split into multiple requests carrying pagination
+	// metadata. Zero means no limit.
+	MaxPayloadSize int `yaml:"max_payload_size,omitempty" json:"max_payload_size,omitempty"`
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
@@ -288,9 +394,58 @@ func (c *WebhookConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if c.URL == "" {
 		return fmt.Errorf("missing URL in webhook config")
 	}
+	switch c.PayloadVersion {
+	case WebhookPayloadV1, WebhookPayloadV2:
+	default:
+		return fmt.Errorf("unknown payload_version %q in webhook config", c.PayloadVersion)
+	}
+	if c.MaxAlerts < 0 {
+		return fmt.Errorf("max_alerts must not be negative in webhook config")
+	}
+	if c.MaxAlerts > 0 && c.PayloadVersion != WebhookPayloadV2 {
+		return fmt.Errorf("max_alerts requires payload_version %q in webhook config", WebhookPayloadV2)
+	}
+	if c.MaxPayloadSize < 0 {
+		return fmt.Errorf("max_payload_size must not be negative in webhook config")
+	}
 	return checkOverflow(c.XXX, "webhook config")
 }
 
+// FileConfig configures notifications that are written as files to a
+// spool directory.
+type FileConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// Directory the notification files are written to.
+	Directory string `yaml:"directory" json:"directory"`
+	// Filename is a template for the name of notification files. Names are
+	// prefixed with the time the notification was written.
+	Filename string `yaml:"filename,omitempty" json:"filename,omitempty"`
+	// MaxFiles and MaxAge limit the files kept in the directory. The oldest
+	// files are removed after writing a notification.
+	MaxFiles int            `yaml:"max_files,omitempty" json:"max_files,omitempty"`
+	MaxAge   model.Duration `yaml:"max_age,omitempty" json:"max_age,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *FileConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultFileConfig
+	type plain FileConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Directory == "" {
+		return fmt.Errorf("missing directory in file config")
+	}
+	if c.MaxFiles < 0 {
+		return fmt.Errorf("max_files in file config must not be negative")
+	}
+	return checkOverflow(c.XXX, "file config")
+}
+
 // OpsGenieConfig configures notifications via OpsGenie.
 type OpsGenieConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
@@ -304,6 +459,13 @@ type OpsGenieConfig struct {
 	Teams       string            `yaml:"teams,omitempty" json:"teams,omitempty"`
 	Tags        string            `yaml:"tags,omitempty" json:"tags,omitempty"`
 	Note        string            `yaml:"note,omitempty" json:"note,omitempty"`
+	Priority    string            `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Names of the templates used for the message and description instead
+	// of opsgenie.default.message and opsgenie.default.description. They
+	// are mutually exclusive with the respective settings.
+	MessageTemplate     string `yaml:"message_template,omitempty" json:"message_template,omitempty"`
+	DescriptionTemplate string `yaml:"description_template,omitempty" json:"description_template,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
@@ -319,6 +481,12 @@ func (c *OpsGenieConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.APIKey == "" {
 		return fmt.Errorf("missing API key in OpsGenie config")
 	}
+	if err := setTemplate(&c.Message, DefaultOpsGenieConfig.Message, c.MessageTemplate, "message", "OpsGenie config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Description, DefaultOpsGenieConfig.Description, c.DescriptionTemplate, "description", "OpsGenie config"); err != nil {
+		return err
+	}
 	return checkOverflow(c.XXX, "opsgenie config")
 }
 
@@ -334,6 +502,12 @@ type VictorOpsConfig struct {
 	EntityDisplayName string `yaml:"entity_display_name" json:"entity_display_name"`
 	MonitoringTool    string `yaml:"monitoring_tool" json:"monitoring_tool"`
 
+	// Names of the templates used for the state message and entity display
+	// name instead of the respective victorops.default.* templates. They are
+	// mutually exclusive with the respective settings.
+	StateMessageTemplate      string `yaml:"state_message_template,omitempty" json:"state_message_template,omitempty"`
+	EntityDisplayNameTemplate string `yaml:"entity_display_name_template,omitempty" json:"entity_display_name_template,omitempty"`
+
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
 }
 
@@ -347,34 +521,132 @@ func (c *VictorOpsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.RoutingKey == "" {
 		return fmt.Errorf("missing Routing key in VictorOps config")
 	}
+	if err := setTemplate(&c.StateMessage, DefaultVictorOpsConfig.StateMessage, c.StateMessageTemplate, "state_message", "VictorOps config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.EntityDisplayName, DefaultVictorOpsConfig.EntityDisplayName, c.EntityDisplayNameTemplate, "entity_display_name", "VictorOps config"); err != nil {
+		return err
+	}
 	return checkOverflow(c.XXX, "victorops config")
 }
 
-type duration time.Duration
+// DingTalkConfig configures notifications via DingTalk group robots.
+type DingTalkConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// Webhook URL of the robot including its access token.
+	URL Secret `yaml:"url" json:"url"`
+	// Secret used to sign requests if the robot has signing enabled.
+	Secret  Secret `yaml:"secret,omitempty" json:"secret,omitempty"`
+	Title   string `yaml:"title,omitempty" json:"title,omitempty"`
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+	// Comma-separated mobile numbers of the users to @-mention.
+	Mentions   string `yaml:"mentions,omitempty" json:"mentions,omitempty"`
+	MentionAll bool   `yaml:"mention_all,omitempty" json:"mention_all,omitempty"`
+
+	// Names of the templates used for the title and message instead of
+	// dingtalk.default.title and dingtalk.default.message. They are mutually exclusive
+	// with the respective settings.
+	TitleTemplate   string `yaml:"title_template,omitempty" json:"title_template,omitempty"`
+	MessageTemplate string `yaml:"message_template,omitempty" json:"message_template,omitempty"`
 
-func (d *duration) UnmarshalText(text []byte) error {
-	parsed, err := time.ParseDuration(string(text))
-	if err == nil {
-		*d = duration(parsed)
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DingTalkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultDingTalkConfig
+	type plain DingTalkConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
 	}
-	return err
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in DingTalk config")
+	}
+	if err := setTemplate(&c.Title, DefaultDingTalkConfig.Title, c.TitleTemplate, "title", "DingTalk config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Message, DefaultDingTalkConfig.Message, c.MessageTemplate, "message", "DingTalk config"); err != nil {
+		return err
+	}
+	return checkOverflow(c.XXX, "dingtalk config")
 }
 
-func (d duration) MarshalText() ([]byte, error) {
-	return []byte(time.Duration(d).String()), nil
+// LarkConfig configures notifications via Lark (Feishu) group robots.
+type LarkConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// Webhook URL of the robot.
+	URL Secret `yaml:"url" json:"url"`
+	// Secret used to sign requests if the robot has signing enabled.
+	Secret  Secret `yaml:"secret,omitempty" json:"secret,omitempty"`
+	Title   string `yaml:"title,omitempty" json:"title,omitempty"`
+	Message string `yaml:"message,omitempty" json:"message,omitempty"`
+	// Comma-separated open IDs of the users to @-mention.
+	Mentions   string `yaml:"mentions,omitempty" json:"mentions,omitempty"`
+	MentionAll bool   `yaml:"mention_all,omitempty" json:"mention_all,omitempty"`
+
+	// Names of the templates used for the title and message instead of
+	// lark.default.title and lark.default.message. They are mutually exclusive
+	// with the respective settings.
+	TitleTemplate   string `yaml:"title_template,omitempty" json:"title_template,omitempty"`
+	MessageTemplate string `yaml:"message_template,omitempty" json:"message_template,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline" json:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *LarkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultLarkConfig
+	type plain LarkConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in Lark config")
+	}
+	if err := setTemplate(&c.Title, DefaultLarkConfig.Title, c.TitleTemplate, "title", "Lark config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Message, DefaultLarkConfig.Message, c.MessageTemplate, "message", "Lark config"); err != nil {
+		return err
+	}
+	return checkOverflow(c.XXX, "lark config")
 }
 
+// Limits imposed by Pushover on the retry and expire parameters of
+// emergency priority notifications.
+const (
+	PushoverMinRetry  = 30 * time.Second
+	PushoverMaxExpire = 3 * time.Hour
+)
+
+// PushoverConfig configures notifications via Pushover.
 type PushoverConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	UserKey  Secret   `yaml:"user_key,omitempty" json:"user_key,omitempty"`
-	Token    Secret   `yaml:"token,omitempty" json:"token,omitempty"`
-	Title    string   `yaml:"title,omitempty" json:"title,omitempty"`
-	Message  string   `yaml:"message,omitempty" json:"message,omitempty"`
-	URL      string   `yaml:"url,omitempty" json:"url,omitempty"`
-	Priority string   `yaml:"priority,omitempty" json:"priority,omitempty"`
-	Retry    duration `yaml:"retry,omitempty" json:"retry,omitempty"`
-	Expire   duration `yaml:"expire,omitempty" json:"expire,omitempty"`
+	UserKey  Secret `yaml:"user_key,omitempty" json:"user_key,omitempty"`
+	Token    Secret `yaml:"token,omitempty" json:"token,omitempty"`
+	Title    string `yaml:"title,omitempty" json:"title,omitempty"`
+	Message  string `yaml:"message,omitempty" json:"message,omitempty"`
+	URL      string `yaml:"url,omitempty" json:"url,omitempty"`
+	URLTitle string `yaml:"url_title,omitempty" json:"url_title,omitempty"`
+	// Comma-separated names of the user's devices to notify. All devices
+	// are notified if empty.
+	Device   string `yaml:"device,omitempty" json:"device,omitempty"`
+	Priority string `yaml:"priority,omitempty" json:"priority,omitempty"`
+	// Retry and Expire are durations only used for emergency priority
+	// notifications. Both may be templated.
+	Retry  string `yaml:"retry,omitempty" json:"retry,omitempty"`
+	Expire string `yaml:"expire,omitempty" json:"expire,omitempty"`
+
+	// Names of the templates used for the title and message instead of
+	// pushover.default.title and pushover.default.message. They are mutually
+	// exclusive with the respective settings.
+	TitleTemplate   string `yaml:"title_template,omitempty" json:"title_template,omitempty"`
+	MessageTemplate string `yaml:"message_template,omitempty" json:"message_template,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline" json:"-"`
@@ -393,5 +665,30 @@ func (c *PushoverConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.Token == "" {
 		return fmt.Errorf("missing token in Pushover config")
 	}
+	if err := setTemplate(&c.Title, DefaultPushoverConfig.Title, c.TitleTemplate, "title", "Pushover config"); err != nil {
+		return err
+	}
+	if err := setTemplate(&c.Message, DefaultPushoverConfig.Message, c.MessageTemplate, "message", "Pushover config"); err != nil {
+		return err
+	}
+	// Templated durations can only be checked once they are expanded.
+	if !strings.Contains(c.Retry, "{{") {
+		d, err := time.ParseDuration(c.Retry)
+		if err != nil {
+			return fmt.Errorf("invalid retry in Pushover config: %s", err)
+		}
+		if d < PushoverMinRetry {
+			return fmt.Errorf("retry in Pushover config must be at least %s", PushoverMinRetry)
+		}
+	}
+	if !strings.Contains(c.Expire, "{{") {
+		d, err := time.ParseDuration(c.Expire)
+		if err != nil {
+			return fmt.Errorf("invalid expire in Pushover config: %s", err)
+		}
+		if d > PushoverMaxExpire {
+			return fmt.Errorf("expire in Pushover config must not exceed %s", PushoverMaxExpire)
+		}
+	}
 	return checkOverflow(c.XXX, "pushover config")
 }
@@ -93,6 +93,73 @@ url: ''
 	}
 }
 
+func TestWebhookPayloadVersion(t *testing.T) {
+	in := `
+url: 'http://example.com'
+payload_version: 'v3'
+`
+	var cfg WebhookConfig
+	err := yaml.Unmarshal([]byte(in), &cfg)
+
+	expected := `unknown payload_version "v3" in webhook config`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+
+	in = `
+url: 'http://example.com'
+`
+	cfg = WebhookConfig{}
+	if err := yaml.Unmarshal([]byte(in), &cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.PayloadVersion != WebhookPayloadV1 {
+		t.Errorf("expected default payload version %q, got %q", WebhookPayloadV1, cfg.PayloadVersion)
+	}
+
+	in = `
+url: 'http://example.com'
+max_alerts: 10
+`
+	cfg = WebhookConfig{}
+	err = yaml.Unmarshal([]byte(in), &cfg)
+	expected = `max_alerts requires payload_version "v2" in webhook config`
+	if err == nil || err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err)
+	}
+
+	in = `
+url: 'http://example.com'
+payload_version: 'v2'
+max_alerts: 10
+`
+	cfg = WebhookConfig{}
+	if err := yaml.Unmarshal([]byte(in), &cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFileDirectoryIsPresent(t *testing.T) {
+	in := `
+filename: 'alerts.json'
+`
+	var cfg FileConfig
+	err := yaml.Unmarshal([]byte(in), &cfg)
+
+	expected := "missing directory in file config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
 func TestOpsGenieAPIKeyIsPresent(t *testing.T) {
 	in := `
 api_key: ''
@@ -161,3 +228,140 @@ token: ''
 		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
 	}
 }
+
+func TestPushoverDurations(t *testing.T) {
+	in := `
+user_key: '<user_key>'
+token: '<token>'
+retry: 10s
+`
+	var cfg PushoverConfig
+	err := yaml.Unmarshal([]byte(in), &cfg)
+
+	expected := "retry in Pushover config must be at least 30s"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+
+	in = `
+user_key: '<user_key>'
+token: '<token>'
+retry: '{{ .CommonLabels.retry }}'
+expire: 2h
+`
+	if err := yaml.Unmarshal([]byte(in), &cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestDingTalkURLIsPresent(t *testing.T) {
+	in := `
+secret: 'SECabc'
+`
+	var cfg DingTalkConfig
+	err := yaml.Unmarshal([]byte(in), &cfg)
+
+	expected := "missing URL in DingTalk config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestLarkURLIsPresent(t *testing.T) {
+	in := `
+secret: 'abc'
+`
+	var cfg LarkConfig
+	err := yaml.Unmarshal([]byte(in), &cfg)
+
+	expected := "missing URL in Lark config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestNamedTemplates(t *testing.T) {
+	in := `
+api_url: 'http://localhost/'
+title_template: 'custom.title'
+text_template: 'custom.text'
+`
+	var cfg SlackConfig
+	if err := yaml.Unmarshal([]byte(in), &cfg); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if exp := `{{ template "custom.title" . }}`; cfg.Title != exp {
+		t.Errorf("Expected title %q, got %q", exp, cfg.Title)
+	}
+	if exp := `{{ template "custom.text" . }}`; cfg.Text != exp {
+		t.Errorf("Expected text %q, got %q", exp, cfg.Text)
+	}
+	if cfg.Pretext != DefaultSlackConfig.Pretext {
+		t.Errorf("Expected default pretext, got %q", cfg.Pretext)
+	}
+
+	// Marshaled configs must load again.
+	out, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg2 SlackConfig
+	if err := yaml.Unmarshal(out, &cfg2); err != nil {
+		t.Fatalf("Unexpected error loading marshaled config: %s", err)
+	}
+
+	in = `
+api_url: 'http://localhost/'
+title: 'Title'
+title_template: 'custom.title'
+`
+	err = yaml.Unmarshal([]byte(in), &cfg)
+	expected := "title and title_template are mutually exclusive in slack config"
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestEmailSubjectTemplate(t *testing.T) {
+	in := `
+to: 'to@email.com'
+subject_template: 'custom.subject'
+`
+	var cfg EmailConfig
+	if err := yaml.Unmarshal([]byte(in), &cfg); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if exp := `{{ template "custom.subject" . }}`; cfg.Headers["Subject"] != exp {
+		t.Errorf("Expected Subject header %q, got %q", exp, cfg.Headers["Subject"])
+	}
+
+	in = `
+to: 'to@email.com'
+subject_template: 'custom.subject'
+headers:
+  subject: 'Alert'
+`
+	err := yaml.Unmarshal([]byte(in), &cfg)
+	expected := "Subject header and subject_template are mutually exclusive in email config"
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "fmt"
+
+// TemplateRef is a reference to a named template by a *_template setting
+// of an integration.
+type TemplateRef struct {
+	// Path is the location of the setting in the configuration.
+	Path string `json:"path"`
+	// Name is the name of the referenced template.
+	Name string `json:"name"`
+}
+
+// TemplateRefs returns all references to named templates in the
+// configuration. The templates must be defined by the template files of
+// the configuration or the default templates.
+func (c *Config) TemplateRefs() []TemplateRef {
+	res := []TemplateRef{}
+
+	add := func(rcv *Receiver, integration string, i int, setting, name string) {
+		if name == "" {
+			return
+		}
+		res = append(res, TemplateRef{
+			Path: fmt.Sprintf("receivers[%s].%s[%d].%s", rcv.Name, integration, i, setting),
+			Name: name,
+		})
+	}
+	for _, rcv := range c.Receivers {
+		for i, ec := range rcv.EmailConfigs {
+			add(rcv, "email_configs", i, "subject_template", ec.SubjectTemplate)
+			add(rcv, "email_configs", i, "html_template", ec.HTMLTemplate)
+			add(rcv, "email_configs", i, "text_template", ec.TextTemplate)
+		}
+		for i, pc := range rcv.PagerdutyConfigs {
+			add(rcv, "pagerduty_configs", i, "description_template", pc.DescriptionTemplate)
+		}
+		for i, hc := range rcv.HipchatConfigs {
+			add(rcv, "hipchat_configs", i, "message_template", hc.MessageTemplate)
+		}
+		for i, sc := range rcv.SlackConfigs {
+			add(rcv, "slack_configs", i, "title_template", sc.TitleTemplate)
+			add(rcv, "slack_configs", i, "text_template", sc.TextTemplate)
+			add(rcv, "slack_configs", i, "pretext_template", sc.PretextTemplate)
+			add(rcv, "slack_configs", i, "fallback_template", sc.FallbackTemplate)
+		}
+		for i, oc := range rcv.OpsGenieConfigs {
+			add(rcv, "opsgenie_configs", i, "message_template", oc.MessageTemplate)
+			add(rcv, "opsgenie_configs", i, "description_template", oc.DescriptionTemplate)
+		}
+		for i, pc := range rcv.PushoverConfigs {
+			add(rcv, "pushover_configs", i, "title_template", pc.TitleTemplate)
+			add(rcv, "pushover_configs", i, "message_template", pc.MessageTemplate)
+		}
+		for i, vc := range rcv.VictorOpsConfigs {
+			add(rcv, "victorops_configs", i, "state_message_template", vc.StateMessageTemplate)
+			add(rcv, "victorops_configs", i, "entity_display_name_template", vc.EntityDisplayNameTemplate)
+		}
+		for i, dc := range rcv.DingTalkConfigs {
+			add(rcv, "dingtalk_configs", i, "title_template", dc.TitleTemplate)
+			add(rcv, "dingtalk_configs", i, "message_template", dc.MessageTemplate)
+		}
+		for i, lc := range rcv.LarkConfigs {
+			add(rcv, "lark_configs", i, "title_template", lc.TitleTemplate)
+			add(rcv, "lark_configs", i, "message_template", lc.MessageTemplate)
+		}
+	}
+	return res
+}
+
+// templateRef returns the template text executing the named template.
+func templateRef(name string) string {
+	return fmt.Sprintf(`{{ template %q . }}`, name)
+}
+
+// setTemplate makes the templated setting execute the named template if a
+// name is given. The setting itself must not be changed from its default
+// value then.
+func setTemplate(value *string, def, name, setting, kind string) error {
+	if name == "" {
+		return nil
+	}
+	ref := templateRef(name)
+	if *value != def && *value != ref {
+		return fmt.Errorf("%s and %s_template are mutually exclusive in %s", setting, setting, kind)
+	}
+	*value = ref
+	return nil
+}
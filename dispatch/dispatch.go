@@ -34,6 +34,11 @@ type Dispatcher struct {
 	ctx    context.Context
 	cancel func()
 
+	// drainc is closed once the dispatcher drains. Flushes of aggregation
+	// groups hold a read lock of flushMtx while they are in progress.
+	drainc   chan struct{}
+	flushMtx sync.RWMutex
+
 	logger log.Logger
 }
 
@@ -59,16 +64,16 @@ func NewDispatcher(
 
 // Run starts dispatching alerts incoming via the updates channel.
 func (d *Dispatcher) Run() {
-	d.done = make(chan struct{})
-
 	d.mtx.Lock()
+	d.done = make(chan struct{})
 	d.aggrGroups = map[*Route]map[model.Fingerprint]*aggrGroup{}
-	d.mtx.Unlock()
-
 	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.drainc = make(chan struct{})
+	done := d.done
+	d.mtx.Unlock()
 
 	d.run(d.alerts.Subscribe())
-	close(d.done)
+	close(done)
 }
 
 // AlertBlock contains a list of alerts associated with a set of
@@ -84,7 +89,11 @@ type APIAlert struct {
 	*model.Alert
 	Status      types.AlertStatus `json:"status"`
 	Receivers   []string          `json:"receivers"`
+	Routes      []*MatchedRoute   `json:"routes,omitempty"`
 	Fingerprint string            `json:"fingerprint"`
+	// Enrichment holds the annotations attached through the API and
+	// their sources.
+	Enrichment *types.Enrichment `json:"enrichment,omitempty"`
 }
 
 // AlertGroup is a list of alert blocks grouped by the same label set.
@@ -131,6 +140,7 @@ func (d *Dispatcher) Groups(matchers []*labels.Matcher) AlertOverview {
 			}
 
 			now := time.Now()
+			matched := route.Matched()
 
 			var apiAlerts []*APIAlert
 			for _, a := range types.Alerts(ag.alertSlice()...) {
@@ -141,6 +151,8 @@ func (d *Dispatcher) Groups(matchers []*labels.Matcher) AlertOverview {
 				aa := &APIAlert{
 					Alert:       a,
 					Status:      status,
+					Receivers:   []string{matched.Receiver},
+					Routes:      []*MatchedRoute{matched},
 					Fingerprint: a.Fingerprint().String(),
 				}
 
@@ -219,13 +231,57 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 
 // Stop the dispatcher.
 func (d *Dispatcher) Stop() {
-	if d == nil || d.cancel == nil {
+	if d == nil {
+		return
+	}
+	d.mtx.Lock()
+	if d.cancel == nil {
+		d.mtx.Unlock()
 		return
 	}
 	d.cancel()
 	d.cancel = nil
+	done := d.done
+	d.mtx.Unlock()
 
-	<-d.done
+	<-done
+}
+
+// Drain stops aggregation groups from starting new flushes and waits up to
+// the timeout for flushes in progress to finish. It stops the dispatcher
+// afterwards and returns false if flushes had to be aborted.
+func (d *Dispatcher) Drain(timeout time.Duration) bool {
+	if d == nil {
+		return true
+	}
+	d.mtx.Lock()
+	if d.cancel == nil {
+		d.mtx.Unlock()
+		return true
+	}
+	select {
+	case <-d.drainc:
+	default:
+		close(d.drainc)
+	}
+	d.mtx.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		d.flushMtx.Lock()
+		d.flushMtx.Unlock()
+		close(drained)
+	}()
+
+	ok := true
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		ok = false
+	}
+	d.Stop()
+
+	return ok
 }
 
 // notifyFunc is a function that performs notifcation for the alert
@@ -236,13 +292,7 @@ type notifyFunc func(context.Context, ...*types.Alert) bool
 // processAlert determines in which aggregation group the alert falls
 // and inserts it.
 func (d *Dispatcher) processAlert(alert *types.Alert, route *Route) {
-	groupLabels := model.LabelSet{}
-
-	for ln, lv := range alert.Labels {
-		if _, ok := route.RouteOpts.GroupBy[ln]; ok {
-			groupLabels[ln] = lv
-		}
-	}
+	groupLabels := getGroupLabels(alert, route)
 
 	fp := groupLabels.Fingerprint()
 
@@ -258,10 +308,15 @@ func (d *Dispatcher) processAlert(alert *types.Alert, route *Route) {
 	ag, ok := group[fp]
 	if !ok {
 		ag = newAggrGroup(d.ctx, groupLabels, route, d.timeout, d.logger)
+		ag.drainc, ag.flushing = d.drainc, &d.flushMtx
+		ag.marker = d.marker
 		group[fp] = ag
 
 		go ag.run(func(ctx context.Context, alerts ...*types.Alert) bool {
 			_, _, err := d.stage.Exec(ctx, d.logger, alerts...)
+			if err == notify.ErrDeferred {
+				return false
+			}
 			if err != nil {
 				level.Error(d.logger).Log("msg", "Notify for alerts failed", "num_alerts", len(alerts), "err", err)
 			}
@@ -272,6 +327,18 @@ func (d *Dispatcher) processAlert(alert *types.Alert, route *Route) {
 	ag.insert(alert)
 }
 
+// getGroupLabels returns the labels of the alert the route groups by.
+func getGroupLabels(alert *types.Alert, route *Route) model.LabelSet {
+	groupLabels := model.LabelSet{}
+
+	for ln, lv := range alert.Labels {
+		if _, ok := route.RouteOpts.GroupBy[ln]; ok {
+			groupLabels[ln] = lv
+		}
+	}
+	return groupLabels
+}
+
 // aggrGroup aggregates alert fingerprints into groups to which a
 // common set of routing options applies.
 // It emits notifications in the specified intervals.
@@ -287,9 +354,30 @@ type aggrGroup struct {
 	next    *time.Timer
 	timeout func(time.Duration) time.Duration
 
+	// Set by the dispatcher to let it drain the group.
+	drainc   <-chan struct{}
+	flushing *sync.RWMutex
+	// Set by the dispatcher to skip muted alerts when escalating.
+	marker types.Marker
+
 	mtx     sync.RWMutex
 	alerts  map[model.Fingerprint]*types.Alert
 	hasSent bool
+
+	// The current group interval, which may be backed off from the
+	// configured one, and whether alerts changed since the last flush.
+	interval  time.Duration
+	changed   bool
+	lastFlush time.Time
+	// The time of the last flush expected to send a notification, from
+	// which repeat notifications are scheduled while backing off.
+	notifiedAt time.Time
+	// Whether the alerts were resolved at the last flush.
+	flushedResolved map[model.Fingerprint]bool
+	// The last time resolved alerts were successfully notified about.
+	lastResolvedFlush time.Time
+	// The time since which the group has been firing continuously.
+	firingSince time.Time
 }
 
 // newAggrGroup returns a new aggregation group.
@@ -303,6 +391,7 @@ func newAggrGroup(ctx context.Context, labels model.LabelSet, r *Route, to func(
 		opts:     &r.RouteOpts,
 		timeout:  to,
 		alerts:   map[model.Fingerprint]*types.Alert{},
+		interval: r.RouteOpts.GroupInterval,
 	}
 	ag.ctx, ag.cancel = context.WithCancel(ctx)
 
@@ -319,8 +408,12 @@ func (ag *aggrGroup) fingerprint() model.Fingerprint {
 	return ag.labels.Fingerprint()
 }
 
+// GroupKey returns the key identifying the group in the notification log.
+// It is built from the sorted matchers of the routes leading to the group's
+// route and the sorted group labels, so that reordering the matchers or
+// group_by entries of routes in the configuration does not change it.
 func (ag *aggrGroup) GroupKey() string {
-	return fmt.Sprintf("%s:%s", ag.routeKey, ag.labels)
+	return fmt.Sprintf("%s:%s", ag.routeKey, types.LabelSetString(ag.labels))
 }
 
 func (ag *aggrGroup) String() string {
@@ -347,6 +440,9 @@ func (ag *aggrGroup) run(nf notifyFunc) {
 	for {
 		select {
 		case now := <-ag.next.C:
+			if !ag.startFlush() {
+				return
+			}
 			// Give the notifcations time until the next flush to
 			// finish before terminating them.
 			ctx, cancel := context.WithTimeout(ag.ctx, ag.timeout(ag.opts.GroupInterval))
@@ -362,17 +458,18 @@ func (ag *aggrGroup) run(nf notifyFunc) {
 			ctx = notify.WithGroupLabels(ctx, ag.labels)
 			ctx = notify.WithReceiverName(ctx, ag.opts.Receiver)
 			ctx = notify.WithRepeatInterval(ctx, ag.opts.RepeatInterval)
+			ctx = notify.WithChangeDetection(ctx, ag.opts.ChangeDetection)
 
 			// Wait the configured interval before calling flush again.
 			ag.mtx.Lock()
-			ag.next.Reset(ag.opts.GroupInterval)
+			ag.next.Reset(ag.nextInterval(now))
+			ag.lastFlush = now
 			ag.mtx.Unlock()
 
-			ag.flush(func(alerts ...*types.Alert) bool {
-				return nf(ctx, alerts...)
-			})
+			ag.flush(now, ag.notifier(ctx, now, nf))
 
 			cancel()
+			ag.finishFlush()
 
 		case <-ag.ctx.Done():
 			return
@@ -380,6 +477,154 @@ func (ag *aggrGroup) run(nf notifyFunc) {
 	}
 }
 
+// startFlush returns false if the group is drained and must not flush
+// anymore. Otherwise finishFlush must be called once the flush is done.
+func (ag *aggrGroup) startFlush() bool {
+	if ag.flushing != nil {
+		ag.flushing.RLock()
+	}
+	select {
+	case <-ag.drainc:
+		ag.finishFlush()
+		return false
+	default:
+		return true
+	}
+}
+
+func (ag *aggrGroup) finishFlush() {
+	if ag.flushing != nil {
+		ag.flushing.RUnlock()
+	}
+}
+
+// nextInterval returns the duration until the next flush after the flush
+// at the given time. If backoff is enabled, the interval doubles up to its
+// maximum for each flush without changes to the group's alerts. It never
+// extends past the flush at which the next repeat notification would be
+// sent, or an alert resolving by timing out would be noticed, without
+// backoff. Must be called with the lock held.
+func (ag *aggrGroup) nextInterval(now time.Time) time.Duration {
+	if ag.opts.GroupIntervalMax <= ag.opts.GroupInterval {
+		return ag.opts.GroupInterval
+	}
+	switch {
+	case ag.changed || !ag.hasSent:
+		ag.interval = ag.opts.GroupInterval
+		ag.notifiedAt = now
+	case 2*ag.interval > ag.opts.GroupIntervalMax:
+		ag.interval = ag.opts.GroupIntervalMax
+	default:
+		ag.interval *= 2
+	}
+	ag.changed = false
+
+	gi, d := ag.opts.GroupInterval, ag.interval
+	if gi <= 0 {
+		return d
+	}
+	if ag.opts.RepeatInterval > 0 {
+		// Without backoff, notifications are repeated at the first flush
+		// more than the repeat interval after the last one.
+		repeat := (ag.opts.RepeatInterval/gi + 1) * gi
+		if !now.Before(ag.notifiedAt.Add(repeat)) {
+			ag.notifiedAt = now
+		}
+		if r := ag.notifiedAt.Add(repeat).Sub(now); r < d {
+			d = r
+		}
+	}
+	// Alerts resolving by timing out are not inserted again. Without
+	// backoff, they are noticed at the first flush after they end.
+	for _, a := range ag.alerts {
+		if !a.EndsAt.After(now) {
+			continue
+		}
+		if r := (a.EndsAt.Sub(now) + gi - 1) / gi * gi; r < d {
+			d = r
+		}
+	}
+	return d
+}
+
+// resetBackoff makes the group flush once the regular group interval passed
+// since the last flush if it is backing off. Must be called with the lock
+// held.
+func (ag *aggrGroup) resetBackoff() {
+	ag.changed = true
+
+	if ag.interval > ag.opts.GroupInterval {
+		ag.interval = ag.opts.GroupInterval
+
+		d := ag.lastFlush.Add(ag.interval).Sub(time.Now())
+		if d < 0 {
+			d = 0
+		}
+		ag.next.Reset(d)
+	}
+}
+
+// notifier returns the function the alerts of a flush at the given time
+// are notified about with. Escalated notifications are marked in their
+// context, which templates see, and are additionally sent to the
+// escalation receiver.
+func (ag *aggrGroup) notifier(ctx context.Context, now time.Time, nf notifyFunc) func(...*types.Alert) bool {
+	return func(alerts ...*types.Alert) bool {
+		if !ag.escalate(now, alerts) {
+			return nf(ctx, alerts...)
+		}
+		ectx := notify.WithEscalated(ctx, true)
+		if ag.opts.EscalationReceiver == "" {
+			return nf(ectx, alerts...)
+		}
+		ok := nf(ctx, alerts...)
+		return nf(notify.WithReceiverName(ectx, ag.opts.EscalationReceiver), alerts...) && ok
+	}
+}
+
+// escalate reports whether the notification about the alerts is escalated,
+// which is the case once the group has been firing unacknowledged for the
+// configured number of repeat intervals. Alerts the marker reports as
+// silenced or inhibited are acknowledged and reset the escalation, like
+// resolved ones.
+func (ag *aggrGroup) escalate(now time.Time, alerts []*types.Alert) bool {
+	if ag.opts.EscalateAfter <= 0 {
+		return false
+	}
+	ag.mtx.Lock()
+	defer ag.mtx.Unlock()
+
+	firing := false
+	for _, a := range alerts {
+		if !a.Resolved() && !ag.muted(a) {
+			firing = true
+			break
+		}
+	}
+	if !firing {
+		ag.firingSince = time.Time{}
+		return false
+	}
+	if ag.firingSince.IsZero() {
+		ag.firingSince = now
+	}
+	return !now.Before(ag.firingSince.Add(time.Duration(ag.opts.EscalateAfter) * ag.opts.RepeatInterval))
+}
+
+// muted returns whether the marker reports the alert as silenced or
+// inhibited.
+func (ag *aggrGroup) muted(a *types.Alert) bool {
+	if ag.marker == nil {
+		return false
+	}
+	fp := a.Fingerprint()
+	if _, ok := ag.marker.Silenced(fp); ok {
+		return true
+	}
+	_, ok := ag.marker.Inhibited(fp)
+	return ok
+}
+
 func (ag *aggrGroup) stop() {
 	// Calling cancel will terminate all in-process notifications
 	// and the run() loop.
@@ -393,6 +638,10 @@ func (ag *aggrGroup) insert(alert *types.Alert) {
 	ag.mtx.Lock()
 	defer ag.mtx.Unlock()
 
+	if old, ok := ag.alerts[alert.Fingerprint()]; !ok || old.Resolved() != alert.Resolved() {
+		// Changes must not wait for a backed off interval.
+		ag.resetBackoff()
+	}
 	ag.alerts[alert.Fingerprint()] = alert
 
 	// Immediately trigger a flush if the wait duration for this
@@ -410,7 +659,7 @@ func (ag *aggrGroup) empty() bool {
 }
 
 // flush sends notifications for all new alerts.
-func (ag *aggrGroup) flush(notify func(...*types.Alert) bool) {
+func (ag *aggrGroup) flush(now time.Time, notify func(...*types.Alert) bool) {
 	if ag.empty() {
 		return
 	}
@@ -419,14 +668,46 @@ func (ag *aggrGroup) flush(notify func(...*types.Alert) bool) {
 	var (
 		alerts      = make(map[model.Fingerprint]*types.Alert, len(ag.alerts))
 		alertsSlice = make([]*types.Alert, 0, len(ag.alerts))
+		// Resolved alerts not sent in this flush remain in the group
+		// until the resolve interval has passed.
+		withResolved = ag.opts.ResolveInterval <= 0 || !now.Before(ag.lastResolvedFlush.Add(ag.opts.ResolveInterval))
+		hasResolved  bool
+		changed      bool
+		resolved     = make(map[model.Fingerprint]bool, len(ag.alerts))
 	)
 	for fp, alert := range ag.alerts {
+		r := alert.Resolved()
+		if was, ok := ag.flushedResolved[fp]; ok && was != r {
+			changed = true
+		}
+		resolved[fp] = r
+
+		if r {
+			if ag.opts.ResolvedMaxAge > 0 && alert.EndsAt.Before(now.Add(-ag.opts.ResolvedMaxAge)) {
+				delete(ag.alerts, fp)
+				continue
+			}
+			if !withResolved {
+				continue
+			}
+			hasResolved = true
+		}
 		alerts[fp] = alert
 		alertsSlice = append(alertsSlice, alert)
 	}
+	ag.flushedResolved = resolved
+	// Alerts resolving by timing out are not inserted again, so their
+	// change is only noticed here.
+	if changed {
+		ag.resetBackoff()
+	}
 
 	ag.mtx.Unlock()
 
+	if len(alertsSlice) == 0 {
+		return
+	}
+
 	level.Debug(ag.logger).Log("msg", "Flushing", "alerts", fmt.Sprintf("%v", alertsSlice))
 
 	if notify(alertsSlice...) {
@@ -440,6 +721,15 @@ func (ag *aggrGroup) flush(notify func(...*types.Alert) bool) {
 		}
 
 		ag.hasSent = true
+		if hasResolved {
+			ag.lastResolvedFlush = now
+		}
 		ag.mtx.Unlock()
+		return
 	}
+
+	// Failed notifications are retried without backing off.
+	ag.mtx.Lock()
+	ag.resetBackoff()
+	ag.mtx.Unlock()
 }
@@ -12,7 +12,9 @@ import (
 	"github.com/prometheus/prometheus/pkg/labels"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -90,6 +92,60 @@ func TestFilterLabels(t *testing.T) {
 	}
 }
 
+func TestGroupKeyStability(t *testing.T) {
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "a", "cluster": "c1", "service": "s1", "team": "t1"},
+		},
+	}
+
+	var keys []string
+	for _, conf := range []string{`
+route:
+  receiver: 'default'
+  routes:
+  - match:
+      team: t1
+    match_re:
+      service: s.*
+      cluster: c.*
+    group_by: ['alertname', 'cluster', 'service']
+receivers:
+- name: 'default'
+`, `
+route:
+  receiver: 'default'
+  routes:
+  - match_re:
+      cluster: c.*
+      service: s.*
+    match:
+      team: t1
+    group_by: ['service', 'alertname', 'cluster']
+receivers:
+- name: 'default'
+`} {
+		cfg, err := config.Load(conf)
+		if err != nil {
+			t.Fatalf("Error parsing config: %s", err)
+		}
+		routes := NewRoute(cfg.Route, nil).Match(alert.Labels)
+		if len(routes) != 1 {
+			t.Fatalf("expected one matching route, got %d", len(routes))
+		}
+		ag := newAggrGroup(context.Background(), getGroupLabels(alert, routes[0]), routes[0], nil, log.NewNopLogger())
+		keys = append(keys, ag.GroupKey())
+	}
+
+	if keys[0] != keys[1] {
+		t.Fatalf("group keys differ after reordering the route: %q, %q", keys[0], keys[1])
+	}
+	exp := `{}/{cluster=~"^(?:c.*)$",service=~"^(?:s.*)$",team="t1"}:{alertname="a", cluster="c1", service="s1"}`
+	if keys[0] != exp {
+		t.Fatalf("expected group key %q, got %q", exp, keys[0])
+	}
+}
+
 func TestAggrGroup(t *testing.T) {
 	lset := model.LabelSet{
 		"a": "v1",
@@ -300,3 +356,462 @@ func TestAggrGroup(t *testing.T) {
 
 	ag.stop()
 }
+
+func TestAggrGroupIntervalBackoff(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:         "n1",
+			GroupBy:          map[model.LabelName]struct{}{},
+			GroupWait:        time.Hour,
+			GroupInterval:    time.Minute,
+			GroupIntervalMax: 5 * time.Minute,
+			RepeatInterval:   time.Hour,
+		},
+	}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route, nil, log.NewNopLogger())
+	defer ag.next.Stop()
+
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"a": "v1"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	ag.insert(a)
+	ag.hasSent = true
+
+	now := time.Now()
+	exp := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, d := range exp {
+		if got := ag.nextInterval(now); got != d {
+			t.Fatalf("unexpected interval %d: expected %s, got %s", i, d, got)
+		}
+		// Updates without changes do not reset the backoff.
+		ag.insert(a)
+	}
+
+	// A new alert resets the interval.
+	ag.insert(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"a": "v2"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	})
+	if got := ag.nextInterval(now); got != time.Minute {
+		t.Fatalf("expected interval to be reset, got %s", got)
+	}
+
+	// A failed notification resets the interval.
+	ag.nextInterval(now)
+	ag.nextInterval(now)
+	ag.flush(now, func(...*types.Alert) bool { return false })
+	if got := ag.nextInterval(now); got != time.Minute {
+		t.Fatalf("expected interval to be reset after failed notification, got %s", got)
+	}
+
+	// Backoff is disabled without a larger maximum.
+	route.RouteOpts.GroupIntervalMax = 0
+	for i := 0; i < 3; i++ {
+		if got := ag.nextInterval(now); got != time.Minute {
+			t.Fatalf("unexpected interval with backoff disabled: %s", got)
+		}
+	}
+}
+
+func TestAggrGroupIntervalBackoffTimeout(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:         "n1",
+			GroupBy:          map[model.LabelName]struct{}{},
+			GroupWait:        time.Hour,
+			GroupInterval:    time.Minute,
+			GroupIntervalMax: time.Hour,
+			RepeatInterval:   time.Hour,
+		},
+	}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route, nil, log.NewNopLogger())
+	defer ag.next.Stop()
+
+	start := time.Now()
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"a": "v1"},
+			StartsAt: start,
+			EndsAt:   start.Add(10 * time.Minute),
+		},
+	}
+	ag.insert(a)
+	ag.flush(start, func(...*types.Alert) bool { return true })
+
+	// Backed off flushes do not skip past the time the alert times out.
+	var flushes []time.Duration
+	for now := start; now.Sub(start) < 10*time.Minute; {
+		now = now.Add(ag.nextInterval(now))
+		flushes = append(flushes, now.Sub(start))
+	}
+	exp := []time.Duration{1, 3, 7, 10}
+	for i := range exp {
+		exp[i] *= time.Minute
+	}
+	if !reflect.DeepEqual(exp, flushes) {
+		t.Fatalf("expected flushes after %v, got %v", exp, flushes)
+	}
+
+	// The flush noticing that the alert timed out resets the backoff.
+	a.EndsAt = time.Now().Add(-time.Second)
+	var got []*types.Alert
+	ag.flush(start.Add(10*time.Minute), func(alerts ...*types.Alert) bool {
+		got = alerts
+		return true
+	})
+	if len(got) != 1 || !got[0].Resolved() {
+		t.Fatalf("expected resolved alert to be flushed, got %v", got)
+	}
+	if got := ag.nextInterval(start.Add(10 * time.Minute)); got != time.Minute {
+		t.Fatalf("expected interval to be reset after alert timed out, got %s", got)
+	}
+}
+
+func TestAggrGroupIntervalBackoffRepeat(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:         "n1",
+			GroupBy:          map[model.LabelName]struct{}{},
+			GroupWait:        time.Hour,
+			GroupInterval:    time.Minute,
+			GroupIntervalMax: time.Hour,
+			RepeatInterval:   10 * time.Minute,
+		},
+	}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route, nil, log.NewNopLogger())
+	defer ag.next.Stop()
+
+	ag.insert(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"a": "v1"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	})
+
+	// Flushes back off but still happen when the notification would be
+	// repeated without backoff, which is the first flush more than the
+	// repeat interval after the last notification at start.
+	start := time.Now()
+	var flushes []time.Duration
+	for now := start; now.Sub(start) < 40*time.Minute; {
+		now = now.Add(ag.nextInterval(now))
+		ag.hasSent = true
+		flushes = append(flushes, now.Sub(start))
+	}
+	exp := []time.Duration{1, 3, 7, 11, 22, 33, 44}
+	for i := range exp {
+		exp[i] *= time.Minute
+	}
+	if !reflect.DeepEqual(exp, flushes) {
+		t.Fatalf("expected flushes after %v, got %v", exp, flushes)
+	}
+}
+
+func TestAggrGroupResolvedBatching(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:        "n1",
+			GroupBy:         map[model.LabelName]struct{}{},
+			GroupWait:       time.Hour,
+			GroupInterval:   time.Minute,
+			RepeatInterval:  time.Hour,
+			ResolveInterval: 15 * time.Minute,
+			ResolvedMaxAge:  time.Hour,
+		},
+	}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route, nil, log.NewNopLogger())
+	defer ag.next.Stop()
+
+	var (
+		now    = time.Now()
+		firing = &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"a": "firing"},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+		resolved = func(name string, at time.Time) *types.Alert {
+			return &types.Alert{
+				Alert: model.Alert{
+					Labels:   model.LabelSet{"a": model.LabelValue(name)},
+					StartsAt: at.Add(-time.Hour),
+					EndsAt:   at,
+				},
+			}
+		}
+	)
+
+	var got []*types.Alert
+	flush := func(now time.Time) {
+		got = nil
+		ag.flush(now, func(alerts ...*types.Alert) bool {
+			got = alerts
+			return true
+		})
+	}
+
+	ag.insert(firing)
+	ag.insert(resolved("r1", now.Add(-time.Minute)))
+	ag.insert(resolved("old", now.Add(-2*time.Hour)))
+
+	// The first resolved alert is sent right away, resolved alerts past
+	// the maximum age are dropped.
+	flush(now)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 alerts in first flush, got %v", got)
+	}
+	if _, ok := ag.alerts[resolved("old", now).Fingerprint()]; ok {
+		t.Fatalf("resolved alert past max age was not dropped")
+	}
+
+	// Further resolved alerts are held back until the resolve interval passed.
+	ag.insert(resolved("r2", now))
+	flush(now.Add(time.Minute))
+	if len(got) != 1 || got[0] != firing {
+		t.Fatalf("expected only the firing alert to be sent, got %v", got)
+	}
+
+	flush(now.Add(15 * time.Minute))
+	if len(got) != 2 {
+		t.Fatalf("expected held resolved alert to be sent, got %v", got)
+	}
+	if len(ag.alerts) != 1 {
+		t.Fatalf("expected only the firing alert to remain, got %v", ag.alerts)
+	}
+}
+
+func TestAggrGroupEscalation(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:           "n1",
+			GroupBy:            map[model.LabelName]struct{}{"a": struct{}{}},
+			GroupWait:          time.Minute,
+			GroupInterval:      time.Minute,
+			RepeatInterval:     time.Hour,
+			EscalateAfter:      2,
+			EscalationReceiver: "pager",
+		},
+	}
+	lset := model.LabelSet{"a": "v1"}
+	ag := newAggrGroup(context.Background(), lset, route, nil, log.NewNopLogger())
+	defer ag.next.Stop()
+	marker := types.NewMarker()
+	ag.marker = marker
+
+	var (
+		now    = time.Now()
+		firing = &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+			},
+		}
+		resolved = &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now,
+				EndsAt:   now,
+			},
+		}
+	)
+
+	type notification struct {
+		receiver  string
+		escalated bool
+	}
+	var got []notification
+	nf := func(ctx context.Context, alerts ...*types.Alert) bool {
+		recv, _ := notify.ReceiverName(ctx)
+		escalated, _ := notify.Escalated(ctx)
+		if labels, _ := notify.GroupLabels(ctx); !labels.Equal(lset) {
+			t.Errorf("unexpected group labels %v", labels)
+		}
+		got = append(got, notification{recv, escalated})
+		return true
+	}
+	send := func(at time.Time, alerts ...*types.Alert) []notification {
+		got = nil
+		ctx := notify.WithReceiverName(context.Background(), "n1")
+		ctx = notify.WithGroupLabels(ctx, lset)
+		ag.notifier(ctx, at, nf)(alerts...)
+		return got
+	}
+
+	exp := []notification{{"n1", false}}
+	if res := send(now, firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v, got %v", exp, res)
+	}
+	if res := send(now.Add(119*time.Minute), firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v before escalation, got %v", exp, res)
+	}
+
+	exp = []notification{{"n1", false}, {"pager", true}}
+	if res := send(now.Add(2*time.Hour), firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v after escalation, got %v", exp, res)
+	}
+
+	// Resolving the group resets the escalation.
+	exp = []notification{{"n1", false}}
+	if res := send(now.Add(3*time.Hour), resolved); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v after resolution, got %v", exp, res)
+	}
+	if res := send(now.Add(4*time.Hour), firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v after firing again, got %v", exp, res)
+	}
+
+	// Silencing the alerts resets the escalation.
+	marker.SetSilenced(firing.Fingerprint(), "sil")
+	if res := send(now.Add(6*time.Hour), firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v while silenced, got %v", exp, res)
+	}
+	marker.SetSilenced(firing.Fingerprint())
+	if res := send(now.Add(7*time.Hour), firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v after the silence expired, got %v", exp, res)
+	}
+	marker.SetInhibited(firing.Fingerprint(), "inhibitor")
+	if res := send(now.Add(9*time.Hour), firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v while inhibited, got %v", exp, res)
+	}
+	marker.SetInhibited(firing.Fingerprint())
+	send(now.Add(10*time.Hour), firing)
+
+	// Without escalation receiver the route's receiver is marked escalated.
+	route.RouteOpts.EscalationReceiver = ""
+	exp = []notification{{"n1", true}}
+	if res := send(now.Add(12*time.Hour), firing); !reflect.DeepEqual(res, exp) {
+		t.Fatalf("expected %v, got %v", exp, res)
+	}
+}
+
+func TestAggrGroupDrain(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      time.Millisecond,
+			GroupInterval:  time.Millisecond,
+			RepeatInterval: time.Hour,
+		},
+	}
+	var (
+		drainc   = make(chan struct{})
+		flushMtx sync.RWMutex
+	)
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route, nil, log.NewNopLogger())
+	ag.drainc, ag.flushing = drainc, &flushMtx
+
+	ag.insert(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"a": "v1"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	})
+
+	var (
+		started  = make(chan struct{}, 1)
+		release  = make(chan struct{})
+		flushes  int
+		finished = make(chan struct{})
+	)
+	go func() {
+		ag.run(func(ctx context.Context, alerts ...*types.Alert) bool {
+			flushes++
+			started <- struct{}{}
+			<-release
+			return true
+		})
+		close(finished)
+	}()
+
+	<-started
+	close(drainc)
+
+	drained := make(chan struct{})
+	go func() {
+		flushMtx.Lock()
+		flushMtx.Unlock()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		t.Fatalf("drained while flush was in progress")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatalf("not drained after flush finished")
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatalf("aggregation group kept running after being drained")
+	}
+	if flushes != 1 {
+		t.Fatalf("expected 1 flush, got %d", flushes)
+	}
+	ag.cancel()
+}
+
+func TestDispatcherDrainStop(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{},
+			GroupWait:      time.Millisecond,
+			GroupInterval:  time.Millisecond,
+			RepeatInterval: time.Hour,
+		},
+	}
+	d := NewDispatcher(alerts, route, nil, types.NewMarker(), nil, log.NewNopLogger())
+
+	// Draining or stopping a dispatcher that does not run does nothing.
+	if !d.Drain(time.Second) {
+		t.Fatal("expected drain of idle dispatcher to succeed")
+	}
+	d.Stop()
+
+	go d.Run()
+	for {
+		d.mtx.RLock()
+		running := d.cancel != nil
+		d.mtx.RUnlock()
+		if running {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Concurrent calls must neither race nor close the drain channel twice.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			d.Drain(time.Second)
+		}()
+		go func() {
+			defer wg.Done()
+			d.Stop()
+		}()
+	}
+	wg.Wait()
+}
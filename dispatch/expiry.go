@@ -0,0 +1,244 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var (
+	numExpiryNotifications = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "silence_expiry_notifications_total",
+		Help:      "The total number of attempted notifications about expiring silences.",
+	})
+	numFailedExpiryNotifications = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "silence_expiry_notifications_failed_total",
+		Help:      "The total number of failed notifications about expiring silences.",
+	})
+)
+
+func init() {
+	prometheus.Register(numExpiryNotifications)
+	prometheus.Register(numFailedExpiryNotifications)
+}
+
+const (
+	// DefaultSilenceExpiryInterval is the interval at which silences are
+	// checked for expiring soon.
+	DefaultSilenceExpiryInterval = time.Minute
+
+	// SilenceExpiryAlertName is the alert name of notifications about
+	// expiring silences. Their silence_id label holds the ID of the
+	// silence.
+	SilenceExpiryAlertName = "SilenceExpiring"
+
+	// maxExpiryAlerts is the number of silenced alerts listed in expiry
+	// notifications.
+	maxExpiryAlerts = 20
+)
+
+// A SilenceExpiryNotifier notifies a receiver about silences expiring soon
+// while they still silence alerts, so that they can be extended before the
+// alerts start to notify.
+type SilenceExpiryNotifier struct {
+	silences *silence.Silences
+	alerts   provider.Alerts
+	marker   types.Marker
+	stage    notify.Stage
+	receiver string
+	before   time.Duration
+	interval time.Duration
+	now      func() time.Time
+	logger   log.Logger
+
+	ctx    context.Context
+	cancel func()
+	done   chan struct{}
+}
+
+// NewSilenceExpiryNotifier returns a new SilenceExpiryNotifier checking for
+// expiring silences at the given interval. The stage notifies the receiver
+// of the configuration. Nothing is notified about if the configuration is
+// nil.
+func NewSilenceExpiryNotifier(conf *config.SilenceExpiry, s *silence.Silences, ap provider.Alerts, m types.Marker, stage notify.Stage, interval time.Duration, l log.Logger) *SilenceExpiryNotifier {
+	n := &SilenceExpiryNotifier{
+		silences: s,
+		alerts:   ap,
+		marker:   m,
+		stage:    stage,
+		interval: interval,
+		now:      time.Now,
+		logger:   l,
+		done:     make(chan struct{}),
+	}
+	if conf != nil {
+		n.receiver = conf.Receiver
+		n.before = time.Duration(conf.NotifyBefore)
+	}
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+
+	return n
+}
+
+// Run the SilenceExpiryNotifier's background processing until it is
+// stopped.
+func (n *SilenceExpiryNotifier) Run() {
+	defer close(n.done)
+
+	if n.receiver == "" {
+		<-n.ctx.Done()
+		return
+	}
+
+	t := time.NewTicker(n.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-t.C:
+			n.notifyExpiring()
+		}
+	}
+}
+
+// Stop the SilenceExpiryNotifier's background processing and wait for it
+// to finish.
+func (n *SilenceExpiryNotifier) Stop() {
+	if n == nil {
+		return
+	}
+	n.cancel()
+	<-n.done
+}
+
+// notifyExpiring notifies about the active silences expiring within the
+// configured duration that still silence alerts.
+func (n *SilenceExpiryNotifier) notifyExpiring() {
+	now := n.now()
+
+	sils, err := n.silences.Query(silence.QState(silence.StateActive))
+	if err != nil {
+		level.Error(n.logger).Log("msg", "Querying silences failed", "err", err)
+		return
+	}
+	expiring := map[string]*silencepb.Silence{}
+	for _, s := range sils {
+		if s.EndsAt.Sub(now) <= n.before {
+			expiring[s.Id] = s
+		}
+	}
+	if len(expiring) == 0 {
+		return
+	}
+
+	silenced := map[string][]*types.Alert{}
+	alerts := n.alerts.GetPending()
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			level.Error(n.logger).Log("msg", "Error iterating alerts", "err", err)
+			break
+		}
+		if a.Resolved() {
+			continue
+		}
+		ids, ok := n.marker.Silenced(a.Fingerprint())
+		if !ok {
+			continue
+		}
+		for _, id := range ids {
+			if _, ok := expiring[id]; ok {
+				silenced[id] = append(silenced[id], a)
+			}
+		}
+	}
+	alerts.Close()
+
+	for id, as := range silenced {
+		n.notify(now, expiring[id], as)
+	}
+}
+
+// notify notifies about the expiring silence and the alerts it silences.
+func (n *SilenceExpiryNotifier) notify(now time.Time, s *silencepb.Silence, alerts []*types.Alert) {
+	a := expiryAlert(s, alerts, n.before)
+
+	ctx, cancel := context.WithTimeout(n.ctx, n.interval)
+	defer cancel()
+
+	ctx = notify.WithNow(ctx, now)
+	ctx = notify.WithGroupKey(ctx, "silence_expiry:"+a.Labels.String())
+	ctx = notify.WithGroupLabels(ctx, a.Labels)
+	ctx = notify.WithReceiverName(ctx, n.receiver)
+	// Notify once unless the silence is extended and expires soon again.
+	ctx = notify.WithRepeatInterval(ctx, n.before)
+	ctx = notify.WithChangeDetection(ctx, notify.ChangeDetection{Annotations: []string{"ends_at"}})
+
+	numExpiryNotifications.Inc()
+	if _, _, err := n.stage.Exec(ctx, n.logger, a); err != nil {
+		numFailedExpiryNotifications.Inc()
+		level.Error(n.logger).Log("msg", "Notifying about expiring silence failed", "silence", s.Id, "err", err)
+	}
+}
+
+// expiryAlert returns the alert notified about for the expiring silence.
+// It starts as the silence enters the notification window and ends with
+// the silence.
+func expiryAlert(s *silencepb.Silence, alerts []*types.Alert, before time.Duration) *types.Alert {
+	names := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		names = append(names, a.Labels.String())
+	}
+	sort.Strings(names)
+	if len(names) > maxExpiryAlerts {
+		names = append(names[:maxExpiryAlerts], fmt.Sprintf("and %d more", len(names)-maxExpiryAlerts))
+	}
+
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: SilenceExpiryAlertName,
+				"silence_id":         model.LabelValue(s.Id),
+			},
+			Annotations: model.LabelSet{
+				"summary":         model.LabelValue(fmt.Sprintf("Silence by %s expires while silencing %d alerts", s.CreatedBy, len(alerts))),
+				"description":     model.LabelValue(s.Comment),
+				"ends_at":         model.LabelValue(s.EndsAt.Format(time.RFC3339)),
+				"silenced_alerts": model.LabelValue(strings.Join(names, "\n")),
+			},
+			StartsAt: s.EndsAt.Add(-before),
+			EndsAt:   s.EndsAt,
+		},
+		UpdatedAt: s.UpdatedAt,
+	}
+}
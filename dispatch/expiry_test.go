@@ -0,0 +1,119 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceExpiryNotifier(t *testing.T) {
+	marker := types.NewMarker()
+	alerts, err := mem.NewAlerts(marker, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	silences, err := silence.New(silence.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	newSilence := func(endsAt time.Time) string {
+		id, err := silences.Set(&silencepb.Silence{
+			Matchers:  []*silencepb.Matcher{{Name: "a", Pattern: "b"}},
+			StartsAt:  now.Add(-time.Hour),
+			EndsAt:    endsAt,
+			CreatedBy: "alice",
+			Comment:   "maintenance",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	var (
+		expiring = newSilence(now.Add(30 * time.Minute))
+		lasting  = newSilence(now.Add(2 * time.Hour))
+		idle     = newSilence(now.Add(10 * time.Minute))
+	)
+
+	newAlert := func(lset model.LabelSet, endsAt time.Time, silencedBy ...string) {
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   endsAt,
+			},
+		}
+		if err := alerts.Put(a); err != nil {
+			t.Fatal(err)
+		}
+		marker.SetSilenced(a.Fingerprint(), silencedBy...)
+	}
+	newAlert(model.LabelSet{"alertname": "a", "i": "1"}, now.Add(time.Hour), expiring)
+	newAlert(model.LabelSet{"alertname": "a", "i": "2"}, now.Add(time.Hour), expiring, lasting)
+	newAlert(model.LabelSet{"alertname": "b"}, now.Add(time.Hour), lasting)
+	// Resolved alerts are not listed.
+	newAlert(model.LabelSet{"alertname": "c"}, now.Add(-time.Minute), expiring, idle)
+
+	var (
+		notified []*types.Alert
+		receiver string
+	)
+	stage := notify.StageFunc(func(ctx context.Context, l log.Logger, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		receiver, _ = notify.ReceiverName(ctx)
+		notified = append(notified, as...)
+		return ctx, as, nil
+	})
+	conf := &config.SilenceExpiry{Receiver: "team", NotifyBefore: model.Duration(time.Hour)}
+	n := NewSilenceExpiryNotifier(conf, silences, alerts, marker, stage, time.Minute, log.NewNopLogger())
+	n.now = func() time.Time { return now }
+
+	n.notifyExpiring()
+
+	if len(notified) != 1 {
+		t.Fatalf("Expected one notification but got %v", notified)
+	}
+	if receiver != "team" {
+		t.Errorf("Unexpected receiver %q", receiver)
+	}
+	a := notified[0]
+	exp := model.LabelSet{"alertname": SilenceExpiryAlertName, "silence_id": model.LabelValue(expiring)}
+	if !a.Labels.Equal(exp) {
+		t.Errorf("Unexpected labels %v", a.Labels)
+	}
+	if a.Resolved() || !a.EndsAt.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("Unexpected end time %s", a.EndsAt)
+	}
+	exps := `{alertname="a", i="1"}` + "\n" + `{alertname="a", i="2"}`
+	if s := string(a.Annotations["silenced_alerts"]); s != exps {
+		t.Errorf("Unexpected silenced alerts:\n%s", s)
+	}
+	if s := string(a.Annotations["summary"]); !strings.Contains(s, "alice") || !strings.Contains(s, "2 alerts") {
+		t.Errorf("Unexpected summary %q", s)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var numAutoResolvedAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "alerts_auto_resolved_total",
+	Help:      "The total number of alerts resolved by auto-resolve rules.",
+}, []string{"reason"})
+
+func init() {
+	numAutoResolvedAlerts.WithLabelValues("timeout")
+	numAutoResolvedAlerts.WithLabelValues("resolution")
+
+	prometheus.Register(numAutoResolvedAlerts)
+}
+
+// DefaultAutoResolveInterval is the interval at which alerts are checked
+// for having timed out according to the auto-resolve rules.
+const DefaultAutoResolveInterval = 10 * time.Second
+
+// An AutoResolver resolves alerts according to auto-resolve rules. It is
+// meant for senders that only report events and never resolve the alerts
+// they sent.
+type AutoResolver struct {
+	alerts   provider.Alerts
+	rules    []*autoResolveRule
+	interval time.Duration
+	now      func() time.Time
+	logger   log.Logger
+
+	// Resolution alerts received but not yet processed. Alerts are
+	// resolved outside of the subscription loop as inserting them blocks
+	// until all subscribers received them.
+	mtx     sync.Mutex
+	pending []*types.Alert
+	pendc   chan struct{}
+
+	ctx    context.Context
+	cancel func()
+	done   chan struct{}
+}
+
+type autoResolveRule struct {
+	matchers   types.Matchers
+	resolution types.Matchers
+	timeout    time.Duration
+	equal      []model.LabelName
+}
+
+// NewAutoResolver returns a new AutoResolver checking for timed out alerts
+// at the given interval.
+func NewAutoResolver(ap provider.Alerts, rs []*config.AutoResolveRule, interval time.Duration, l log.Logger) *AutoResolver {
+	ar := &AutoResolver{
+		alerts:   ap,
+		interval: interval,
+		now:      time.Now,
+		logger:   l,
+		pendc:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	ar.ctx, ar.cancel = context.WithCancel(context.Background())
+
+	for _, cr := range rs {
+		r := &autoResolveRule{
+			timeout: time.Duration(cr.Timeout),
+			equal:   cr.Equal,
+		}
+		for ln, lv := range cr.Match {
+			r.matchers = append(r.matchers, types.NewMatcher(model.LabelName(ln), lv))
+		}
+		for ln, lv := range cr.MatchRE {
+			r.matchers = append(r.matchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+		}
+		for ln, lv := range cr.ResolutionMatch {
+			r.resolution = append(r.resolution, types.NewMatcher(model.LabelName(ln), lv))
+		}
+		for ln, lv := range cr.ResolutionMatchRE {
+			r.resolution = append(r.resolution, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
+		}
+		ar.rules = append(ar.rules, r)
+	}
+	return ar
+}
+
+// Run the AutoResolver's background processing until it is stopped.
+func (ar *AutoResolver) Run() {
+	defer close(ar.done)
+
+	if len(ar.rules) == 0 {
+		<-ar.ctx.Done()
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ar.run()
+	}()
+	defer wg.Wait()
+
+	it := ar.alerts.Subscribe()
+	defer it.Close()
+
+	for {
+		select {
+		case <-ar.ctx.Done():
+			return
+		case a := <-it.Next():
+			if err := it.Err(); err != nil {
+				level.Error(ar.logger).Log("msg", "Error iterating alerts", "err", err)
+				continue
+			}
+			if a.Resolved() || !ar.isResolution(a) {
+				continue
+			}
+			ar.mtx.Lock()
+			ar.pending = append(ar.pending, a)
+			ar.mtx.Unlock()
+
+			select {
+			case ar.pendc <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (ar *AutoResolver) run() {
+	t := time.NewTicker(ar.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ar.ctx.Done():
+			return
+		case <-t.C:
+			ar.resolveTimedOut()
+		case <-ar.pendc:
+			ar.mtx.Lock()
+			pending := ar.pending
+			ar.pending = nil
+			ar.mtx.Unlock()
+
+			for _, a := range pending {
+				ar.resolveBy(a)
+			}
+		}
+	}
+}
+
+// isResolution returns whether the alert is a resolution alert of any rule.
+func (ar *AutoResolver) isResolution(a *types.Alert) bool {
+	for _, r := range ar.rules {
+		if len(r.resolution) > 0 && r.resolution.Match(a.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop the AutoResolver's background processing and wait for it to finish.
+func (ar *AutoResolver) Stop() {
+	if ar == nil {
+		return
+	}
+	ar.cancel()
+	<-ar.done
+}
+
+// resolveTimedOut resolves the alerts that were not updated within the
+// timeout of a matching rule.
+func (ar *AutoResolver) resolveTimedOut() {
+	now := ar.now()
+
+	ar.resolve("timeout", func(a *types.Alert) (time.Time, bool) {
+		for _, r := range ar.rules {
+			if r.timeout == 0 || !r.matchers.Match(a.Labels) {
+				continue
+			}
+			if endsAt := a.UpdatedAt.Add(r.timeout); !endsAt.After(now) {
+				return endsAt, true
+			}
+		}
+		return time.Time{}, false
+	})
+}
+
+// resolveBy resolves the alerts that the given alert is a resolution alert
+// for. Only alerts last updated before the resolution alert are resolved.
+func (ar *AutoResolver) resolveBy(res *types.Alert) {
+	if res.Resolved() {
+		return
+	}
+	var rules []*autoResolveRule
+	for _, r := range ar.rules {
+		if len(r.resolution) > 0 && r.resolution.Match(res.Labels) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return
+	}
+	fp := res.Fingerprint()
+
+	ar.resolve("resolution", func(a *types.Alert) (time.Time, bool) {
+		if a.Fingerprint() == fp || !a.UpdatedAt.Before(res.UpdatedAt) {
+			return time.Time{}, false
+		}
+		for _, r := range rules {
+			if r.matchers.Match(a.Labels) && equalLabels(a.Labels, res.Labels, r.equal) {
+				return res.UpdatedAt, true
+			}
+		}
+		return time.Time{}, false
+	})
+}
+
+// resolve resolves all active alerts for which f returns true at the time
+// returned by f.
+func (ar *AutoResolver) resolve(reason string, f func(*types.Alert) (time.Time, bool)) {
+	var (
+		now      = ar.now()
+		resolved []*types.Alert
+	)
+	alerts := ar.alerts.GetPending()
+	for a := range alerts.Next() {
+		if err := alerts.Err(); err != nil {
+			level.Error(ar.logger).Log("msg", "Error iterating alerts", "err", err)
+			break
+		}
+		if a.Resolved() {
+			continue
+		}
+		endsAt, ok := f(a)
+		if !ok {
+			continue
+		}
+		// Auto-resolved alerts are resolved like alerts resolved through
+		// the API, which overrides the end time given by their sender.
+		ra := *a
+		ra.EndsAt = endsAt
+		ra.UpdatedAt = now
+		ra.Timeout = false
+		ra.ForceResolved = true
+
+		resolved = append(resolved, &ra)
+	}
+	alerts.Close()
+
+	if len(resolved) == 0 {
+		return
+	}
+	if err := ar.alerts.Put(resolved...); err != nil {
+		level.Error(ar.logger).Log("msg", "Auto-resolving alerts failed", "err", err)
+		return
+	}
+	level.Debug(ar.logger).Log("msg", "Auto-resolved alerts", "reason", reason, "alerts", len(resolved))
+	numAutoResolvedAlerts.WithLabelValues(reason).Add(float64(len(resolved)))
+}
+
+// equalLabels returns whether both label sets have the same values for the
+// given label names.
+func equalLabels(a, b model.LabelSet, names []model.LabelName) bool {
+	for _, ln := range names {
+		if a[ln] != b[ln] {
+			return false
+		}
+	}
+	return true
+}
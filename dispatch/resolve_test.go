@@ -0,0 +1,120 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestAutoResolver(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []*config.AutoResolveRule{
+		{
+			Match:   map[string]string{"source": "events"},
+			Timeout: model.Duration(2 * time.Minute),
+		},
+		{
+			Match:           map[string]string{"alertname": "JobFailed"},
+			ResolutionMatch: map[string]string{"alertname": "JobSucceeded"},
+			Equal:           []model.LabelName{"job"},
+		},
+	}
+	ar := NewAutoResolver(alerts, rules, time.Minute, log.NewNopLogger())
+
+	// Alerts are resolved relative to the actual time, so the test time
+	// must not advance past it.
+	now := time.Now().Add(-10 * time.Second)
+	ar.now = func() time.Time { return now }
+
+	newAlert := func(updatedAt time.Time, lset model.LabelSet) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: updatedAt,
+				EndsAt:   now.Add(time.Hour),
+			},
+			UpdatedAt: updatedAt,
+			Timeout:   true,
+		}
+	}
+	var (
+		stale  = newAlert(now.Add(-3*time.Minute), model.LabelSet{"source": "events", "id": "1"})
+		fresh  = newAlert(now.Add(-time.Minute), model.LabelSet{"source": "events", "id": "2"})
+		other  = newAlert(now.Add(-time.Hour), model.LabelSet{"source": "metrics"})
+		failed = newAlert(now.Add(-time.Hour), model.LabelSet{"alertname": "JobFailed", "job": "a"})
+		failB  = newAlert(now.Add(-time.Hour), model.LabelSet{"alertname": "JobFailed", "job": "b"})
+	)
+	if err := alerts.Put(stale, fresh, other, failed, failB); err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := func(a *types.Alert) bool {
+		got, err := alerts.Get(a.Fingerprint())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Resolved()
+	}
+
+	ar.resolveTimedOut()
+	now = now.Add(time.Second)
+
+	if !resolved(stale) {
+		t.Errorf("alert not updated within the timeout must be resolved")
+	}
+	if got, _ := alerts.Get(stale.Fingerprint()); !got.EndsAt.Equal(stale.UpdatedAt.Add(2 * time.Minute)) {
+		t.Errorf("expected alert to end after the timeout, got %s", got.EndsAt)
+	}
+	if resolved(fresh) || resolved(other) {
+		t.Errorf("unexpected resolved alerts")
+	}
+
+	succeeded := newAlert(now, model.LabelSet{"alertname": "JobSucceeded", "job": "a"})
+	if err := alerts.Put(succeeded); err != nil {
+		t.Fatal(err)
+	}
+	ar.resolveBy(succeeded)
+
+	if !resolved(failed) {
+		t.Errorf("alert must be resolved by its resolution alert")
+	}
+	if resolved(failB) {
+		t.Errorf("alert with different equal labels must not be resolved")
+	}
+	if resolved(succeeded) {
+		t.Errorf("resolution alert must not be resolved")
+	}
+
+	// Alerts firing again after the resolution alert are not resolved.
+	refired := newAlert(now.Add(time.Minute), model.LabelSet{"alertname": "JobFailed", "job": "a"})
+	refired.StartsAt = now.Add(time.Minute)
+	if err := alerts.Put(refired); err != nil {
+		t.Fatal(err)
+	}
+	ar.resolveBy(succeeded)
+	if resolved(refired) {
+		t.Errorf("alert updated after the resolution alert must not be resolved")
+	}
+}
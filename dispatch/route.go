@@ -22,6 +22,7 @@ import (
 	"github.com/prometheus/common/model"
 
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -47,9 +48,17 @@ type Route struct {
 	// this route.
 	Matchers types.Matchers
 
+	// Minimum normalized severity an alert must have to match this route.
+	MinSeverity types.Severity
+	severities  types.SeverityMap
+
 	// If true, an alert matches further routes on the same level.
 	Continue bool
 
+	// Descriptive information about the route. Unlike the routing
+	// options it is not inherited by children routes.
+	Metadata *config.Metadata
+
 	// Children routes of this route.
 	Routes []*Route
 }
@@ -80,6 +89,29 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 	if cr.RepeatInterval != nil {
 		opts.RepeatInterval = time.Duration(*cr.RepeatInterval)
 	}
+	if cr.GroupIntervalMax != nil {
+		opts.GroupIntervalMax = time.Duration(*cr.GroupIntervalMax)
+	}
+	if cr.ResolveInterval != nil {
+		opts.ResolveInterval = time.Duration(*cr.ResolveInterval)
+	}
+	if cr.ResolvedMaxAge != nil {
+		opts.ResolvedMaxAge = time.Duration(*cr.ResolvedMaxAge)
+	}
+	if cr.EscalateAfter != nil {
+		opts.EscalateAfter = *cr.EscalateAfter
+	}
+	if cr.EscalationReceiver != "" {
+		opts.EscalationReceiver = cr.EscalationReceiver
+	}
+	if cr.ChangeDetection != "" {
+		opts.ChangeDetection.Mode = cr.ChangeDetection
+		opts.ChangeDetection.Thresholds = cr.ChangeThresholds
+	}
+	// An empty list stops tracking the annotations of the parent.
+	if cr.ChangeAnnotations != nil {
+		opts.ChangeDetection.Annotations = cr.ChangeAnnotations
+	}
 
 	// Build matchers.
 	var matchers types.Matchers
@@ -97,6 +129,11 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 		RouteOpts: opts,
 		Matchers:  matchers,
 		Continue:  cr.Continue,
+		Metadata:  cr.Metadata,
+	}
+	if cr.MinSeverity != "" {
+		route.MinSeverity, _ = types.ParseSeverity(cr.MinSeverity)
+		route.severities = cr.Severities
 	}
 
 	route.Routes = NewRoutes(cr.Routes, route)
@@ -119,6 +156,9 @@ func (r *Route) Match(lset model.LabelSet) []*Route {
 	if !r.Matchers.Match(lset) {
 		return nil
 	}
+	if r.MinSeverity > types.SeverityUnknown && r.severities.Severity(lset) < r.MinSeverity {
+		return nil
+	}
 
 	var all []*Route
 
@@ -148,7 +188,101 @@ func (r *Route) Key() string {
 		b = append(b, r.parent.Key()...)
 		b = append(b, '/')
 	}
-	return string(append(b, r.Matchers.String()...))
+	b = append(b, r.Matchers.String()...)
+	if r.MinSeverity > types.SeverityUnknown {
+		b = append(b, "severity>="+r.MinSeverity.String()...)
+	}
+	return string(b)
+}
+
+// Path returns the indices of the child routes leading from the root route
+// to the route.
+func (r *Route) Path() []int {
+	if r.parent == nil {
+		return []int{}
+	}
+	path := r.parent.Path()
+	for i, cr := range r.parent.Routes {
+		if cr == r {
+			path = append(path, i)
+			break
+		}
+	}
+	return path
+}
+
+// MatchedRoute identifies a route of the routing tree along with the
+// receiver alerts matching it are sent to.
+type MatchedRoute struct {
+	// Path holds the indices of the child routes leading from the root
+	// to the route.
+	Path     []int  `json:"path"`
+	Receiver string `json:"receiver"`
+}
+
+// Matched returns the MatchedRoute identifying the route.
+func (r *Route) Matched() *MatchedRoute {
+	return &MatchedRoute{
+		Path:     r.Path(),
+		Receiver: r.RouteOpts.Receiver,
+	}
+}
+
+// RouteMatchers are the conditions of a single route an alert has to
+// fulfill to match it.
+type RouteMatchers struct {
+	Matchers    types.Matchers `json:"matchers"`
+	MinSeverity string         `json:"minSeverity,omitempty"`
+}
+
+// EffectiveRoute describes a leaf route of the routing tree along with the
+// routing options it inherited from its parents.
+type EffectiveRoute struct {
+	// Path holds the indices of the child routes leading from the root
+	// to the route.
+	Path []int `json:"path"`
+	// MatcherChain holds the conditions of all routes from the root to
+	// the route. An alert matches the route only if it fulfills all of them.
+	MatcherChain []*RouteMatchers `json:"matcherChain"`
+	Continue     bool             `json:"continue"`
+	RouteOpts    *RouteOpts       `json:"routeOpts"`
+	Metadata     *config.Metadata `json:"metadata,omitempty"`
+}
+
+// Leaves returns the routes of the tree below r that have no child routes,
+// in depth-first left-to-right order. It returns r itself if it has no
+// child routes.
+func (r *Route) Leaves() []*Route {
+	if len(r.Routes) == 0 {
+		return []*Route{r}
+	}
+	var res []*Route
+	for _, cr := range r.Routes {
+		res = append(res, cr.Leaves()...)
+	}
+	return res
+}
+
+// Effective returns the EffectiveRoute describing the route.
+func (r *Route) Effective() *EffectiveRoute {
+	var chain []*RouteMatchers
+	for cr := r; cr != nil; cr = cr.parent {
+		rm := &RouteMatchers{Matchers: cr.Matchers}
+		if rm.Matchers == nil {
+			rm.Matchers = types.Matchers{}
+		}
+		if cr.MinSeverity > types.SeverityUnknown {
+			rm.MinSeverity = cr.MinSeverity.String()
+		}
+		chain = append([]*RouteMatchers{rm}, chain...)
+	}
+	return &EffectiveRoute{
+		Path:         r.Path(),
+		MatcherChain: chain,
+		Continue:     r.Continue,
+		RouteOpts:    &r.RouteOpts,
+		Metadata:     r.Metadata,
+	}
 }
 
 // RouteOpts holds various routing options necessary for processing alerts
@@ -165,6 +299,27 @@ type RouteOpts struct {
 	GroupWait      time.Duration
 	GroupInterval  time.Duration
 	RepeatInterval time.Duration
+
+	// The upper bound to which the group interval is backed off while
+	// a group's alerts remain unchanged. Backoff is disabled if it is not
+	// greater than the group interval.
+	GroupIntervalMax time.Duration
+
+	// The minimum time between notifications that include resolved alerts
+	// and the age after which resolved alerts are no longer notified about
+	// at all. Both are disabled if zero.
+	ResolveInterval time.Duration
+	ResolvedMaxAge  time.Duration
+
+	// The number of repeat intervals after which a group that keeps firing
+	// is escalated and the receiver escalated notifications are sent to in
+	// addition. Escalation is disabled if EscalateAfter is zero.
+	EscalateAfter      int
+	EscalationReceiver string
+
+	// Which changes of a group's alerts are notified about before the
+	// repeat interval passed.
+	ChangeDetection notify.ChangeDetection
 }
 
 func (ro *RouteOpts) String() string {
@@ -183,15 +338,37 @@ func (ro *RouteOpts) MarshalJSON() ([]byte, error) {
 		GroupWait      time.Duration    `json:"groupWait"`
 		GroupInterval  time.Duration    `json:"groupInterval"`
 		RepeatInterval time.Duration    `json:"repeatInterval"`
+
+		GroupIntervalMax time.Duration `json:"groupIntervalMax,omitempty"`
+		ResolveInterval  time.Duration `json:"resolveInterval,omitempty"`
+		ResolvedMaxAge   time.Duration `json:"resolvedMaxAge,omitempty"`
+
+		EscalateAfter      int    `json:"escalateAfter,omitempty"`
+		EscalationReceiver string `json:"escalationReceiver,omitempty"`
+
+		ChangeDetection   string   `json:"changeDetection,omitempty"`
+		ChangeThresholds  []int    `json:"changeThresholds,omitempty"`
+		ChangeAnnotations []string `json:"changeAnnotations,omitempty"`
 	}{
-		Receiver:       ro.Receiver,
-		GroupWait:      ro.GroupWait,
-		GroupInterval:  ro.GroupInterval,
-		RepeatInterval: ro.RepeatInterval,
+		Receiver:         ro.Receiver,
+		GroupWait:        ro.GroupWait,
+		GroupInterval:    ro.GroupInterval,
+		RepeatInterval:   ro.RepeatInterval,
+		GroupIntervalMax: ro.GroupIntervalMax,
+		ResolveInterval:  ro.ResolveInterval,
+		ResolvedMaxAge:   ro.ResolvedMaxAge,
+
+		EscalateAfter:      ro.EscalateAfter,
+		EscalationReceiver: ro.EscalationReceiver,
+
+		ChangeDetection:   ro.ChangeDetection.Mode,
+		ChangeThresholds:  ro.ChangeDetection.Thresholds,
+		ChangeAnnotations: ro.ChangeDetection.Annotations,
 	}
 	for ln := range ro.GroupBy {
 		v.GroupBy = append(v.GroupBy, ln)
 	}
+	sort.Sort(v.GroupBy)
 
 	return json.Marshal(&v)
 }
@@ -258,3 +258,78 @@ routes:
 		}
 	}
 }
+
+func TestRouteMatchMinSeverity(t *testing.T) {
+	conf, err := config.Load(`
+global:
+  severity_mapping:
+    p1: critical
+    p3: warning
+route:
+  receiver: 'notify-def'
+  routes:
+  - min_severity: error
+    receiver: 'notify-pager'
+receivers:
+- name: 'notify-def'
+- name: 'notify-pager'
+`)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	tree := NewRoute(conf.Route, nil)
+
+	tests := []struct {
+		input    model.LabelSet
+		receiver string
+	}{
+		{model.LabelSet{"severity": "p1"}, "notify-pager"},
+		{model.LabelSet{"severity": "ERROR"}, "notify-pager"},
+		{model.LabelSet{"severity": "p3"}, "notify-def"},
+		{model.LabelSet{"severity": "page"}, "notify-def"},
+		{model.LabelSet{}, "notify-def"},
+	}
+	for _, test := range tests {
+		matches := tree.Match(test.input)
+		if len(matches) != 1 {
+			t.Fatalf("expected one match for %v, got %d", test.input, len(matches))
+		}
+		if matches[0].RouteOpts.Receiver != test.receiver {
+			t.Errorf("expected receiver %q for %v, got %q", test.receiver, test.input, matches[0].RouteOpts.Receiver)
+		}
+	}
+	if k := tree.Routes[0].Key(); k != "{}/{}severity>=error" {
+		t.Errorf("unexpected route key %q", k)
+	}
+}
+
+func TestRouteChangeAnnotations(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: 'notify-def'
+  change_annotations: [summary]
+  routes:
+  - match:
+      team: a
+    change_detection: new_firing
+    receiver: 'notify-def'
+  - match:
+      team: b
+    change_annotations: []
+    receiver: 'notify-def'
+receivers:
+- name: 'notify-def'
+`)
+	if err != nil {
+		t.Fatalf("Error parsing config: %s", err)
+	}
+	tree := NewRoute(conf.Route, nil)
+
+	cd := tree.Routes[0].RouteOpts.ChangeDetection
+	if cd.Mode != config.ChangeDetectionNewFiring || !reflect.DeepEqual(cd.Annotations, []string{"summary"}) {
+		t.Errorf("unexpected inherited change detection %+v", cd)
+	}
+	if cd := tree.Routes[1].RouteOpts.ChangeDetection; len(cd.Annotations) != 0 {
+		t.Errorf("unexpected change annotations %v", cd.Annotations)
+	}
+}
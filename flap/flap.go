@@ -0,0 +1,243 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flap tracks how often alerts change between the firing and
+// resolved state and identifies alerts that are flapping.
+package flap
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Options configures a Detector.
+type Options struct {
+	// Window is the time range over which state transitions of an
+	// alert are counted towards its flapping score.
+	Window time.Duration
+	// Threshold is the number of transitions within the window at which
+	// an alert is considered flapping. A zero value disables detection.
+	Threshold int
+	// Hold is the duration a flapping alert has to remain in the same
+	// state before notifications for it are sent again.
+	Hold time.Duration
+}
+
+// A Detector observes alerts from a provider and keeps track of their
+// firing/resolved transitions. All methods are goroutine-safe.
+type Detector struct {
+	alerts provider.Alerts
+	opts   Options
+	logger log.Logger
+	now    func() time.Time
+
+	mtx    sync.RWMutex
+	states map[model.Fingerprint]*alertState
+	cancel func()
+}
+
+type alertState struct {
+	// The end time of the last seen version of the alert.
+	endsAt time.Time
+	// Whether the alert was resolved when it was last seen.
+	resolved bool
+	// Timestamps of transitions, oldest first.
+	transitions []time.Time
+}
+
+// NewDetector returns a new Detector for alerts of the given provider.
+func NewDetector(ap provider.Alerts, o Options, l log.Logger) *Detector {
+	if l == nil {
+		l = log.NewNopLogger()
+	}
+	return &Detector{
+		alerts: ap,
+		opts:   o,
+		logger: l,
+		now:    utcNow,
+		states: map[model.Fingerprint]*alertState{},
+	}
+}
+
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// Enabled returns whether flapping detection is configured.
+func (d *Detector) Enabled() bool {
+	return d != nil && d.opts.Threshold > 0
+}
+
+// Run the Detector's background processing.
+func (d *Detector) Run() {
+	var ctx context.Context
+
+	d.mtx.Lock()
+	ctx, d.cancel = context.WithCancel(context.Background())
+	d.mtx.Unlock()
+
+	go d.runGC(ctx)
+
+	it := d.alerts.Subscribe()
+	defer it.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-it.Next():
+			if err := it.Err(); err != nil {
+				level.Error(d.logger).Log("msg", "Error iterating alerts", "err", err)
+				continue
+			}
+			if a != nil {
+				d.observe(a)
+			}
+		}
+	}
+}
+
+// Stop the Detector's background processing.
+func (d *Detector) Stop() {
+	if d == nil {
+		return
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+func (d *Detector) runGC(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(15 * time.Minute):
+			d.gc()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// gc removes state for resolved alerts that have not changed within
+// the detection window.
+func (d *Detector) gc() {
+	now := d.now()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	for fp, s := range d.states {
+		s.trim(now.Add(-d.opts.Window))
+
+		if len(s.transitions) == 0 && !s.endsAt.After(now) {
+			delete(d.states, fp)
+		}
+	}
+}
+
+func (d *Detector) observe(a *types.Alert) {
+	var (
+		now      = d.now()
+		fp       = a.Fingerprint()
+		resolved = !a.EndsAt.IsZero() && !a.EndsAt.After(now)
+	)
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	s, ok := d.states[fp]
+	if !ok {
+		d.states[fp] = &alertState{endsAt: a.EndsAt, resolved: resolved}
+		return
+	}
+	// An alert may have timed out without a resolving update being
+	// received. Account for that implicit transition at the time it happened.
+	if !s.resolved && !s.endsAt.IsZero() && !s.endsAt.After(now) {
+		s.transitions = append(s.transitions, s.endsAt)
+		s.resolved = true
+	}
+	if s.resolved != resolved {
+		s.transitions = append(s.transitions, now)
+	}
+	s.endsAt = a.EndsAt
+	s.resolved = resolved
+
+	s.trim(now.Add(-d.opts.Window))
+}
+
+// trim drops all transitions before the given time.
+func (s *alertState) trim(since time.Time) {
+	i := 0
+	for i < len(s.transitions) && s.transitions[i].Before(since) {
+		i++
+	}
+	s.transitions = s.transitions[i:]
+}
+
+// Score returns the number of firing/resolved transitions the alert with
+// the given fingerprint went through within the detection window.
+func (d *Detector) Score(fp model.Fingerprint) int {
+	if d == nil {
+		return 0
+	}
+	since := d.now().Add(-d.opts.Window)
+
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	s, ok := d.states[fp]
+	if !ok {
+		return 0
+	}
+	n := 0
+	for _, t := range s.transitions {
+		if !t.Before(since) {
+			n++
+		}
+	}
+	return n
+}
+
+// Flapping returns true iff the alert's score reached the threshold.
+func (d *Detector) Flapping(fp model.Fingerprint) bool {
+	return d.Enabled() && d.Score(fp) >= d.opts.Threshold
+}
+
+// Held returns true iff notifications for the alert should be held back
+// because it is flapping and has not been stable for the hold duration.
+func (d *Detector) Held(fp model.Fingerprint) bool {
+	if !d.Flapping(fp) {
+		return false
+	}
+	d.mtx.RLock()
+	defer d.mtx.RUnlock()
+
+	s := d.states[fp]
+	if s == nil || len(s.transitions) == 0 {
+		return false
+	}
+	last := s.transitions[len(s.transitions)-1]
+
+	return d.now().Sub(last) < d.opts.Hold
+}
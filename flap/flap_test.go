@@ -0,0 +1,105 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDetectorScore(t *testing.T) {
+	now := time.Now()
+	d := NewDetector(nil, Options{
+		Window:    time.Hour,
+		Threshold: 3,
+		Hold:      10 * time.Minute,
+	}, nil)
+	d.now = func() time.Time { return now }
+
+	lset := model.LabelSet{"a": "b"}
+	fp := lset.Fingerprint()
+
+	firing := func() *types.Alert {
+		return &types.Alert{Alert: model.Alert{
+			Labels:   lset,
+			StartsAt: now,
+			EndsAt:   now.Add(5 * time.Minute),
+		}}
+	}
+	resolved := func() *types.Alert {
+		return &types.Alert{Alert: model.Alert{
+			Labels:   lset,
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now,
+		}}
+	}
+
+	// The first observation only establishes the state.
+	d.observe(firing())
+	require.Equal(t, 0, d.Score(fp))
+
+	// Repeated updates in the same state are no transition.
+	now = now.Add(time.Minute)
+	d.observe(firing())
+	require.Equal(t, 0, d.Score(fp))
+
+	now = now.Add(time.Minute)
+	d.observe(resolved())
+	require.Equal(t, 1, d.Score(fp))
+	require.False(t, d.Flapping(fp))
+
+	now = now.Add(time.Minute)
+	d.observe(firing())
+	require.Equal(t, 2, d.Score(fp))
+
+	// The alert times out before it fires again, which counts as two transitions.
+	now = now.Add(10 * time.Minute)
+	d.observe(firing())
+	require.Equal(t, 4, d.Score(fp))
+	require.True(t, d.Flapping(fp))
+	require.True(t, d.Held(fp))
+
+	// Once stable for the hold duration, notifications are no longer held.
+	now = now.Add(10 * time.Minute)
+	require.True(t, d.Flapping(fp))
+	require.False(t, d.Held(fp))
+
+	// Transitions drop out of the window over time.
+	now = now.Add(time.Hour)
+	require.Equal(t, 0, d.Score(fp))
+	require.False(t, d.Flapping(fp))
+
+	// A resolving update for an alert that already timed out is no new transition.
+	d.observe(resolved())
+	require.Equal(t, 0, d.Score(fp))
+
+	// Garbage collection removes resolved alerts without recent transitions.
+	d.gc()
+	require.Empty(t, d.states)
+}
+
+func TestDetectorDisabled(t *testing.T) {
+	var d *Detector
+	require.False(t, d.Enabled())
+	require.Equal(t, 0, d.Score(1))
+	require.False(t, d.Held(1))
+
+	d = NewDetector(nil, Options{Window: time.Hour}, nil)
+	require.False(t, d.Enabled())
+}
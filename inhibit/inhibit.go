@@ -204,6 +204,20 @@ func NewInhibitRule(cr *config.InhibitRule) *InhibitRule {
 	}
 }
 
+// Inhibits returns whether an alert with the source label set inhibits an
+// alert with the target label set according to the rule.
+func (r *InhibitRule) Inhibits(source, target model.LabelSet) bool {
+	if !r.SourceMatchers.Match(source) || !r.TargetMatchers.Match(target) {
+		return false
+	}
+	for n := range r.Equal {
+		if source[n] != target[n] {
+			return false
+		}
+	}
+	return true
+}
+
 // set the alert in the source cache.
 func (r *InhibitRule) set(a *types.Alert) {
 	r.mtx.Lock()
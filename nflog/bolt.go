@@ -0,0 +1,130 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nflog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	bolt "go.etcd.io/bbolt"
+
+	pb "github.com/prometheus/alertmanager/nflog/nflogpb"
+)
+
+// boltBucket holds the entries by their state key.
+var boltBucket = []byte("entries")
+
+// boltOpenTimeout is how long opening waits for the database file lock,
+// which another process running on the same data directory may hold.
+const boltOpenTimeout = 5 * time.Second
+
+// boltStore persists the entries of the log in a BoltDB database. Every
+// change of an entry is written in a transaction of its own, or of all the
+// entries merged at once, before it is visible to queries.
+type boltStore struct {
+	db *bolt.DB
+}
+
+func openBoltStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+// load returns all entries stored in the database.
+func (s *boltStore) load() (gossipData, error) {
+	gd := gossipData{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, v []byte) error {
+			var e pb.MeshEntry
+			if err := e.Unmarshal(v); err != nil {
+				return fmt.Errorf("decoding entry %q: %s", k, err)
+			}
+			gd[string(k)] = &e
+			return nil
+		})
+	})
+	return gd, err
+}
+
+// update stores the entries and removes the entries of the deleted keys in
+// one transaction.
+func (s *boltStore) update(put gossipData, del []string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for k, e := range put {
+			v, err := e.Marshal()
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		for _, k := range del {
+			if err := b.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) close() error {
+	return s.db.Close()
+}
+
+// WithBoltDB persists every change of the log to a BoltDB database at the
+// given path, so that no notifications are lost between snapshots. The log
+// is initialized from the database instead of snapshots, unless the
+// database is new or empty, in which case the snapshot is loaded and
+// written to the database. Queries are still served from memory and the
+// state is gossiped as without a database. The history of entries is not
+// persisted. The database is closed as the maintenance configured by
+// WithMaintenance stops.
+func WithBoltDB(path string) Option {
+	return func(l *nlog) error {
+		s, err := openBoltStore(path)
+		if err != nil {
+			return fmt.Errorf("opening notification log database: %s", err)
+		}
+		l.bolt = s
+		return nil
+	}
+}
+
+// persist writes the changed and deleted entries to the database, if any.
+// Entries are written with their shard locked so that concurrent changes
+// of an entry are persisted in order. Failures are logged as the entries
+// were changed in memory and shared with peers already.
+func (l *nlog) persist(put gossipData, del ...string) {
+	if l.bolt == nil || (len(put) == 0 && len(del) == 0) {
+		return
+	}
+	if err := l.bolt.update(put, del); err != nil {
+		l.metrics.persistFailuresTotal.Inc()
+		level.Error(l.logger).Log("msg", "Persisting notification log entries failed", "entries", len(put)+len(del), "err", err)
+	}
+}
@@ -18,12 +18,16 @@
 package nflog
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
-	"math/rand"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,34 +35,159 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/matttproud/golang_protobuf_extensions/pbutil"
 	pb "github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/snapshot"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaveworks/mesh"
 )
 
+// GossipVersion is the version of the state schema shared with peers. It
+// is incremented on changes that peers of the previous version cannot
+// merge.
+const GossipVersion = 1
+
 // ErrNotFound is returned for empty query results.
 var ErrNotFound = errors.New("not found")
 
+// ErrKeyCollision is returned if an entry cannot be logged because its
+// hashed state key is already used by an entry of another group key or
+// receiver.
+var ErrKeyCollision = errors.New("state key collision")
+
+// ErrReadOnly is returned for writes to a read-only log.
+var ErrReadOnly = errors.New("notification log is read-only")
+
+// Kinds of errors that may occur while loading a snapshot. They are wrapped
+// in a *SnapshotError and can be tested for with errors.Is.
+var (
+	// ErrSnapshotCorrupt is returned if the snapshot's framing is damaged
+	// or the snapshot ends in the middle of an entry.
+	ErrSnapshotCorrupt = errors.New("snapshot corrupt")
+	// ErrSnapshotVersion is returned if the snapshot was written in a
+	// format this version cannot read.
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrEntryDecode is returned if an entry was read completely but
+	// could not be decoded.
+	ErrEntryDecode = errors.New("decoding entry failed")
+)
+
+// Snapshots start with snapshotMagic followed by the uvarint encoded format
+// version. Snapshots written before the header was introduced start with
+// the first entry directly and are read as version 0. Their first bytes are
+// a length prefix and the tag of the entry field, which never match the
+// magic.
+//
+// Since version 2 the entries are followed by an empty entry and a
+// checksum trailer of the entries, which detects truncated snapshots.
+var snapshotMagic = []byte("ANFL")
+
+// snapshotVersion is the version of the snapshot format written.
+const snapshotVersion = 2
+
+// incrementalMagic starts incremental snapshots, followed by their uvarint
+// format version and the big-endian CRC-32C checksum of the encoded entries
+// of the full snapshot they were written on top of.
+var incrementalMagic = []byte("ANFI")
+
+// incrementalVersion is the format version of incremental snapshots.
+const incrementalVersion = 1
+
+// SnapshotError describes a failure to load a snapshot. All entries before
+// Offset were read successfully.
+type SnapshotError struct {
+	// Kind is one of ErrSnapshotCorrupt, ErrSnapshotVersion or ErrEntryDecode.
+	Kind error
+	// Offset is the byte offset of the entry that could not be read.
+	Offset int64
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *SnapshotError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s at offset %d", e.Kind, e.Offset)
+	}
+	return fmt.Sprintf("%s at offset %d: %s", e.Kind, e.Offset, e.Err)
+}
+
+// Is reports whether target is the kind of the error.
+func (e *SnapshotError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the underlying error.
+func (e *SnapshotError) Unwrap() error {
+	return e.Err
+}
+
 // Log stores and serves information about notifications
 // about byte-slice addressed alert objects to different receivers.
 type Log interface {
 	// The Log* methods store a notification log entry for
 	// a fully qualified receiver and a given IDs identifying the
-	// alert object.
-	Log(r *pb.Receiver, key string, firing, resolved []uint64) error
-
-	// Query the log along the given Paramteres.
-	//
-	// TODO(fabxc):
-	// - extend the interface by a `QueryOne` method?
-	// - return an iterator rather than a materialized list?
+	// alert object. Optional fields of the entry are set by the given
+	// parameters.
+	Log(r *pb.Receiver, key string, firing, resolved []uint64, p ...LogParam) error
+	// Delete removes the entry for the receiver and group key, so that
+	// the group is notified about as if it never was. The deletion is
+	// shared with the peers. It returns ErrNotFound if there is no entry.
+	Delete(r *pb.Receiver, key []byte) error
+	// Acknowledge records that the notification of the entry for the
+	// receiver and group key was acknowledged at the provider at the given
+	// time. The acknowledgement is shared with the peers. It returns the
+	// entry and whether it was acknowledged by this call, which it is not
+	// if it was acknowledged before or the acknowledgement is older than
+	// the notification. It returns ErrNotFound if there is no entry.
+	Acknowledge(r *pb.Receiver, key []byte, at time.Time) (*pb.Entry, bool, error)
+
+	// Query the log along the given Paramteres. Entries are ordered by
+	// their timestamp, most recent last. A receiver must be given. Without
+	// a group key, the entries of the receiver for all group keys are
+	// returned. Entries with equal timestamps are ordered by group key,
+	// so that results can be paginated with QOffset and QLimit.
 	Query(p ...QueryParam) ([]*pb.Entry, error)
+	// QueryIter returns an iterator over the entries matching the given
+	// parameters. Unlike Query it accepts any combination of parameters,
+	// including none at all. Entries are ordered by group key and
+	// receiver.
+	QueryIter(p ...QueryParam) (EntryIterator, error)
+	// QueryOne returns the most recent entry matching the given parameters.
+	// It returns ErrNotFound if no entry matches.
+	QueryOne(p ...QueryParam) (*pb.Entry, error)
 
 	// Snapshot the current log state and return the number
 	// of bytes written.
 	Snapshot(w io.Writer) (int, error)
+	// SnapshotFile writes a snapshot to the configured snapshot file and
+	// store and rotates previous snapshot files. With incremental snapshots
+	// it writes only the changed entries if possible. It does nothing if
+	// neither is configured.
+	SnapshotFile() error
 	// GC removes expired entries from the log. It returns
 	// the total number of deleted entries.
 	GC() (int, error)
+	// View returns a read-only view of the current log state.
+	View() *View
+	// Merge merges the given entries into the log like entries received
+	// from a peer and shares the ones that were newer with the peers.
+	// It returns the number of merged entries.
+	Merge(entries ...*pb.MeshEntry) (int, error)
+	// Flush blocks until all entries logged before the call are applied to
+	// the log. It returns immediately unless writes are asynchronous.
+	Flush()
+	// Repair requests entries from the peer. If keys are given, only their
+	// entries are requested. Otherwise the peer is sent a digest of the
+	// log and replies with all entries missing or outdated locally. The
+	// entries are merged once they are received.
+	Repair(peer mesh.PeerName, keys ...RepairKey) error
+	// Stats returns the number of notifications logged locally per
+	// receiver integration within the last hour and day.
+	Stats() []ReceiverStats
+}
+
+// RepairKey identifies an entry requested from a peer.
+type RepairKey struct {
+	GroupKey string
+	Receiver *pb.Receiver
 }
 
 // query currently allows filtering by and/or receiver group key.
@@ -67,8 +196,44 @@ type Log interface {
 // TODO(fabxc): Future versions could allow querying a certain receiver
 // group or a given time interval.
 type query struct {
-	recv     *pb.Receiver
-	groupKey string
+	recv *pb.Receiver
+	// receiverName selects entries of all integrations of the receiver if
+	// set.
+	receiverName string
+	groupKey     string
+	offset       int
+	// limit is the maximum number of entries returned. Zero means
+	// unlimited.
+	limit int
+	// resolved selects entries with or without firing alerts if set.
+	resolved *bool
+}
+
+// LogParam is a function that sets an optional field of a logged entry.
+type LogParam func(*pb.MeshEntry)
+
+// LProviderID sets the identifier the notified provider returned for the
+// notification.
+func LProviderID(id string) LogParam {
+	return func(e *pb.MeshEntry) {
+		e.Entry.ProviderId = id
+	}
+}
+
+// LAnnotationsHash sets the hash over the notified annotations of the
+// firing alerts.
+func LAnnotationsHash(h uint64) LogParam {
+	return func(e *pb.MeshEntry) {
+		e.Entry.AnnotationsHash = h
+	}
+}
+
+// LRetention keeps the entry for the given duration instead of the
+// retention of the log.
+func LRetention(d time.Duration) LogParam {
+	return func(e *pb.MeshEntry) {
+		e.ExpiresAt = e.Entry.Timestamp.Add(d)
+	}
 }
 
 // QueryParam is a function that modifies a query to incorporate
@@ -84,6 +249,15 @@ func QReceiver(r *pb.Receiver) QueryParam {
 	}
 }
 
+// QReceiverName selects the entries of all integrations of the named
+// receiver.
+func QReceiverName(name string) QueryParam {
+	return func(q *query) error {
+		q.receiverName = name
+		return nil
+	}
+}
+
 // QGroupKey adds a group key as querying argument.
 func QGroupKey(gk string) QueryParam {
 	return func(q *query) error {
@@ -92,16 +266,109 @@ func QGroupKey(gk string) QueryParam {
 	}
 }
 
+// QOffset skips the first n matching entries.
+func QOffset(n int) QueryParam {
+	return func(q *query) error {
+		if n < 0 {
+			return fmt.Errorf("query offset must not be negative")
+		}
+		q.offset = n
+		return nil
+	}
+}
+
+// QLimit limits a query to at most n entries.
+func QLimit(n int) QueryParam {
+	return func(q *query) error {
+		if n <= 0 {
+			return fmt.Errorf("query limit must be positive")
+		}
+		q.limit = n
+		return nil
+	}
+}
+
+// QResolved selects entries of notifications about resolved alerts only if
+// resolved is true, and entries of notifications with firing alerts
+// otherwise.
+func QResolved(resolved bool) QueryParam {
+	return func(q *query) error {
+		q.resolved = &resolved
+		return nil
+	}
+}
+
+// matches returns whether the entry matches the receiver name and status
+// selected by the query. It does not check the exact receiver and group key.
+func (q *query) matches(e *pb.Entry) bool {
+	if q.receiverName != "" && e.Receiver.GroupName != q.receiverName {
+		return false
+	}
+	return q.resolved == nil || *q.resolved == (len(e.FiringAlerts) == 0)
+}
+
+// filter returns the entries of res matching the query.
+func (q *query) filter(res []*pb.Entry) []*pb.Entry {
+	if q.receiverName == "" && q.resolved == nil {
+		return res
+	}
+	filtered := res[:0]
+	for _, e := range res {
+		if q.matches(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// page returns the entries of res selected by the offset and limit of
+// the query.
+func (q *query) page(res []*pb.Entry) []*pb.Entry {
+	if q.offset >= len(res) {
+		return []*pb.Entry{}
+	}
+	res = res[q.offset:]
+	if q.limit > 0 && q.limit < len(res) {
+		res = res[:q.limit]
+	}
+	return res
+}
+
 type nlog struct {
 	logger    log.Logger
 	metrics   *metrics
 	now       func() time.Time
 	retention time.Duration
+	// Returns the retention of entries of a receiver, falling back to
+	// retention if it is not positive.
+	receiverRetention func(*pb.Receiver) time.Duration
 
 	runInterval time.Duration
 	snapf       string
-	stopc       chan struct{}
-	done        func()
+	// Size limit for snapshots and whether to drop entries to stay below it.
+	maxSnapshotSize int64
+	pruneSnapshot   bool
+	// Number of previous snapshots to keep.
+	snapshotRotations int
+	compression       snapshot.Compression
+	codec             snapshot.Codec
+	sync              snapshot.SyncPolicy
+	store             snapshot.Store
+	storeName         string
+	// Database persisting every change, if any.
+	bolt    *boltStore
+	snapMtx sync.Mutex
+	stopc   chan struct{}
+	done    func()
+
+	// Maximum number of incremental snapshots written between full ones.
+	incrementals int
+	// The state of the last snapshot written to the snapshot file, the
+	// checksum of the last full snapshot and the number of incremental
+	// snapshots written on top of it. Guarded by snapMtx.
+	lastSnapshot    *View
+	incrementalBase uint32
+	incrementalSeq  int
 
 	gossip mesh.Gossip // gossip channel for sharing log state.
 
@@ -110,8 +377,37 @@ type nlog struct {
 	// Currently our memory state is equivalent to the mesh.GossipData
 	// representation. This may change in the future as we support history
 	// and indexing.
-	mtx sync.RWMutex
-	st  gossipData
+	st     state
+	shards int
+
+	// At most history previous entries are kept per key.
+	history int
+
+	observers []func(*pb.MeshEntry)
+
+	// Whether state keys are hashed.
+	hashKeys bool
+	// Whether writes are rejected and the state is not shared with peers.
+	readOnly bool
+	// Maximum number of entries. Zero means unlimited.
+	maxEntries int
+	// How much newer than a written entry the entry it replaces may be.
+	skewTolerance time.Duration
+
+	// Pending asynchronous writes. Writes are synchronous if nil.
+	writec chan write
+
+	// Notifications logged locally per receiver integration.
+	statsMtx sync.Mutex
+	stats    map[statsKey]*receiverCounts
+}
+
+// write is an entry to be written to the log asynchronously. Writes without
+// an entry only close flushed once all previous writes were applied.
+type write struct {
+	key     string
+	e       *pb.MeshEntry
+	flushed chan struct{}
 }
 
 type metrics struct {
@@ -120,6 +416,16 @@ type metrics struct {
 	queriesTotal     prometheus.Counter
 	queryErrorsTotal prometheus.Counter
 	queryDuration    prometheus.Histogram
+	snapshotSize     prometheus.Gauge
+	prunedTotal      prometheus.Counter
+	collisionsTotal  prometheus.Counter
+	invalidTotal     prometheus.Counter
+	evictedTotal     prometheus.Counter
+	writeBatchSize   prometheus.Summary
+	repairedTotal    prometheus.Counter
+	staleWritesTotal prometheus.Counter
+	// Failed writes to the database configured by WithBoltDB.
+	persistFailuresTotal prometheus.Counter
 }
 
 func newMetrics(r prometheus.Registerer) *metrics {
@@ -133,6 +439,42 @@ func newMetrics(r prometheus.Registerer) *metrics {
 		Name: "alertmanager_nflog_snapshot_duration_seconds",
 		Help: "Duration of the last notification log snapshot.",
 	})
+	m.snapshotSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "alertmanager_nflog_snapshot_size_bytes",
+		Help: "Size of the last notification log snapshot in bytes.",
+	})
+	m.prunedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_snapshot_pruned_entries_total",
+		Help: "Number of notification log entries dropped to stay within the maximum snapshot size.",
+	})
+	m.collisionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_key_collisions_total",
+		Help: "Number of notification log entries dropped because their hashed state key was used by another entry.",
+	})
+	m.invalidTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_gossip_invalid_entries_total",
+		Help: "Number of notification log entries received from peers that were dropped as invalid.",
+	})
+	m.evictedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_evicted_entries_total",
+		Help: "Number of notification log entries evicted to stay within the maximum number of entries.",
+	})
+	m.writeBatchSize = prometheus.NewSummary(prometheus.SummaryOpts{
+		Name: "alertmanager_nflog_write_batch_size",
+		Help: "Number of notification log entries applied together by asynchronous writes.",
+	})
+	m.repairedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_gossip_repaired_entries_total",
+		Help: "Number of notification log entries updated by repair requests to peers.",
+	})
+	m.staleWritesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_stale_writes_total",
+		Help: "Number of notification log writes rejected because the existing entry has a newer timestamp.",
+	})
+	m.persistFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_persist_failures_total",
+		Help: "Number of failed writes of notification log entries to the database.",
+	})
 	m.queriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "alertmanager_nflog_queries_total",
 		Help: "Number of notification log queries were received.",
@@ -153,6 +495,15 @@ func newMetrics(r prometheus.Registerer) *metrics {
 			m.queriesTotal,
 			m.queryErrorsTotal,
 			m.queryDuration,
+			m.snapshotSize,
+			m.prunedTotal,
+			m.collisionsTotal,
+			m.invalidTotal,
+			m.evictedTotal,
+			m.writeBatchSize,
+			m.repairedTotal,
+			m.staleWritesTotal,
+			m.persistFailuresTotal,
 		)
 	}
 	return m
@@ -178,6 +529,16 @@ func WithRetention(d time.Duration) Option {
 	}
 }
 
+// WithReceiverRetention sets the retention time of the entries of each
+// receiver. The retention set by WithRetention applies to receivers for
+// which f returns a value that is not positive.
+func WithReceiverRetention(f func(*pb.Receiver) time.Duration) Option {
+	return func(l *nlog) error {
+		l.receiverRetention = f
+		return nil
+	}
+}
+
 // WithNow overwrites the function used to retrieve a timestamp
 // for the current point in time.
 // This is generally useful for injection during tests.
@@ -200,6 +561,9 @@ func WithLogger(logger log.Logger) Option {
 func WithMetrics(r prometheus.Registerer) Option {
 	return func(l *nlog) error {
 		l.metrics = newMetrics(r)
+		if r != nil {
+			r.MustRegister(statsCollector{l})
+		}
 		return nil
 	}
 }
@@ -231,6 +595,208 @@ func WithSnapshot(sf string) Option {
 	}
 }
 
+// WithSnapshotStore uploads snapshots to the store under the given name in
+// addition to writing them to the snapshot file, if any. The log is
+// initialized from the store if no snapshot file could be loaded.
+func WithSnapshotStore(s snapshot.Store, name string) Option {
+	return func(l *nlog) error {
+		if name == "" {
+			return fmt.Errorf("snapshot store name must not be empty")
+		}
+		l.store = s
+		l.storeName = name
+		return nil
+	}
+}
+
+// WithMaxSnapshotSize sets a size limit in bytes for snapshots. Exceeding it
+// logs a warning. If prune is true, the entries expiring soonest are removed
+// from the log before snapshotting until the snapshot fits into the limit.
+// Pruning considers the uncompressed size of the entries.
+func WithMaxSnapshotSize(size int64, prune bool) Option {
+	return func(l *nlog) error {
+		if size < 0 {
+			return fmt.Errorf("maximum snapshot size must not be negative")
+		}
+		l.maxSnapshotSize = size
+		l.pruneSnapshot = prune
+		return nil
+	}
+}
+
+// WithSnapshotRotation keeps the given number of previous snapshots next to
+// the snapshot file. If the latest snapshot cannot be loaded, the previous
+// ones are tried from newest to oldest.
+func WithSnapshotRotation(n int) Option {
+	return func(l *nlog) error {
+		if n < 0 {
+			return fmt.Errorf("number of snapshot rotations must not be negative")
+		}
+		l.snapshotRotations = n
+		return nil
+	}
+}
+
+// WithIncrementalSnapshots writes up to n incremental snapshots holding
+// the entries changed since the previous snapshot before another full
+// snapshot replaces them. Incremental snapshots are written next to the
+// snapshot file and are replayed on top of it when loading. They are
+// neither rotated nor uploaded to the snapshot store.
+func WithIncrementalSnapshots(n int) Option {
+	return func(l *nlog) error {
+		if n < 0 {
+			return fmt.Errorf("number of incremental snapshots must not be negative")
+		}
+		l.incrementals = n
+		return nil
+	}
+}
+
+// WithSnapshotCompression compresses written snapshots. The compression of
+// loaded snapshots is detected automatically.
+func WithSnapshotCompression(c snapshot.Compression) Option {
+	return func(l *nlog) error {
+		if _, err := snapshot.ParseCompression(string(c)); err != nil {
+			return err
+		}
+		l.compression = c
+		return nil
+	}
+}
+
+// WithSnapshotCodec sets the codec the entries of written snapshots are
+// encoded with. It defaults to snapshot.ProtoCodec. The codec of loaded
+// snapshots is detected automatically. Incremental snapshots are always
+// encoded with snapshot.ProtoCodec.
+func WithSnapshotCodec(c snapshot.Codec) Option {
+	return func(l *nlog) error {
+		l.codec = c
+		return nil
+	}
+}
+
+// WithSnapshotSync sets when written snapshots are synced to stable
+// storage. It defaults to snapshot.SyncOnClose.
+func WithSnapshotSync(p snapshot.SyncPolicy) Option {
+	return func(l *nlog) error {
+		if _, err := snapshot.ParseSyncPolicy(string(p)); err != nil {
+			return err
+		}
+		l.sync = p
+		return nil
+	}
+}
+
+// WithHistory keeps up to n previous entries per group key and receiver in
+// addition to the most recent one. Previous entries are returned by Query,
+// but they are kept in memory only and not shared with other peers.
+func WithHistory(n int) Option {
+	return func(l *nlog) error {
+		if n < 0 {
+			return fmt.Errorf("history size must not be negative")
+		}
+		l.history = n
+		return nil
+	}
+}
+
+// WithObserver calls f for every entry written to the log or merged from
+// peers. It is called synchronously while the entry's shard of the log is
+// locked, so f must not block or call into the log, and it must not modify
+// the entry. It may be called concurrently for entries of different shards.
+// The option may be given multiple times.
+func WithObserver(f func(*pb.MeshEntry)) Option {
+	return func(l *nlog) error {
+		l.observers = append(l.observers, f)
+		return nil
+	}
+}
+
+// WithHashedKeys stores entries under a fixed-size hash of their group key
+// and receiver instead of their concatenation. This bounds the memory used
+// by the keys of large group keys, which are then only kept in the entries.
+// Entries whose hash collides with that of another entry are dropped.
+func WithHashedKeys(enabled bool) Option {
+	return func(l *nlog) error {
+		l.hashKeys = enabled
+		return nil
+	}
+}
+
+// WithReadOnly makes the log reject writes with ErrReadOnly. It still
+// serves queries and merges the entries gossiped by its peers, but never
+// shares its own state with them, so that it cannot affect deduplication
+// in the cluster. Broadcasts of peers are still relayed.
+func WithReadOnly(enabled bool) Option {
+	return func(l *nlog) error {
+		l.readOnly = enabled
+		return nil
+	}
+}
+
+// WithMaxEntries limits the log to n entries. If the limit is exceeded, the
+// entries with the oldest timestamps are evicted. Zero means no limit.
+//
+// The limit applies to each peer on its own. Evicted entries are not removed
+// from peers, which share them again if they keep more entries. They are
+// evicted again right away and counted as evicted each time. The limit thus
+// only bounds the log of the cluster if all peers use the same limit.
+func WithMaxEntries(n int) Option {
+	return func(l *nlog) error {
+		if n < 0 {
+			return fmt.Errorf("maximum number of entries must not be negative")
+		}
+		l.maxEntries = n
+		return nil
+	}
+}
+
+// WithAsyncWrites makes Log return without waiting for the entry to be
+// written. Up to n entries are buffered and applied in batches, so that
+// concurrent writers contend for the lock less often. Entries only become
+// visible to queries and peers once they are applied, which Flush waits for.
+// Errors of asynchronous writes are logged instead of being returned.
+func WithAsyncWrites(n int) Option {
+	return func(l *nlog) error {
+		if n < 0 {
+			return fmt.Errorf("write buffer size must not be negative")
+		}
+		if n > 0 {
+			l.writec = make(chan write, n)
+		}
+		return nil
+	}
+}
+
+// WithClockSkewTolerance lets written entries replace entries that are up to
+// d newer, like entries received from a peer whose clock is slightly ahead.
+// Replacing entries are stored with a timestamp just after the one of the
+// replaced entry, so that peers accept them as well. Writes of entries
+// older than that are rejected.
+func WithClockSkewTolerance(d time.Duration) Option {
+	return func(l *nlog) error {
+		if d < 0 {
+			return fmt.Errorf("clock skew tolerance must not be negative")
+		}
+		l.skewTolerance = d
+		return nil
+	}
+}
+
+// WithShards splits the log state into n shards by state key, each with its
+// own lock. Queries and writes of entries in different shards do not
+// contend with each other, while operations on the whole state, like views
+// and eviction, lock all shards.
+func WithShards(n int) Option {
+	return func(l *nlog) error {
+		if n < 1 {
+			return fmt.Errorf("number of shards must be positive")
+		}
+		l.shards = n
+		return nil
+	}
+}
+
 func utcNow() time.Time {
 	return time.Now().UTC()
 }
@@ -241,36 +807,55 @@ func New(opts ...Option) (Log, error) {
 	l := &nlog{
 		logger: log.NewNopLogger(),
 		now:    utcNow,
-		st:     map[string]*pb.MeshEntry{},
+		shards: defaultShards,
 	}
 	for _, o := range opts {
 		if err := o(l); err != nil {
 			return nil, err
 		}
 	}
+	l.st = newState(l.shards, nil)
 	if l.metrics == nil {
 		l.metrics = newMetrics(nil)
 	}
 
-	if l.snapf != "" {
-		if f, err := os.Open(l.snapf); !os.IsNotExist(err) {
-			if err != nil {
-				return l, err
-			}
-			defer f.Close()
-
-			if err := l.loadSnapshot(f); err != nil {
-				return l, err
-			}
+	var loaded bool
+	if l.bolt != nil {
+		gd, err := l.bolt.load()
+		if err != nil {
+			return l, err
+		}
+		// A new or empty database is initialized from the snapshots so
+		// that existing state is not lost when enabling the database.
+		l.st.replace(gd)
+		loaded = len(gd) > 0
+	}
+	fromBolt := loaded
+	if !loaded && l.snapf != "" {
+		var err error
+		if loaded, err = l.loadSnapshots(); err != nil {
+			return l, err
+		}
+	}
+	if !loaded && l.store != nil {
+		if err := l.loadStoreSnapshot(); err != nil {
+			return l, err
 		}
 	}
+	if l.bolt != nil && !fromBolt {
+		l.persist(l.st.all())
+	}
 
+	if l.writec != nil {
+		go l.runWrites()
+	}
 	go l.run()
 
 	return l, nil
 }
 
-// run periodic background maintenance.
+// run periodic background maintenance. A final maintenance is run on
+// shutdown so that the latest state is persisted in the snapshot.
 func (l *nlog) run() {
 	if l.runInterval == 0 || l.stopc == nil {
 		return
@@ -281,217 +866,1503 @@ func (l *nlog) run() {
 	if l.done != nil {
 		defer l.done()
 	}
-
-	f := func() error {
-		start := l.now()
-		level.Info(l.logger).Log("msg", "Running maintenance")
-		defer level.Info(l.logger).Log("msg", "Maintenance done", "duration", l.now().Sub(start))
-
-		if _, err := l.GC(); err != nil {
-			return err
-		}
-		if l.snapf == "" {
-			return nil
-		}
-		f, err := openReplace(l.snapf)
-		if err != nil {
-			return err
+	// Deferred after done so that it completes before done is called.
+	defer func() {
+		// No need to run final maintenance if we don't want to snapshot.
+		if l.snapf != "" || l.store != nil {
+			l.Flush()
+			if err := l.maintenance(); err != nil {
+				level.Error(l.logger).Log("msg", "Creating shutdown snapshot failed", "err", err)
+			}
 		}
-		// TODO(fabxc): potentially expose snapshot size in log message.
-		if _, err := l.Snapshot(f); err != nil {
-			return err
+		if l.bolt != nil {
+			l.Flush()
+			if err := l.bolt.close(); err != nil {
+				level.Error(l.logger).Log("msg", "Closing notification log database failed", "err", err)
+			}
 		}
-		return f.Close()
-	}
+	}()
 
-Loop:
 	for {
 		select {
 		case <-l.stopc:
-			break Loop
+			return
 		case <-t.C:
-			if err := f(); err != nil {
+			if err := l.maintenance(); err != nil {
 				level.Error(l.logger).Log("msg", "Running maintenance failed", "err", err)
 			}
 		}
 	}
-	// No need to run final maintenance if we don't want to snapshot.
-	if l.snapf == "" {
-		return
-	}
-	if err := f(); err != nil {
-		level.Error(l.logger).Log("msg", "Creating shutdown snapshot failed", "err", err)
-	}
 }
 
-func receiverKey(r *pb.Receiver) string {
-	return fmt.Sprintf("%s/%s/%d", r.GroupName, r.Integration, r.Idx)
-}
+// maintenance garbage collects the log and writes a snapshot. The snapshot
+// is written even if garbage collection fails.
+func (l *nlog) maintenance() error {
+	start := l.now()
+	level.Info(l.logger).Log("msg", "Running maintenance")
+	defer level.Info(l.logger).Log("msg", "Maintenance done", "duration", l.now().Sub(start))
 
-// stateKey returns a string key for a log entry consisting of the group key
-// and receiver.
-func stateKey(k string, r *pb.Receiver) string {
-	return fmt.Sprintf("%s:%s", k, receiverKey(r))
+	_, gcErr := l.GC()
+	if err := l.SnapshotFile(); err != nil {
+		return err
+	}
+	return gcErr
 }
 
-func (l *nlog) Log(r *pb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error {
-	// Write all st with the same timestamp.
-	now := l.now()
-	key := stateKey(gkey, r)
+// SnapshotFile implements the Log interface.
+func (l *nlog) SnapshotFile() error {
+	if l.snapf == "" && l.store == nil {
+		return nil
+	}
+	// Snapshots written concurrently must not interleave their rotation.
+	l.snapMtx.Lock()
+	defer l.snapMtx.Unlock()
+
+	pruned := 0
+	if l.maxSnapshotSize > 0 && l.pruneSnapshot {
+		if pruned = l.prune(l.maxSnapshotSize); pruned > 0 {
+			level.Warn(l.logger).Log("msg", "Pruned entries to stay within maximum snapshot size", "entries", pruned, "max_size", l.maxSnapshotSize)
+			l.metrics.prunedTotal.Add(float64(pruned))
+		}
+	}
+	v := l.View()
 
-	l.mtx.Lock()
-	defer l.mtx.Unlock()
+	// Removed entries cannot be expressed by incremental snapshots.
+	if l.snapf != "" && l.lastSnapshot != nil && l.incrementalSeq < l.incrementals &&
+		pruned == 0 && !l.removedLive(l.lastSnapshot, v) {
+		return l.snapshotIncremental(v)
+	}
 
-	if prevle, ok := l.st[key]; ok {
-		// Entry already exists, only overwrite if timestamp is newer.
-		// This may happen with raciness or clock-drift across AM nodes.
-		if prevle.Entry.Timestamp.After(now) {
-			return nil
+	var (
+		f   *snapshot.File
+		w   io.Writer
+		buf bytes.Buffer
+		err error
+	)
+	if l.snapf != "" {
+		if f, err = snapshot.OpenReplace(l.snapf, l.sync); err != nil {
+			return err
 		}
+		w = f
 	}
-
-	e := &pb.MeshEntry{
-		Entry: &pb.Entry{
-			Receiver:       r,
-			GroupKey:       []byte(gkey),
-			Timestamp:      now,
-			FiringAlerts:   firingAlerts,
-			ResolvedAlerts: resolvedAlerts,
-		},
-		ExpiresAt: now.Add(l.retention),
+	if l.store != nil {
+		if w == nil {
+			w = &buf
+		} else {
+			w = io.MultiWriter(f, &buf)
+		}
 	}
-	if l.gossip != nil {
-		l.gossip.GossipBroadcast(gossipData{
-			key: e,
-		})
+	// discard closes and removes the temporary file of a failed snapshot.
+	discard := func() {
+		if f != nil {
+			f.File.Close()
+			os.Remove(f.File.Name())
+		}
+	}
+	size, sum, err := l.snapshot(w, v)
+	if err != nil {
+		discard()
+		return err
 	}
-	l.st[key] = e
+	l.metrics.snapshotSize.Set(float64(size))
 
+	if l.maxSnapshotSize > 0 && int64(size) > l.maxSnapshotSize {
+		level.Warn(l.logger).Log("msg", "Snapshot exceeds maximum size", "size", size, "max_size", l.maxSnapshotSize)
+	}
+	if f != nil {
+		if err := rotateSnapshots(l.snapf, l.snapshotRotations); err != nil {
+			discard()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		// Incremental snapshots left behind would not match the checksum
+		// of the new snapshot and are ignored, but they are removed to not
+		// accumulate across restarts.
+		if err := removeIncrementals(l.snapf); err != nil {
+			return err
+		}
+		l.lastSnapshot = v
+		l.incrementalBase = sum
+		l.incrementalSeq = 0
+	}
+	if l.store != nil {
+		if err := l.store.Put(l.storeName, buf.Bytes()); err != nil {
+			return fmt.Errorf("uploading snapshot: %s", err)
+		}
+	}
 	return nil
 }
 
-// GC implements the Log interface.
-func (l *nlog) GC() (int, error) {
+// snapshotIncremental writes the entries of the view changed since the
+// last snapshot to the next incremental snapshot file. It must be called
+// with snapMtx held.
+func (l *nlog) snapshotIncremental(v *View) error {
+	start := time.Now()
+	defer func() { l.metrics.snapshotDuration.Observe(time.Since(start).Seconds()) }()
+
+	f, err := snapshot.OpenReplace(incrementalName(l.snapf, l.incrementalSeq+1), l.sync)
+	if err != nil {
+		return err
+	}
+	sw, err := snapshot.NewWriter(f, l.compression)
+	if err != nil {
+		f.File.Close()
+		os.Remove(f.File.Name())
+		return err
+	}
+	err = writeIncrementalHeader(sw, l.incrementalBase)
+	if err == nil {
+		_, err = l.writeEntries(sw, snapshot.ProtoCodec{}, v, l.lastSnapshot)
+	}
+	if err == nil {
+		err = sw.Close()
+	}
+	if err != nil {
+		f.File.Close()
+		os.Remove(f.File.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	l.lastSnapshot = v
+	l.incrementalSeq++
+
+	return nil
+}
+
+// removedLive returns whether entries of prev that have not expired yet are
+// missing from v, as after evictions. Expired entries are removed by
+// garbage collection again after loading.
+func (l *nlog) removedLive(prev, v *View) bool {
+	now := l.now()
+	for _, st := range prev.st {
+		for k, e := range st {
+			if v.get(k) == nil && e.ExpiresAt.After(now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func receiverKey(r *pb.Receiver) string {
+	if r.ConfigHash != "" {
+		return fmt.Sprintf("%s/%s/%d/%s", r.GroupName, r.Integration, r.Idx, r.ConfigHash)
+	}
+	return fmt.Sprintf("%s/%s/%d", r.GroupName, r.Integration, r.Idx)
+}
+
+// stateKey returns a string key for a log entry consisting of the group key
+// and receiver.
+func stateKey(k string, r *pb.Receiver) string {
+	return fmt.Sprintf("%s:%s", k, receiverKey(r))
+}
+
+// key returns the state key of the group key and receiver, which is hashed
+// if configured.
+func (l *nlog) key(k string, r *pb.Receiver) string {
+	sk := stateKey(k, r)
+	if !l.hashKeys {
+		return sk
+	}
+	h := sha256.Sum256([]byte(sk))
+	return string(h[:])
+}
+
+// retentionOf returns the retention of the entries of the receiver.
+func (l *nlog) retentionOf(r *pb.Receiver) time.Duration {
+	if l.receiverRetention != nil {
+		if d := l.receiverRetention(r); d > 0 {
+			return d
+		}
+	}
+	return l.retention
+}
+
+// sameKey returns whether both entries are for the same group key and
+// receiver. Entries with different ones only share a state key if their
+// hashes collide.
+func sameKey(a, b *pb.Entry) bool {
+	return bytes.Equal(a.GroupKey, b.GroupKey) && receiverKey(a.Receiver) == receiverKey(b.Receiver)
+}
+
+func (l *nlog) Log(r *pb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, params ...LogParam) error {
+	if l.readOnly {
+		return ErrReadOnly
+	}
+	// Write all st with the same timestamp.
+	now := l.now()
+	key := l.key(gkey, r)
+
+	e := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			Receiver:       r,
+			GroupKey:       []byte(gkey),
+			Timestamp:      now,
+			FiringAlerts:   firingAlerts,
+			ResolvedAlerts: resolvedAlerts,
+		},
+		ExpiresAt: now.Add(l.retentionOf(r)),
+	}
+	for _, p := range params {
+		p(e)
+	}
+	if l.writec != nil {
+		l.writec <- write{key: key, e: e}
+		return nil
+	}
+
+	sh := l.st.shard(key)
+	sh.mtx.Lock()
+	ok, err := l.write(sh, key, e)
+	sh.mtx.Unlock()
+
+	if err != nil || !ok {
+		return err
+	}
+	if l.gossip != nil {
+		l.gossip.GossipBroadcast(gossipData{
+			key: e,
+		})
+	}
+	l.evict()
+
+	return nil
+}
+
+// Delete implements the Log interface.
+func (l *nlog) Delete(r *pb.Receiver, gkey []byte) error {
+	if l.readOnly {
+		return ErrReadOnly
+	}
+	// Pending writes must not restore the entry.
+	l.Flush()
+
+	key := l.key(string(gkey), r)
+	sh := l.st.shard(key)
+	sh.mtx.Lock()
+
+	prev, ok := sh.st[key]
+	if !ok || prev.Entry.Deleted || !sameKey(prev.Entry, &pb.Entry{GroupKey: gkey, Receiver: r}) {
+		sh.mtx.Unlock()
+		return ErrNotFound
+	}
+	// The deletion is stored as an entry that replaces the previous one
+	// on all peers. It is kept until the previous entry expires so that
+	// peers that missed the deletion cannot share the entry again.
+	now := l.now()
+	if !now.After(prev.Entry.Timestamp) {
+		now = prev.Entry.Timestamp.Add(time.Nanosecond)
+	}
+	e := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			Receiver:  r,
+			GroupKey:  gkey,
+			Timestamp: now,
+			Deleted:   true,
+		},
+		ExpiresAt: prev.ExpiresAt,
+	}
+	sh.mutable()[key] = e
+	delete(sh.hist, key)
+	l.observe(e)
+	l.persist(gossipData{key: e})
+	sh.mtx.Unlock()
+
+	if l.gossip != nil {
+		l.gossip.GossipBroadcast(gossipData{
+			key: e,
+		})
+	}
+	return nil
+}
+
+// Acknowledge implements the Log interface.
+func (l *nlog) Acknowledge(r *pb.Receiver, gkey []byte, at time.Time) (*pb.Entry, bool, error) {
+	if l.readOnly {
+		return nil, false, ErrReadOnly
+	}
+	// The acknowledgement must not be recorded for an older notification
+	// than the pending ones.
+	l.Flush()
+
+	key := l.key(string(gkey), r)
+	sh := l.st.shard(key)
+	sh.mtx.Lock()
+
+	prev, ok := sh.st[key]
+	if !ok || prev.Entry.Deleted || !sameKey(prev.Entry, &pb.Entry{GroupKey: gkey, Receiver: r}) {
+		sh.mtx.Unlock()
+		return nil, false, ErrNotFound
+	}
+	if prev.Entry.AcknowledgedAt != nil || at.Before(prev.Entry.Timestamp) {
+		sh.mtx.Unlock()
+		return prev.Entry, false, nil
+	}
+	// The acknowledged entry replaces the previous one on all peers. Its
+	// timestamp is increased minimally so that peers consider it newer.
+	at = at.UTC()
+	ae := *prev.Entry
+	ae.Timestamp = ae.Timestamp.Add(time.Nanosecond)
+	ae.AcknowledgedAt = &at
+
+	e := &pb.MeshEntry{Entry: &ae, ExpiresAt: prev.ExpiresAt}
+	sh.mutable()[key] = e
+	l.observe(e)
+	l.persist(gossipData{key: e})
+	sh.mtx.Unlock()
+
+	if l.gossip != nil {
+		l.gossip.GossipBroadcast(gossipData{
+			key: e,
+		})
+	}
+	return &ae, true, nil
+}
+
+// isAcknowledgement returns whether e is the acknowledgement of the
+// notification of prev written by Acknowledge.
+func isAcknowledgement(prev, e *pb.Entry) bool {
+	return e.AcknowledgedAt != nil && prev.AcknowledgedAt == nil &&
+		e.Timestamp.Equal(prev.Timestamp.Add(time.Nanosecond))
+}
+
+// write stores the entry under the key in its shard and returns whether it
+// was stored. It must be called with the shard locked.
+func (l *nlog) write(sh *shard, key string, e *pb.MeshEntry) (bool, error) {
+	prevle, ok := sh.st[key]
+	if ok && !sameKey(prevle.Entry, e.Entry) {
+		l.metrics.collisionsTotal.Inc()
+		return false, ErrKeyCollision
+	}
+	if ok {
+		// Entry already exists, only overwrite if timestamp is newer.
+		// This may happen with raciness or clock-drift across AM nodes.
+		prevts := prevle.Entry.Timestamp
+		if prevts.After(e.Entry.Timestamp.Add(l.skewTolerance)) {
+			l.metrics.staleWritesTotal.Inc()
+			level.Debug(l.logger).Log("msg", "Rejecting write of outdated entry", "receiver", receiverKey(e.Entry.Receiver), "timestamp", e.Entry.Timestamp, "existing", prevts)
+			return false, nil
+		}
+		if prevts.After(e.Entry.Timestamp) {
+			e.Entry.Timestamp = prevts.Add(time.Nanosecond)
+		}
+		l.addHistory(sh, key, prevle.Entry)
+	}
+	sh.mutable()[key] = e
+	l.observe(e)
+	l.count(e.Entry)
+	l.persist(gossipData{key: e})
+
+	return true, nil
+}
+
+// runWrites applies asynchronous writes. All writes pending at once are
+// applied together and shared with peers in one broadcast.
+func (l *nlog) runWrites() {
+	for w := range l.writec {
+		batch := []write{w}
+	pending:
+		for len(batch) < cap(l.writec) {
+			select {
+			case w := <-l.writec:
+				batch = append(batch, w)
+			default:
+				break pending
+			}
+		}
+		l.applyWrites(batch)
+	}
+}
+
+func (l *nlog) applyWrites(batch []write) {
+	var (
+		delta   = gossipData{}
+		flushed []chan struct{}
+	)
+	for _, w := range batch {
+		if w.e == nil {
+			flushed = append(flushed, w.flushed)
+			continue
+		}
+		sh := l.st.shard(w.key)
+		sh.mtx.Lock()
+		ok, err := l.write(sh, w.key, w.e)
+		sh.mtx.Unlock()
+
+		if err != nil {
+			level.Warn(l.logger).Log("msg", "Writing notification log entry failed", "receiver", receiverKey(w.e.Entry.Receiver), "err", err)
+			continue
+		}
+		if ok {
+			delta[w.key] = w.e
+		}
+	}
+	for _, k := range l.evict() {
+		delete(delta, k)
+	}
+
+	l.metrics.writeBatchSize.Observe(float64(len(batch) - len(flushed)))
+	if l.gossip != nil && len(delta) > 0 {
+		l.gossip.GossipBroadcast(delta)
+	}
+	for _, c := range flushed {
+		close(c)
+	}
+}
+
+// Flush implements the Log interface.
+func (l *nlog) Flush() {
+	if l.writec == nil {
+		return
+	}
+	c := make(chan struct{})
+	l.writec <- write{flushed: c}
+	<-c
+}
+
+// evict removes the oldest entries until the log is within the maximum
+// number of entries and returns their keys. It must be called without any
+// shard locked.
+func (l *nlog) evict() []string {
+	if l.maxEntries == 0 || l.st.len() <= l.maxEntries {
+		return nil
+	}
+	l.st.lock()
+	defer l.st.unlock()
+
+	type keyed struct {
+		sh  *shard
+		key string
+		ts  time.Time
+	}
+	var entries []keyed
+	for _, sh := range l.st {
+		for k, e := range sh.st {
+			entries = append(entries, keyed{sh: sh, key: k, ts: e.Entry.Timestamp})
+		}
+	}
+	// Concurrent writes may have evicted entries already.
+	if len(entries) <= l.maxEntries {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ts.Before(entries[j].ts)
+	})
+	entries = entries[:len(entries)-l.maxEntries]
+
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		delete(e.sh.mutable(), e.key)
+		delete(e.sh.hist, e.key)
+		keys = append(keys, e.key)
+	}
+	l.persist(nil, keys...)
+	l.metrics.evictedTotal.Add(float64(len(keys)))
+	return keys
+}
+
+// observe passes the entry to all observers. Must be called with the
+// entry's shard locked.
+func (l *nlog) observe(e *pb.MeshEntry) {
+	for _, f := range l.observers {
+		f(e)
+	}
+}
+
+// GC implements the Log interface.
+func (l *nlog) GC() (int, error) {
 	start := time.Now()
 	defer func() { l.metrics.gcDuration.Observe(time.Since(start).Seconds()) }()
 
-	now := l.now()
-	var n int
+	now := l.now()
+	var n int
+
+	gc := func(sh *shard) error {
+		sh.mtx.Lock()
+		defer sh.mtx.Unlock()
+
+		var deleted []string
+		defer func() { l.persist(nil, deleted...) }()
+
+		st := sh.mutable()
+		for k, le := range st {
+			if le.ExpiresAt.IsZero() {
+				return errors.New("unexpected zero expiration timestamp")
+			}
+			if !le.ExpiresAt.After(now) {
+				delete(st, k)
+				delete(sh.hist, k)
+				deleted = append(deleted, k)
+				n++
+			}
+		}
+		return nil
+	}
+	for _, sh := range l.st {
+		if err := gc(sh); err != nil {
+			return n, err
+		}
+	}
+	l.gcStats(now)
+
+	return n, nil
+}
+
+// Notifications are counted in buckets of statsBucket covering the last
+// statsWindow.
+const (
+	statsBucket  = 10 * time.Minute
+	statsWindow  = 24 * time.Hour
+	statsBuckets = int64(statsWindow / statsBucket)
+)
+
+// ReceiverStats are the number of notifications logged for a receiver
+// integration.
+type ReceiverStats struct {
+	Receiver    string
+	Integration string
+	LastHour    uint64
+	LastDay     uint64
+}
+
+// statsKey identifies the receiver integration notifications are counted for.
+type statsKey struct {
+	name, integration string
+}
+
+// receiverCounts is a ring of notification counts per bucket.
+type receiverCounts struct {
+	counts [statsBuckets]uint64
+	// The most recent bucket counted into.
+	last int64
+}
+
+func statsBucketOf(t time.Time) int64 {
+	return t.Unix() / int64(statsBucket/time.Second)
+}
+
+func (c *receiverCounts) add(t time.Time) {
+	b := statsBucketOf(t)
+	if b <= c.last-statsBuckets {
+		return
+	}
+	if b > c.last {
+		// Reset the buckets skipped since the last notification.
+		for i := c.last + 1; i <= b && i <= c.last+statsBuckets; i++ {
+			c.counts[i%statsBuckets] = 0
+		}
+		c.last = b
+	}
+	c.counts[b%statsBuckets]++
+}
+
+// sum returns the number of notifications within d before now.
+func (c *receiverCounts) sum(now time.Time, d time.Duration) uint64 {
+	var (
+		b   = statsBucketOf(now)
+		n   uint64
+		num = int64(d / statsBucket)
+	)
+	for i := b - num + 1; i <= b; i++ {
+		if i > c.last || i <= c.last-statsBuckets {
+			continue
+		}
+		n += c.counts[i%statsBuckets]
+	}
+	return n
+}
+
+// count adds the logged entry to the notification statistics.
+func (l *nlog) count(e *pb.Entry) {
+	k := statsKey{name: e.Receiver.GroupName, integration: e.Receiver.Integration}
+
+	l.statsMtx.Lock()
+	defer l.statsMtx.Unlock()
+
+	if l.stats == nil {
+		l.stats = map[statsKey]*receiverCounts{}
+	}
+	c, ok := l.stats[k]
+	if !ok {
+		c = &receiverCounts{}
+		l.stats[k] = c
+	}
+	c.add(e.Timestamp)
+}
+
+// gcStats removes receivers without notifications within the statistics
+// window.
+func (l *nlog) gcStats(now time.Time) {
+	l.statsMtx.Lock()
+	defer l.statsMtx.Unlock()
+
+	for k, c := range l.stats {
+		if c.last <= statsBucketOf(now)-statsBuckets {
+			delete(l.stats, k)
+		}
+	}
+}
+
+// Stats implements the Log interface.
+func (l *nlog) Stats() []ReceiverStats {
+	now := l.now()
+
+	l.statsMtx.Lock()
+	defer l.statsMtx.Unlock()
+
+	res := make([]ReceiverStats, 0, len(l.stats))
+	for k, c := range l.stats {
+		res = append(res, ReceiverStats{
+			Receiver:    k.name,
+			Integration: k.integration,
+			LastHour:    c.sum(now, time.Hour),
+			LastDay:     c.sum(now, statsWindow),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Receiver != res[j].Receiver {
+			return res[i].Receiver < res[j].Receiver
+		}
+		return res[i].Integration < res[j].Integration
+	})
+	return res
+}
+
+var receiverNotificationsDesc = prometheus.NewDesc(
+	"alertmanager_nflog_receiver_notifications",
+	"Number of notifications logged for a receiver integration within the window.",
+	[]string{"receiver", "integration", "window"}, nil,
+)
+
+// statsCollector exposes the notification statistics of a log.
+type statsCollector struct {
+	l *nlog
+}
+
+// Describe implements prometheus.Collector.
+func (c statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- receiverNotificationsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c statsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.l.Stats() {
+		ch <- prometheus.MustNewConstMetric(receiverNotificationsDesc, prometheus.GaugeValue, float64(s.LastHour), s.Receiver, s.Integration, "1h")
+		ch <- prometheus.MustNewConstMetric(receiverNotificationsDesc, prometheus.GaugeValue, float64(s.LastDay), s.Receiver, s.Integration, "24h")
+	}
+}
+
+// addHistory adds a replaced entry to the history of the state key in its
+// shard. It must be called with the shard locked.
+func (l *nlog) addHistory(sh *shard, key string, e *pb.Entry) {
+	if l.history == 0 || e.Deleted {
+		return
+	}
+	h := append(sh.hist[key], e)
+	if len(h) > l.history {
+		h = append(h[:0:0], h[len(h)-l.history:]...)
+	}
+	sh.hist[key] = h
+}
+
+// merge merges the gossip data into the log state and returns the entries
+// that changed. It must be called without any shard locked.
+func (l *nlog) merge(gd gossipData) gossipData {
+	delta := gossipData{}
+	for sh, sgd := range l.st.split(gd) {
+		sh.mtx.Lock()
+		l.mergeShard(sh, sgd, delta)
+		sh.mtx.Unlock()
+	}
+	// Entries evicted right away are not passed on to other peers.
+	for _, k := range l.evict() {
+		delete(delta, k)
+	}
+	return delta
+}
+
+// mergeShard merges the gossip data of a shard into it and adds the entries
+// that changed to delta. It must be called with the shard locked.
+func (l *nlog) mergeShard(sh *shard, gd, delta gossipData) {
+	st := sh.mutable()
+
+	for k, e := range gd {
+		if pe, ok := st[k]; ok && !sameKey(pe.Entry, e.Entry) {
+			delete(gd, k)
+			l.metrics.collisionsTotal.Inc()
+		}
+	}
+
+	var prev map[string]*pb.MeshEntry
+	if l.history > 0 {
+		prev = map[string]*pb.MeshEntry{}
+		for k := range gd {
+			if e, ok := st[k]; ok {
+				prev[k] = e
+			}
+		}
+	}
+	changed := st.mergeDelta(gd)
+	for k, e := range changed {
+		if e.Entry.Deleted {
+			delete(sh.hist, k)
+		} else if pe, ok := prev[k]; ok && !isAcknowledgement(pe.Entry, e.Entry) {
+			l.addHistory(sh, k, pe.Entry)
+		}
+		l.observe(e)
+		delta[k] = e
+	}
+	l.persist(changed)
+}
+
+// Merge implements the Log interface.
+func (l *nlog) Merge(entries ...*pb.MeshEntry) (int, error) {
+	if l.readOnly {
+		return 0, ErrReadOnly
+	}
+	gd := make(gossipData, len(entries))
+	for _, e := range entries {
+		if e.Entry == nil || e.Entry.Receiver == nil {
+			return 0, errors.New("entry without receiver")
+		}
+		gd[l.key(string(e.Entry.GroupKey), e.Entry.Receiver)] = e
+	}
+
+	delta := l.merge(gd)
+	if l.gossip != nil && len(delta) > 0 {
+		l.gossip.GossipBroadcast(delta)
+	}
+	return len(delta), nil
+}
+
+// Query implements the Log interface.
+func (l *nlog) Query(params ...QueryParam) ([]*pb.Entry, error) {
+	start := time.Now()
+	l.metrics.queriesTotal.Inc()
+
+	entries, err := func() ([]*pb.Entry, error) {
+		q := &query{}
+		for _, p := range params {
+			if err := p(q); err != nil {
+				return nil, err
+			}
+		}
+		if q.recv == nil {
+			// TODO(fabxc): allow more complex queries in the future.
+			return nil, errors.New("no query parameters specified")
+		}
+
+		if q.groupKey != "" {
+			key := l.key(q.groupKey, q.recv)
+			sh := l.st.shard(key)
+			sh.mtx.RLock()
+			defer sh.mtx.RUnlock()
+
+			if le, ok := sh.st[key]; ok && !le.Entry.Deleted && sameKey(le.Entry, &pb.Entry{GroupKey: []byte(q.groupKey), Receiver: q.recv}) {
+				res := make([]*pb.Entry, 0, len(sh.hist[key])+1)
+				res = append(res, sh.hist[key]...)
+				if res = q.filter(append(res, le.Entry)); len(res) > 0 {
+					return q.page(res), nil
+				}
+			}
+			return nil, ErrNotFound
+		}
+
+		var (
+			recv = receiverKey(q.recv)
+			res  []*pb.Entry
+		)
+		for _, sh := range l.st {
+			sh.mtx.RLock()
+			for key, le := range sh.st {
+				if le.Entry.Deleted || receiverKey(le.Entry.Receiver) != recv {
+					continue
+				}
+				res = append(res, sh.hist[key]...)
+				res = append(res, le.Entry)
+			}
+			sh.mtx.RUnlock()
+		}
+		if res = q.filter(res); len(res) == 0 {
+			return nil, ErrNotFound
+		}
+		sort.SliceStable(res, func(i, j int) bool {
+			if !res[i].Timestamp.Equal(res[j].Timestamp) {
+				return res[i].Timestamp.Before(res[j].Timestamp)
+			}
+			return bytes.Compare(res[i].GroupKey, res[j].GroupKey) < 0
+		})
+		return q.page(res), nil
+	}()
+	if err != nil {
+		l.metrics.queryErrorsTotal.Inc()
+	}
+	l.metrics.queryDuration.Observe(time.Since(start).Seconds())
+	return entries, err
+}
+
+// QueryOne implements the Log interface.
+func (l *nlog) QueryOne(params ...QueryParam) (*pb.Entry, error) {
+	entries, err := l.Query(params...)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNotFound
+	}
+	return entries[len(entries)-1], nil
+}
+
+// EntryIterator iterates over the entries of a query result.
+type EntryIterator interface {
+	// Next advances the iterator and returns false if there are no
+	// more entries.
+	Next() bool
+	// At returns the current entry. It must not be modified.
+	At() *pb.Entry
+}
+
+// QueryIter implements the Log interface. The iterator reads from a view
+// of the log state at the time of the call and does not block writers.
+func (l *nlog) QueryIter(params ...QueryParam) (EntryIterator, error) {
+	l.metrics.queriesTotal.Inc()
+
+	q := &query{}
+	for _, p := range params {
+		if err := p(q); err != nil {
+			l.metrics.queryErrorsTotal.Inc()
+			return nil, err
+		}
+	}
+	it := &entryIterator{
+		v:        l.View(),
+		q:        q,
+		groupKey: q.groupKey,
+		offset:   q.offset,
+		limit:    q.limit,
+	}
+	if q.recv != nil {
+		it.recv = receiverKey(q.recv)
+	}
+
+	// Only the keys are materialized. Entries are looked up and filtered
+	// as the iterator advances.
+	if q.recv != nil && q.groupKey != "" {
+		if k := l.key(q.groupKey, q.recv); it.v.get(k) != nil {
+			it.keys = []string{k}
+		}
+		return it, nil
+	}
+	it.keys = make([]string, 0, it.v.Len())
+	for _, st := range it.v.st {
+		for k := range st {
+			it.keys = append(it.keys, k)
+		}
+	}
+	sort.Strings(it.keys)
+	return it, nil
+}
+
+type entryIterator struct {
+	v        *View
+	q        *query
+	keys     []string
+	groupKey string
+	recv     string
+	offset   int
+	limit    int
+	// n is the number of entries returned so far.
+	n   int
+	cur *pb.Entry
+}
+
+func (it *entryIterator) Next() bool {
+	for len(it.keys) > 0 && (it.limit == 0 || it.n < it.limit) {
+		e := it.v.get(it.keys[0]).Entry
+		it.keys = it.keys[1:]
+
+		if e.Deleted {
+			continue
+		}
+		if it.groupKey != "" && string(e.GroupKey) != it.groupKey {
+			continue
+		}
+		if it.recv != "" && receiverKey(e.Receiver) != it.recv {
+			continue
+		}
+		if !it.q.matches(e) {
+			continue
+		}
+		if it.offset > 0 {
+			it.offset--
+			continue
+		}
+		it.n++
+		it.cur = e
+		return true
+	}
+	it.cur = nil
+	return false
+}
+
+func (it *entryIterator) At() *pb.Entry {
+	return it.cur
+}
+
+// loadSnapshots loads the state from the snapshot file. If the file is
+// missing or corrupted, rotated snapshots are tried in order. It returns
+// whether a snapshot was loaded.
+func (l *nlog) loadSnapshots() (bool, error) {
+	var lastErr error
+
+	for i := 0; i <= l.snapshotRotations; i++ {
+		fn := rotatedName(l.snapf, i)
+
+		f, err := os.Open(fn)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		st, sum, err := l.readSnapshot(f)
+		f.Close()
+
+		if err == nil {
+			if i > 0 {
+				level.Warn(l.logger).Log("msg", "Restored state from previous snapshot", "file", fn)
+			} else if err := l.replayIncrementals(st, sum); err != nil {
+				return false, err
+			}
+			l.st.replace(st)
+			l.evict()
+			return true, nil
+		}
+		if _, ok := err.(*SnapshotError); !ok {
+			return false, err
+		}
+		if l.snapshotRotations > 0 {
+			level.Warn(l.logger).Log("msg", "Loading snapshot failed", "file", fn, "err", err)
+		}
+		lastErr = err
+	}
+	return false, lastErr
+}
+
+// loadStoreSnapshot loads the snapshot from the snapshot store. It is not
+// an error if the store has none.
+func (l *nlog) loadStoreSnapshot() error {
+	r, err := l.store.Get(l.storeName)
+	if err == os.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fetching snapshot from store: %s", err)
+	}
+	defer r.Close()
+
+	level.Info(l.logger).Log("msg", "Loading snapshot from store", "name", l.storeName)
+	return l.loadSnapshot(r)
+}
+
+// loadSnapshot loads a snapshot generated by Snapshot() into the state.
+// Failures are reported as *SnapshotError and leave the state unchanged.
+func (l *nlog) loadSnapshot(r io.Reader) error {
+	st, _, err := l.readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	l.st.replace(st)
+	l.evict()
 
-	l.mtx.Lock()
-	defer l.mtx.Unlock()
+	return nil
+}
 
-	for k, le := range l.st {
-		if le.ExpiresAt.IsZero() {
-			return n, errors.New("unexpected zero expiration timestamp")
-		}
-		if !le.ExpiresAt.After(now) {
-			delete(l.st, k)
-			n++
-		}
+// readSnapshot decodes a snapshot generated by Snapshot(). It returns its
+// entries and the checksum incremental snapshots on top of it refer to.
+// Failures are reported as *SnapshotError.
+func (l *nlog) readSnapshot(r io.Reader) (gossipData, uint32, error) {
+	zr, err := snapshot.NewReader(r)
+	if err != nil {
+		return nil, 0, &SnapshotError{Kind: ErrSnapshotCorrupt, Err: err}
 	}
+	br := bufio.NewReader(zr)
 
-	return n, nil
+	off, _, codec, err := readSnapshotHeader(br)
+	if err != nil {
+		return nil, 0, err
+	}
+	cr := snapshot.NewChecksumReader(br)
+	st, err := l.readEntries(cr, codec, off)
+	if err != nil {
+		return nil, 0, err
+	}
+	return st, cr.Sum32(), nil
 }
 
-// Query implements the Log interface.
-func (l *nlog) Query(params ...QueryParam) ([]*pb.Entry, error) {
-	start := time.Now()
-	l.metrics.queriesTotal.Inc()
+// replayIncrementals applies the chain of incremental snapshots written on
+// top of the full snapshot with the given checksum to its entries. The
+// chain ends at the first missing incremental snapshot. Incremental
+// snapshots that are corrupted or belong to another full snapshot end it as
+// well, the entries changed from then on are lost.
+func (l *nlog) replayIncrementals(st gossipData, sum uint32) error {
+	for i := 1; ; i++ {
+		fn := incrementalName(l.snapf, i)
+
+		f, err := os.Open(fn)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		base, changed, err := l.readIncremental(f)
+		f.Close()
 
-	entries, err := func() ([]*pb.Entry, error) {
-		q := &query{}
-		for _, p := range params {
-			if err := p(q); err != nil {
-				return nil, err
+		if err == nil && base != sum {
+			err = &SnapshotError{Kind: ErrSnapshotCorrupt, Err: errors.New("incremental snapshot of another snapshot")}
+		}
+		if err != nil {
+			if _, ok := err.(*SnapshotError); !ok {
+				return err
 			}
+			level.Warn(l.logger).Log("msg", "Loading incremental snapshot failed", "file", fn, "err", err)
+			return nil
 		}
-		// TODO(fabxc): For now our only query mode is the most recent entry for a
-		// receiver/group_key combination.
-		if q.recv == nil || q.groupKey == "" {
-			// TODO(fabxc): allow more complex queries in the future.
-			// How to enable pagination?
-			return nil, errors.New("no query parameters specified")
+		for k, e := range changed {
+			st[k] = e
 		}
+	}
+}
 
-		l.mtx.RLock()
-		defer l.mtx.RUnlock()
+// readIncremental decodes an incremental snapshot. It returns the checksum
+// of the full snapshot it was written on top of and the changed entries.
+func (l *nlog) readIncremental(r io.Reader) (uint32, gossipData, error) {
+	zr, err := snapshot.NewReader(r)
+	if err != nil {
+		return 0, nil, &SnapshotError{Kind: ErrSnapshotCorrupt, Err: err}
+	}
+	br := bufio.NewReader(zr)
 
-		if le, ok := l.st[stateKey(q.groupKey, q.recv)]; ok {
-			return []*pb.Entry{le.Entry}, nil
+	var hdr [4]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil || !bytes.Equal(hdr[:], incrementalMagic) {
+		return 0, nil, &SnapshotError{Kind: ErrSnapshotCorrupt, Err: errors.New("not an incremental snapshot")}
+	}
+	v, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, nil, &SnapshotError{Kind: ErrSnapshotCorrupt, Err: fmt.Errorf("truncated header")}
+	}
+	if v != incrementalVersion {
+		return 0, nil, &SnapshotError{
+			Kind: ErrSnapshotVersion,
+			Err:  fmt.Errorf("incremental version %d, expected %d", v, incrementalVersion),
 		}
-		return nil, ErrNotFound
-	}()
+	}
+	var base [4]byte
+	if _, err := io.ReadFull(br, base[:]); err != nil {
+		return 0, nil, &SnapshotError{Kind: ErrSnapshotCorrupt, Err: fmt.Errorf("truncated header")}
+	}
+	off := int64(len(incrementalMagic) + uvarintSize(v) + len(base))
+
+	st, err := l.readEntries(br, snapshot.ProtoCodec{}, off)
 	if err != nil {
-		l.metrics.queryErrorsTotal.Inc()
+		return 0, nil, err
 	}
-	l.metrics.queryDuration.Observe(time.Since(start).Seconds())
-	return entries, err
+	return binary.BigEndian.Uint32(base[:]), st, nil
 }
 
-// loadSnapshot loads a snapshot generated by Snapshot() into the state.
-func (l *nlog) loadSnapshot(r io.Reader) error {
-	l.mtx.Lock()
-	defer l.mtx.Unlock()
-
-	st := gossipData{}
-
+// readEntries decodes the entries of a snapshot encoded with the codec
+// following its header of off bytes.
+func (l *nlog) readEntries(r snapshot.ByteReader, c snapshot.Codec, off int64) (gossipData, error) {
+	var (
+		st  = gossipData{}
+		dec = c.NewDecoder(r)
+	)
 	for {
 		var e pb.MeshEntry
-		if _, err := pbutil.ReadDelimited(r, &e); err != nil {
-			if err == io.EOF {
-				break
+		n, err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*snapshot.EntryError); ok {
+				return nil, &SnapshotError{Kind: ErrEntryDecode, Offset: off, Err: err}
 			}
-			return err
+			return nil, &SnapshotError{Kind: ErrSnapshotCorrupt, Offset: off, Err: err}
+		}
+		if e.Entry == nil {
+			return nil, &SnapshotError{Kind: ErrEntryDecode, Offset: off, Err: errors.New("missing entry")}
+		}
+		k := l.key(string(e.Entry.GroupKey), e.Entry.Receiver)
+		if pe, ok := st[k]; ok && !sameKey(pe.Entry, e.Entry) {
+			l.metrics.collisionsTotal.Inc()
+		} else {
+			st[k] = &e
 		}
-		st[stateKey(string(e.Entry.GroupKey), e.Entry.Receiver)] = &e
+
+		off += int64(n)
 	}
-	l.st = st
+	return st, nil
+}
 
-	return nil
+// readSnapshotHeader consumes the snapshot header and returns its size, the
+// format version and the codec of the entries.
+func readSnapshotHeader(br *bufio.Reader) (int64, uint64, snapshot.Codec, error) {
+	h, ok, err := snapshot.ReadHeader(br, snapshotMagic)
+	if err != nil {
+		return 0, 0, nil, &SnapshotError{Kind: ErrSnapshotCorrupt, Err: err}
+	}
+	if !ok {
+		return 0, 0, snapshot.ProtoCodec{Unchecksummed: true}, nil
+	}
+	if h.Version < 1 || h.Version > snapshotVersion {
+		return 0, 0, nil, &SnapshotError{
+			Kind: ErrSnapshotVersion,
+			Err:  fmt.Errorf("version %d, expected at most %d", h.Version, snapshotVersion),
+		}
+	}
+	if _, ok := h.Codec.(snapshot.ProtoCodec); ok && h.Version < 2 {
+		h.Codec = snapshot.ProtoCodec{Unchecksummed: true}
+	}
+	return int64(h.Size), h.Version, h.Codec, nil
+}
+
+// writeIncrementalHeader writes the header of incremental snapshots written
+// on top of the full snapshot with the given checksum.
+func writeIncrementalHeader(w io.Writer, base uint32) error {
+	var buf [binary.MaxVarintLen64 + 4]byte
+	n := binary.PutUvarint(buf[:], incrementalVersion)
+	binary.BigEndian.PutUint32(buf[n:], base)
+
+	if _, err := w.Write(incrementalMagic); err != nil {
+		return err
+	}
+	_, err := w.Write(buf[:n+4])
+	return err
+}
+
+// prune removes the entries expiring soonest until a snapshot of the
+// remaining entries does not exceed max bytes. It returns the number of
+// removed entries.
+func (l *nlog) prune(max int64) int {
+	l.st.lock()
+	defer l.st.unlock()
+
+	type keyed struct {
+		sh  *shard
+		key string
+		e   *pb.MeshEntry
+	}
+	var (
+		size    int64
+		entries []keyed
+	)
+	for _, sh := range l.st {
+		for k, e := range sh.st {
+			size += entrySize(e)
+			entries = append(entries, keyed{sh: sh, key: k, e: e})
+		}
+	}
+	if size <= max {
+		return 0
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].e.ExpiresAt.Before(entries[j].e.ExpiresAt)
+	})
+
+	var pruned []string
+	for _, e := range entries {
+		if size <= max {
+			break
+		}
+		size -= entrySize(e.e)
+		delete(e.sh.mutable(), e.key)
+		delete(e.sh.hist, e.key)
+		pruned = append(pruned, e.key)
+	}
+	l.persist(nil, pruned...)
+	return len(pruned)
+}
+
+// entrySize returns the number of bytes the entry occupies in a snapshot.
+func entrySize(e *pb.MeshEntry) int64 {
+	n := e.Size()
+	return int64(uvarintSize(uint64(n)) + n)
+}
+
+// uvarintSize returns the number of bytes x occupies when varint encoded.
+func uvarintSize(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
 }
 
 // Snapshot implements the Log interface.
 func (l *nlog) Snapshot(w io.Writer) (int, error) {
+	// Write from a view so that slow writers do not block the log.
+	n, _, err := l.snapshot(w, l.View())
+	return n, err
+}
+
+// snapshot writes a full snapshot of the view to w. It returns the number
+// of bytes written and the checksum incremental snapshots refer to.
+func (l *nlog) snapshot(w io.Writer, v *View) (int, uint32, error) {
 	start := time.Now()
 	defer func() { l.metrics.snapshotDuration.Observe(time.Since(start).Seconds()) }()
 
-	l.mtx.RLock()
-	defer l.mtx.RUnlock()
+	sw, err := snapshot.NewWriter(w, l.compression)
+	if err != nil {
+		return 0, 0, err
+	}
+	codec := l.codec
+	if codec == nil {
+		codec = snapshot.ProtoCodec{}
+	}
+	if err := snapshot.WriteHeader(sw, snapshotMagic, snapshotVersion, codec); err != nil {
+		return sw.Written(), 0, err
+	}
+	sum, err := l.writeEntries(sw, codec, v, nil)
+	if err != nil {
+		return sw.Written(), 0, err
+	}
+	err = sw.Close()
+	return sw.Written(), sum, err
+}
 
-	var n int
-	for _, e := range l.st {
-		m, err := pbutil.WriteDelimited(w, e)
-		if err != nil {
-			return n + m, err
+// writeEntries encodes the entries of the view that differ from the ones
+// of prev with the codec. All entries are written if prev is nil. It
+// returns the checksum of the written data.
+func (l *nlog) writeEntries(w io.Writer, c snapshot.Codec, v, prev *View) (uint32, error) {
+	var (
+		cw  = snapshot.NewChecksumWriter(w)
+		enc = c.NewEncoder(cw)
+	)
+	for _, st := range v.st {
+		for k, e := range st {
+			// Entries are never modified in place, so unchanged entries
+			// are the same in both views.
+			if prev != nil && prev.get(k) == e {
+				continue
+			}
+			if err := enc.Encode(e); err != nil {
+				return 0, err
+			}
 		}
-		n += m
 	}
-	return n, nil
+	if err := enc.Close(); err != nil {
+		return 0, err
+	}
+	return cw.Sum32(), nil
+}
+
+// View is a point-in-time view of the log state. Reading it does not block
+// writers to the log. The returned entries must not be modified.
+type View struct {
+	// The state of each shard of the log.
+	st []gossipData
+}
+
+// View implements the Log interface.
+func (l *nlog) View() *View {
+	l.st.lock()
+	defer l.st.unlock()
+
+	v := &View{st: make([]gossipData, len(l.st))}
+	for i, sh := range l.st {
+		sh.shared = true
+		v.st[i] = sh.st
+	}
+	return v
+}
+
+// get returns the entry stored under the state key or nil.
+func (v *View) get(key string) *pb.MeshEntry {
+	return v.st[shardIndex(key, len(v.st))][key]
+}
+
+// Len returns the number of entries in the view.
+func (v *View) Len() int {
+	n := 0
+	for _, st := range v.st {
+		n += len(st)
+	}
+	return n
+}
+
+// MeshEntries returns the entries in the view along with the times they
+// expire at. Unlike Range it includes the entries marking deletions.
+func (v *View) MeshEntries() []*pb.MeshEntry {
+	res := make([]*pb.MeshEntry, 0, v.Len())
+	for _, st := range v.st {
+		for _, e := range st {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Range calls f for each entry in the view in no particular order until f
+// returns false. Deleted entries are skipped.
+func (v *View) Range(f func(*pb.Entry) bool) {
+	for _, st := range v.st {
+		for _, e := range st {
+			if e.Entry.Deleted {
+				continue
+			}
+			if !f(e.Entry) {
+				return
+			}
+		}
+	}
+}
+
+// defaultShards is the number of shards the log state is split into unless
+// configured otherwise.
+const defaultShards = 16
+
+// state is the log state split into shards by state key.
+type state []*shard
+
+// shard holds the entries of a part of the state keys.
+type shard struct {
+	mtx sync.RWMutex
+	st  gossipData
+	// shared is set if st is referenced by a view and must be copied
+	// before it is modified.
+	shared bool
+	// Previous entries by state key, oldest first.
+	hist map[string][]*pb.Entry
+}
+
+// newState returns a state of n shards holding the entries of gd.
+func newState(n int, gd gossipData) state {
+	s := make(state, n)
+	for i := range s {
+		s[i] = &shard{st: gossipData{}, hist: map[string][]*pb.Entry{}}
+	}
+	for k, e := range gd {
+		s.shard(k).st[k] = e
+	}
+	return s
+}
+
+// shardIndex returns the index of the shard of n holding the state key.
+func shardIndex(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// shard returns the shard holding the state key.
+func (s state) shard(key string) *shard {
+	return s[shardIndex(key, len(s))]
+}
+
+// split returns the entries of gd by the shard holding them.
+func (s state) split(gd gossipData) map[*shard]gossipData {
+	res := map[*shard]gossipData{}
+	for k, e := range gd {
+		sh := s.shard(k)
+		if res[sh] == nil {
+			res[sh] = gossipData{}
+		}
+		res[sh][k] = e
+	}
+	return res
+}
+
+// lock locks all shards in order.
+func (s state) lock() {
+	for _, sh := range s {
+		sh.mtx.Lock()
+	}
+}
+
+func (s state) unlock() {
+	for _, sh := range s {
+		sh.mtx.Unlock()
+	}
+}
+
+// len returns the number of entries.
+func (s state) len() int {
+	n := 0
+	for _, sh := range s {
+		sh.mtx.RLock()
+		n += len(sh.st)
+		sh.mtx.RUnlock()
+	}
+	return n
+}
+
+// all returns a copy of all entries.
+func (s state) all() gossipData {
+	gd := gossipData{}
+	for _, sh := range s {
+		sh.mtx.RLock()
+		for k, e := range sh.st {
+			gd[k] = e
+		}
+		sh.mtx.RUnlock()
+	}
+	return gd
+}
+
+// replace replaces all entries and their history with the entries of gd.
+func (s state) replace(gd gossipData) {
+	s.lock()
+	defer s.unlock()
+
+	for _, sh := range s {
+		sh.st = gossipData{}
+		sh.shared = false
+		sh.hist = map[string][]*pb.Entry{}
+	}
+	for k, e := range gd {
+		s.shard(k).st[k] = e
+	}
+}
+
+// mutable returns the entries of the shard for modification. They are
+// copied first if a view references them. It must be called with the shard
+// locked.
+func (sh *shard) mutable() gossipData {
+	if sh.shared {
+		sh.st = sh.st.clone()
+		sh.shared = false
+	}
+	return sh.st
 }
 
 // Gossip implements the mesh.Gossiper interface.
 func (l *nlog) Gossip() mesh.GossipData {
-	l.mtx.RLock()
-	defer l.mtx.RUnlock()
+	if l.readOnly {
+		return nil
+	}
+	return l.st.all()
+}
 
-	gd := make(gossipData, len(l.st))
-	for k, v := range l.st {
-		gd[k] = v
+// decode decodes gossip data received from a peer. Invalid entries are
+// dropped.
+func (l *nlog) decode(msg []byte) (gossipData, error) {
+	gd, invalid, err := decodeGossipData(msg, l.key)
+	if invalid > 0 {
+		level.Warn(l.logger).Log("msg", "Dropping invalid entries received from peer", "count", invalid)
+		l.metrics.invalidTotal.Add(float64(invalid))
 	}
-	return gd
+	return gd, err
 }
 
 // OnGossip implements the mesh.Gossiper interface.
 func (l *nlog) OnGossip(msg []byte) (mesh.GossipData, error) {
-	gd, err := decodeGossipData(msg)
+	gd, err := l.decode(msg)
 	if err != nil {
 		return nil, err
 	}
-	l.mtx.Lock()
-	defer l.mtx.Unlock()
-
-	if delta := l.st.mergeDelta(gd); len(delta) > 0 {
+	if delta := l.merge(gd); len(delta) > 0 {
 		return delta, nil
 	}
 	return nil, nil
@@ -499,41 +2370,156 @@ func (l *nlog) OnGossip(msg []byte) (mesh.GossipData, error) {
 
 // OnGossipBroadcast implements the mesh.Gossiper interface.
 func (l *nlog) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
-	gd, err := decodeGossipData(msg)
+	gd, err := l.decode(msg)
 	if err != nil {
 		return nil, err
 	}
-	l.mtx.Lock()
-	defer l.mtx.Unlock()
+	return l.merge(gd), nil
+}
+
+// Types of unicast messages. Repair requests hold entries that only
+// identify the requested entry and the version known to the requester by
+// their timestamp. Responses hold the requested entries like other gossip.
+const (
+	// unicastRepairKeys requests newer versions of the given entries.
+	unicastRepairKeys byte = iota + 1
+	// unicastRepairDigest additionally requests all entries missing from
+	// the given ones, which are the complete state of the requester.
+	unicastRepairDigest
+	unicastRepairResponse
+)
+
+// Repair implements the Log interface.
+func (l *nlog) Repair(peer mesh.PeerName, keys ...RepairKey) error {
+	if l.gossip == nil {
+		return errors.New("gossip is not configured")
+	}
+	var (
+		typ   = unicastRepairKeys
+		stubs []*pb.MeshEntry
+	)
+	stub := func(gkey string, r *pb.Receiver, ts time.Time) *pb.MeshEntry {
+		return &pb.MeshEntry{Entry: &pb.Entry{GroupKey: []byte(gkey), Receiver: r, Timestamp: ts}}
+	}
+
+	if len(keys) == 0 {
+		typ = unicastRepairDigest
+		v := l.View()
+		for _, st := range v.st {
+			for _, e := range st {
+				stubs = append(stubs, stub(string(e.Entry.GroupKey), e.Entry.Receiver, e.Entry.Timestamp))
+			}
+		}
+	}
+	for _, k := range keys {
+		var (
+			ts  time.Time
+			key = l.key(k.GroupKey, k.Receiver)
+			sh  = l.st.shard(key)
+		)
+		sh.mtx.RLock()
+		if e, ok := sh.st[key]; ok && sameKey(e.Entry, &pb.Entry{GroupKey: []byte(k.GroupKey), Receiver: k.Receiver}) {
+			ts = e.Entry.Timestamp
+		}
+		sh.mtx.RUnlock()
+		stubs = append(stubs, stub(k.GroupKey, k.Receiver, ts))
+	}
 
-	return l.st.mergeDelta(gd), nil
+	// Unlike responses, requests are not split as a digest must describe
+	// the complete state.
+	buf := bytes.NewBuffer([]byte{typ})
+	for _, e := range stubs {
+		if _, err := pbutil.WriteDelimited(buf, e); err != nil {
+			return err
+		}
+	}
+	return l.gossip.GossipUnicast(peer, buf.Bytes())
 }
 
-// OnGossipUnicast implements the mesh.Gossiper interface.
+// OnGossipUnicast implements the mesh.Gossiper interface. It answers
+// repair requests and merges the responses to them.
 func (l *nlog) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
-	panic("not implemented")
+	if len(msg) == 0 {
+		return errors.New("empty unicast message")
+	}
+	typ, msg := msg[0], msg[1:]
+
+	gd, err := l.decode(msg)
+	if err != nil {
+		return err
+	}
+	switch typ {
+	case unicastRepairKeys, unicastRepairDigest:
+		if l.gossip == nil || l.readOnly {
+			return nil
+		}
+		for _, b := range l.repairEntries(gd, typ == unicastRepairDigest).Encode() {
+			if err := l.gossip.GossipUnicast(src, append([]byte{unicastRepairResponse}, b...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case unicastRepairResponse:
+		delta := l.merge(gd)
+		l.metrics.repairedTotal.Add(float64(len(delta)))
+		return nil
+	}
+	return fmt.Errorf("unknown unicast message type %d", typ)
+}
+
+// repairEntries returns the entries that are newer than the requested
+// ones. If all is set, entries not requested are returned as well.
+func (l *nlog) repairEntries(req gossipData, all bool) gossipData {
+	v := l.View()
+
+	res := gossipData{}
+	for k, r := range req {
+		if e := v.get(k); e != nil && sameKey(e.Entry, r.Entry) && e.Entry.Timestamp.After(r.Entry.Timestamp) {
+			res[k] = e
+		}
+	}
+	if !all {
+		return res
+	}
+	for _, st := range v.st {
+		for k, e := range st {
+			if _, ok := req[k]; !ok {
+				res[k] = e
+			}
+		}
+	}
+	return res
 }
 
 // gossipData is a representation of the current log state that
 // implements the mesh.GossipData interface.
 type gossipData map[string]*pb.MeshEntry
 
-func decodeGossipData(msg []byte) (gossipData, error) {
-	gd := gossipData{}
-	rd := bytes.NewReader(msg)
-
+// decodeGossipData decodes the entries of msg and stores them under the
+// state keys returned by key. It skips entries without an entry or receiver
+// and returns their number.
+func decodeGossipData(msg []byte, key func(string, *pb.Receiver) string) (gossipData, int, error) {
+	var (
+		gd      = gossipData{}
+		rd      = bytes.NewReader(msg)
+		invalid int
+	)
 	for {
 		var e pb.MeshEntry
 		if _, err := pbutil.ReadDelimited(rd, &e); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return gd, err
+			return gd, invalid, err
+		}
+		if e.Entry == nil || e.Entry.Receiver == nil {
+			invalid++
+			continue
 		}
-		gd[stateKey(string(e.Entry.GroupKey), e.Entry.Receiver)] = &e
+		gd[key(string(e.Entry.GroupKey), e.Entry.Receiver)] = &e
 	}
 
-	return gd, nil
+	return gd, invalid, nil
 }
 
 // Encode implements the mesh.GossipData interface.
@@ -578,6 +2564,11 @@ func (gd gossipData) clone() gossipData {
 // unmodified. Needs to be clarified upstream.
 func (gd gossipData) Merge(other mesh.GossipData) mesh.GossipData {
 	for k, e := range other.(gossipData) {
+		// Invalid entries are dropped on decoding. Skip any others rather
+		// than crashing on them.
+		if e.Entry == nil {
+			continue
+		}
 		prev, ok := gd[k]
 		if !ok {
 			gd[k] = e
@@ -595,6 +2586,10 @@ func (gd gossipData) Merge(other mesh.GossipData) mesh.GossipData {
 func (gd gossipData) mergeDelta(od gossipData) gossipData {
 	delta := gossipData{}
 	for k, e := range od {
+		// See Merge.
+		if e.Entry == nil {
+			continue
+		}
 		prev, ok := gd[k]
 		if !ok {
 			gd[k] = e
@@ -609,34 +2604,42 @@ func (gd gossipData) mergeDelta(od gossipData) gossipData {
 	return delta
 }
 
-// replaceFile wraps a file that is moved to another filename on closing.
-type replaceFile struct {
-	*os.File
-	filename string
-}
-
-func (f *replaceFile) Close() error {
-	if err := f.File.Sync(); err != nil {
-		return err
-	}
-	if err := f.File.Close(); err != nil {
-		return err
+// rotatedName returns the filename of the i-th previous snapshot.
+func rotatedName(filename string, i int) string {
+	if i == 0 {
+		return filename
 	}
-	return os.Rename(f.File.Name(), f.filename)
+	return fmt.Sprintf("%s.%d", filename, i)
 }
 
-// openReplace opens a new temporary file that is moved to filename on closing.
-func openReplace(filename string) (*replaceFile, error) {
-	tmpFilename := fmt.Sprintf("%s.%x", filename, uint64(rand.Int63()))
+// incrementalName returns the name of the i-th incremental snapshot of the
+// snapshot file, starting at 1.
+func incrementalName(filename string, i int) string {
+	return fmt.Sprintf("%s.inc.%d", filename, i)
+}
 
-	f, err := os.Create(tmpFilename)
-	if err != nil {
-		return nil, err
+// removeIncrementals removes the chain of incremental snapshots of the
+// snapshot file.
+func removeIncrementals(filename string) error {
+	for i := 1; ; i++ {
+		err := os.Remove(incrementalName(filename, i))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 	}
+}
 
-	rf := &replaceFile{
-		File:     f,
-		filename: filename,
+// rotateSnapshots moves the current snapshot and the up to n-1 previous
+// ones to the next older generation, dropping the oldest.
+func rotateSnapshots(filename string, n int) error {
+	for i := n - 1; i >= 0; i-- {
+		err := os.Rename(rotatedName(filename, i), rotatedName(filename, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
-	return rf, nil
+	return nil
 }
@@ -14,14 +14,26 @@
 package nflog
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
 	pb "github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/snapshot"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
 )
 
 func TestNlogGC(t *testing.T) {
@@ -34,11 +46,11 @@ func TestNlogGC(t *testing.T) {
 	}
 
 	l := &nlog{
-		st: gossipData{
+		st: newState(defaultShards, gossipData{
 			"a1": newEntry(now),
 			"a2": newEntry(now.Add(time.Second)),
 			"a3": newEntry(now.Add(-time.Second)),
-		},
+		}),
 		now:     func() time.Time { return now },
 		metrics: newMetrics(nil),
 	}
@@ -49,7 +61,7 @@ func TestNlogGC(t *testing.T) {
 	expected := gossipData{
 		"a2": newEntry(now.Add(time.Second)),
 	}
-	require.Equal(t, l.st, expected, "unepexcted state after garbage collection")
+	require.Equal(t, l.st.all(), expected, "unepexcted state after garbage collection")
 }
 
 func TestNlogSnapshot(t *testing.T) {
@@ -94,61 +106,590 @@ func TestNlogSnapshot(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		f, err := ioutil.TempFile("", "snapshot")
-		require.NoError(t, err, "creating temp file failed")
+		for _, comp := range []snapshot.Compression{snapshot.CompressionNone, snapshot.CompressionGzip, snapshot.CompressionSnappy} {
+			f, err := ioutil.TempFile("", "snapshot")
+			require.NoError(t, err, "creating temp file failed")
+
+			// Setup internal state manually.
+			st := gossipData{}
+			for _, e := range c.entries {
+				st[stateKey(string(e.Entry.GroupKey), e.Entry.Receiver)] = e
+			}
+			l1 := &nlog{
+				st:          newState(defaultShards, st),
+				metrics:     newMetrics(nil),
+				compression: comp,
+			}
+			n, err := l1.Snapshot(f)
+			require.NoError(t, err, "creating snapshot failed")
+
+			fi, err := f.Stat()
+			require.NoError(t, err)
+			require.Equal(t, fi.Size(), int64(n), "reported snapshot size did not match file size")
+			require.NoError(t, f.Close(), "closing snapshot file failed")
 
-		l1 := &nlog{
-			st:      gossipData{},
-			metrics: newMetrics(nil),
+			f, err = os.Open(f.Name())
+			require.NoError(t, err, "opening snapshot file failed")
+
+			// Check again against new nlog instance. The compression
+			// is detected on loading.
+			l2 := &nlog{st: newState(defaultShards, nil)}
+			err = l2.loadSnapshot(f)
+			require.NoError(t, err, "error loading %s snapshot", comp)
+			require.Equal(t, l1.st.all(), l2.st.all(), "state after loading %s snapshot did not match snapshotted state", comp)
+
+			require.NoError(t, f.Close(), "closing snapshot file failed")
 		}
-		// Setup internal state manually.
-		for _, e := range c.entries {
-			l1.st[stateKey(string(e.Entry.GroupKey), e.Entry.Receiver)] = e
+	}
+}
+
+func TestNlogLoadSnapshotErrors(t *testing.T) {
+	now := utcNow()
+	entry := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			GroupKey:  []byte("d8e8fca2dc0f896fd7cb4cb0031ba249"),
+			Receiver:  &pb.Receiver{GroupName: "abc", Integration: "test1", Idx: 1},
+			Timestamp: now,
+		},
+		ExpiresAt: now,
+	}
+	var valid bytes.Buffer
+	n, err := pbutil.WriteDelimited(&valid, entry)
+	require.NoError(t, err)
+
+	withValid := func(b ...byte) []byte {
+		return append(append([]byte{}, valid.Bytes()...), b...)
+	}
+
+	cases := []struct {
+		name string
+		data []byte
+		kind error
+		off  int64
+	}{
+		{
+			name: "truncated entry",
+			data: withValid(valid.Bytes()[:n-3]...),
+			kind: ErrSnapshotCorrupt,
+			off:  int64(n),
+		}, {
+			name: "truncated length prefix",
+			data: withValid(0x80),
+			kind: ErrSnapshotCorrupt,
+			off:  int64(n),
+		}, {
+			name: "oversized entry",
+			data: []byte{0xff, 0xff, 0xff, 0xff, 0x0f},
+			kind: ErrSnapshotCorrupt,
+		}, {
+			name: "undecodable entry",
+			data: withValid(0x02, 0xff, 0xff),
+			kind: ErrEntryDecode,
+			off:  int64(n),
+		}, {
+			name: "missing entry",
+			data: withValid(0x00),
+			kind: ErrEntryDecode,
+			off:  int64(n),
+		}, {
+			name: "truncated header",
+			data: []byte("ANFL"),
+			kind: ErrSnapshotCorrupt,
+		}, {
+			name: "unsupported version",
+			data: append([]byte("ANFL\x03"), valid.Bytes()...),
+			kind: ErrSnapshotVersion,
+		}, {
+			name: "truncated entry after header",
+			data: append([]byte("ANFL\x01"), valid.Bytes()[:n-3]...),
+			kind: ErrSnapshotCorrupt,
+			off:  5,
+		}, {
+			name: "missing checksum",
+			data: append([]byte("ANFL\x02"), valid.Bytes()...),
+			kind: ErrSnapshotCorrupt,
+			off:  5 + int64(n),
+		}, {
+			name: "truncated checksum",
+			data: append([]byte("ANFL\x02"), withValid(0x00, 0x01, 0x02)...),
+			kind: ErrSnapshotCorrupt,
+			off:  5 + int64(n),
+		}, {
+			name: "checksum mismatch",
+			data: append([]byte("ANFL\x02"), withValid(0x00, 0x01, 0x02, 0x03, 0x04)...),
+			kind: ErrSnapshotCorrupt,
+			off:  5 + int64(n),
+		},
+	}
+	for _, c := range cases {
+		l := &nlog{st: newState(defaultShards, nil)}
+
+		err := l.loadSnapshot(bytes.NewReader(c.data))
+		require.Error(t, err, c.name)
+		require.True(t, errors.Is(err, c.kind), "%s: unexpected error %q", c.name, err)
+
+		serr, ok := err.(*SnapshotError)
+		require.True(t, ok, c.name)
+		require.Equal(t, c.off, serr.Offset, c.name)
+		require.Empty(t, l.st.all(), "%s: state modified on failure", c.name)
+	}
+}
+
+func TestShutdownSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shutdown_snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	snapf := filepath.Join(dir, "nflog")
+	stopc := make(chan struct{})
+	done := make(chan struct{})
+
+	l, err := New(
+		WithRetention(time.Hour),
+		WithSnapshot(snapf),
+		WithMaintenance(time.Hour, stopc, func() { close(done) }),
+	)
+	require.NoError(t, err)
+
+	recv := &pb.Receiver{GroupName: "abc", Integration: "test", Idx: 1}
+	require.NoError(t, l.Log(recv, "key", []uint64{1}, nil))
+
+	close(stopc)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("maintenance did not finish")
+	}
+
+	// The entry logged after the last periodic maintenance must be
+	// restored from the shutdown snapshot.
+	l2, err := New(WithRetention(time.Hour), WithSnapshot(snapf))
+	require.NoError(t, err)
+	e, err := l2.QueryOne(QReceiver(recv), QGroupKey("key"))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, e.FiringAlerts)
+}
+
+func TestBoltDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog_bolt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		path = filepath.Join(dir, "nflog.db")
+		now  = utcNow()
+		recv = &pb.Receiver{GroupName: "abc", Integration: "test", Idx: 1}
+	)
+	open := func() (*nlog, func()) {
+		stopc := make(chan struct{})
+		done := make(chan struct{})
+		l, err := New(
+			WithNow(func() time.Time { return now }),
+			WithRetention(time.Hour),
+			WithBoltDB(path),
+			WithMaintenance(time.Hour, stopc, func() { close(done) }),
+		)
+		require.NoError(t, err)
+		return l.(*nlog), func() {
+			close(stopc)
+			<-done
 		}
-		_, err = l1.Snapshot(f)
-		require.NoError(t, err, "creating snapshot failed")
+	}
+
+	l, stop := open()
+	require.NoError(t, l.Log(recv, "logged", []uint64{1}, nil))
+	require.NoError(t, l.Log(recv, "deleted", []uint64{2}, nil))
+	require.NoError(t, l.Log(recv, "expired", []uint64{3}, nil, LRetention(time.Minute)))
+	require.NoError(t, l.Delete(recv, []byte("deleted")))
+	_, err = l.OnGossipBroadcast(1, gossipData{
+		l.key("merged", recv): &pb.MeshEntry{
+			Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("merged"), Timestamp: now, FiringAlerts: []uint64{4}},
+			ExpiresAt: now.Add(time.Hour),
+		},
+	}.Encode()[0])
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	n, err := l.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	stop()
+
+	// The log is restored from the database without any snapshot.
+	l, stop = open()
+	defer stop()
+	require.Equal(t, 3, l.st.len())
+	for gk, firing := range map[string][]uint64{"logged": {1}, "merged": {4}} {
+		e, err := l.QueryOne(QReceiver(recv), QGroupKey(gk))
+		require.NoError(t, err)
+		require.Equal(t, firing, e.FiringAlerts)
+	}
+	e := l.st.shard(l.key("deleted", recv)).st[l.key("deleted", recv)]
+	require.NotNil(t, e)
+	require.True(t, e.Entry.Deleted)
+	_, err = l.QueryOne(QReceiver(recv), QGroupKey("expired"))
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestBoltDBFromSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog_bolt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		snapf = filepath.Join(dir, "nflog")
+		path  = filepath.Join(dir, "nflog.db")
+		recv  = &pb.Receiver{GroupName: "abc", Integration: "test", Idx: 1}
+	)
+	l, err := New(WithRetention(time.Hour), WithSnapshot(snapf))
+	require.NoError(t, err)
+	require.NoError(t, l.Log(recv, "key", []uint64{1}, nil))
+	require.NoError(t, l.SnapshotFile())
 
-		require.NoError(t, f.Close(), "closing snapshot file failed")
+	// A new database is initialized from the snapshot.
+	l, err = New(WithRetention(time.Hour), WithSnapshot(snapf), WithBoltDB(path))
+	require.NoError(t, err)
+	e, err := l.QueryOne(QReceiver(recv), QGroupKey("key"))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, e.FiringAlerts)
+	require.NoError(t, l.(*nlog).bolt.close())
 
-		f, err = os.Open(f.Name())
-		require.NoError(t, err, "opening snapshot file failed")
+	// The loaded state was written to the database.
+	l, err = New(WithRetention(time.Hour), WithBoltDB(path))
+	require.NoError(t, err)
+	defer l.(*nlog).bolt.close()
+	e, err = l.QueryOne(QReceiver(recv), QGroupKey("key"))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, e.FiringAlerts)
+}
+
+func TestNlogSnapshotHeader(t *testing.T) {
+	now := utcNow()
+	entry := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			GroupKey:  []byte("d8e8fca2dc0f896fd7cb4cb0031ba249"),
+			Receiver:  &pb.Receiver{GroupName: "abc", Integration: "test1", Idx: 1},
+			Timestamp: now,
+		},
+		ExpiresAt: now,
+	}
+	st := gossipData{stateKey(string(entry.Entry.GroupKey), entry.Entry.Receiver): entry}
+
+	var buf bytes.Buffer
+	l := &nlog{st: newState(defaultShards, st), metrics: newMetrics(nil)}
+	_, err := l.Snapshot(&buf)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("ANFL\x02")), "snapshot does not start with header")
+	snap := append([]byte{}, buf.Bytes()...)
+
+	l = &nlog{st: newState(defaultShards, nil)}
+	require.NoError(t, l.loadSnapshot(&buf))
+	require.Equal(t, st, l.st.all())
+
+	// Corrupted entries that still decode are detected by the checksum.
+	corrupt := bytes.Replace(snap, []byte("d8e8"), []byte("d8e9"), 1)
+	l = &nlog{st: newState(defaultShards, nil)}
+	err = l.loadSnapshot(bytes.NewReader(corrupt))
+	require.True(t, errors.Is(err, ErrSnapshotCorrupt), "unexpected error %q", err)
+
+	// Snapshots of the previous version have no checksum.
+	buf.Reset()
+	buf.WriteString("ANFL\x01")
+	_, err = pbutil.WriteDelimited(&buf, entry)
+	require.NoError(t, err)
+
+	l = &nlog{st: newState(defaultShards, nil)}
+	require.NoError(t, l.loadSnapshot(&buf))
+	require.Equal(t, st, l.st.all())
+
+	// Snapshots written before the header was introduced are still read.
+	buf.Reset()
+	_, err = pbutil.WriteDelimited(&buf, entry)
+	require.NoError(t, err)
+
+	l = &nlog{st: newState(defaultShards, nil)}
+	require.NoError(t, l.loadSnapshot(&buf))
+	require.Equal(t, st, l.st.all())
 
-		// Check again against new nlog instance.
-		l2 := &nlog{}
-		err = l2.loadSnapshot(f)
-		require.NoError(t, err, "error loading snapshot")
-		require.Equal(t, l1.st, l2.st, "state after loading snapshot did not match snapshotted state")
+	// JSON snapshots are named in a text header and loaded like any other.
+	buf.Reset()
+	l = &nlog{st: newState(defaultShards, st), codec: snapshot.JSONCodec{}, metrics: newMetrics(nil)}
+	_, err = l.Snapshot(&buf)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("ANFL json 2\n")), "snapshot does not start with JSON header")
 
-		require.NoError(t, f.Close(), "closing snapshot file failed")
+	l = &nlog{st: newState(defaultShards, nil)}
+	require.NoError(t, l.loadSnapshot(&buf))
+	require.Equal(t, st, l.st.all())
+}
+
+func TestNlogPrune(t *testing.T) {
+	now := utcNow()
+	newEntry := func(ts time.Time) *pb.MeshEntry {
+		return &pb.MeshEntry{
+			Entry:     &pb.Entry{Receiver: &pb.Receiver{}},
+			ExpiresAt: ts,
+		}
+	}
+	l := &nlog{
+		st: newState(defaultShards, gossipData{
+			"a1": newEntry(now.Add(time.Minute)),
+			"a2": newEntry(now.Add(-time.Minute)),
+			"a3": newEntry(now),
+		}),
 	}
+	size := entrySize(l.st.all()["a1"])
+
+	require.Equal(t, 0, l.prune(3*size))
+	require.Len(t, l.st.all(), 3)
+
+	require.Equal(t, 2, l.prune(size))
+	require.Equal(t, []string{"a1"}, func() (keys []string) {
+		for k := range l.st.all() {
+			keys = append(keys, k)
+		}
+		return keys
+	}())
 }
 
-func TestReplaceFile(t *testing.T) {
-	dir, err := ioutil.TempDir("", "replace_file")
-	require.NoError(t, err, "creating temp dir failed")
+func TestNlogSnapshotRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog_rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
 
-	origFilename := filepath.Join(dir, "testfile")
+	snapf := filepath.Join(dir, "nflog")
 
-	of, err := os.Create(origFilename)
-	require.NoError(t, err, "creating file failed")
+	write := func(fn string, keys ...string) {
+		f, err := os.Create(fn)
+		require.NoError(t, err)
+		st := gossipData{}
+		for _, k := range keys {
+			st[k] = &pb.MeshEntry{Entry: &pb.Entry{
+				GroupKey: []byte(k),
+				Receiver: &pb.Receiver{GroupName: "abc", Integration: "test", Idx: 0},
+			}}
+		}
+		l := &nlog{st: newState(defaultShards, st), metrics: newMetrics(nil)}
+		_, err = l.Snapshot(f)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
 
-	nf, err := openReplace(origFilename)
-	require.NoError(t, err, "opening replacement file failed")
+	write(snapf, "first")
+	require.NoError(t, rotateSnapshots(snapf, 2))
+	write(snapf, "second")
+	require.NoError(t, rotateSnapshots(snapf, 2))
+	write(snapf, "third")
+	require.NoError(t, rotateSnapshots(snapf, 2))
 
-	_, err = nf.Write([]byte("test"))
-	require.NoError(t, err, "writing replace file failed")
+	for i, exp := range []string{"third", "second"} {
+		l := &nlog{st: newState(defaultShards, nil)}
+		f, err := os.Open(rotatedName(snapf, i+1))
+		require.NoError(t, err)
+		require.NoError(t, l.loadSnapshot(f))
+		f.Close()
+		require.Equal(t, exp, string(l.st.all()[stateKey(exp, &pb.Receiver{GroupName: "abc", Integration: "test"})].Entry.GroupKey))
+	}
+	_, err = os.Stat(rotatedName(snapf, 3))
+	require.True(t, os.IsNotExist(err), "oldest snapshot was not dropped")
 
-	require.NotEqual(t, nf.Name(), of.Name(), "replacement file must have different name while editing")
-	require.NoError(t, nf.Close(), "closing replacement file failed")
-	require.NoError(t, of.Close(), "closing original file failed")
+	// A corrupted latest snapshot falls back to the previous one.
+	require.NoError(t, ioutil.WriteFile(snapf, []byte{0x05, 0x01}, 0644))
 
-	ofr, err := os.Open(origFilename)
-	require.NoError(t, err, "opening original file failed")
-	defer ofr.Close()
+	l, err := New(WithSnapshot(snapf), WithSnapshotRotation(2))
+	require.NoError(t, err)
+	entries, err := l.Query(QGroupKey("third"), QReceiver(&pb.Receiver{GroupName: "abc", Integration: "test"}))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
 
-	res, err := ioutil.ReadAll(ofr)
-	require.NoError(t, err, "reading original file failed")
-	require.Equal(t, "test", string(res), "unexpected file contents")
+	// Without rotation the error is returned.
+	_, err = New(WithSnapshot(snapf))
+	require.True(t, errors.Is(err, ErrSnapshotCorrupt))
+}
+
+func TestNlogSnapshotFileFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog_snapshot_failure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	snapf := filepath.Join(dir, "nflog")
+	l, err := New(WithSnapshot(snapf), WithSnapshotRotation(1), WithRetention(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, l.Log(&pb.Receiver{GroupName: "abc", Integration: "test"}, "key", []uint64{1}, nil))
+	require.NoError(t, l.SnapshotFile())
+
+	// Rotating fails if a directory is in the way of the rotated snapshot.
+	require.NoError(t, os.MkdirAll(filepath.Join(rotatedName(snapf, 1), "x"), 0777))
+	require.Error(t, l.SnapshotFile())
+
+	// The temporary file of the failed snapshot was removed.
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, fi := range files {
+		names = append(names, fi.Name())
+	}
+	require.Equal(t, []string{"nflog", "nflog.1"}, names)
+}
+
+func TestNlogIncrementalSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog_incremental")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		snapf = filepath.Join(dir, "nflog")
+		recv  = &pb.Receiver{GroupName: "abc", Integration: "test"}
+		now   = time.Now().UTC()
+	)
+	l, err := New(
+		WithSnapshot(snapf),
+		WithIncrementalSnapshots(2),
+		WithRetention(time.Hour),
+		WithNow(func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}),
+	)
+	require.NoError(t, err)
+
+	exists := func(fn string) bool {
+		_, err := os.Stat(fn)
+		return err == nil
+	}
+	snapshotWith := func(keys ...string) {
+		for _, k := range keys {
+			require.NoError(t, l.Log(recv, k, nil, nil))
+		}
+		require.NoError(t, l.SnapshotFile())
+	}
+	changed := func(i int) []string {
+		f, err := os.Open(incrementalName(snapf, i))
+		require.NoError(t, err)
+		defer f.Close()
+		_, st, err := l.(*nlog).readIncremental(f)
+		require.NoError(t, err)
+
+		var keys []string
+		for _, e := range st {
+			keys = append(keys, string(e.Entry.GroupKey))
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	loaded := func() []string {
+		l, err := New(WithSnapshot(snapf), WithIncrementalSnapshots(2))
+		require.NoError(t, err)
+
+		var keys []string
+		l.View().Range(func(e *pb.Entry) bool {
+			keys = append(keys, string(e.GroupKey))
+			return true
+		})
+		sort.Strings(keys)
+		return keys
+	}
+
+	// The first snapshot is always a full one.
+	snapshotWith("a")
+	require.False(t, exists(incrementalName(snapf, 1)))
+
+	// Subsequent snapshots only hold the changed entries.
+	snapshotWith("b")
+	require.Equal(t, []string{"b"}, changed(1))
+	snapshotWith("a")
+	require.Equal(t, []string{"a"}, changed(2))
+	require.Equal(t, []string{"a", "b"}, loaded())
+
+	aTS, err := l.QueryOne(QGroupKey("a"), QReceiver(recv))
+	require.NoError(t, err)
+
+	// Once the maximum number of incremental snapshots is reached, a full
+	// snapshot replaces them.
+	snapshotWith("c")
+	require.False(t, exists(incrementalName(snapf, 1)))
+	require.False(t, exists(incrementalName(snapf, 2)))
+
+	snapshotWith("d")
+	require.Equal(t, []string{"d"}, changed(1))
+	require.Equal(t, []string{"a", "b", "c", "d"}, loaded())
+
+	nl, err := New(WithSnapshot(snapf), WithIncrementalSnapshots(2))
+	require.NoError(t, err)
+	e, err := nl.QueryOne(QGroupKey("a"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Equal(t, aTS.Timestamp, e.Timestamp)
+
+	// The chain ends at a corrupted incremental snapshot.
+	snapshotWith("e")
+	require.Equal(t, []string{"e"}, changed(2))
+	b, err := ioutil.ReadFile(incrementalName(snapf, 1))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(incrementalName(snapf, 1), []byte("garbage"), 0644))
+	require.Equal(t, []string{"a", "b", "c"}, loaded())
+
+	// Incremental snapshots of a previous full snapshot are ignored.
+	snapshotWith("f")
+	require.False(t, exists(incrementalName(snapf, 1)))
+	require.NoError(t, ioutil.WriteFile(incrementalName(snapf, 1), b, 0644))
+	require.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, loaded())
+
+	// Evicted entries cannot be expressed by incremental snapshots and
+	// force a full snapshot.
+	require.NoError(t, os.Remove(snapf))
+	require.NoError(t, removeIncrementals(snapf))
+	l, err = New(
+		WithSnapshot(snapf),
+		WithIncrementalSnapshots(2),
+		WithRetention(time.Hour),
+		WithMaxEntries(2),
+	)
+	require.NoError(t, err)
+	snapshotWith("x", "y")
+	snapshotWith("z")
+	require.False(t, exists(incrementalName(snapf, 1)))
+	require.Equal(t, []string{"y", "z"}, loaded())
+}
+
+func TestNlogSnapshotStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog_store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := snapshot.NewStore("file://" + filepath.Join(dir, "store"))
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "store"), 0755))
+
+	recv := &pb.Receiver{GroupName: "abc", Integration: "test"}
+
+	// Without a snapshot file, snapshots are only written to the store.
+	l1, err := New(WithSnapshotStore(store, "nflog"))
+	require.NoError(t, err)
+	require.NoError(t, l1.Log(recv, "key", []uint64{1}, nil))
+	require.NoError(t, l1.SnapshotFile())
+
+	// The state is restored from the store if there is no snapshot file.
+	snapf := filepath.Join(dir, "nflog")
+	l2, err := New(WithSnapshot(snapf), WithSnapshotStore(store, "nflog"))
+	require.NoError(t, err)
+	entries, err := l2.Query(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// Snapshots are written to both.
+	require.NoError(t, l2.Log(recv, "other", []uint64{2}, nil))
+	require.NoError(t, l2.SnapshotFile())
+	_, err = os.Stat(snapf)
+	require.NoError(t, err)
+
+	l3, err := New(WithSnapshotStore(store, "nflog"))
+	require.NoError(t, err)
+	entries, err = l3.Query(QGroupKey("other"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	_, err = New(WithSnapshotStore(store, ""))
+	require.Error(t, err)
 }
 
 func TestGossipDataMerge(t *testing.T) {
@@ -258,13 +799,88 @@ func TestGossipDataCoding(t *testing.T) {
 		msg := in.Encode()
 		require.Equal(t, 1, len(msg), "expected single message for input")
 
-		out, err := decodeGossipData(msg[0])
+		out, _, err := decodeGossipData(msg[0], stateKey)
 		require.NoError(t, err, "decoding message failed")
 
 		require.Equal(t, in, out, "decoded data doesn't match encoded data")
 	}
 }
 
+func TestNlogView(t *testing.T) {
+	now := utcNow()
+	l := &nlog{
+		st:      newState(defaultShards, nil),
+		now:     func() time.Time { return now },
+		metrics: newMetrics(nil),
+	}
+	recv := &pb.Receiver{GroupName: "test", Integration: "slack"}
+
+	require.NoError(t, l.Log(recv, "a", []uint64{1}, nil))
+	v := l.View()
+
+	// Modifications after taking the view must not be visible in it.
+	require.NoError(t, l.Log(recv, "b", []uint64{2}, nil))
+	now = now.Add(time.Hour)
+	require.NoError(t, l.Log(recv, "a", []uint64{3}, nil))
+
+	require.Equal(t, 1, v.Len())
+	v.Range(func(e *pb.Entry) bool {
+		require.Equal(t, "a", string(e.GroupKey))
+		require.Equal(t, []uint64{1}, e.FiringAlerts)
+		return true
+	})
+
+	v = l.View()
+	require.Equal(t, 2, v.Len())
+
+	var n int
+	v.Range(func(*pb.Entry) bool {
+		n++
+		return false
+	})
+	require.Equal(t, 1, n)
+}
+
+// blockingWriter calls f on the first write and fails the write if f does
+// not return in time. The error returned by f is kept in err.
+type blockingWriter struct {
+	f    func() error
+	done bool
+	err  error
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	if !w.done {
+		w.done = true
+		c := make(chan error, 1)
+		go func() {
+			c <- w.f()
+		}()
+		select {
+		case w.err = <-c:
+		case <-time.After(5 * time.Second):
+			return 0, errors.New("blocked by snapshot")
+		}
+	}
+	return len(b), nil
+}
+
+func TestSnapshotDoesNotBlockLog(t *testing.T) {
+	nl, err := New()
+	require.NoError(t, err, "constructing nflog failed")
+
+	recv := &pb.Receiver{GroupName: "test", Integration: "slack"}
+	require.NoError(t, nl.Log(recv, "a", []uint64{1}, nil))
+
+	w := &blockingWriter{f: func() error {
+		return nl.Log(recv, "b", []uint64{2}, nil)
+	}}
+	_, err = nl.Snapshot(w)
+	require.NoError(t, err, "logging was blocked during snapshot")
+	require.NoError(t, w.err, "logging during snapshot failed")
+	require.Equal(t, 2, nl.View().Len())
+}
+
 func TestNilGossipDoesNotCrash(t *testing.T) {
 	nl, err := New()
 	if err != nil {
@@ -274,6 +890,42 @@ func TestNilGossipDoesNotCrash(t *testing.T) {
 	require.NoError(t, err, "logging notification failed")
 }
 
+func TestInvalidGossipDoesNotCrash(t *testing.T) {
+	nl, err := New(WithRetention(time.Hour))
+	require.NoError(t, err)
+	l := nl.(*nlog)
+
+	now := utcNow()
+	valid := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			GroupKey:  []byte("key"),
+			Receiver:  &pb.Receiver{GroupName: "abc", Integration: "test", Idx: 1},
+			Timestamp: now,
+		},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	var buf bytes.Buffer
+	for _, e := range []*pb.MeshEntry{
+		{ExpiresAt: now.Add(time.Hour)},
+		{Entry: &pb.Entry{GroupKey: []byte("key"), Timestamp: now}, ExpiresAt: now.Add(time.Hour)},
+		valid,
+	} {
+		_, err := pbutil.WriteDelimited(&buf, e)
+		require.NoError(t, err)
+	}
+
+	delta, err := l.OnGossipBroadcast(mesh.UnknownPeerName, buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, delta, 1)
+
+	var m dto.Metric
+	require.NoError(t, l.metrics.invalidTotal.Write(&m))
+	require.Equal(t, float64(2), m.GetCounter().GetValue())
+
+	_, err = l.QueryOne(QReceiver(valid.Entry.Receiver), QGroupKey("key"))
+	require.NoError(t, err)
+}
+
 func TestQuery(t *testing.T) {
 	nl, err := New()
 	if err != nil {
@@ -286,9 +938,9 @@ func TestQuery(t *testing.T) {
 	_, err = nl.Query(QGroupKey("key"))
 	require.EqualError(t, err, "no query parameters specified")
 
-	// no recv param
+	// no recv entries
 	_, err = nl.Query(QReceiver(recv))
-	require.EqualError(t, err, "no query parameters specified")
+	require.EqualError(t, err, "not found")
 
 	// no entry
 	_, err = nl.Query(QGroupKey("nonexistingkey"), QReceiver(recv))
@@ -306,3 +958,998 @@ func TestQuery(t *testing.T) {
 	require.EqualValues(t, firingAlerts, entry.FiringAlerts)
 	require.EqualValues(t, resolvedAlerts, entry.ResolvedAlerts)
 }
+
+func TestQueryReceiver(t *testing.T) {
+	var (
+		now   = time.Now()
+		recv1 = &pb.Receiver{GroupName: "a", Integration: "test"}
+		recv2 = &pb.Receiver{GroupName: "b", Integration: "test"}
+	)
+	nl, err := New(WithHistory(1), WithNow(func() time.Time { return now }))
+	require.NoError(t, err, "constructing nflog failed")
+
+	for i, l := range []struct {
+		recv *pb.Receiver
+		key  string
+	}{
+		{recv1, "key2"},
+		{recv1, "key1"},
+		{recv2, "key1"},
+		{recv1, "key2"},
+	} {
+		now = now.Add(time.Minute)
+		require.NoError(t, nl.Log(l.recv, l.key, []uint64{uint64(i)}, nil))
+	}
+
+	entries, err := nl.Query(QReceiver(recv1))
+	require.NoError(t, err)
+
+	var res []uint64
+	for _, e := range entries {
+		require.Equal(t, recv1, e.Receiver)
+		res = append(res, e.FiringAlerts...)
+	}
+	require.Equal(t, []uint64{0, 1, 3}, res)
+
+	entry, err := nl.QueryOne(QReceiver(recv1))
+	require.NoError(t, err)
+	require.Equal(t, "key2", string(entry.GroupKey))
+	require.EqualValues(t, []uint64{3}, entry.FiringAlerts)
+}
+
+func TestQueryConfigHash(t *testing.T) {
+	var (
+		recv1 = &pb.Receiver{GroupName: "a", Integration: "test"}
+		recv2 = &pb.Receiver{GroupName: "a", Integration: "test", ConfigHash: "1"}
+		recv3 = &pb.Receiver{GroupName: "a", Integration: "test", ConfigHash: "2"}
+	)
+	nl, err := New()
+	require.NoError(t, err, "constructing nflog failed")
+
+	require.NoError(t, nl.Log(recv1, "key", []uint64{1}, nil))
+	require.NoError(t, nl.Log(recv2, "key", []uint64{2}, nil))
+
+	// Entries of receivers with different configuration hashes are kept
+	// apart.
+	for recv, firing := range map[*pb.Receiver][]uint64{recv1: {1}, recv2: {2}} {
+		entry, err := nl.QueryOne(QGroupKey("key"), QReceiver(recv))
+		require.NoError(t, err)
+		require.Equal(t, firing, entry.FiringAlerts)
+	}
+	_, err = nl.QueryOne(QGroupKey("key"), QReceiver(recv3))
+	require.Equal(t, ErrNotFound, err)
+
+	it, err := nl.QueryIter(QReceiverName("a"))
+	require.NoError(t, err)
+	var n int
+	for it.Next() {
+		n++
+	}
+	require.Equal(t, 2, n)
+}
+
+func TestQueryOne(t *testing.T) {
+	nl, err := New()
+	require.NoError(t, err, "constructing nflog failed")
+
+	recv := new(pb.Receiver)
+
+	_, err = nl.QueryOne(QGroupKey("key"))
+	require.EqualError(t, err, "no query parameters specified")
+
+	_, err = nl.QueryOne(QGroupKey("key"), QReceiver(recv))
+	require.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, nl.Log(recv, "key", []uint64{1, 2}, []uint64{3}))
+
+	entry, err := nl.QueryOne(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Equal(t, "key", string(entry.GroupKey))
+	require.EqualValues(t, []uint64{1, 2}, entry.FiringAlerts)
+	require.EqualValues(t, []uint64{3}, entry.ResolvedAlerts)
+}
+
+func TestQueryIter(t *testing.T) {
+	nl, err := New()
+	require.NoError(t, err, "constructing nflog failed")
+
+	var (
+		recv1 = &pb.Receiver{GroupName: "a", Integration: "slack"}
+		recv2 = &pb.Receiver{GroupName: "b", Integration: "email"}
+	)
+	require.NoError(t, nl.Log(recv1, "key1", []uint64{1}, nil))
+	require.NoError(t, nl.Log(recv1, "key2", []uint64{2}, nil))
+	require.NoError(t, nl.Log(recv2, "key1", []uint64{3}, nil))
+
+	collect := func(params ...QueryParam) []uint64 {
+		it, err := nl.QueryIter(params...)
+		require.NoError(t, err)
+
+		var res []uint64
+		for it.Next() {
+			res = append(res, it.At().FiringAlerts...)
+		}
+		require.Nil(t, it.At())
+		sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+		return res
+	}
+
+	require.Equal(t, []uint64{1, 2, 3}, collect())
+	require.Equal(t, []uint64{1, 3}, collect(QGroupKey("key1")))
+	require.Equal(t, []uint64{1, 2}, collect(QReceiver(recv1)))
+	require.Equal(t, []uint64{3}, collect(QGroupKey("key1"), QReceiver(recv2)))
+	require.Empty(t, collect(QGroupKey("key2"), QReceiver(recv2)))
+	require.Equal(t, []uint64{1, 2}, collect(QReceiverName("a")))
+	require.Equal(t, []uint64{3}, collect(QReceiverName("b"), QGroupKey("key1")))
+	require.Empty(t, collect(QReceiverName("c")))
+
+	// Entries logged after the iterator was created are not visible.
+	it, err := nl.QueryIter(QReceiver(recv2))
+	require.NoError(t, err)
+	require.NoError(t, nl.Log(recv2, "key3", []uint64{4}, nil))
+	require.True(t, it.Next())
+	require.Equal(t, []uint64{3}, it.At().FiringAlerts)
+	require.False(t, it.Next())
+}
+
+func TestQueryPagination(t *testing.T) {
+	var (
+		now  = time.Now()
+		recv = &pb.Receiver{GroupName: "a", Integration: "test"}
+	)
+	nl, err := New(WithNow(func() time.Time { return now }))
+	require.NoError(t, err, "constructing nflog failed")
+
+	// key1 and key2 share a timestamp and are ordered by group key.
+	require.NoError(t, nl.Log(recv, "key2", []uint64{2}, nil))
+	require.NoError(t, nl.Log(recv, "key1", []uint64{1}, nil))
+	now = now.Add(time.Minute)
+	require.NoError(t, nl.Log(recv, "key3", []uint64{3}, nil))
+
+	query := func(params ...QueryParam) []uint64 {
+		entries, err := nl.Query(append(params, QReceiver(recv))...)
+		require.NoError(t, err)
+
+		res := []uint64{}
+		for _, e := range entries {
+			res = append(res, e.FiringAlerts...)
+		}
+		return res
+	}
+	require.Equal(t, []uint64{1, 2, 3}, query())
+	require.Equal(t, []uint64{1, 2}, query(QLimit(2)))
+	require.Equal(t, []uint64{3}, query(QOffset(2), QLimit(2)))
+	require.Equal(t, []uint64{}, query(QOffset(3)))
+	require.Equal(t, []uint64{}, query(QGroupKey("key1"), QOffset(1)))
+
+	_, err = nl.QueryOne(QReceiver(recv), QOffset(3))
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = nl.Query(QReceiver(recv), QLimit(0))
+	require.EqualError(t, err, "query limit must be positive")
+	_, err = nl.Query(QReceiver(recv), QOffset(-1))
+	require.EqualError(t, err, "query offset must not be negative")
+
+	it, err := nl.QueryIter(QOffset(1), QLimit(1))
+	require.NoError(t, err)
+	require.True(t, it.Next())
+	require.Equal(t, "key2", string(it.At().GroupKey))
+	require.False(t, it.Next())
+}
+
+func TestQueryHistory(t *testing.T) {
+	now := utcNow()
+	nl, err := New(WithHistory(2), WithRetention(time.Hour), WithNow(func() time.Time { return now }))
+	require.NoError(t, err, "constructing nflog failed")
+
+	recv := &pb.Receiver{GroupName: "a", Integration: "slack"}
+	for i := uint64(1); i <= 4; i++ {
+		now = now.Add(time.Minute)
+		require.NoError(t, nl.Log(recv, "key", []uint64{i}, nil))
+	}
+
+	entries, err := nl.Query(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, e := range entries {
+		require.Equal(t, []uint64{uint64(i + 2)}, e.FiringAlerts)
+	}
+
+	entry, err := nl.QueryOne(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4}, entry.FiringAlerts)
+
+	// Gossiped entries replacing the latest one are added to the history.
+	e := &pb.MeshEntry{
+		Entry: &pb.Entry{
+			Receiver:     recv,
+			GroupKey:     []byte("key"),
+			Timestamp:    now.Add(time.Minute),
+			FiringAlerts: []uint64{5},
+		},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	_, err = nl.(*nlog).OnGossipBroadcast(0, gossipData{stateKey("key", recv): e}.Encode()[0])
+	require.NoError(t, err)
+
+	entries, err = nl.Query(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, []uint64{3}, entries[0].FiringAlerts)
+	require.Equal(t, []uint64{5}, entries[2].FiringAlerts)
+
+	// The history is dropped along with the latest entry.
+	now = now.Add(2 * time.Hour)
+	_, err = nl.GC()
+	require.NoError(t, err)
+	for _, sh := range nl.(*nlog).st {
+		require.Empty(t, sh.hist)
+	}
+}
+
+func TestQueryResolved(t *testing.T) {
+	now := utcNow()
+	nl, err := New(WithHistory(2), WithRetention(time.Hour), WithNow(func() time.Time { return now }))
+	require.NoError(t, err, "constructing nflog failed")
+
+	recv := &pb.Receiver{GroupName: "a", Integration: "slack"}
+	require.NoError(t, nl.Log(recv, "key1", []uint64{1}, nil))
+	now = now.Add(time.Minute)
+	require.NoError(t, nl.Log(recv, "key1", nil, []uint64{1}))
+	require.NoError(t, nl.Log(recv, "key2", []uint64{2}, nil))
+
+	entry, err := nl.QueryOne(QGroupKey("key1"), QReceiver(recv), QResolved(false))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, entry.FiringAlerts, "previous firing notification must be found")
+
+	entry, err = nl.QueryOne(QGroupKey("key1"), QReceiver(recv), QResolved(true))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, entry.ResolvedAlerts)
+
+	_, err = nl.QueryOne(QGroupKey("key2"), QReceiver(recv), QResolved(true))
+	require.Equal(t, ErrNotFound, err)
+
+	entries, err := nl.Query(QReceiver(recv), QResolved(false))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, []byte("key1"), entries[0].GroupKey)
+	require.Equal(t, []byte("key2"), entries[1].GroupKey)
+
+	// The receiver name is applied without a status filter as well.
+	_, err = nl.QueryOne(QGroupKey("key1"), QReceiver(recv), QReceiverName("b"))
+	require.Equal(t, ErrNotFound, err)
+	_, err = nl.Query(QReceiver(recv), QReceiverName("b"))
+	require.Equal(t, ErrNotFound, err)
+	entries, err = nl.Query(QReceiver(recv), QReceiverName("a"))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	// Iterators only consider the latest entries.
+	it, err := nl.QueryIter(QResolved(true))
+	require.NoError(t, err)
+	require.True(t, it.Next())
+	require.Equal(t, []byte("key1"), it.At().GroupKey)
+	require.False(t, it.Next())
+}
+
+func TestObserver(t *testing.T) {
+	var (
+		now      = time.Now()
+		recv     = &pb.Receiver{GroupName: "a", Integration: "test"}
+		observed []string
+	)
+	nl, err := New(
+		WithNow(func() time.Time { return now }),
+		WithRetention(time.Hour),
+		WithObserver(func(e *pb.MeshEntry) {
+			observed = append(observed, string(e.Entry.GroupKey))
+		}),
+	)
+	require.NoError(t, err, "constructing nflog failed")
+
+	require.NoError(t, nl.Log(recv, "key1", []uint64{1}, nil))
+
+	newer := &pb.MeshEntry{
+		Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("key2"), Timestamp: now},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	older := &pb.MeshEntry{
+		Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("key1"), Timestamp: now.Add(-time.Minute)},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	_, err = nl.(*nlog).OnGossipBroadcast(0, gossipData{
+		stateKey("key2", recv): newer,
+		stateKey("key1", recv): older,
+	}.Encode()[0])
+	require.NoError(t, err)
+
+	merged := &pb.MeshEntry{
+		Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("key3"), Timestamp: now},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	_, err = nl.Merge(merged)
+	require.NoError(t, err)
+
+	// Entries older than the ones in the log are not observed.
+	require.Equal(t, []string{"key1", "key2", "key3"}, observed)
+}
+
+func TestHashedKeys(t *testing.T) {
+	var (
+		now  = time.Now()
+		recv = &pb.Receiver{GroupName: "a", Integration: "test"}
+		gkey = strings.Repeat("{alertname=\"x\"}", 100)
+	)
+	nl, err := New(
+		WithNow(func() time.Time { return now }),
+		WithRetention(time.Hour),
+		WithHashedKeys(true),
+	)
+	require.NoError(t, err, "constructing nflog failed")
+	l := nl.(*nlog)
+
+	require.NoError(t, l.Log(recv, gkey, []uint64{1}, nil))
+	for k := range l.st.all() {
+		require.Len(t, k, sha256.Size)
+	}
+	e, err := l.QueryOne(QReceiver(recv), QGroupKey(gkey))
+	require.NoError(t, err)
+	require.Equal(t, gkey, string(e.GroupKey))
+
+	// Entries merged from peers are stored under hashed keys as well.
+	other := &pb.MeshEntry{
+		Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("other"), Timestamp: now},
+		ExpiresAt: now.Add(time.Hour),
+	}
+	_, err = l.OnGossipBroadcast(0, gossipData{stateKey("other", recv): other}.Encode()[0])
+	require.NoError(t, err)
+	_, err = l.QueryOne(QReceiver(recv), QGroupKey("other"))
+	require.NoError(t, err)
+
+	// Simulate a collision by storing an entry under the key of another
+	// group key.
+	collides := l.key("collides", recv)
+	l.st.shard(collides).st[collides] = l.st.all()[l.key("other", recv)]
+
+	require.Equal(t, ErrKeyCollision, l.Log(recv, "collides", []uint64{2}, nil))
+	_, err = l.QueryOne(QReceiver(recv), QGroupKey("collides"))
+	require.Equal(t, ErrNotFound, err)
+
+	n, err := l.Merge(&pb.MeshEntry{
+		Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("collides"), Timestamp: now.Add(time.Minute)},
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, n, "colliding entry must not be merged")
+	require.Equal(t, "other", string(l.st.all()[collides].Entry.GroupKey))
+}
+
+func TestMaxEntries(t *testing.T) {
+	var (
+		now  = time.Now()
+		recv = &pb.Receiver{GroupName: "a", Integration: "test"}
+	)
+	nl, err := New(
+		WithNow(func() time.Time { return now }),
+		WithRetention(time.Hour),
+		WithMaxEntries(2),
+	)
+	require.NoError(t, err, "constructing nflog failed")
+	l := nl.(*nlog)
+
+	var evicted *pb.MeshEntry
+	for _, k := range []string{"key1", "key2", "key3"} {
+		require.NoError(t, l.Log(recv, k, []uint64{1}, nil))
+		if evicted == nil {
+			evicted = l.st.all()[l.key(k, recv)]
+		}
+		now = now.Add(time.Minute)
+	}
+	require.Len(t, l.st.all(), 2)
+	_, err = l.QueryOne(QReceiver(recv), QGroupKey("key1"))
+	require.Equal(t, ErrNotFound, err, "oldest entry must be evicted")
+
+	// Evicted entries are not removed from peers. If one shares it again,
+	// it is evicted again and not gossiped on.
+	delta, err := l.OnGossip(gossipData{l.key("key1", recv): evicted}.Encode()[0])
+	require.NoError(t, err)
+	require.Nil(t, delta)
+	require.Len(t, l.st.all(), 2)
+	_, err = l.QueryOne(QReceiver(recv), QGroupKey("key1"))
+	require.Equal(t, ErrNotFound, err, "merged entry must be evicted again")
+
+	var m dto.Metric
+	require.NoError(t, l.metrics.evictedTotal.Write(&m))
+	require.Equal(t, 2.0, m.GetCounter().GetValue())
+
+	// Updating an existing entry does not evict another one.
+	require.NoError(t, l.Log(recv, "key2", []uint64{2}, nil))
+	require.Len(t, l.st.all(), 2)
+
+	// Merged entries older than all others are evicted right away and not
+	// reported as changed.
+	n, err := l.Merge(&pb.MeshEntry{
+		Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("old"), Timestamp: now.Add(-time.Hour)},
+		ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Len(t, l.st.all(), 2)
+
+	_, err = New(WithMaxEntries(-1))
+	require.Error(t, err)
+}
+
+func TestLogRetention(t *testing.T) {
+	var (
+		now  = utcNow()
+		recv = &pb.Receiver{GroupName: "a", Integration: "pagerduty"}
+	)
+	nl, err := New(
+		WithNow(func() time.Time { return now }),
+		WithRetention(time.Hour),
+	)
+	require.NoError(t, err, "constructing nflog failed")
+	l := nl.(*nlog)
+
+	require.NoError(t, l.Log(recv, "short", []uint64{1}, nil))
+	require.NoError(t, l.Log(recv, "long", []uint64{1}, nil, LRetention(24*time.Hour)))
+
+	require.Equal(t, now.Add(time.Hour), l.st.all()[l.key("short", recv)].ExpiresAt)
+	require.Equal(t, now.Add(24*time.Hour), l.st.all()[l.key("long", recv)].ExpiresAt)
+
+	now = now.Add(2 * time.Hour)
+	n, err := l.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = l.QueryOne(QReceiver(recv), QGroupKey("long"))
+	require.NoError(t, err, "entry with longer retention must not be collected")
+}
+
+func TestReceiverRetention(t *testing.T) {
+	var (
+		now   = utcNow()
+		pager = &pb.Receiver{GroupName: "a", Integration: "pagerduty"}
+		slack = &pb.Receiver{GroupName: "a", Integration: "slack"}
+		email = &pb.Receiver{GroupName: "a", Integration: "email"}
+	)
+	nl, err := New(
+		WithNow(func() time.Time { return now }),
+		WithRetention(time.Hour),
+		WithReceiverRetention(func(r *pb.Receiver) time.Duration {
+			switch r.Integration {
+			case "pagerduty":
+				return 7 * 24 * time.Hour
+			case "slack":
+				return 2 * time.Minute
+			}
+			return 0
+		}),
+	)
+	require.NoError(t, err, "constructing nflog failed")
+	l := nl.(*nlog)
+
+	for _, r := range []*pb.Receiver{pager, slack, email} {
+		require.NoError(t, l.Log(r, "key", []uint64{1}, nil))
+	}
+	require.NoError(t, l.Log(slack, "long", []uint64{1}, nil, LRetention(24*time.Hour)))
+
+	st := l.st.all()
+	require.Equal(t, now.Add(7*24*time.Hour), st[l.key("key", pager)].ExpiresAt)
+	require.Equal(t, now.Add(2*time.Minute), st[l.key("key", slack)].ExpiresAt)
+	require.Equal(t, now.Add(time.Hour), st[l.key("key", email)].ExpiresAt, "receivers without retention must use the default")
+	require.Equal(t, now.Add(24*time.Hour), st[l.key("long", slack)].ExpiresAt, "entry retention must take precedence")
+
+	now = now.Add(2 * time.Hour)
+	n, err := l.GC()
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	_, err = l.QueryOne(QReceiver(pager), QGroupKey("key"))
+	require.NoError(t, err)
+	_, err = l.QueryOne(QReceiver(slack), QGroupKey("key"))
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestStats(t *testing.T) {
+	var (
+		now   = time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+		email = &pb.Receiver{GroupName: "a", Integration: "email"}
+		pager = &pb.Receiver{GroupName: "a", Integration: "pagerduty"}
+		other = &pb.Receiver{GroupName: "b", Integration: "email"}
+	)
+	reg := prometheus.NewRegistry()
+	nl, err := New(
+		WithNow(func() time.Time { return now }),
+		WithRetention(48*time.Hour),
+		WithMetrics(reg),
+	)
+	require.NoError(t, err, "constructing nflog failed")
+
+	require.NoError(t, nl.Log(email, "1", []uint64{1}, nil))
+	require.NoError(t, nl.Log(pager, "1", []uint64{1}, nil))
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, nl.Log(email, "1", []uint64{1}, nil))
+	require.NoError(t, nl.Log(email, "2", []uint64{1}, nil))
+	require.NoError(t, nl.Log(other, "1", nil, []uint64{1}))
+
+	require.Equal(t, []ReceiverStats{
+		{Receiver: "a", Integration: "email", LastHour: 2, LastDay: 3},
+		{Receiver: "a", Integration: "pagerduty", LastHour: 0, LastDay: 1},
+		{Receiver: "b", Integration: "email", LastHour: 1, LastDay: 1},
+	}, nl.Stats())
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	var found bool
+	for _, mf := range mfs {
+		if mf.GetName() != "alertmanager_nflog_receiver_notifications" {
+			continue
+		}
+		found = true
+		require.Len(t, mf.GetMetric(), 6)
+	}
+	require.True(t, found, "notification statistics not exposed")
+
+	// Notifications older than a day are no longer counted and receivers
+	// without recent notifications are removed on garbage collection.
+	now = now.Add(23*time.Hour + 30*time.Minute)
+	require.NoError(t, nl.Log(email, "1", []uint64{1}, nil))
+	_, err = nl.GC()
+	require.NoError(t, err)
+	require.Equal(t, []ReceiverStats{
+		{Receiver: "a", Integration: "email", LastHour: 1, LastDay: 3},
+		{Receiver: "b", Integration: "email", LastHour: 0, LastDay: 1},
+	}, nl.Stats())
+}
+
+func TestClockSkewTolerance(t *testing.T) {
+	var (
+		now  = utcNow()
+		recv = &pb.Receiver{GroupName: "a", Integration: "slack"}
+	)
+	for _, tc := range []struct {
+		tolerance time.Duration
+		accepted  bool
+	}{
+		{tolerance: 0, accepted: false},
+		{tolerance: 10 * time.Second, accepted: false},
+		{tolerance: time.Minute, accepted: true},
+	} {
+		nl, err := New(
+			WithNow(func() time.Time { return now }),
+			WithRetention(time.Hour),
+			WithClockSkewTolerance(tc.tolerance),
+		)
+		require.NoError(t, err, "constructing nflog failed")
+		l := nl.(*nlog)
+
+		// An entry from a peer whose clock is ahead.
+		peerTs := now.Add(30 * time.Second)
+		_, err = l.Merge(&pb.MeshEntry{
+			Entry:     &pb.Entry{Receiver: recv, GroupKey: []byte("key"), Timestamp: peerTs, FiringAlerts: []uint64{1}},
+			ExpiresAt: now.Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, l.Log(recv, "key", []uint64{1, 2}, nil))
+		e, err := l.QueryOne(QReceiver(recv), QGroupKey("key"))
+		require.NoError(t, err)
+
+		var m dto.Metric
+		require.NoError(t, l.metrics.staleWritesTotal.Write(&m))
+		if tc.accepted {
+			require.Equal(t, []uint64{1, 2}, e.FiringAlerts, "tolerance %s", tc.tolerance)
+			require.True(t, e.Timestamp.After(peerTs), "replacing entry must be newer than the replaced one")
+			require.Equal(t, 0.0, m.GetCounter().GetValue())
+		} else {
+			require.Equal(t, []uint64{1}, e.FiringAlerts, "tolerance %s", tc.tolerance)
+			require.Equal(t, 1.0, m.GetCounter().GetValue())
+		}
+	}
+
+	_, err := New(WithClockSkewTolerance(-time.Second))
+	require.Error(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	var (
+		now  = utcNow()
+		recv = &pb.Receiver{GroupName: "a", Integration: "slack"}
+		opts = []Option{
+			WithHistory(2),
+			WithRetention(time.Hour),
+			WithNow(func() time.Time { return now }),
+		}
+	)
+	nl1, err := New(opts...)
+	require.NoError(t, err, "constructing nflog failed")
+	nl2, err := New(opts...)
+	require.NoError(t, err, "constructing nflog failed")
+
+	require.Equal(t, ErrNotFound, nl1.Delete(recv, []byte("key")))
+
+	require.NoError(t, nl1.Log(recv, "key", []uint64{1}, nil))
+	now = now.Add(time.Minute)
+	require.NoError(t, nl1.Log(recv, "key", []uint64{1, 2}, nil))
+	require.NoError(t, nl1.Log(recv, "other", []uint64{3}, nil))
+	stale := nl1.View().MeshEntries()
+	_, err = nl2.Merge(stale...)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, nl1.Delete(recv, []byte("key")))
+	require.Equal(t, ErrNotFound, nl1.Delete(recv, []byte("key")))
+	require.Equal(t, ErrNotFound, nl1.Delete(&pb.Receiver{GroupName: "b", Integration: "slack"}, []byte("other")))
+
+	// The deletion replaces the entry and its history on peers.
+	n, err := nl2.Merge(nl1.View().MeshEntries()...)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	for _, l := range []Log{nl1, nl2} {
+		_, err := l.QueryOne(QGroupKey("key"), QReceiver(recv))
+		require.Equal(t, ErrNotFound, err)
+
+		entries, err := l.Query(QReceiver(recv))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, []byte("other"), entries[0].GroupKey)
+
+		it, err := l.QueryIter()
+		require.NoError(t, err)
+		require.True(t, it.Next())
+		require.Equal(t, []byte("other"), it.At().GroupKey)
+		require.False(t, it.Next())
+	}
+
+	// Outdated entries of peers that missed the deletion are ignored.
+	n, err = nl1.Merge(stale...)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	_, err = nl1.QueryOne(QGroupKey("key"), QReceiver(recv))
+	require.Equal(t, ErrNotFound, err)
+
+	// New notifications replace the deletion without history.
+	now = now.Add(time.Minute)
+	require.NoError(t, nl1.Log(recv, "key", []uint64{4}, nil))
+	entries, err := nl1.Query(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, []uint64{4}, entries[0].FiringAlerts)
+}
+
+func TestAcknowledge(t *testing.T) {
+	var (
+		now  = utcNow()
+		recv = &pb.Receiver{GroupName: "a", Integration: "pagerduty"}
+		opts = []Option{
+			WithHistory(2),
+			WithRetention(time.Hour),
+			WithNow(func() time.Time { return now }),
+		}
+	)
+	nl1, err := New(opts...)
+	require.NoError(t, err, "constructing nflog failed")
+	nl2, err := New(opts...)
+	require.NoError(t, err, "constructing nflog failed")
+
+	_, _, err = nl1.Acknowledge(recv, []byte("key"), now)
+	require.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, nl1.Log(recv, "key", []uint64{1}, nil, LProviderID("id")))
+	_, err = nl2.Merge(nl1.View().MeshEntries()...)
+	require.NoError(t, err)
+	notified := now
+
+	// Acknowledgements older than the notification are ignored.
+	e, ok, err := nl1.Acknowledge(recv, []byte("key"), now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Nil(t, e.AcknowledgedAt)
+
+	ackAt := now.Add(time.Minute)
+	e, ok, err = nl1.Acknowledge(recv, []byte("key"), ackAt)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, ackAt, *e.AcknowledgedAt)
+	require.Equal(t, "id", e.ProviderId)
+	require.Equal(t, []uint64{1}, e.FiringAlerts)
+
+	// Only the first acknowledgement is recorded.
+	e, ok, err = nl1.Acknowledge(recv, []byte("key"), ackAt.Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, ackAt, *e.AcknowledgedAt)
+
+	// The acknowledgement replaces the entry on peers without adding to
+	// its history.
+	n, err := nl2.Merge(nl1.View().MeshEntries()...)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	entries, err := nl2.Query(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, ackAt, *entries[0].AcknowledgedAt)
+	require.True(t, entries[0].Timestamp.After(notified))
+
+	// It survives snapshots.
+	var buf bytes.Buffer
+	_, err = nl1.Snapshot(&buf)
+	require.NoError(t, err)
+	nl3, err := New(opts...)
+	require.NoError(t, err)
+	require.NoError(t, nl3.(*nlog).loadSnapshot(&buf))
+	e, err = nl3.QueryOne(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Equal(t, ackAt, *e.AcknowledgedAt)
+
+	// New notifications are not acknowledged.
+	now = now.Add(time.Hour)
+	require.NoError(t, nl1.Log(recv, "key", []uint64{1}, nil))
+	e, err = nl1.QueryOne(QGroupKey("key"), QReceiver(recv))
+	require.NoError(t, err)
+	require.Nil(t, e.AcknowledgedAt)
+}
+
+type testGossip struct {
+	mtx        sync.Mutex
+	broadcasts []gossipData
+}
+
+func (g *testGossip) GossipUnicast(mesh.PeerName, []byte) error { return nil }
+
+func (g *testGossip) GossipBroadcast(update mesh.GossipData) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.broadcasts = append(g.broadcasts, update.(gossipData))
+}
+
+func TestAsyncWrites(t *testing.T) {
+	recv := &pb.Receiver{GroupName: "a", Integration: "test"}
+	g := &testGossip{}
+
+	nl, err := New(
+		WithRetention(time.Hour),
+		WithAsyncWrites(100),
+		WithMesh(func(mesh.Gossiper) mesh.Gossip { return g }),
+	)
+	require.NoError(t, err, "constructing nflog failed")
+	l := nl.(*nlog)
+
+	var wg sync.WaitGroup
+	errc := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errc <- l.Log(recv, fmt.Sprintf("key%d", i), []uint64{uint64(i)}, nil)
+		}(i)
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		require.NoError(t, err)
+	}
+	l.Flush()
+
+	for i := 0; i < 50; i++ {
+		e, err := l.QueryOne(QReceiver(recv), QGroupKey(fmt.Sprintf("key%d", i)))
+		require.NoError(t, err)
+		require.Equal(t, []uint64{uint64(i)}, e.FiringAlerts)
+	}
+
+	// All written entries are shared with peers, possibly batched.
+	g.mtx.Lock()
+	var shared int
+	for _, gd := range g.broadcasts {
+		shared += len(gd)
+	}
+	g.mtx.Unlock()
+	require.Equal(t, 50, shared)
+
+	// Flushing without pending writes returns right away.
+	l.Flush()
+
+	_, err = New(WithAsyncWrites(-1))
+	require.Error(t, err)
+}
+
+// unicastGossip delivers unicast messages directly to the gossipers of
+// the destination peers.
+type unicastGossip struct {
+	self  mesh.PeerName
+	peers map[mesh.PeerName]mesh.Gossiper
+}
+
+func (g *unicastGossip) GossipBroadcast(mesh.GossipData) {}
+
+func (g *unicastGossip) GossipUnicast(dst mesh.PeerName, msg []byte) error {
+	return g.peers[dst].OnGossipUnicast(g.self, msg)
+}
+
+func TestRepair(t *testing.T) {
+	var (
+		peers  = map[mesh.PeerName]mesh.Gossiper{}
+		now    = utcNow()
+		recv   = &pb.Receiver{GroupName: "a", Integration: "test"}
+		create = func(name mesh.PeerName) *nlog {
+			nl, err := New(
+				WithRetention(time.Hour),
+				WithNow(func() time.Time { return now }),
+				WithMesh(func(g mesh.Gossiper) mesh.Gossip {
+					peers[name] = g
+					return &unicastGossip{self: name, peers: peers}
+				}),
+			)
+			require.NoError(t, err)
+			return nl.(*nlog)
+		}
+		a = create(1)
+		b = create(2)
+	)
+	require.NoError(t, a.Log(recv, "k1", []uint64{1}, nil))
+	require.NoError(t, a.Log(recv, "local", []uint64{1}, nil))
+	now = now.Add(time.Minute)
+	for _, k := range []string{"k1", "k2", "k3"} {
+		require.NoError(t, b.Log(recv, k, []uint64{2}, nil))
+	}
+
+	firing := func(l *nlog, gkey string) []uint64 {
+		res, err := l.Query(QGroupKey(gkey), QReceiver(recv))
+		if err == ErrNotFound {
+			return nil
+		}
+		require.NoError(t, err)
+		return res[0].FiringAlerts
+	}
+
+	// Only the requested entries are sent.
+	require.NoError(t, a.Repair(2, RepairKey{GroupKey: "k2", Receiver: recv}, RepairKey{GroupKey: "missing", Receiver: recv}))
+	require.Equal(t, []uint64{2}, firing(a, "k2"))
+	require.Equal(t, []uint64{1}, firing(a, "k1"))
+	require.Nil(t, firing(a, "k3"))
+
+	// With a digest all missing and outdated entries are sent.
+	require.NoError(t, a.Repair(2))
+	require.Equal(t, []uint64{2}, firing(a, "k1"))
+	require.Equal(t, []uint64{2}, firing(a, "k3"))
+
+	// The peer does not receive the entries of the requester.
+	require.Nil(t, firing(b, "local"))
+
+	var m dto.Metric
+	require.NoError(t, a.metrics.repairedTotal.Write(&m))
+	require.Equal(t, 3.0, m.GetCounter().GetValue())
+
+	require.Error(t, a.OnGossipUnicast(2, []byte{0xff}))
+	require.Error(t, a.OnGossipUnicast(2, nil))
+}
+
+func TestReadOnly(t *testing.T) {
+	var (
+		peers  = map[mesh.PeerName]mesh.Gossiper{}
+		recv   = &pb.Receiver{GroupName: "a", Integration: "test"}
+		create = func(name mesh.PeerName, opts ...Option) *nlog {
+			nl, err := New(append([]Option{
+				WithRetention(time.Hour),
+				WithMesh(func(g mesh.Gossiper) mesh.Gossip {
+					peers[name] = g
+					return &unicastGossip{self: name, peers: peers}
+				}),
+			}, opts...)...)
+			require.NoError(t, err)
+			return nl.(*nlog)
+		}
+		rw = create(1)
+		ro = create(2, WithReadOnly(true))
+	)
+	require.NoError(t, rw.Log(recv, "key", []uint64{1}, nil))
+	e, err := rw.QueryOne(QReceiver(recv), QGroupKey("key"))
+	require.NoError(t, err)
+
+	// Writes are rejected.
+	require.Equal(t, ErrReadOnly, ro.Log(recv, "key", []uint64{2}, nil))
+	require.Equal(t, ErrReadOnly, ro.Delete(recv, []byte("key")))
+	_, _, err = ro.Acknowledge(recv, []byte("key"), utcNow())
+	require.Equal(t, ErrReadOnly, err)
+	_, err = ro.Merge(&pb.MeshEntry{Entry: e, ExpiresAt: utcNow().Add(time.Hour)})
+	require.Equal(t, ErrReadOnly, err)
+
+	// Entries of peers are received and queried.
+	require.NoError(t, ro.Repair(1))
+	res, err := ro.QueryOne(QReceiver(recv), QGroupKey("key"))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, res.FiringAlerts)
+
+	_, err = ro.OnGossipBroadcast(1, rw.Gossip().Encode()[0])
+	require.NoError(t, err)
+
+	// The state is never shared with peers.
+	require.Nil(t, ro.Gossip())
+	rw.st = newState(defaultShards, nil)
+	require.NoError(t, rw.Repair(2))
+	require.Empty(t, rw.st.all())
+}
+
+func BenchmarkQueryDuringMerge(b *testing.B) {
+	for _, shards := range []int{1, defaultShards} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			benchmarkQueryDuringMerge(b, shards)
+		})
+	}
+}
+
+// benchmarkQueryDuringMerge measures deduplication queries of many groups
+// while entries received from peers are merged concurrently.
+func benchmarkQueryDuringMerge(b *testing.B, shards int) {
+	const (
+		groups    = 5000
+		batchSize = 100
+	)
+	var (
+		now     = utcNow()
+		recv    = &pb.Receiver{GroupName: "bench", Integration: "email"}
+		entries = make([]*pb.MeshEntry, groups)
+	)
+	nl, err := New(WithShards(shards), WithRetention(time.Hour))
+	require.NoError(b, err)
+
+	for i := range entries {
+		entries[i] = &pb.MeshEntry{
+			Entry: &pb.Entry{
+				GroupKey:  []byte(fmt.Sprintf("group-%d", i)),
+				Receiver:  recv,
+				Timestamp: now,
+			},
+			ExpiresAt: now.Add(time.Hour),
+		}
+	}
+	_, err = nl.Merge(entries...)
+	require.NoError(b, err)
+
+	var (
+		stopc = make(chan struct{})
+		done  = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		for i := 1; ; i++ {
+			select {
+			case <-stopc:
+				return
+			default:
+			}
+			batch := make([]*pb.MeshEntry, 0, batchSize)
+			for j := 0; j < batchSize; j++ {
+				e := entries[(i*batchSize+j)%groups]
+				batch = append(batch, &pb.MeshEntry{
+					Entry: &pb.Entry{
+						GroupKey:  e.Entry.GroupKey,
+						Receiver:  recv,
+						Timestamp: now.Add(time.Duration(i) * time.Millisecond),
+					},
+					ExpiresAt: e.ExpiresAt,
+				})
+			}
+			if _, err := nl.Merge(batch...); err != nil {
+				panic(err)
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(p *testing.PB) {
+		for i := 0; p.Next(); i++ {
+			gkey := string(entries[i%groups].Entry.GroupKey)
+			if _, err := nl.Query(QReceiver(recv), QGroupKey(gkey)); err != nil {
+				panic(err)
+			}
+		}
+	})
+	b.StopTimer()
+
+	close(stopc)
+	<-done
+}
@@ -45,6 +45,9 @@ type Receiver struct {
 	// Index of the receiver with respect to the integration.
 	// Every integration in a group may have 0..N configurations.
 	Idx uint32 `protobuf:"varint,3,opt,name=idx,proto3" json:"idx,omitempty"`
+	// Hash over the configuration of the integration. Entries are only
+	// shared by receivers with the same hash. Empty if not namespaced.
+	ConfigHash string `protobuf:"bytes,4,opt,name=config_hash,json=configHash,proto3" json:"config_hash,omitempty"`
 }
 
 func (m *Receiver) Reset()                    { *m = Receiver{} }
@@ -71,6 +74,17 @@ type Entry struct {
 	FiringAlerts []uint64 `protobuf:"varint,6,rep,packed,name=firing_alerts,json=firingAlerts" json:"firing_alerts,omitempty"`
 	// ResolvedAlerts list of hashes of resolved alerts at the last notification time.
 	ResolvedAlerts []uint64 `protobuf:"varint,7,rep,packed,name=resolved_alerts,json=resolvedAlerts" json:"resolved_alerts,omitempty"`
+	// Identifier of the notification returned by the notified provider,
+	// such as a PagerDuty incident key or a Slack message timestamp.
+	ProviderId string `protobuf:"bytes,8,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	// Whether the entry marks the deletion of the previous entry for the
+	// group and receiver.
+	Deleted bool `protobuf:"varint,9,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	// Time at which the notification was acknowledged at the provider.
+	AcknowledgedAt *time.Time `protobuf:"bytes,10,opt,name=acknowledged_at,json=acknowledgedAt,stdtime" json:"acknowledged_at,omitempty"`
+	// Hash over the annotations of the firing alerts whose changes are
+	// notified about at notification time. Zero if they are not tracked.
+	AnnotationsHash uint64 `protobuf:"varint,11,opt,name=annotations_hash,json=annotationsHash,proto3" json:"annotations_hash,omitempty"`
 }
 
 func (m *Entry) Reset()                    { *m = Entry{} }
@@ -130,6 +144,12 @@ func (m *Receiver) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintNflog(dAtA, i, uint64(m.Idx))
 	}
+	if len(m.ConfigHash) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintNflog(dAtA, i, uint64(len(m.ConfigHash)))
+		i += copy(dAtA[i:], m.ConfigHash)
+	}
 	return i, nil
 }
 
@@ -222,6 +242,37 @@ func (m *Entry) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintNflog(dAtA, i, uint64(j5))
 		i += copy(dAtA[i:], dAtA6[:j5])
 	}
+	if len(m.ProviderId) > 0 {
+		dAtA[i] = 0x42
+		i++
+		i = encodeVarintNflog(dAtA, i, uint64(len(m.ProviderId)))
+		i += copy(dAtA[i:], m.ProviderId)
+	}
+	if m.Deleted {
+		dAtA[i] = 0x48
+		i++
+		if m.Deleted {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.AcknowledgedAt != nil {
+		dAtA[i] = 0x52
+		i++
+		i = encodeVarintNflog(dAtA, i, uint64(github_com_gogo_protobuf_types.SizeOfStdTime(*m.AcknowledgedAt)))
+		n7, err := github_com_gogo_protobuf_types.StdTimeMarshalTo(*m.AcknowledgedAt, dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n7
+	}
+	if m.AnnotationsHash != 0 {
+		dAtA[i] = 0x58
+		i++
+		i = encodeVarintNflog(dAtA, i, uint64(m.AnnotationsHash))
+	}
 	return i, nil
 }
 
@@ -244,20 +295,20 @@ func (m *MeshEntry) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0xa
 		i++
 		i = encodeVarintNflog(dAtA, i, uint64(m.Entry.Size()))
-		n7, err := m.Entry.MarshalTo(dAtA[i:])
+		n8, err := m.Entry.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n7
+		i += n8
 	}
 	dAtA[i] = 0x12
 	i++
 	i = encodeVarintNflog(dAtA, i, uint64(github_com_gogo_protobuf_types.SizeOfStdTime(m.ExpiresAt)))
-	n8, err := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ExpiresAt, dAtA[i:])
+	n9, err := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ExpiresAt, dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n8
+	i += n9
 	return i, nil
 }
 
@@ -302,6 +353,10 @@ func (m *Receiver) Size() (n int) {
 	if m.Idx != 0 {
 		n += 1 + sovNflog(uint64(m.Idx))
 	}
+	l = len(m.ConfigHash)
+	if l > 0 {
+		n += 1 + l + sovNflog(uint64(l))
+	}
 	return n
 }
 
@@ -339,6 +394,20 @@ func (m *Entry) Size() (n int) {
 		}
 		n += 1 + sovNflog(uint64(l)) + l
 	}
+	l = len(m.ProviderId)
+	if l > 0 {
+		n += 1 + l + sovNflog(uint64(l))
+	}
+	if m.Deleted {
+		n += 2
+	}
+	if m.AcknowledgedAt != nil {
+		l = github_com_gogo_protobuf_types.SizeOfStdTime(*m.AcknowledgedAt)
+		n += 1 + l + sovNflog(uint64(l))
+	}
+	if m.AnnotationsHash != 0 {
+		n += 1 + sovNflog(uint64(m.AnnotationsHash))
+	}
 	return n
 }
 
@@ -473,6 +542,35 @@ func (m *Receiver) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConfigHash", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNflog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthNflog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ConfigHash = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipNflog(dAtA[iNdEx:])
@@ -792,6 +890,107 @@ func (m *Entry) Unmarshal(dAtA []byte) error {
 			} else {
 				return fmt.Errorf("proto: wrong wireType = %d for field ResolvedAlerts", wireType)
 			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProviderId", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNflog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthNflog
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ProviderId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Deleted", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNflog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Deleted = bool(v != 0)
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AcknowledgedAt", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNflog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthNflog
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.AcknowledgedAt == nil {
+				m.AcknowledgedAt = new(time.Time)
+			}
+			if err := github_com_gogo_protobuf_types.StdTimeUnmarshal(m.AcknowledgedAt, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AnnotationsHash", wireType)
+			}
+			m.AnnotationsHash = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowNflog
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.AnnotationsHash |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipNflog(dAtA[iNdEx:])
@@ -1034,30 +1233,34 @@ var (
 func init() { proto.RegisterFile("nflog.proto", fileDescriptorNflog) }
 
 var fileDescriptorNflog = []byte{
-	// 385 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x90, 0xcf, 0x6e, 0xd3, 0x40,
-	0x10, 0xc6, 0xbb, 0x4d, 0xd3, 0xda, 0xe3, 0xb4, 0x94, 0x15, 0x07, 0xcb, 0x08, 0xc7, 0x0a, 0x48,
-	0xf8, 0x82, 0x23, 0x95, 0x27, 0x68, 0x10, 0x12, 0x12, 0x82, 0xc3, 0x8a, 0x2b, 0xb2, 0x36, 0x74,
-	0xb2, 0x5e, 0x61, 0x7b, 0xad, 0xf5, 0x36, 0x6a, 0xde, 0x82, 0x47, 0xe0, 0x71, 0x72, 0xe4, 0x09,
-	0xf8, 0x93, 0x27, 0x41, 0xde, 0xb5, 0x1d, 0x8e, 0xdc, 0x66, 0x7f, 0xf3, 0xcd, 0xcc, 0xb7, 0x1f,
-	0x04, 0xf5, 0xa6, 0x54, 0x22, 0x6b, 0xb4, 0x32, 0x8a, 0x5e, 0xd8, 0x47, 0xb3, 0x8e, 0xe6, 0x42,
-	0x29, 0x51, 0xe2, 0xd2, 0xe2, 0xf5, 0xfd, 0x66, 0x69, 0x64, 0x85, 0xad, 0xe1, 0x55, 0xe3, 0x94,
-	0xd1, 0x13, 0xa1, 0x84, 0xb2, 0xe5, 0xb2, 0xab, 0x1c, 0x5d, 0x7c, 0x06, 0x8f, 0xe1, 0x17, 0x94,
-	0x5b, 0xd4, 0xf4, 0x19, 0x80, 0xd0, 0xea, 0xbe, 0xc9, 0x6b, 0x5e, 0x61, 0x48, 0x12, 0x92, 0xfa,
-	0xcc, 0xb7, 0xe4, 0x23, 0xaf, 0x90, 0x26, 0x10, 0xc8, 0xda, 0xa0, 0xd0, 0xdc, 0x48, 0x55, 0x87,
-	0xa7, 0xb6, 0xff, 0x2f, 0xa2, 0xd7, 0x30, 0x91, 0x77, 0x0f, 0xe1, 0x24, 0x21, 0xe9, 0x25, 0xeb,
-	0xca, 0xc5, 0xf7, 0x53, 0x98, 0xbe, 0xad, 0x8d, 0xde, 0xd1, 0xa7, 0xe0, 0x56, 0xe5, 0x5f, 0x71,
-	0x67, 0x77, 0xcf, 0x98, 0x67, 0xc1, 0x7b, 0xdc, 0xd1, 0x57, 0xe0, 0xe9, 0xde, 0x85, 0xdd, 0x1b,
-	0xdc, 0x3c, 0xce, 0xfa, 0x8f, 0x65, 0x83, 0x3d, 0x36, 0x4a, 0x8e, 0x46, 0x0b, 0xde, 0x16, 0xf6,
-	0xdc, 0xac, 0x37, 0xfa, 0x8e, 0xb7, 0x05, 0x8d, 0xba, 0x6d, 0xad, 0x2a, 0xb7, 0x78, 0x17, 0x9e,
-	0x25, 0x24, 0xf5, 0xd8, 0xf8, 0xa6, 0x2b, 0xf0, 0xc7, 0x60, 0xc2, 0xa9, 0x3d, 0x15, 0x65, 0x2e,
-	0xba, 0x6c, 0x88, 0x2e, 0xfb, 0x34, 0x28, 0x56, 0xde, 0xfe, 0xe7, 0xfc, 0xe4, 0xdb, 0xaf, 0x39,
-	0x61, 0xc7, 0x31, 0xfa, 0x1c, 0x2e, 0x37, 0x52, 0xcb, 0x5a, 0xe4, 0xbc, 0x44, 0x6d, 0xda, 0xf0,
-	0x3c, 0x99, 0xa4, 0x67, 0x6c, 0xe6, 0xe0, 0xad, 0x65, 0xf4, 0x25, 0x3c, 0x1a, 0x8e, 0x0e, 0xb2,
-	0x0b, 0x2b, 0xbb, 0x1a, 0xb0, 0x13, 0x2e, 0xb6, 0xe0, 0x7f, 0xc0, 0xb6, 0x70, 0x29, 0xbd, 0x80,
-	0x29, 0x76, 0x85, 0x4d, 0x28, 0xb8, 0xb9, 0x1a, 0x53, 0xb0, 0x6d, 0xe6, 0x9a, 0xf4, 0x0d, 0x00,
-	0x3e, 0x34, 0x52, 0x63, 0x9b, 0x73, 0xd3, 0x07, 0xf6, 0x9f, 0xbf, 0xe8, 0xe7, 0x6e, 0xcd, 0xea,
-	0x7a, 0xff, 0x27, 0x3e, 0xd9, 0x1f, 0x62, 0xf2, 0xe3, 0x10, 0x93, 0xdf, 0x87, 0x98, 0xac, 0xcf,
-	0xed, 0xe8, 0xeb, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x49, 0xcd, 0xa7, 0x1e, 0x61, 0x02, 0x00,
-	0x00,
+	// 463 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x95, 0x51, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0x6e, 0xea, 0xa4, 0xb1, 0xc7, 0x6d, 0x92, 0xae, 0x38, 0xac, 0x82, 0x68, 0xa2, 0x82, 0x44,
+	0x39, 0xe0, 0x48, 0xe5, 0x09, 0x12, 0x84, 0x44, 0x85, 0xe0, 0xb0, 0xe2, 0x6e, 0x39, 0xf1, 0x66,
+	0xb3, 0xaa, 0xb3, 0x6b, 0xd9, 0xdb, 0xb4, 0xb9, 0xf4, 0x19, 0xfa, 0x58, 0x39, 0xc2, 0x0b, 0xd0,
+	0xc2, 0x93, 0xb0, 0x9e, 0x8d, 0xd3, 0xdc, 0x10, 0x87, 0x95, 0x66, 0xbf, 0xf9, 0xf9, 0xe6, 0xfb,
+	0x06, 0x42, 0x35, 0xcf, 0xb4, 0x88, 0xf2, 0x42, 0x1b, 0x4d, 0xda, 0xf8, 0xc9, 0xa7, 0xfd, 0x81,
+	0xd0, 0x5a, 0x64, 0x7c, 0x84, 0xf0, 0xf4, 0x66, 0x3e, 0x32, 0x72, 0xc9, 0x4b, 0x93, 0x2c, 0x73,
+	0x57, 0xd9, 0x7f, 0x21, 0xb4, 0xd0, 0x18, 0x8e, 0xaa, 0xc8, 0xa1, 0xe7, 0xf7, 0xe0, 0x33, 0x3e,
+	0xe3, 0x72, 0xc5, 0x0b, 0xf2, 0x0a, 0x40, 0x14, 0xfa, 0x26, 0x8f, 0x55, 0xb2, 0xe4, 0xb4, 0x31,
+	0x6c, 0x5c, 0x04, 0x2c, 0x40, 0xe4, 0x9b, 0x05, 0xc8, 0x10, 0x42, 0xa9, 0x0c, 0x17, 0x45, 0x62,
+	0xa4, 0x56, 0xf4, 0x10, 0xf3, 0xfb, 0x10, 0xe9, 0x81, 0x27, 0xd3, 0x3b, 0xea, 0xd9, 0xcc, 0x09,
+	0xab, 0x42, 0x32, 0x80, 0x70, 0xa6, 0xd5, 0x5c, 0x8a, 0x78, 0x91, 0x94, 0x0b, 0xda, 0xc4, 0x1e,
+	0x70, 0xd0, 0x67, 0x8b, 0x9c, 0xff, 0xf4, 0xa0, 0xf5, 0x49, 0x99, 0x62, 0x4d, 0x5e, 0x82, 0xe3,
+	0x8a, 0xaf, 0xf9, 0x1a, 0xc9, 0x8f, 0x99, 0x8f, 0xc0, 0x17, 0xbe, 0x26, 0xef, 0xc1, 0x2f, 0xb6,
+	0x6b, 0x22, 0x71, 0x78, 0x79, 0x1a, 0x6d, 0x95, 0x47, 0xf5, 0xfe, 0x6c, 0x57, 0xf2, 0xac, 0x04,
+	0x59, 0x3d, 0x1c, 0xe6, 0xa6, 0x57, 0xa4, 0xa4, 0x5f, 0x4d, 0x2b, 0x75, 0xb6, 0xe2, 0x29, 0xae,
+	0xe4, 0xb3, 0xdd, 0x9f, 0x4c, 0x20, 0xd8, 0x39, 0x47, 0x5b, 0x48, 0xd5, 0x8f, 0x9c, 0xb7, 0x51,
+	0xed, 0x6d, 0xf4, 0xbd, 0xae, 0x98, 0xf8, 0x9b, 0x5f, 0x83, 0x83, 0x87, 0xc7, 0x41, 0x83, 0x3d,
+	0xb7, 0x91, 0xd7, 0x70, 0x32, 0x97, 0x85, 0x54, 0x22, 0x4e, 0x32, 0x5e, 0x98, 0x92, 0x1e, 0x0d,
+	0xbd, 0x8b, 0x26, 0x3b, 0x76, 0xe0, 0x18, 0x31, 0xf2, 0x16, 0xba, 0x35, 0x69, 0x5d, 0xd6, 0xc6,
+	0xb2, 0x4e, 0x0d, 0x6f, 0x0b, 0xad, 0x87, 0x96, 0x78, 0x25, 0x53, 0x5e, 0xc4, 0x32, 0xa5, 0xbe,
+	0xf3, 0xb0, 0x86, 0xae, 0x52, 0x42, 0xa1, 0x9d, 0xf2, 0x8c, 0x1b, 0xab, 0x26, 0x40, 0x35, 0xf5,
+	0x97, 0x5c, 0x41, 0x37, 0x99, 0x5d, 0x2b, 0x7d, 0x9b, 0xf1, 0x54, 0x54, 0x3c, 0x86, 0xc2, 0x3f,
+	0x25, 0x35, 0x51, 0x4e, 0x67, 0xbf, 0x71, 0x6c, 0xc8, 0x3b, 0xe8, 0x25, 0x4a, 0x69, 0x83, 0x97,
+	0x2e, 0x9d, 0xb1, 0xa1, 0x9d, 0xd5, 0x64, 0xdd, 0x3d, 0x1c, 0x6f, 0xba, 0x82, 0xe0, 0x2b, 0x2f,
+	0x17, 0xee, 0xac, 0x6f, 0xa0, 0xc5, 0xab, 0x00, 0x4f, 0x1a, 0x5e, 0x76, 0x76, 0x67, 0xc3, 0x34,
+	0x73, 0x49, 0xf2, 0x11, 0x80, 0xdf, 0xe5, 0xd2, 0x2a, 0xaf, 0x76, 0x3c, 0xfc, 0x1f, 0xdb, 0xb7,
+	0x7d, 0x63, 0x33, 0xe9, 0x6d, 0x7e, 0x9f, 0x1d, 0x6c, 0xfe, 0x9c, 0x35, 0x7e, 0xd8, 0xf7, 0x64,
+	0xdf, 0xf4, 0x08, 0x5b, 0x3f, 0xfc, 0x05, 0xa4, 0x4c, 0x47, 0xc5, 0x33, 0x03, 0x00, 0x00,
 }
@@ -0,0 +1,159 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+)
+
+var timeToAcknowledge = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "alertmanager",
+	Name:      "notification_time_to_acknowledge_seconds",
+	Help:      "Time from notifications to their acknowledgement at the provider.",
+	Buckets:   []float64{30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400, 28800},
+}, []string{"integration"})
+
+func init() {
+	prometheus.Register(timeToAcknowledge)
+}
+
+// Acknowledgement is the acknowledgement of a notification reported by the
+// provider it was sent to.
+type Acknowledgement struct {
+	// DedupKey identifies the notification at the provider, such as the
+	// PagerDuty incident key or the OpsGenie alert alias.
+	DedupKey string
+	// Time at which the notification was acknowledged.
+	At time.Time
+}
+
+// ParseAcknowledgements decodes the acknowledgements in a webhook payload
+// sent by the provider of the integration. Other events in the payload are
+// ignored. Acknowledgements without a time of their own are considered to
+// have happened at now.
+func ParseAcknowledgements(integration string, r io.Reader, now time.Time) ([]Acknowledgement, error) {
+	var acks []Acknowledgement
+
+	switch integration {
+	case "pagerduty":
+		// Webhooks v2 deliver batches of incident events.
+		var msg struct {
+			Messages []struct {
+				Event     string    `json:"event"`
+				CreatedOn time.Time `json:"created_on"`
+				Incident  struct {
+					IncidentKey string `json:"incident_key"`
+				} `json:"incident"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r).Decode(&msg); err != nil {
+			return nil, err
+		}
+		for _, m := range msg.Messages {
+			if m.Event != "incident.acknowledge" || m.Incident.IncidentKey == "" {
+				continue
+			}
+			at := m.CreatedOn
+			if at.IsZero() {
+				at = now
+			}
+			acks = append(acks, Acknowledgement{DedupKey: m.Incident.IncidentKey, At: at})
+		}
+	case "opsgenie":
+		// Outgoing webhooks deliver a single alert action.
+		var msg struct {
+			Action string `json:"action"`
+			Alert  struct {
+				Alias string `json:"alias"`
+			} `json:"alert"`
+		}
+		if err := json.NewDecoder(r).Decode(&msg); err != nil {
+			return nil, err
+		}
+		if msg.Action == "Acknowledge" && msg.Alert.Alias != "" {
+			acks = append(acks, Acknowledgement{DedupKey: msg.Alert.Alias, At: now})
+		}
+	default:
+		return nil, fmt.Errorf("acknowledgements of integration %q are not supported", integration)
+	}
+	return acks, nil
+}
+
+// Acknowledge records the acknowledgements of notifications of the
+// integration in the notification log and observes the time it took to
+// acknowledge them. Notifications are correlated by the deduplication key
+// returned by the provider, or derived from the group key as done by the
+// PagerDuty and OpsGenie notifiers. It returns the entries acknowledged for
+// the first time.
+func Acknowledge(l nflog.Log, integration string, acks []Acknowledgement) ([]*nflogpb.Entry, error) {
+	if len(acks) == 0 {
+		return nil, nil
+	}
+	// Providers may report multiple acknowledgements of a notification,
+	// the earliest one counts.
+	keys := make(map[string]time.Time, len(acks))
+	for _, a := range acks {
+		if at, ok := keys[a.DedupKey]; !ok || a.At.Before(at) {
+			keys[a.DedupKey] = a.At
+		}
+	}
+
+	it, err := l.QueryIter()
+	if err != nil {
+		return nil, err
+	}
+	type match struct {
+		e  *nflogpb.Entry
+		at time.Time
+	}
+	var matches []match
+	for it.Next() {
+		e := it.At()
+		if e.Receiver.Integration != integration {
+			continue
+		}
+		at, ok := keys[e.ProviderId]
+		if !ok {
+			at, ok = keys[hashKey(string(e.GroupKey))]
+		}
+		if ok {
+			matches = append(matches, match{e: e, at: at})
+		}
+	}
+
+	var res []*nflogpb.Entry
+	for _, m := range matches {
+		e, ok, err := l.Acknowledge(m.e.Receiver, m.e.GroupKey, m.at)
+		if err == nflog.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			continue
+		}
+		timeToAcknowledge.WithLabelValues(integration).Observe(m.at.Sub(m.e.Timestamp).Seconds())
+		res = append(res, e)
+	}
+	return res, nil
+}
@@ -0,0 +1,309 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var (
+	numDecisionEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "decision_log_events_total",
+		Help:      "The total number of recorded notification pipeline decisions.",
+	}, []string{"decision"})
+	numDroppedDecisionEvents = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "decision_log_events_dropped_total",
+		Help:      "The total number of notification pipeline decisions that were not posted as the queue was full or posting failed.",
+	})
+)
+
+func init() {
+	for _, d := range []string{DecisionInhibited, DecisionSilenced, DecisionDeduplicated, DecisionSent, DecisionFailed} {
+		numDecisionEvents.WithLabelValues(d)
+	}
+
+	prometheus.Register(numDecisionEvents)
+	prometheus.Register(numDroppedDecisionEvents)
+}
+
+// Decisions of the notification pipeline.
+const (
+	// DecisionInhibited means alerts were not notified about as they are
+	// inhibited.
+	DecisionInhibited = "inhibited"
+	// DecisionSilenced means alerts were not notified about as they are
+	// silenced.
+	DecisionSilenced = "silenced"
+	// DecisionDeduplicated means no notification was sent to an
+	// integration as it was notified about the alerts before.
+	DecisionDeduplicated = "deduplicated"
+	// DecisionSent means a notification was sent to an integration.
+	DecisionSent = "sent"
+	// DecisionFailed means sending a notification to an integration
+	// failed.
+	DecisionFailed = "failed"
+)
+
+const (
+	// decisionQueueSize is the number of events held while they are posted.
+	decisionQueueSize = 1000
+	// maxDecisionBatch is the maximum number of events posted at once.
+	maxDecisionBatch = 100
+)
+
+// A DecisionEvent describes a decision of the notification pipeline about
+// alerts of a group.
+type DecisionEvent struct {
+	Time        time.Time      `json:"time"`
+	Decision    string         `json:"decision"`
+	Receiver    string         `json:"receiver"`
+	GroupKey    string         `json:"groupKey"`
+	GroupLabels model.LabelSet `json:"groupLabels"`
+	// Integration is set for decisions made for each integration of the
+	// receiver.
+	Integration string          `json:"integration,omitempty"`
+	Alerts      []DecisionAlert `json:"alerts"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// A DecisionAlert is an alert a decision was made about.
+type DecisionAlert struct {
+	Fingerprint string         `json:"fingerprint"`
+	Labels      model.LabelSet `json:"labels"`
+	Status      string         `json:"status"`
+	SilencedBy  []string       `json:"silencedBy,omitempty"`
+	InhibitedBy []string       `json:"inhibitedBy,omitempty"`
+}
+
+// A DecisionLog posts the decisions of notification pipelines to a
+// webhook. Events are posted asynchronously and dropped if they cannot be
+// posted fast enough, so that notifications are not held up.
+type DecisionLog struct {
+	url     string
+	timeout time.Duration
+	marker  types.Marker
+	logger  log.Logger
+	events  chan *DecisionEvent
+
+	ctx    context.Context
+	cancel func()
+	done   chan struct{}
+}
+
+// NewDecisionLog returns a new DecisionLog posting events as configured.
+// The marker provides the silences and inhibitions of alerts. It returns
+// nil if the configuration is nil.
+func NewDecisionLog(conf *config.DecisionLog, m types.Marker, l log.Logger) *DecisionLog {
+	if conf == nil {
+		return nil
+	}
+	d := &DecisionLog{
+		url:     conf.URL,
+		timeout: time.Duration(conf.Timeout),
+		marker:  m,
+		logger:  l,
+		events:  make(chan *DecisionEvent, decisionQueueSize),
+		done:    make(chan struct{}),
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	return d
+}
+
+// Run posts recorded events until the DecisionLog is stopped. Events
+// still queued when stopping are posted before returning. Posts are bound
+// by the timeout only, so that stopping does not abort them.
+func (d *DecisionLog) Run() {
+	defer close(d.done)
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			for len(d.events) > 0 {
+				d.post(d.batch(<-d.events))
+			}
+			return
+		case e := <-d.events:
+			d.post(d.batch(e))
+		}
+	}
+}
+
+// Stop the DecisionLog and wait for queued events to be posted.
+func (d *DecisionLog) Stop() {
+	if d == nil {
+		return
+	}
+	d.cancel()
+	<-d.done
+}
+
+// batch returns the event along with the queued events, up to the maximum
+// batch size.
+func (d *DecisionLog) batch(e *DecisionEvent) []*DecisionEvent {
+	events := []*DecisionEvent{e}
+	for len(events) < maxDecisionBatch {
+		select {
+		case e := <-d.events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+func (d *DecisionLog) post(events []*DecisionEvent) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(events); err != nil {
+		numDroppedDecisionEvents.Add(float64(len(events)))
+		level.Error(d.logger).Log("msg", "Encoding decision events failed", "err", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	resp, err := ctxhttp.Post(ctx, http.DefaultClient, d.url, contentTypeJSON, &buf)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			err = fmt.Errorf("unexpected status code %v", resp.StatusCode)
+		}
+	}
+	if err != nil {
+		numDroppedDecisionEvents.Add(float64(len(events)))
+		level.Error(d.logger).Log("msg", "Posting decision events failed", "events", len(events), "err", err)
+	}
+}
+
+// record queues an event about the decision for the alerts of the group
+// whose notification is in progress.
+func (d *DecisionLog) record(ctx context.Context, decision, integration string, alerts []*types.Alert, err error) {
+	now, ok := Now(ctx)
+	if !ok {
+		now = time.Now()
+	}
+	e := &DecisionEvent{
+		Time:        now,
+		Decision:    decision,
+		Integration: integration,
+		Alerts:      make([]DecisionAlert, 0, len(alerts)),
+	}
+	e.Receiver, _ = ReceiverName(ctx)
+	e.GroupKey, _ = GroupKey(ctx)
+	e.GroupLabels, _ = GroupLabels(ctx)
+	if err != nil {
+		e.Error = err.Error()
+	}
+	for _, a := range alerts {
+		fp := a.Fingerprint()
+		da := DecisionAlert{
+			Fingerprint: fp.String(),
+			Labels:      a.Labels,
+			Status:      string(a.Status()),
+		}
+		switch decision {
+		case DecisionSilenced:
+			da.SilencedBy, _ = d.marker.Silenced(fp)
+		case DecisionInhibited:
+			da.InhibitedBy, _ = d.marker.Inhibited(fp)
+		}
+		e.Alerts = append(e.Alerts, da)
+	}
+
+	numDecisionEvents.WithLabelValues(decision).Inc()
+	select {
+	case d.events <- e:
+	default:
+		numDroppedDecisionEvents.Inc()
+	}
+}
+
+// muteStage returns a stage recording the alerts filtered by s, which
+// mutes alerts for the given decision. s is returned if d is nil.
+func (d *DecisionLog) muteStage(s Stage, decision string) Stage {
+	if d == nil {
+		return s
+	}
+	return StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		ctx, res, err := s.Exec(ctx, l, alerts...)
+		if err != nil || len(res) == len(alerts) {
+			return ctx, res, err
+		}
+		passed := make(map[*types.Alert]struct{}, len(res))
+		for _, a := range res {
+			passed[a] = struct{}{}
+		}
+		var muted []*types.Alert
+		for _, a := range alerts {
+			if _, ok := passed[a]; !ok {
+				muted = append(muted, a)
+			}
+		}
+		d.record(ctx, decision, "", muted, nil)
+		return ctx, res, err
+	})
+}
+
+// dedupStage returns a stage recording if s deduplicates the notification
+// to the integration. s is returned if d is nil.
+func (d *DecisionLog) dedupStage(s Stage, integration string) Stage {
+	if d == nil {
+		return s
+	}
+	return StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		ctx, res, err := s.Exec(ctx, l, alerts...)
+		if err == nil && len(res) == 0 {
+			d.record(ctx, DecisionDeduplicated, integration, alerts, nil)
+		}
+		return ctx, res, err
+	})
+}
+
+// retryStage returns a stage recording whether s notified the integration
+// successfully. s is returned if d is nil.
+func (d *DecisionLog) retryStage(s *RetryStage) Stage {
+	if d == nil {
+		return s
+	}
+	return StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		if skip, err := s.skips(ctx, alerts); err != nil || skip {
+			return s.Exec(ctx, l, alerts...)
+		}
+		ctx, res, err := s.Exec(ctx, l, alerts...)
+		if err != nil {
+			d.record(ctx, DecisionFailed, s.integration.name, alerts, err)
+		} else {
+			d.record(ctx, DecisionSent, s.integration.name, alerts, nil)
+		}
+		return ctx, res, err
+	})
+}
@@ -15,11 +15,14 @@ package notify
 
 import (
 	"bytes"
+	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime"
 	"mime/multipart"
@@ -29,6 +32,9 @@ import (
 	"net/smtp"
 	"net/textproto"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,6 +42,7 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/version"
+	"github.com/satori/go.uuid"
 	"golang.org/x/net/context"
 	"golang.org/x/net/context/ctxhttp"
 
@@ -48,6 +55,14 @@ type notifierConfig interface {
 	SendResolved() bool
 }
 
+// statefulIntegrations are integrations which track incidents on the remote
+// side. They are always notified about force-resolved alerts so that no
+// incident remains open.
+var statefulIntegrations = map[string]bool{
+	"pagerduty": true,
+	"opsgenie":  true,
+}
+
 // A Notifier notifies about alerts under constraints of the given context.
 // It returns an error if unsuccessful and a flag whether the error is
 // recoverable. This information is useful for a retry logic.
@@ -74,7 +89,7 @@ func (i *Integration) Notify(ctx context.Context, alerts ...*types.Alert) (bool,
 		res = alerts
 	} else {
 		for _, a := range alerts {
-			if a.Status() != model.AlertResolved {
+			if a.Status() != model.AlertResolved || i.closes(a) {
 				res = append(res, a)
 			}
 		}
@@ -86,6 +101,12 @@ func (i *Integration) Notify(ctx context.Context, alerts ...*types.Alert) (bool,
 	return i.notifier.Notify(ctx, res...)
 }
 
+// closes returns true iff the integration has to be notified about the
+// alert to close a remote incident regardless of its send_resolved setting.
+func (i *Integration) closes(a *types.Alert) bool {
+	return a.ForceResolved && statefulIntegrations[i.name]
+}
+
 // BuildReceiverIntegrations builds a list of integration notifiers off of a
 // receivers config.
 func BuildReceiverIntegrations(nc *config.Receiver, tmpl *template.Template, logger log.Logger) []Integration {
@@ -129,10 +150,22 @@ func BuildReceiverIntegrations(nc *config.Receiver, tmpl *template.Template, log
 		n := NewVictorOps(c, tmpl, logger)
 		add("victorops", i, n, c)
 	}
+	for i, c := range nc.DingTalkConfigs {
+		n := NewDingTalk(c, tmpl, logger)
+		add("dingtalk", i, n, c)
+	}
+	for i, c := range nc.LarkConfigs {
+		n := NewLark(c, tmpl, logger)
+		add("lark", i, n, c)
+	}
 	for i, c := range nc.PushoverConfigs {
 		n := NewPushover(c, tmpl, logger)
 		add("pushover", i, n, c)
 	}
+	for i, c := range nc.FileConfigs {
+		n := NewFile(c, tmpl, logger)
+		add("file", i, n, c)
+	}
 	return integrations
 }
 
@@ -140,17 +173,37 @@ const contentTypeJSON = "application/json"
 
 var userAgentHeader = fmt.Sprintf("Alertmanager/%s", version.Version)
 
+// do sends the request with the default client. The trace context of the
+// notification is propagated along with it.
+func do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if tp, ok := TraceParent(ctx); ok {
+		req.Header.Set("traceparent", tp)
+	}
+	return ctxhttp.Do(ctx, http.DefaultClient, req)
+}
+
+// post issues a POST request to the URL like do.
+func post(ctx context.Context, rawurl, bodyType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest("POST", rawurl, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", bodyType)
+	return do(ctx, req)
+}
+
 // Webhook implements a Notifier for generic webhooks.
 type Webhook struct {
 	// The URL to which notifications are sent.
 	URL    string
+	conf   *config.WebhookConfig
 	tmpl   *template.Template
 	logger log.Logger
 }
 
 // NewWebhook returns a new Webhook.
 func NewWebhook(conf *config.WebhookConfig, t *template.Template, l log.Logger) *Webhook {
-	return &Webhook{URL: conf.URL, tmpl: t, logger: l}
+	return &Webhook{URL: conf.URL, conf: conf, tmpl: t, logger: l}
 }
 
 // WebhookMessage defines the JSON object send to webhook endpoints.
@@ -160,42 +213,204 @@ type WebhookMessage struct {
 	// The protocol version.
 	Version  string `json:"version"`
 	GroupKey string `json:"groupKey"`
+
+	Pagination *WebhookPagination `json:"pagination,omitempty"`
+}
+
+// WebhookMessageV2 defines the JSON object send to webhook endpoints
+// pinned to the v2 payload.
+type WebhookMessageV2 struct {
+	*template.Data
+
+	Version  string         `json:"version"`
+	GroupKey string         `json:"groupKey"`
+	Alerts   []WebhookAlert `json:"alerts"`
+	// TruncatedAlerts is the number of alerts left out of the payload
+	// due to the configured limit.
+	TruncatedAlerts int `json:"truncatedAlerts"`
+
+	Pagination *WebhookPagination `json:"pagination,omitempty"`
+}
+
+// WebhookAlert holds one alert of a v2 webhook payload.
+type WebhookAlert struct {
+	template.Alert
+
+	Fingerprint string `json:"fingerprint"`
+}
+
+// WebhookPagination identifies a part of a notification that was split
+// into multiple requests as it exceeded the maximum payload size. All
+// parts share the notification ID and hold the same data apart from the
+// alerts.
+type WebhookPagination struct {
+	NotificationID string `json:"notificationId"`
+	// Part is the 1-based index of the part.
+	Part  int `json:"part"`
+	Parts int `json:"parts"`
 }
 
 // Notify implements the Notifier interface.
 func (w *Webhook) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
-	data := w.tmpl.Data(receiverName(ctx, w.logger), groupLabels(ctx, w.logger), alerts...)
+	data := templateData(ctx, w.tmpl, w.logger, alerts...)
 
 	groupKey, ok := GroupKey(ctx)
 	if !ok {
 		level.Error(w.logger).Log("msg", "group key missing")
 	}
 
-	msg := &WebhookMessage{
-		Version:  "4",
-		Data:     data,
-		GroupKey: groupKey,
+	// Only payload v2 tells receivers about left out alerts.
+	var truncated int
+	if n := w.conf.MaxAlerts; n > 0 && len(data.Alerts) > n && w.conf.PayloadVersion == config.WebhookPayloadV2 {
+		truncated = len(data.Alerts) - n
+		data.Alerts = data.Alerts[:n]
 	}
 
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+	bodies, err := w.payloads(data, groupKey, notificationID(ctx, groupKey, alerts), alerts, truncated)
+	if err != nil {
 		return false, err
 	}
+	// Parts waiting to be sent are held against the payload budget, so
+	// that they are spilled to disk if they exceed it.
+	parts := make([]*payload, 0, len(bodies))
+	defer func() {
+		for _, p := range parts {
+			p.release()
+		}
+	}()
+	for i, b := range bodies {
+		p, err := holdPayload(ctx, b)
+		if err != nil {
+			return true, err
+		}
+		parts = append(parts, p)
+		bodies[i] = nil
+	}
+	for _, p := range parts {
+		req, err := p.request("POST", w.URL)
+		if err != nil {
+			return true, err
+		}
+		req.Header.Set("Content-Type", contentTypeJSON)
+		req.Header.Set("User-Agent", userAgentHeader)
 
-	req, err := http.NewRequest("POST", w.URL, &buf)
-	if err != nil {
-		return true, err
+		resp, err := do(ctx, req)
+		if err != nil {
+			return true, err
+		}
+		resp.Body.Close()
+
+		if retry, err := w.retry(resp.StatusCode); err != nil {
+			return retry, err
+		}
 	}
-	req.Header.Set("Content-Type", contentTypeJSON)
-	req.Header.Set("User-Agent", userAgentHeader)
+	return false, nil
+}
+
+// notificationID returns the ID shared by the parts of a notification. It
+// is derived from the group key, the time of the notification and the
+// notified alerts, so that retries resending the parts reuse it and
+// receivers can complete the parts delivered before.
+func notificationID(ctx context.Context, groupKey string, alerts []*types.Alert) string {
+	const sep = '\xff'
 
-	resp, err := ctxhttp.Do(ctx, http.DefaultClient, req)
+	b := append([]byte(groupKey), sep)
+	if now, ok := Now(ctx); ok {
+		b = strconv.AppendInt(b, now.UnixNano(), 10)
+	}
+	for _, a := range alerts {
+		b = append(b, sep)
+		b = strconv.AppendUint(b, uint64(a.Fingerprint()), 16)
+		b = append(b, sep)
+		b = append(b, a.Status()...)
+	}
+	return uuid.NewV5(uuid.NamespaceOID, string(b)).String()
+}
+
+// payloads encodes the request bodies of the notification. If the payload
+// exceeds the maximum size, the alerts are split evenly across as many
+// parts as necessary, which carry the notification ID. A single alert
+// exceeding the maximum size is sent as is.
+func (w *Webhook) payloads(data *template.Data, groupKey, id string, alerts []*types.Alert, truncated int) ([][]byte, error) {
+	encode := func(as template.Alerts, offset int, p *WebhookPagination) ([]byte, error) {
+		d := *data
+		d.Alerts = as
+
+		var msg interface{}
+		switch w.conf.PayloadVersion {
+		case config.WebhookPayloadV2:
+			m := &WebhookMessageV2{
+				Version:         config.WebhookPayloadV2,
+				Data:            &d,
+				GroupKey:        groupKey,
+				Alerts:          make([]WebhookAlert, 0, len(as)),
+				TruncatedAlerts: truncated,
+				Pagination:      p,
+			}
+			// Template data holds the alerts in the order they were passed.
+			for i, a := range as {
+				m.Alerts = append(m.Alerts, WebhookAlert{
+					Alert:       a,
+					Fingerprint: alerts[offset+i].Fingerprint().String(),
+				})
+			}
+			msg = m
+		default:
+			msg = &WebhookMessage{
+				Version:    "4",
+				Data:       &d,
+				GroupKey:   groupKey,
+				Pagination: p,
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	b, err := encode(data.Alerts, 0, nil)
 	if err != nil {
-		return true, err
+		return nil, err
+	}
+	max := w.conf.MaxPayloadSize
+	if max == 0 || len(b) <= max || len(data.Alerts) < 2 {
+		return [][]byte{b}, nil
 	}
-	resp.Body.Close()
 
-	return w.retry(resp.StatusCode)
+	// Start with the number of parts the total size requires and add
+	// parts until all of them fit.
+	for parts := (len(b) + max - 1) / max; ; parts++ {
+		if parts > len(data.Alerts) {
+			parts = len(data.Alerts)
+		}
+		var (
+			bodies = make([][]byte, 0, parts)
+			fits   = true
+		)
+		for i := 0; i < parts; i++ {
+			start, end := i*len(data.Alerts)/parts, (i+1)*len(data.Alerts)/parts
+
+			b, err := encode(data.Alerts[start:end], start, &WebhookPagination{
+				NotificationID: id,
+				Part:           i + 1,
+				Parts:          parts,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if len(b) > max && end-start > 1 {
+				fits = false
+				break
+			}
+			bodies = append(bodies, b)
+		}
+		if fits {
+			return bodies, nil
+		}
+	}
 }
 
 func (w *Webhook) retry(statusCode int) (bool, error) {
@@ -208,6 +423,111 @@ func (w *Webhook) retry(statusCode int) (bool, error) {
 	return false, nil
 }
 
+// fileTimeFormat is the format of the timestamp prefixing notification
+// files. It has a fixed width so files sort chronologically by name.
+const fileTimeFormat = "20060102T150405.000000000Z"
+
+// File implements a Notifier that writes notifications to files in a
+// spool directory. The files contain the same payload as webhook requests.
+type File struct {
+	conf   *config.FileConfig
+	tmpl   *template.Template
+	logger log.Logger
+	now    func() time.Time
+}
+
+// NewFile returns a new File notifier.
+func NewFile(c *config.FileConfig, t *template.Template, l log.Logger) *File {
+	return &File{conf: c, tmpl: t, logger: l, now: utcNow}
+}
+
+// Notify implements the Notifier interface.
+func (f *File) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	var err error
+	var (
+		data = templateData(ctx, f.tmpl, f.logger, alerts...)
+		tmpl = tmplText(f.tmpl, data, &err)
+	)
+	name := tmpl(f.conf.Filename)
+	if err != nil {
+		return false, err
+	}
+	// Notification files must not escape the spool directory.
+	name = strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(name)
+
+	groupKey, ok := GroupKey(ctx)
+	if !ok {
+		level.Error(f.logger).Log("msg", "group key missing")
+	}
+	msg := &WebhookMessage{
+		Version:  "4",
+		Data:     data,
+		GroupKey: groupKey,
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return false, err
+	}
+
+	now := f.now()
+	fn := filepath.Join(f.conf.Directory, now.Format(fileTimeFormat)+"-"+name)
+
+	// Write to a hidden temporary file first so consumers never pick up
+	// partially written notifications.
+	tmpf, err := ioutil.TempFile(f.conf.Directory, ".tmp-")
+	if err != nil {
+		return true, err
+	}
+	if _, err := tmpf.Write(b); err != nil {
+		tmpf.Close()
+		os.Remove(tmpf.Name())
+		return true, err
+	}
+	if err := tmpf.Close(); err != nil {
+		os.Remove(tmpf.Name())
+		return true, err
+	}
+	if err := os.Rename(tmpf.Name(), fn); err != nil {
+		os.Remove(tmpf.Name())
+		return true, err
+	}
+
+	if err := f.cleanup(now); err != nil {
+		level.Warn(f.logger).Log("msg", "Cleaning up notification files failed", "dir", f.conf.Directory, "err", err)
+	}
+	return false, nil
+}
+
+// cleanup removes the oldest files from the spool directory until the
+// configured limits are met. Hidden files are ignored.
+func (f *File) cleanup(now time.Time) error {
+	if f.conf.MaxFiles <= 0 && f.conf.MaxAge <= 0 {
+		return nil
+	}
+	infos, err := ioutil.ReadDir(f.conf.Directory)
+	if err != nil {
+		return err
+	}
+	var files []os.FileInfo
+	for _, fi := range infos {
+		if fi.Mode().IsRegular() && !strings.HasPrefix(fi.Name(), ".") {
+			files = append(files, fi)
+		}
+	}
+	// ReadDir returns the files sorted by name and thus by age.
+	for i, fi := range files {
+		tooMany := f.conf.MaxFiles > 0 && len(files)-i > f.conf.MaxFiles
+		tooOld := f.conf.MaxAge > 0 && fi.ModTime().Before(now.Add(-time.Duration(f.conf.MaxAge)))
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(filepath.Join(f.conf.Directory, fi.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 // Email implements a Notifier for email notifications.
 type Email struct {
 	conf   *config.EmailConfig
@@ -325,7 +645,7 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	}
 
 	var (
-		data = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data = templateData(ctx, n.tmpl, n.logger, as...)
 		tmpl = tmplText(n.tmpl, data, &err)
 		from = tmpl(n.conf.From)
 		to   = tmpl(n.conf.To)
@@ -459,7 +779,7 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 	var err error
 	var (
 		alerts    = types.Alerts(as...)
-		data      = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data      = templateData(ctx, n.tmpl, n.logger, as...)
 		tmpl      = tmplText(n.tmpl, data, &err)
 		eventType = pagerDutyEventTrigger
 	)
@@ -494,13 +814,29 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, n.conf.URL, contentTypeJSON, &buf)
+	resp, err := postPayload(ctx, n.conf.URL, contentTypeJSON, buf.Bytes())
 	if err != nil {
 		return true, err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
-	return n.retry(resp.StatusCode)
+	retry, err := n.retry(resp.StatusCode)
+	if err != nil {
+		return retry, err
+	}
+	// The incident key is the deduplication key of the event, which
+	// PagerDuty echoes in its response.
+	var res pagerDutyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || res.IncidentKey == "" {
+		res.IncidentKey = msg.IncidentKey
+	}
+	setProviderID(ctx, res.IncidentKey)
+
+	return false, nil
+}
+
+type pagerDutyResponse struct {
+	IncidentKey string `json:"incident_key"`
 }
 
 func (n *PagerDuty) retry(statusCode int) (bool, error) {
@@ -563,7 +899,7 @@ type slackAttachmentField struct {
 func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	var err error
 	var (
-		data     = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data     = templateData(ctx, n.tmpl, n.logger, as...)
 		tmplText = tmplText(n.tmpl, data, &err)
 	)
 
@@ -593,13 +929,28 @@ func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, string(n.conf.APIURL), contentTypeJSON, &buf)
+	resp, err := postPayload(ctx, string(n.conf.APIURL), contentTypeJSON, buf.Bytes())
 	if err != nil {
 		return true, err
 	}
-	resp.Body.Close()
+	defer resp.Body.Close()
 
-	return n.retry(resp.StatusCode)
+	retry, err := n.retry(resp.StatusCode)
+	if err != nil {
+		return retry, err
+	}
+	// Incoming webhooks only respond with "ok". The Web API responds with
+	// the timestamp identifying the posted message.
+	var res slackResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err == nil && res.TS != "" {
+		setProviderID(ctx, res.TS)
+	}
+
+	return false, nil
+}
+
+type slackResponse struct {
+	TS string `json:"ts"`
 }
 
 func (n *Slack) retry(statusCode int) (bool, error) {
@@ -642,7 +993,7 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	var err error
 	var msg string
 	var (
-		data     = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data     = templateData(ctx, n.tmpl, n.logger, as...)
 		tmplText = tmplText(n.tmpl, data, &err)
 		tmplHTML = tmplHTML(n.tmpl, data, &err)
 		url      = fmt.Sprintf("%sv2/room/%s/notification?auth_token=%s", n.conf.APIURL, n.conf.RoomID, n.conf.AuthToken)
@@ -670,7 +1021,7 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, url, contentTypeJSON, &buf)
+	resp, err := postPayload(ctx, url, contentTypeJSON, buf.Bytes())
 	if err != nil {
 		return true, err
 	}
@@ -712,6 +1063,7 @@ type opsGenieCreateMessage struct {
 	Teams       string            `json:"teams,omitempty"`
 	Tags        string            `json:"tags,omitempty"`
 	Note        string            `json:"note,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
 }
 
 type opsGenieCloseMessage struct {
@@ -724,7 +1076,7 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	if !ok {
 		return false, fmt.Errorf("group key missing")
 	}
-	data := n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+	data := templateData(ctx, n.tmpl, n.logger, as...)
 
 	level.Debug(n.logger).Log("msg", "Notifying OpsGenie", "incident", key)
 
@@ -763,6 +1115,7 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 			Teams:       tmpl(n.conf.Teams),
 			Tags:        tmpl(n.conf.Tags),
 			Note:        tmpl(n.conf.Note),
+			Priority:    tmpl(n.conf.Priority),
 		}
 	}
 	if err != nil {
@@ -774,14 +1127,20 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 		return false, err
 	}
 
-	req, err := http.NewRequest("POST", apiURL, &buf)
+	p, err := holdPayload(ctx, buf.Bytes())
+	if err != nil {
+		return true, err
+	}
+	defer p.release()
+
+	req, err := p.request("POST", apiURL)
 	if err != nil {
 		return true, err
 	}
 	req.Header.Set("Content-Type", contentTypeJSON)
 	req.Header.Set("Authorization", fmt.Sprintf("GenieKey %s", n.conf.APIKey))
 
-	resp, err := ctxhttp.Do(ctx, http.DefaultClient, req)
+	resp, err := do(ctx, req)
 
 	if err != nil {
 		return true, err
@@ -806,9 +1165,18 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 		)
 		return false, fmt.Errorf("unexpected status code %v", resp.StatusCode)
 	}
+
+	var res opsGenieResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err == nil && res.RequestID != "" {
+		setProviderID(ctx, res.RequestID)
+	}
 	return false, nil
 }
 
+type opsGenieResponse struct {
+	RequestID string `json:"requestId"`
+}
+
 // VictorOps implements a Notifier for VictorOps notifications.
 type VictorOps struct {
 	conf   *config.VictorOpsConfig
@@ -859,7 +1227,7 @@ func (n *VictorOps) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 	var err error
 	var (
 		alerts       = types.Alerts(as...)
-		data         = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data         = templateData(ctx, n.tmpl, n.logger, as...)
 		tmpl         = tmplText(n.tmpl, data, &err)
 		apiURL       = fmt.Sprintf("%s%s/%s", n.conf.APIURL, n.conf.APIKey, tmpl(n.conf.RoutingKey))
 		messageType  = tmpl(n.conf.MessageType)
@@ -896,7 +1264,7 @@ func (n *VictorOps) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 		return false, err
 	}
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, apiURL, contentTypeJSON, &buf)
+	resp, err := postPayload(ctx, apiURL, contentTypeJSON, buf.Bytes())
 	if err != nil {
 		return true, err
 	}
@@ -945,13 +1313,20 @@ func NewPushover(c *config.PushoverConfig, t *template.Template, l log.Logger) *
 	return &Pushover{conf: c, tmpl: t, logger: l}
 }
 
+// pushoverAPIURL is the endpoint Pushover messages are sent to.
+var pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+// pushoverPriorityEmergency is the priority of notifications that are
+// repeated until they are acknowledged.
+const pushoverPriorityEmergency = "2"
+
 // Notify implements the Notifier interface.
 func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	key, ok := GroupKey(ctx)
 	if !ok {
 		return false, fmt.Errorf("group key missing")
 	}
-	data := n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+	data := templateData(ctx, n.tmpl, n.logger, as...)
 
 	level.Debug(n.logger).Log("msg", "Notifying Pushover", "incident", key)
 
@@ -988,22 +1363,45 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	}
 	parameters.Add("url", supplementaryURL)
 
-	parameters.Add("priority", tmpl(n.conf.Priority))
-	parameters.Add("retry", fmt.Sprintf("%d", int64(time.Duration(n.conf.Retry).Seconds())))
-	parameters.Add("expire", fmt.Sprintf("%d", int64(time.Duration(n.conf.Expire).Seconds())))
+	if urlTitle := tmpl(n.conf.URLTitle); urlTitle != "" {
+		if len(urlTitle) > 100 {
+			urlTitle = urlTitle[:97] + "..."
+			level.Debug(n.logger).Log("msg", "Truncated URL title due to Pushover url_title limit", "truncated_url_title", urlTitle, "incident", key)
+		}
+		parameters.Add("url_title", urlTitle)
+	}
+	if device := strings.Replace(tmpl(n.conf.Device), " ", "", -1); device != "" {
+		parameters.Add("device", device)
+	}
+
+	priority := strings.TrimSpace(tmpl(n.conf.Priority))
+	parameters.Add("priority", priority)
+
+	retry, expire := tmpl(n.conf.Retry), tmpl(n.conf.Expire)
 	if err != nil {
 		return false, err
 	}
+	if priority == pushoverPriorityEmergency {
+		r, err := pushoverSeconds(retry, config.PushoverMinRetry, 0)
+		if err != nil {
+			return false, fmt.Errorf("invalid retry: %s", err)
+		}
+		e, err := pushoverSeconds(expire, 0, config.PushoverMaxExpire)
+		if err != nil {
+			return false, fmt.Errorf("invalid expire: %s", err)
+		}
+		parameters.Add("retry", r)
+		parameters.Add("expire", e)
+	}
 
-	apiURL := "https://api.pushover.net/1/messages.json"
-	u, err := url.Parse(apiURL)
+	u, err := url.Parse(pushoverAPIURL)
 	if err != nil {
 		return false, err
 	}
 	u.RawQuery = parameters.Encode()
 	level.Debug(n.logger).Log("msg", "Sending Pushover message", "incident", key, "url", u.String())
 
-	resp, err := ctxhttp.Post(ctx, http.DefaultClient, u.String(), "text/plain", nil)
+	resp, err := post(ctx, u.String(), "text/plain", nil)
 	if err != nil {
 		return true, err
 	}
@@ -1027,6 +1425,277 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	return false, nil
 }
 
+// DingTalk implements a Notifier for DingTalk group robot notifications.
+type DingTalk struct {
+	conf   *config.DingTalkConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewDingTalk returns a new DingTalk notifier.
+func NewDingTalk(c *config.DingTalkConfig, t *template.Template, l log.Logger) *DingTalk {
+	return &DingTalk{conf: c, tmpl: t, logger: l}
+}
+
+type dingTalkMessage struct {
+	MsgType  string           `json:"msgtype"`
+	Markdown dingTalkMarkdown `json:"markdown"`
+	At       dingTalkAt       `json:"at"`
+}
+
+type dingTalkMarkdown struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type dingTalkAt struct {
+	AtMobiles []string `json:"atMobiles,omitempty"`
+	IsAtAll   bool     `json:"isAtAll"`
+}
+
+type dingTalkResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// dingTalkErrTooFast is returned by DingTalk if a robot sends more than
+// its message quota.
+const dingTalkErrTooFast = 130101
+
+// Notify implements the Notifier interface.
+func (n *DingTalk) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := templateData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	var (
+		mentions = splitMentions(tmpl(n.conf.Mentions))
+		text     = tmpl(n.conf.Message)
+	)
+	// Mentioned users are only notified if they appear in the text as well.
+	for _, m := range mentions {
+		text += " @" + m
+	}
+	msg := &dingTalkMessage{
+		MsgType: "markdown",
+		Markdown: dingTalkMarkdown{
+			Title: tmpl(n.conf.Title),
+			Text:  text,
+		},
+		At: dingTalkAt{
+			AtMobiles: mentions,
+			IsAtAll:   n.conf.MentionAll,
+		},
+	}
+	if err != nil {
+		return false, fmt.Errorf("templating error: %s", err)
+	}
+
+	u, err := url.Parse(string(n.conf.URL))
+	if err != nil {
+		return false, err
+	}
+	if n.conf.Secret != "" {
+		ts := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+		q := u.Query()
+		q.Set("timestamp", ts)
+		q.Set("sign", signHMAC(string(n.conf.Secret), ts+"\n"+string(n.conf.Secret)))
+		u.RawQuery = q.Encode()
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return false, err
+	}
+
+	level.Debug(n.logger).Log("msg", "Notifying DingTalk", "incident", key)
+
+	resp, err := postPayload(ctx, u.String(), contentTypeJSON, buf.Bytes())
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 5 {
+		return true, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	if resp.StatusCode/100 != 2 {
+		return false, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	// DingTalk reports errors in the body of successful responses.
+	var res dingTalkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return false, fmt.Errorf("could not parse response: %s", err)
+	}
+	if res.ErrCode != 0 {
+		return res.ErrCode == dingTalkErrTooFast, fmt.Errorf("error when posting alert: code %d, message %q", res.ErrCode, res.ErrMsg)
+	}
+	return false, nil
+}
+
+// Lark implements a Notifier for Lark (Feishu) group robot notifications.
+type Lark struct {
+	conf   *config.LarkConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewLark returns a new Lark notifier.
+func NewLark(c *config.LarkConfig, t *template.Template, l log.Logger) *Lark {
+	return &Lark{conf: c, tmpl: t, logger: l}
+}
+
+type larkMessage struct {
+	Timestamp string   `json:"timestamp,omitempty"`
+	Sign      string   `json:"sign,omitempty"`
+	MsgType   string   `json:"msg_type"`
+	Card      larkCard `json:"card"`
+}
+
+type larkCard struct {
+	Header   larkCardHeader    `json:"header"`
+	Elements []larkCardElement `json:"elements"`
+}
+
+type larkCardHeader struct {
+	Title    larkText `json:"title"`
+	Template string   `json:"template"`
+}
+
+type larkCardElement struct {
+	Tag  string   `json:"tag"`
+	Text larkText `json:"text"`
+}
+
+type larkText struct {
+	Tag     string `json:"tag"`
+	Content string `json:"content"`
+}
+
+type larkResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// larkErrTooFast is returned by Lark if a robot sends more than its
+// message quota.
+const larkErrTooFast = 11232
+
+// Notify implements the Notifier interface.
+func (n *Lark) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := templateData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	text := tmpl(n.conf.Message)
+	mentions := splitMentions(tmpl(n.conf.Mentions))
+	if n.conf.MentionAll {
+		mentions = append(mentions, "all")
+	}
+	for _, m := range mentions {
+		text += fmt.Sprintf(" <at id=%s></at>", m)
+	}
+
+	color := "red"
+	if data.Status == string(model.AlertResolved) {
+		color = "green"
+	}
+	msg := &larkMessage{
+		MsgType: "interactive",
+		Card: larkCard{
+			Header: larkCardHeader{
+				Title:    larkText{Tag: "plain_text", Content: tmpl(n.conf.Title)},
+				Template: color,
+			},
+			Elements: []larkCardElement{
+				{Tag: "div", Text: larkText{Tag: "lark_md", Content: text}},
+			},
+		},
+	}
+	if err != nil {
+		return false, fmt.Errorf("templating error: %s", err)
+	}
+	if n.conf.Secret != "" {
+		// Lark signs an empty message with the timestamp and secret as key.
+		msg.Timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+		msg.Sign = signHMAC(msg.Timestamp+"\n"+string(n.conf.Secret), "")
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return false, err
+	}
+
+	level.Debug(n.logger).Log("msg", "Notifying Lark", "incident", key)
+
+	resp, err := postPayload(ctx, string(n.conf.URL), contentTypeJSON, buf.Bytes())
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 5 {
+		return true, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	if resp.StatusCode/100 != 2 {
+		return false, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+
+	var res larkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return false, fmt.Errorf("could not parse response: %s", err)
+	}
+	if res.Code != 0 {
+		return res.Code == larkErrTooFast, fmt.Errorf("error when posting alert: code %d, message %q", res.Code, res.Msg)
+	}
+	return false, nil
+}
+
+// splitMentions returns the non-empty elements of a comma-separated list.
+func splitMentions(s string) []string {
+	var res []string
+	for _, m := range strings.Split(s, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// signHMAC returns the base64 encoded HMAC-SHA256 of msg.
+func signHMAC(key, msg string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(msg))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// pushoverSeconds parses the duration d and returns it in seconds, bounded
+// by the given limits. A zero max imposes no upper limit.
+func pushoverSeconds(d string, min, max time.Duration) (string, error) {
+	v, err := time.ParseDuration(strings.TrimSpace(d))
+	if err != nil {
+		return "", err
+	}
+	if v < min {
+		v = min
+	}
+	if max > 0 && v > max {
+		v = max
+	}
+	return strconv.FormatInt(int64(v.Seconds()), 10), nil
+}
+
 func tmplText(tmpl *template.Template, data *template.Data, err *error) func(string) string {
 	return func(name string) (s string) {
 		if *err != nil {
@@ -14,8 +14,10 @@
 package notify
 
 import (
+	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -26,8 +28,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
 
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/flap"
 	"github.com/prometheus/alertmanager/inhibit"
 	"github.com/prometheus/alertmanager/nflog"
 	"github.com/prometheus/alertmanager/nflog/nflogpb"
@@ -50,6 +54,10 @@ var (
 	}, []string{"integration"})
 )
 
+// ErrDeferred is returned by stages that postpone the notification about
+// the alerts to a later flush of their group.
+var ErrDeferred = errors.New("notification deferred")
+
 func init() {
 	numNotifications.WithLabelValues("email")
 	numNotifications.WithLabelValues("hipchat")
@@ -59,6 +67,8 @@ func init() {
 	numNotifications.WithLabelValues("opsgenie")
 	numNotifications.WithLabelValues("webhook")
 	numNotifications.WithLabelValues("victorops")
+	numNotifications.WithLabelValues("dingtalk")
+	numNotifications.WithLabelValues("lark")
 	numFailedNotifications.WithLabelValues("email")
 	numFailedNotifications.WithLabelValues("hipchat")
 	numFailedNotifications.WithLabelValues("pagerduty")
@@ -67,6 +77,8 @@ func init() {
 	numFailedNotifications.WithLabelValues("opsgenie")
 	numFailedNotifications.WithLabelValues("webhook")
 	numFailedNotifications.WithLabelValues("victorops")
+	numFailedNotifications.WithLabelValues("dingtalk")
+	numFailedNotifications.WithLabelValues("lark")
 
 	prometheus.Register(numNotifications)
 	prometheus.Register(numFailedNotifications)
@@ -88,6 +100,12 @@ const (
 	keyFiringAlerts
 	keyResolvedAlerts
 	keyNow
+	keyTraceParent
+	keyProviderID
+	keyPayloadBudget
+	keyChangeDetection
+	keyAnnotationsHash
+	keyEscalated
 )
 
 // WithReceiverName populates a context with a receiver name.
@@ -120,6 +138,11 @@ func WithNow(ctx context.Context, t time.Time) context.Context {
 	return context.WithValue(ctx, keyNow, t)
 }
 
+// WithTraceParent populates a context with a W3C traceparent header value.
+func WithTraceParent(ctx context.Context, tp string) context.Context {
+	return context.WithValue(ctx, keyTraceParent, tp)
+}
+
 // WithRepeatInterval populates a context with a repeat interval.
 func WithRepeatInterval(ctx context.Context, t time.Duration) context.Context {
 	return context.WithValue(ctx, keyRepeatInterval, t)
@@ -132,6 +155,59 @@ func RepeatInterval(ctx context.Context) (time.Duration, bool) {
 	return v, ok
 }
 
+// ChangeDetection controls which changes of a group's alerts are notified
+// about before the repeat interval passed. Its mode is one of the change
+// detection modes of the config package. The zero value notifies about any
+// change.
+type ChangeDetection struct {
+	Mode string
+	// Ascending numbers of firing alerts, crossing any of which is a
+	// change in the group size mode.
+	Thresholds []int
+	// Names of annotations a change of which on any firing alert is a
+	// change regardless of the mode.
+	Annotations []string
+}
+
+// WithChangeDetection populates a context with a change detection.
+func WithChangeDetection(ctx context.Context, cd ChangeDetection) context.Context {
+	return context.WithValue(ctx, keyChangeDetection, cd)
+}
+
+// ChangeDetectionFrom extracts a change detection from the context. Iff none
+// exists, the second argument is false.
+func ChangeDetectionFrom(ctx context.Context) (ChangeDetection, bool) {
+	v, ok := ctx.Value(keyChangeDetection).(ChangeDetection)
+	return v, ok
+}
+
+// WithAnnotationsHash populates a context with a hash over the notified
+// annotations of the firing alerts.
+func WithAnnotationsHash(ctx context.Context, h uint64) context.Context {
+	return context.WithValue(ctx, keyAnnotationsHash, h)
+}
+
+// AnnotationsHash extracts a hash over the notified annotations of the
+// firing alerts from the context. Iff none exists, the second argument is
+// false.
+func AnnotationsHash(ctx context.Context) (uint64, bool) {
+	v, ok := ctx.Value(keyAnnotationsHash).(uint64)
+	return v, ok
+}
+
+// WithEscalated populates a context with whether the notification is
+// escalated.
+func WithEscalated(ctx context.Context, escalated bool) context.Context {
+	return context.WithValue(ctx, keyEscalated, escalated)
+}
+
+// Escalated extracts whether the notification is escalated from the
+// context. Iff none exists, the second argument is false.
+func Escalated(ctx context.Context) (bool, bool) {
+	v, ok := ctx.Value(keyEscalated).(bool)
+	return v, ok
+}
+
 // ReceiverName extracts a receiver name from the context. Iff none exists, the
 // second argument is false.
 func ReceiverName(ctx context.Context) (string, bool) {
@@ -162,6 +238,14 @@ func groupLabels(ctx context.Context, l log.Logger) model.LabelSet {
 	return groupLabels
 }
 
+// templateData returns the template data of the notification about the
+// alerts in the context.
+func templateData(ctx context.Context, t *template.Template, l log.Logger, alerts ...*types.Alert) *template.Data {
+	data := t.Data(receiverName(ctx, l), groupLabels(ctx, l), alerts...)
+	data.Escalated, _ = Escalated(ctx)
+	return data
+}
+
 // GroupLabels extracts grouping label set from the context. Iff none exists, the
 // second argument is false.
 func GroupLabels(ctx context.Context) (model.LabelSet, bool) {
@@ -169,6 +253,58 @@ func GroupLabels(ctx context.Context) (model.LabelSet, bool) {
 	return v, ok
 }
 
+// TraceParent extracts a W3C traceparent header value from the context.
+// Iff none exists, the second argument is false.
+func TraceParent(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(keyTraceParent).(string)
+	return v, ok
+}
+
+// providerID holds the identifier a provider returned for a notification.
+type providerID struct {
+	id string
+}
+
+// withProviderID populates a context with a holder for the identifier a
+// notifier received from its provider.
+func withProviderID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyProviderID, &providerID{})
+}
+
+// setProviderID records the identifier the provider returned for the
+// notification. It does nothing if the context holds no provider ID.
+func setProviderID(ctx context.Context, id string) {
+	if v, ok := ctx.Value(keyProviderID).(*providerID); ok {
+		v.id = id
+	}
+}
+
+// ProviderID extracts the identifier the provider returned for the last
+// successful notification from the context. Iff none exists, the second
+// argument is false.
+func ProviderID(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(keyProviderID).(*providerID)
+	if !ok || v.id == "" {
+		return "", false
+	}
+	return v.id, true
+}
+
+// traceParent returns the trace context of the most recently updated
+// alert that has one.
+func traceParent(alerts []*types.Alert) string {
+	var (
+		tp     string
+		latest time.Time
+	)
+	for _, a := range alerts {
+		if a.TraceParent != "" && (tp == "" || a.UpdatedAt.After(latest)) {
+			tp, latest = a.TraceParent, a.UpdatedAt
+		}
+	}
+	return tp
+}
+
 // Now extracts a now timestamp from the context. Iff none exists, the
 // second argument is false.
 func Now(ctx context.Context) (time.Time, bool) {
@@ -212,21 +348,37 @@ func BuildPipeline(
 	silences *silence.Silences,
 	notificationLog nflog.Log,
 	marker types.Marker,
+	flaps *flap.Detector,
+	budget *PayloadBudget,
+	decisions *DecisionLog,
 	logger log.Logger,
 ) RoutingStage {
 	rs := RoutingStage{}
 
-	is := NewInhibitStage(inhibitor, marker)
-	ss := NewSilenceStage(silences, marker)
+	is := decisions.muteStage(NewInhibitStage(inhibitor, marker), DecisionInhibited)
+	ss := decisions.muteStage(NewSilenceStage(silences, marker), DecisionSilenced)
+	fs := NewFlapStage(flaps)
 
 	for _, rc := range confs {
-		rs[rc.Name] = MultiStage{is, ss, createStage(rc, tmpl, wait, notificationLog, logger)}
+		ms := MultiStage{is, ss}
+		if flaps.Enabled() {
+			ms = append(ms, fs)
+		}
+		rs[rc.Name] = append(ms, createStage(rc, tmpl, wait, notificationLog, budget, decisions, logger))
 	}
 	return rs
 }
 
-// createStage creates a pipeline of stages for a receiver.
-func createStage(rc *config.Receiver, tmpl *template.Template, wait func() time.Duration, notificationLog nflog.Log, logger log.Logger) Stage {
+// BuildReceiverStage builds the stages notifying the receiver. Unlike the
+// pipeline built by BuildPipeline, alerts are not inhibited or silenced
+// beforehand, which is meant for notifications about Alertmanager itself.
+func BuildReceiverStage(rc *config.Receiver, tmpl *template.Template, wait func() time.Duration, notificationLog nflog.Log, budget *PayloadBudget, logger log.Logger) Stage {
+	return createStage(rc, tmpl, wait, notificationLog, budget, nil, logger)
+}
+
+// createStage creates a pipeline of stages for a receiver. Their decisions
+// are recorded in the decision log, which may be nil.
+func createStage(rc *config.Receiver, tmpl *template.Template, wait func() time.Duration, notificationLog nflog.Log, budget *PayloadBudget, decisions *DecisionLog, logger log.Logger) Stage {
 	var fs FanoutStage
 	for _, i := range BuildReceiverIntegrations(rc, tmpl, logger) {
 		recv := &nflogpb.Receiver{
@@ -234,17 +386,38 @@ func createStage(rc *config.Receiver, tmpl *template.Template, wait func() time.
 			Integration: i.name,
 			Idx:         uint32(i.idx),
 		}
+		if rc.DedupByConfig {
+			h, err := configHash(i.conf)
+			if err != nil {
+				level.Error(logger).Log("msg", "Hashing integration configuration failed", "receiver", rc.Name, "integration", i.name, "err", err)
+			}
+			recv.ConfigHash = h
+		}
 		var s MultiStage
 		s = append(s, NewWaitStage(wait))
-		s = append(s, NewDedupStage(notificationLog, recv))
-		s = append(s, NewRetryStage(i))
+		s = append(s, decisions.dedupStage(NewDedupStage(notificationLog, recv), i.name))
+		s = append(s, decisions.retryStage(NewRetryStage(i, budget)))
 		s = append(s, NewSetNotifiesStage(notificationLog, recv))
 
 		fs = append(fs, s)
 	}
+	if rc.AlignInterval > 0 {
+		return MultiStage{NewAlignStage(rc, tmpl.Severities), fs}
+	}
 	return fs
 }
 
+// configHash returns a hash over the configuration of an integration.
+// Secrets are not revealed on marshaling, so changing them alone does
+// not change the hash.
+func configHash(c notifierConfig) (string, error) {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%016x", xxhash.Sum64(b)), nil
+}
+
 // RoutingStage executes the inner stages based on the receiver specified in
 // the context.
 type RoutingStage map[string]Stage
@@ -379,7 +552,32 @@ func (n *SilenceStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.
 				ids[i] = s.Id
 			}
 			n.marker.SetSilenced(a.Labels.Fingerprint(), ids...)
+			n.silences.ObserveMuted(a.Labels.Fingerprint(), ids...)
+		}
+	}
+
+	return ctx, filtered, nil
+}
+
+// FlapStage holds back alerts that are flapping until they stabilized.
+type FlapStage struct {
+	flaps *flap.Detector
+}
+
+// NewFlapStage returns a new FlapStage.
+func NewFlapStage(d *flap.Detector) *FlapStage {
+	return &FlapStage{flaps: d}
+}
+
+// Exec implements the Stage interface.
+func (n *FlapStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	var filtered []*types.Alert
+	for _, a := range alerts {
+		if n.flaps.Held(a.Fingerprint()) {
+			level.Debug(l).Log("msg", "Holding back flapping alert", "alert", a)
+			continue
 		}
+		filtered = append(filtered, a)
 	}
 
 	return ctx, filtered, nil
@@ -408,6 +606,93 @@ func (ws *WaitStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Al
 	return ctx, alerts, nil
 }
 
+// AlignStage defers notifications to the first flush of their group after
+// each multiple of an interval, batching the changes in between into a
+// single notification.
+type AlignStage struct {
+	interval time.Duration
+	bypass   types.Severity
+	sevs     types.SeverityMap
+
+	mtx sync.Mutex
+	// Start of the interval in which each group was last let through.
+	passed map[string]time.Time
+	// Start of the interval in which passed was last swept.
+	swept time.Time
+}
+
+// NewAlignStage returns a new AlignStage for the receiver. Firing alerts
+// of at least the receiver's bypass severity are never deferred.
+func NewAlignStage(rc *config.Receiver, sevs types.SeverityMap) *AlignStage {
+	bypass := types.SeverityCritical
+	if rc.AlignBypassSeverity != "" {
+		bypass, _ = types.ParseSeverity(rc.AlignBypassSeverity)
+	}
+	return &AlignStage{
+		interval: time.Duration(rc.AlignInterval),
+		bypass:   bypass,
+		sevs:     sevs,
+		passed:   map[string]time.Time{},
+	}
+}
+
+// alignRetention is how long groups not let through are remembered.
+const alignRetention = 24 * time.Hour
+
+// Exec implements the Stage interface. It returns ErrDeferred if the alerts
+// were already let through in the current interval.
+func (as *AlignStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("now time missing")
+	}
+
+	firing := false
+	for _, a := range alerts {
+		if a.Resolved() {
+			continue
+		}
+		firing = true
+		if as.sevs.Severity(a.Labels) >= as.bypass {
+			return ctx, alerts, nil
+		}
+	}
+	start := now.Truncate(as.interval)
+
+	as.mtx.Lock()
+	defer as.mtx.Unlock()
+
+	if start.After(as.swept) {
+		for k, t := range as.passed {
+			if t.Before(start.Add(-alignRetention)) {
+				delete(as.passed, k)
+			}
+		}
+		as.swept = start
+	}
+
+	// Groups seen for the first time wait for the next interval as well.
+	last, ok := as.passed[gkey]
+	if !ok || !last.Before(start) {
+		if !ok {
+			as.passed[gkey] = start
+		}
+		level.Debug(l).Log("msg", "Deferring notification to next aligned interval", "next", start.Add(as.interval))
+		return ctx, nil, ErrDeferred
+	}
+	if firing {
+		as.passed[gkey] = start
+	} else {
+		// The group is resolved and about to be removed.
+		delete(as.passed, gkey)
+	}
+	return ctx, alerts, nil
+}
+
 // DedupStage filters alerts.
 // Filtering happens based on a notification log.
 type DedupStage struct {
@@ -472,18 +757,57 @@ func hashAlert(a *types.Alert) uint64 {
 	return hash
 }
 
-func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, firing, resolved map[uint64]struct{}, repeat time.Duration) (bool, error) {
+// hashAnnotations returns a hash over the given annotations of the firing
+// alerts. It does not depend on the order of the alerts.
+func hashAnnotations(alerts []*types.Alert, names []string, hash func(*types.Alert) uint64) uint64 {
+	const sep = '\xff'
+
+	type firingAlert struct {
+		hash uint64
+		a    *types.Alert
+	}
+	var firing []firingAlert
+	for _, a := range alerts {
+		if !a.Resolved() {
+			firing = append(firing, firingAlert{hash: hash(a), a: a})
+		}
+	}
+	sort.Slice(firing, func(i, j int) bool { return firing[i].hash < firing[j].hash })
+
+	b := getHashBuffer()
+	defer putHashBuffer(b)
+
+	for _, f := range firing {
+		b = strconv.AppendUint(b, f.hash, 16)
+		b = append(b, sep)
+		for _, an := range names {
+			b = append(b, an...)
+			b = append(b, sep)
+			b = append(b, string(f.a.Annotations[model.LabelName(an)])...)
+			b = append(b, sep)
+		}
+	}
+	h := xxhash.Sum64(b)
+	// Zero marks entries whose annotations are not tracked.
+	if h == 0 {
+		h = 1
+	}
+	return h
+}
+
+func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, firing, resolved map[uint64]struct{}, annotationsHash uint64, repeat time.Duration, cd ChangeDetection) (bool, error) {
 	// If we haven't notified about the alert group before, notify right away
 	// unless we only have resolved alerts.
 	if entry == nil {
 		return len(firing) > 0, nil
 	}
 
-	if !entry.IsFiringSubset(firing) {
+	if n.changed(entry, firing, resolved, cd) {
 		return true, nil
 	}
-
-	if !entry.IsResolvedSubset(resolved) {
+	// Entries logged before the annotations were tracked are not
+	// considered changed.
+	if len(firing) > 0 && entry.AnnotationsHash != 0 && annotationsHash != 0 && entry.AnnotationsHash != annotationsHash {
 		return true, nil
 	}
 
@@ -491,6 +815,27 @@ func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, firing, resolved map[uint
 	return entry.Timestamp.Before(n.now().Add(-repeat)), nil
 }
 
+// changed returns whether the alerts changed since the notification of the
+// entry according to the change detection. The resolution of all firing
+// alerts is a change regardless of it.
+func (n *DedupStage) changed(entry *nflogpb.Entry, firing, resolved map[uint64]struct{}, cd ChangeDetection) bool {
+	if len(firing) == 0 && len(entry.FiringAlerts) > 0 {
+		return true
+	}
+	switch cd.Mode {
+	case config.ChangeDetectionNewFiring:
+		return !entry.IsFiringSubset(firing)
+	case config.ChangeDetectionGroupSize:
+		return thresholdLevel(len(entry.FiringAlerts), cd.Thresholds) != thresholdLevel(len(firing), cd.Thresholds)
+	}
+	return !entry.IsFiringSubset(firing) || !entry.IsResolvedSubset(resolved)
+}
+
+// thresholdLevel returns the number of ascending thresholds n reaches.
+func thresholdLevel(n int, thresholds []int) int {
+	return sort.Search(len(thresholds), func(i int) bool { return thresholds[i] > n })
+}
+
 // Exec implements the Stage interface.
 func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
 	gkey, ok := GroupKey(ctx)
@@ -523,20 +868,20 @@ func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Al
 	ctx = WithFiringAlerts(ctx, firing)
 	ctx = WithResolvedAlerts(ctx, resolved)
 
-	entries, err := n.nflog.Query(nflog.QGroupKey(gkey), nflog.QReceiver(n.recv))
-
+	entry, err := n.nflog.QueryOne(nflog.QGroupKey(gkey), nflog.QReceiver(n.recv))
 	if err != nil && err != nflog.ErrNotFound {
 		return ctx, nil, err
 	}
-	var entry *nflogpb.Entry
-	switch len(entries) {
-	case 0:
-	case 1:
-		entry = entries[0]
-	case 2:
-		return ctx, nil, fmt.Errorf("unexpected entry result size %d", len(entries))
+	// Without change detection any change is notified about.
+	cd, _ := ChangeDetectionFrom(ctx)
+
+	var annotationsHash uint64
+	if len(cd.Annotations) > 0 {
+		annotationsHash = hashAnnotations(alerts, cd.Annotations, n.hash)
+		ctx = WithAnnotationsHash(ctx, annotationsHash)
 	}
-	if ok, err := n.needsUpdate(entry, firingSet, resolvedSet, repeatInterval); err != nil {
+
+	if ok, err := n.needsUpdate(entry, firingSet, resolvedSet, annotationsHash, repeatInterval, cd); err != nil {
 		return ctx, nil, err
 	} else if ok {
 		return ctx, alerts, nil
@@ -548,12 +893,15 @@ func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Al
 // succeeds. It aborts if the context is canceled or timed out.
 type RetryStage struct {
 	integration Integration
+	budget      *PayloadBudget
 }
 
-// NewRetryStage returns a new instance of a RetryStage.
-func NewRetryStage(i Integration) *RetryStage {
+// NewRetryStage returns a new instance of a RetryStage. Rendered payloads
+// are held against the budget while they are sent. It may be nil.
+func NewRetryStage(i Integration, b *PayloadBudget) *RetryStage {
 	return &RetryStage{
 		integration: i,
+		budget:      b,
 	}
 }
 
@@ -562,14 +910,21 @@ func (r RetryStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Ale
 	// If we shouldn't send notifications for resolved alerts, but there are only
 	// resolved alerts, report them all as successfully notified (we still want the
 	// notification log to log them).
-	if !r.integration.conf.SendResolved() {
-		firing, ok := FiringAlerts(ctx)
-		if !ok {
-			return ctx, alerts, fmt.Errorf("firing alerts missing")
-		}
-		if len(firing) == 0 {
-			return ctx, alerts, nil
-		}
+	if skip, err := r.skips(ctx, alerts); err != nil {
+		return ctx, alerts, err
+	} else if skip {
+		return ctx, alerts, nil
+	}
+
+	// Notifications continue the trace of the alerts they are about.
+	if tp := traceParent(alerts); tp != "" {
+		ctx = WithTraceParent(ctx, tp)
+	}
+	// Notifiers record identifiers returned by their provider so that
+	// they end up in the notification log.
+	ctx = withProviderID(ctx)
+	if r.budget != nil {
+		ctx = WithPayloadBudget(ctx, r.budget)
 	}
 
 	var (
@@ -619,6 +974,28 @@ func (r RetryStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Ale
 	}
 }
 
+// skips returns whether the alerts are not notified about as they are all
+// resolved and the integration does not send resolved notifications.
+func (r RetryStage) skips(ctx context.Context, alerts []*types.Alert) (bool, error) {
+	if r.integration.conf.SendResolved() {
+		return false, nil
+	}
+	firing, ok := FiringAlerts(ctx)
+	if !ok {
+		return false, fmt.Errorf("firing alerts missing")
+	}
+	return len(firing) == 0 && !r.closesAny(alerts), nil
+}
+
+func (r RetryStage) closesAny(alerts []*types.Alert) bool {
+	for _, a := range alerts {
+		if r.integration.closes(a) {
+			return true
+		}
+	}
+	return false
+}
+
 // SetNotifiesStage sets the notification information about passed alerts. The
 // passed alerts should have already been sent to the receivers.
 type SetNotifiesStage struct {
@@ -651,5 +1028,12 @@ func (n SetNotifiesStage) Exec(ctx context.Context, l log.Logger, alerts ...*typ
 		return ctx, nil, fmt.Errorf("resolved alerts missing")
 	}
 
-	return ctx, alerts, n.nflog.Log(n.recv, gkey, firing, resolved)
+	var params []nflog.LogParam
+	if id, ok := ProviderID(ctx); ok {
+		params = append(params, nflog.LProviderID(id))
+	}
+	if h, ok := AnnotationsHash(ctx); ok {
+		params = append(params, nflog.LAnnotationsHash(h))
+	}
+	return ctx, alerts, n.nflog.Log(n.recv, gkey, firing, resolved, params...)
 }
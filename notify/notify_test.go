@@ -13,10 +13,18 @@
 package notify
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,12 +33,15 @@ import (
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/mesh"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/nflog"
 	"github.com/prometheus/alertmanager/nflog/nflogpb"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/template"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -63,10 +74,49 @@ func (l *testNflog) Query(p ...nflog.QueryParam) ([]*nflogpb.Entry, error) {
 	return l.qres, l.qerr
 }
 
-func (l *testNflog) Log(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error {
+func (l *testNflog) QueryOne(p ...nflog.QueryParam) (*nflogpb.Entry, error) {
+	switch len(l.qres) {
+	case 0:
+		return nil, l.qerr
+	case 1:
+		return l.qres[0], l.qerr
+	}
+	return nil, fmt.Errorf("unexpected entry result size %d", len(l.qres))
+}
+
+func (l *testNflog) QueryIter(p ...nflog.QueryParam) (nflog.EntryIterator, error) {
+	return &testEntryIterator{entries: l.qres}, l.qerr
+}
+
+type testEntryIterator struct {
+	entries []*nflogpb.Entry
+	cur     *nflogpb.Entry
+}
+
+func (it *testEntryIterator) Next() bool {
+	if len(it.entries) == 0 {
+		return false
+	}
+	it.cur, it.entries = it.entries[0], it.entries[1:]
+	return true
+}
+
+func (it *testEntryIterator) At() *nflogpb.Entry {
+	return it.cur
+}
+
+func (l *testNflog) Log(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, params ...nflog.LogParam) error {
 	return l.logFunc(r, gkey, firingAlerts, resolvedAlerts)
 }
 
+func (l *testNflog) Delete(r *nflogpb.Receiver, gkey []byte) error {
+	return nil
+}
+
+func (l *testNflog) Acknowledge(r *nflogpb.Receiver, gkey []byte, at time.Time) (*nflogpb.Entry, bool, error) {
+	return nil, false, nil
+}
+
 func (l *testNflog) GC() (int, error) {
 	return 0, nil
 }
@@ -75,6 +125,28 @@ func (l *testNflog) Snapshot(w io.Writer) (int, error) {
 	return 0, nil
 }
 
+func (l *testNflog) SnapshotFile() error {
+	return nil
+}
+
+func (l *testNflog) View() *nflog.View {
+	return &nflog.View{}
+}
+
+func (l *testNflog) Flush() {}
+
+func (l *testNflog) Repair(mesh.PeerName, ...nflog.RepairKey) error {
+	return nil
+}
+
+func (l *testNflog) Stats() []nflog.ReceiverStats {
+	return nil
+}
+
+func (l *testNflog) Merge(entries ...*nflogpb.MeshEntry) (int, error) {
+	return 0, nil
+}
+
 func mustTimestampProto(ts time.Time) *timestamp.Timestamp {
 	tspb, err := ptypes.TimestampProto(ts)
 	if err != nil {
@@ -143,7 +215,7 @@ func TestDedupStageNeedsUpdate(t *testing.T) {
 		s := &DedupStage{
 			now: func() time.Time { return now },
 		}
-		ok, err := s.needsUpdate(c.entry, c.firingAlerts, nil, c.repeat)
+		ok, err := s.needsUpdate(c.entry, c.firingAlerts, nil, 0, c.repeat, ChangeDetection{})
 		if c.resErr {
 			require.Error(t, err)
 		} else {
@@ -153,6 +225,57 @@ func TestDedupStageNeedsUpdate(t *testing.T) {
 	}
 }
 
+func TestDedupStageChangeDetection(t *testing.T) {
+	now := utcNow()
+	entry := &nflogpb.Entry{
+		FiringAlerts:   []uint64{1, 2, 3},
+		ResolvedAlerts: []uint64{4},
+		Timestamp:      now.Add(-time.Minute),
+	}
+	var (
+		newFiring = ChangeDetection{Mode: config.ChangeDetectionNewFiring}
+		groupSize = ChangeDetection{Mode: config.ChangeDetectionGroupSize, Thresholds: []int{3, 5}}
+	)
+
+	cases := []struct {
+		cd       ChangeDetection
+		firing   map[uint64]struct{}
+		resolved map[uint64]struct{}
+		res      bool
+	}{
+		{cd: ChangeDetection{}, firing: alertHashSet(1, 2), resolved: alertHashSet(3, 4), res: true},
+		{cd: newFiring, firing: alertHashSet(1, 2), resolved: alertHashSet(3, 4), res: false},
+		{cd: newFiring, firing: alertHashSet(1, 2, 5), resolved: alertHashSet(3, 4), res: true},
+		{cd: newFiring, firing: alertHashSet(), resolved: alertHashSet(1, 2, 3, 4), res: true},
+		{cd: groupSize, firing: alertHashSet(1, 2, 5), resolved: alertHashSet(3, 4), res: false},
+		{cd: groupSize, firing: alertHashSet(1, 2, 3, 5, 6), res: true},
+		{cd: groupSize, firing: alertHashSet(1, 2), resolved: alertHashSet(3, 4), res: true},
+		{cd: groupSize, firing: alertHashSet(), resolved: alertHashSet(1, 2, 3, 4), res: true},
+	}
+	for i, c := range cases {
+		s := &DedupStage{
+			now: func() time.Time { return now },
+		}
+		ok, err := s.needsUpdate(entry, c.firing, c.resolved, 0, time.Hour, c.cd)
+		require.NoError(t, err)
+		require.Equal(t, c.res, ok, "case %d", i)
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	c := &config.WebhookConfig{URL: "http://example.com/a"}
+	h1, err := configHash(c)
+	require.NoError(t, err)
+	h2, err := configHash(&config.WebhookConfig{URL: "http://example.com/a"})
+	require.NoError(t, err)
+	require.Equal(t, h1, h2)
+
+	c.URL = "http://example.com/b"
+	h2, err = configHash(c)
+	require.NoError(t, err)
+	require.NotEqual(t, h1, h2)
+}
+
 func TestDedupStage(t *testing.T) {
 	i := 0
 	now := utcNow()
@@ -237,6 +360,119 @@ func TestDedupStage(t *testing.T) {
 	require.Equal(t, alerts, res, "unexpected alerts returned")
 }
 
+func TestDedupStageAnnotationChange(t *testing.T) {
+	now := utcNow()
+	s := &DedupStage{
+		hash: hashAlert,
+		now:  func() time.Time { return now },
+	}
+	newAlerts := func(summary, runbook string) []*types.Alert {
+		return []*types.Alert{
+			{Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": "a"},
+				Annotations: model.LabelSet{"summary": model.LabelValue(summary), "runbook": model.LabelValue(runbook)},
+			}},
+			{Alert: model.Alert{
+				Labels: model.LabelSet{"alertname": "b"},
+			}},
+		}
+	}
+	cd := ChangeDetection{Annotations: []string{"summary"}}
+	alerts := newAlerts("old", "old")
+
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithRepeatInterval(ctx, time.Hour)
+	ctx = WithChangeDetection(ctx, cd)
+
+	entry := &nflogpb.Entry{
+		FiringAlerts:    []uint64{hashAlert(alerts[0]), hashAlert(alerts[1])},
+		AnnotationsHash: hashAnnotations(alerts, cd.Annotations, hashAlert),
+		Timestamp:       now,
+	}
+	s.nflog = &testNflog{qres: []*nflogpb.Entry{entry}}
+
+	// The hash does not depend on the order of the alerts.
+	rctx, res, err := s.Exec(ctx, log.NewNopLogger(), alerts[1], alerts[0])
+	require.NoError(t, err)
+	require.Nil(t, res)
+	h, ok := AnnotationsHash(rctx)
+	require.True(t, ok)
+	require.Equal(t, entry.AnnotationsHash, h)
+
+	// Changes of other annotations are not notified about.
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), newAlerts("old", "new")...)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	changed := newAlerts("new", "old")
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), changed...)
+	require.NoError(t, err)
+	require.Equal(t, changed, res)
+
+	// Entries without annotations hash are not considered changed.
+	entry.AnnotationsHash = 0
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), changed...)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	// Without tracked annotations no hash is computed.
+	rctx, _, err = s.Exec(WithChangeDetection(ctx, ChangeDetection{}), log.NewNopLogger(), changed...)
+	require.NoError(t, err)
+	_, ok = AnnotationsHash(rctx)
+	require.False(t, ok)
+}
+
+func TestAlignStage(t *testing.T) {
+	s := NewAlignStage(&config.Receiver{AlignInterval: model.Duration(30 * time.Minute)}, nil)
+
+	var (
+		start    = time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+		warning  = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"severity": "warning"}}}
+		critical = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"severity": "critical"}}}
+		resolved = &types.Alert{Alert: model.Alert{EndsAt: start.Add(-time.Minute)}}
+	)
+	exec := func(gkey string, offset time.Duration, alerts ...*types.Alert) ([]*types.Alert, error) {
+		ctx := WithGroupKey(context.Background(), gkey)
+		ctx = WithNow(ctx, start.Add(offset))
+		_, res, err := s.Exec(ctx, log.NewNopLogger(), alerts...)
+		return res, err
+	}
+
+	_, _, err := s.Exec(context.Background(), log.NewNopLogger(), warning)
+	require.EqualError(t, err, "group key missing")
+
+	// New groups wait for the next interval.
+	_, err = exec("1", 10*time.Minute, warning)
+	require.Equal(t, ErrDeferred, err)
+	_, err = exec("1", 25*time.Minute, warning)
+	require.Equal(t, ErrDeferred, err)
+
+	// The first flush in the next interval is let through, later ones not.
+	res, err := exec("1", 31*time.Minute, warning)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{warning}, res)
+	_, err = exec("1", 36*time.Minute, warning)
+	require.Equal(t, ErrDeferred, err)
+
+	// Critical alerts bypass the alignment.
+	res, err = exec("1", 41*time.Minute, warning, critical)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{warning, critical}, res)
+	_, err = exec("1", 46*time.Minute, warning)
+	require.Equal(t, ErrDeferred, err)
+
+	// Missing the first flush of an interval lets the next one through.
+	res, err = exec("1", 75*time.Minute, warning)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{warning}, res)
+
+	// Groups are forgotten once resolved notifications were let through.
+	res, err = exec("1", 90*time.Minute, resolved)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{resolved}, res)
+	require.Empty(t, s.passed)
+}
+
 func TestMultiStage(t *testing.T) {
 	var (
 		alerts1 = []*types.Alert{{}}
@@ -371,6 +607,40 @@ func TestIntegrationSendResolved(t *testing.T) {
 	require.Equal(t, res, alerts)
 }
 
+func TestIntegrationForceResolved(t *testing.T) {
+	alerts := []*types.Alert{
+		&types.Alert{
+			Alert: model.Alert{
+				EndsAt: time.Now().Add(-time.Hour),
+			},
+		},
+		&types.Alert{
+			Alert: model.Alert{
+				EndsAt: time.Now().Add(-time.Hour),
+			},
+			ForceResolved: true,
+		},
+	}
+
+	for name, exp := range map[string]int{"pagerduty": 1, "opsgenie": 1, "webhook": 0} {
+		res := []*types.Alert{}
+		r := notifierFunc(func(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+			res = append(res, alerts...)
+
+			return false, nil
+		})
+		i := Integration{
+			notifier: r,
+			conf:     notifierConfigFunc(func() bool { return false }),
+			name:     name,
+		}
+
+		i.Notify(nil, alerts...)
+
+		require.Equal(t, exp, len(res), name)
+	}
+}
+
 func TestSetNotifiesStage(t *testing.T) {
 	tnflog := &testNflog{}
 	s := &SetNotifiesStage{
@@ -539,3 +809,770 @@ func TestInhibitStage(t *testing.T) {
 		t.Fatalf("Muting failed, expected: %v\ngot %v", out, got)
 	}
 }
+
+func TestDecisionLog(t *testing.T) {
+	posted := make(chan []*DecisionEvent, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []*DecisionEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&events))
+		posted <- events
+	}))
+	defer srv.Close()
+
+	marker := types.NewMarker()
+	d := NewDecisionLog(&config.DecisionLog{URL: srv.URL, Timeout: model.Duration(time.Second)}, marker, log.NewNopLogger())
+
+	var (
+		muted    = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"a": "1"}, EndsAt: time.Now().Add(time.Hour)}}
+		notMuted = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"a": "2"}, EndsAt: time.Now().Add(time.Hour)}}
+		alerts   = []*types.Alert{muted, notMuted}
+		now      = time.Now()
+	)
+	marker.SetSilenced(muted.Fingerprint(), "sil")
+
+	ctx := WithReceiverName(context.Background(), "team")
+	ctx = WithGroupKey(ctx, "{}:{a=\"1\"}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"a": "1"})
+	ctx = WithNow(ctx, now)
+	ctx = WithFiringAlerts(ctx, []uint64{1, 2})
+
+	silencer := d.muteStage(StageFunc(func(ctx context.Context, l log.Logger, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, as[1:], nil
+	}), DecisionSilenced)
+	_, res, err := silencer.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{notMuted}, res)
+
+	// Nothing is recorded if no alerts are muted.
+	inhibitor := d.muteStage(StageFunc(func(ctx context.Context, l log.Logger, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, as, nil
+	}), DecisionInhibited)
+	_, _, err = inhibitor.Exec(ctx, log.NewNopLogger(), res...)
+	require.NoError(t, err)
+
+	dedup := d.dedupStage(StageFunc(func(ctx context.Context, l log.Logger, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, nil, nil
+	}), "webhook")
+	_, _, err = dedup.Exec(ctx, log.NewNopLogger(), res...)
+	require.NoError(t, err)
+
+	newRetry := func(err error) Stage {
+		return d.retryStage(NewRetryStage(Integration{
+			name:     "webhook",
+			conf:     notifierConfigFunc(func() bool { return true }),
+			notifier: notifierFunc(func(ctx context.Context, as ...*types.Alert) (bool, error) { return false, err }),
+		}, nil))
+	}
+	_, _, err = newRetry(nil).Exec(ctx, log.NewNopLogger(), res...)
+	require.NoError(t, err)
+	_, _, err = newRetry(errors.New("unreachable")).Exec(ctx, log.NewNopLogger(), res...)
+	require.Error(t, err)
+
+	go d.Run()
+	d.Stop()
+
+	var events []*DecisionEvent
+	for len(posted) > 0 {
+		events = append(events, <-posted...)
+	}
+	require.Len(t, events, 4)
+
+	e := events[0]
+	require.Equal(t, DecisionSilenced, e.Decision)
+	require.Equal(t, "team", e.Receiver)
+	require.Equal(t, "{}:{a=\"1\"}", e.GroupKey)
+	require.Equal(t, model.LabelSet{"a": "1"}, e.GroupLabels)
+	require.True(t, now.Equal(e.Time))
+	require.Equal(t, []DecisionAlert{{
+		Fingerprint: muted.Fingerprint().String(),
+		Labels:      muted.Labels,
+		Status:      "firing",
+		SilencedBy:  []string{"sil"},
+	}}, e.Alerts)
+
+	for i, exp := range []string{DecisionDeduplicated, DecisionSent, DecisionFailed} {
+		e := events[i+1]
+		require.Equal(t, exp, e.Decision)
+		require.Equal(t, "webhook", e.Integration)
+		require.Len(t, e.Alerts, 1)
+		require.Equal(t, notMuted.Fingerprint().String(), e.Alerts[0].Fingerprint)
+	}
+	require.Contains(t, events[3].Error, "unreachable")
+
+	// Without a configuration nothing is recorded.
+	var nd *DecisionLog
+	require.Nil(t, NewDecisionLog(nil, marker, log.NewNopLogger()))
+	require.Equal(t, failStage{}, nd.dedupStage(failStage{}, "webhook"))
+	nd.Stop()
+}
+
+func TestFileNotify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_notify")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	now := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFile(&config.FileConfig{
+		Directory: dir,
+		Filename:  `{{ .Receiver }}/{{ .Status }}.json`,
+		MaxFiles:  2,
+	}, tmpl, log.NewNopLogger())
+	f.now = func() time.Time { return now }
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "test"},
+			StartsAt: now.Add(-time.Minute),
+		},
+	}
+	for i := 0; i < 3; i++ {
+		retry, err := f.Notify(ctx, alert)
+		require.NoError(t, err)
+		require.False(t, retry)
+		now = now.Add(time.Second)
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, fi := range infos {
+		names = append(names, fi.Name())
+	}
+	// The oldest file was removed and the templated name cannot escape the directory.
+	require.Equal(t, []string{
+		"20171001T120001.000000000Z-team-x_firing.json",
+		"20171001T120002.000000000Z-team-x_firing.json",
+	}, names)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, names[0]))
+	require.NoError(t, err)
+
+	var msg WebhookMessage
+	require.NoError(t, json.Unmarshal(b, &msg))
+	require.Equal(t, "{}:{}", msg.GroupKey)
+	require.Equal(t, "team-x", msg.Receiver)
+	require.Len(t, msg.Alerts, 1)
+}
+
+func TestTemplateDataEscalated(t *testing.T) {
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"a": "b"})
+	require.False(t, templateData(ctx, tmpl, log.NewNopLogger()).Escalated)
+
+	data := templateData(WithEscalated(ctx, true), tmpl, log.NewNopLogger())
+	require.True(t, data.Escalated)
+	require.Equal(t, template.KV{"a": "b"}, data.GroupLabels)
+}
+
+func TestWebhookPayloadVersions(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	var alerts []*types.Alert
+	for _, name := range []string{"a", "b", "c"} {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name)},
+				StartsAt: time.Now(),
+			},
+		})
+	}
+
+	w := NewWebhook(&config.WebhookConfig{
+		URL:            srv.URL,
+		PayloadVersion: config.WebhookPayloadV1,
+	}, tmpl, log.NewNopLogger())
+	_, err = w.Notify(ctx, alerts...)
+	require.NoError(t, err)
+
+	var v1 WebhookMessage
+	require.NoError(t, json.Unmarshal(body, &v1))
+	require.Equal(t, "4", v1.Version)
+	require.Len(t, v1.Alerts, 3)
+
+	// The v1 payload cannot report left out alerts and is never truncated.
+	w = NewWebhook(&config.WebhookConfig{URL: srv.URL, MaxAlerts: 2}, tmpl, log.NewNopLogger())
+	_, err = w.Notify(ctx, alerts...)
+	require.NoError(t, err)
+	v1 = WebhookMessage{}
+	require.NoError(t, json.Unmarshal(body, &v1))
+	require.Len(t, v1.Alerts, 3)
+
+	w = NewWebhook(&config.WebhookConfig{
+		URL:            srv.URL,
+		PayloadVersion: config.WebhookPayloadV2,
+		MaxAlerts:      2,
+	}, tmpl, log.NewNopLogger())
+	_, err = w.Notify(ctx, alerts...)
+	require.NoError(t, err)
+
+	var v2 WebhookMessageV2
+	require.NoError(t, json.Unmarshal(body, &v2))
+	require.Equal(t, "v2", v2.Version)
+	require.Equal(t, "{}:{}", v2.GroupKey)
+	require.Equal(t, 1, v2.TruncatedAlerts)
+	require.Len(t, v2.Alerts, 2)
+	for i, a := range v2.Alerts {
+		require.Equal(t, alerts[i].Fingerprint().String(), a.Fingerprint)
+		require.Equal(t, string(alerts[i].Labels["alertname"]), a.Labels["alertname"])
+	}
+}
+
+func TestWebhookPagination(t *testing.T) {
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, b)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	var alerts []*types.Alert
+	for i := 0; i < 10; i++ {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": model.LabelValue(fmt.Sprintf("alert-%d", i)),
+					"padding":   model.LabelValue(strings.Repeat("x", 200)),
+				},
+				StartsAt: time.Now(),
+			},
+		})
+	}
+
+	w := NewWebhook(&config.WebhookConfig{
+		URL:            srv.URL,
+		PayloadVersion: config.WebhookPayloadV2,
+		MaxPayloadSize: 2000,
+	}, tmpl, log.NewNopLogger())
+	_, err = w.Notify(ctx, alerts...)
+	require.NoError(t, err)
+	require.True(t, len(bodies) > 1, "payload must be split")
+
+	var (
+		id  string
+		fps []string
+	)
+	for i, b := range bodies {
+		require.True(t, len(b) <= 2000, "part %d exceeds the maximum size", i)
+
+		var msg WebhookMessageV2
+		require.NoError(t, json.Unmarshal(b, &msg))
+		require.NotNil(t, msg.Pagination)
+		require.Equal(t, i+1, msg.Pagination.Part)
+		require.Equal(t, len(bodies), msg.Pagination.Parts)
+		if i == 0 {
+			id = msg.Pagination.NotificationID
+		}
+		require.Equal(t, id, msg.Pagination.NotificationID)
+
+		for _, a := range msg.Alerts {
+			fps = append(fps, a.Fingerprint)
+		}
+	}
+	require.Len(t, fps, len(alerts))
+	for i, a := range alerts {
+		require.Equal(t, a.Fingerprint().String(), fps[i])
+	}
+
+	// Retries after a failed part resend all parts under the same ID.
+	var (
+		failed bool
+		ids    []string
+	)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg WebhookMessageV2
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		ids = append(ids, msg.Pagination.NotificationID)
+		if msg.Pagination.Part == 2 && !failed {
+			failed = true
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	ctx = WithNow(ctx, time.Now())
+	retry, err := w.Notify(ctx, alerts...)
+	require.Error(t, err)
+	require.True(t, retry)
+	_, err = w.Notify(ctx, alerts...)
+	require.NoError(t, err)
+	require.Len(t, ids, 2+len(bodies))
+	for _, pid := range ids {
+		require.Equal(t, ids[0], pid)
+	}
+
+	// Later notifications of the same alerts get a new ID.
+	retried := ids[0]
+	ids = nil
+	_, err = w.Notify(WithNow(ctx, time.Now().Add(time.Minute)), alerts...)
+	require.NoError(t, err)
+	require.NotEqual(t, retried, ids[0])
+
+	// Payloads within the limit are not paginated.
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, b)
+	})
+	bodies = nil
+	_, err = w.Notify(ctx, alerts[:1]...)
+	require.NoError(t, err)
+	require.Len(t, bodies, 1)
+
+	var msg WebhookMessageV2
+	require.NoError(t, json.Unmarshal(bodies[0], &msg))
+	require.Nil(t, msg.Pagination)
+}
+
+func TestPayloadBudget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payloads")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Payloads left over from a previous run are removed.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, spillPrefix+"stale"), []byte("x"), 0644))
+
+	b, err := NewPayloadBudget(10, dir)
+	require.NoError(t, err)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 0)
+
+	p1, err := b.hold([]byte("12345678"))
+	require.NoError(t, err)
+	require.Equal(t, "", p1.file, "payload within the budget must be kept in memory")
+	require.Equal(t, int64(8), b.Used())
+
+	p2, err := b.hold([]byte("abcdefgh"))
+	require.NoError(t, err)
+	require.NotEqual(t, "", p2.file, "payload exceeding the budget must be spilled")
+	require.Equal(t, int64(8), b.Used())
+
+	for _, p := range []*payload{p1, p2} {
+		r, err := p.body()
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.Len(t, got, 8)
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+	}
+	spilled := p2.file
+	p1.release()
+	p2.release()
+	require.Equal(t, int64(0), b.Used())
+	_, err = os.Stat(spilled)
+	require.True(t, os.IsNotExist(err), "spilled payload was not removed")
+
+	// Parts of paginated webhook notifications beyond the budget are
+	// spilled until they are sent.
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(body)), r.ContentLength)
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	b, err = NewPayloadBudget(2000, dir)
+	require.NoError(t, err)
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+	ctx = WithPayloadBudget(ctx, b)
+
+	var alerts []*types.Alert
+	for i := 0; i < 10; i++ {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": model.LabelValue(fmt.Sprintf("alert-%d", i)),
+					"padding":   model.LabelValue(strings.Repeat("x", 200)),
+				},
+				StartsAt: time.Now(),
+			},
+		})
+	}
+	w := NewWebhook(&config.WebhookConfig{
+		URL:            srv.URL,
+		MaxPayloadSize: 1500,
+	}, tmpl, log.NewNopLogger())
+	_, err = w.Notify(ctx, alerts...)
+	require.NoError(t, err)
+	require.True(t, len(bodies) > 2, "payload must be split")
+
+	var n int
+	for _, body := range bodies {
+		var msg WebhookMessage
+		require.NoError(t, json.Unmarshal(body, &msg))
+		n += len(msg.Alerts)
+	}
+	require.Equal(t, len(alerts), n)
+	require.Equal(t, int64(0), b.Used())
+
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 0, "spilled payloads were not removed")
+}
+
+func TestTraceParentPropagation(t *testing.T) {
+	now := time.Now()
+	alerts := []*types.Alert{
+		{UpdatedAt: now.Add(-time.Minute), TraceParent: "00-00000000000000000000000000000001-0000000000000001-01"},
+		{UpdatedAt: now, TraceParent: "00-00000000000000000000000000000002-0000000000000002-01"},
+		{UpdatedAt: now.Add(time.Minute)},
+	}
+	tp := traceParent(alerts)
+	require.Equal(t, "00-00000000000000000000000000000002-0000000000000002-01", tp)
+	require.Equal(t, "", traceParent(alerts[2:]))
+
+	var header string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+	ctx = WithTraceParent(ctx, tp)
+
+	w := NewWebhook(&config.WebhookConfig{URL: srv.URL}, tmpl, log.NewNopLogger())
+	_, err = w.Notify(ctx, alerts...)
+	require.NoError(t, err)
+	require.Equal(t, tp, header)
+}
+
+func TestDingTalkNotify(t *testing.T) {
+	var (
+		query url.Values
+		msg   dingTalkMessage
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	conf := config.DefaultDingTalkConfig
+	conf.URL = config.Secret(srv.URL + "/robot/send?access_token=abc")
+	conf.Secret = "SECabc"
+	conf.Mentions = `{{ .CommonLabels.oncall }}`
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a", "oncall": "1380000,1390000"},
+			StartsAt: time.Now(),
+		},
+	}
+	_, err = NewDingTalk(&conf, tmpl, log.NewNopLogger()).Notify(ctx, alert)
+	require.NoError(t, err)
+
+	require.Equal(t, "abc", query.Get("access_token"))
+	ts := query.Get("timestamp")
+	require.NotEmpty(t, ts)
+	require.Equal(t, signHMAC("SECabc", ts+"\nSECabc"), query.Get("sign"))
+
+	require.Equal(t, "markdown", msg.MsgType)
+	require.Equal(t, []string{"1380000", "1390000"}, msg.At.AtMobiles)
+	require.Contains(t, msg.Markdown.Text, "**a**")
+	require.Contains(t, msg.Markdown.Text, "@1380000 @1390000")
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":130101,"errmsg":"send too fast"}`))
+	})
+	retry, err := NewDingTalk(&conf, tmpl, log.NewNopLogger()).Notify(ctx, alert)
+	require.Error(t, err)
+	require.True(t, retry)
+}
+
+func TestLarkNotify(t *testing.T) {
+	var msg larkMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		w.Write([]byte(`{"code":0,"msg":"success"}`))
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	conf := config.DefaultLarkConfig
+	conf.URL = config.Secret(srv.URL)
+	conf.Secret = "abc"
+	conf.Mentions = `{{ .CommonLabels.oncall }}`
+	conf.MentionAll = true
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a", "oncall": "ou_123"},
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(-time.Minute),
+		},
+	}
+	_, err = NewLark(&conf, tmpl, log.NewNopLogger()).Notify(ctx, alert)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, msg.Timestamp)
+	require.Equal(t, signHMAC(msg.Timestamp+"\nabc", ""), msg.Sign)
+	require.Equal(t, "interactive", msg.MsgType)
+	require.Equal(t, "green", msg.Card.Header.Template)
+	require.Len(t, msg.Card.Elements, 1)
+	require.Contains(t, msg.Card.Elements[0].Text.Content, "<at id=ou_123></at> <at id=all></at>")
+}
+
+func TestPushoverNotify(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+	}))
+	defer srv.Close()
+
+	defer func(u string) { pushoverAPIURL = u }(pushoverAPIURL)
+	pushoverAPIURL = srv.URL
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+
+	conf := config.DefaultPushoverConfig
+	conf.UserKey = "user"
+	conf.Token = "token"
+	conf.Device = `{{ .CommonLabels.device }}`
+	conf.URLTitle = `Runbook for {{ .CommonLabels.alertname }}`
+	conf.Retry = `{{ .CommonLabels.retry }}`
+	conf.Expire = `24h`
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "a", "device": "phone, tablet", "retry": "10s"},
+			StartsAt: time.Now(),
+		},
+	}
+	n := NewPushover(&conf, tmpl, log.NewNopLogger())
+	_, err = n.Notify(ctx, alert)
+	require.NoError(t, err)
+
+	require.Equal(t, "phone,tablet", query.Get("device"))
+	require.Equal(t, "Runbook for a", query.Get("url_title"))
+	require.Equal(t, "2", query.Get("priority"))
+	require.Equal(t, "30", query.Get("retry"))
+	require.Equal(t, "10800", query.Get("expire"))
+
+	// Retry and expire are only sent with emergency priority.
+	conf.Priority = "1"
+	_, err = n.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.Equal(t, "1", query.Get("priority"))
+	require.Empty(t, query.Get("retry"))
+	require.Empty(t, query.Get("expire"))
+
+	conf.Priority = "2"
+	conf.Retry = "soon"
+	_, err = n.Notify(ctx, alert)
+	require.Error(t, err)
+}
+
+func TestProviderID(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	tmpl, err := template.FromGlobs()
+	require.NoError(t, err)
+	tmpl.ExternalURL, _ = url.Parse("http://am.example.org")
+
+	ctx := WithReceiverName(context.Background(), "team-x")
+	ctx = WithGroupKey(ctx, "{}:{}")
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+	alerts := []*types.Alert{{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "a"},
+		StartsAt: time.Now(),
+	}}}
+
+	for _, tc := range []struct {
+		notifier Notifier
+		body     string
+		id       string
+	}{
+		{
+			notifier: NewPagerDuty(&config.PagerdutyConfig{URL: srv.URL}, tmpl, log.NewNopLogger()),
+			body:     `{"status":"success","incident_key":"abc"}`,
+			id:       "abc",
+		},
+		{
+			// Without a response body the sent incident key is used.
+			notifier: NewPagerDuty(&config.PagerdutyConfig{URL: srv.URL}, tmpl, log.NewNopLogger()),
+			id:       hashKey("{}:{}"),
+		},
+		{
+			notifier: NewSlack(&config.SlackConfig{APIURL: config.Secret(srv.URL)}, tmpl, log.NewNopLogger()),
+			body:     `{"ok":true,"ts":"1503435956.000247"}`,
+			id:       "1503435956.000247",
+		},
+		{
+			notifier: NewSlack(&config.SlackConfig{APIURL: config.Secret(srv.URL)}, tmpl, log.NewNopLogger()),
+			body:     "ok",
+		},
+		{
+			notifier: NewOpsGenie(&config.OpsGenieConfig{APIHost: srv.URL + "/"}, tmpl, log.NewNopLogger()),
+			body:     `{"result":"Request will be processed","requestId":"43a29c5c"}`,
+			id:       "43a29c5c",
+		},
+	} {
+		body = tc.body
+		ctx := withProviderID(ctx)
+
+		_, err := tc.notifier.Notify(ctx, alerts...)
+		require.NoError(t, err)
+
+		id, ok := ProviderID(ctx)
+		require.Equal(t, tc.id != "", ok)
+		require.Equal(t, tc.id, id)
+	}
+
+	// The provider ID is stored in the notification log.
+	nl, err := nflog.New(nflog.WithRetention(time.Hour))
+	require.NoError(t, err)
+	recv := &nflogpb.Receiver{GroupName: "team-x", Integration: "slack"}
+
+	ctx = withProviderID(ctx)
+	ctx = WithFiringAlerts(ctx, []uint64{1})
+	ctx = WithResolvedAlerts(ctx, []uint64{})
+	setProviderID(ctx, "1503435956.000247")
+
+	_, _, err = NewSetNotifiesStage(nl, recv).Exec(ctx, log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+
+	e, err := nl.QueryOne(nflog.QReceiver(recv), nflog.QGroupKey("{}:{}"))
+	require.NoError(t, err)
+	require.Equal(t, "1503435956.000247", e.ProviderId)
+}
+
+func TestAcknowledge(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := ParseAcknowledgements("slack", strings.NewReader("{}"), now)
+	require.Error(t, err)
+	_, err = ParseAcknowledgements("pagerduty", strings.NewReader("{"), now)
+	require.Error(t, err)
+
+	acks, err := ParseAcknowledgements("pagerduty", strings.NewReader(`{"messages": [
+		{"event": "incident.acknowledge", "created_on": "2017-09-01T10:00:00Z", "incident": {"incident_key": "pd-key"}},
+		{"event": "incident.resolve", "created_on": "2017-09-01T11:00:00Z", "incident": {"incident_key": "pd-key"}},
+		{"event": "incident.acknowledge", "incident": {"incident_key": "other"}}
+	]}`), now)
+	require.NoError(t, err)
+	require.Equal(t, []Acknowledgement{
+		{DedupKey: "pd-key", At: time.Date(2017, 9, 1, 10, 0, 0, 0, time.UTC)},
+		{DedupKey: "other", At: now},
+	}, acks)
+
+	acks, err = ParseAcknowledgements("opsgenie", strings.NewReader(`{"action": "Close", "alert": {"alias": "og-key"}}`), now)
+	require.NoError(t, err)
+	require.Empty(t, acks)
+	acks, err = ParseAcknowledgements("opsgenie", strings.NewReader(`{"action": "Acknowledge", "alert": {"alias": "og-key"}}`), now)
+	require.NoError(t, err)
+	require.Equal(t, []Acknowledgement{{DedupKey: "og-key", At: now}}, acks)
+
+	nl, err := nflog.New(nflog.WithRetention(time.Hour), nflog.WithNow(func() time.Time { return now }))
+	require.NoError(t, err)
+	var (
+		pd = &nflogpb.Receiver{GroupName: "team-x", Integration: "pagerduty"}
+		og = &nflogpb.Receiver{GroupName: "team-x", Integration: "opsgenie"}
+	)
+	// PagerDuty notifications are correlated by the incident key returned
+	// as provider ID, OpsGenie ones by the alias derived from the group key.
+	require.NoError(t, nl.Log(pd, "{}:{a=\"1\"}", []uint64{1}, nil, nflog.LProviderID("pd-key")))
+	require.NoError(t, nl.Log(pd, "{}:{a=\"2\"}", []uint64{1}, nil, nflog.LProviderID("other-key")))
+	require.NoError(t, nl.Log(og, "{}:{a=\"1\"}", []uint64{1}, nil, nflog.LProviderID("43a29c5c")))
+
+	ackAt := now.Add(5 * time.Minute)
+	entries, err := Acknowledge(nl, "pagerduty", []Acknowledgement{
+		{DedupKey: "pd-key", At: ackAt.Add(time.Minute)},
+		{DedupKey: "pd-key", At: ackAt},
+		{DedupKey: hashKey("{}:{a=\"1\"}"), At: ackAt},
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, pd, entries[0].Receiver)
+	require.Equal(t, ackAt, *entries[0].AcknowledgedAt)
+
+	entries, err = Acknowledge(nl, "opsgenie", []Acknowledgement{{DedupKey: hashKey("{}:{a=\"1\"}"), At: ackAt}})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, og, entries[0].Receiver)
+
+	// Repeated acknowledgements are not reported again.
+	entries, err = Acknowledge(nl, "opsgenie", []Acknowledgement{{DedupKey: hashKey("{}:{a=\"1\"}"), At: ackAt}})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	e, err := nl.QueryOne(nflog.QGroupKey("{}:{a=\"2\"}"), nflog.QReceiver(pd))
+	require.NoError(t, err)
+	require.Nil(t, e.AcknowledgedAt)
+}
@@ -0,0 +1,245 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+var (
+	payloadBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_payload_bytes",
+		Help:      "The size of rendered notification payloads currently held in memory.",
+	})
+	numSpilledPayloads = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_payloads_spilled_total",
+		Help:      "The total number of rendered notification payloads written to disk as they exceeded the memory budget.",
+	})
+	spilledPayloadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_payload_spilled_bytes_total",
+		Help:      "The total size of rendered notification payloads written to disk.",
+	})
+	numFailedSpills = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_payload_spill_failures_total",
+		Help:      "The total number of rendered notification payloads that could not be written to disk.",
+	})
+)
+
+func init() {
+	prometheus.Register(payloadBytes)
+	prometheus.Register(numSpilledPayloads)
+	prometheus.Register(spilledPayloadBytes)
+	prometheus.Register(numFailedSpills)
+}
+
+// spillPrefix is the prefix of the names of spilled payload files.
+const spillPrefix = "payload-"
+
+// A PayloadBudget bounds the total size of rendered notification payloads
+// held in memory across all integrations. Payloads exceeding the budget are
+// written to files in the spill directory until they were sent, so that
+// alert storms do not push the process beyond its memory limit.
+type PayloadBudget struct {
+	limit int64
+	dir   string
+
+	mtx  sync.Mutex
+	used int64
+}
+
+// NewPayloadBudget returns a budget of limit bytes spilling payloads to
+// files in dir. The directory is created if necessary and payloads spilled
+// by a previous run are removed from it.
+func NewPayloadBudget(limit int64, dir string) (*PayloadBudget, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("payload budget must not be negative")
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, spillPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return nil, err
+		}
+	}
+	return &PayloadBudget{limit: limit, dir: dir}, nil
+}
+
+// Used returns the size of the payloads currently held in memory.
+func (b *PayloadBudget) Used() int64 {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.used
+}
+
+// reserve accounts n bytes against the budget if they fit into it.
+func (b *PayloadBudget) reserve(n int64) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.used+n > b.limit {
+		return false
+	}
+	b.used += n
+	payloadBytes.Add(float64(n))
+	return true
+}
+
+func (b *PayloadBudget) free(n int64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.used -= n
+	payloadBytes.Sub(float64(n))
+}
+
+// hold returns the payload kept in memory if it fits into the budget and
+// spilled to disk otherwise. Without a budget payloads are always kept in
+// memory. The payload must be released once it was sent.
+func (b *PayloadBudget) hold(data []byte) (*payload, error) {
+	p := &payload{size: int64(len(data))}
+	if b == nil {
+		p.data = data
+		return p, nil
+	}
+	if b.reserve(p.size) {
+		p.data = data
+		p.budget = b
+		return p, nil
+	}
+	f, err := ioutil.TempFile(b.dir, spillPrefix)
+	if err != nil {
+		numFailedSpills.Inc()
+		return nil, fmt.Errorf("spilling notification payload: %s", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		numFailedSpills.Inc()
+		return nil, fmt.Errorf("spilling notification payload: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		numFailedSpills.Inc()
+		return nil, fmt.Errorf("spilling notification payload: %s", err)
+	}
+	numSpilledPayloads.Inc()
+	spilledPayloadBytes.Add(float64(p.size))
+
+	p.file = f.Name()
+	return p, nil
+}
+
+// payload is a rendered notification payload held in memory or spilled to
+// a file.
+type payload struct {
+	data   []byte
+	file   string
+	size   int64
+	budget *PayloadBudget
+}
+
+// body returns a reader of the payload.
+func (p *payload) body() (io.Reader, error) {
+	if p.file == "" {
+		return bytes.NewReader(p.data), nil
+	}
+	return os.Open(p.file)
+}
+
+// release frees the memory or file held by the payload.
+func (p *payload) release() {
+	if p.budget != nil {
+		p.budget.free(p.size)
+		p.budget = nil
+	}
+	p.data = nil
+	if p.file != "" {
+		os.Remove(p.file)
+		p.file = ""
+	}
+}
+
+// request returns a request sending the payload as its body.
+func (p *payload) request(method, rawurl string) (*http.Request, error) {
+	body, err := p.body()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, rawurl, body)
+	if err != nil {
+		if c, ok := body.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	// Spilled payloads are sent with their known length instead of chunked.
+	req.ContentLength = p.size
+	return req, nil
+}
+
+// WithPayloadBudget populates a context with the budget that rendered
+// payloads are held against while they are sent.
+func WithPayloadBudget(ctx context.Context, b *PayloadBudget) context.Context {
+	return context.WithValue(ctx, keyPayloadBudget, b)
+}
+
+// payloadBudget extracts the payload budget from the context. It returns
+// nil if there is none.
+func payloadBudget(ctx context.Context) *PayloadBudget {
+	b, _ := ctx.Value(keyPayloadBudget).(*PayloadBudget)
+	return b
+}
+
+// holdPayload holds the payload against the budget of the context.
+func holdPayload(ctx context.Context, data []byte) (*payload, error) {
+	return payloadBudget(ctx).hold(data)
+}
+
+// postPayload issues a POST request of the payload to the URL like post.
+// The payload is held against the budget of the context until it was
+// sent.
+func postPayload(ctx context.Context, rawurl, bodyType string, data []byte) (*http.Response, error) {
+	p, err := holdPayload(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	req, err := p.request("POST", rawurl)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", bodyType)
+	return do(ctx, req)
+}
@@ -3,13 +3,18 @@ package parse
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
+
+	"github.com/prometheus/alertmanager/types"
 )
 
 var (
-	re      = regexp.MustCompile(`(?:\s?)(\w+)(=|=~|!=|!~)(?:\"([^"=~!]+)\"|([^"=~!]+))`)
+	// Label names that are not classic Prometheus label names must be quoted.
+	re      = regexp.MustCompile(`(?:\s?)(\w+|"(?:[^"\\]|\\.)+")(=|=~|!=|!~)(?:\"([^"=~!]+)\"|([^"=~!]+))`)
 	typeMap = map[string]labels.MatchType{
 		"=":  labels.MatchEqual,
 		"!=": labels.MatchNotEqual,
@@ -20,21 +25,65 @@ var (
 
 func Matchers(s string) ([]*labels.Matcher, error) {
 	matchers := []*labels.Matcher{}
+	for _, toParse := range split(s) {
+		m, err := Matcher(toParse)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+// split returns the matchers of an expression in optional braces.
+func split(s string) []string {
 	if strings.HasPrefix(s, "{") {
 		s = s[1:]
 	}
 	if strings.HasSuffix(s, "}") {
 		s = s[:len(s)-1]
 	}
+	return strings.Split(s, ",")
+}
 
-	for _, toParse := range strings.Split(s, ",") {
-		m, err := Matcher(toParse)
+// SilenceMatchers parses a matcher expression such as
+// {job="api",instance=~"web-.*"} into the matchers of a silence. amtool and
+// the API both use it, so that they accept the same expressions.
+func SilenceMatchers(s string) (types.Matchers, error) {
+	var res types.Matchers
+	for _, toParse := range split(s) {
+		// Unlike the matchers returned by Matcher, silence matchers hold
+		// regular expressions as given rather than anchored.
+		name, value, matchType, err := Input(toParse)
 		if err != nil {
 			return nil, err
 		}
-		matchers = append(matchers, m)
+		sm, err := SilenceMatcher(&labels.Matcher{Type: matchType, Name: name, Value: value})
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sm)
 	}
-	return matchers, nil
+	return res, nil
+}
+
+// SilenceMatcher converts a matcher into the matcher of a silence.
+func SilenceMatcher(m *labels.Matcher) (*types.Matcher, error) {
+	sm := types.NewMatcher(model.LabelName(m.Name), m.Value)
+
+	switch m.Type {
+	case labels.MatchEqual:
+	case labels.MatchRegexp:
+		sm.IsRegex = true
+	case labels.MatchNotEqual:
+		sm.IsNegative = true
+	case labels.MatchNotRegexp:
+		sm.IsRegex = true
+		sm.IsNegative = true
+	default:
+		return nil, fmt.Errorf("invalid match type for creation operation: %s", m.Type)
+	}
+	return sm, nil
 }
 
 func Matcher(s string) (*labels.Matcher, error) {
@@ -60,6 +109,12 @@ func Input(s string) (name, value string, matchType labels.MatchType, err error)
 	name = ms[1]
 	matchType, prs = typeMap[ms[2]]
 
+	if strings.HasPrefix(name, `"`) {
+		if name, err = strconv.Unquote(name); err != nil {
+			return "", "", labels.MatchEqual, fmt.Errorf("bad label name: %s", err)
+		}
+	}
+
 	if ms[3] != "" {
 		value = ms[3]
 	} else {
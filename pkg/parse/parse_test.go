@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/prometheus/prometheus/pkg/labels"
+
+	"github.com/prometheus/alertmanager/types"
 )
 
 func TestMatchers(t *testing.T) {
@@ -20,6 +22,15 @@ func TestMatchers(t *testing.T) {
 				return append(ms, m)
 			}(),
 		},
+		{
+			input: `{"service.name"="bar", "ünïcode"!~"baz.*"}`,
+			want: func() []*labels.Matcher {
+				ms := []*labels.Matcher{}
+				m, _ := labels.NewMatcher(labels.MatchEqual, "service.name", "bar")
+				m2, _ := labels.NewMatcher(labels.MatchNotRegexp, "ünïcode", "baz.*")
+				return append(ms, m, m2)
+			}(),
+		},
 		{
 			input: `{foo=~"bar.*"}`,
 			want: func() []*labels.Matcher {
@@ -114,3 +125,25 @@ func TestMatchers(t *testing.T) {
 	}
 
 }
+
+func TestSilenceMatchers(t *testing.T) {
+	got, err := SilenceMatchers(`{job="api",instance=~"web-.*", env!="dev", "service.name"!~"batch-.*"}`)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	want := types.Matchers{
+		{Name: "job", Value: "api"},
+		{Name: "instance", Value: "web-.*", IsRegex: true},
+		{Name: "env", Value: "dev", IsNegative: true},
+		{Name: "service.name", Value: "batch-.*", IsRegex: true, IsNegative: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("error not equal:\ngot  %v\nwant %v", got, want)
+	}
+
+	for _, s := range []string{`{}`, `{job}`, `{job="api",}`} {
+		if _, err := SilenceMatchers(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
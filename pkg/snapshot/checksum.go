@@ -0,0 +1,121 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ErrChecksumMismatch is returned if the checksum trailer of a snapshot
+// does not match its contents.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// TrailerSize is the size of the checksum trailer in bytes.
+const TrailerSize = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumWriter computes the CRC-32C checksum of the data written through
+// it, which is appended as a trailer by WriteTrailer.
+type ChecksumWriter struct {
+	w io.Writer
+	h hash.Hash32
+}
+
+// NewChecksumWriter returns a ChecksumWriter writing to w.
+func NewChecksumWriter(w io.Writer) *ChecksumWriter {
+	return &ChecksumWriter{w: w, h: crc32.New(castagnoli)}
+}
+
+func (w *ChecksumWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.h.Write(b[:n])
+	return n, err
+}
+
+// WriteTrailer writes the checksum of the data written so far. No data
+// must be written afterwards.
+func (w *ChecksumWriter) WriteTrailer() error {
+	var buf [TrailerSize]byte
+	binary.BigEndian.PutUint32(buf[:], w.h.Sum32())
+	_, err := w.w.Write(buf[:])
+	return err
+}
+
+// Sum32 returns the checksum of the data written so far, excluding the
+// trailer.
+func (w *ChecksumWriter) Sum32() uint32 {
+	return w.h.Sum32()
+}
+
+// ByteReader is the reader snapshots are decoded from.
+type ByteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// ChecksumReader computes the CRC-32C checksum of the data read through
+// it to verify it against the trailer written by a ChecksumWriter. It does
+// not read ahead of the data consumed by its caller.
+type ChecksumReader struct {
+	r ByteReader
+	h hash.Hash32
+}
+
+// NewChecksumReader returns a ChecksumReader reading from r.
+func NewChecksumReader(r ByteReader) *ChecksumReader {
+	return &ChecksumReader{r: r, h: crc32.New(castagnoli)}
+}
+
+func (r *ChecksumReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.h.Write(b[:n])
+	return n, err
+}
+
+// ReadByte implements io.ByteReader.
+func (r *ChecksumReader) ReadByte() (byte, error) {
+	c, err := r.r.ReadByte()
+	if err == nil {
+		r.h.Write([]byte{c})
+	}
+	return c, err
+}
+
+// VerifyTrailer reads the trailer and checks it against the checksum of
+// the data read so far. The trailer must be the end of the data.
+func (r *ChecksumReader) VerifyTrailer() error {
+	var buf [TrailerSize]byte
+	if _, err := io.ReadFull(r.r, buf[:]); err != nil {
+		return fmt.Errorf("truncated checksum")
+	}
+	if _, err := r.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("unexpected data after checksum")
+	}
+	if binary.BigEndian.Uint32(buf[:]) != r.h.Sum32() {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// Sum32 returns the checksum of the data read so far, excluding the
+// trailer.
+func (r *ChecksumReader) Sum32() uint32 {
+	return r.h.Sum32()
+}
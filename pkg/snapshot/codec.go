@@ -0,0 +1,291 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+)
+
+// MaxEntrySize is the size above which the length prefix of an entry is
+// considered corrupted rather than allocating a buffer for it.
+const MaxEntrySize = 1 << 24
+
+// Codec encodes the entries of snapshots.
+type Codec interface {
+	// Name identifies the codec in snapshot headers.
+	Name() string
+	// NewEncoder returns an Encoder writing entries to w.
+	NewEncoder(w io.Writer) Encoder
+	// NewDecoder returns a Decoder reading the entries written by an
+	// Encoder of the codec from r.
+	NewDecoder(r ByteReader) Decoder
+}
+
+// Encoder writes the entries of a snapshot.
+type Encoder interface {
+	// Encode writes the entry.
+	Encode(m proto.Message) error
+	// Close marks the end of the entries. Nothing must be written
+	// afterwards.
+	Close() error
+}
+
+// Decoder reads the entries of a snapshot.
+type Decoder interface {
+	// Decode reads the next entry into m and returns the number of bytes
+	// it occupied. It returns io.EOF after the last entry and an
+	// *EntryError if the entry was read but could not be decoded.
+	Decode(m proto.Message) (int, error)
+}
+
+// EntryError is returned by decoders for entries that could not be decoded.
+type EntryError struct {
+	Err error
+}
+
+func (e *EntryError) Error() string {
+	return e.Err.Error()
+}
+
+// ParseCodec returns the codec with the given name. The empty string is
+// equivalent to ProtoCodec.
+func ParseCodec(s string) (Codec, error) {
+	switch s {
+	case "", ProtoCodec{}.Name():
+		return ProtoCodec{}, nil
+	case JSONCodec{}.Name():
+		return JSONCodec{}, nil
+	}
+	return nil, fmt.Errorf("unknown snapshot codec %q", s)
+}
+
+// ProtoCodec encodes entries as varint length-delimited protocol buffer
+// messages. The entries are followed by an empty entry and a checksum
+// trailer, which detects truncated snapshots.
+type ProtoCodec struct {
+	// Unchecksummed reads entries up to the end of the data without
+	// expecting an empty entry and a checksum trailer, as written by old
+	// snapshot formats. It is not supported for writing.
+	Unchecksummed bool
+}
+
+// Name implements the Codec interface.
+func (ProtoCodec) Name() string { return "proto" }
+
+// NewEncoder implements the Codec interface.
+func (ProtoCodec) NewEncoder(w io.Writer) Encoder {
+	return &protoEncoder{w: NewChecksumWriter(w)}
+}
+
+// NewDecoder implements the Codec interface.
+func (c ProtoCodec) NewDecoder(r ByteReader) Decoder {
+	if c.Unchecksummed {
+		return &protoDecoder{r: r}
+	}
+	cr := NewChecksumReader(r)
+	return &protoDecoder{r: cr, cr: cr}
+}
+
+type protoEncoder struct {
+	w *ChecksumWriter
+}
+
+func (e *protoEncoder) Encode(m proto.Message) error {
+	_, err := pbutil.WriteDelimited(e.w, m)
+	return err
+}
+
+func (e *protoEncoder) Close() error {
+	// An empty entry marks the end of the entries.
+	if _, err := e.w.Write([]byte{0}); err != nil {
+		return err
+	}
+	return e.w.WriteTrailer()
+}
+
+type protoDecoder struct {
+	r ByteReader
+	// Reads the checksum trailer. Nil for unchecksummed entries.
+	cr *ChecksumReader
+}
+
+func (d *protoDecoder) Decode(m proto.Message) (int, error) {
+	size, err := binary.ReadUvarint(d.r)
+	if err == io.EOF {
+		if d.cr != nil {
+			return 0, errors.New("missing checksum")
+		}
+		return 0, io.EOF
+	}
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("truncated length prefix")
+		}
+		return 0, err
+	}
+	if size == 0 && d.cr != nil {
+		if err := d.cr.VerifyTrailer(); err != nil {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+	if size > MaxEntrySize {
+		return 0, fmt.Errorf("entry size %d exceeds limit of %d bytes", size, MaxEntrySize)
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return 0, fmt.Errorf("truncated entry of %d bytes", size)
+	}
+	if err := proto.Unmarshal(buf, m); err != nil {
+		return 0, &EntryError{Err: err}
+	}
+	var lbuf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(lbuf[:], size) + int(size), nil
+}
+
+// JSONCodec encodes entries as JSON objects, one per line. It is meant for
+// debugging, as the snapshots can be read and edited with text tools.
+// Unlike ProtoCodec it does not detect truncated snapshots.
+type JSONCodec struct{}
+
+// Name implements the Codec interface.
+func (JSONCodec) Name() string { return "json" }
+
+// NewEncoder implements the Codec interface.
+func (JSONCodec) NewEncoder(w io.Writer) Encoder {
+	return jsonEncoder{json.NewEncoder(w)}
+}
+
+// NewDecoder implements the Codec interface.
+func (JSONCodec) NewDecoder(r ByteReader) Decoder {
+	return &jsonDecoder{d: json.NewDecoder(r)}
+}
+
+type jsonEncoder struct {
+	e *json.Encoder
+}
+
+func (e jsonEncoder) Encode(m proto.Message) error {
+	return e.e.Encode(m)
+}
+
+func (e jsonEncoder) Close() error {
+	return nil
+}
+
+type jsonDecoder struct {
+	d *json.Decoder
+}
+
+func (d *jsonDecoder) Decode(m proto.Message) (int, error) {
+	start := d.d.InputOffset()
+	m.Reset()
+
+	if err := d.d.Decode(m); err != nil {
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			return 0, errors.New("truncated entry")
+		}
+		return 0, &EntryError{Err: err}
+	}
+	return int(d.d.InputOffset() - start), nil
+}
+
+// WriteHeader writes the header of a snapshot of the format identified by
+// magic in the given version, whose entries are encoded with the codec.
+// For ProtoCodec the magic is followed by the uvarint encoded version.
+// Other codecs are named in a text line following the magic, such as
+// "ANFL json 2", so that snapshots encoded as text remain text.
+func WriteHeader(w io.Writer, magic []byte, version uint64, c Codec) error {
+	var buf bytes.Buffer
+	buf.Write(magic)
+
+	if _, ok := c.(ProtoCodec); ok {
+		var vbuf [binary.MaxVarintLen64]byte
+		buf.Write(vbuf[:binary.PutUvarint(vbuf[:], version)])
+	} else {
+		fmt.Fprintf(&buf, " %s %d\n", c.Name(), version)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Header describes a snapshot header read by ReadHeader.
+type Header struct {
+	// Version of the snapshot format.
+	Version uint64
+	// Codec the entries are encoded with.
+	Codec Codec
+	// Size of the header in bytes.
+	Size int
+}
+
+// ReadHeader consumes the header written by WriteHeader for the magic. It
+// returns false without consuming any data if the snapshot does not start
+// with the magic.
+func ReadHeader(br *bufio.Reader, magic []byte) (Header, bool, error) {
+	b, err := br.Peek(len(magic) + 1)
+	if err != nil && err != io.EOF {
+		return Header{}, false, err
+	}
+	if !bytes.HasPrefix(b, magic) {
+		return Header{}, false, nil
+	}
+	br.Discard(len(magic))
+
+	// The version of proto encoded snapshots is never a space.
+	if len(b) > len(magic) && b[len(magic)] == ' ' {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return Header{}, true, errors.New("truncated header")
+		}
+		f := strings.Fields(line)
+		if len(f) != 2 {
+			return Header{}, true, fmt.Errorf("invalid header %q", line)
+		}
+		c, err := ParseCodec(f[0])
+		if err != nil {
+			return Header{}, true, err
+		}
+		v, err := strconv.ParseUint(f[1], 10, 64)
+		if err != nil {
+			return Header{}, true, fmt.Errorf("invalid header %q", line)
+		}
+		return Header{Version: v, Codec: c, Size: len(magic) + len(line)}, true, nil
+	}
+
+	v, err := binary.ReadUvarint(br)
+	if err != nil {
+		return Header{}, true, errors.New("truncated header")
+	}
+	var vbuf [binary.MaxVarintLen64]byte
+	return Header{
+		Version: v,
+		Codec:   ProtoCodec{},
+		Size:    len(magic) + binary.PutUvarint(vbuf[:], v),
+	}, true, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/duration"
+)
+
+func TestParseCodec(t *testing.T) {
+	for s, exp := range map[string]Codec{
+		"":      ProtoCodec{},
+		"proto": ProtoCodec{},
+		"json":  JSONCodec{},
+	} {
+		c, err := ParseCodec(s)
+		if err != nil {
+			t.Fatalf("parsing %q failed: %s", s, err)
+		}
+		if c != exp {
+			t.Errorf("%q: expected codec %v, got %v", s, exp, c)
+		}
+	}
+	if _, err := ParseCodec("xml"); err == nil {
+		t.Errorf("expected error for unknown codec")
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	magic := []byte("ATST")
+	entries := []*duration.Duration{{Seconds: 1}, {Seconds: 2, Nanos: 3}, {Nanos: 4}}
+
+	for _, c := range []Codec{ProtoCodec{}, JSONCodec{}} {
+		var buf bytes.Buffer
+		if err := WriteHeader(&buf, magic, 3, c); err != nil {
+			t.Fatalf("%s: writing header failed: %s", c.Name(), err)
+		}
+		enc := c.NewEncoder(&buf)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				t.Fatalf("%s: encoding failed: %s", c.Name(), err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatalf("%s: closing encoder failed: %s", c.Name(), err)
+		}
+
+		br := bufio.NewReader(&buf)
+		h, ok, err := ReadHeader(br, magic)
+		if err != nil || !ok {
+			t.Fatalf("%s: reading header failed: %v, %v", c.Name(), ok, err)
+		}
+		if h.Version != 3 || h.Codec != c {
+			t.Fatalf("%s: unexpected header %+v", c.Name(), h)
+		}
+
+		dec := h.Codec.NewDecoder(br)
+		for i, exp := range entries {
+			var e duration.Duration
+			_, err := dec.Decode(&e)
+			if err != nil {
+				t.Fatalf("%s: decoding entry %d failed: %s", c.Name(), i, err)
+			}
+			if !proto.Equal(&e, exp) {
+				t.Errorf("%s: expected entry %v, got %v", c.Name(), exp, &e)
+			}
+		}
+		if _, err := dec.Decode(&duration.Duration{}); err != io.EOF {
+			t.Fatalf("%s: expected EOF after last entry, got %v", c.Name(), err)
+		}
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	magic := []byte("ATST")
+
+	br := bufio.NewReader(strings.NewReader("\x0a\x02"))
+	if _, ok, err := ReadHeader(br, magic); ok || err != nil {
+		t.Fatalf("expected no header, got %v, %v", ok, err)
+	}
+	if br.Buffered() != 2 {
+		t.Errorf("data without header must not be consumed")
+	}
+
+	br = bufio.NewReader(strings.NewReader("ATST json 1\n{}\n"))
+	h, ok, err := ReadHeader(br, magic)
+	if err != nil || !ok {
+		t.Fatalf("reading text header failed: %v, %v", ok, err)
+	}
+	if h.Version != 1 || h.Codec != (JSONCodec{}) || h.Size != 12 {
+		t.Errorf("unexpected header %+v", h)
+	}
+
+	for _, s := range []string{"ATST", "ATST json 1", "ATST xml 1\n", "ATST json\n", "ATST json x\n"} {
+		if _, _, err := ReadHeader(bufio.NewReader(strings.NewReader(s)), magic); err == nil {
+			t.Errorf("expected error for header %q", s)
+		}
+	}
+}
+
+func TestProtoDecoderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	enc := ProtoCodec{}.NewEncoder(&buf)
+	if err := enc.Encode(&duration.Duration{Seconds: 1}); err != nil {
+		t.Fatalf("encoding failed: %s", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("closing encoder failed: %s", err)
+	}
+	data := buf.Bytes()
+
+	for name, b := range map[string][]byte{
+		"missing checksum":  data[:3],
+		"truncated entry":   data[:2],
+		"checksum mismatch": append(append([]byte{}, data[:len(data)-1]...), data[len(data)-1]+1),
+	} {
+		dec := ProtoCodec{}.NewDecoder(bytes.NewReader(b))
+		var err error
+		for err == nil {
+			_, err = dec.Decode(&duration.Duration{})
+		}
+		if err == io.EOF {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	dec := ProtoCodec{}.NewDecoder(bytes.NewReader([]byte{0x02, 0xff, 0xff}))
+	if _, err := dec.Decode(&duration.Duration{}); err == nil {
+		t.Errorf("expected error for malformed entry")
+	} else if _, ok := err.(*EntryError); !ok {
+		t.Errorf("expected entry error, got %T", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot provides helpers shared by the snapshot files of the
+// notification log and the silences.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// Compression is the compression applied to snapshots when writing them.
+type Compression string
+
+// The supported snapshot compressions.
+const (
+	CompressionNone   Compression = "none"
+	CompressionGzip   Compression = "gzip"
+	CompressionSnappy Compression = "snappy"
+)
+
+// The magic bytes starting compressed snapshots. For snappy this is the
+// stream identifier of the framing format.
+var (
+	gzipMagic   = []byte{0x1f, 0x8b}
+	snappyMagic = []byte("\xff\x06\x00\x00sNaPpY")
+)
+
+// ParseCompression returns the compression with the given name. The empty
+// string is equivalent to CompressionNone.
+func ParseCompression(s string) (Compression, error) {
+	switch c := Compression(s); c {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, CompressionSnappy:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown snapshot compression %q", s)
+}
+
+// Writer compresses the data written to it and counts the bytes written
+// to the underlying writer. It must be closed to flush compressed data.
+type Writer struct {
+	w  io.Writer
+	zw io.WriteCloser
+	n  int
+}
+
+// NewWriter returns a Writer writing to w with the given compression.
+func NewWriter(w io.Writer, c Compression) (*Writer, error) {
+	sw := &Writer{w: w}
+
+	switch c {
+	case "", CompressionNone:
+	case CompressionGzip:
+		sw.zw = gzip.NewWriter(countWriter{sw})
+	case CompressionSnappy:
+		sw.zw = snappy.NewBufferedWriter(countWriter{sw})
+	default:
+		return nil, fmt.Errorf("unknown snapshot compression %q", c)
+	}
+	return sw, nil
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if w.zw != nil {
+		return w.zw.Write(b)
+	}
+	return countWriter{w}.Write(b)
+}
+
+// Close flushes pending compressed data. It does not close the underlying
+// writer.
+func (w *Writer) Close() error {
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+	return nil
+}
+
+// Written returns the number of bytes written to the underlying writer.
+func (w *Writer) Written() int {
+	return w.n
+}
+
+type countWriter struct {
+	*Writer
+}
+
+func (w countWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += n
+	return n, err
+}
+
+// NewReader returns a reader of the uncompressed contents of r. The
+// compression is detected from the first bytes of r, which for uncompressed
+// snapshots must never match the magic bytes of a compressed stream.
+func NewReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	b, err := br.Peek(len(snappyMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(b, snappyMagic):
+		return snappy.NewReader(br), nil
+	case bytes.HasPrefix(b, gzipMagic):
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("\x0a\x10some snapshot entry"), 100)
+
+	for _, c := range []Compression{"", CompressionNone, CompressionGzip, CompressionSnappy} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, c)
+		if err != nil {
+			t.Fatalf("creating %q writer failed: %s", c, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("writing %q failed: %s", c, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("closing %q writer failed: %s", c, err)
+		}
+		if w.Written() != buf.Len() {
+			t.Errorf("%q: expected %d bytes written, got %d", c, buf.Len(), w.Written())
+		}
+		if (c == CompressionGzip || c == CompressionSnappy) && buf.Len() >= len(data) {
+			t.Errorf("expected %q to compress %d bytes, got %d", c, len(data), buf.Len())
+		}
+
+		r, err := NewReader(&buf)
+		if err != nil {
+			t.Fatalf("creating reader for %q failed: %s", c, err)
+		}
+		res, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("reading %q failed: %s", c, err)
+		}
+		if !bytes.Equal(data, res) {
+			t.Errorf("%q: read data does not match written data", c)
+		}
+	}
+
+	r, err := NewReader(bytes.NewReader(nil))
+	if err != nil {
+		t.Fatalf("creating reader for empty input failed: %s", err)
+	}
+	if res, _ := ioutil.ReadAll(r); len(res) != 0 {
+		t.Errorf("expected no data for empty input, got %q", res)
+	}
+
+	if _, err := NewWriter(ioutil.Discard, "lz4"); err == nil {
+		t.Errorf("expected error for unknown compression")
+	}
+	if _, err := ParseCompression("lz4"); err == nil {
+		t.Errorf("expected error parsing unknown compression")
+	}
+}
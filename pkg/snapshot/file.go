@@ -0,0 +1,111 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+// SyncPolicy controls when snapshot files are synced to stable storage.
+type SyncPolicy string
+
+// The supported sync policies.
+const (
+	// SyncAlways syncs the file after every write and its directory after
+	// it was moved into place.
+	SyncAlways SyncPolicy = "always"
+	// SyncOnClose syncs the file once it was written completely and its
+	// directory after it was moved into place.
+	SyncOnClose SyncPolicy = "on-close"
+	// SyncNever leaves syncing to the operating system. Snapshots may be
+	// lost or truncated on power loss.
+	SyncNever SyncPolicy = "never"
+)
+
+// ParseSyncPolicy returns the sync policy with the given name. The empty
+// string is equivalent to SyncOnClose.
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case "", SyncOnClose:
+		return SyncOnClose, nil
+	case SyncAlways, SyncNever:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown snapshot sync policy %q", s)
+}
+
+// File is a temporary file that is moved to another filename on closing.
+type File struct {
+	*os.File
+	filename string
+	sync     SyncPolicy
+}
+
+// OpenReplace opens a new temporary file that is moved to filename on
+// closing. It is synced according to the given policy.
+func OpenReplace(filename string, p SyncPolicy) (*File, error) {
+	if _, err := ParseSyncPolicy(string(p)); err != nil {
+		return nil, err
+	}
+	tmpFilename := fmt.Sprintf("%s.%x", filename, uint64(rand.Int63()))
+
+	f, err := os.Create(tmpFilename)
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: f, filename: filename, sync: p}, nil
+}
+
+func (f *File) Write(b []byte) (int, error) {
+	n, err := f.File.Write(b)
+	if err == nil && f.sync == SyncAlways {
+		err = f.File.Sync()
+	}
+	return n, err
+}
+
+// Close moves the file to its final filename.
+func (f *File) Close() error {
+	if f.sync != SyncNever {
+		if err := f.File.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.File.Name(), f.filename); err != nil {
+		return err
+	}
+	if f.sync == SyncNever {
+		return nil
+	}
+	// The rename is only durable once the directory is synced.
+	return syncDir(filepath.Dir(f.filename))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replace_file")
+	if err != nil {
+		t.Fatalf("creating temp dir failed: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	origFilename := filepath.Join(dir, "testfile")
+
+	for _, p := range []SyncPolicy{"", SyncAlways, SyncOnClose, SyncNever} {
+		if err := ioutil.WriteFile(origFilename, []byte("old"), 0644); err != nil {
+			t.Fatalf("creating file failed: %s", err)
+		}
+
+		f, err := OpenReplace(origFilename, p)
+		if err != nil {
+			t.Fatalf("%q: opening replacement file failed: %s", p, err)
+		}
+		if _, err := f.Write([]byte("test")); err != nil {
+			t.Fatalf("%q: writing replacement file failed: %s", p, err)
+		}
+		if f.Name() == origFilename {
+			t.Fatalf("%q: replacement file must have different name while editing", p)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("%q: closing replacement file failed: %s", p, err)
+		}
+
+		res, err := ioutil.ReadFile(origFilename)
+		if err != nil {
+			t.Fatalf("%q: reading original file failed: %s", p, err)
+		}
+		if string(res) != "test" {
+			t.Errorf("%q: unexpected file contents %q", p, res)
+		}
+	}
+
+	if _, err := OpenReplace(origFilename, "sometimes"); err == nil {
+		t.Errorf("expected error for unknown sync policy")
+	}
+}
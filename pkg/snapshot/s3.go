@@ -0,0 +1,179 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Store stores snapshots as objects in an S3 bucket. Objects are
+// addressed by path, so that bucket names need not be valid host names.
+type s3Store struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	region   string
+
+	accessKey    string
+	secretKey    string
+	sessionToken string
+
+	client *http.Client
+	now    func() time.Time
+}
+
+func newS3Store(u *url.URL) (*s3Store, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket in snapshot store URL %q", u)
+	}
+	s := &s3Store{
+		bucket:       u.Host,
+		prefix:       strings.Trim(u.Path, "/"),
+		region:       u.Query().Get("region"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: DefaultStoreTimeout},
+		now:          time.Now,
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.region)
+	}
+	var err error
+	if s.endpoint, err = url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint in snapshot store URL: %s", err)
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for S3 snapshot stores")
+	}
+	return s, nil
+}
+
+func (s *s3Store) request(method, name string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = path.Join("/", u.Path, s.bucket, s.prefix, name)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body, s.now().UTC())
+	return s.client.Do(req)
+}
+
+func (s *s3Store) Put(name string, b []byte) error {
+	resp, err := s.request("PUT", name, b)
+	if err != nil {
+		return err
+	}
+	return checkStoreResponse(resp, name)
+}
+
+func (s *s3Store) Get(name string) (io.ReadCloser, error) {
+	resp, err := s.request("GET", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return storeResponseBody(resp, name)
+}
+
+// sign adds an AWS Signature Version 4 to the request.
+func (s *s3Store) sign(req *http.Request, body []byte, now time.Time) {
+	var (
+		date        = now.Format("20060102")
+		amzDate     = now.Format("20060102T150405Z")
+		payloadHash = sha256Hex(body)
+		scope       = strings.Join([]string{date, s.region, "s3", "aws4_request"}, "/")
+	)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, vs := range req.Header {
+		if k := strings.ToLower(k); k == "content-type" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(vs, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, headers[k])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		strings.Replace(req.URL.Query().Encode(), "+", "%20", -1),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s.secretKey, date, s.region, "s3"), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+// signingKey derives the Signature Version 4 key for the date, region and
+// service.
+func signingKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,167 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store persists snapshots outside of the local data directory, e.g. in
+// object storage for deployments without durable local disks.
+type Store interface {
+	// Put stores the snapshot under the given name, replacing any previous
+	// snapshot of the same name.
+	Put(name string, b []byte) error
+	// Get returns the snapshot stored under the given name. It returns
+	// os.ErrNotExist if there is none.
+	Get(name string) (io.ReadCloser, error)
+}
+
+// DefaultStoreTimeout is the timeout of requests to remote stores.
+const DefaultStoreTimeout = time.Minute
+
+// NewStore returns the store for the URL.
+//
+// With file:///path/to/dir, snapshots are files in the directory, e.g. on
+// a network file system.
+//
+// With http:// and https:// URLs, snapshots are uploaded with PUT and
+// downloaded with GET requests below the URL. Query parameters are kept,
+// so that e.g. Azure Blob Storage containers can be used with a shared
+// access signature.
+//
+// With s3://bucket/prefix?region=eu-west-1&endpoint=https://host,
+// snapshots are objects in an S3 bucket. Requests are signed with the
+// credentials from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables. The endpoint defaults to the
+// one of AWS for the region. Other S3-compatible services, like Google
+// Cloud Storage with HMAC keys, can be used by setting it.
+func NewStore(rawurl string) (Store, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("missing directory in snapshot store URL %q", rawurl)
+		}
+		return dirStore(u.Path), nil
+	case "http", "https":
+		return &httpStore{
+			url:    u,
+			client: &http.Client{Timeout: DefaultStoreTimeout},
+		}, nil
+	case "s3":
+		return newS3Store(u)
+	}
+	return nil, fmt.Errorf("unsupported snapshot store URL %q", rawurl)
+}
+
+// dirStore stores snapshots as files in a directory.
+type dirStore string
+
+func (d dirStore) Put(name string, b []byte) error {
+	f, err := OpenReplace(filepath.Join(string(d), name), SyncOnClose)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.File.Close()
+		os.Remove(f.File.Name())
+		return err
+	}
+	return f.Close()
+}
+
+func (d dirStore) Get(name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(string(d), name))
+	if os.IsNotExist(err) {
+		return nil, os.ErrNotExist
+	}
+	return f, err
+}
+
+// httpStore stores snapshots below a URL with PUT requests.
+type httpStore struct {
+	url    *url.URL
+	client *http.Client
+}
+
+func (s *httpStore) objectURL(name string) string {
+	u := *s.url
+	u.Path = path.Join("/", u.Path, name)
+	return u.String()
+}
+
+func (s *httpStore) Put(name string, b []byte) error {
+	req, err := http.NewRequest("PUT", s.objectURL(name), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if strings.HasSuffix(s.url.Host, ".blob.core.windows.net") {
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return checkStoreResponse(resp, name)
+}
+
+func (s *httpStore) Get(name string) (io.ReadCloser, error) {
+	resp, err := s.client.Get(s.objectURL(name))
+	if err != nil {
+		return nil, err
+	}
+	return storeResponseBody(resp, name)
+}
+
+// checkStoreResponse closes the response and returns an error if its
+// status is not successful.
+func checkStoreResponse(resp *http.Response, name string) error {
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("storing snapshot %s: unexpected status %s: %s", name, resp.Status, bytes.TrimSpace(b))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// storeResponseBody returns the body of a successful response. Missing
+// snapshots are reported as os.ErrNotExist.
+func storeResponseBody(resp *http.Response, name string) (io.ReadCloser, error) {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, os.ErrNotExist
+	case resp.StatusCode/100 != 2:
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching snapshot %s: unexpected status %s: %s", name, resp.Status, bytes.TrimSpace(b))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testStoreRoundtrip checks that snapshots put into the store are returned
+// and that missing snapshots are reported as such.
+func testStoreRoundtrip(t *testing.T, s Store) {
+	if _, err := s.Get("nflog"); err != os.ErrNotExist {
+		t.Fatalf("expected os.ErrNotExist for missing snapshot, got %v", err)
+	}
+	for _, content := range []string{"first", "second"} {
+		if err := s.Put("nflog", []byte(content)); err != nil {
+			t.Fatalf("storing snapshot failed: %s", err)
+		}
+		r, err := s.Get("nflog")
+		if err != nil {
+			t.Fatalf("fetching snapshot failed: %s", err)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("reading snapshot failed: %s", err)
+		}
+		if string(b) != content {
+			t.Fatalf("expected snapshot %q, got %q", content, b)
+		}
+	}
+}
+
+// objectServer serves objects stored with PUT requests by their path.
+type objectServer struct {
+	mtx     sync.Mutex
+	objects map[string][]byte
+	check   func(*http.Request)
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.check != nil {
+		s.check(r)
+	}
+	switch r.Method {
+	case "PUT":
+		b, _ := ioutil.ReadAll(r.Body)
+		s.objects[r.URL.Path] = b
+	case "GET":
+		b, ok := s.objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestDirStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dir_store")
+	if err != nil {
+		t.Fatalf("creating temp dir failed: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewStore("file://" + dir)
+	if err != nil {
+		t.Fatalf("creating store failed: %s", err)
+	}
+	testStoreRoundtrip(t, s)
+}
+
+func TestHTTPStore(t *testing.T) {
+	srv := &objectServer{objects: map[string][]byte{}}
+	srv.check = func(r *http.Request) {
+		if r.URL.Query().Get("sig") != "secret" {
+			t.Errorf("expected query parameters to be kept, got %q", r.URL.RawQuery)
+		}
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	s, err := NewStore(ts.URL + "/container/prefix?sig=secret")
+	if err != nil {
+		t.Fatalf("creating store failed: %s", err)
+	}
+	testStoreRoundtrip(t, s)
+
+	if _, ok := srv.objects["/container/prefix/nflog"]; !ok {
+		t.Fatalf("expected snapshot below the URL path, got %v", srv.objects)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	for k, v := range map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		"AWS_SESSION_TOKEN":     "",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+	srv := &objectServer{objects: map[string][]byte{}}
+	srv.check = func(r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			t.Errorf("unexpected authorization header %q", auth)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if h := r.Header.Get("x-amz-content-sha256"); h != sha256Hex(b) {
+			t.Errorf("expected payload hash %s, got %s", sha256Hex(b), h)
+		}
+		r.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	s, err := NewStore("s3://bucket/prefix/?region=eu-west-1&endpoint=" + url.QueryEscape(ts.URL))
+	if err != nil {
+		t.Fatalf("creating store failed: %s", err)
+	}
+	testStoreRoundtrip(t, s)
+
+	if _, ok := srv.objects["/bucket/prefix/nflog"]; !ok {
+		t.Fatalf("expected path-style object, got %v", srv.objects)
+	}
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "")
+	if _, err := NewStore("s3://bucket"); err == nil {
+		t.Fatalf("expected error for missing credentials")
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation.
+	k := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+
+	exp := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(k); got != exp {
+		t.Fatalf("expected signing key %s, got %s", exp, got)
+	}
+}
+
+func TestNewStoreInvalid(t *testing.T) {
+	for _, u := range []string{"ftp://host/dir", "file://", "s3:///prefix"} {
+		if _, err := NewStore(u); err == nil {
+			t.Errorf("%q: expected error", u)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// maxBackfillResponseSize is the maximum number of bytes read from a
+// Prometheus API response.
+const maxBackfillResponseSize = 32 << 20
+
+// BackfillOptions configures fetching the alerts of Prometheus servers.
+type BackfillOptions struct {
+	// URLs of the Prometheus servers.
+	URLs []string
+	// Timeout of each request.
+	Timeout time.Duration
+	// ResolveTimeout after which the inserted alerts are resolved unless
+	// they are sent again.
+	ResolveTimeout time.Duration
+	Logger         log.Logger
+}
+
+// Backfill inserts the firing alerts of the Prometheus servers into the
+// alert store, e.g. to recover them after a restart before they are sent
+// again. The external labels of a server are added to its alerts like when
+// it sends them. Servers that cannot be queried are skipped. It returns
+// the number of inserted alerts.
+func Backfill(ap Alerts, o BackfillOptions) int {
+	logger := o.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	client := &http.Client{Timeout: o.Timeout}
+
+	var n int
+	for _, u := range o.URLs {
+		alerts, err := fetchAlerts(client, u, time.Now(), o.ResolveTimeout)
+		if err != nil {
+			level.Warn(logger).Log("msg", "Backfilling alerts failed", "url", u, "err", err)
+			continue
+		}
+		if err := ap.Put(alerts...); err != nil {
+			level.Warn(logger).Log("msg", "Inserting backfilled alerts failed", "url", u, "err", err)
+			continue
+		}
+		level.Info(logger).Log("msg", "Backfilled alerts", "url", u, "alerts", len(alerts))
+		n += len(alerts)
+	}
+	return n
+}
+
+// fetchAlerts returns the valid firing alerts of the Prometheus server.
+func fetchAlerts(client *http.Client, u string, now time.Time, resolveTimeout time.Duration) ([]*types.Alert, error) {
+	var res struct {
+		Alerts []struct {
+			Labels      model.LabelSet `json:"labels"`
+			Annotations model.LabelSet `json:"annotations"`
+			State       string         `json:"state"`
+			ActiveAt    time.Time      `json:"activeAt"`
+		} `json:"alerts"`
+	}
+	if err := getPrometheusAPI(client, u, "/api/v1/alerts", &res); err != nil {
+		return nil, err
+	}
+	external, err := fetchExternalLabels(client, u)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*types.Alert, 0, len(res.Alerts))
+	for _, pa := range res.Alerts {
+		if pa.State != "firing" {
+			continue
+		}
+		lset := pa.Labels.Clone()
+		if lset == nil {
+			lset = model.LabelSet{}
+		}
+		for ln, lv := range external {
+			if _, ok := lset[ln]; !ok {
+				lset[ln] = lv
+			}
+		}
+		a := &types.Alert{
+			Alert: model.Alert{
+				Labels:      lset,
+				Annotations: pa.Annotations,
+				StartsAt:    pa.ActiveAt,
+				EndsAt:      now.Add(resolveTimeout),
+			},
+			UpdatedAt: now,
+			Timeout:   true,
+		}
+		if a.StartsAt.IsZero() || a.StartsAt.After(now) {
+			a.StartsAt = now
+		}
+		if a.Validate() != nil {
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// fetchExternalLabels returns the external labels from the configuration
+// of the Prometheus server.
+func fetchExternalLabels(client *http.Client, u string) (model.LabelSet, error) {
+	var res struct {
+		YAML string `json:"yaml"`
+	}
+	if err := getPrometheusAPI(client, u, "/api/v1/status/config", &res); err != nil {
+		return nil, err
+	}
+	var conf struct {
+		Global struct {
+			ExternalLabels model.LabelSet `yaml:"external_labels"`
+		} `yaml:"global"`
+	}
+	if err := yaml.Unmarshal([]byte(res.YAML), &conf); err != nil {
+		return nil, fmt.Errorf("decoding configuration failed: %s", err)
+	}
+	return conf.Global.ExternalLabels, nil
+}
+
+// getPrometheusAPI decodes the data of a successful response of the
+// Prometheus API endpoint into v.
+func getPrometheusAPI(client *http.Client, u, endpoint string, v interface{}) error {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	pu.Path = path.Join(pu.Path, endpoint)
+
+	resp, err := client.Get(pu.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Status string          `json:"status"`
+		Data   json.RawMessage `json:"data"`
+		Error  string          `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBackfillResponseSize)).Decode(&res); err != nil {
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("%s: unexpected status code %d", endpoint, resp.StatusCode)
+		}
+		return fmt.Errorf("%s: decoding response failed: %s", endpoint, err)
+	}
+	if res.Status != "success" {
+		return fmt.Errorf("%s failed: %s", endpoint, res.Error)
+	}
+	if err := json.Unmarshal(res.Data, v); err != nil {
+		return fmt.Errorf("%s: decoding data failed: %s", endpoint, err)
+	}
+	return nil
+}
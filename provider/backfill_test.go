@@ -0,0 +1,80 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestBackfill(t *testing.T) {
+	activeAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prometheus/api/v1/alerts", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"status": "success", "data": {"alerts": [
+			{"labels": {"alertname": "Firing", "cluster": "override"}, "annotations": {"summary": "firing"}, "state": "firing", "activeAt": %q},
+			{"labels": {"alertname": "Pending"}, "state": "pending", "activeAt": %q}
+		]}}`, activeAt.Format(time.RFC3339), activeAt.Format(time.RFC3339))
+	})
+	mux.HandleFunc("/prometheus/api/v1/status/config", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status": "success", "data": {"yaml": "global:\n  external_labels:\n    cluster: eu\n    replica: a\n"}}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	alerts, err := mem.NewAlerts(types.NewMarker(), 30*time.Minute, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	n := provider.Backfill(alerts, provider.BackfillOptions{
+		URLs:           []string{failing.URL, srv.URL + "/prometheus"},
+		Timeout:        time.Second,
+		ResolveTimeout: 5 * time.Minute,
+	})
+	if n != 1 {
+		t.Fatalf("Expected 1 backfilled alert, got %d", n)
+	}
+
+	lset := model.LabelSet{"alertname": "Firing", "cluster": "override", "replica": "a"}
+	a, err := alerts.Get(lset.Fingerprint())
+	if err != nil {
+		t.Fatalf("Backfilled alert with external labels not found: %s", err)
+	}
+	if !a.StartsAt.Equal(activeAt) {
+		t.Errorf("Expected alert to start at %s, got %s", activeAt, a.StartsAt)
+	}
+	if a.Resolved() || !a.Timeout {
+		t.Errorf("Expected firing alert resolved after the resolve timeout, got %v", a)
+	}
+	if a.Annotations["summary"] != "firing" {
+		t.Errorf("Unexpected annotations %v", a.Annotations)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// LabelCardinality is the number of distinct values of a label among the
+// active alerts with the same alert name.
+type LabelCardinality struct {
+	AlertName string `json:"alertname"`
+	Label     string `json:"label"`
+	Values    int    `json:"values"`
+}
+
+type cardinalityKey struct {
+	alertName model.LabelValue
+	label     model.LabelName
+}
+
+// TopLabelCardinality returns the k alert name and label pairs with the most
+// distinct label values among the active alerts, highest cardinality first.
+// All pairs are returned if k is not positive.
+func TopLabelCardinality(alerts Alerts, k int) ([]LabelCardinality, error) {
+	it := alerts.GetPending()
+	defer it.Close()
+
+	values := map[cardinalityKey]map[model.LabelValue]struct{}{}
+	for a := range it.Next() {
+		if a.Resolved() {
+			continue
+		}
+		name := a.Labels[model.AlertNameLabel]
+		for ln, lv := range a.Labels {
+			if ln == model.AlertNameLabel {
+				continue
+			}
+			key := cardinalityKey{alertName: name, label: ln}
+			if values[key] == nil {
+				values[key] = map[model.LabelValue]struct{}{}
+			}
+			values[key][lv] = struct{}{}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make([]LabelCardinality, 0, len(values))
+	for key, vs := range values {
+		res = append(res, LabelCardinality{
+			AlertName: string(key.alertName),
+			Label:     string(key.label),
+			Values:    len(vs),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Values != res[j].Values {
+			return res[i].Values > res[j].Values
+		}
+		if res[i].AlertName != res[j].AlertName {
+			return res[i].AlertName < res[j].AlertName
+		}
+		return res[i].Label < res[j].Label
+	})
+	if k > 0 && len(res) > k {
+		res = res[:k]
+	}
+	return res, nil
+}
+
+type cardinalityCollector struct {
+	alerts Alerts
+	k      int
+	desc   *prometheus.Desc
+}
+
+// NewCardinalityCollector returns a collector exporting the k labels with
+// the highest cardinality as computed by TopLabelCardinality.
+func NewCardinalityCollector(alerts Alerts, k int) prometheus.Collector {
+	return &cardinalityCollector{
+		alerts: alerts,
+		k:      k,
+		desc: prometheus.NewDesc(
+			"alertmanager_alerts_label_cardinality",
+			"Number of distinct values of the labels with the highest cardinality among active alerts by alert name.",
+			[]string{"alertname", "label"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *cardinalityCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector.
+func (c *cardinalityCollector) Collect(ch chan<- prometheus.Metric) {
+	res, err := TopLabelCardinality(c.alerts, c.k)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for _, lc := range res {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(lc.Values), lc.AlertName, lc.Label)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// LabelCatalog records the label names of recently received alerts, so
+// that references to label names that never occur can be detected.
+type LabelCatalog struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mtx   sync.Mutex
+	names map[model.LabelName]time.Time
+}
+
+// NewLabelCatalog returns a LabelCatalog forgetting label names that were
+// not observed within the retention.
+func NewLabelCatalog(retention time.Duration) *LabelCatalog {
+	return &LabelCatalog{
+		retention: retention,
+		now:       time.Now,
+		names:     map[model.LabelName]time.Time{},
+	}
+}
+
+// Observe records the label names of the alerts.
+func (c *LabelCatalog) Observe(alerts ...*types.Alert) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := c.now()
+	for _, a := range alerts {
+		for ln := range a.Labels {
+			c.names[ln] = now
+		}
+	}
+	for ln, t := range c.names {
+		if now.Sub(t) > c.retention {
+			delete(c.names, ln)
+		}
+	}
+}
+
+// ObservePending records the label names of the alerts currently held by
+// the provider, such as the ones loaded on startup.
+func (c *LabelCatalog) ObservePending(alerts Alerts) error {
+	it := alerts.GetPending()
+	defer it.Close()
+
+	var res []*types.Alert
+	for a := range it.Next() {
+		res = append(res, a)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	c.Observe(res...)
+	return nil
+}
+
+// Contains returns whether the label name was observed within the
+// retention.
+func (c *LabelCatalog) Contains(ln model.LabelName) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t, ok := c.names[ln]
+	return ok && c.now().Sub(t) <= c.retention
+}
+
+// Retention returns how long label names are remembered.
+func (c *LabelCatalog) Retention() time.Duration {
+	return c.retention
+}
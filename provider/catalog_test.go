@@ -0,0 +1,55 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestLabelCatalog(t *testing.T) {
+	now := time.Now()
+	c := NewLabelCatalog(time.Hour)
+	c.now = func() time.Time { return now }
+
+	c.Observe(&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a", "severity": "critical"}}})
+	for _, ln := range []model.LabelName{"alertname", "severity"} {
+		if !c.Contains(ln) {
+			t.Errorf("expected label name %q to be observed", ln)
+		}
+	}
+	if c.Contains("sevrity") {
+		t.Errorf("unexpected label name %q", "sevrity")
+	}
+
+	// Label names expire unless they are observed again.
+	now = now.Add(50 * time.Minute)
+	c.Observe(&types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "b"}}})
+	now = now.Add(11 * time.Minute)
+	if c.Contains("severity") {
+		t.Errorf("expected label name %q to expire", "severity")
+	}
+	if !c.Contains("alertname") {
+		t.Errorf("expected label name %q to be observed", "alertname")
+	}
+
+	c.Observe()
+	if _, ok := c.names["severity"]; ok {
+		t.Errorf("expired label name was not removed")
+	}
+}
@@ -14,14 +14,40 @@
 package mem
 
 import (
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 )
 
+var (
+	alertBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "alerts_memory_bytes",
+		Help:      "The approximate size of the alerts held in memory.",
+	})
+	alertMemoryLimit = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "alerts_memory_limit_bytes",
+		Help:      "The size of alerts held in memory above which new alerts are refused. Zero if there is no limit.",
+	})
+	numRefusedAlerts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "alerts_refused_memory_limit_total",
+		Help:      "The total number of alerts refused as they would have exceeded the memory limit.",
+	})
+)
+
+func init() {
+	prometheus.Register(alertBytes)
+	prometheus.Register(alertMemoryLimit)
+	prometheus.Register(numRefusedAlerts)
+}
+
 // Alerts gives access to a set of alerts. All methods are goroutine-safe.
 type Alerts struct {
 	mtx        sync.RWMutex
@@ -32,6 +58,13 @@ type Alerts struct {
 
 	listeners map[int]chan *types.Alert
 	next      int
+	// The sequence number of the last stored alert.
+	seq uint64
+
+	// The approximate size of the stored alerts and the limit above which
+	// alerts are refused.
+	size  int64
+	limit int64
 }
 
 // NewAlerts returns a new alert provider.
@@ -66,6 +99,7 @@ func (a *Alerts) runGC() {
 			if alert.EndsAt.Before(time.Now()) {
 				delete(a.alerts, fp)
 				a.marker.Delete(fp)
+				a.grow(-alertSize(alert))
 			}
 		}
 
@@ -83,16 +117,21 @@ func (a *Alerts) Close() error {
 // resolved and successfully notified about.
 // They are not guaranteed to be in chronological order.
 func (a *Alerts) Subscribe() provider.AlertIterator {
+	return a.SubscribeFrom(0)
+}
+
+// SubscribeFrom works like Subscribe but skips alerts whose latest version
+// has a sequence number less than or equal to seq. Alerts that were garbage
+// collected in the meantime are not replayed.
+func (a *Alerts) SubscribeFrom(seq uint64) provider.AlertIterator {
 	var (
 		ch   = make(chan *types.Alert, 200)
 		done = make(chan struct{})
 	)
-	alerts, err := a.getPending()
 
 	a.mtx.Lock()
 	i := a.next
 	a.next++
-	a.listeners[i] = ch
 	a.mtx.Unlock()
 
 	go func() {
@@ -103,18 +142,49 @@ func (a *Alerts) Subscribe() provider.AlertIterator {
 			a.mtx.Unlock()
 		}()
 
-		for _, a := range alerts {
-			select {
-			case ch <- a:
-			case <-done:
-				return
+		// Replay stored alerts until we caught up. Only register as a listener
+		// once no newer alerts are left to ensure that an alert is never
+		// followed by an older version of itself.
+		for {
+			a.mtx.Lock()
+			alerts := a.since(seq)
+			if len(alerts) == 0 {
+				a.listeners[i] = ch
+				a.mtx.Unlock()
+				break
+			}
+			a.mtx.Unlock()
+
+			for _, a := range alerts {
+				select {
+				case ch <- a:
+				case <-done:
+					return
+				}
 			}
+			seq = alerts[len(alerts)-1].Seq
 		}
 
 		<-done
 	}()
 
-	return provider.NewAlertIterator(ch, done, err)
+	return provider.NewAlertIterator(ch, done, nil)
+}
+
+// since returns all alerts with a sequence number larger than seq, ordered
+// by their sequence number. The caller must hold the lock.
+func (a *Alerts) since(seq uint64) []*types.Alert {
+	var res []*types.Alert
+
+	for _, alert := range a.alerts {
+		if alert.Seq > seq {
+			res = append(res, alert)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Seq < res[j].Seq
+	})
+	return res
 }
 
 // GetPending returns an iterator over all alerts that have
@@ -167,21 +237,49 @@ func (a *Alerts) Get(fp model.Fingerprint) (*types.Alert, error) {
 	return alert, nil
 }
 
-// Put adds the given alert to the set.
+// Put adds the given alert to the set. Alerts growing the size of the set
+// beyond the memory limit are refused with a *provider.MemoryLimitError,
+// the others are stored nonetheless.
 func (a *Alerts) Put(alerts ...*types.Alert) error {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
+	var refused provider.MemoryLimitError
+
 	for _, alert := range alerts {
 		fp := alert.Fingerprint()
 
-		if old, ok := a.alerts[fp]; ok {
+		old, ok := a.alerts[fp]
+		if ok {
 			// Merge alerts if there is an overlap in activity range.
 			if (alert.EndsAt.After(old.StartsAt) && alert.EndsAt.Before(old.EndsAt)) ||
 				(alert.StartsAt.After(old.StartsAt) && alert.StartsAt.Before(old.EndsAt)) {
 				alert = old.Merge(alert)
 			}
+			// Annotations attached through the API are kept while the
+			// alert keeps firing.
+			if alert.Enrichment == nil && old.Enrichment != nil && !alert.StartsAt.After(old.EndsAt) {
+				alert = alert.WithEnrichment(old.Enrichment)
+			}
+		}
+		growth := alertSize(alert)
+		if ok {
+			growth -= alertSize(old)
+		}
+		// Updates not growing the set, such as resolved alerts, are always
+		// accepted so that the memory they hold is released eventually.
+		if a.limit > 0 && growth > 0 && a.size+growth > a.limit {
+			refused.Refused++
+			refused.Required += growth
+			continue
 		}
+		a.grow(growth)
+
+		// Number a copy so the caller's alert is not modified.
+		a.seq++
+		numbered := *alert
+		numbered.Seq = a.seq
+		alert = &numbered
 
 		a.alerts[fp] = alert
 
@@ -190,5 +288,59 @@ func (a *Alerts) Put(alerts ...*types.Alert) error {
 		}
 	}
 
+	if refused.Refused > 0 {
+		numRefusedAlerts.Add(float64(refused.Refused))
+		refused.Usage = provider.MemoryUsage{Used: a.size, Limit: a.limit}
+		return &refused
+	}
 	return nil
 }
+
+// SetMemoryLimit sets the approximate size in bytes of the stored alerts
+// above which new alerts are refused. Zero disables the limit.
+func (a *Alerts) SetMemoryLimit(limit int64) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.limit = limit
+	alertMemoryLimit.Set(float64(limit))
+}
+
+// MemoryUsage implements the provider.MemoryAccounter interface.
+func (a *Alerts) MemoryUsage() provider.MemoryUsage {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	return provider.MemoryUsage{Used: a.size, Limit: a.limit}
+}
+
+// grow accounts for the stored alerts growing by n bytes. The caller must
+// hold the lock.
+func (a *Alerts) grow(n int64) {
+	a.size += n
+	alertBytes.Add(float64(n))
+}
+
+const (
+	// Approximate memory held by an alert besides its strings, including
+	// its struct and its entry in the set.
+	alertOverhead = 256
+	// Approximate memory held by a label pair besides its strings.
+	labelOverhead = 48
+)
+
+// alertSize returns the approximate number of bytes an alert holds in
+// memory. It is meant for accounting rather than being exact.
+func alertSize(a *types.Alert) int64 {
+	n := int64(alertOverhead + len(a.GeneratorURL) + len(a.TraceParent))
+	var enriched model.LabelSet
+	if a.Enrichment != nil {
+		enriched = a.Enrichment.Annotations
+	}
+	for _, ls := range []model.LabelSet{a.Labels, a.Annotations, enriched} {
+		for ln, lv := range ls {
+			n += int64(len(ln)+len(lv)) + labelOverhead
+		}
+	}
+	return n
+}
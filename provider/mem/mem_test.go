@@ -175,6 +175,78 @@ func TestAlertsGC(t *testing.T) {
 	}
 }
 
+func TestAlertsSubscribeFrom(t *testing.T) {
+	marker := types.NewMarker()
+	alerts, err := NewAlerts(marker, 30*time.Minute, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	var (
+		t0 = time.Now()
+		t1 = t0.Add(10 * time.Minute)
+	)
+	newAlert := func(name string) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name)},
+				StartsAt: t0,
+				EndsAt:   t1,
+			},
+			UpdatedAt: t0,
+		}
+	}
+	next := func(it provider.AlertIterator) *types.Alert {
+		select {
+		case a := <-it.Next():
+			return a
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for alert")
+		}
+		return nil
+	}
+
+	if err := alerts.Put(newAlert("a"), newAlert("b"), newAlert("c")); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	// Updating an alert moves it to the end of the sequence.
+	if err := alerts.Put(newAlert("a")); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+
+	it := alerts.SubscribeFrom(0)
+	var names []model.LabelValue
+	for i := 0; i < 3; i++ {
+		names = append(names, next(it).Labels["alertname"])
+	}
+	if !reflect.DeepEqual(names, []model.LabelValue{"b", "c", "a"}) {
+		t.Fatalf("unexpected replay order %v", names)
+	}
+	it.Close()
+
+	// Resuming only yields updates after the given sequence number,
+	// followed by live updates.
+	b, err := alerts.Get(newAlert("b").Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	it = alerts.SubscribeFrom(b.Seq)
+	defer it.Close()
+
+	for _, exp := range []string{"c", "a"} {
+		if a := next(it); string(a.Labels["alertname"]) != exp {
+			t.Fatalf("expected alert %q but got %v", exp, a)
+		}
+	}
+	if err := alerts.Put(newAlert("d")); err != nil {
+		t.Fatalf("Insert failed: %s", err)
+	}
+	if a := next(it); a.Labels["alertname"] != "d" || a.Seq != 5 {
+		t.Fatalf("unexpected live update %v with sequence %d", a, a.Seq)
+	}
+}
+
 func alertsEqual(a1, a2 *types.Alert) bool {
 	if !reflect.DeepEqual(a1.Labels, a2.Labels) {
 		return false
@@ -208,3 +280,113 @@ func alertListEqual(a1, a2 []*types.Alert) bool {
 	}
 	return true
 }
+
+func TestTopLabelCardinality(t *testing.T) {
+	marker := types.NewMarker()
+	alerts, err := NewAlerts(marker, 30*time.Minute, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	now := time.Now()
+	newAlert := func(name, instance, job string, endsAt time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": model.LabelValue(name),
+					"instance":  model.LabelValue(instance),
+					"job":       model.LabelValue(job),
+				},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   endsAt,
+			},
+		}
+	}
+	err = alerts.Put(
+		newAlert("HighLatency", "a", "api", now.Add(time.Hour)),
+		newAlert("HighLatency", "b", "api", now.Add(time.Hour)),
+		newAlert("HighLatency", "c", "db", now.Add(time.Hour)),
+		newAlert("DiskFull", "a", "node", now.Add(time.Hour)),
+		// Resolved alerts are not counted.
+		newAlert("DiskFull", "b", "node", now.Add(-time.Minute)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := provider.TopLabelCardinality(alerts, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []provider.LabelCardinality{
+		{AlertName: "HighLatency", Label: "instance", Values: 3},
+		{AlertName: "HighLatency", Label: "job", Values: 2},
+		{AlertName: "DiskFull", Label: "instance", Values: 1},
+	}
+	if !reflect.DeepEqual(res, exp) {
+		t.Errorf("Unexpected cardinality:\n%s", pretty.Compare(exp, res))
+	}
+}
+
+func TestAlertsMemoryLimit(t *testing.T) {
+	alerts, err := NewAlerts(types.NewMarker(), 30*time.Minute, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alerts.Close()
+
+	now := time.Now()
+	newAlert := func(name string, endsAt time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"alertname": model.LabelValue(name)},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   endsAt,
+			},
+			// Firing alerts time out as if received through the API.
+			Timeout: endsAt.After(now),
+		}
+	}
+	size := alertSize(newAlert("a", now))
+	alerts.SetMemoryLimit(2 * size)
+
+	if err := alerts.Put(newAlert("a", now.Add(time.Hour)), newAlert("b", now.Add(time.Hour))); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if mu := alerts.MemoryUsage(); mu.Used != 2*size || mu.Limit != 2*size {
+		t.Fatalf("Unexpected memory usage %+v", mu)
+	}
+
+	// New alerts are refused while updates of stored ones are accepted.
+	err = alerts.Put(newAlert("c", now.Add(time.Hour)), newAlert("a", now.Add(-time.Minute)))
+	merr, ok := err.(*provider.MemoryLimitError)
+	if !ok {
+		t.Fatalf("Expected memory limit error but got %v", err)
+	}
+	if merr.Refused != 1 || merr.Required != size {
+		t.Fatalf("Unexpected memory limit error %+v", merr)
+	}
+	if _, err := alerts.Get(newAlert("c", now).Fingerprint()); err != provider.ErrNotFound {
+		t.Fatalf("Refused alert was stored")
+	}
+	a, err := alerts.Get(newAlert("a", now).Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Resolved() {
+		t.Fatalf("Update of stored alert was refused")
+	}
+	if mu := alerts.MemoryUsage(); mu.Used != 2*size {
+		t.Fatalf("Unexpected memory usage %+v", mu)
+	}
+
+	// Without a limit all alerts are accepted.
+	alerts.SetMemoryLimit(0)
+	if err := alerts.Put(newAlert("c", now.Add(time.Hour))); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if mu := alerts.MemoryUsage(); mu.Used != 3*size {
+		t.Fatalf("Unexpected memory usage %+v", mu)
+	}
+}
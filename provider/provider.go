@@ -26,6 +26,35 @@ var (
 	ErrNotFound = fmt.Errorf("item not found")
 )
 
+// MemoryLimitError is returned by providers refusing to store alerts as
+// they would exceed the memory budget. Other alerts passed along with the
+// refused ones were stored.
+type MemoryLimitError struct {
+	// Number of refused alerts.
+	Refused int
+	// Approximate number of bytes the refused alerts required.
+	Required int64
+	// Memory usage at the time the alerts were refused.
+	Usage MemoryUsage
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("%d alerts requiring %d bytes refused, %d of %d bytes of the alert memory budget are used", e.Refused, e.Required, e.Usage.Used, e.Usage.Limit)
+}
+
+// MemoryUsage is the approximate memory used by the alerts of a provider.
+type MemoryUsage struct {
+	Used int64 `json:"used"`
+	// Limit above which alerts are refused. Zero if there is none.
+	Limit int64 `json:"limit"`
+}
+
+// MemoryAccounter is implemented by providers accounting for the memory
+// used by their alerts.
+type MemoryAccounter interface {
+	MemoryUsage() MemoryUsage
+}
+
 // Iterator provides the functions common to all iterators. To be useful, a
 // specific iterator interface (e.g. AlertIterator) has to be implemented that
 // provides a Next method.
@@ -78,11 +107,17 @@ type Alerts interface {
 	// resolved and successfully notified about.
 	// They are not guaranteed to be in chronological order.
 	Subscribe() AlertIterator
+	// SubscribeFrom works like Subscribe but skips alerts whose latest
+	// version has a sequence number less than or equal to seq. Resuming
+	// with the sequence number of the last received alert thus yields all
+	// updates that were missed in between without resending known alerts.
+	SubscribeFrom(seq uint64) AlertIterator
 	// GetPending returns an iterator over all alerts that have
 	// pending notifications.
 	GetPending() AlertIterator
 	// Get returns the alert for a given fingerprint.
 	Get(model.Fingerprint) (*types.Alert, error)
-	// Put adds the given alert to the set.
+	// Put adds the given alert to the set. Every stored alert is assigned
+	// a sequence number larger than that of all previously stored ones.
 	Put(...*types.Alert) error
 }
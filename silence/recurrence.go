@@ -0,0 +1,90 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+const (
+	minutesPerDay = 24 * 60
+	// maxRecurrenceMinutes is the maximum length of recurrence windows.
+	maxRecurrenceMinutes = 7 * minutesPerDay
+)
+
+// locations caches the time zones of recurrences, as loading them reads
+// the time zone database.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func validateRecurrence(r *pb.Recurrence) error {
+	if r.Weekdays >= 1<<7 {
+		return fmt.Errorf("invalid weekdays %b", r.Weekdays)
+	}
+	if r.StartMinute >= minutesPerDay {
+		return fmt.Errorf("start minute %d is not within a day", r.StartMinute)
+	}
+	if r.DurationMinutes == 0 || r.DurationMinutes > maxRecurrenceMinutes {
+		return fmt.Errorf("duration of %d minutes must be positive and at most a week", r.DurationMinutes)
+	}
+	if _, err := loadLocation(r.Location); err != nil {
+		return errors.Wrap(err, "invalid location")
+	}
+	return nil
+}
+
+// recurrenceActive returns whether ts is within one of the windows of the
+// recurrence. Recurrences whose time zone is unknown are never active.
+func recurrenceActive(r *pb.Recurrence, ts time.Time) bool {
+	loc, err := loadLocation(r.Location)
+	if err != nil {
+		return false
+	}
+	ts = ts.In(loc)
+	d := time.Duration(r.DurationMinutes) * time.Minute
+
+	// Windows that started on one of the previous days may still last.
+	days := (int(r.DurationMinutes) + minutesPerDay - 1) / minutesPerDay
+	for i := 0; i <= days; i++ {
+		day := ts.AddDate(0, 0, -i)
+		if r.Weekdays != 0 && r.Weekdays&(1<<uint(day.Weekday())) == 0 {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, int(r.StartMinute), 0, 0, loc)
+		if !ts.Before(start) && ts.Before(start.Add(d)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+func TestRecurrenceActive(t *testing.T) {
+	// 2017-10-02 is a Monday.
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2017, 10, day, hour, min, 0, 0, time.UTC)
+	}
+	weekdays := func(wds ...time.Weekday) (res uint32) {
+		for _, wd := range wds {
+			res |= 1 << uint(wd)
+		}
+		return res
+	}
+
+	cases := []struct {
+		r      *pb.Recurrence
+		ts     time.Time
+		active bool
+	}{
+		// Daily window from 02:00 to 04:00.
+		{
+			r:      &pb.Recurrence{StartMinute: 120, DurationMinutes: 120},
+			ts:     at(2, 1, 59),
+			active: false,
+		}, {
+			r:      &pb.Recurrence{StartMinute: 120, DurationMinutes: 120},
+			ts:     at(2, 2, 0),
+			active: true,
+		}, {
+			r:      &pb.Recurrence{StartMinute: 120, DurationMinutes: 120},
+			ts:     at(5, 3, 59),
+			active: true,
+		}, {
+			r:      &pb.Recurrence{StartMinute: 120, DurationMinutes: 120},
+			ts:     at(5, 4, 0),
+			active: false,
+		},
+		// Windows on Mondays and Wednesdays only.
+		{
+			r:      &pb.Recurrence{Weekdays: weekdays(time.Monday, time.Wednesday), StartMinute: 120, DurationMinutes: 120},
+			ts:     at(3, 3, 0),
+			active: false,
+		}, {
+			r:      &pb.Recurrence{Weekdays: weekdays(time.Monday, time.Wednesday), StartMinute: 120, DurationMinutes: 120},
+			ts:     at(4, 3, 0),
+			active: true,
+		},
+		// Windows lasting into the next day belong to the day they start.
+		{
+			r:      &pb.Recurrence{Weekdays: weekdays(time.Friday), StartMinute: 22 * 60, DurationMinutes: 3 * 60},
+			ts:     at(7, 0, 30),
+			active: true,
+		}, {
+			r:      &pb.Recurrence{Weekdays: weekdays(time.Friday), StartMinute: 22 * 60, DurationMinutes: 3 * 60},
+			ts:     at(6, 0, 30),
+			active: false,
+		},
+		// Windows across the weekend.
+		{
+			r:      &pb.Recurrence{Weekdays: weekdays(time.Friday), StartMinute: 18 * 60, DurationMinutes: 62 * 60},
+			ts:     at(9, 7, 59),
+			active: true,
+		}, {
+			r:      &pb.Recurrence{Weekdays: weekdays(time.Friday), StartMinute: 18 * 60, DurationMinutes: 62 * 60},
+			ts:     at(9, 8, 0),
+			active: false,
+		},
+		// Start times are in the time zone of the recurrence.
+		{
+			r:      &pb.Recurrence{StartMinute: 120, DurationMinutes: 60, Location: "Europe/Berlin"},
+			ts:     at(2, 0, 30),
+			active: true,
+		}, {
+			r:      &pb.Recurrence{StartMinute: 120, DurationMinutes: 60, Location: "Europe/Berlin"},
+			ts:     at(2, 2, 30),
+			active: false,
+		},
+		// Unknown time zones are never active.
+		{
+			r:      &pb.Recurrence{StartMinute: 0, DurationMinutes: 7 * 24 * 60, Location: "Nowhere/Atlantis"},
+			ts:     at(2, 0, 30),
+			active: false,
+		},
+	}
+	for i, c := range cases {
+		require.Equal(t, c.active, recurrenceActive(c.r, c.ts), "case %d: %v at %s", i, c.r, c.ts)
+	}
+}
+
+func TestValidateRecurrence(t *testing.T) {
+	cases := []struct {
+		r   *pb.Recurrence
+		err string
+	}{
+		{
+			r: &pb.Recurrence{Weekdays: 1<<7 - 1, StartMinute: 23*60 + 59, DurationMinutes: 7 * 24 * 60, Location: "Europe/Berlin"},
+		}, {
+			r:   &pb.Recurrence{Weekdays: 1 << 7, DurationMinutes: 60},
+			err: "invalid weekdays",
+		}, {
+			r:   &pb.Recurrence{StartMinute: 24 * 60, DurationMinutes: 60},
+			err: "not within a day",
+		}, {
+			r:   &pb.Recurrence{},
+			err: "must be positive",
+		}, {
+			r:   &pb.Recurrence{DurationMinutes: 7*24*60 + 1},
+			err: "at most a week",
+		}, {
+			r:   &pb.Recurrence{DurationMinutes: 60, Location: "Nowhere/Atlantis"},
+			err: "invalid location",
+		},
+	}
+	for _, c := range cases {
+		err := validateRecurrence(c.r)
+		if c.err == "" {
+			require.NoError(t, err)
+			continue
+		}
+		require.Error(t, err)
+		require.Contains(t, err.Error(), c.err)
+	}
+}
+
+func TestRecurringSilenceState(t *testing.T) {
+	now := time.Date(2017, 10, 2, 12, 0, 0, 0, time.UTC)
+	sil := &pb.Silence{
+		StartsAt: now.Add(-24 * time.Hour),
+		EndsAt:   now.Add(24 * time.Hour),
+		Recurrence: &pb.Recurrence{
+			StartMinute:     11 * 60,
+			DurationMinutes: 120,
+		},
+	}
+
+	require.Equal(t, StateActive, getState(sil, now))
+	require.Equal(t, SilenceState(StatePending), getState(sil, now.Add(2*time.Hour)))
+	require.Equal(t, SilenceState(StatePending), getState(sil, now.Add(-24*time.Hour-time.Minute)))
+	require.Equal(t, SilenceState(StateExpired), getState(sil, now.Add(25*time.Hour)))
+
+	// Silences between windows are active with respect to their time range,
+	// so they can only be updated as active silences.
+	later := now.Add(2 * time.Hour)
+	upd := cloneSilence(sil)
+	upd.Recurrence.DurationMinutes = 180
+	require.False(t, canUpdate(sil, upd, later))
+
+	upd = cloneSilence(sil)
+	upd.EndsAt = later
+	require.True(t, canUpdate(sil, upd, later))
+	require.Equal(t, uint32(120), sil.Recurrence.DurationMinutes)
+}
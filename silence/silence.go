@@ -16,10 +16,10 @@
 package silence
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"reflect"
 	"regexp"
@@ -30,6 +30,7 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/matttproud/golang_protobuf_extensions/pbutil"
 	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/pkg/snapshot"
 	pb "github.com/prometheus/alertmanager/silence/silencepb"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,9 +39,22 @@ import (
 	"github.com/weaveworks/mesh"
 )
 
+// GossipVersion is the version of the state schema shared with peers. It
+// is incremented on changes that peers of the previous version cannot
+// merge.
+const GossipVersion = 1
+
 // ErrNotFound is returned if a silence was not found.
 var ErrNotFound = fmt.Errorf("not found")
 
+// ErrConflict is returned if a silence was modified since it was last read.
+var ErrConflict = fmt.Errorf("silence was modified concurrently")
+
+// ErrUnsupportedMatcher is returned if a silence contains a matcher type
+// unknown to this version. Such silences are retained and gossiped as they
+// are but never mute any alerts.
+var ErrUnsupportedMatcher = fmt.Errorf("unsupported matcher type")
+
 func utcNow() time.Time {
 	return time.Now().UTC()
 }
@@ -72,9 +86,17 @@ func (c matcherCache) add(s *pb.Silence) (types.Matchers, error) {
 		}
 		switch m.Type {
 		case pb.Matcher_EQUAL:
-			mt.IsRegex = false
 		case pb.Matcher_REGEXP:
 			mt.IsRegex = true
+		case pb.Matcher_NOT_EQUAL:
+			mt.IsNegative = true
+		case pb.Matcher_NOT_REGEXP:
+			mt.IsRegex = true
+			mt.IsNegative = true
+		default:
+			// Do not cache the result so that the silence is not
+			// mistaken for one without matchers.
+			return nil, ErrUnsupportedMatcher
 		}
 		err := mt.Init()
 		if err != nil {
@@ -96,6 +118,12 @@ type Silences struct {
 	now       func() time.Time
 	retention time.Duration
 
+	compression snapshot.Compression
+	codec       snapshot.Codec
+	sync        snapshot.SyncPolicy
+	store       snapshot.Store
+	storeName   string
+
 	gossip mesh.Gossip // gossip channel for sharing silences
 
 	// We store silences in a map of IDs for now. Currently, the memory
@@ -106,17 +134,22 @@ type Silences struct {
 	mtx sync.Mutex
 	st  *gossipData
 	mc  matcherCache
+	// Fingerprints of the alerts each silence muted on this instance.
+	muted map[string]map[model.Fingerprint]struct{}
 }
 
 type metrics struct {
 	gcDuration       prometheus.Summary
 	snapshotDuration prometheus.Summary
+	invalidTotal     prometheus.Counter
+	repairedTotal    prometheus.Counter
 	queriesTotal     prometheus.Counter
 	queryErrorsTotal prometheus.Counter
 	queryDuration    prometheus.Histogram
 	silencesActive   prometheus.GaugeFunc
 	silencesPending  prometheus.GaugeFunc
 	silencesExpired  prometheus.GaugeFunc
+	efficacy         prometheus.Collector
 }
 
 func newSilenceMetricByState(s *Silences, st SilenceState) prometheus.GaugeFunc {
@@ -147,6 +180,14 @@ func newMetrics(r prometheus.Registerer, s *Silences) *metrics {
 		Name: "alertmanager_silences_snapshot_duration_seconds",
 		Help: "Duration of the last silence snapshot.",
 	})
+	m.invalidTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_silences_gossip_invalid_total",
+		Help: "Number of silences received from peers that were dropped as invalid.",
+	})
+	m.repairedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_silences_gossip_repaired_total",
+		Help: "Number of silences updated by repair requests to peers.",
+	})
 	m.queriesTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "alertmanager_silences_queries_total",
 		Help: "How many silence queries were received.",
@@ -163,11 +204,14 @@ func newMetrics(r prometheus.Registerer, s *Silences) *metrics {
 		m.silencesActive = newSilenceMetricByState(s, StateActive)
 		m.silencesPending = newSilenceMetricByState(s, StatePending)
 		m.silencesExpired = newSilenceMetricByState(s, StateExpired)
+		m.efficacy = &efficacyCollector{s: s}
 	}
 
 	if r != nil {
 		r.MustRegister(
 			m.gcDuration,
+			m.invalidTotal,
+			m.repairedTotal,
 			m.snapshotDuration,
 			m.queriesTotal,
 			m.queryErrorsTotal,
@@ -175,11 +219,56 @@ func newMetrics(r prometheus.Registerer, s *Silences) *metrics {
 			m.silencesActive,
 			m.silencesPending,
 			m.silencesExpired,
+			m.efficacy,
 		)
 	}
 	return m
 }
 
+var (
+	mutedAlertsDesc = prometheus.NewDesc(
+		"alertmanager_silence_muted_alerts",
+		"Number of distinct alerts muted by a silence on this instance.",
+		[]string{"silence_id", "created_by"}, nil,
+	)
+	unusedSilencesDesc = prometheus.NewDesc(
+		"alertmanager_silences_unused",
+		"Number of expired silences that did not mute any alert on this instance.",
+		nil, nil,
+	)
+)
+
+// efficacyCollector exposes how many alerts silences muted, to find
+// silences that are useless or overly broad.
+type efficacyCollector struct {
+	s *Silences
+}
+
+// Describe implements prometheus.Collector.
+func (c *efficacyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mutedAlertsDesc
+	ch <- unusedSilencesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *efficacyCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.s
+	now := s.now()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var unused int
+	for id, sil := range s.st.data {
+		n := len(s.muted[id])
+		if n == 0 && getState(sil.Silence, now) == StateExpired {
+			unused++
+		}
+		ch <- prometheus.MustNewConstMetric(mutedAlertsDesc, prometheus.GaugeValue, float64(n), id, sil.Silence.CreatedBy)
+	}
+	ch <- prometheus.MustNewConstMetric(unusedSilencesDesc, prometheus.GaugeValue, float64(unused))
+}
+
 // Options exposes configuration options for creating a new Silences object.
 // Its zero value is a safe default.
 type Options struct {
@@ -188,6 +277,23 @@ type Options struct {
 	SnapshotFile   string
 	SnapshotReader io.Reader
 
+	// Compression of written snapshots. The compression of loaded
+	// snapshots is detected automatically.
+	SnapshotCompression snapshot.Compression
+	// Codec the silences of written snapshots are encoded with. It
+	// defaults to snapshot.ProtoCodec. The codec of loaded snapshots is
+	// detected automatically.
+	SnapshotCodec snapshot.Codec
+	// When written snapshots are synced to stable storage. It defaults to
+	// snapshot.SyncOnClose.
+	SnapshotSync snapshot.SyncPolicy
+	// A store snapshots are uploaded to in addition to the snapshot file.
+	// The initial state is loaded from it if there is no snapshot file.
+	// Snapshots are stored under SnapshotStoreName, which defaults to
+	// "silences".
+	SnapshotStore     snapshot.Store
+	SnapshotStoreName string
+
 	// Retention time for newly created Silences. Silences may be
 	// garbage collected after the given duration after they ended.
 	Retention time.Duration
@@ -204,6 +310,12 @@ func (o *Options) validate() error {
 	if o.SnapshotFile != "" && o.SnapshotReader != nil {
 		return fmt.Errorf("only one of SnapshotFile and SnapshotReader must be set")
 	}
+	if _, err := snapshot.ParseCompression(string(o.SnapshotCompression)); err != nil {
+		return err
+	}
+	if _, err := snapshot.ParseSyncPolicy(string(o.SnapshotSync)); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -222,12 +334,21 @@ func New(o Options) (*Silences, error) {
 		}
 	}
 	s := &Silences{
-		mc:        matcherCache{},
-		logger:    log.NewNopLogger(),
-		retention: o.Retention,
-		now:       utcNow,
-		gossip:    nopGossip{},
-		st:        newGossipData(),
+		mc:          matcherCache{},
+		muted:       map[string]map[model.Fingerprint]struct{}{},
+		logger:      log.NewNopLogger(),
+		retention:   o.Retention,
+		compression: o.SnapshotCompression,
+		codec:       o.SnapshotCodec,
+		sync:        o.SnapshotSync,
+		store:       o.SnapshotStore,
+		storeName:   o.SnapshotStoreName,
+		now:         utcNow,
+		gossip:      nopGossip{},
+		st:          newGossipData(),
+	}
+	if s.storeName == "" {
+		s.storeName = "silences"
 	}
 	s.metrics = newMetrics(o.Metrics, s)
 
@@ -237,6 +358,17 @@ func New(o Options) (*Silences, error) {
 	if o.Gossip != nil {
 		s.gossip = o.Gossip(gossiper{s})
 	}
+	if o.SnapshotReader == nil && s.store != nil {
+		r, err := s.store.Get(s.storeName)
+		if err != nil && err != os.ErrNotExist {
+			return s, fmt.Errorf("fetching snapshot from store: %s", err)
+		}
+		if err == nil {
+			defer r.Close()
+			o.SnapshotReader = r
+			level.Info(s.logger).Log("msg", "Loading snapshot from store", "name", s.storeName)
+		}
+	}
 	if o.SnapshotReader != nil {
 		if err := s.loadSnapshot(o.SnapshotReader); err != nil {
 			return s, err
@@ -251,7 +383,7 @@ func (nopGossip) GossipBroadcast(d mesh.GossipData)         {}
 func (nopGossip) GossipUnicast(mesh.PeerName, []byte) error { return nil }
 
 // Maintenance garbage collects the silence state at the given interval. If the snapshot
-// file is set, a snapshot is written to it afterwards.
+// file or store is set, a snapshot is written to it afterwards.
 // Terminates on receiving from stopc.
 func (s *Silences) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
 	t := time.NewTicker(interval)
@@ -265,18 +397,10 @@ func (s *Silences) Maintenance(interval time.Duration, snapf string, stopc <-cha
 		if _, err := s.GC(); err != nil {
 			return err
 		}
-		if snapf == "" {
+		if snapf == "" && s.store == nil {
 			return nil
 		}
-		f, err := openReplace(snapf)
-		if err != nil {
-			return err
-		}
-		// TODO(fabxc): potentially expose snapshot size in log message.
-		if _, err := s.Snapshot(f); err != nil {
-			return err
-		}
-		return f.Close()
+		return s.SnapshotFile(snapf)
 	}
 
 Loop:
@@ -291,7 +415,7 @@ Loop:
 		}
 	}
 	// No need for final maintenance if we don't want to snapshot.
-	if snapf == "" {
+	if snapf == "" && s.store == nil {
 		return
 	}
 	if err := f(); err != nil {
@@ -299,6 +423,49 @@ Loop:
 	}
 }
 
+// SnapshotFile replaces the file with a snapshot of the current state. If
+// a snapshot store is configured, the snapshot is uploaded to it as well.
+// The file is skipped if snapf is empty.
+func (s *Silences) SnapshotFile(snapf string) error {
+	var (
+		f   *snapshot.File
+		w   io.Writer
+		buf bytes.Buffer
+		err error
+	)
+	if snapf != "" {
+		if f, err = snapshot.OpenReplace(snapf, s.sync); err != nil {
+			return err
+		}
+		w = f
+	}
+	if s.store != nil {
+		if w == nil {
+			w = &buf
+		} else {
+			w = io.MultiWriter(f, &buf)
+		}
+	}
+	if w == nil {
+		return nil
+	}
+	// TODO(fabxc): potentially expose snapshot size in log message.
+	if _, err := s.Snapshot(w); err != nil {
+		return err
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if s.store != nil {
+		if err := s.store.Put(s.storeName, buf.Bytes()); err != nil {
+			return fmt.Errorf("uploading snapshot: %s", err)
+		}
+	}
+	return nil
+}
+
 // GC runs a garbage collection that removes silences that have ended longer
 // than the configured retention time ago.
 func (s *Silences) GC() (int, error) {
@@ -318,6 +485,7 @@ func (s *Silences) GC() (int, error) {
 		if !sil.ExpiresAt.After(now) {
 			delete(s.st.data, id)
 			delete(s.mc, sil.Silence)
+			delete(s.muted, id)
 			n++
 		}
 	}
@@ -326,15 +494,15 @@ func (s *Silences) GC() (int, error) {
 }
 
 func validateMatcher(m *pb.Matcher) error {
-	if !model.LabelName(m.Name).IsValid() {
+	if !types.IsValidLabelName(model.LabelName(m.Name)) {
 		return fmt.Errorf("invalid label name %q", m.Name)
 	}
 	switch m.Type {
-	case pb.Matcher_EQUAL:
+	case pb.Matcher_EQUAL, pb.Matcher_NOT_EQUAL:
 		if !model.LabelValue(m.Pattern).IsValid() {
 			return fmt.Errorf("invalid label value %q", m.Pattern)
 		}
-	case pb.Matcher_REGEXP:
+	case pb.Matcher_REGEXP, pb.Matcher_NOT_REGEXP:
 		if _, err := regexp.Compile(m.Pattern); err != nil {
 			return fmt.Errorf("invalid regular expression %q: %s", m.Pattern, err)
 		}
@@ -368,12 +536,21 @@ func validateSilence(s *pb.Silence) error {
 	if s.UpdatedAt.IsZero() {
 		return errors.New("invalid zero update timestamp")
 	}
+	if s.Recurrence != nil {
+		if err := validateRecurrence(s.Recurrence); err != nil {
+			return fmt.Errorf("invalid recurrence: %s", err)
+		}
+	}
 	return nil
 }
 
 // cloneSilence returns a shallow copy of a silence.
 func cloneSilence(sil *pb.Silence) *pb.Silence {
 	s := *sil
+	if s.Recurrence != nil {
+		r := *s.Recurrence
+		s.Recurrence = &r
+	}
 	return &s
 }
 
@@ -408,6 +585,49 @@ func (s *Silences) setSilence(sil *pb.Silence) error {
 	return nil
 }
 
+// Merge merges the given silences into the state like silences received
+// from a peer and shares the ones that were newer with the peers. Silences
+// that already expired are skipped. It returns the number of merged silences.
+func (s *Silences) Merge(sils ...*pb.MeshSilence) (int, error) {
+	gd := newGossipData()
+	for _, sil := range sils {
+		if sil.Silence == nil {
+			return 0, errors.New("silence missing")
+		}
+		if err := validateSilence(sil.Silence); err != nil {
+			return 0, errors.Wrapf(err, "silence %q invalid", sil.Silence.Id)
+		}
+		gd.data[sil.Silence.Id] = sil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	delta := s.st.mergeDelta(gd)
+	if len(delta.data) > 0 {
+		go s.gossip.GossipBroadcast(delta)
+	}
+	return len(delta.data), nil
+}
+
+// MeshSilences returns all silences along with the times they expire at.
+func (s *Silences) MeshSilences() []*pb.MeshSilence {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.st.mtx.RLock()
+	defer s.st.mtx.RUnlock()
+
+	res := make([]*pb.MeshSilence, 0, len(s.st.data))
+	for _, sil := range s.st.data {
+		res = append(res, &pb.MeshSilence{
+			Silence:   cloneSilence(sil.Silence),
+			ExpiresAt: sil.ExpiresAt,
+		})
+	}
+	return res
+}
+
 // Set the specified silence. If a silence with the ID already exists and the modification
 // modifies history, the old silence gets expired and a new one is created.
 func (s *Silences) Set(sil *pb.Silence) (string, error) {
@@ -421,7 +641,9 @@ func (s *Silences) Set(sil *pb.Silence) (string, error) {
 		return "", ErrNotFound
 	}
 	if ok {
+		sil.Team = prev.Team
 		if canUpdate(prev, sil, now) {
+			sil.Principal, sil.Source = prev.Principal, prev.Source
 			return sil.Id, s.setSilence(sil)
 		}
 		if getState(prev, s.now()) != StateExpired {
@@ -448,11 +670,15 @@ func canUpdate(a, b *pb.Silence, now time.Time) bool {
 		return false
 	}
 	// Allowed timestamp modifications depend on the current time.
-	switch st := getState(a, now); st {
+	switch st := rangeState(a, now); st {
 	case StateActive:
 		if !b.StartsAt.Equal(a.StartsAt) {
 			return false
 		}
+		// Changing the schedule would change when the silence was active.
+		if !reflect.DeepEqual(a.Recurrence, b.Recurrence) {
+			return false
+		}
 		if b.EndsAt.Before(now) {
 			return false
 		}
@@ -484,7 +710,7 @@ func (s *Silences) expire(id string) error {
 	sil = cloneSilence(sil)
 	now := s.now()
 
-	switch getState(sil, now) {
+	switch rangeState(sil, now) {
 	case StateExpired:
 		return errors.Errorf("silence %s already expired", id)
 	case StateActive:
@@ -498,6 +724,96 @@ func (s *Silences) expire(id string) error {
 	return s.setSilence(sil)
 }
 
+// DurationLimit limits how long silences may last.
+type DurationLimit struct {
+	// Max is the maximum duration of a silence. Silences that started
+	// already may last it from now on. Zero means no limit.
+	Max time.Duration
+	// Clamp shortens silences to the maximum duration instead of
+	// rejecting them.
+	Clamp bool
+}
+
+// Extend postpones the end of the active or pending silence with the given
+// ID by d and returns the updated silence. If updatedAt is not zero, the
+// silence is only modified if it was last updated at that time. Otherwise
+// ErrConflict is returned. The extended silence must not last longer than
+// the limit allows, which is checked against the current state of the
+// silence.
+func (s *Silences) Extend(id string, d time.Duration, updatedAt time.Time, limit DurationLimit) (*pb.Silence, error) {
+	if d <= 0 {
+		return nil, errors.New("extension must be positive")
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sil, ok := s.getSilence(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !updatedAt.IsZero() && !sil.UpdatedAt.Equal(updatedAt) {
+		return nil, ErrConflict
+	}
+	now := s.now()
+	if getState(sil, now) == StateExpired {
+		return nil, errors.Errorf("silence %s already expired", id)
+	}
+	endsAt := sil.EndsAt.Add(d)
+	if limit.Max > 0 {
+		latest := sil.StartsAt
+		if latest.Before(now) {
+			latest = now
+		}
+		latest = latest.Add(limit.Max)
+		if endsAt.After(latest) {
+			if !limit.Clamp {
+				return nil, errors.Errorf("silence must not last longer than %s", model.Duration(limit.Max))
+			}
+			if !latest.After(sil.EndsAt) {
+				return nil, errors.Errorf("silence %s already lasts the maximum duration of %s", id, model.Duration(limit.Max))
+			}
+			endsAt = latest
+		}
+	}
+	sil = cloneSilence(sil)
+	sil.EndsAt = endsAt
+
+	if err := s.setSilence(sil); err != nil {
+		return nil, err
+	}
+	return cloneSilence(sil), nil
+}
+
+// Transfer hands the active or pending silence with the given ID over to
+// team and returns the updated silence. If updatedAt is not zero, the
+// silence is only modified if it was last updated at that time. Otherwise
+// ErrConflict is returned.
+func (s *Silences) Transfer(id, team string, updatedAt time.Time) (*pb.Silence, error) {
+	if team == "" {
+		return nil, errors.New("team missing")
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	sil, ok := s.getSilence(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !updatedAt.IsZero() && !sil.UpdatedAt.Equal(updatedAt) {
+		return nil, ErrConflict
+	}
+	if getState(sil, s.now()) == StateExpired {
+		return nil, errors.Errorf("silence %s already expired", id)
+	}
+	sil = cloneSilence(sil)
+	sil.Team = team
+
+	if err := s.setSilence(sil); err != nil {
+		return nil, err
+	}
+	return cloneSilence(sil), nil
+}
+
 // QueryParam expresses parameters along which silences are queried.
 type QueryParam func(*query) error
 
@@ -529,11 +845,25 @@ func QTimeRange(start, end time.Time) QueryParam {
 	}
 }
 
+// QTeam returns silences owned by the given team.
+func QTeam(team string) QueryParam {
+	return func(q *query) error {
+		f := func(sil *pb.Silence, _ *Silences, _ time.Time) (bool, error) {
+			return sil.Team == team, nil
+		}
+		q.filters = append(q.filters, f)
+		return nil
+	}
+}
+
 // QMatches returns silences that match the given label set.
 func QMatches(set model.LabelSet) QueryParam {
 	return func(q *query) error {
 		f := func(sil *pb.Silence, s *Silences, _ time.Time) (bool, error) {
 			m, err := s.mc.Get(sil)
+			if err == ErrUnsupportedMatcher {
+				return false, nil
+			}
 			if err != nil {
 				return true, err
 			}
@@ -554,8 +884,24 @@ const (
 	StateExpired              = "expired"
 )
 
+// State returns a silence's SilenceState at the given timestamp. Recurring
+// silences are pending between the windows of their schedule.
+func State(sil *pb.Silence, ts time.Time) SilenceState {
+	return getState(sil, ts)
+}
+
 // getState returns a silence's SilenceState at the given timestamp.
 func getState(sil *pb.Silence, ts time.Time) SilenceState {
+	st := rangeState(sil, ts)
+	if st == StateActive && sil.Recurrence != nil && !recurrenceActive(sil.Recurrence, ts) {
+		return StatePending
+	}
+	return st
+}
+
+// rangeState returns a silence's SilenceState at the given timestamp with
+// respect to its time range only.
+func rangeState(sil *pb.Silence, ts time.Time) SilenceState {
 	if ts.Before(sil.StartsAt) {
 		return StatePending
 	}
@@ -617,6 +963,25 @@ func (s *Silences) Query(params ...QueryParam) ([]*pb.Silence, error) {
 	return sils, err
 }
 
+// ObserveMuted records that the alert with the given fingerprint was muted
+// by the silences with the given IDs.
+func (s *Silences) ObserveMuted(fp model.Fingerprint, ids ...string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, id := range ids {
+		if _, ok := s.st.data[id]; !ok {
+			continue
+		}
+		m, ok := s.muted[id]
+		if !ok {
+			m = map[model.Fingerprint]struct{}{}
+			s.muted[id] = m
+		}
+		m[fp] = struct{}{}
+	}
+}
+
 // Count silences by state.
 func (s *Silences) CountState(states ...SilenceState) (int, error) {
 	// This could probably be optimized.
@@ -669,21 +1034,49 @@ func (s *Silences) query(q *query, now time.Time) ([]*pb.Silence, error) {
 	return resf, nil
 }
 
+// Snapshots start with snapshotMagic followed by the uvarint encoded format
+// version. The silences are followed by an empty silence and a checksum
+// trailer of the silences, which detects truncated snapshots. Snapshots
+// written before the header was introduced start with the first silence
+// directly and have no checksum. Their first bytes are a length prefix and
+// the tag of a silence field, which never match the magic.
+var snapshotMagic = []byte("ASIL")
+
+// snapshotVersion is the version of the snapshot format written.
+const snapshotVersion = 1
+
 // loadSnapshot loads a snapshot generated by Snapshot() into the state.
-// Any previous state is wiped.
 func (s *Silences) loadSnapshot(r io.Reader) error {
 	st := newGossipData()
 
+	r, err := snapshot.NewReader(r)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+
+	codec, err := readSnapshotHeader(br)
+	if err != nil {
+		return err
+	}
+	dec := codec.NewDecoder(br)
+
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
 	for {
 		var sil pb.MeshSilence
-		if _, err := pbutil.ReadDelimited(r, &sil); err != nil {
+		if _, err := dec.Decode(&sil); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return err
+			if _, ok := err.(*snapshot.EntryError); ok {
+				return err
+			}
+			return errors.Wrap(err, "snapshot corrupt")
+		}
+		if sil.Silence == nil {
+			return errors.New("snapshot corrupt: missing silence")
 		}
 		// Comments list was moved to a single comment. Upgrade on loading the snapshot.
 		if len(sil.Silence.Comments) > 0 {
@@ -693,8 +1086,8 @@ func (s *Silences) loadSnapshot(r io.Reader) error {
 		}
 
 		st.data[sil.Silence.Id] = &sil
-		_, err := s.mc.Get(sil.Silence)
-		if err != nil {
+		_, err = s.mc.Get(sil.Silence)
+		if err != nil && err != ErrUnsupportedMatcher {
 			return err
 		}
 	}
@@ -704,6 +1097,22 @@ func (s *Silences) loadSnapshot(r io.Reader) error {
 	return nil
 }
 
+// readSnapshotHeader consumes the snapshot header and returns the codec of
+// the silences.
+func readSnapshotHeader(br *bufio.Reader) (snapshot.Codec, error) {
+	h, ok, err := snapshot.ReadHeader(br, snapshotMagic)
+	if err != nil {
+		return nil, errors.Wrap(err, "snapshot corrupt")
+	}
+	if !ok {
+		return snapshot.ProtoCodec{Unchecksummed: true}, nil
+	}
+	if h.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", h.Version, snapshotVersion)
+	}
+	return h.Codec, nil
+}
+
 // Snapshot writes the full internal state into the writer and returns the number of bytes
 // written.
 func (s *Silences) Snapshot(w io.Writer) (int, error) {
@@ -713,15 +1122,28 @@ func (s *Silences) Snapshot(w io.Writer) (int, error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	var n int
+	sw, err := snapshot.NewWriter(w, s.compression)
+	if err != nil {
+		return 0, err
+	}
+	codec := s.codec
+	if codec == nil {
+		codec = snapshot.ProtoCodec{}
+	}
+	if err := snapshot.WriteHeader(sw, snapshotMagic, snapshotVersion, codec); err != nil {
+		return sw.Written(), err
+	}
+	enc := codec.NewEncoder(sw)
 	for _, s := range s.st.data {
-		m, err := pbutil.WriteDelimited(w, s)
-		if err != nil {
-			return n + m, err
+		if err := enc.Encode(s); err != nil {
+			return sw.Written(), err
 		}
-		n += m
 	}
-	return n, nil
+	if err := enc.Close(); err != nil {
+		return sw.Written(), err
+	}
+	err = sw.Close()
+	return sw.Written(), err
 }
 
 type gossiper struct {
@@ -736,9 +1158,20 @@ func (g gossiper) Gossip() mesh.GossipData {
 	return g.st.clone()
 }
 
+// decode decodes gossip data received from a peer. Invalid silences are
+// dropped.
+func (g gossiper) decode(msg []byte) (*gossipData, error) {
+	gd, invalid, err := decodeGossipData(msg)
+	if invalid > 0 {
+		level.Warn(g.logger).Log("msg", "Dropping invalid silences received from peer", "count", invalid)
+		g.metrics.invalidTotal.Add(float64(invalid))
+	}
+	return gd, err
+}
+
 // OnGossip implements the mesh.Gossiper interface.
 func (g gossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
-	gd, err := decodeGossipData(msg)
+	gd, err := g.decode(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -753,7 +1186,7 @@ func (g gossiper) OnGossip(msg []byte) (mesh.GossipData, error) {
 
 // OnGossipBroadcast implements the mesh.Gossiper interface.
 func (g gossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipData, error) {
-	gd, err := decodeGossipData(msg)
+	gd, err := g.decode(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -763,10 +1196,116 @@ func (g gossiper) OnGossipBroadcast(src mesh.PeerName, msg []byte) (mesh.GossipD
 	return g.st.mergeDelta(gd), nil
 }
 
-// OnGossipUnicast implements the mesh.Gossiper interface.
-// It always panics.
+// Types of unicast messages. Repair requests hold silences that only
+// consist of their ID and the time they were last updated at on the
+// requester. Responses hold the requested silences like other gossip.
+const (
+	// unicastRepairIDs requests newer versions of the given silences.
+	unicastRepairIDs byte = iota + 1
+	// unicastRepairDigest additionally requests all silences missing from
+	// the given ones, which are the complete state of the requester.
+	unicastRepairDigest
+	unicastRepairResponse
+)
+
+// Repair requests silences from the peer. If IDs are given, only these
+// silences are requested. Otherwise the peer is sent a digest of all
+// silences and replies with the ones missing or outdated locally. The
+// silences are merged once they are received.
+func (s *Silences) Repair(peer mesh.PeerName, ids ...string) error {
+	var (
+		typ   = unicastRepairIDs
+		stubs []*pb.MeshSilence
+	)
+	stub := func(id string, updatedAt time.Time) *pb.MeshSilence {
+		return &pb.MeshSilence{Silence: &pb.Silence{Id: id, UpdatedAt: updatedAt}}
+	}
+
+	s.mtx.Lock()
+	s.st.mtx.RLock()
+	if len(ids) == 0 {
+		typ = unicastRepairDigest
+		for id, sil := range s.st.data {
+			stubs = append(stubs, stub(id, sil.Silence.UpdatedAt))
+		}
+	}
+	for _, id := range ids {
+		var updatedAt time.Time
+		if sil, ok := s.st.data[id]; ok {
+			updatedAt = sil.Silence.UpdatedAt
+		}
+		stubs = append(stubs, stub(id, updatedAt))
+	}
+	s.st.mtx.RUnlock()
+	s.mtx.Unlock()
+
+	// Unlike responses, requests are not split as a digest must describe
+	// the complete state.
+	buf := bytes.NewBuffer([]byte{typ})
+	for _, sil := range stubs {
+		if _, err := pbutil.WriteDelimited(buf, sil); err != nil {
+			return err
+		}
+	}
+	return s.gossip.GossipUnicast(peer, buf.Bytes())
+}
+
+// OnGossipUnicast implements the mesh.Gossiper interface. It answers
+// repair requests and merges the responses to them.
 func (g gossiper) OnGossipUnicast(src mesh.PeerName, msg []byte) error {
-	panic("not implemented")
+	if len(msg) == 0 {
+		return errors.New("empty unicast message")
+	}
+	typ, msg := msg[0], msg[1:]
+
+	gd, err := g.decode(msg)
+	if err != nil {
+		return err
+	}
+	switch typ {
+	case unicastRepairIDs, unicastRepairDigest:
+		for _, b := range g.repairSilences(gd, typ == unicastRepairDigest).Encode() {
+			if err := g.gossip.GossipUnicast(src, append([]byte{unicastRepairResponse}, b...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case unicastRepairResponse:
+		g.mtx.Lock()
+		delta := g.st.mergeDelta(gd)
+		g.mtx.Unlock()
+
+		g.metrics.repairedTotal.Add(float64(len(delta.data)))
+		return nil
+	}
+	return fmt.Errorf("unknown unicast message type %d", typ)
+}
+
+// repairSilences returns the silences that were updated after the
+// requested ones. If all is set, silences not requested are returned as
+// well.
+func (g gossiper) repairSilences(req *gossipData, all bool) *gossipData {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.st.mtx.RLock()
+	defer g.st.mtx.RUnlock()
+
+	res := newGossipData()
+	for id, r := range req.data {
+		if sil, ok := g.st.data[id]; ok && sil.Silence.UpdatedAt.After(r.Silence.UpdatedAt) {
+			res.data[id] = sil
+		}
+	}
+	if !all {
+		return res
+	}
+	for id, sil := range g.st.data {
+		if _, ok := req.data[id]; !ok {
+			res.data[id] = sil
+		}
+	}
+	return res
 }
 
 type silenceMap map[string]*pb.MeshSilence
@@ -784,21 +1323,29 @@ func newGossipData() *gossipData {
 	}
 }
 
-func decodeGossipData(msg []byte) (*gossipData, error) {
-	gd := newGossipData()
-	rd := bytes.NewReader(msg)
-
+// decodeGossipData decodes the silences of msg. It skips entries without a
+// silence and returns their number.
+func decodeGossipData(msg []byte) (*gossipData, int, error) {
+	var (
+		gd      = newGossipData()
+		rd      = bytes.NewReader(msg)
+		invalid int
+	)
 	for {
 		var s pb.MeshSilence
 		if _, err := pbutil.ReadDelimited(rd, &s); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return gd, err
+			return gd, invalid, err
+		}
+		if s.Silence == nil {
+			invalid++
+			continue
 		}
 		gd.data[s.Silence.Id] = &s
 	}
-	return gd, nil
+	return gd, invalid, nil
 }
 
 // Encode implements the mesh.GossipData interface.
@@ -854,6 +1401,11 @@ func (gd *gossipData) Merge(other mesh.GossipData) mesh.GossipData {
 	defer gd.mtx.Unlock()
 
 	for id, s := range ot.data {
+		// Invalid silences are dropped on decoding. Skip any others rather
+		// than crashing on them.
+		if s.Silence == nil {
+			continue
+		}
 		// Comments list was moved to a single comment. Apply upgrade
 		// on silences received from peers.
 		if len(s.Silence.Comments) > 0 {
@@ -897,6 +1449,10 @@ func (gd *gossipData) mergeDelta(od *gossipData) *gossipData {
 		if !s.ExpiresAt.After(utcNow()) {
 			continue
 		}
+		// See Merge.
+		if s.Silence == nil {
+			continue
+		}
 
 		// Comments list was moved to a single comment. Apply upgrade
 		// on silences received from peers.
@@ -919,35 +1475,3 @@ func (gd *gossipData) mergeDelta(od *gossipData) *gossipData {
 	}
 	return delta
 }
-
-// replaceFile wraps a file that is moved to another filename on closing.
-type replaceFile struct {
-	*os.File
-	filename string
-}
-
-func (f *replaceFile) Close() error {
-	if err := f.File.Sync(); err != nil {
-		return err
-	}
-	if err := f.File.Close(); err != nil {
-		return err
-	}
-	return os.Rename(f.File.Name(), f.filename)
-}
-
-// openReplace opens a new temporary file that is moved to filename on closing.
-func openReplace(filename string) (*replaceFile, error) {
-	tmpFilename := fmt.Sprintf("%s.%x", filename, uint64(rand.Int63()))
-
-	f, err := os.Create(tmpFilename)
-	if err != nil {
-		return nil, err
-	}
-
-	rf := &replaceFile{
-		File:     f,
-		filename: filename,
-	}
-	return rf, nil
-}
@@ -17,13 +17,18 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+	"github.com/prometheus/alertmanager/pkg/snapshot"
 	pb "github.com/prometheus/alertmanager/silence/silencepb"
 	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/mesh"
@@ -134,35 +139,242 @@ func TestSilencesSnapshot(t *testing.T) {
 					},
 					ExpiresAt: now.Add(24 * time.Hour),
 				},
+				{
+					// Silences with matcher types unknown to this version
+					// are retained as they are.
+					Silence: &pb.Silence{
+						Id: "d1ae3a1c-9bf8-4a6c-93e4-4c1fa6bb43a6",
+						Matchers: []*pb.Matcher{
+							{Name: "label1", Pattern: "val1", Type: pb.Matcher_NOT_EQUAL},
+							{Name: "label2", Pattern: "val2", Type: 333},
+						},
+						StartsAt:  now,
+						EndsAt:    now.Add(time.Hour),
+						UpdatedAt: now,
+					},
+					ExpiresAt: now.Add(24 * time.Hour),
+				},
 			},
 		},
 	}
 
 	for _, c := range cases {
-		f, err := ioutil.TempFile("", "snapshot")
-		require.NoError(t, err, "creating temp file failed")
+		for _, comp := range []snapshot.Compression{snapshot.CompressionNone, snapshot.CompressionGzip, snapshot.CompressionSnappy} {
+			f, err := ioutil.TempFile("", "snapshot")
+			require.NoError(t, err, "creating temp file failed")
+
+			s1 := &Silences{st: newGossipData(), metrics: newMetrics(nil, nil), compression: comp}
+			// Setup internal state manually.
+			for _, e := range c.entries {
+				s1.st.data[e.Silence.Id] = e
+			}
+			_, err = s1.Snapshot(f)
+			require.NoError(t, err, "creating snapshot failed")
 
-		s1 := &Silences{st: newGossipData(), metrics: newMetrics(nil, nil)}
-		// Setup internal state manually.
-		for _, e := range c.entries {
-			s1.st.data[e.Silence.Id] = e
+			require.NoError(t, f.Close(), "closing snapshot file failed")
+
+			f, err = os.Open(f.Name())
+			require.NoError(t, err, "opening snapshot file failed")
+
+			// Check again against new nlog instance.
+			s2 := &Silences{mc: matcherCache{}, st: newGossipData()}
+			err = s2.loadSnapshot(f)
+			require.NoError(t, err, "error loading %s snapshot", comp)
+			require.Equal(t, s1.st.data, s2.st.data, "state after loading %s snapshot did not match snapshotted state", comp)
+
+			require.NoError(t, f.Close(), "closing snapshot file failed")
 		}
-		_, err = s1.Snapshot(f)
-		require.NoError(t, err, "creating snapshot failed")
+	}
+}
+
+func TestSilencesSnapshotStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silences_store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := snapshot.NewStore("file://" + dir)
+	require.NoError(t, err)
+
+	s1, err := New(Options{SnapshotStore: store})
+	require.NoError(t, err)
+	id, err := s1.Set(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt: utcNow(),
+		EndsAt:   utcNow().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// Without a snapshot file, the snapshot is only uploaded.
+	require.NoError(t, s1.SnapshotFile(""))
+	_, err = os.Stat(filepath.Join(dir, "silences"))
+	require.NoError(t, err, "snapshot was not stored under the default name")
+
+	s2, err := New(Options{
+		SnapshotFile:  filepath.Join(dir, "missing"),
+		SnapshotStore: store,
+	})
+	require.NoError(t, err)
+	sil, err := s2.QueryOne(QIDs(id))
+	require.NoError(t, err)
+	require.Equal(t, id, sil.Id)
+}
+
+func TestSilencesSnapshotChecksum(t *testing.T) {
+	now := utcNow()
+	sil := &pb.MeshSilence{
+		Silence: &pb.Silence{
+			Id:        "3be80475-e219-4ee7-b6fc-4b65114e362f",
+			Matchers:  []*pb.Matcher{{Name: "label1", Pattern: "val1", Type: pb.Matcher_EQUAL}},
+			StartsAt:  now,
+			EndsAt:    now,
+			UpdatedAt: now,
+		},
+		ExpiresAt: now,
+	}
+	s1 := &Silences{st: newGossipData(), metrics: newMetrics(nil, nil)}
+	s1.st.data[sil.Silence.Id] = sil
 
-		require.NoError(t, f.Close(), "closing snapshot file failed")
+	var buf bytes.Buffer
+	_, err := s1.Snapshot(&buf)
+	require.NoError(t, err)
+	snap := buf.Bytes()
+	require.True(t, bytes.HasPrefix(snap, []byte("ASIL\x01")), "snapshot does not start with header")
+
+	// Snapshots written before the header was introduced are still read.
+	var legacy bytes.Buffer
+	_, err = pbutil.WriteDelimited(&legacy, sil)
+	require.NoError(t, err)
 
-		f, err = os.Open(f.Name())
-		require.NoError(t, err, "opening snapshot file failed")
+	s2 := &Silences{mc: matcherCache{}, st: newGossipData()}
+	require.NoError(t, s2.loadSnapshot(&legacy))
+	require.Equal(t, s1.st.data, s2.st.data)
 
-		// Check again against new nlog instance.
+	for name, data := range map[string][]byte{
+		"truncated":           snap[:len(snap)-snapshot.TrailerSize-1],
+		"truncated checksum":  snap[:len(snap)-1],
+		"checksum mismatch":   bytes.Replace(snap, []byte("val1"), []byte("val2"), 1),
+		"unsupported version": append([]byte("ASIL\x02"), snap[5:]...),
+	} {
 		s2 := &Silences{mc: matcherCache{}, st: newGossipData()}
-		err = s2.loadSnapshot(f)
-		require.NoError(t, err, "error loading snapshot")
-		require.Equal(t, s1.st.data, s2.st.data, "state after loading snapshot did not match snapshotted state")
+		require.Error(t, s2.loadSnapshot(bytes.NewReader(data)), name)
+		require.Empty(t, s2.st.data, "%s: state modified on failure", name)
+	}
+
+	// JSON snapshots are loaded regardless of the configured codec.
+	s1.codec = snapshot.JSONCodec{}
+	buf.Reset()
+	_, err = s1.Snapshot(&buf)
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("ASIL json 1\n")), "snapshot does not start with JSON header")
+
+	s2 = &Silences{mc: matcherCache{}, st: newGossipData()}
+	require.NoError(t, s2.loadSnapshot(&buf))
+	require.Equal(t, s1.st.data, s2.st.data)
+}
+
+// unicastGossip delivers unicast messages directly to the gossipers of
+// the destination peers.
+type unicastGossip struct {
+	self  mesh.PeerName
+	peers map[mesh.PeerName]mesh.Gossiper
+}
+
+func (g *unicastGossip) GossipBroadcast(mesh.GossipData) {}
+
+func (g *unicastGossip) GossipUnicast(dst mesh.PeerName, msg []byte) error {
+	return g.peers[dst].OnGossipUnicast(g.self, msg)
+}
+
+func TestSilencesRepair(t *testing.T) {
+	var (
+		peers  = map[mesh.PeerName]mesh.Gossiper{}
+		now    = utcNow()
+		create = func(name mesh.PeerName, ids map[string]time.Time) *Silences {
+			s, err := New(Options{
+				Retention: time.Hour,
+				Gossip: func(g mesh.Gossiper) mesh.Gossip {
+					peers[name] = g
+					return &unicastGossip{self: name, peers: peers}
+				},
+			})
+			require.NoError(t, err)
+			for id, updatedAt := range ids {
+				s.st.data[id] = &pb.MeshSilence{
+					Silence: &pb.Silence{
+						Id:        id,
+						Matchers:  []*pb.Matcher{{Name: "a", Pattern: "b"}},
+						StartsAt:  now,
+						EndsAt:    now.Add(time.Hour),
+						UpdatedAt: updatedAt,
+					},
+					ExpiresAt: now.Add(2 * time.Hour),
+				}
+			}
+			return s
+		}
+		a = create(1, map[string]time.Time{"s1": now, "local": now})
+		b = create(2, map[string]time.Time{"s1": now.Add(time.Minute), "s2": now, "s3": now})
+	)
+	updatedAt := func(s *Silences, id string) time.Time {
+		sil, ok := s.st.data[id]
+		if !ok {
+			return time.Time{}
+		}
+		return sil.Silence.UpdatedAt
+	}
+
+	// Only the requested silences are sent.
+	require.NoError(t, a.Repair(2, "s2", "missing"))
+	require.Equal(t, now, updatedAt(a, "s2"))
+	require.Equal(t, now, updatedAt(a, "s1"))
+	require.True(t, updatedAt(a, "s3").IsZero())
+
+	// With a digest all missing and outdated silences are sent.
+	require.NoError(t, a.Repair(2))
+	require.Equal(t, now.Add(time.Minute), updatedAt(a, "s1"))
+	require.Equal(t, now, updatedAt(a, "s3"))
+
+	// The peer does not receive the silences of the requester.
+	require.True(t, updatedAt(b, "local").IsZero())
+
+	var m dto.Metric
+	require.NoError(t, a.metrics.repairedTotal.Write(&m))
+	require.Equal(t, 3.0, m.GetCounter().GetValue())
+
+	require.Error(t, gossiper{a}.OnGossipUnicast(2, []byte{0xff}))
+}
+
+func TestInvalidGossipDoesNotCrash(t *testing.T) {
+	s, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
 
-		require.NoError(t, f.Close(), "closing snapshot file failed")
+	now := utcNow()
+	valid := &pb.MeshSilence{
+		Silence: &pb.Silence{
+			Id:        "valid",
+			Matchers:  []*pb.Matcher{{Name: "a", Pattern: "b"}},
+			StartsAt:  now,
+			EndsAt:    now.Add(time.Hour),
+			UpdatedAt: now,
+		},
+		ExpiresAt: now.Add(2 * time.Hour),
+	}
+	var buf bytes.Buffer
+	for _, sil := range []*pb.MeshSilence{{ExpiresAt: now.Add(time.Hour)}, valid} {
+		_, err := pbutil.WriteDelimited(&buf, sil)
+		require.NoError(t, err)
 	}
+
+	delta, err := gossiper{s}.OnGossipBroadcast(mesh.UnknownPeerName, buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, delta.(*gossipData).data, 1)
+
+	var m dto.Metric
+	require.NoError(t, s.metrics.invalidTotal.Write(&m))
+	require.Equal(t, float64(1), m.GetCounter().GetValue())
+
+	_, err = s.QueryOne(QIDs("valid"))
+	require.NoError(t, err)
 }
 
 type mockGossip struct {
@@ -523,6 +735,48 @@ func TestQMatches(t *testing.T) {
 			},
 			drop: false,
 		},
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "method", Pattern: "POST", Type: pb.Matcher_NOT_EQUAL},
+				},
+			},
+			drop: true,
+		},
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "job", Pattern: "test", Type: pb.Matcher_NOT_EQUAL},
+				},
+			},
+			drop: false,
+		},
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "path", Pattern: "/nothing/.+", Type: pb.Matcher_NOT_REGEXP},
+				},
+			},
+			drop: true,
+		},
+		{
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "path", Pattern: "/user/.+", Type: pb.Matcher_NOT_REGEXP},
+				},
+			},
+			drop: false,
+		},
+		{
+			// Silences with unknown matcher types never match.
+			sil: &pb.Silence{
+				Matchers: []*pb.Matcher{
+					{Name: "job", Pattern: "test", Type: pb.Matcher_EQUAL},
+					{Name: "job", Pattern: "test", Type: 333},
+				},
+			},
+			drop: false,
+		},
 	}
 	for _, c := range cases {
 		drop, err := f(c.sil, &Silences{mc: matcherCache{}, st: newGossipData()}, time.Time{})
@@ -829,6 +1083,153 @@ func TestSilenceExpire(t *testing.T) {
 	}, sil)
 }
 
+func TestSilenceExtend(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	m := &pb.Matcher{Type: pb.Matcher_EQUAL, Name: "a", Pattern: "b"}
+
+	s.st = &gossipData{
+		data: silenceMap{
+			"active": &pb.MeshSilence{Silence: &pb.Silence{
+				Id:        "active",
+				Matchers:  []*pb.Matcher{m},
+				StartsAt:  now.Add(-time.Minute),
+				EndsAt:    now.Add(time.Hour),
+				UpdatedAt: now.Add(-time.Hour),
+			}},
+			"expired": &pb.MeshSilence{Silence: &pb.Silence{
+				Id:        "expired",
+				Matchers:  []*pb.Matcher{m},
+				StartsAt:  now.Add(-time.Hour),
+				EndsAt:    now.Add(-time.Minute),
+				UpdatedAt: now.Add(-time.Hour),
+			}},
+		},
+	}
+
+	_, err = s.Extend("active", 0, time.Time{}, DurationLimit{})
+	require.Error(t, err)
+
+	_, err = s.Extend("missing", time.Hour, time.Time{}, DurationLimit{})
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = s.Extend("active", time.Hour, now, DurationLimit{})
+	require.Equal(t, ErrConflict, err)
+
+	_, err = s.Extend("expired", time.Hour, time.Time{}, DurationLimit{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already expired")
+
+	sil, err := s.Extend("active", time.Hour, now.Add(-time.Hour), DurationLimit{})
+	require.NoError(t, err)
+
+	exp := &pb.Silence{
+		Id:        "active",
+		Matchers:  []*pb.Matcher{m},
+		StartsAt:  now.Add(-time.Minute),
+		EndsAt:    now.Add(2 * time.Hour),
+		UpdatedAt: now,
+	}
+	require.Equal(t, exp, sil)
+
+	sil, err = s.QueryOne(QIDs("active"))
+	require.NoError(t, err)
+	require.Equal(t, exp, sil)
+
+	// The previous modification time no longer matches.
+	_, err = s.Extend("active", time.Hour, now.Add(-time.Hour), DurationLimit{})
+	require.Equal(t, ErrConflict, err)
+
+	// Silences that started already may last the maximum duration from
+	// now on. Modifications must be newer to replace the silence.
+	now = now.Add(time.Minute)
+	_, err = s.Extend("active", 2*time.Hour, time.Time{}, DurationLimit{Max: 3 * time.Hour})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must not last longer than 3h")
+
+	sil, err = s.Extend("active", 2*time.Hour, time.Time{}, DurationLimit{Max: 3 * time.Hour, Clamp: true})
+	require.NoError(t, err)
+	require.Equal(t, now.Add(3*time.Hour), sil.EndsAt)
+
+	_, err = s.Extend("active", time.Hour, time.Time{}, DurationLimit{Max: 3 * time.Hour, Clamp: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already lasts the maximum duration of 3h")
+}
+
+func TestSilenceTransfer(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	m := &pb.Matcher{Type: pb.Matcher_EQUAL, Name: "a", Pattern: "b"}
+
+	s.st = &gossipData{
+		data: silenceMap{
+			"active": &pb.MeshSilence{Silence: &pb.Silence{
+				Id:        "active",
+				Matchers:  []*pb.Matcher{m},
+				StartsAt:  now.Add(-time.Minute),
+				EndsAt:    now.Add(time.Hour),
+				UpdatedAt: now.Add(-time.Hour),
+				Team:      "a",
+			}},
+			"expired": &pb.MeshSilence{Silence: &pb.Silence{
+				Id:        "expired",
+				Matchers:  []*pb.Matcher{m},
+				StartsAt:  now.Add(-time.Hour),
+				EndsAt:    now.Add(-time.Minute),
+				UpdatedAt: now.Add(-time.Hour),
+				Team:      "a",
+			}},
+		},
+	}
+
+	_, err = s.Transfer("active", "", time.Time{})
+	require.EqualError(t, err, "team missing")
+
+	_, err = s.Transfer("missing", "b", time.Time{})
+	require.Equal(t, ErrNotFound, err)
+
+	_, err = s.Transfer("active", "b", now)
+	require.Equal(t, ErrConflict, err)
+
+	_, err = s.Transfer("expired", "b", time.Time{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already expired")
+
+	sil, err := s.Transfer("active", "b", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "b", sil.Team)
+	require.Equal(t, now, sil.UpdatedAt)
+
+	sils, err := s.Query(QTeam("b"))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, "active", sils[0].Id)
+
+	sils, err = s.Query(QTeam("a"))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, "expired", sils[0].Id)
+
+	// Updates cannot change the owning team.
+	upd := cloneSilence(sil)
+	upd.EndsAt = now.Add(2 * time.Hour)
+	upd.Team = "c"
+	_, err = s.Set(upd)
+	require.NoError(t, err)
+
+	sil, err = s.QueryOne(QIDs("active"))
+	require.NoError(t, err)
+	require.Equal(t, "b", sil.Team)
+}
+
 func TestValidateMatcher(t *testing.T) {
 	cases := []struct {
 		m   *pb.Matcher
@@ -862,6 +1263,20 @@ func TestValidateMatcher(t *testing.T) {
 				Type:    pb.Matcher_EQUAL,
 			},
 			err: "invalid label value",
+		}, {
+			m: &pb.Matcher{
+				Name:    "a",
+				Pattern: "b",
+				Type:    pb.Matcher_NOT_EQUAL,
+			},
+			err: "",
+		}, {
+			m: &pb.Matcher{
+				Name:    "a",
+				Pattern: "((",
+				Type:    pb.Matcher_NOT_REGEXP,
+			},
+			err: "invalid regular expression",
 		}, {
 			m: &pb.Matcher{
 				Name:    "a",
@@ -1174,6 +1589,21 @@ func TestGossipDataCoding(t *testing.T) {
 					},
 					ExpiresAt: now.Add(24 * time.Hour),
 				},
+				{
+					// Silences with matcher types unknown to this version
+					// are retained as they are.
+					Silence: &pb.Silence{
+						Id: "d1ae3a1c-9bf8-4a6c-93e4-4c1fa6bb43a6",
+						Matchers: []*pb.Matcher{
+							{Name: "label1", Pattern: "val1", Type: pb.Matcher_NOT_EQUAL},
+							{Name: "label2", Pattern: "val2", Type: 333},
+						},
+						StartsAt:  now,
+						EndsAt:    now.Add(time.Hour),
+						UpdatedAt: now,
+					},
+					ExpiresAt: now.Add(24 * time.Hour),
+				},
 			},
 		},
 	}
@@ -1187,9 +1617,72 @@ func TestGossipDataCoding(t *testing.T) {
 		msg := in.Encode()
 		require.Equal(t, 1, len(msg), "expected single message for input")
 
-		out, err := decodeGossipData(msg[0])
+		out, _, err := decodeGossipData(msg[0])
 		require.NoError(t, err, "decoding message failed")
 
 		require.Equal(t, in, out, "decoded data doesn't match encoded data")
 	}
 }
+
+func TestSilenceEfficacyMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	s, err := New(Options{Metrics: reg, Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	m := &pb.Matcher{Type: pb.Matcher_EQUAL, Name: "a", Pattern: "b"}
+	s.st = &gossipData{
+		data: silenceMap{
+			"broad": &pb.MeshSilence{Silence: &pb.Silence{
+				Id:        "broad",
+				Matchers:  []*pb.Matcher{m},
+				StartsAt:  now.Add(-time.Hour),
+				EndsAt:    now.Add(time.Hour),
+				CreatedBy: "alice",
+			}},
+			"useless": &pb.MeshSilence{Silence: &pb.Silence{
+				Id:        "useless",
+				Matchers:  []*pb.Matcher{m},
+				StartsAt:  now.Add(-time.Hour),
+				EndsAt:    now.Add(-time.Minute),
+				CreatedBy: "bob",
+			}},
+		},
+	}
+
+	s.ObserveMuted(1, "broad")
+	s.ObserveMuted(2, "broad")
+	// Alerts muted repeatedly are counted once.
+	s.ObserveMuted(1, "broad", "unknown")
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+
+	var (
+		muted  = map[string]float64{}
+		unused float64
+	)
+	for _, mf := range mfs {
+		switch mf.GetName() {
+		case "alertmanager_silence_muted_alerts":
+			for _, m := range mf.GetMetric() {
+				muted[labelValue(m, "silence_id")+"/"+labelValue(m, "created_by")] = m.GetGauge().GetValue()
+			}
+		case "alertmanager_silences_unused":
+			unused = mf.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	require.Equal(t, map[string]float64{"broad/alice": 2, "useless/bob": 0}, muted)
+	require.Equal(t, float64(1), unused)
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, lp := range m.GetLabel() {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+	return ""
+}
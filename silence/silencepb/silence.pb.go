@@ -40,20 +40,30 @@ const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 
 // Type specifies how the given name and pattern are matched
 // against a label set.
+//
+// New types may be added in the future. Peers that do not know a
+// matcher's type must retain the silence unchanged when storing and
+// gossiping it, but never evaluate it against label sets.
 type Matcher_Type int32
 
 const (
-	Matcher_EQUAL  Matcher_Type = 0
-	Matcher_REGEXP Matcher_Type = 1
+	Matcher_EQUAL      Matcher_Type = 0
+	Matcher_REGEXP     Matcher_Type = 1
+	Matcher_NOT_EQUAL  Matcher_Type = 2
+	Matcher_NOT_REGEXP Matcher_Type = 3
 )
 
 var Matcher_Type_name = map[int32]string{
 	0: "EQUAL",
 	1: "REGEXP",
+	2: "NOT_EQUAL",
+	3: "NOT_REGEXP",
 }
 var Matcher_Type_value = map[string]int32{
-	"EQUAL":  0,
-	"REGEXP": 1,
+	"EQUAL":      0,
+	"REGEXP":     1,
+	"NOT_EQUAL":  2,
+	"NOT_REGEXP": 3,
 }
 
 func (x Matcher_Type) String() string {
@@ -106,6 +116,16 @@ type Silence struct {
 	// Comment for the silence.
 	CreatedBy string `protobuf:"bytes,8,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
 	Comment   string `protobuf:"bytes,9,opt,name=comment,proto3" json:"comment,omitempty"`
+	// Authenticated principal and client that created the silence through
+	// the API, if known. They are kept when the silence is updated.
+	Principal string `protobuf:"bytes,10,opt,name=principal,proto3" json:"principal,omitempty"`
+	Source    string `protobuf:"bytes,11,opt,name=source,proto3" json:"source,omitempty"`
+	// Team owning the silence. It is set when the silence is created and
+	// only changed by transferring it.
+	Team string `protobuf:"bytes,12,opt,name=team,proto3" json:"team,omitempty"`
+	// Schedule by which the silence recurs within its time range. The
+	// silence is only active during the windows of the schedule if set.
+	Recurrence *Recurrence `protobuf:"bytes,13,opt,name=recurrence" json:"recurrence,omitempty"`
 }
 
 func (m *Silence) Reset()                    { *m = Silence{} }
@@ -125,11 +145,30 @@ func (m *MeshSilence) String() string            { return proto.CompactTextStrin
 func (*MeshSilence) ProtoMessage()               {}
 func (*MeshSilence) Descriptor() ([]byte, []int) { return fileDescriptorSilence, []int{3} }
 
+// Recurrence is a schedule of windows recurring on days of the week.
+type Recurrence struct {
+	// Days of the week on which a window starts. Bit i is set for the
+	// weekday i, counted from Sunday as 0. Every day if zero.
+	Weekdays uint32 `protobuf:"varint,1,opt,name=weekdays,proto3" json:"weekdays,omitempty"`
+	// Minutes after midnight at which the windows start.
+	StartMinute uint32 `protobuf:"varint,2,opt,name=start_minute,json=startMinute,proto3" json:"start_minute,omitempty"`
+	// Length of the windows in minutes.
+	DurationMinutes uint32 `protobuf:"varint,3,opt,name=duration_minutes,json=durationMinutes,proto3" json:"duration_minutes,omitempty"`
+	// Name of the time zone the schedule is defined in. UTC if empty.
+	Location string `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+}
+
+func (m *Recurrence) Reset()                    { *m = Recurrence{} }
+func (m *Recurrence) String() string            { return proto.CompactTextString(m) }
+func (*Recurrence) ProtoMessage()               {}
+func (*Recurrence) Descriptor() ([]byte, []int) { return fileDescriptorSilence, []int{4} }
+
 func init() {
 	proto.RegisterType((*Matcher)(nil), "silencepb.Matcher")
 	proto.RegisterType((*Comment)(nil), "silencepb.Comment")
 	proto.RegisterType((*Silence)(nil), "silencepb.Silence")
 	proto.RegisterType((*MeshSilence)(nil), "silencepb.MeshSilence")
+	proto.RegisterType((*Recurrence)(nil), "silencepb.Recurrence")
 	proto.RegisterEnum("silencepb.Matcher_Type", Matcher_Type_name, Matcher_Type_value)
 }
 func (m *Matcher) Marshal() (dAtA []byte, err error) {
@@ -286,6 +325,34 @@ func (m *Silence) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintSilence(dAtA, i, uint64(len(m.Comment)))
 		i += copy(dAtA[i:], m.Comment)
 	}
+	if len(m.Principal) > 0 {
+		dAtA[i] = 0x52
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.Principal)))
+		i += copy(dAtA[i:], m.Principal)
+	}
+	if len(m.Source) > 0 {
+		dAtA[i] = 0x5a
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.Source)))
+		i += copy(dAtA[i:], m.Source)
+	}
+	if len(m.Team) > 0 {
+		dAtA[i] = 0x62
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.Team)))
+		i += copy(dAtA[i:], m.Team)
+	}
+	if m.Recurrence != nil {
+		dAtA[i] = 0x6a
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(m.Recurrence.Size()))
+		n5, err := m.Recurrence.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n5
+	}
 	return i, nil
 }
 
@@ -308,20 +375,59 @@ func (m *MeshSilence) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0xa
 		i++
 		i = encodeVarintSilence(dAtA, i, uint64(m.Silence.Size()))
-		n5, err := m.Silence.MarshalTo(dAtA[i:])
+		n6, err := m.Silence.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n5
+		i += n6
 	}
 	dAtA[i] = 0x12
 	i++
 	i = encodeVarintSilence(dAtA, i, uint64(github_com_gogo_protobuf_types.SizeOfStdTime(m.ExpiresAt)))
-	n6, err := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ExpiresAt, dAtA[i:])
+	n7, err := github_com_gogo_protobuf_types.StdTimeMarshalTo(m.ExpiresAt, dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n6
+	i += n7
+	return i, nil
+}
+
+func (m *Recurrence) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Recurrence) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Weekdays != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(m.Weekdays))
+	}
+	if m.StartMinute != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(m.StartMinute))
+	}
+	if m.DurationMinutes != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(m.DurationMinutes))
+	}
+	if len(m.Location) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.Location)))
+		i += copy(dAtA[i:], m.Location)
+	}
 	return i, nil
 }
 
@@ -418,6 +524,22 @@ func (m *Silence) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovSilence(uint64(l))
 	}
+	l = len(m.Principal)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	l = len(m.Source)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	l = len(m.Team)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	if m.Recurrence != nil {
+		l = m.Recurrence.Size()
+		n += 1 + l + sovSilence(uint64(l))
+	}
 	return n
 }
 
@@ -433,6 +555,25 @@ func (m *MeshSilence) Size() (n int) {
 	return n
 }
 
+func (m *Recurrence) Size() (n int) {
+	var l int
+	_ = l
+	if m.Weekdays != 0 {
+		n += 1 + sovSilence(uint64(m.Weekdays))
+	}
+	if m.StartMinute != 0 {
+		n += 1 + sovSilence(uint64(m.StartMinute))
+	}
+	if m.DurationMinutes != 0 {
+		n += 1 + sovSilence(uint64(m.DurationMinutes))
+	}
+	l = len(m.Location)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	return n
+}
+
 func sovSilence(x uint64) (n int) {
 	for {
 		n++
@@ -979,6 +1120,126 @@ func (m *Silence) Unmarshal(dAtA []byte) error {
 			}
 			m.Comment = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Principal", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Principal = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Source", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Source = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Team", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Team = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Recurrence", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Recurrence == nil {
+				m.Recurrence = &Recurrence{}
+			}
+			if err := m.Recurrence.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipSilence(dAtA[iNdEx:])
@@ -1113,6 +1374,142 @@ func (m *MeshSilence) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *Recurrence) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowSilence
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Recurrence: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Recurrence: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Weekdays", wireType)
+			}
+			m.Weekdays = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Weekdays |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartMinute", wireType)
+			}
+			m.StartMinute = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartMinute |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DurationMinutes", wireType)
+			}
+			m.DurationMinutes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DurationMinutes |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Location", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Location = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipSilence(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthSilence
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipSilence(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
@@ -1221,33 +1618,42 @@ var (
 func init() { proto.RegisterFile("silence.proto", fileDescriptorSilence) }
 
 var fileDescriptorSilence = []byte{
-	// 444 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x51, 0x4d, 0x6b, 0xdb, 0x40,
-	0x10, 0xf5, 0x2a, 0x8e, 0x65, 0x8d, 0x69, 0x30, 0x43, 0x69, 0x85, 0x21, 0xb6, 0xd1, 0xc9, 0xd0,
-	0x22, 0x83, 0x7b, 0xee, 0x41, 0x0e, 0xa6, 0x97, 0x06, 0x5a, 0x35, 0x85, 0xde, 0xca, 0xda, 0x9a,
-	0xda, 0x82, 0x48, 0xbb, 0x48, 0x63, 0xa8, 0x4f, 0x2d, 0xf4, 0x0f, 0xf4, 0x67, 0xf9, 0xd8, 0x5f,
-	0xd0, 0x0f, 0xff, 0x8b, 0xde, 0x8a, 0x56, 0x2b, 0x37, 0x21, 0x27, 0xdf, 0x66, 0x66, 0xdf, 0x9b,
-	0xb7, 0xef, 0x0d, 0x3c, 0x2a, 0xd3, 0x5b, 0xca, 0x57, 0x14, 0xea, 0x42, 0xb1, 0x42, 0xcf, 0xb6,
-	0x7a, 0x39, 0x18, 0xad, 0x95, 0x5a, 0xdf, 0xd2, 0xd4, 0x3c, 0x2c, 0xb7, 0x9f, 0xa6, 0x9c, 0x66,
-	0x54, 0xb2, 0xcc, 0x74, 0x8d, 0x1d, 0x3c, 0x5e, 0xab, 0xb5, 0x32, 0xe5, 0xb4, 0xaa, 0xea, 0x69,
-	0xf0, 0x4d, 0x80, 0x7b, 0x2d, 0x79, 0xb5, 0xa1, 0x02, 0x9f, 0x41, 0x9b, 0x77, 0x9a, 0x7c, 0x31,
-	0x16, 0x93, 0x8b, 0xd9, 0xd3, 0xf0, 0xb8, 0x3c, 0xb4, 0x88, 0xf0, 0x66, 0xa7, 0x29, 0x36, 0x20,
-	0x44, 0x68, 0xe7, 0x32, 0x23, 0xdf, 0x19, 0x8b, 0x89, 0x17, 0x9b, 0x1a, 0x7d, 0x70, 0xb5, 0x64,
-	0xa6, 0x22, 0xf7, 0xcf, 0xcc, 0xb8, 0x69, 0x83, 0x4b, 0x68, 0x57, 0x5c, 0xf4, 0xe0, 0x7c, 0xf1,
-	0xf6, 0x7d, 0xf4, 0xba, 0xdf, 0x42, 0x80, 0x4e, 0xbc, 0x78, 0xb5, 0xf8, 0xf0, 0xa6, 0x2f, 0x82,
-	0x2f, 0xe0, 0x5e, 0xa9, 0x2c, 0xa3, 0x9c, 0xf1, 0x09, 0x74, 0xe4, 0x96, 0x37, 0xaa, 0x30, 0xdf,
-	0xf0, 0x62, 0xdb, 0x55, 0xbb, 0x57, 0x35, 0xc4, 0x4a, 0x36, 0x2d, 0xce, 0xc1, 0x3b, 0x7a, 0x35,
-	0xba, 0xbd, 0xd9, 0x20, 0xac, 0xd3, 0x08, 0x9b, 0x34, 0xc2, 0x9b, 0x06, 0x31, 0xef, 0xee, 0x7f,
-	0x8e, 0x5a, 0xdf, 0x7f, 0x8d, 0x44, 0xfc, 0x9f, 0x16, 0xfc, 0x75, 0xc0, 0x7d, 0x57, 0xdb, 0xc5,
-	0x0b, 0x70, 0xd2, 0xc4, 0xaa, 0x3b, 0x69, 0x82, 0x21, 0x74, 0xb3, 0xda, 0x7f, 0xe9, 0x3b, 0xe3,
-	0xb3, 0x49, 0x6f, 0x86, 0x0f, 0xa3, 0x89, 0x8f, 0x18, 0x8c, 0xc0, 0x2b, 0x59, 0x16, 0x5c, 0x7e,
-	0x94, 0x7c, 0xd2, 0x7f, 0xba, 0x35, 0x2d, 0x62, 0x7c, 0x09, 0x2e, 0xe5, 0x89, 0x59, 0xd0, 0x3e,
-	0x61, 0x41, 0xa7, 0x22, 0x45, 0x8c, 0x57, 0x00, 0x5b, 0x9d, 0x48, 0xa6, 0xa4, 0xda, 0x70, 0x7e,
-	0x4a, 0x24, 0x96, 0x17, 0x71, 0x65, 0xdb, 0x26, 0x5c, 0xfa, 0xee, 0x03, 0xdb, 0xf6, 0x5c, 0xf1,
-	0x11, 0x83, 0x97, 0x00, 0xab, 0x82, 0x8c, 0xe8, 0x72, 0xe7, 0x77, 0x4d, 0x7c, 0x9e, 0x9d, 0xcc,
-	0x77, 0x77, 0xef, 0xe7, 0xdd, 0xbb, 0x5f, 0xf0, 0x55, 0x40, 0xef, 0x9a, 0xca, 0x4d, 0x93, 0xff,
-	0x73, 0x70, 0xad, 0x8e, 0x39, 0xc2, 0x7d, 0x5d, 0x0b, 0x8a, 0x1b, 0x48, 0xe5, 0x95, 0x3e, 0xeb,
-	0xb4, 0x20, 0x93, 0x96, 0x73, 0x8a, 0x57, 0xcb, 0x8b, 0x78, 0xde, 0xdf, 0xff, 0x19, 0xb6, 0xf6,
-	0x87, 0xa1, 0xf8, 0x71, 0x18, 0x8a, 0xdf, 0x87, 0xa1, 0x58, 0x76, 0x0c, 0xf5, 0xc5, 0xbf, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0xde, 0x36, 0xea, 0xdd, 0x71, 0x03, 0x00, 0x00,
+	// 581 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x95, 0x53, 0xc9, 0x8e, 0xd3, 0x40,
+	0x10, 0x8d, 0x93, 0x4c, 0x1c, 0x57, 0xc6, 0x43, 0xd4, 0x62, 0xb1, 0x22, 0x98, 0x80, 0x4f, 0x20,
+	0x90, 0x23, 0x0d, 0xe2, 0x06, 0x87, 0x64, 0x14, 0x71, 0x21, 0x2c, 0x4d, 0x90, 0xb8, 0x8d, 0x3a,
+	0x76, 0x4f, 0x62, 0x11, 0x2f, 0x6a, 0xb7, 0x05, 0x39, 0xc1, 0x27, 0x20, 0x3e, 0x81, 0xaf, 0xc9,
+	0x91, 0x2f, 0x60, 0xfb, 0x0c, 0x4e, 0xf4, 0x66, 0x4f, 0x46, 0x73, 0xca, 0xc1, 0x52, 0x2d, 0xaf,
+	0xaa, 0x5e, 0xbd, 0x2e, 0x83, 0x5b, 0xc4, 0x6b, 0x9a, 0x86, 0x34, 0xc8, 0x59, 0xc6, 0x33, 0xe4,
+	0x18, 0x37, 0x5f, 0x0c, 0x86, 0xcb, 0x2c, 0x5b, 0xae, 0xe9, 0x48, 0x25, 0x16, 0xe5, 0xf9, 0x88,
+	0xc7, 0x09, 0x2d, 0x38, 0x49, 0x72, 0x8d, 0x1d, 0x5c, 0x5f, 0x66, 0xcb, 0x4c, 0x99, 0x23, 0x69,
+	0xe9, 0xa8, 0xff, 0xdd, 0x02, 0x7b, 0x46, 0x78, 0xb8, 0xa2, 0x0c, 0x3d, 0x84, 0x36, 0xdf, 0xe4,
+	0xd4, 0xb3, 0xee, 0x5a, 0xf7, 0x8f, 0x4e, 0x6e, 0x05, 0x75, 0xf3, 0xc0, 0x20, 0x82, 0xb9, 0x48,
+	0x63, 0x05, 0x42, 0x08, 0xda, 0x29, 0x49, 0xa8, 0xd7, 0x14, 0x60, 0x07, 0x2b, 0x1b, 0x79, 0x60,
+	0xe7, 0x84, 0x73, 0xca, 0x52, 0xaf, 0xa5, 0xc2, 0x95, 0xeb, 0x3f, 0x85, 0xb6, 0xac, 0x45, 0x0e,
+	0x1c, 0x4c, 0xdf, 0xbc, 0x1b, 0xbf, 0xe8, 0x37, 0x10, 0x40, 0x07, 0x4f, 0x9f, 0x4f, 0xdf, 0xbf,
+	0xee, 0x5b, 0xc8, 0x05, 0xe7, 0xe5, 0xab, 0xf9, 0x99, 0x4e, 0x35, 0xd1, 0x11, 0x80, 0x74, 0x4d,
+	0xba, 0xe5, 0x7f, 0x06, 0xfb, 0x34, 0x4b, 0x12, 0x9a, 0x72, 0x74, 0x13, 0x3a, 0xa4, 0xe4, 0xab,
+	0x8c, 0x29, 0x96, 0x0e, 0x36, 0x9e, 0x1c, 0x1d, 0x6a, 0x88, 0x61, 0x54, 0xb9, 0x68, 0x02, 0x4e,
+	0x2d, 0x85, 0xa2, 0xd5, 0x3b, 0x19, 0x04, 0x5a, 0xac, 0xa0, 0x12, 0x2b, 0x98, 0x57, 0x88, 0x49,
+	0x77, 0xfb, 0x73, 0xd8, 0xf8, 0xfa, 0x6b, 0x68, 0xe1, 0x8b, 0x32, 0xff, 0x5f, 0x0b, 0xec, 0xb7,
+	0x5a, 0x0d, 0x41, 0xae, 0x19, 0x47, 0x66, 0xba, 0xb0, 0x50, 0x00, 0xdd, 0x44, 0xcb, 0x53, 0x88,
+	0xd1, 0x2d, 0xd1, 0x1e, 0x5d, 0x55, 0x0e, 0xd7, 0x18, 0x34, 0x06, 0x47, 0x34, 0x65, 0xbc, 0x38,
+	0x23, 0x7c, 0x2f, 0x3e, 0x5d, 0x5d, 0x36, 0xe6, 0xe8, 0x19, 0xd8, 0x34, 0x8d, 0x54, 0x83, 0xf6,
+	0x1e, 0x0d, 0x3a, 0xb2, 0x48, 0x94, 0x9f, 0x02, 0x94, 0x79, 0x44, 0x38, 0x8d, 0x64, 0x87, 0x83,
+	0x7d, 0x24, 0x31, 0x75, 0xa2, 0x89, 0x58, 0xdb, 0x28, 0x5c, 0x78, 0xf6, 0x95, 0xb5, 0xcd, 0x73,
+	0xe1, 0x1a, 0x83, 0xee, 0x00, 0x84, 0x8c, 0xaa, 0xa1, 0x8b, 0x8d, 0xd7, 0x55, 0xf2, 0x39, 0x26,
+	0x32, 0xd9, 0xec, 0xbe, 0x9f, 0x73, 0xf9, 0xfd, 0x6e, 0x83, 0x93, 0xb3, 0x38, 0x0d, 0xe3, 0x9c,
+	0xac, 0x3d, 0xd0, 0x75, 0x75, 0x40, 0xde, 0x43, 0x91, 0x95, 0x2c, 0xa4, 0x5e, 0x4f, 0xdf, 0x83,
+	0xf6, 0xe4, 0x79, 0x72, 0x4a, 0x12, 0xef, 0x50, 0x9f, 0xa7, 0xb4, 0xd1, 0x13, 0x00, 0x46, 0xc3,
+	0x92, 0x31, 0x49, 0xd2, 0x73, 0xd5, 0xde, 0x37, 0x76, 0x48, 0xe3, 0x3a, 0x89, 0x77, 0x80, 0xfe,
+	0x17, 0x0b, 0x7a, 0x33, 0x5a, 0xac, 0xaa, 0x03, 0x78, 0x04, 0xb6, 0xa9, 0x51, 0x57, 0x70, 0x79,
+	0x71, 0x03, 0xc2, 0x15, 0x44, 0x8a, 0x4d, 0x3f, 0xe5, 0x31, 0xa3, 0xea, 0xb9, 0x9a, 0xfb, 0x88,
+	0x6d, 0xea, 0xc6, 0xdc, 0xff, 0x66, 0x01, 0x5c, 0xb0, 0x43, 0x03, 0xe8, 0x7e, 0xa4, 0xf4, 0x43,
+	0x44, 0x36, 0x85, 0xa2, 0xe0, 0xe2, 0xda, 0x47, 0xf7, 0xe0, 0x50, 0xdd, 0xc9, 0x59, 0x12, 0xa7,
+	0x25, 0xd7, 0xff, 0xa7, 0x8b, 0x7b, 0x2a, 0x36, 0x53, 0x21, 0xf4, 0x00, 0xfa, 0x51, 0xc9, 0x08,
+	0x8f, 0xb3, 0xd4, 0xa0, 0x0a, 0x75, 0x88, 0x2e, 0xbe, 0x56, 0xc5, 0x35, 0xb2, 0x90, 0x93, 0xd6,
+	0x59, 0xa8, 0x42, 0xea, 0xd4, 0x1c, 0x5c, 0xfb, 0x93, 0xfe, 0xf6, 0xcf, 0x71, 0x63, 0xfb, 0xf7,
+	0xd8, 0xfa, 0x21, 0xbe, 0xdf, 0xe2, 0x5b, 0x74, 0xd4, 0x3e, 0x8f, 0xff, 0x03, 0x98, 0xe1, 0x8f,
+	0x72, 0xa6, 0x04, 0x00, 0x00,
 }
@@ -68,7 +68,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x3b\x6b\x6f\xdb\x36\xbb\xdf\xf5\x2b\x9e\x69\x38\x58\x03\x58\x96\x93\x6e\xc5\xe2\xd8\x39\x70\x1d\xa5\x11\x8e\x23\x07\xb2\xd2\xae\x18\x86\x80\x96\x68\x9b\xad\x44\x6a\x24\x95\xc4\xcb\xfc\xdf\x0f\x48\xc9\x17\xc5\x72\xe2\x14\x5d\xe2\xf7\x5d\x12\xb4\x91\x28\x3e\xf7\x2b\x45\xea\xee\x0e\x22\x3c\x22\x14\x83\x79\x75\x85\x62\xcc\x65\x82\x28\x1a\x63\x6e\xc2\x6c\xd6\x51\xf7\xe7\xf9\xfd\xdd\x1d\x60\x1a\xc1\x6c\x66\x6c\x04\xb9\xf4\x7b\x0a\xea\xee\x0e\xea\xce\xad\xc4\x9c\xa2\xf8\xd2\xef\xc1\x6c\x66\xff\x68\xeb\x79\xe2\x7f\x39\x0e\x31\xb9\xc6\xbc\xad\x26\xf9\xc5\x4d\x0e\x53\x60\x2f\xa3\x17\xd9\xf0\x0b\x0e\xa5\x42\xfb\xbb\x02\x19\x48\x24\x33\x01\x7f\x83\x64\x97\x69\x3a\x07\x25\x23\xc0\x7f\x2e\x1e\x9a\x23\xc2\x09\x1d\x2b\x98\xa6\x82\xd1\x52\x88\xfa\xa9\x1e\x85\xbf\x21\xc6\x74\x95\xe2\x1f\xa0\x26\x7d\xe0\x2c\x4b\x7b\x68\x88\x63\x51\x1f\x30\x2e\x71\x74\x81\x08\x17\xf5\x8f\x28\xce\xb0\x22\xf8\x85\x11\x0a\x26\x28\xac\x90\x93\x1c\x4b\x78\xa3\x70\xd5\xbb\x2c\x49\x18\xcd\x81\xf7\x8a\xb1\x15\x7c\x7b\x30\x9b\xbd\xb9\xbb\x83\x1b\x22\x27\xe5\xc9\x75\x1f\x27\xec\x1a\x97\xa9\x7b\x28\xc1\xa2\x50\x63\x15\xf5\x05\xe3\x7b\x8b\xab\x0d\xb6\x89\xb0\x08\x39\x49\x25\x61\xd4\x7c\x40\xc7\x12\xdf\xca\xdc\x8e\x57\x31\x11\xb2\x98\xca\x11\x1d\x63\xa8\xc3\x6c\x96\xf3\xd5\x34\x96\x83\xeb\x7a\x52\x5a\xb1\xb4\x22\x15\xfb\xea\xae\x0d\x0b\x01\x0a\xc6\x72\xe2\x1d\x4a\x99\x44\x8a\xa7\x12\xca\x95\xe1\x6f\xc3\x3b\x60\x19\x0f\x71\x33\x37\x26\xa6\x98\x23\xc9\x78\xee\x7e\x46\x85\xa2\x4a\x3a\x10\x31\x0a\xbf\xd6\x23\x3c\x42\x59\x2c\xeb\x92\xc8\x18\x17\x5a\x90\x38\x49\x63\x24\xcb\xbe\x58\xdf\xa4\xf2\x32\x9e\x4c\xa8\x10\x48\xaa\x50\x95\x03\x6d\x4b\x7c\x23\x14\xc7\x43\x14\x7e\x5d\xc3\x57\xc9\xbe\x42\x0a\x7f\xc3\x63\x13\x63\x42\xbf\x6e\xcd\x41\xca\xb1\x72\x16\x73\xbb\xd9\x2b\xf8\x1f\x54\x80\x4e\x1b\x5b\x72\x40\x42\x46\x71\xc2\xbe\x90\x2d\x79\x50\xf3\x33\x1e\x6f\xcb\xf1\x9a\x70\x25\x37\x99\x90\x34\x9c\x20\xb9\x34\x08\x67\xc9\xb7\x1b\xf7\x3e\xb6\x04\x0b\x81\xc6\x4f\x70\xbc\x12\x6f\xa9\xa2\x16\x65\x72\xba\xc0\xb7\x1e\xfd\x4f\x73\xe6\x75\x8c\x61\x4c\x30\x95\xdf\x2e\xf1\x26\x8c\xcb\xba\xf1\x6d\x2e\xb2\x8e\x97\x50\x21\x11\x0d\xb1\xa8\xc0\xbb\x96\xee\x1e\xd0\x2a\x4b\xc5\x18\x53\x82\xbf\xdd\x48\x0f\x21\x5b\xb7\x50\x51\x1d\x36\x24\xc3\xca\x72\x60\xdc\x2b\x46\xa5\x6a\xb7\x07\x0d\xb0\x66\x33\x23\x1f\x84\x7c\x50\xa7\xdd\x87\x35\x52\x2e\x99\x9a\x88\xb5\x22\x51\x05\x3d\x1f\x0b\x16\x5f\xe3\xe8\x1e\xc5\xf9\xf0\xf6\x34\xe7\x10\x6b\x54\xad\x6d\x54\x2a\x74\x15\x78\xba\x37\x95\xac\x7e\x4d\x42\xc9\x38\x4b\xc5\x12\xad\x44\x12\x5f\x95\x8d\xff\x6a\xab\xa7\xd9\x6a\x5d\xab\x98\x4a\x22\xa7\x57\x11\x11\x69\x8c\xa6\x57\x1b\x6a\xe5\xe3\x81\xb5\x8e\x39\x61\x94\x48\xa6\x14\x72\x25\x19\x8b\x9f\x98\xb2\x56\x71\xe3\x04\x91\x78\xe9\x07\xcb\x76\xf4\xc9\x5c\x96\x31\x4d\x64\xa2\xd9\x32\x5a\x3f\x9c\xf4\xbb\xc1\xe7\x0b\x07\xd4\x10\x5c\x5c\xbe\xef\xb9\x5d\x30\x2d\xdb\xfe\xf4\xb6\x6b\xdb\x27\xc1\x09\xfc\x76\x16\x9c\xf7\x60\xbf\xde\x80\x80\x23\x2a\x88\x72\x36\x14\xdb\xb6\xe3\x99\x60\x4e\xa4\x4c\x9b\xb6\x7d\x73\x73\x53\xbf\x79\x5b\x67\x7c\x6c\x07\xbe\x7d\xab\x70\xed\x2b\xe0\xe2\xd2\x92\x2b\x90\xf5\x48\x46\xe6\xb1\xd1\xfa\xc1\xb2\x8c\x81\x9c\xc6\x18\x10\x8d\x40\x13\x89\x30\x27\xca\xa0\xaa\xb4\x81\x42\x2d\x9a\xb6\x3d\x26\x72\x92\x0d\xeb\x21\x4b\x6c\x25\xc3\x38\xa3\xb6\x46\x87\xc2\x1c\x9f\xa5\x45\xb3\xe6\xea\x10\x86\x61\x04\x13\x0c\xe7\x6e\x00\x3d\x12\x62\x2a\x30\xbc\x39\x77\x83\x3d\xc3\xe8\xb2\x74\xca\xc9\x78\x22\xe1\x4d\xb8\x07\x07\x8d\xfd\x9f\xe1\x3c\xc7\x68\x18\x17\x98\x27\x44\x08\xc2\x28\x10\x01\x13\xcc\xf1\x70\x0a\x63\x8e\xa8\xc4\x51\x0d\x46\x1c\x63\x60\x23\x08\x27\x88\x8f\x71\x0d\x24\x03\x44\xa7\x90\x62\x2e\x18\x05\x36\x94\x88\x50\xe5\xff\x08\x42\x96\x4e\x0d\x36\x02\x39\x21\x02\x04\x1b\xc9\x1b\xc4\x73\x09\x91\x10\x2c\x24\x48\xe2\x08\x22\x16\x66\x09\xa6\x79\xe0\xc2\x88\xc4\x58\xc0\x1b\x39\xc1\x60\x0e\x0a\x08\x73\x4f\x13\x89\x30\x8a\x0d\x42\x41\x3d\x9b\x3f\xd2\x9d\x3c\xcb\x24\x70\x2c\x24\x27\x5a\x0b\x35\x20\x34\x8c\xb3\x48\xf1\x30\x7f\x1c\x93\x84\x14\x14\x14\xb8\x16\x5c\x18\x92\x41\x26\x70\x4d\xf3\x59\x83\x84\x45\x64\xa4\xfe\x62\x2d\x56\x9a\x0d\x63\x22\x26\x35\x88\x88\x42\x3d\xcc\x24\xae\x81\x50\x83\x5a\x8f\x35\x25\x87\xcd\x38\x08\x1c\xc7\x46\xc8\x52\x82\x05\x68\x59\x97\xdc\xe9\x39\x8a\xf5\x54\x29\x54\x16\x2a\x12\x6a\xe4\x66\xc2\x92\xb2\x24\x44\x18\xa3\x8c\x53\x22\x26\x58\xc3\x44\x0c\x04\xd3\x14\x95\x37\xab\x11\x35\x7d\xc4\xe2\x98\xdd\x28\xd1\x42\x46\x23\x52\x34\xef\xda\xc8\x68\xa8\x16\x30\xe1\xc2\xae\x94\x49\x12\xe6\xea\xd6\x06\x48\x97\x56\x2d\x1e\x89\x09\x8a\x63\x18\xe2\x42\x61\x38\x02\x42\x01\xad\x88\xc3\x15\x79\x55\xbf\x25\x41\x31\xa4\x8c\x6b\x7a\xf7\xc5\xac\x1b\x46\x70\xe6\xc0\xa0\x7f\x1a\x7c\xea\xf8\x0e\xb8\x03\xb8\xf0\xfb\x1f\xdd\x13\xe7\x04\xcc\xce\x00\xdc\x81\x59\x83\x4f\x6e\x70\xd6\xbf\x0c\xe0\x53\xc7\xf7\x3b\x5e\xf0\x19\xfa\xa7\xd0\xf1\x3e\xc3\xff\xb9\xde\x49\x0d\x9c\xdf\x2e\x7c\x67\x30\x80\xbe\x6f\xb8\xe7\x17\x3d\xd7\x39\xa9\x81\xeb\x75\x7b\x97\x27\xae\xf7\x01\xde\x5f\x06\xe0\xf5\x03\xe8\xb9\xe7\x6e\xe0\x9c\x40\xd0\x07\x45\xb0\x40\xe5\x3a\x03\x85\xec\xdc\xf1\xbb\x67\x1d\x2f\xe8\xbc\x77\x7b\x6e\xf0\xb9\x66\x9c\xba\x81\xa7\x70\x9e\xf6\x7d\xe8\xc0\x45\xc7\x0f\xdc\xee\x65\xaf\xe3\xc3\xc5\xa5\x7f\xd1\x1f\x38\xd0\xf1\x4e\xc0\xeb\x7b\xae\x77\xea\xbb\xde\x07\xe7\xdc\xf1\x82\x3a\xb8\x1e\x78\x7d\x70\x3e\x3a\x5e\x00\x83\xb3\x4e\xaf\xa7\x48\x19\x9d\xcb\xe0\xac\xef\x2b\xfe\xa0\xdb\xbf\xf8\xec\xbb\x1f\xce\x02\x38\xeb\xf7\x4e\x1c\x7f\x00\xef\x1d\xe8\xb9\x9d\xf7\x3d\x27\x27\xe5\x7d\x86\x6e\xaf\xe3\x9e\xd7\xe0\xa4\x73\xde\xf9\xe0\x68\xa8\x7e\x70\xe6\xf8\x86\x9a\x96\x73\x07\x9f\xce\x1c\x35\xa4\xe8\x75\x3c\xe8\x74\x03\xb7\xef\x29\x31\xba\x7d\x2f\xf0\x3b\xdd\xa0\x06\x41\xdf\x0f\x16\xa0\x9f\xdc\x81\x53\x83\x8e\xef\x0e\x94\x42\x4e\xfd\xfe\x79\xcd\x50\xea\xec\x9f\xaa\x29\xae\xa7\xe0\x3c\x27\xc7\xa2\x54\x0d\x25\x8b\xf4\x7d\x7d\x7f\x39\x70\x16\x08\xe1\xc4\xe9\xf4\x5c\xef\xc3\x40\x01\x2b\x11\xe7\x93\xeb\x86\x65\x1d\x1b\x2d\x9d\x02\x6f\x93\x98\x8a\x76\x45\x62\xdb\x3f\x3c\x3c\xcc\xf3\x99\xb9\xdd\x24\xa1\x92\x5b\xdb\x1c\x31\x2a\xad\x11\x4a\x48\x3c\x6d\xc2\x4f\x67\x38\xbe\xc6\x92\x84\x08\x3c\x9c\xe1\x9f\x6a\xb0\x18\xa8\x41\x87\x13\x14\xd7\x40\x20\x2a\x2c\x81\x39\x19\x1d\xc1\x90\xdd\x5a\x82\xfc\xa5\x6a\x31\x0c\x19\x8f\x30\xb7\x86\xec\xf6\x08\x34\x52\x41\xfe\xc2\x4d\xd8\xff\x39\xbd\x3d\x82\x04\xf1\x31\xa1\x4d\x68\x1c\xa9\xdc\x3a\xc1\x28\x7a\x49\xfa\x09\x96\x08\x54\x45\x6d\x9b\xd7\x04\xdf\xa8\x28\x32\x55\xf4\x4a\x4c\x65\xdb\xbc\x21\x91\x9c\xb4\x23\x7c\x4d\x42\x6c\xe9\x9b\x97\x53\x16\xd8\x73\x76\x95\x31\x2d\xfc\x67\x46\xae\xdb\x66\x37\x67\xd5\x0a\xa6\x29\x5e\x61\x5c\xb5\x22\xb6\x32\xee\x91\xae\x04\x02\xcb\xf6\x65\x70\x6a\xfd\xfa\xc2\xec\xeb\xa5\xee\xcb\x99\xfb\xa1\x5e\xa4\x65\x6b\xe6\x8e\x0d\xa3\x65\x2b\xa7\x54\x17\x43\x16\x4d\x81\x48\x9c\x88\x90\xa5\xb8\x6d\x9a\xfa\x46\x4e\xd5\x75\x11\x51\x22\x9c\xe0\x04\xe9\x88\x72\x54\x75\x3f\x9f\xf7\xbe\xcf\x2a\xa4\x75\x83\x87\x5f\x89\xb4\xf2\x07\x09\x63\x72\xa2\x81\xf2\xda\x40\x90\xc0\xd1\x72\x92\xf2\x0d\x0d\x6d\xa1\xe8\x4b\x26\x64\x13\x28\xa3\xf8\x08\x26\x58\x55\xa6\x26\xec\x37\x1a\xff\x73\x04\x31\xa1\xd8\x5a\x0c\xd5\xdf\xe1\xe4\x08\x74\x04\xe4\x13\xe0\x07\x92\xa8\x60\x41\x54\x1e\xc1\x10\x85\x5f\xc7\x9c\x65\x34\xb2\x42\x16\x33\xde\x84\x1f\x47\xef\xd4\xef\xaa\xfa\x21\x45\x51\xa4\xb9\x52\xde\x30\x1c\xeb\x99\x6d\xb3\x98\x69\x2a\x7d\x4b\x34\x7c\x6e\xf7\x58\x11\x69\x4b\x39\x2a\x79\x07\x68\x49\xfe\x82\x79\x0c\x40\x71\xf0\xcc\x99\xf4\x1a\x73\x85\x24\xb6\x50\x4c\xc6\xb4\x09\x92\xa5\x65\x45\x5d\xeb\x07\x6d\x53\xb2\xd4\x3c\x6e\xd9\x32\x5a\x32\x9a\x67\x56\xf3\x5d\xa3\xf1\xcc\xa1\x52\xc9\x74\xb1\xb4\x6a\xc2\x30\x66\xe1\xd7\x92\x6f\x27\xe8\xd6\x2a\x9c\xe4\x5d\xa3\x91\xde\x96\x1e\x86\x31\x46\x5c\x11\x94\x93\xd2\xf8\xa6\x40\x59\x28\x07\x50\x26\xd9\xbd\x90\x28\x69\x4b\x2b\x0a\xa0\x15\x91\xeb\xe7\x76\xab\xb2\xbc\xf7\x95\xf3\xb0\x10\x73\xbe\x95\x91\x75\x30\x17\x76\x56\x9a\x30\x21\xc4\x71\x5c\xcc\x6e\x9b\x8d\xfc\x5e\xa4\x28\x9c\xdf\x3f\xab\xa0\xc5\x43\x8e\x22\x92\x89\x26\xbc\xd5\x63\x15\x09\x60\x34\x2a\x65\xb1\x1c\xac\x09\xfb\xe9\x2d\x08\x16\x93\x08\x7e\xc4\x87\xea\xb7\x9c\x18\x46\xa3\x15\x5d\xec\x42\x76\x58\x72\xf2\x7c\x59\xe2\xdd\xc6\x80\x2b\x69\x57\x83\xdc\x14\xa5\xe6\x97\x46\xe3\x08\x74\x89\x2a\xe6\x87\x98\x4a\xcc\xab\xec\xa5\xff\x35\xb4\x51\xd6\xed\xe6\xbc\xfb\xe5\xe0\xa0\x5b\x5d\x80\x0e\x94\x5f\x9b\x50\xc4\x5b\x4e\x60\xd5\x7a\x39\x6c\x75\x44\xce\x7f\x96\x3b\x66\x8b\xad\x32\xd0\x2f\x4b\x2a\xdf\x25\xed\xc1\x3e\xcc\x66\x62\xf1\xc2\x03\x46\x8c\xc3\x72\x57\x67\xc3\xae\x1a\xcc\x66\xf7\xa8\xc2\xea\x1e\x4f\xbb\xb4\xc3\xb3\x36\xad\x78\xb5\x52\x32\xfe\x22\x07\x2f\xee\xf9\xab\x9b\x6e\x53\xcc\x96\xce\xb3\x9f\x3b\xcf\x43\xbe\xb1\xf3\xb9\x6f\xa3\xda\x77\xcb\x09\x76\xdd\x15\x1a\xd0\x98\xe7\x92\x87\xdc\xa1\x10\x03\xc1\x84\xe3\x51\xdb\xdc\xe6\x8d\xfb\x33\xfb\xc3\x3c\x69\x9e\x9e\x9e\x16\xc9\x37\xc2\x21\xe3\xfa\x9d\xdc\x7c\x79\x50\x5a\x10\x1c\xa8\xe5\x40\x29\x6f\x0f\x59\x1c\x55\x27\xee\x30\xe3\x42\x61\x4f\x19\xc9\x07\x16\x0d\x05\xa1\x1a\x69\xd1\x57\xdc\x4b\xf0\xbf\x28\xc6\x34\x3e\xfd\x12\x75\xc4\x78\xd2\x84\x10\xa5\x44\xa2\x98\xfc\x85\x2b\x93\xfe\xdb\x9f\x7f\xc5\x11\xaa\xa8\xd7\x6b\x33\x8a\x61\xad\xe5\x66\x5e\xc8\x17\x83\x8b\xee\x2d\xbd\x2d\xcc\x7b\xfc\x91\xe0\x1b\x20\x14\x1e\x7d\x3b\xde\xb2\x51\xa5\x0f\xdf\x4b\xbc\xd5\xe9\x37\xff\x79\x6c\xf3\xa3\xa2\x28\xbc\x86\xec\x3f\x13\xb2\x42\x72\x46\xc7\x2f\xa7\xda\xdf\x37\x9f\xcb\xf9\xa3\xd8\xf9\x6a\xd9\x39\x93\xdf\xc1\xeb\x2a\x1a\x86\xe2\xc9\xfc\xf0\xc9\xfd\x2d\xb4\x57\x3f\xfc\x77\xf8\x61\xde\x9a\x2e\x5c\xad\x35\x7c\x39\x33\x83\x5d\xad\xa3\x47\x4e\x5d\x6d\x3e\x1a\xf5\xc2\xc2\x6c\x8e\x3b\xa8\xa8\x05\xcb\x4d\xf4\xbc\x12\xbc\xb8\x67\xac\x70\xb4\x2b\xee\xf1\xa8\x46\x1f\x3d\x4a\xf7\x1f\xea\x2c\xab\x1d\xe6\xfd\xb3\x7d\x2f\xd4\x50\xce\xdb\xad\xb5\x9e\x32\xa3\x11\xe6\xaa\xfb\x2b\xbb\x53\x7e\x3a\x51\x35\x51\xbb\x97\x63\xbe\xad\x9a\x6e\xd9\xde\xad\x9e\x35\xa9\x34\xef\x6b\x57\xb8\x33\xd5\x78\xe7\x3c\x13\xa0\x35\xd9\x41\x9e\x76\x4e\x4f\x4f\x89\xe0\x87\x3a\xe2\xd7\xc0\xfa\xef\x6c\x73\x57\x97\x5b\x8b\x33\x7b\xcb\x05\xd7\x7c\xe8\x05\x96\x5c\xab\x27\x08\x5f\xbd\xf1\xdf\xe1\x8d\xaf\x8b\xae\xd7\x45\xd7\xeb\xa2\x6b\xd7\x9d\xe5\x75\xd1\xb5\x33\x2d\xdb\x26\x43\xb5\x6c\xbd\x1f\x77\xfc\x84\xad\xd0\x05\xc8\x72\xe4\xd9\x4f\x62\x94\x8e\x26\xad\x9c\x34\x59\x1a\xfa\xf0\xf0\xf0\xa1\x0d\xee\xf2\xce\xee\xfa\x96\xe4\x6e\x34\x0d\xbb\xd4\xbe\x3c\x67\xeb\x72\xb0\xb1\x75\xa9\xdc\x44\x7b\xcc\xe4\x2b\xbd\xcd\xbd\x73\x0d\xe5\x53\x58\xab\xe9\xaa\xfc\xf5\xf1\xf3\x39\xc4\xc1\x6a\xb6\xd2\x12\x6d\x9d\xaa\x30\x95\x30\x9c\x6e\xb7\x0f\xb7\x9e\x3b\xd6\xce\x3b\xdc\xcf\x0c\x2d\x3b\x22\xd7\xc7\xf9\xff\x46\x39\x4d\xec\x5a\x5b\xbb\xe1\x78\x5d\x2e\xe2\x32\x7f\xb5\xec\x21\x8b\xa6\x6a\x64\x22\x93\xf8\xd8\x30\xaa\xbf\xdf\x49\x33\x31\x61\xd7\x98\x7f\x87\xaf\x7b\xd7\x50\xfd\xf3\xdf\x83\x7d\x9f\xcf\xc1\xb6\xff\x1a\xec\xfb\x7d\x0c\xb6\x42\x73\x0b\x4d\x2e\x3f\xd1\x7d\xc2\x37\x7b\xff\x1f\x00\x00\xff\xff\xae\x05\x86\xad\xbb\x40\x00\x00")
+var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xed\x1c\x6b\x73\xda\xc6\xf6\xbb\x7e\xc5\xa9\xd2\xde\xda\x19\x04\xb6\xd3\x64\xea\x07\xbe\x43\xb0\x1c\x33\x17\x03\x03\x38\x69\xa6\xd3\xf1\x08\x69\x81\x8d\x85\x56\x91\x56\xc6\x34\xed\x7f\xbf\x67\x57\x42\x0f\x10\x0f\xfb\x66\x30\xee\xa5\x69\x1b\x74\x76\xf7\xbc\x5f\x2b\xad\xf4\xed\x1b\x58\xa4\x4f\x1d\x02\xea\xed\xad\x61\x13\x8f\x8f\x0c\xc7\x18\x10\x4f\x85\xbf\xff\xae\x88\xeb\xeb\xf0\xfa\xdb\x37\x20\x8e\x85\x40\xe5\xdb\xa2\x25\x37\xed\xba\x58\x85\xe3\x45\xfd\x81\x13\xcf\x31\x6c\x04\x21\xa4\xf4\xaa\x24\xe7\xf9\xff\xf6\x88\x49\xe8\x3d\xf1\xca\x62\x52\x3b\xba\x08\xd7\xac\xc2\xfe\xc1\x63\x81\x9b\x90\xe0\x64\xe4\xda\x06\xcf\x67\xa2\x88\x73\xfe\xd5\xa7\x36\x0f\x29\x8d\x29\x1f\x42\x51\x22\xa8\x1b\x3d\x62\xfb\xc5\x0e\xf3\x38\xb1\x5a\x06\xf5\xfc\x10\x9d\xfa\x4d\x85\xbf\x20\xf0\xec\xaf\x01\xf1\x26\x21\xcc\x33\x9c\x01\x81\x1f\x69\x01\x7e\x74\xe1\xa4\x2c\xb1\x22\x98\xf6\x11\x16\xad\x2a\xcc\xaf\x0a\xe5\xc0\x1f\xae\x47\x1d\xde\x07\xf5\x27\xbf\xfc\xd3\x57\x15\x71\x14\x1b\xc6\x88\x88\xbf\x3f\x1a\x76\x40\x16\x2f\x54\xff\x5e\x82\x35\x52\x53\x56\x4f\x7e\xd0\xfb\x42\x4c\x2e\x54\xf3\xbb\xd0\x6c\x87\x1b\x3c\xf0\x11\x09\x67\x37\xae\x3b\xd5\x30\x32\x4e\xbe\xc6\x83\x6a\x9f\x22\x83\x03\xb1\xe6\x44\xac\x91\xc6\xf6\x8b\x97\x12\x8a\x4b\x6d\xe2\xa4\x29\xfe\x01\x62\xd2\x02\x1d\x86\x12\x09\x82\x5f\x18\x75\x40\x05\x81\x15\x42\x92\x03\x0e\x7b\x02\x57\xb1\xca\x46\x23\xe6\x84\x8b\xf7\x23\x58\x0a\xdf\x3e\x2e\xd9\x8b\x8d\x95\x9e\x8c\x8e\x32\x62\xf7\x24\x4b\x5d\xe8\x32\xb2\x5d\x2e\xf5\x98\xf1\xfd\x1c\xed\x65\x94\x67\x11\xdf\xf4\xa8\xcb\x29\x73\xd4\xc5\xb3\x90\x85\x2f\x34\x1a\x0f\x59\xec\x0e\xc9\x88\x14\x75\x01\x8f\xf8\x88\x84\x5e\x6e\x2b\x4e\x1e\x78\xe8\xb1\xb7\x36\xf5\xb9\x9a\xf6\x35\x81\x21\x94\xef\x44\x49\x80\xb9\x3e\x0b\x9a\x34\x88\x74\x29\xbc\x2a\x43\xac\x88\x88\xf5\x90\x78\xc5\x71\x18\xda\x1b\x65\xcb\xa0\x4c\x81\x9f\x86\xb7\xc3\x02\xcf\x24\x27\xa1\x53\x10\x87\x78\x06\x67\x5e\x18\xed\xca\x2a\x85\xa7\x34\xe0\x07\xa3\x91\xe1\x4d\x22\x25\x48\x97\xc8\xfa\x21\xb2\x10\xfa\x69\x01\x66\x26\xb4\x89\xcf\xec\x7b\x22\xd0\x83\x37\xfd\x1d\xa6\x99\x22\x5c\x33\x9f\x43\xdf\x23\x18\x41\x0e\x07\x5b\x28\x10\xee\xa5\x97\xa4\xb5\xd0\x09\xa9\xc3\xdb\x39\xb9\xa5\x60\xa9\xf8\xbf\x97\xf1\x1f\xf9\x59\x26\x0b\x14\x32\x16\xff\xf1\x3e\xd6\x15\xec\x85\xd7\x55\x16\x20\x0b\x33\x7e\x18\xc6\x45\xf1\x02\x1d\x80\x3a\xe6\x34\x42\x42\xfc\xfb\xd3\xc5\xc9\x30\x02\xac\xe9\xef\x50\x8c\xfd\x8c\x6e\x23\x5b\xdb\x76\xa4\x00\xc9\xfe\xa2\x0c\x99\x24\xd2\x62\x66\xb9\x92\x71\x54\xdf\x36\xcc\xbb\x22\x5e\x19\x81\xcd\x8b\x9c\x72\x9b\xe4\x64\xde\x28\x2a\x8a\xf3\x23\x71\x4a\x2a\x2e\x72\x84\x2c\x85\xc0\x17\x05\x63\x44\x56\xa4\xf7\xb5\xf1\xf5\x0d\xdb\xee\x21\x60\x0e\x5f\xae\x60\x32\x74\xff\x82\x55\x13\x6d\xea\xdc\xad\xcd\x81\xeb\x11\xe1\xe9\xea\x7a\xb3\x53\xf8\xd7\xab\x6f\x6b\xe0\xa4\x26\x73\xd2\x79\x6b\x9d\xf9\x58\x76\xd6\xe5\x78\x4e\xb8\x8c\x03\x0d\xa9\x6b\x0e\x0d\x9e\x18\xc4\x63\xa3\xa7\x1b\x77\x16\x1b\x26\x7f\x1f\x97\xa8\x6b\x3b\x5e\x86\x37\x57\x50\xb3\x02\x3e\x89\xf1\xcd\x17\x81\xc7\x39\xf3\x3c\x46\xd3\xa6\x98\x7b\x9e\x2e\xf1\x22\x8c\x8f\x68\x81\xd6\xc4\x4b\x1d\x9f\x1b\x8e\x49\xfc\x1c\xbc\x73\xd5\x6a\x89\x56\x99\xeb\x0f\x88\x43\xc9\xd3\x8d\xb4\x0c\xd9\xbc\x85\xa2\x26\x61\x41\x2d\xcb\xed\x0a\x94\x30\xf7\x46\x99\x9f\xfe\x49\xac\xa5\x22\xc7\xe5\x69\xca\xa6\xed\x93\x74\x02\xdf\x9b\xaf\x58\xfb\x70\x00\x1a\x12\x0a\x81\x10\x02\x65\xd1\x59\xae\xd6\xd9\xb2\x87\x2b\xb4\x94\x5a\x72\xe8\x4d\x0b\xe0\x0c\xc5\x29\x78\x7d\x9a\xa9\x4a\x9a\xa2\x9a\xb6\x8a\xb6\x8e\x81\x7c\xd9\x12\xfc\x4f\xbe\x39\x87\x13\x7b\x68\xe6\x51\x3e\xc9\x34\x5e\x1d\x82\x9b\x06\x04\x62\x27\x1b\x29\x2b\x69\x6e\x41\x45\x27\xe1\xd4\x34\x64\x16\x6b\x1d\x4e\x8d\x16\x0f\x13\xcf\x63\x72\x73\xd3\x3a\x9a\x1b\x1b\x1b\x9e\x13\xb5\xc4\xad\x37\x73\xa3\xd4\xe9\x33\x39\xf4\x76\xbe\xc1\x59\x10\x11\xf7\xd4\xc4\xb6\x08\xa5\x4a\x94\x84\x9e\x4a\x6e\xb3\x81\xb1\xf3\xe3\xe7\xf4\xe3\x79\x1b\x61\x92\x45\xef\xba\xc5\x8e\x0b\x69\x4e\x6e\x17\x74\x25\xab\x53\xd8\x3c\x66\xb4\x32\x45\x10\x6a\xe6\x96\x33\x66\x3f\xb2\x38\xa4\x71\x93\x91\x41\xed\xc4\xab\x92\xfd\xdf\x77\x6c\xd0\xb2\x34\x86\x7c\x24\x19\x56\xce\x7e\xb8\x68\x56\xbb\x9f\x5b\x3a\x08\x10\xb4\x6e\xde\xd7\x6b\x55\x50\xb5\x52\xe9\xd3\x9b\x6a\xa9\x74\xd1\xbd\x80\xdf\xae\xba\xd7\x75\x38\x2c\x1e\x40\x17\x9b\x68\x9f\x0a\xa7\x36\xec\x52\x49\x6f\xa0\xfb\x0e\x39\x77\x4f\x4a\xa5\xf1\x78\x5c\x1c\xbf\x29\x32\x6f\x50\xea\xb6\x4b\x0f\x02\xd7\xa1\x58\x1c\xfd\xd4\x78\x6a\x65\xd1\xe2\x96\x7a\x8e\x94\x35\x4d\xe9\xf0\x89\x4d\xc0\x40\x6e\x25\x11\x0b\x53\x81\xb0\xb9\x68\x2f\x40\xa0\xf6\x11\xf7\x00\xf3\x44\xd0\x2b\x9a\x6c\x54\x12\x32\x0c\x02\xa7\x24\xd1\x19\x66\x88\x4f\x93\xa2\x69\x53\x75\xf8\x18\xb5\xb8\x9b\x83\xeb\x5a\x17\xea\xd4\x24\x0e\x46\xc7\x1e\x5e\xec\x2b\x4a\x95\xb9\x13\x8f\x0e\x86\xe8\xb3\xe6\x3e\x1c\x1d\x1c\xfe\x02\xd7\x21\x46\x45\x69\x11\x6f\x44\x7d\x1f\x31\x02\xf5\x61\x48\x3c\xd2\x9b\xc0\x00\xe9\x60\xe8\x16\xc4\xee\x83\x00\xeb\x03\xb6\x2d\xde\x80\x14\x70\x2b\x8e\x4c\x4f\x00\x77\xe3\x3e\x2e\x60\x3d\x6e\x50\x91\x6c\xc0\x00\x13\x69\x28\x38\x93\x0f\x11\x8d\xcf\xfa\x1c\xf3\x50\x28\xa1\xe1\xfb\xcc\xa4\xc8\xa1\x05\x16\x33\x83\x11\x7a\xa6\x4c\x10\xb8\x27\xb2\x31\x25\xec\x71\x64\x5a\xed\x44\x2b\xd4\x7d\x49\xc4\x22\x86\xad\x60\xa2\x10\x63\xd3\x21\x99\x38\x59\xc0\xc5\x46\x09\x73\xa6\xd4\x42\x01\x70\x3b\x61\x07\x96\xe0\x61\x3a\x6c\xd3\x11\x8d\x28\x88\xe5\x52\x70\x5f\x41\xa4\xd8\x9d\x17\x24\x9f\x05\x18\x31\x8b\xf6\xc5\xdf\x44\x8a\xe5\x06\x3d\x8c\xc2\x61\x41\x6e\x50\x3c\xda\x0b\x38\x02\x7d\x01\x94\x7a\x2c\x08\x39\x4a\xcc\x03\x9f\xd8\xb6\x82\x18\x28\xf2\x2d\x65\x4d\xb8\x93\x73\x04\xeb\xae\x50\x28\x8f\x54\xe4\x0b\xc8\x78\x88\x56\xcd\x48\x42\x7d\xa5\x1f\x60\x9a\xf6\x87\x44\xae\xb1\x18\xaa\x4c\x52\x14\xde\x2c\x20\x62\x7a\x9f\xd9\x36\x1b\x0b\xd1\xb0\xa5\xb5\x68\xb4\xff\x95\x46\x36\x7a\xe2\x5e\x82\x19\xdb\x15\x93\x2e\xb2\x1a\xb2\x20\x0c\xe0\x26\x56\x8d\x86\xfc\x21\xee\x25\xa0\x47\x22\x85\x21\x5d\x54\xaf\x91\x12\xc7\x13\xe4\x45\x0f\xc5\xa9\x61\x83\x8b\xb9\x5b\xd0\x9b\x15\xb3\x88\xf4\xaf\x74\xe8\x34\x2f\xbb\x9f\x2a\x6d\x1d\x6a\x1d\x68\xb5\x9b\x1f\x6b\x17\xfa\x05\xa8\x95\x0e\x5e\xab\x05\xf8\x54\xeb\x5e\x35\x6f\xba\x80\x33\xda\x95\x46\xf7\x33\x34\x2f\xa1\xd2\xf8\x0c\xff\xa9\x35\x2e\x0a\xa0\xff\xd6\x6a\xeb\x9d\x0e\x34\xdb\x4a\xed\xba\x55\xaf\xe9\x08\xab\x35\xaa\xf5\x9b\x8b\x5a\xe3\x03\xbc\xc7\x75\x8d\x26\xba\x70\x0d\x7d\x17\x91\x76\x9b\x20\x08\x46\xa8\x6a\x7a\x47\x20\xbb\xd6\xdb\xd5\x2b\xbc\xac\xbc\xaf\xd5\x6b\xdd\xcf\x05\xe5\xb2\xd6\x6d\x08\x9c\x97\xcd\x36\x54\xa0\x55\x69\x77\x6b\xd5\x9b\x7a\xa5\x8d\x81\xdd\x6e\x35\x3b\x3a\x92\xbf\x40\xb4\x8d\x5a\xe3\xb2\x8d\x54\xf4\x6b\xbd\xd1\x2d\x22\x55\x84\x81\xfe\x11\x2f\xa0\x73\x55\xa9\xd7\x05\x29\xa5\x72\x83\xdc\xb7\x05\x7f\x50\x6d\xb6\x3e\xb7\x6b\x1f\xae\xba\x70\xd5\xac\x5f\xe8\x08\x7c\xaf\x23\x67\x95\xf7\x75\x3d\x24\x85\x42\x55\xeb\x95\xda\x75\x01\x2e\x2a\xd7\x95\x0f\xba\x5c\xd5\x44\x2c\x6d\x45\x4c\x0b\xb9\x83\x4f\x57\xba\x00\x09\x7a\x15\xfc\xb7\xda\xad\x35\x1b\x42\x8c\x6a\xb3\xd1\x6d\xe3\x65\x01\xa5\x6c\x77\xe3\xa5\x9f\x6a\x1d\xbd\x00\x95\x76\xad\x23\x14\x72\xd9\x6e\x5e\x17\x14\xa1\x4e\x5c\xd1\x94\x48\x70\x5d\x43\x0f\xb1\x08\x55\x43\xc6\x22\x38\x45\x5c\xdf\x74\xf4\x18\x21\x5c\xe8\x95\x3a\xe2\xea\x88\xc5\x42\xc4\xe9\xe4\xa2\xa2\x69\x98\x91\x64\x0a\x7c\x18\xd9\x8e\x5f\xce\x49\x6c\x87\xc7\xc7\xc7\x61\x3e\x53\xd7\x9b\xe4\x8b\xe4\x56\x56\xfb\xcc\xe1\x5a\xdf\x18\x51\x7b\x72\x02\x3f\x5f\x11\xac\x6a\xa2\xa7\x81\x06\x09\xc8\xcf\x05\x88\x01\x28\xaa\x87\x2e\x87\xee\x8f\xc9\x4d\xc3\x1d\x34\xed\x9f\x42\x8f\x3d\x68\x3e\xfd\x53\x94\x6b\xfc\xed\x61\x82\xd4\x10\x74\x0a\x12\x29\x0e\x90\x13\x38\xfc\xc5\x45\x00\xb6\x04\x03\xea\x9c\xc0\xc1\xa9\xc8\xad\x43\x62\x58\xcf\x49\x7f\x44\xb8\x01\xa2\xd6\x96\xb1\x70\x92\xb1\x88\x22\x55\x44\x2f\xc7\xa4\x57\x56\xc7\xd4\xe2\xc3\xb2\x45\xb0\xa6\x12\x4d\x5e\x3c\x9f\xb2\xa0\x34\x65\x57\x18\x53\x23\x5f\x03\x7a\x5f\x56\xab\x21\xab\x5a\x77\xe2\x92\x14\xe3\xa2\x5b\x29\x09\xe3\x9e\xca\x4a\xe0\x13\x5e\xbe\xe9\x5e\x6a\xbf\x3e\x33\xfb\xf2\x76\xc3\xf3\x99\x7b\x59\x2f\x72\x56\x92\xcc\x9d\x2b\xca\x59\x49\x38\xa5\xf8\xd1\x63\xd6\x04\x28\x2e\xf1\x31\xe7\x22\xc7\xaa\xbc\xe0\x13\xf1\x3b\x8a\x28\xdf\x1c\x62\x55\x97\x11\xa5\x8b\xea\x7e\x3d\xed\xb1\x37\x2a\xa4\x36\x26\xbd\x3b\x8a\x84\xe4\xc0\x88\x31\xac\x29\x62\x51\x58\x1b\xa8\xe1\x13\x2b\x99\x24\x7c\x43\xae\xd6\x0c\xeb\x4b\xe0\xf3\x13\xac\x38\x0e\x39\xc5\x56\x42\x54\x26\x44\x79\x70\xf0\xd3\x29\x16\x65\x87\x68\x31\xa8\xf8\x8e\x8c\x4e\x41\x46\x40\x38\x01\x7e\xa0\x23\x11\x2c\x48\x01\xf9\x34\xcc\xbb\x81\xc7\x02\xc7\xd2\x4c\x66\x33\xef\x04\x5e\xf5\xdf\x89\x3f\x69\xf5\x83\x6b\x58\x96\xe4\x4a\x78\x43\x6f\x20\x67\x96\xd5\x68\xa6\x2a\xf4\xcd\x8d\xde\xa6\xdd\x23\x25\xd2\x9a\x72\xe4\xf2\x0e\x70\xc6\xbd\x67\xcc\x63\x00\x82\x83\x0d\x67\x52\xdc\x0f\xcb\x3d\xaf\x86\x2e\x36\x40\x4e\x38\x73\xb3\x8a\xba\x97\x03\x98\x8d\x98\xab\x9e\x63\x80\x59\x09\xa3\x61\x66\x55\xdf\x1d\x1c\xa8\x5b\xc0\x74\xb4\xe9\xc2\xa5\x36\x33\xef\x32\xbe\x3d\x32\x1e\xb4\xc8\x49\x90\x59\xf7\x21\x33\x68\xda\xc4\xf0\x04\x41\x3e\xcc\xc0\x17\x05\x4a\xac\x1c\x30\x02\xce\x66\x42\x22\xa3\x2d\xa9\x28\x54\x95\x45\xef\x37\xed\x56\x59\x79\x67\x95\xb3\x5c\x88\x29\xdf\xc2\xc8\x32\x98\x23\x3b\x0b\x4d\x60\x79\xc2\x6e\x3c\x9a\x5d\x56\x0f\xc2\x6b\xdf\x35\xcc\xe9\xf5\x46\x05\x8d\x06\x3d\xc3\xa2\x81\x7f\x02\x6f\x24\x2c\x27\x01\xf4\xfb\x99\x2c\x16\x2e\x43\x24\xe8\x0a\xb8\xf1\xa7\x16\xbc\x22\xc7\xe2\x4f\x36\x31\xf4\xfb\x29\x5d\x6c\x43\x76\x48\x38\xd9\x5c\x96\x78\xb7\x30\xe0\x32\xda\x95\x4b\xc6\x51\xa9\x79\x7b\x80\x4a\x96\x25\x2a\x9a\x8f\x1b\x3a\x4e\xbc\x3c\x7b\xc9\xff\x0e\xa4\x51\xe6\xec\x36\xf3\xbc\xb4\x2a\xa0\xc9\xf3\xd2\xe4\x66\xd4\x2b\xfd\xdd\xdb\xa3\xa3\x6a\x7c\x27\x22\xbf\x62\x1d\x89\x40\x50\x21\x0a\xd0\x90\xa3\x94\xb9\x9f\x4a\x2c\x3f\xe6\xa7\xff\x24\x8f\xc7\xe3\xe7\xe2\xe1\x83\xb6\xdc\x1b\x5a\xfb\x70\x88\x13\xfc\x18\x37\x6a\xd5\x4b\x1e\x27\x2e\x39\x86\x30\x43\x15\xd2\x0f\x24\xcb\x99\xc7\xb0\x73\xd3\xa2\x9b\x37\x19\xf7\x8a\xb3\x7c\x7c\xed\xed\x02\x61\x9d\x72\x99\x78\xdb\x61\xe8\x6d\xcb\x7c\x63\xeb\xb3\xeb\x42\xb5\x6f\x97\x13\x6c\xbb\x2b\x60\x76\x9b\x26\x9f\x65\xee\x10\x89\x81\x5b\x43\x8f\xf4\x65\x3e\x5a\xf9\x24\x64\xc3\xfe\x30\x4d\xf8\x97\x97\x97\x51\x7a\xb7\x88\xc9\x3c\x79\xd7\x6f\xba\x01\xc9\x6c\x39\x8e\xc4\x86\x23\x53\x19\x7a\xcc\xb6\xf2\x4b\x83\x19\x78\xbe\xc0\xee\x32\x1a\x02\xe2\x96\x85\x3a\x12\x69\xd4\xb9\xcc\x94\x90\xb7\x82\x31\x89\x4f\xde\xa6\xc5\x84\x39\x42\x9c\x86\x4b\x39\xe2\xff\x93\xe4\xb6\x03\x6f\x7e\xf9\x95\x58\x46\x4e\x47\x30\x37\x23\x02\x4b\x2d\x9f\x84\xad\x42\x0c\x8c\xfb\x43\x2c\x60\xa1\x79\xcf\x3f\x52\x32\x16\x77\xf8\x56\xde\x99\x3f\x2b\x19\xb9\x3e\x3c\x93\x78\xf3\xd3\x6f\x9c\xba\xe7\x1e\xe3\xec\x82\x74\x43\x41\xea\x73\x8f\x39\x83\xe7\x53\xed\xef\x8b\x8f\xdd\xfd\x11\x3d\x70\x2b\x40\x7a\x52\xfc\x80\x2b\x99\x36\x05\x9d\x95\x42\x69\xce\xcf\x7a\xde\xb3\xde\x59\xca\xd3\x74\xfe\x89\xab\x97\x79\xdc\xea\x99\xf5\x9b\xdf\xeb\x3d\xa6\xee\x64\x8f\x7f\x3d\x53\xf1\x99\xa6\xe6\xb9\xfa\x83\x19\x9e\x78\xa2\x52\x64\x43\x45\xe6\x64\x23\x3e\xdf\x26\x32\xef\xf6\x39\xfa\x23\x13\x7f\xb4\x15\x59\x50\x13\x16\x3f\x95\xdf\x55\x88\x5d\x85\x48\x2a\x44\x9c\xf8\xbf\x83\x43\xe6\x27\x96\xd4\xa9\xe1\xd9\xb3\x1d\x3b\x3f\xfc\xff\xf0\xc3\xf0\x76\xc5\x0b\xe9\x31\x16\xbd\xe2\xb1\xe0\x4c\xfb\x56\x17\xf4\xd9\x5a\x90\x1c\x11\x0b\x2b\xc1\xb3\x7b\x46\x8a\xa3\x6d\x71\x8f\x95\x1a\x5d\xf9\x0e\xc4\x0b\x75\x96\x74\xf7\x37\xfb\x52\xc6\x0b\xe9\xf3\xc2\xd7\x4a\xfe\x19\xed\x5d\x74\x68\x6f\xbd\xf6\x2e\x7d\x08\x32\xd7\xbc\xbb\xae\x70\x6b\xaa\xf1\x16\x56\xbf\xb3\xe1\x16\xf2\xf4\xa2\x23\x78\x59\x47\xbc\x0b\xac\x7f\xfe\x76\x6b\x9d\x7b\x6d\x9b\xdb\x72\xa5\x8f\xb6\xef\xbc\x71\xb7\xe9\xda\x6d\xba\x76\x9b\xae\xdd\xa6\x6b\xb7\xe9\xda\x6d\xba\xd6\xad\xa7\xb9\x23\x88\x47\x9c\xde\x38\x7f\xc4\xc1\x99\x78\x49\x02\xd9\xf8\xc9\xc0\xcc\x51\xd9\xd4\xc9\xc7\xc4\x05\x8e\x8f\x8f\x97\x9d\x9f\xca\x9e\x03\x9a\x3f\xc0\xb2\x2d\xe7\x82\xb6\xa7\xb1\xd9\x64\x53\x73\xb4\xb0\xa9\xc9\x3d\x72\xb1\xca\xe4\xa9\xae\x67\xe6\xd8\x5c\xf6\x54\x70\x3a\x91\x65\xbf\xdf\xa3\x6e\x56\xf4\x8c\x44\x6b\x27\x31\xf1\xa1\x8f\xde\x64\xbd\x53\x1b\xf3\x59\x65\xee\x74\xdc\x6c\x66\x38\x2b\x61\x98\x9f\x87\xff\x57\xb2\x69\xe2\x85\x1c\xf7\x0e\x45\x4c\xf2\xd7\x59\x49\xbc\x55\x21\x20\xe2\xf5\x94\x73\x45\xc9\x7f\xd3\xd4\x0d\xfc\x21\x43\x8a\x2b\xbf\x05\xb2\xc6\x47\x12\x66\x51\xbd\x90\xf7\xa0\xbf\xcf\x6b\xd0\xeb\xbf\x05\xfd\xfd\x5e\x82\xce\xfb\x10\xcf\x1a\x76\x49\x3e\x02\xf2\xc4\x6f\x4c\xcc\x22\x7c\xc2\x7b\xfc\xf1\x8b\xfa\x87\xcb\xde\xd3\x3f\x58\xf8\x9a\xbe\x76\x98\x18\xf8\x68\xe9\x1b\xfb\x99\x8f\x13\xa1\x7f\xdc\x59\x6c\xec\x2c\xfb\x44\x93\xa2\xc1\xeb\xd7\xc2\x61\xa3\x5d\x8f\x9c\xea\x84\xbd\xab\x1c\x58\xd9\xec\x62\x38\xce\x7f\x6f\x28\x6e\x78\x97\x71\xbb\x84\xd9\xe9\xf7\x3b\x36\x12\x10\xaf\x5f\x67\x43\xe2\xf5\xeb\x59\x17\xcd\xd5\xe4\x92\xc0\x58\x2b\x2e\x62\xb2\x71\x64\x3c\x92\x70\x6e\x7c\x28\xbf\xaf\x7d\xe8\xef\x8f\xbd\x75\xa2\x62\x7f\x85\x9f\x89\xca\xcc\x0d\xfb\xee\x3b\x24\xd5\x39\x54\xa9\xa4\xfa\x0a\xff\x81\x65\x18\x97\xab\xce\x5f\xf6\x05\x02\x1b\xa7\x7e\x07\xf6\x33\x68\x16\x7f\x30\x66\x05\x63\xff\x05\x98\xca\xe0\x1d\xc7\x50\x00\x00")
 
 func templateDefaultTmplBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +83,7 @@ func templateDefaultTmpl() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "template/default.tmpl", size: 16571, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	info := bindataFileInfo{name: "template/default.tmpl", size: 20679, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -0,0 +1,118 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// maxQueryResponseSize is the maximum number of bytes read from a
+// query response.
+const maxQueryResponseSize = 1 << 20
+
+// QueryOptions configures the Prometheus server queried by the query
+// template function.
+type QueryOptions struct {
+	URL     *url.URL
+	Timeout time.Duration
+	// MaxSamples is the maximum number of samples returned by a query.
+	// Further samples are dropped.
+	MaxSamples int
+	// DryRun makes queries return no samples without contacting the
+	// server, e.g. to check templates.
+	DryRun bool
+}
+
+// Sample is a single element of a query result.
+type Sample struct {
+	Labels KV
+	Value  float64
+}
+
+// query executes an instant query against the configured Prometheus server.
+// Scalar results are returned as a single sample without labels.
+func (t *Template) query(q string) ([]Sample, error) {
+	o := t.Query
+	if o != nil && o.DryRun {
+		return nil, nil
+	}
+	if o == nil || o.URL == nil {
+		return nil, fmt.Errorf("query: no Prometheus server configured")
+	}
+	u := *o.URL
+	u.Path = path.Join(u.Path, "/api/v1/query")
+	u.RawQuery = url.Values{"query": {q}}.Encode()
+
+	client := &http.Client{Timeout: o.Timeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("query %q: %s", q, err)
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxQueryResponseSize)).Decode(&res); err != nil {
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("query %q: unexpected status code %d", q, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("query %q: decoding response failed: %s", q, err)
+	}
+	if res.Status != "success" {
+		return nil, fmt.Errorf("query %q failed: %s", q, res.Error)
+	}
+
+	var v model.Vector
+	switch res.Data.ResultType {
+	case "vector":
+		if err := json.Unmarshal(res.Data.Result, &v); err != nil {
+			return nil, fmt.Errorf("query %q: decoding vector failed: %s", q, err)
+		}
+	case "scalar":
+		var s model.Scalar
+		if err := json.Unmarshal(res.Data.Result, &s); err != nil {
+			return nil, fmt.Errorf("query %q: decoding scalar failed: %s", q, err)
+		}
+		v = model.Vector{{Metric: model.Metric{}, Value: s.Value, Timestamp: s.Timestamp}}
+	default:
+		return nil, fmt.Errorf("query %q: unsupported result type %q", q, res.Data.ResultType)
+	}
+
+	if o.MaxSamples > 0 && len(v) > o.MaxSamples {
+		v = v[:o.MaxSamples]
+	}
+	samples := make([]Sample, 0, len(v))
+	for _, s := range v {
+		lbls := make(KV, len(s.Metric))
+		for ln, lv := range s.Metric {
+			lbls[string(ln)] = string(lv)
+		}
+		samples = append(samples, Sample{Labels: lbls, Value: float64(s.Value)})
+	}
+	return samples, nil
+}
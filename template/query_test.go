@@ -0,0 +1,170 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	const (
+		vector = `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"job":"a"},"value":[1500000000,"1"]},
+			{"metric":{"job":"b"},"value":[1500000000,"2"]}
+		]}}`
+		scalar = `{"status":"success","data":{"resultType":"scalar","result":[1500000000,"42"]}}`
+	)
+
+	cases := []struct {
+		name       string
+		status     int
+		body       string
+		opts       QueryOptions
+		noURL      bool
+		expected   []Sample
+		errMatches string
+	}{
+		{
+			name: "vector",
+			body: vector,
+			expected: []Sample{
+				{Labels: KV{"job": "a"}, Value: 1},
+				{Labels: KV{"job": "b"}, Value: 2},
+			},
+		},
+		{
+			name:     "scalar",
+			body:     scalar,
+			expected: []Sample{{Labels: KV{}, Value: 42}},
+		},
+		{
+			name:     "max samples",
+			body:     vector,
+			opts:     QueryOptions{MaxSamples: 1},
+			expected: []Sample{{Labels: KV{"job": "a"}, Value: 1}},
+		},
+		{
+			name:     "empty vector",
+			body:     `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			expected: []Sample{},
+		},
+		{
+			name:       "query error",
+			status:     http.StatusBadRequest,
+			body:       `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			errMatches: `query "up" failed: parse error`,
+		},
+		{
+			name:       "unexpected status code",
+			status:     http.StatusBadGateway,
+			body:       "bad gateway",
+			errMatches: `query "up": unexpected status code 502`,
+		},
+		{
+			name:       "invalid response",
+			body:       "{",
+			errMatches: `query "up": decoding response failed`,
+		},
+		{
+			name:       "unsupported result type",
+			body:       `{"status":"success","data":{"resultType":"matrix","result":[]}}`,
+			errMatches: `query "up": unsupported result type "matrix"`,
+		},
+		{
+			name:  "dry run",
+			body:  vector,
+			opts:  QueryOptions{DryRun: true},
+			noURL: true,
+		},
+		{
+			name:       "no server",
+			noURL:      true,
+			errMatches: "query: no Prometheus server configured",
+		},
+	}
+
+	for _, c := range cases {
+		var queried *url.URL
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			queried = r.URL
+			if c.status != 0 {
+				w.WriteHeader(c.status)
+			}
+			fmt.Fprint(w, c.body)
+		}))
+
+		opts := c.opts
+		opts.Timeout = time.Second
+		if !c.noURL {
+			u, err := url.Parse(srv.URL + "/prometheus")
+			require.NoError(t, err)
+			opts.URL = u
+		}
+		tmpl := &Template{Query: &opts}
+
+		samples, err := tmpl.query("up")
+		srv.Close()
+
+		if c.errMatches != "" {
+			require.Error(t, err, c.name)
+			require.Contains(t, err.Error(), c.errMatches, c.name)
+			continue
+		}
+		require.NoError(t, err, c.name)
+		require.Equal(t, c.expected, samples, c.name)
+		if c.noURL {
+			require.Nil(t, queried, c.name)
+			continue
+		}
+		require.Equal(t, "/prometheus/api/v1/query", queried.Path, c.name)
+		require.Equal(t, "up", queried.Query().Get("query"), c.name)
+	}
+}
+
+func TestQueryFunc(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"job":"a"},"value":[1500000000,"1"]}
+		]}}`)
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	tmpl, err := FromGlobs()
+	require.NoError(t, err)
+	tmpl.Query = &QueryOptions{URL: u, Timeout: time.Second}
+
+	const text = `{{ range query "up" }}{{ .Labels.job }}={{ .Value }}{{ end }}`
+
+	s, err := tmpl.ExecuteTextString(text, nil)
+	require.NoError(t, err)
+	require.Equal(t, "a=1", s)
+
+	s, err = tmpl.ExecuteHTMLString(text, nil)
+	require.NoError(t, err)
+	require.Equal(t, "a=1", s)
+
+	tmpl.Query = nil
+	_, err = tmpl.ExecuteTextString(text, nil)
+	require.Error(t, err)
+}
@@ -37,6 +37,24 @@ type Template struct {
 	html *tmplhtml.Template
 
 	ExternalURL *url.URL
+	// Themes holds the presentation settings by value of the severity label.
+	Themes map[string]Theme
+	// Query configures the query function. Executing templates that call
+	// it fails if it is nil.
+	Query *QueryOptions
+	// SummaryThreshold is the number of alerts above which notifications are
+	// summarized instead of listing every alert. Zero disables summarization.
+	SummaryThreshold int
+	// Severities normalizes values of the severity label.
+	Severities types.SeverityMap
+}
+
+// Theme holds settings for how notifications about alerts of a certain
+// severity are presented.
+type Theme struct {
+	Color    string
+	Emoji    string
+	Priority string
 }
 
 // FromGlobs calls ParseGlob on all path globs provided and returns the
@@ -51,6 +69,9 @@ func FromGlobs(paths ...string) (*Template, error) {
 	t.text = t.text.Funcs(tmpltext.FuncMap(DefaultFuncs))
 	t.html = t.html.Funcs(tmplhtml.FuncMap(DefaultFuncs))
 
+	t.text = t.text.Funcs(tmpltext.FuncMap{"query": t.query})
+	t.html = t.html.Funcs(tmplhtml.FuncMap{"query": t.query})
+
 	b, err := deftmpl.Asset("template/default.tmpl")
 	if err != nil {
 		return nil, err
@@ -81,6 +102,12 @@ func FromGlobs(paths ...string) (*Template, error) {
 	return t, nil
 }
 
+// Defined returns whether a template with the given name is defined by the
+// default templates or the parsed template files.
+func (t *Template) Defined(name string) bool {
+	return t.text.Lookup(name) != nil
+}
+
 // ExecuteTextString needs a meaningful doc comment (TODO(fabxc)).
 func (t *Template) ExecuteTextString(text string, data interface{}) (string, error) {
 	if text == "" {
@@ -229,6 +256,59 @@ type Data struct {
 	CommonAnnotations KV `json:"commonAnnotations"`
 
 	ExternalURL string `json:"externalURL"`
+
+	// Theme for the severity all alerts have in common.
+	Theme Theme `json:"-"`
+	// Severity is the highest normalized severity of the firing alerts,
+	// or of all alerts if none is firing.
+	Severity types.Severity `json:"-"`
+	// Summarized is set if the notification contains more alerts than the
+	// configured summary threshold.
+	Summarized bool `json:"-"`
+	// Escalated is set if the group kept firing for the number of repeat
+	// intervals its route escalates after.
+	Escalated bool `json:"escalated,omitempty"`
+}
+
+// LabelSummary describes how the values of a label are distributed
+// across a set of alerts.
+type LabelSummary struct {
+	Name string
+	// Values holds the most frequent values, most frequent first.
+	Values []ValueCount
+	// Distinct is the total number of distinct values of the label.
+	Distinct int
+}
+
+// ValueCount is the number of alerts having a label value.
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// Summary returns the distribution of the values of every label not common
+// to all alerts, sorted by label name. At most n values are returned per
+// label, all of them if n is not positive.
+func (d Data) Summary(n int) []LabelSummary {
+	names := map[string]struct{}{}
+	for _, a := range d.Alerts {
+		for ln := range a.Labels {
+			if _, ok := d.CommonLabels[ln]; !ok {
+				names[ln] = struct{}{}
+			}
+		}
+	}
+	res := make([]LabelSummary, 0, len(names))
+	for ln := range names {
+		vals := d.Alerts.CountByLabel(ln)
+		ls := LabelSummary{Name: ln, Values: vals, Distinct: len(vals)}
+		if n > 0 && len(vals) > n {
+			ls.Values = vals[:n]
+		}
+		res = append(res, ls)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
 }
 
 // Alert holds one alert for notification templates.
@@ -239,6 +319,9 @@ type Alert struct {
 	StartsAt     time.Time `json:"startsAt"`
 	EndsAt       time.Time `json:"endsAt"`
 	GeneratorURL string    `json:"generatorURL"`
+
+	Theme    Theme          `json:"-"`
+	Severity types.Severity `json:"-"`
 }
 
 // Alerts is a list of Alert objects.
@@ -266,6 +349,37 @@ func (as Alerts) Resolved() []Alert {
 	return res
 }
 
+// CountByLabel returns the number of alerts per value of the given label,
+// most frequent first. Alerts without the label are not counted.
+func (as Alerts) CountByLabel(name string) []ValueCount {
+	counts := map[string]int{}
+	for _, a := range as {
+		if v, ok := a.Labels[name]; ok {
+			counts[v]++
+		}
+	}
+	res := make([]ValueCount, 0, len(counts))
+	for v, c := range counts {
+		res = append(res, ValueCount{Value: v, Count: c})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Value < res[j].Value
+	})
+	return res
+}
+
+// theme returns the theme of the given severity label value. Values without
+// a theme of their own use the theme of their normalized severity.
+func (t *Template) theme(value string, sev types.Severity) Theme {
+	if th, ok := t.Themes[value]; ok {
+		return th
+	}
+	return t.Themes[sev.String()]
+}
+
 // Data assembles data for template expansion.
 func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*types.Alert) *Data {
 	data := &Data{
@@ -276,11 +390,14 @@ func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*type
 		CommonLabels:      KV{},
 		CommonAnnotations: KV{},
 		ExternalURL:       t.ExternalURL.String(),
+		Summarized:        t.SummaryThreshold > 0 && len(alerts) > t.SummaryThreshold,
 	}
 
 	// The call to types.Alert is necessary to correctly resolve the internal
 	// representation to the user representation.
+	var firingSeverity, resolvedSeverity types.Severity
 	for _, a := range types.Alerts(alerts...) {
+		sev := t.Severities.Severity(a.Labels)
 		alert := Alert{
 			Status:       string(a.Status()),
 			Labels:       make(KV, len(a.Labels)),
@@ -288,6 +405,15 @@ func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*type
 			StartsAt:     a.StartsAt,
 			EndsAt:       a.EndsAt,
 			GeneratorURL: a.GeneratorURL,
+			Theme:        t.theme(string(a.Labels[types.SeverityLabel]), sev),
+			Severity:     sev,
+		}
+		if a.Status() == model.AlertFiring {
+			if sev > firingSeverity {
+				firingSeverity = sev
+			}
+		} else if sev > resolvedSeverity {
+			resolvedSeverity = sev
 		}
 		for k, v := range a.Labels {
 			alert.Labels[string(k)] = string(v)
@@ -326,6 +452,13 @@ func (t *Template) Data(recv string, groupLabels model.LabelSet, alerts ...*type
 			data.CommonAnnotations[string(k)] = string(v)
 		}
 	}
+	data.Severity = firingSeverity
+	if len(data.Alerts.Firing()) == 0 {
+		data.Severity = resolvedSeverity
+	}
+	if sev, ok := data.CommonLabels[string(types.SeverityLabel)]; ok {
+		data.Theme = t.theme(sev, t.Severities.Severity(alerts[0].Labels))
+	}
 
 	return data
 }
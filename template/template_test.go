@@ -0,0 +1,114 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package template
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCountByLabel(t *testing.T) {
+	as := Alerts{
+		{Labels: KV{"instance": "b", "job": "x"}},
+		{Labels: KV{"instance": "a", "job": "x"}},
+		{Labels: KV{"instance": "c", "job": "y"}},
+		{Labels: KV{"instance": "a", "job": "y"}},
+		{Labels: KV{"instance": "b"}},
+		{Labels: KV{"instance": "a"}},
+	}
+
+	cases := []struct {
+		name     string
+		expected []ValueCount
+	}{
+		{
+			// Values with equal counts are sorted by value.
+			name:     "instance",
+			expected: []ValueCount{{"a", 3}, {"b", 2}, {"c", 1}},
+		},
+		{
+			// Alerts without the label are not counted.
+			name:     "job",
+			expected: []ValueCount{{"x", 2}, {"y", 2}},
+		},
+		{
+			name:     "missing",
+			expected: []ValueCount{},
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expected, as.CountByLabel(c.name), c.name)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	d := Data{
+		Alerts: Alerts{
+			{Labels: KV{"alertname": "Down", "instance": "a", "job": "x"}},
+			{Labels: KV{"alertname": "Down", "instance": "b", "job": "x"}},
+			{Labels: KV{"alertname": "Down", "instance": "c", "job": "y"}},
+			{Labels: KV{"alertname": "Down", "instance": "a", "zone": "eu"}},
+		},
+		CommonLabels: KV{"alertname": "Down"},
+	}
+
+	cases := []struct {
+		n        int
+		expected []LabelSummary
+	}{
+		{
+			n: 0,
+			expected: []LabelSummary{
+				{Name: "instance", Values: []ValueCount{{"a", 2}, {"b", 1}, {"c", 1}}, Distinct: 3},
+				{Name: "job", Values: []ValueCount{{"x", 2}, {"y", 1}}, Distinct: 2},
+				{Name: "zone", Values: []ValueCount{{"eu", 1}}, Distinct: 1},
+			},
+		},
+		{
+			// Only the most frequent values are returned but all distinct
+			// values are counted.
+			n: 1,
+			expected: []LabelSummary{
+				{Name: "instance", Values: []ValueCount{{"a", 2}}, Distinct: 3},
+				{Name: "job", Values: []ValueCount{{"x", 2}}, Distinct: 2},
+				{Name: "zone", Values: []ValueCount{{"eu", 1}}, Distinct: 1},
+			},
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expected, d.Summary(c.n), "n=%d", c.n)
+	}
+
+	require.Empty(t, Data{CommonLabels: KV{}}.Summary(0))
+}
+
+func TestDataSummarized(t *testing.T) {
+	tmpl := &Template{ExternalURL: &url.URL{}, SummaryThreshold: 2}
+
+	alerts := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"instance": "a"}}},
+		{Alert: model.Alert{Labels: model.LabelSet{"instance": "b"}}},
+		{Alert: model.Alert{Labels: model.LabelSet{"instance": "c"}}},
+	}
+	require.False(t, tmpl.Data("recv", nil, alerts[:2]...).Summarized)
+	require.True(t, tmpl.Data("recv", nil, alerts...).Summarized)
+
+	tmpl.SummaryThreshold = 0
+	require.False(t, tmpl.Data("recv", nil, alerts...).Summarized)
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prometheus/common/model"
+)
+
+// UTF8LabelNames allows label names consisting of any UTF-8 characters,
+// such as the dotted attribute names of OpenTelemetry, instead of only
+// classic Prometheus label names. It must be set before any configuration,
+// alerts or silences are processed.
+var UTF8LabelNames = false
+
+// IsValidLabelName returns whether ln is a valid label name.
+func IsValidLabelName(ln model.LabelName) bool {
+	if UTF8LabelNames {
+		return len(ln) > 0 && utf8.ValidString(string(ln))
+	}
+	return ln.IsValid()
+}
+
+// ValidateLabelSet checks whether the names and values of the label set
+// are valid.
+func ValidateLabelSet(ls model.LabelSet) error {
+	for ln, lv := range ls {
+		if !IsValidLabelName(ln) {
+			return fmt.Errorf("invalid name %q", ln)
+		}
+		if !lv.IsValid() {
+			return fmt.Errorf("invalid value %q", lv)
+		}
+	}
+	return nil
+}
+
+// quoteLabelName returns classic label names as is and quotes all
+// others.
+func quoteLabelName(ln string) string {
+	if model.LabelName(ln).IsValid() {
+		return ln
+	}
+	return strconv.Quote(ln)
+}
+
+// LabelSetString formats the label set like model.LabelSet.String, but
+// quotes names that are not classic label names to keep the result
+// unambiguous. It is used wherever label sets are encoded into keys.
+func LabelSetString(ls model.LabelSet) string {
+	lstrs := make([]string, 0, len(ls))
+	for ln, lv := range ls {
+		lstrs = append(lstrs, fmt.Sprintf("%s=%q", quoteLabelName(string(ln)), lv))
+	}
+
+	sort.Strings(lstrs)
+	return fmt.Sprintf("{%s}", strings.Join(lstrs, ", "))
+}
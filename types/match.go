@@ -24,10 +24,12 @@ import (
 )
 
 // Matcher defines a matching rule for the value of a given label.
+// A negative matcher matches iff the value does not match.
 type Matcher struct {
-	Name    string `json:"name"`
-	Value   string `json:"value"`
-	IsRegex bool   `json:"isRegex"`
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	IsRegex    bool   `json:"isRegex"`
+	IsNegative bool   `json:"isNegative"`
 
 	regex *regexp.Regexp
 }
@@ -45,15 +47,21 @@ func (m *Matcher) Init() error {
 }
 
 func (m *Matcher) String() string {
-	if m.IsRegex {
-		return fmt.Sprintf("%s=~%q", m.Name, m.Value)
-	}
-	return fmt.Sprintf("%s=%q", m.Name, m.Value)
+	op := "="
+	switch {
+	case m.IsRegex && m.IsNegative:
+		op = "!~"
+	case m.IsRegex:
+		op = "=~"
+	case m.IsNegative:
+		op = "!="
+	}
+	return fmt.Sprintf("%s%s%q", quoteLabelName(m.Name), op, m.Value)
 }
 
 // Validate returns true iff all fields of the matcher have valid values.
 func (m *Matcher) Validate() error {
-	if !model.LabelName(m.Name).IsValid() {
+	if !IsValidLabelName(model.LabelName(m.Name)) {
 		return fmt.Errorf("invalid name %q", m.Name)
 	}
 	if m.IsRegex {
@@ -74,10 +82,13 @@ func (m *Matcher) Match(lset model.LabelSet) bool {
 	// for the comparison below.
 	v := lset[model.LabelName(m.Name)]
 
+	var match bool
 	if m.IsRegex {
-		return m.regex.MatchString(string(v))
+		match = m.regex.MatchString(string(v))
+	} else {
+		match = string(v) == m.Value
 	}
-	return string(v) == m.Value
+	return match != m.IsNegative
 }
 
 // NewMatcher returns a new matcher that compares against equality of
@@ -130,7 +141,10 @@ func (ms Matchers) Less(i, j int) bool {
 	if ms[i].Value < ms[j].Value {
 		return true
 	}
-	return !ms[i].IsRegex && ms[j].IsRegex
+	if ms[i].IsRegex != ms[j].IsRegex {
+		return !ms[i].IsRegex
+	}
+	return !ms[i].IsNegative && ms[j].IsNegative
 }
 
 // Equal returns whether both Matchers are equal.
@@ -0,0 +1,75 @@
+// Copyright 2017 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// SeverityLabel is the label holding the severity of an alert.
+const SeverityLabel model.LabelName = "severity"
+
+// Severity is a position on the canonical severity scale. Higher values
+// are more severe.
+type Severity int
+
+// The canonical severities in ascending order.
+const (
+	SeverityUnknown Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var severityNames = []string{"unknown", "info", "warning", "error", "critical"}
+
+func (s Severity) String() string {
+	if s < SeverityUnknown || s > SeverityCritical {
+		return severityNames[SeverityUnknown]
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity returns the canonical severity with the given name. Names
+// are matched case-insensitively.
+func ParseSeverity(s string) (Severity, error) {
+	for i, n := range severityNames {
+		if strings.EqualFold(s, n) {
+			return Severity(i), nil
+		}
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q", s)
+}
+
+// SeverityMap maps values of the severity label to canonical severities.
+type SeverityMap map[string]Severity
+
+// Severity returns the canonical severity of an alert with the given labels.
+// Severity label values not in the map are resolved if they are the name
+// of a canonical severity.
+func (m SeverityMap) Severity(lset model.LabelSet) Severity {
+	v, ok := lset[SeverityLabel]
+	if !ok {
+		return SeverityUnknown
+	}
+	if s, ok := m[string(v)]; ok {
+		return s
+	}
+	s, _ := ParseSeverity(string(v))
+	return s
+}
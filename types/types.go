@@ -14,6 +14,7 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -36,6 +37,8 @@ type AlertStatus struct {
 	State       AlertState `json:"state"`
 	SilencedBy  []string   `json:"silencedBy"`
 	InhibitedBy []string   `json:"inhibitedBy"`
+	// FlapScore is the number of recent firing/resolved transitions.
+	FlapScore int `json:"flapScore,omitempty"`
 }
 
 // Marker helps to mark alerts as silenced and/or inhibited.
@@ -263,6 +266,125 @@ type Alert struct {
 	// The authoritative timestamp.
 	UpdatedAt time.Time
 	Timeout   bool
+	// ForceResolved is set if the alert was resolved through the API
+	// rather than by its source.
+	ForceResolved bool
+	// Seq is the sequence number the alert provider assigned to this
+	// version of the alert when it was stored.
+	Seq uint64
+	// TraceParent is the W3C trace context the alert was received with.
+	TraceParent string `json:"-"`
+	// Enrichment holds the annotations attached to the alert through the
+	// API rather than by its source. It is never decoded from alerts sent
+	// by clients, which must not be able to forge it.
+	Enrichment *Enrichment `json:"-"`
+}
+
+// Enrichment holds annotations attached to an alert by others than its
+// source, such as bots adding triage information. They override the
+// annotations of the alert, also after its source updates it.
+type Enrichment struct {
+	Annotations model.LabelSet `json:"annotations"`
+	// Sources maps the names of the annotations to the source that
+	// attached them.
+	Sources map[model.LabelName]string `json:"sources"`
+}
+
+// Size returns the total length of the names and values of the
+// annotations.
+func (e *Enrichment) Size() int {
+	var n int
+	if e != nil {
+		for ln, lv := range e.Annotations {
+			n += len(ln) + len(lv)
+		}
+	}
+	return n
+}
+
+// Enrich returns a copy of the alert with the annotations attached by the
+// source. Annotations with empty values are removed from the alert.
+func (a *Alert) Enrich(source string, annotations model.LabelSet) *Alert {
+	e := &Enrichment{
+		Annotations: model.LabelSet{},
+		Sources:     map[model.LabelName]string{},
+	}
+	if a.Enrichment != nil {
+		for ln, lv := range a.Enrichment.Annotations {
+			e.Annotations[ln] = lv
+			e.Sources[ln] = a.Enrichment.Sources[ln]
+		}
+	}
+	res := *a
+	res.Annotations = a.Annotations.Clone()
+
+	for ln, lv := range annotations {
+		if lv == "" {
+			delete(e.Annotations, ln)
+			delete(e.Sources, ln)
+			delete(res.Annotations, ln)
+			continue
+		}
+		e.Annotations[ln] = lv
+		e.Sources[ln] = source
+	}
+	return res.WithEnrichment(e)
+}
+
+// WithEnrichment returns a copy of the alert carrying the enrichment, whose
+// annotations override the ones of the alert.
+func (a *Alert) WithEnrichment(e *Enrichment) *Alert {
+	res := *a
+	res.Enrichment = e
+	if e != nil {
+		res.Annotations = make(model.LabelSet, len(a.Annotations)+len(e.Annotations))
+		for ln, lv := range a.Annotations {
+			res.Annotations[ln] = lv
+		}
+		for ln, lv := range e.Annotations {
+			res.Annotations[ln] = lv
+		}
+	}
+	return &res
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Unlike for
+// model.Alert, label names are not checked while decoding but by Validate.
+func (a *Alert) UnmarshalJSON(b []byte) error {
+	type plain Alert
+	v := struct {
+		*plain
+		Labels      map[model.LabelName]model.LabelValue `json:"labels"`
+		Annotations map[model.LabelName]model.LabelValue `json:"annotations"`
+	}{plain: (*plain)(a)}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	a.Labels = model.LabelSet(v.Labels)
+	a.Annotations = model.LabelSet(v.Annotations)
+	return nil
+}
+
+// Validate checks the alert like model.Alert.Validate, but accepts the
+// label names allowed by IsValidLabelName.
+func (a *Alert) Validate() error {
+	if a.StartsAt.IsZero() {
+		return fmt.Errorf("start time missing")
+	}
+	if !a.EndsAt.IsZero() && a.EndsAt.Before(a.StartsAt) {
+		return fmt.Errorf("start time must be before end time")
+	}
+	if err := ValidateLabelSet(a.Labels); err != nil {
+		return fmt.Errorf("invalid label set: %s", err)
+	}
+	if len(a.Labels) == 0 {
+		return fmt.Errorf("at least one label pair required")
+	}
+	if err := ValidateLabelSet(a.Annotations); err != nil {
+		return fmt.Errorf("invalid annotations: %s", err)
+	}
+	return nil
 }
 
 // AlertSlice is a sortable slice of Alerts.
@@ -299,14 +421,19 @@ func (a *Alert) Merge(o *Alert) *Alert {
 
 	res := *o
 
+	if res.TraceParent == "" {
+		res.TraceParent = a.TraceParent
+	}
+
 	// Always pick the earliest starting time.
 	if a.StartsAt.Before(o.StartsAt) {
 		res.StartsAt = a.StartsAt
 	}
 
 	// A non-timeout resolved timestamp always rules.
-	// The latest explicit resolved timestamp wins.
-	if a.EndsAt.After(o.EndsAt) && !a.Timeout {
+	// The latest explicit resolved timestamp wins unless the alert
+	// was forcefully resolved.
+	if a.EndsAt.After(o.EndsAt) && !a.Timeout && !o.ForceResolved {
 		res.EndsAt = a.EndsAt
 	}
 
@@ -351,6 +478,19 @@ type Silence struct {
 	CreatedBy string `json:"createdBy"`
 	Comment   string `json:"comment,omitempty"`
 
+	// The authenticated principal and the client that created the
+	// silence through the API, if known. Both are set by the server.
+	Principal string `json:"principal,omitempty"`
+	Source    string `json:"source,omitempty"`
+
+	// The team owning the silence. It can only be changed by transferring
+	// the silence after it was created.
+	Team string `json:"team,omitempty"`
+
+	// The schedule by which the silence recurs within its time range. The
+	// silence is only active during the windows of the schedule if set.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+
 	// timeFunc provides the time against which to evaluate
 	// the silence. Used for test injection.
 	now func() time.Time
@@ -358,6 +498,20 @@ type Silence struct {
 	Status SilenceStatus `json:"status"`
 }
 
+// Recurrence is a schedule of windows recurring on days of the week.
+type Recurrence struct {
+	// Lowercase names of the days of the week on which a window starts,
+	// such as "saturday". Every day if empty.
+	Weekdays []string `json:"weekdays,omitempty"`
+	// Time of day at which the windows start in 24-hour HH:MM notation.
+	StartTime string `json:"startTime"`
+	// Length of the windows, such as "4h".
+	Duration string `json:"duration"`
+	// Name of the time zone the schedule is defined in, such as
+	// "Europe/Berlin". UTC if empty.
+	Location string `json:"location,omitempty"`
+}
+
 type SilenceStatus struct {
 	State SilenceState `json:"state"`
 }
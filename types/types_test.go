@@ -14,6 +14,7 @@
 package types
 
 import (
+	"encoding/json"
 	"reflect"
 	"regexp"
 	"testing"
@@ -41,6 +42,36 @@ func TestMatcher(t *testing.T) {
 	}
 }
 
+func TestNegativeMatcher(t *testing.T) {
+	m := &Matcher{Name: "foo", Value: "bar", IsNegative: true}
+	if err := m.Init(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.String() != "foo!=\"bar\"" {
+		t.Errorf("unexpected matcher string %#v", m.String())
+	}
+	if m.Match(model.LabelSet{"foo": "bar"}) {
+		t.Errorf("negative matcher unexpectedly matched equal value")
+	}
+	if !m.Match(model.LabelSet{"foo": "baz"}) || !m.Match(model.LabelSet{}) {
+		t.Errorf("negative matcher did not match different value")
+	}
+
+	m = &Matcher{Name: "foo", Value: "b.*", IsRegex: true, IsNegative: true}
+	if err := m.Init(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.String() != "foo!~\"b.*\"" {
+		t.Errorf("unexpected matcher string %#v", m.String())
+	}
+	if m.Match(model.LabelSet{"foo": "bar"}) {
+		t.Errorf("negative regex matcher unexpectedly matched")
+	}
+	if !m.Match(model.LabelSet{"foo": "qux"}) {
+		t.Errorf("negative regex matcher did not match")
+	}
+}
+
 func TestMatchers(t *testing.T) {
 	m1 := NewMatcher("foo", "bar")
 
@@ -98,3 +129,104 @@ func TestAlertMerge(t *testing.T) {
 		}
 	}
 }
+
+func TestAlertEnrich(t *testing.T) {
+	a := &Alert{
+		Alert: model.Alert{
+			Annotations: model.LabelSet{"summary": "disk full", "runbook": "http://example.com"},
+		},
+	}
+	res := a.Enrich("bot", model.LabelSet{"summary": "disk full on db-1", "ticket": "OPS-1"})
+	res = res.Enrich("oncall", model.LabelSet{"ticket": "OPS-2", "runbook": ""})
+
+	if exp := (model.LabelSet{"summary": "disk full on db-1", "ticket": "OPS-2"}); !reflect.DeepEqual(exp, res.Annotations) {
+		t.Errorf("unexpected annotations %v", res.Annotations)
+	}
+	if exp := (map[model.LabelName]string{"summary": "bot", "ticket": "oncall"}); !reflect.DeepEqual(exp, res.Enrichment.Sources) {
+		t.Errorf("unexpected sources %v", res.Enrichment.Sources)
+	}
+	if n := res.Enrichment.Size(); n != len("summarydisk full on db-1ticketOPS-2") {
+		t.Errorf("unexpected size %d", n)
+	}
+	if len(a.Annotations) != 2 || a.Enrichment != nil {
+		t.Errorf("original alert was modified: %#v", a)
+	}
+
+	// Annotations of the alert are overridden after it is updated.
+	u := &Alert{Alert: model.Alert{Annotations: model.LabelSet{"summary": "disk full"}}}
+	if s := u.WithEnrichment(res.Enrichment).Annotations["summary"]; s != "disk full on db-1" {
+		t.Errorf("unexpected summary %q", s)
+	}
+}
+
+func TestSeverityMap(t *testing.T) {
+	m := SeverityMap{"p1": SeverityCritical, "Sev2": SeverityError}
+
+	tests := []struct {
+		lset model.LabelSet
+		sev  Severity
+	}{
+		{model.LabelSet{"severity": "p1"}, SeverityCritical},
+		{model.LabelSet{"severity": "Sev2"}, SeverityError},
+		{model.LabelSet{"severity": "Warning"}, SeverityWarning},
+		{model.LabelSet{"severity": "info"}, SeverityInfo},
+		{model.LabelSet{"severity": "p9"}, SeverityUnknown},
+		{model.LabelSet{}, SeverityUnknown},
+	}
+	for _, test := range tests {
+		if sev := m.Severity(test.lset); sev != test.sev {
+			t.Errorf("expected severity %s for %v, got %s", test.sev, test.lset, sev)
+		}
+	}
+
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Errorf("expected error parsing unknown severity")
+	}
+	if SeverityCritical.String() != "critical" {
+		t.Errorf("unexpected severity name %q", SeverityCritical.String())
+	}
+}
+
+func TestUTF8LabelNames(t *testing.T) {
+	defer func() { UTF8LabelNames = false }()
+
+	in := `{"labels":{"service.name":"api","alertname":"Down"},"annotations":{"ünïcode":"x"},"startsAt":"2017-01-01T00:00:00Z"}`
+
+	var a Alert
+	if err := json.Unmarshal([]byte(in), &a); err != nil {
+		t.Fatalf("unexpected error decoding alert: %s", err)
+	}
+	if a.Labels["service.name"] != "api" || a.Annotations["ünïcode"] != "x" || a.StartsAt.IsZero() {
+		t.Fatalf("unexpected alert %v", a)
+	}
+	if err := a.Validate(); err == nil || err.Error() != `invalid label set: invalid name "service.name"` {
+		t.Errorf("unexpected validation error with classic label names: %v", err)
+	}
+
+	m := NewMatcher("service.name", "api")
+	if err := m.Validate(); err == nil {
+		t.Errorf("expected matcher to be invalid with classic label names")
+	}
+
+	UTF8LabelNames = true
+
+	if err := a.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %s", err)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("unexpected matcher validation error: %s", err)
+	}
+	if m.String() != `"service.name"="api"` {
+		t.Errorf("unexpected matcher string %s", m)
+	}
+
+	// Classic label names are formatted like model.LabelSet does.
+	ls := model.LabelSet{"b": "2", "a": "1"}
+	if LabelSetString(ls) != ls.String() {
+		t.Errorf("expected %s, got %s", ls, LabelSetString(ls))
+	}
+	exp := `{"a,b=\"c"="d", alertname="Down"}`
+	if s := LabelSetString(model.LabelSet{`a,b="c`: "d", "alertname": "Down"}); s != exp {
+		t.Errorf("expected %s, got %s", exp, s)
+	}
+}
@@ -41,6 +41,7 @@ silenceDecoder =
         |: (field "updatedAt" iso8601Time)
         |: (field "matchers" (Json.list matcherDecoder))
         |: (field "status" statusDecoder)
+        |: (Json.maybe (field "recurrence" Json.value))
 
 
 statusDecoder : Json.Decoder Status
@@ -70,7 +71,8 @@ stateDecoder state =
 
 matcherDecoder : Json.Decoder Matcher
 matcherDecoder =
-    Json.map3 Matcher
+    Json.map4 Matcher
         (field "isRegex" Json.bool)
+        (field "isNegative" Json.bool)
         (field "name" Json.string)
         (field "value" Json.string)
@@ -9,13 +9,18 @@ import Utils.Date
 silence : Silence -> Encode.Value
 silence silence =
     Encode.object
-        [ ( "id", Encode.string silence.id )
-        , ( "createdBy", Encode.string silence.createdBy )
-        , ( "comment", Encode.string silence.comment )
-        , ( "startsAt", Encode.string (Utils.Date.encode silence.startsAt) )
-        , ( "endsAt", Encode.string (Utils.Date.encode silence.endsAt) )
-        , ( "matchers", Encode.list (List.map matcher silence.matchers) )
-        ]
+        ([ ( "id", Encode.string silence.id )
+         , ( "createdBy", Encode.string silence.createdBy )
+         , ( "comment", Encode.string silence.comment )
+         , ( "startsAt", Encode.string (Utils.Date.encode silence.startsAt) )
+         , ( "endsAt", Encode.string (Utils.Date.encode silence.endsAt) )
+         , ( "matchers", Encode.list (List.map matcher silence.matchers) )
+         ]
+            ++ (silence.recurrence
+                    |> Maybe.map (\r -> [ ( "recurrence", r ) ])
+                    |> Maybe.withDefault []
+               )
+        )
 
 
 matcher : Matcher -> Encode.Value
@@ -24,4 +29,5 @@ matcher m =
         [ ( "name", Encode.string m.name )
         , ( "value", Encode.string m.value )
         , ( "isRegex", Encode.bool m.isRegex )
+        , ( "isNegative", Encode.bool m.isNegative )
         ]
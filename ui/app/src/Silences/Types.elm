@@ -13,6 +13,7 @@ module Silences.Types
 
 import Utils.Types exposing (Matcher)
 import Time exposing (Time)
+import Json.Encode
 
 
 nullSilence : Silence
@@ -25,6 +26,7 @@ nullSilence =
     , updatedAt = 0
     , matchers = [ nullMatcher ]
     , status = nullSilenceStatus
+    , recurrence = Nothing
     }
 
 
@@ -36,7 +38,7 @@ nullSilenceStatus =
 
 nullMatcher : Matcher
 nullMatcher =
-    Matcher False "" ""
+    Matcher False False "" ""
 
 
 nullTime : Time
@@ -53,6 +55,9 @@ type alias Silence =
     , updatedAt : Time
     , matchers : List Matcher
     , status : Status
+
+    -- The recurrence is not editable, but kept when the silence is updated.
+    , recurrence : Maybe Json.Encode.Value
     }
 
 
@@ -2,7 +2,7 @@ module Status.Api exposing (getStatus)
 
 import Utils.Api exposing (send, get)
 import Utils.Types exposing (ApiData)
-import Status.Types exposing (StatusResponse, VersionInfo, MeshStatus, MeshPeer)
+import Status.Types exposing (StatusResponse, VersionInfo, MeshStatus, MeshPeer, MemoryUsage)
 import Json.Decode exposing (Decoder, map2, string, field, at, list, int, maybe)
 
 
@@ -25,11 +25,12 @@ decodeStatusResponse =
 
 decodeData : Decoder StatusResponse
 decodeData =
-    Json.Decode.map4 StatusResponse
+    Json.Decode.map5 StatusResponse
         (field "configYAML" string)
         (field "uptime" string)
         (field "versionInfo" decodeVersionInfo)
         (field "meshStatus" (maybe decodeMeshStatus))
+        (maybe (field "alertMemory" decodeMemoryUsage))
 
 
 decodeVersionInfo : Decoder VersionInfo
@@ -57,3 +58,10 @@ decodeMeshPeer =
         (field "name" string)
         (field "nickName" string)
         (field "uid" int)
+
+
+decodeMemoryUsage : Decoder MemoryUsage
+decodeMemoryUsage =
+    Json.Decode.map2 MemoryUsage
+        (field "used" int)
+        (field "limit" int)
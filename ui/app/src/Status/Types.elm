@@ -1,4 +1,4 @@
-module Status.Types exposing (StatusResponse, VersionInfo, MeshStatus, MeshPeer)
+module Status.Types exposing (StatusResponse, VersionInfo, MeshStatus, MeshPeer, MemoryUsage)
 
 
 type alias StatusResponse =
@@ -6,6 +6,7 @@ type alias StatusResponse =
     , uptime : String
     , versionInfo : VersionInfo
     , meshStatus : Maybe MeshStatus
+    , alertMemory : Maybe MemoryUsage
     }
 
 
@@ -31,3 +32,9 @@ type alias MeshPeer =
     , nickName : String
     , uid : Int
     }
+
+
+type alias MemoryUsage =
+    { used : Int
+    , limit : Int
+    }
@@ -55,10 +55,18 @@ mstring : Matcher -> String
 mstring m =
     let
         sep =
-            if m.isRegex then
-                "=~"
-            else
-                "="
+            case ( m.isRegex, m.isNegative ) of
+                ( True, True ) ->
+                    "!~"
+
+                ( True, False ) ->
+                    "=~"
+
+                ( False, True ) ->
+                    "!="
+
+                ( False, False ) ->
+                    "="
     in
         String.join sep [ m.name, toString m.value ]
 
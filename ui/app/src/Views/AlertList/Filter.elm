@@ -32,33 +32,35 @@ byLabel matchers block =
     alertsFromBlock
         (\a ->
             -- Check that all labels are present within the alert's label set.
-            List.all
-                (\m ->
-                    -- Check for regex or direct match
-                    if m.isRegex then
-                        -- Check if key is present, then regex match value.
-                        let
-                            x =
-                                List.head <| List.filter (\( key, value ) -> key == m.name) a.labels
-
-                            regex =
-                                Regex.regex m.value
-                        in
-                            -- No regex match
-                            case x of
-                                Just ( _, value ) ->
-                                    Regex.contains regex value
-
-                                Nothing ->
-                                    False
-                    else
-                        List.member ( m.name, m.value ) a.labels
-                )
-                matchers
+            -- Negative matchers require the opposite.
+            List.all (\m -> matchesLabels m a.labels /= m.isNegative) matchers
         )
         block
 
 
+matchesLabels : Utils.Types.Matcher -> Utils.Types.Labels -> Bool
+matchesLabels m labels =
+    -- Check for regex or direct match
+    if m.isRegex then
+        -- Check if key is present, then regex match value.
+        let
+            x =
+                List.head <| List.filter (\( key, value ) -> key == m.name) labels
+
+            regex =
+                Regex.regex m.value
+        in
+            -- No regex match
+            case x of
+                Just ( _, value ) ->
+                    Regex.contains regex value
+
+                Nothing ->
+                    False
+    else
+        List.member ( m.name, m.value ) labels
+
+
 filterAlertGroupLabels : Utils.Types.Matchers -> AlertGroup -> Maybe AlertGroup
 filterAlertGroupLabels matchers alertGroup =
     let
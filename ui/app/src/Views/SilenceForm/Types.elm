@@ -17,6 +17,7 @@ import Silences.Types exposing (Silence, SilenceId, nullSilence)
 import Alerts.Types exposing (Alert)
 import Utils.Types exposing (Matcher, Duration, ApiData(..))
 import Time exposing (Time)
+import Json.Encode
 import Utils.Date exposing (timeFromString, timeToString, durationFormat, parseDuration)
 import Time exposing (Time)
 import Utils.FormValidation
@@ -44,6 +45,7 @@ type alias SilenceForm =
     , endsAt : ValidatedField
     , duration : ValidatedField
     , matchers : List MatcherForm
+    , recurrence : Maybe Json.Encode.Value
     }
 
 
@@ -51,6 +53,7 @@ type alias MatcherForm =
     { name : ValidatedField
     , value : ValidatedField
     , isRegex : Bool
+    , isNegative : Bool
     }
 
 
@@ -84,6 +87,7 @@ type SilenceFormFieldMsg
     | UpdateMatcherValue Int String
     | ValidateMatcherValue Int
     | UpdateMatcherRegex Int Bool
+    | UpdateMatcherNegative Int Bool
 
 
 initSilenceForm : Model
@@ -95,7 +99,7 @@ initSilenceForm =
 
 
 toSilence : SilenceForm -> Maybe Silence
-toSilence { id, comment, matchers, createdBy, startsAt, endsAt } =
+toSilence { id, comment, matchers, createdBy, startsAt, endsAt, recurrence } =
     Result.map5
         (\nonEmptyComment validMatchers nonEmptyCreatedBy parsedStartsAt parsedEndsAt ->
             { nullSilence
@@ -105,6 +109,7 @@ toSilence { id, comment, matchers, createdBy, startsAt, endsAt } =
                 , createdBy = nonEmptyCreatedBy
                 , startsAt = parsedStartsAt
                 , endsAt = parsedEndsAt
+                , recurrence = recurrence
             }
         )
         (stringNotEmpty comment.value)
@@ -116,7 +121,7 @@ toSilence { id, comment, matchers, createdBy, startsAt, endsAt } =
 
 
 fromSilence : Silence -> SilenceForm
-fromSilence { id, createdBy, comment, startsAt, endsAt, matchers } =
+fromSilence { id, createdBy, comment, startsAt, endsAt, matchers, recurrence } =
     { id = id
     , createdBy = initialField createdBy
     , comment = initialField comment
@@ -124,11 +129,12 @@ fromSilence { id, createdBy, comment, startsAt, endsAt, matchers } =
     , endsAt = initialField (timeToString endsAt)
     , duration = initialField (durationFormat (endsAt - startsAt))
     , matchers = List.map fromMatcher matchers
+    , recurrence = recurrence
     }
 
 
 validateForm : SilenceForm -> SilenceForm
-validateForm { id, createdBy, comment, startsAt, endsAt, duration, matchers } =
+validateForm { id, createdBy, comment, startsAt, endsAt, duration, matchers, recurrence } =
     { id = id
     , createdBy = validate stringNotEmpty createdBy
     , comment = validate stringNotEmpty comment
@@ -136,14 +142,16 @@ validateForm { id, createdBy, comment, startsAt, endsAt, duration, matchers } =
     , endsAt = validate timeFromString endsAt
     , duration = validate parseDuration duration
     , matchers = List.map validateMatcherForm matchers
+    , recurrence = recurrence
     }
 
 
 validateMatcherForm : MatcherForm -> MatcherForm
-validateMatcherForm { name, value, isRegex } =
+validateMatcherForm { name, value, isRegex, isNegative } =
     { name = validate stringNotEmpty name
     , value = validate stringNotEmpty value
     , isRegex = isRegex
+    , isNegative = isNegative
     }
 
 
@@ -156,12 +164,14 @@ empty =
     , endsAt = initialField ""
     , duration = initialField ""
     , matchers = []
+    , recurrence = Nothing
     }
 
 
 emptyMatcher : MatcherForm
 emptyMatcher =
     { isRegex = False
+    , isNegative = False
     , name = initialField ""
     , value = initialField ""
     }
@@ -188,14 +198,15 @@ fromMatchersAndTime matchers now =
 
 
 appendMatcher : MatcherForm -> Result String (List Matcher) -> Result String (List Matcher)
-appendMatcher { isRegex, name, value } =
+appendMatcher { isRegex, isNegative, name, value } =
     Result.map2 (::)
-        (Result.map2 (Matcher isRegex) (stringNotEmpty name.value) (stringNotEmpty value.value))
+        (Result.map2 (Matcher isRegex isNegative) (stringNotEmpty name.value) (stringNotEmpty value.value))
 
 
 fromMatcher : Matcher -> MatcherForm
-fromMatcher { name, value, isRegex } =
+fromMatcher { name, value, isRegex, isNegative } =
     { name = initialField name
     , value = initialField value
     , isRegex = isRegex
+    , isNegative = isNegative
     }
@@ -168,6 +168,15 @@ updateForm msg form =
             in
                 { form | matchers = matchers }
 
+        UpdateMatcherNegative index isNegative ->
+            let
+                matchers =
+                    Utils.List.replaceIndex index
+                        (\matcher -> { matcher | isNegative = isNegative })
+                        form.matchers
+            in
+                { form | matchers = matchers }
+
 
 update : SilenceFormMsg -> Model -> String -> String -> ( Model, Cmd SilenceFormMsg )
 update msg model basePath apiUrl =
@@ -85,7 +85,7 @@ matcherInput matchers =
                 , span [ class "" ] [ text "Alerts affected by this silence." ]
                 ]
             , div [ class "row" ]
-                [ label [ class "col-5" ] [ text "Name" ]
+                [ label [ class "col-4" ] [ text "Name" ]
                 , label [ class "col-5" ] [ text "Value" ]
                 ]
             ]
@@ -149,12 +149,13 @@ previewSilenceBtn =
 
 
 matcherForm : Bool -> Int -> MatcherForm -> Html SilenceFormMsg
-matcherForm showDeleteButton index { name, value, isRegex } =
+matcherForm showDeleteButton index { name, value, isRegex, isNegative } =
     div [ class "row" ]
-        [ div [ class "col-5" ] [ validatedField input "" "" (UpdateMatcherName index) (ValidateMatcherName index) name ]
+        [ div [ class "col-4" ] [ validatedField input "" "" (UpdateMatcherName index) (ValidateMatcherName index) name ]
         , div [ class "col-5" ] [ validatedField input "" "" (UpdateMatcherValue index) (ValidateMatcherValue index) value ]
-        , div [ class "col-2 d-flex align-items-center" ]
+        , div [ class "col-3 d-flex align-items-center" ]
             [ checkbox "Regex" isRegex (UpdateMatcherRegex index)
+            , checkbox "Negate" isNegative (UpdateMatcherNegative index)
             , if showDeleteButton then
                 iconButtonMsg "btn btn-secondary ml-auto" "fa-trash-o" (DeleteMatcher index)
               else
@@ -74,10 +74,18 @@ matcherButton : Matcher -> Html Msg
 matcherButton matcher =
     let
         op =
-            if matcher.isRegex then
-                Utils.Filter.RegexMatch
-            else
-                Utils.Filter.Eq
+            case ( matcher.isRegex, matcher.isNegative ) of
+                ( True, True ) ->
+                    Utils.Filter.NotRegexMatch
+
+                ( True, False ) ->
+                    Utils.Filter.RegexMatch
+
+                ( False, True ) ->
+                    Utils.Filter.NotEq
+
+                ( False, False ) ->
+                    Utils.Filter.Eq
 
         msg =
             FilterBarTypes.AddFilterMatcher False
@@ -1,8 +1,8 @@
 module Views.Status.Views exposing (view)
 
 import Html exposing (..)
-import Html.Attributes exposing (class, style)
-import Status.Types exposing (StatusResponse, VersionInfo, MeshStatus, MeshPeer)
+import Html.Attributes exposing (class, classList, style)
+import Status.Types exposing (StatusResponse, VersionInfo, MeshStatus, MeshPeer, MemoryUsage)
 import Types exposing (Msg(MsgForStatus))
 import Utils.Types exposing (ApiData(Failure, Success, Loading, Initial))
 import Views.Status.Types exposing (StatusModel)
@@ -33,6 +33,7 @@ viewStatusInfo status =
             [ b [ class "col-sm-2" ] [ text "Uptime:" ]
             , div [ class "col-sm-10" ] [ text status.uptime ]
             ]
+        , viewAlertMemory status.alertMemory
         , viewMeshStatus status.meshStatus
         , viewVersionInformation status.versionInfo
         , viewConfig status.config
@@ -51,6 +52,41 @@ viewConfig config =
         ]
 
 
+viewAlertMemory : Maybe MemoryUsage -> Html Types.Msg
+viewAlertMemory alertMemory =
+    case alertMemory of
+        Just { used, limit } ->
+            let
+                headroom =
+                    if limit > 0 then
+                        toString (max 0 (limit - used))
+                            ++ " bytes ("
+                            ++ toString (max 0 (100 - 100 * used // limit))
+                            ++ "%)"
+                    else
+                        "No limit"
+            in
+                div []
+                    [ div [ class "form-group row" ]
+                        [ b [ class "col-sm-2" ] [ text "Alert Memory:" ]
+                        , div [ class "col-sm-10" ] [ text <| toString used ++ " bytes" ]
+                        ]
+                    , div [ class "form-group row" ]
+                        [ b [ class "col-sm-2" ] [ text "Headroom:" ]
+                        , div
+                            [ classList
+                                [ ( "col-sm-10", True )
+                                , ( "text-danger", limit > 0 && 10 * used >= 9 * limit )
+                                ]
+                            ]
+                            [ text headroom ]
+                        ]
+                    ]
+
+        Nothing ->
+            text ""
+
+
 viewMeshStatus : Maybe MeshStatus -> Html Types.Msg
 viewMeshStatus meshStatus =
     case meshStatus of
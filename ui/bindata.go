@@ -120,6 +120,26 @@ func uiAppIndexHtml() (*asset, error) {
 	return a, nil
 }
 
+var _uiAppRoutesHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x59\x6d\x8f\xdb\xb8\x11\xfe\x9e\x5f\xc1\xa8\xc0\x49\x46\x2c\x69\x73\xd7\x2b\x0e\x6b\x6b\x0f\xdb\xdc\xa2\x4d\x91\xcb\x1d\x36\x29\xd0\xe2\x70\x1f\x28\x71\x24\xb1\xa1\x48\x95\xa4\xbc\x31\x82\xbd\xdf\xde\x21\xe5\x37\x79\x2d\x79\x9d\x6a\x11\xdb\x22\x39\x0f\x87\xcf\xbc\x4a\x59\xbe\xfc\xe9\x97\x37\x1f\xff\xfd\xeb\x1d\xa9\x6d\x23\x6e\x5e\x2c\xdd\x17\x11\x54\x56\x59\x00\x32\xb8\x79\x41\xf0\x5a\xd6\x40\x59\xff\xd3\xdf\x36\x60\x29\x29\x6a\xaa\x0d\xd8\x2c\xe8\x6c\x19\xff\x10\x1c\x4f\x4b\xda\x40\x16\xac\x38\x3c\xb4\x4a\xdb\x80\x14\x4a\x5a\x90\xb8\xfc\x81\x33\x5b\x67\x0c\x56\xbc\x80\xd8\xdf\xcc\x09\x97\xdc\x72\x2a\x62\x53\x50\x01\xd9\xeb\x39\x31\xb5\xe6\xf2\x53\x6c\x55\x5c\x72\x9b\x49\x75\x08\x6f\xb9\x15\x70\x73\x2b\x40\xdb\x86\x4a\x5a\x81\x26\x31\xb9\x57\x9d\xe5\xb2\x22\x56\x03\x2c\xd3\x7e\xc9\x5e\x44\x20\x18\xd1\x20\xb2\xc0\xd8\xb5\x00\x53\x03\xa0\x4a\xb5\x86\x32\x0b\x04\xcf\xd3\x5c\x29\x6b\xac\xa6\x6d\xfc\xe7\xe4\x2a\xb9\x8a\xa9\x68\x6b\x9a\xfc\x25\x66\xdc\xd8\xb4\x30\x66\xbf\x20\x69\xb8\x4c\x70\xe4\x50\x21\x8f\xb9\xbf\x77\x57\x27\x12\x8d\x1a\x81\x21\x5f\x88\x40\x90\xd8\xaf\xb9\x26\x52\x49\x58\x90\x96\x32\x86\xca\xc6\x02\x4a\x7b\x4d\x5e\x27\xdf\x6b\x68\x16\x24\x57\x9a\x81\xde\x0e\xb6\x9f\x89\x51\x82\x33\xf2\x27\xc6\xd8\x82\x3c\x0e\xe0\x7b\x70\xc4\x6e\xa8\xae\xb8\xbc\x26\xc9\xb7\x0e\x83\x5c\xed\xb0\x77\x43\x43\x70\x4d\x19\xef\xcc\x76\xf2\x34\x6c\xd2\x50\x5b\xd4\xc0\x10\x3e\xa7\xc5\xa7\x0a\x07\x25\x8b\x0b\x25\x94\xbe\x46\x6d\xca\xf2\x8a\xfd\xb0\x20\x25\xda\x33\x7e\x00\x5e\xd5\xa8\x6d\xae\xc4\x88\x8e\x09\x92\xb6\x02\x74\x94\x11\xb8\xf2\x7b\xf7\xf7\x44\xb6\x57\x41\xef\x4e\x18\xeb\x7e\xa3\xa7\x7a\x2f\xd3\x03\xfa\x97\xe9\xde\x53\x97\xb9\x62\xeb\x03\x2b\x49\xba\x22\x85\xa0\xc6\x64\x01\xfe\xcc\xa9\x26\xfd\x17\x3a\x59\x55\x09\xa0\xb9\x80\xb8\x61\xdb\x41\xe1\xf6\x23\x79\x15\x97\x94\xa1\xee\x4d\x1e\x7f\x17\x0c\x4d\xbc\x64\x7c\x07\xe8\x9c\x9b\x72\x09\xfa\x68\x8d\x5f\x47\x87\xdb\xc6\xb9\xa6\x92\x6d\xbd\x2f\x49\x83\x81\x2b\x2f\x53\x7a\x02\xc2\xb4\x54\x1e\xa1\x58\xf8\x6c\x83\x9b\xa1\xdb\xbb\x65\x47\x4a\xa6\xa8\xe5\x01\x09\x29\x4a\x1f\xdc\x3e\xe3\x08\xcb\x52\xe9\x86\x70\x96\x05\xde\x26\xb1\xbb\x0d\xb6\x42\xee\x26\xe6\x12\xc3\x0b\x4e\x51\xe4\xe5\xb9\x6c\x3b\xeb\x01\x04\xcd\x41\x98\xa1\xb0\xdb\x56\x2b\x41\x1a\x1d\x7f\x1b\x10\x6f\xca\x4d\x8a\xb8\x26\xdf\x5d\xa1\xad\x83\x27\x88\x9b\xab\x15\xb4\x80\x1a\x1d\x0f\x74\x16\xbe\x73\xd0\x04\xd3\xd1\x9c\x40\x52\x25\xe4\x8b\x01\xed\x12\x8c\xdb\x45\x05\x98\x4f\x00\x9d\x90\xdb\x35\x9e\x13\xbf\x38\xa6\x99\xe0\x31\x3c\xa1\x6c\xde\x59\xab\x24\xb1\xeb\x16\x45\x4d\x97\x37\xdc\xee\xf4\xcd\xad\x24\xf8\x2f\x6e\x35\x47\xa7\x5c\x07\x37\x3f\x3b\x42\x96\x69\x2f\x73\xcc\xbb\x3b\xdd\x09\x87\x71\x3c\x80\xd6\x4a\xef\x60\xa9\xb3\x3e\xf1\x9f\x31\xc3\xbc\x8b\x16\x20\x35\x67\x0c\xe4\xcd\x91\xf5\x06\x20\xce\xe2\xc1\xcd\x13\xfb\x0e\x6f\x0d\x9e\xb6\xb5\x43\x84\x34\x25\xf7\x20\x91\x35\x43\x6c\x0d\x44\x1f\x78\x10\xa6\x48\xdb\x69\x89\xfe\x9e\xaf\xfd\xe4\xed\xaf\x6f\x09\x3a\x2b\xea\x53\xd5\x3e\x22\xbc\xcc\x31\xdc\x26\xd3\x51\x52\xf1\x15\x48\x22\xb6\xb6\x20\xdc\xf4\x73\x8c\x58\x95\x0c\xa4\xa2\xb2\x93\x85\xe5\x4a\x46\x33\xf2\xe5\x89\x19\x56\x18\x9c\x4e\x9f\x3b\x41\x32\xc2\x54\xd1\x35\x58\x39\x92\x0a\xec\x9d\x00\xf7\xf3\xaf\xeb\xb7\x2c\x0a\xdd\x8a\x70\xb6\x38\x29\xed\x29\x9e\x16\xf7\x4b\x9c\xfc\x13\x80\xad\x6e\x04\x45\xa2\x4e\x8b\x39\x29\xf2\x53\x6a\x6e\x37\xfb\x5c\x6b\xdc\x48\xc2\x03\xf9\xd7\xcf\xef\xfe\x6e\x6d\x7b\x0f\xff\xed\xc0\xd8\xe8\x84\x72\xee\xc2\xf5\x89\x6a\x41\x46\xe1\xdf\xee\x3e\x86\x73\x82\x5b\x4c\xad\x94\x42\x51\x86\x1b\x4c\x52\x76\xa8\x8f\x06\xb3\x18\x5d\x60\xf5\x7a\x42\xdc\x5d\x28\x8e\xbb\xfd\xe3\xc3\x2f\xef\x93\xd6\x95\xf8\xc8\x69\x81\x83\xad\x92\x06\x3e\x62\xda\x99\x8d\xa3\x3f\x92\xc2\x05\x05\x89\x60\x76\x76\x17\xe7\x6a\x58\xe8\xd5\xc3\x9d\xb3\x44\x14\xbe\x95\x2b\xea\x0a\xde\x76\x2b\x52\x6a\xd5\x90\x90\xbc\x9a\x20\xc8\xef\x39\x3a\xc3\x4b\x12\x21\x5a\x62\x2c\xb5\x9d\x21\x2f\xb3\x8c\x84\xa6\x2b\x0a\x30\x26\xbc\x58\x3f\x07\xe4\x7d\xe6\xab\x54\xd9\x38\x64\xd2\x47\x36\xf2\x6b\x75\x07\xe3\x40\x45\xee\xf7\x63\xd4\xd2\x91\xed\x1e\xa7\x3c\xc6\xef\x76\xe4\x32\x87\x54\x6f\x1c\x14\xc3\x72\xcb\x2f\x7e\x86\xa4\xa4\x5c\x00\xc3\x98\x98\x42\x37\x98\x3c\x4e\x79\xf6\xe3\x44\x20\xed\xb7\x6e\x4c\x35\xc6\xfc\x96\x22\x57\xd9\xde\xf4\xdd\x22\x1e\x01\x05\x16\x2f\x9e\xc5\x68\x49\x85\x81\xcb\xf4\xda\xb4\x19\x1f\x2c\x76\x9b\x55\xd4\x4c\x05\xb9\x6a\x9d\x32\x09\x37\xf7\x50\xc1\x67\xf2\x23\x89\xdc\xcd\x7b\xa8\xa8\xc5\xac\x87\xf7\xe1\xcb\x3f\x42\x72\x4d\xc2\xec\x0f\xf4\xad\xeb\x13\xd3\x59\x3f\x1d\x8e\x18\x74\xe3\x70\x4d\xe2\x3a\x67\x34\x07\x6e\xf8\xaa\x8f\x42\xe3\xd5\xe3\xe5\x1a\x31\x31\x44\x3a\xb8\x90\x7d\xed\xd3\xbd\x6b\x15\x20\xf2\xf9\x78\x8e\x5d\xa2\xad\xa7\x4e\x2b\xf8\x61\xea\x2c\x34\x50\x0b\x9b\xec\x19\x85\x82\x8f\x9d\xc1\x89\xba\x02\x35\x2e\x8b\xb3\x63\xc2\x38\x95\xf8\x9a\xf8\xde\x11\x80\xa1\xea\x75\x0d\xc7\x17\x63\x89\xb9\xb5\xc8\x4d\xee\x0e\x16\xba\x58\x89\xdd\xb9\xc2\xfe\x78\xc9\x7f\x14\xc7\x14\x9b\x84\xb3\x53\x39\x7e\x9f\x2b\x8b\x29\x75\x5d\x47\x35\x6e\xb0\xe2\x48\x61\xd7\xc4\x84\x13\x8b\x87\x9e\xdd\xf7\xc7\x38\x01\xe8\x21\x7a\xfc\x98\xb4\xc5\x5a\xc1\xde\xd4\x5c\xb0\xc8\xc1\x8c\x1d\xe7\xb0\x79\xd7\x26\xc1\x06\xe4\x8e\x16\xf5\xbe\xd2\x36\xe7\xea\x46\x4e\x59\x05\x5f\x49\x86\xbb\xbc\xfc\x90\x91\x1e\xd2\x7f\x62\x9f\x58\xaa\x6d\xc4\x85\xe7\x50\x8e\x92\xc0\x51\x9c\x8e\x4b\x1f\x13\xe6\xd1\xc6\x52\xe8\xc8\xb8\x2f\x1b\x9e\x4c\xd7\x9d\x72\xd9\xc1\x39\xe6\xdc\xba\xff\x83\x38\x27\x3e\xce\x1b\x83\x92\x76\xc2\x3e\x83\x3a\x8f\x33\x64\x2e\xdc\x1e\x21\x7c\x3e\x67\x4e\x64\x8c\xb2\x33\x84\xb9\x47\x7f\x17\x88\xe4\x9b\x6f\xc8\x70\x24\x51\x0f\x58\x96\xce\x11\xe9\x17\x4d\x31\xd9\x50\x21\xa6\xa8\xf4\x00\x43\x2e\x1d\x21\x71\xe3\xda\xd0\xf0\x9c\xdc\x11\x77\x7e\xf0\xda\x97\xc8\x53\x87\x79\x3e\xa3\xfd\xd9\x2f\xa1\x54\xf0\x01\x00\x02\x8e\x85\xfd\x9e\xfc\xbe\x0f\x3f\x47\x71\x27\x26\xf8\xed\x26\xc9\xed\xc4\x89\x04\x6d\x26\x58\x3d\xd4\xeb\x69\x42\x2a\xf4\x9c\xf0\x73\x7d\x18\xee\x39\x4c\x80\xfb\x5a\xe6\xe4\x7d\xa6\x47\x87\xc5\xb6\x33\xe2\xb3\xd9\x54\x6f\x36\x31\x77\xc4\x76\x27\x2e\xb2\xd5\xa6\x72\x0b\x7e\x59\x59\xde\x3d\x54\x45\x7d\x64\x9b\xa9\x82\x2c\x15\xf3\x6d\x79\xff\x58\x94\x60\xfb\xa6\xd7\x1f\x40\x40\x61\x95\xbe\x15\x02\xeb\x5c\x5f\x2d\x47\x14\xbf\xd5\x9a\xae\x93\x56\x2b\xab\xdc\xb3\xed\xd6\x16\x09\x3e\x08\x8b\xc8\x63\xcf\xf7\xcd\xa2\x9c\xb2\x89\xec\x5d\xe0\x1d\x37\x16\x6b\x57\xa3\x56\x58\x7a\x37\x2f\x8c\xb0\xf0\x86\xbb\xd7\x3d\xe1\x85\x49\x77\xc3\xc1\x85\x65\x0b\x57\x93\xc8\xf1\xe3\xfa\x95\xab\x05\x7e\x2d\x5d\x97\xe6\xbd\x42\x80\xac\x6c\x8d\x63\xaf\x5e\x9d\x73\x32\x87\xf0\x09\xd6\x64\x27\x6b\x04\x2f\x20\xba\x72\xfe\xb9\xef\x23\x16\x67\x31\xe4\x88\x85\xb6\xe6\xf9\x6d\xd7\xa4\x64\x81\xcb\x2a\x6e\x4f\x6c\xbc\x83\xdf\xcf\x81\xbb\x20\x97\xe7\x4e\x71\x6c\x1e\xca\x58\x84\xbc\x64\x47\x8c\xb8\x66\x74\x6b\x31\xd7\x91\x9e\x35\xd9\xf9\xa7\x9b\xc7\x67\x9b\xfa\x44\x40\xb8\xa7\xec\x90\xb6\x3c\x5d\xbd\x4e\x37\x19\xe5\xc0\x19\xb5\x52\x76\x2a\x30\xbe\x32\x9d\x5d\x94\xca\x26\x72\x90\x53\x6f\x4e\x7e\xfb\x7d\x2c\xf3\x6c\xbc\xe1\x6c\x76\x79\x3c\x95\xde\x47\xdf\x5d\xec\x5f\xc5\x85\x33\x67\xe6\xbb\x15\x4e\x39\x9b\x03\xd6\x19\xac\x92\xfe\xc5\xd5\x21\x8b\xa3\x1d\x0c\x60\x52\x00\x27\xfd\x53\xdf\x65\x44\x13\x9d\x7d\xff\xfe\x6e\xea\x9d\x4a\xbf\x02\x75\xf2\x8f\x29\xa7\x91\x9e\x9a\x3b\xf5\xc7\xf9\xb1\x17\xce\x5c\x5c\x80\x2c\x30\x27\xfd\xf3\xfe\xed\x1b\xd5\xb4\xf8\x38\x8b\xb6\xec\x67\x67\xf3\x7d\xda\x1c\x61\x71\x78\x7f\x78\x9e\x65\x7a\xf8\x3e\x6c\x99\xf6\x2f\x88\x97\x69\xff\x3f\x1f\xff\x03\x30\x17\x7a\x30\x0a\x19\x00\x00")
+
+func uiAppRoutesHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_uiAppRoutesHtml,
+		"ui/app/routes.html",
+	)
+}
+
+func uiAppRoutesHtml() (*asset, error) {
+	bytes, err := uiAppRoutesHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "ui/app/routes.html", size: 6410, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _uiAppFaviconIco = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcc\x5b\x09\x90\x54\xc5\x19\xee\x75\x51\x52\x49\x2a\x50\xb1\xd4\xc4\x4a\x0a\xa2\x52\xae\x8b\xe2\x62\x84\x12\x63\xc4\x44\x4a\xc5\x83\x63\xb9\x2f\x05\x05\x01\x21\x20\x82\xa0\x08\xb8\x01\xc1\x8b\xa0\xd1\x35\x8a\x28\x02\x1e\x1c\xde\x8b\x88\x68\x8c\x8b\x80\x1a\x4e\x2f\x64\x41\x81\x15\x99\x99\x9d\x6b\x67\x76\x76\xae\x9d\x99\x37\x5f\xea\xef\xe9\x1e\xde\xbc\x9d\xf7\x5e\xcf\xb0\x5a\xf9\xaa\xfe\x7a\xbb\x33\xaf\xbb\xff\xee\xfe\xef\xee\x61\xac\x84\x95\xb2\x8e\x1d\xe9\xd9\x99\x4d\x6a\xc7\x58\x4f\xc6\x58\xe7\xce\x99\xff\xd7\x75\x64\x6c\x65\x3b\xc6\x2a\x2a\xc4\xff\x5d\x18\xbb\xe6\x0c\xc6\xca\x18\x63\x1d\xe9\x3d\x96\xf9\x9c\xa3\x1d\xb3\x04\x80\xf6\x00\xfa\x02\x58\xa7\x69\x38\xee\x0b\x68\x49\x7f\x50\x4b\x6a\x1a\x8e\x01\x78\x09\x40\x1f\x00\xa7\x99\xb4\xfd\x2d\x80\x6a\x00\x81\x58\x4b\x1a\xcf\xbe\xd6\x8c\x01\xd3\x3d\x18\x38\xc3\x83\x55\x6f\x87\xd1\x92\x48\x03\x80\x1f\xc0\x3f\x00\x9c\x91\xa7\x6d\x0d\x04\xea\x8e\x26\x30\x66\xae\x0f\x95\x33\x3c\x9c\xc6\xce\xf7\xe1\xc8\xf1\xa4\xfc\x9a\x3a\xda\x20\xfb\x20\x7e\x00\x3c\x49\x5f\xa4\x34\x60\xe3\xd6\x28\x1e\x7f\x29\x84\x07\x9f\x6f\xca\x21\xfa\x6c\xf3\xf6\x28\xb4\x74\xb6\x8f\x87\x01\xb4\x03\x70\x3d\x80\x46\xfa\x30\x91\x4c\x63\xd1\xb3\x41\x0c\xbe\xcb\x8b\x61\x77\xe7\xd2\xa0\xbb\xbc\x78\xe4\x85\x26\x3e\x86\x80\x1b\xc0\x55\xb4\x56\xf2\x83\xe6\x48\x1a\x6b\x37\x87\xf1\xd4\xba\x10\x9e\xde\xd0\x9c\x43\xf4\xd9\xfa\x2d\x11\x44\xa2\x69\xe8\xb0\x02\xc0\x0f\xf2\x9f\xd5\x35\x61\x5c\x3a\xdc\x85\x8a\x21\x4e\x74\x1d\x98\x4b\x97\x0c\x73\xe1\x8f\xc3\x5d\x78\xe5\xdd\xb0\xbe\xfd\x41\x00\x49\xc9\xfb\xbb\xdb\xa2\x78\xf3\xc3\x08\xfa\x4d\xf3\xa0\x7c\xa0\x13\x17\x56\x66\xa8\x7c\x80\x13\x93\x16\xf9\xf9\xda\xd0\x3b\xc9\x54\xb6\x7d\x48\xb6\x97\xd8\xb5\xbf\x05\x3d\x46\xba\xd0\xb5\x32\xb7\xfd\x8d\x53\x3d\xf0\x34\xa6\x60\x00\xb5\x3f\xa6\xff\x60\xc7\xbe\x38\xba\x0f\x33\xb4\x1f\xe8\x44\x9f\xdb\xdd\x70\xb8\x5b\xb5\xaf\x03\xf0\x8a\xfe\x83\x1f\x1a\x52\xb8\x76\x92\x1b\x17\x0d\xca\xcc\xfb\xb2\xd1\x2e\x74\x1f\xea\xc2\xad\x0b\x7c\x88\xc6\xd2\xc6\xf6\xcb\x01\x5c\x0b\xc0\x27\x3f\xa0\xfd\x99\xfb\x44\x00\xe3\xab\xfc\x7c\xcd\x06\xcf\xf4\x72\xde\xdf\xf8\x30\x62\x6c\xeb\x02\x70\x25\x80\x53\x01\x3c\xa6\xff\x82\xd6\x88\x64\xe5\xea\x09\x6e\x3c\xb4\xb2\x09\x8f\xbd\x18\x82\xc3\x93\xc3\x3b\x31\xb2\x18\x40\xa9\x90\xc1\xb3\x00\xbc\x2e\x3e\x47\x24\x96\x86\xb7\x51\xc3\xcd\xf7\xf9\xb0\x7b\x7f\x0b\xbe\x77\x26\xf5\xbc\x93\x04\xbd\x0c\xe0\x74\x83\x0e\x9c\x09\x60\x99\xd0\x11\xbe\x47\xaf\xff\x3b\x02\x7f\x50\xd3\x8f\xeb\x01\xf0\xa0\xb1\xad\xae\x8f\xd3\x84\x8e\xae\x01\x70\xa4\x25\x91\x8e\xa6\x34\xd0\xc4\xbf\x03\xf0\xbc\x90\xd7\x53\xf5\x6d\xe2\x9d\x18\xab\x6f\xcf\x58\x6d\x29\x63\x55\x25\x19\x32\x83\xfc\x9e\xde\xa5\x36\xd4\xb6\x4c\xd8\x98\xab\xf4\x76\xa6\xa3\xb5\x9d\x31\x83\xd0\xc7\x72\x00\xd3\x00\xbc\x01\xe0\x90\x90\xb1\x94\xa0\x20\x80\x03\x42\xe7\x26\x02\x38\x4f\xee\xc1\xc9\x40\x8c\x7b\x19\x80\x67\x00\xd4\x8b\xb1\x38\xc8\x6e\x35\x36\x69\x08\x84\x34\x24\x93\x39\xf2\x97\x00\xf0\xad\x90\x9d\x0a\x00\xa7\x14\x39\xf6\xef\x84\x4d\xf4\xea\x3b\x8f\xc6\xd3\xa8\xa9\x8d\x62\xca\x12\x7f\xc6\x8e\xde\xe9\xc1\x8c\x47\x1a\xf1\xc1\xa7\x31\x69\x4b\xf5\x70\x00\xa8\x32\xda\x55\x85\xb1\x69\xce\x3b\xa4\xdc\x4a\x90\xfd\x26\xb9\x27\x3b\x54\xd6\x3f\xa3\xff\x44\xf4\x37\xd9\x27\xb2\x67\xc9\x56\xaa\xcc\xe5\xfa\x3d\x00\x17\x2a\x8e\xdd\x47\xac\x5f\x2b\xbc\xff\x49\x0c\xdd\x06\x67\xc6\xbb\xc0\x40\xe7\xf7\x73\xa0\xc7\x08\x17\x76\x7e\xd5\x92\xaf\x29\xe1\x73\x00\x3d\x15\xe6\x9d\x77\x6c\xc2\xab\xef\x47\x70\xcb\x3c\x1f\xb7\x39\xf9\x88\xfc\xd1\x96\x4f\x62\x66\xcd\x09\x7b\x01\x74\xb5\xd8\xef\xed\xc6\x06\xcd\xd1\x34\xdc\xfe\x14\xb7\xb1\x64\x6b\x8e\xb9\xac\xc9\xe9\xc9\xbc\xeb\xf1\xa7\xb8\xbd\xc9\x83\x8d\x00\x7e\x6d\x18\xbb\x14\xc0\xd2\x7c\x2f\x3f\xff\x66\x33\xb7\xbb\xd7\x4f\xf1\xe0\x06\x45\xa2\x77\xfb\x4e\x76\x73\x3b\x95\x07\x24\x0f\x73\x0c\xe3\x5f\x2e\x6c\x58\x2b\x90\x6f\x3d\xf7\x06\x07\xdf\xdf\x42\xa8\xcb\x8d\x0e\xce\xbb\x09\x8e\x92\x3d\xd1\xcd\x7d\xb9\xd9\x8b\x34\x87\xdb\x17\xfa\xb9\x9f\x2c\x84\x26\x2e\xf4\x73\x9d\xb4\xc0\x03\x62\xfc\xae\x82\x9f\xbc\x20\x7d\x27\x1b\x53\x0c\x91\xbe\x5a\xe0\x0b\x21\x73\xd3\x8c\xbe\x5e\xc2\xdf\xa4\x61\x5e\x75\x00\x23\xe6\x78\x31\xea\x9e\xc2\x88\xda\x2c\x5e\x11\x44\x28\xac\x99\x4e\x0d\xc0\x50\x61\xcf\x5b\x41\xd3\xc0\xfd\x2b\xf9\xfa\xac\x9e\x0f\x28\x80\xfa\x67\x62\x8c\xe5\xaf\x35\x23\x6d\xbe\x0c\xd5\x22\x56\x6a\x85\x9d\x5f\xb7\xf0\x18\x83\x64\x89\x78\xa0\x58\x83\x62\x8f\x0b\x2b\xad\xe9\xe2\x21\x4e\x5c\x3c\x38\xc3\x03\xb5\xed\x3d\xae\x01\xfb\x0f\x27\xcc\xc6\xdf\x26\xfc\x58\xee\xdc\xd3\xc0\xd2\x55\x4d\xa8\x9c\xe1\xc5\xbd\xff\x0c\xe0\xc5\x77\xc2\x98\x5f\x1d\xb0\x1d\x9f\xf8\xec\x33\xc1\x8d\x35\x1b\xc3\x78\xe2\x95\x10\xa6\x3e\xd8\x88\x9b\xfe\xe6\xc1\x33\x1b\x4c\xf5\xe0\x88\xd0\xc7\x56\x6b\x4f\xb6\x26\xd8\xac\xf1\xb5\xf3\x06\x34\x1e\xeb\x92\xad\xb7\x9b\x3f\xf1\xf8\xc2\x5b\x99\x58\x34\x99\x4a\xc3\x17\xd4\x78\x5f\x26\x7b\xe0\xd5\xfb\x54\x33\xbc\xb7\x23\xc6\xd7\xd4\x6e\x6c\x22\x5a\xf7\xd1\xf7\xfa\x78\xcc\xad\x00\x1a\xbf\xc9\xee\xa5\xea\xb5\x21\xde\xaf\xca\xf8\xb4\x07\x14\x1f\x92\x2d\x56\xc0\x61\x11\xb7\x58\x62\xd9\x9a\x50\x56\x9e\x55\xc6\xef\x7d\x6b\x83\x3e\x2f\xb2\x42\xad\xc8\x99\x2c\xf1\xf2\xa6\x30\xef\x57\x65\x7c\x92\x91\xeb\x26\xbb\xd1\xe0\x53\x9a\xff\xe3\x00\xee\x30\xb3\x3f\x12\x7b\xbe\x69\x41\xcf\x51\xae\xbc\x3c\x50\x3e\xd1\x6b\xb4\x8b\xc7\xf4\x7c\xff\xfb\x3b\x31\xe1\xef\x7e\xc4\xad\x6d\x1f\x81\x9c\x53\x25\x80\x32\x11\x07\x9b\xa2\x29\xac\x61\xe4\x3d\x5e\x2e\x03\xa4\xdf\xdd\x06\xe7\xe6\x30\x43\x67\x79\xb9\x9e\x4b\xfe\xd6\xd4\x84\x55\xe6\x4e\xb1\xc0\xd9\x14\x1f\xca\x3c\xd5\x0a\xff\x5a\x1f\x42\x59\x7f\x07\xcf\x5b\x28\x07\x96\xba\x48\xcf\x99\x4b\x33\x7a\x4e\x71\x11\xc5\x84\x4e\x8f\xd2\xda\x2f\xd0\xf9\xdf\x1e\x22\xef\x31\xc5\xbe\xba\x16\x1e\x5f\xcd\x5e\x16\xc0\xda\xcd\x11\x5c\xa4\x9b\x3f\xe5\xb0\x94\x57\x11\x2f\x64\x7b\x14\x40\x31\x56\x17\xdd\xf8\xa7\x88\xbc\xc9\x14\xe4\xcf\x28\xd6\x7d\xe1\xed\x30\xaf\x03\x5c\x7e\x73\x66\xbd\x29\xf7\xa5\xdc\x92\x7c\xcd\x5f\xc6\xbb\x79\x4e\x65\x03\x7a\xe1\xce\x3c\xf1\xd7\x6f\x00\x7c\x68\xd6\x88\xec\xc9\x7d\x4f\x06\x70\xf8\x87\x24\x7c\x01\x0d\x37\x4c\xf5\xa0\xac\x9f\x03\x43\x66\x79\xb9\x7c\x6c\xda\x16\xe5\x3e\xdf\xc6\xe7\x12\x5e\x05\xd0\xc1\x24\x06\xec\x0e\xe0\xeb\x7c\x8d\x48\x9e\xeb\x9d\x49\x1e\x90\x87\x22\x1a\x86\xde\xed\xe5\xfe\x85\x6c\x13\x81\xe2\x3d\xf2\x75\x36\x72\xff\x29\xe5\x46\x36\x31\xf0\x15\x66\x3c\x64\x79\x49\xa4\xf9\x7e\x93\x7f\xfc\xfa\xdb\x13\xbe\xcd\xe5\x4d\xe5\xcb\x41\x24\x3e\x03\x70\xb1\xd5\xd8\x3a\x1e\x28\x6f\xfa\x20\x9f\x6f\x92\x20\x9f\x46\x31\x46\x73\xd4\x76\xbd\x93\x22\xc6\xe8\xa2\x32\xb6\x8e\x87\x33\x45\xee\xe4\xc8\xd7\x29\xe9\x58\xf5\xba\x90\x9d\x9f\xa1\xb8\x6e\x26\x80\xa2\x32\x5f\xa1\x17\x15\x22\xff\xa7\x38\x25\x27\xb9\xa1\xf8\x3e\x4f\xbe\x15\x13\xfb\xb7\x58\xd8\x36\x8b\xcc\xbd\x20\x3e\xce\x01\x30\x16\xc0\x4a\x00\xbb\x85\xbd\x08\x09\xff\xe9\x10\xfb\x4b\x79\xf2\x08\x00\xbf\x57\x1d\x17\xc1\x0e\x40\x55\x09\xd2\x8c\xdd\x9f\x62\xac\x77\x9c\xb1\x4e\x41\xc6\x3a\xd4\x33\xd6\xbe\x36\x43\xa5\x55\x8c\x95\x48\xb2\xeb\x4f\xf7\x6e\x69\x6d\x86\xda\x53\x5f\xd4\x27\xf5\x4d\x63\xa4\x69\x4a\x55\x25\xa0\xb1\xcb\x18\x63\x15\x8c\xb1\xb1\xfa\x3a\x85\xa5\x86\x14\x07\x00\xbf\x02\xd0\x0b\xc0\x74\x51\xdb\xda\x43\x62\x24\xe2\x60\x59\xc7\x08\x8b\xba\xdf\x67\x62\x9d\x6f\x17\x36\xe1\xe7\x6d\xcf\x91\x12\xcf\x94\x1b\x5d\x00\x60\x16\x80\xff\xa8\xc6\x6b\x06\xdd\xa3\x39\xbe\x03\x60\x12\x80\xce\x6d\x21\x8f\x0a\x7c\x4b\xbd\x79\x42\xac\xa7\xa9\x92\x92\xbd\x24\xdf\x42\xb1\xae\x85\xed\x82\xb0\x45\x87\x00\x2c\x12\xf5\xa5\x1f\x65\x1e\xa2\xe6\xbd\xd0\xcc\xee\x40\xd4\x00\x3f\xaf\x6b\xc1\xb2\x35\x4d\xb8\xed\xfe\x4c\x3d\x7c\xd0\x5d\x5e\x9e\x77\x3e\xbd\xbe\x19\x07\xeb\x13\xb2\x9e\x9d\x0f\x69\x31\x8f\xe9\xc5\xda\x25\x13\xbe\x4b\x00\xf4\x16\x39\x8d\xa9\xdd\xa6\x75\xa6\x78\xf6\x4f\x37\x37\x64\xf3\x34\x59\x47\x92\x7f\x5f\x3d\xde\xcd\xcf\x06\x62\x71\xcb\xfd\x20\xd9\x7a\x1b\x40\xb7\x36\xe0\xfd\x54\x00\xb7\x59\xad\x39\x44\x2e\x5e\xf5\x74\x30\x1b\x67\x5b\xc5\xc0\x15\x43\x9c\xbc\x1e\x96\x32\x5d\x89\x2c\xea\x00\xdc\x58\xac\x3c\x89\xda\xee\xec\x7c\x79\xa4\x11\xef\x7c\x1c\xe5\x79\xac\x4a\x2c\x4f\xef\x50\x8c\xb5\x7b\xbf\x69\x3d\x4d\x8f\x06\x00\x63\x0a\xad\x75\x8a\x75\x9f\x2d\x62\x71\x4b\x90\xcc\x4f\x7b\xa8\x91\xd7\x75\x28\xce\x55\x21\x7a\xf7\xe1\x95\xb6\xe9\x9d\x84\x0f\xc0\xe8\x42\xf6\x01\xc0\xad\x2a\xeb\x4e\x20\x59\x7e\xec\xc5\x10\xa6\x3f\xdc\xc8\xeb\xb5\x2a\x44\xf3\xa5\x5c\x5a\xb3\x97\x21\x09\xb2\xb5\xd7\x29\xf2\x4e\xba\x7a\x5c\xb9\xe7\x9f\x0e\x5f\x91\xcf\xb1\xe1\x9d\x62\xf5\xad\x56\x9d\x50\xfe\xbb\xba\x26\xcc\xed\xc8\x6a\x49\x35\x45\x92\x68\x4f\x7d\x51\x2e\xec\xf6\xdb\xba\xc0\xb5\x00\x7e\x61\xc2\x7b\x89\x88\x2b\x2d\x8d\xdb\xbb\xdb\xa2\x59\x5b\x42\x39\x56\x9b\xd0\x00\x27\xcf\xe1\xf6\x1e\xb0\xd5\xe9\x38\x80\x71\x26\xfc\x77\x57\x91\x1b\xe2\xbf\x9b\x62\xcd\x46\x95\xc8\x26\xf5\x1c\xe5\xc2\x3e\x7b\xfe\x09\xfb\x28\x0e\x35\xf0\x7e\x8a\xa8\xf9\xd9\x62\xd3\xc7\x51\xbe\x66\xaa\xb6\x46\x8d\x1c\x3c\x5f\x55\x58\x7f\x08\xf9\x98\x65\xe0\xbf\x5c\x7f\x2e\x6c\x85\x2f\x0f\x25\xb0\xe0\xa9\x20\xe6\x57\xb7\x1d\xcd\xab\x0e\xf0\x73\x70\x85\x1c\x5b\x82\x62\xdc\xb3\x74\xfc\xdf\xad\xda\xf0\xff\x04\xb4\x51\x43\x04\xef\x1d\x44\x0c\xac\x84\x63\xae\x24\x6a\x77\xc5\x50\xbb\xbb\x0d\x69\x57\x0c\x5b\xf7\xc4\x55\xec\x8f\x1e\xab\x44\x1c\xdf\xcb\xec\x8c\xc5\x08\xca\xe3\xc7\xce\xf3\xf1\x9a\x2b\xc5\x0b\x6d\x45\x15\x43\x5d\xbc\x7e\x46\x3e\xb0\xb1\x49\xd9\xa9\x1d\x00\xd0\x09\xc0\x9d\x2a\xb9\x47\x3c\x91\x89\xd1\xca\x15\xeb\x95\x05\xdb\x20\xf1\xa4\xd8\x3b\xa5\xb6\x0d\x94\xd3\xdd\x24\xce\xf6\x6d\x41\x31\x1a\xd9\x08\xd5\x7a\x6b\xb1\x76\xb4\xd7\x68\x17\xb6\xef\x8d\xab\xb0\x44\x76\x68\x81\xd0\x65\x4b\xc8\x73\x02\x8a\xeb\xf9\xb9\x8d\xee\x1c\xa6\x58\x5f\x70\xd1\xa0\x4c\x5b\x7d\xae\x20\xcf\x84\x26\x2e\xf2\x23\x6c\x5f\xff\x81\xb8\x17\x60\x59\xdf\x24\x6c\xd8\x12\xc9\x9e\xf5\xfc\x75\xbc\x9b\x9f\xf5\x2e\x5e\x11\xe4\xb5\xa6\x6b\x26\xba\x8b\xda\x93\x4b\x86\xb9\x78\x1f\xd5\xeb\x42\x98\xba\xc4\xcf\xeb\xb4\x97\x8d\xce\x9c\x25\xd1\x38\x1f\xed\xb4\x3c\x23\x94\xf8\x44\xd4\x09\x4c\x41\x31\xe2\xfa\xf7\x22\x3c\xff\x23\x3b\x71\xd4\x91\xcc\x9e\xff\xae\x7c\xb3\x59\xf9\xfc\x24\x5f\x2e\x43\xeb\x1c\x0a\x6b\xfc\x3c\xc7\xd3\xa8\x71\xdf\x42\x72\x4a\x79\x1c\x3d\x15\x70\xd0\x2a\x27\xcc\xce\x21\xcf\x56\x92\x1d\xed\x7b\x87\x5b\xe9\xec\xc9\x8c\x28\x17\xdb\x64\xc2\xa7\x62\x6c\xfd\xbd\x0a\xff\xf9\xb0\x7e\x4b\xe4\xa4\xf5\x95\x64\x7d\xe6\xd2\x46\x55\x7b\x63\xc6\xbf\x6d\x8e\x65\x44\x3a\x0d\x7e\xfe\x58\xa6\x78\xf6\x64\x25\x43\xfd\xa6\x79\xb8\x7d\x28\x12\x75\x76\xb9\x79\x3e\xc4\x5a\xd2\x18\x37\xdf\xa7\x7c\x76\x66\x65\x2f\xaf\x1c\xd7\xc0\x6b\xff\x45\x62\x3b\x80\x9d\x85\x36\x22\xfd\x1d\x33\xd7\xa7\x7c\xf6\x68\xc5\xff\x15\xb7\x34\xf0\xba\x50\x91\x20\xdf\xb5\xba\xd0\x46\x89\x64\x1a\x93\x1f\xf0\xb7\xc9\xfa\x5f\x75\x5b\x03\xb7\x69\x45\x80\xac\xca\x5c\x00\x53\xec\xce\x19\xf3\x61\xc9\x73\x4d\x27\xcd\xff\x49\xca\x7f\x48\xdc\xbd\xbd\x54\xc5\x87\x19\x51\x53\x1b\x45\x37\x85\x7b\x0b\x92\xc8\x37\x55\x0c\x6d\x6d\x7f\xa6\x2c\xf1\xf3\xfd\x2c\x02\x5f\x89\x7b\x26\xbf\x14\xf7\xc0\x0a\x02\xed\xf9\xd5\x13\xd4\x7c\x2f\xbd\x33\x62\x8e\x17\x97\x8f\x69\xc8\x79\x9f\xfe\xa6\xdc\xbd\x48\x3c\x2b\xeb\x5a\xa2\x66\x5a\x10\xc8\x67\x92\xed\x96\x32\x44\x7e\x9f\xe2\x3b\xb3\x39\xcc\x79\x3c\xc0\x63\x0f\xc9\x7f\xb9\xb8\xd3\xf1\x5d\x71\xb6\x87\x82\x8b\xfe\xba\xfc\xab\x8b\xb8\xf3\x51\x10\x36\x6e\x8d\xf2\xf8\x81\x62\xdf\x4b\x87\xbb\x78\x1e\xd8\x63\x64\xeb\x39\xd0\xdc\xaa\xd7\x86\xd0\x7f\x9a\x27\xe7\x7c\xfa\xd1\x55\x4d\x56\x77\x70\xac\xf0\xa9\xfe\x4e\xab\xa8\x9d\x3c\x5a\x68\x27\x0e\x4f\x0a\xd7\xdf\xe1\xe6\x76\x94\xc7\xbd\xfb\xe2\x3c\xb6\x33\xda\x55\x8a\x13\x48\x5f\xc6\xce\xcf\x7c\x47\x6b\xdf\x77\xb2\x5b\xf5\x2e\x85\x11\xe4\xad\xa7\xe4\xa9\x9f\x94\x17\xba\x07\x9a\xb8\xbb\x7c\x7e\x3f\x07\xbf\x8b\xd7\xe0\x4b\xf1\x98\x54\x1f\x13\xc9\x3b\xd1\x9f\xd7\xb5\xf0\x73\x6a\xf2\xd9\xf4\x99\x3c\x1f\x2e\x02\x9f\xe9\x73\x77\xc3\x1c\x66\x17\x78\x0e\xc4\x63\x53\xd2\x01\xf2\x07\x14\xc7\xec\xd8\x17\xcf\xd1\x03\x9a\xcb\x80\xe9\x1e\x9e\x17\xae\x7c\xab\x99\xaf\xff\x9f\xc7\x36\xe0\x1b\xf3\xbb\x53\x56\xa0\x58\x67\xb8\x45\xfd\xf0\xf4\x42\x6d\xd1\xb6\xbd\x71\x1e\xaf\xbf\xbc\x29\x63\x47\xbe\x77\x26\x73\xf4\x94\xe6\xb6\x68\x79\x90\x7f\xf7\xdf\x2f\xe3\x3c\xee\x27\x19\x33\xb9\x83\x69\x87\xe7\x00\xfc\xcc\x8c\x7f\x76\xe2\xce\xc6\x61\xd5\x0e\x3f\xda\x15\xe3\x72\x2d\x6b\x07\xc1\x90\xc6\xcf\x8c\x78\x3e\x55\x99\xb1\x49\x5b\x77\x67\xf2\xc1\x80\xf8\x8e\xe4\xa8\x08\x90\xdc\xfc\xc1\x8a\x77\xdd\x1c\x86\xc8\x7b\xed\x76\x38\x70\x24\xc1\x49\x22\x1a\x4f\x67\x75\x98\x68\xe4\x3d\x5e\x7e\xb6\x24\xb1\xe2\xf5\x66\xee\xbb\x0b\x04\xe9\xe5\x15\x2a\xbc\xb3\x13\xf5\xc4\x49\x2a\x77\xc8\x8c\x20\x5f\x3a\x71\x61\x26\x36\x22\x19\x7a\xee\x8d\xdc\x3b\x7a\x2e\x5f\x8a\xff\xa6\xa3\x00\x9f\x4b\xf1\x71\x3f\x55\xde\x75\x73\x68\x27\xee\xcc\xfb\x0a\x9d\xc3\x82\xa7\x82\xd9\x3b\x8e\x75\x47\x5b\xeb\x29\xc9\x18\xf9\x3f\x05\x1c\xd5\xfb\xa9\x22\xe6\x40\xfb\x30\xc8\xee\x1e\x97\x11\x94\xbb\x92\xee\xf2\xdf\x90\x58\x9f\x35\x5a\x61\x37\x80\x2b\x8b\xe5\xdd\x30\x8f\x4b\xc4\x59\xb9\x92\xc3\x21\x5b\x39\xea\x5e\x1f\x66\x3c\xda\x58\xc8\x19\x91\x44\x54\xd4\x06\xcf\x6d\x0b\xde\x75\x73\xe8\x28\xee\x14\x1f\xb2\x3b\xe3\x80\xb8\xdb\x47\x7a\xaa\x78\x5f\x13\x22\x0f\xdf\x23\xce\xea\x7e\x94\x7b\x11\x22\xce\x38\x4f\x9c\xf5\x1f\xb4\xf3\x75\x64\x77\x14\xf4\x34\x21\x7e\x83\x30\x13\xc0\xd9\x3f\x06\xdf\x26\xf3\xe8\x04\x60\xbc\xb8\x03\x55\x2f\xce\x76\x54\x90\x16\x7e\xf4\x90\xf8\x8d\xdf\x08\xb3\x78\xe0\x27\x9a\x4b\x7b\x71\x17\x65\xa4\xf8\x5d\xcc\x66\x71\xcf\xe9\xb8\xb8\x97\xe2\x15\x67\x23\x5f\x88\x7b\x01\x4b\xc4\xfd\xce\x73\x8c\xbf\x6d\x2a\x6e\xfc\xfa\xf6\x00\x3d\x05\xc5\x19\xeb\x44\xcf\x7a\xc6\x3a\xd0\xb3\x96\xb1\x52\x7a\x56\x31\x56\x42\x4f\xde\x88\xb1\x34\x3d\xef\x67\x2c\x45\xcf\xde\x8c\xc5\xe9\xd9\x89\xb1\x20\x3d\x3b\x30\x56\xaf\x7f\xb6\x67\xac\x56\xff\x2c\x3d\xf1\xac\x62\xfc\x82\x52\xab\x27\xd3\x3f\x4f\x16\x79\xfa\x97\x4f\x23\x3f\x92\x4f\xc9\xb7\x9c\x8f\x7c\xca\x79\xca\x79\xcb\x75\x90\xeb\x92\x5d\x27\xb9\x6e\x41\xb1\x8e\x71\xc6\x7a\xeb\xd7\x59\xac\xfb\xff\x02\x00\x00\xff\xff\xeb\x12\x88\x54\xee\x3a\x00\x00")
 
 func uiAppFaviconIcoBytes() ([]byte, error) {
@@ -394,6 +414,7 @@ func AssetNames() []string {
 var _bindata = map[string]func() (*asset, error){
 	"ui/app/script.js": uiAppScriptJs,
 	"ui/app/index.html": uiAppIndexHtml,
+	"ui/app/routes.html": uiAppRoutesHtml,
 	"ui/app/favicon.ico": uiAppFaviconIco,
 	"ui/lib/bootstrap-4.0.0-alpha.6-dist/css/bootstrap.min.css": uiLibBootstrap400Alpha6DistCssBootstrapMinCss,
 	"ui/lib/bootstrap-4.0.0-alpha.6-dist/css/bootstrap.min.css.map": uiLibBootstrap400Alpha6DistCssBootstrapMinCssMap,
@@ -451,6 +472,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"app": &bintree{nil, map[string]*bintree{
 			"favicon.ico": &bintree{uiAppFaviconIco, map[string]*bintree{}},
 			"index.html": &bintree{uiAppIndexHtml, map[string]*bintree{}},
+			"routes.html": &bintree{uiAppRoutesHtml, map[string]*bintree{}},
 			"script.js": &bintree{uiAppScriptJs, map[string]*bintree{}},
 		}},
 		"lib": &bintree{nil, map[string]*bintree{
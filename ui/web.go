@@ -24,6 +24,8 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/audit"
 )
 
 func serveAsset(w http.ResponseWriter, req *http.Request, fp string, logger log.Logger) {
@@ -46,8 +48,9 @@ func serveAsset(w http.ResponseWriter, req *http.Request, fp string, logger log.
 	http.ServeContent(w, req, info.Name(), info.ModTime(), bytes.NewReader(file))
 }
 
-// Register registers handlers to serve files for the web interface.
-func Register(r *route.Router, reloadCh chan<- struct{}, logger log.Logger) {
+// Register registers handlers to serve files for the web interface. Ready
+// reports the readiness of the Alertmanager.
+func Register(r *route.Router, reloadCh, drainCh chan<- struct{}, ready func() bool, al *audit.Log, logger log.Logger) {
 	ihf := prometheus.InstrumentHandlerFunc
 
 	r.Get("/metrics", prometheus.Handler().ServeHTTP)
@@ -56,6 +59,10 @@ func Register(r *route.Router, reloadCh chan<- struct{}, logger log.Logger) {
 		serveAsset(w, req, "ui/app/index.html", logger)
 	}))
 
+	r.Get("/routes", ihf("routes", func(w http.ResponseWriter, req *http.Request) {
+		serveAsset(w, req, "ui/app/routes.html", logger)
+	}))
+
 	r.Get("/script.js", ihf("app", func(w http.ResponseWriter, req *http.Request) {
 		serveAsset(w, req, "ui/app/script.js", logger)
 	}))
@@ -71,9 +78,23 @@ func Register(r *route.Router, reloadCh chan<- struct{}, logger log.Logger) {
 		},
 	))
 
-	r.Post("/-/reload", func(w http.ResponseWriter, req *http.Request) {
+	r.Post("/-/reload", al.Wrap("config_reload", func(w http.ResponseWriter, req *http.Request) {
 		w.Write([]byte("Reloading configuration file..."))
 		reloadCh <- struct{}{}
+	}))
+
+	r.Post("/-/drain", al.Wrap("drain", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("Draining..."))
+		drainCh <- struct{}{}
+	}))
+
+	r.Get("/-/ready", func(w http.ResponseWriter, req *http.Request) {
+		if !ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Not ready."))
+			return
+		}
+		w.Write([]byte("Ready."))
 	})
 
 	r.Get("/debug/*subpath", http.DefaultServeMux.ServeHTTP)